@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// runAlerts dispatches `vitals alerts <subcommand>`.
+func runAlerts(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals alerts check")
+		return 2
+	}
+	switch args[0] {
+	case "check":
+		return runAlertsCheck(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown alerts command %q\n", args[0])
+		return 2
+	}
+}
+
+// runAlertsCheck evaluates every enabled weight-change and user-defined
+// rule and sends due planned entry reminders; schedule it every few minutes
+// so time-of-day rules fire on time.
+func runAlertsCheck(args []string) int {
+	fs := flag.NewFlagSet("alerts check", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 5*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; alert rules live in the server process for the in-memory store")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	unlock, ok, err := db.TryLock(context.Background(), "vitals alerts check")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lock: %v\n", err)
+		return 1
+	}
+	if !ok {
+		fmt.Println("another instance is running alerts check; skipping")
+		return 0
+	}
+	defer unlock()
+
+	notifier := newNotifier()
+	svc := app.NewAlertService(db, db).WithNotifier(domain.AlertChannelWebhook, notifier).WithOutbox(db)
+	rules := app.NewRuleService(db, db, db).WithNotifier(domain.AlertChannelWebhook, notifier).WithOutbox(db)
+	plans := app.NewPlanService(db, db, db).WithNotifier(domain.AlertChannelWebhook, notifier).WithOutbox(db)
+	outbox := app.NewOutboxService(db).WithNotifier(domain.AlertChannelWebhook, notifier)
+	if pub, err := connectMQTT(); err != nil {
+		fmt.Fprintf(os.Stderr, "mqtt: %v\n", err)
+	} else if pub != nil {
+		defer pub.Close()
+		svc.WithNotifier(domain.AlertChannelMQTT, pub)
+		rules.WithNotifier(domain.AlertChannelMQTT, pub)
+		plans.WithNotifier(domain.AlertChannelMQTT, pub)
+		outbox.WithNotifier(domain.AlertChannelMQTT, pub)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// Notifications are queued with their rules and delivered right away
+	// here; the server retries the ones that fail.
+	now := time.Now()
+	sent, err := svc.EvaluateAll(ctx, now)
+	fired, ruleErr := rules.EvaluateAll(ctx, now)
+	reminded, planErr := plans.RemindAll(ctx, now)
+	delivered, outboxErr := outbox.Drain(ctx)
+	fmt.Printf("queued %d alert(s), %d rule notification(s), %d plan reminder(s); delivered %d\n", sent, fired, reminded, delivered)
+	if err = errors.Join(err, ruleErr, planErr, outboxErr); err != nil {
+		fmt.Fprintf(os.Stderr, "alerts: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"vitals/internal/adapter/fieldcrypt"
+	"vitals/internal/adapter/postgres"
+	"vitals/internal/app"
+)
+
+// runDB dispatches `vitals db <subcommand>`.
+func runDB(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals db cleanup [--dry-run] [--vacuum] [--changes-days N] | vitals db downsample [--dry-run] [--days N] | vitals db rotate-keys | vitals db migrate [up|down|status]")
+		return 2
+	}
+	switch args[0] {
+	case "cleanup":
+		return runDBCleanup(args[1:])
+	case "downsample":
+		return runDBDownsample(args[1:])
+	case "rotate-keys":
+		return runDBRotateKeys(args[1:])
+	case "migrate":
+		return runDBMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown db command %q\n", args[0])
+		return 2
+	}
+}
+
+func runDBCleanup(args []string) int {
+	fs := flag.NewFlagSet("db cleanup", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be cleaned without changing anything")
+	vacuum := fs.Bool("vacuum", false, "run VACUUM ANALYZE after cleanup")
+	changeDays := fs.Int("changes-days", envInt("CHANGE_RETENTION_DAYS", app.DefaultChangeRetentionDays), "age in days past which change log entries a full sync no longer needs are purged")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *changeDays < 1 {
+		fmt.Fprintln(os.Stderr, "-changes-days must be at least 1")
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; nothing to clean in the in-memory store")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report, err := app.NewMaintenanceService(db).Cleanup(ctx, app.CleanupOptions{DryRun: *dryRun, Vacuum: *vacuum, ChangeRetentionDays: *changeDays})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cleanup: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+	return 0
+}
+
+// runDBDownsample collapses water events older than the retention age into
+// daily totals; schedule it nightly or weekly to keep multi-year histories
+// small.
+func runDBDownsample(args []string) int {
+	fs := flag.NewFlagSet("db downsample", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be collapsed without changing anything")
+	days := fs.Int("days", envInt("WATER_RETENTION_DAYS", app.DefaultWaterRetentionDays), "age in days past which water events are collapsed into daily totals")
+	timeout := fs.Duration("timeout", 30*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *days < 1 {
+		fmt.Fprintln(os.Stderr, "-days must be at least 1")
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; nothing to downsample in the in-memory store")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	unlock, ok, err := db.TryLock(context.Background(), "vitals db downsample")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lock: %v\n", err)
+		return 1
+	}
+	if !ok {
+		fmt.Println("another instance is running db downsample; skipping")
+		return 0
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	svc := app.NewMaintenanceService(db).WithRetention(db, *days)
+	report, err := svc.DownsampleWater(ctx, app.RetentionCutoff(time.Now(), *days), *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "downsample: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+	return 0
+}
+
+func runDBRotateKeys(args []string) int {
+	fs := flag.NewFlagSet("db rotate-keys", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; the in-memory store is not encrypted")
+		return 2
+	}
+	applyPostgresEnv()
+
+	keys, err := fieldcrypt.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encryption keys: %v\n", err)
+		return 1
+	}
+	if keys == nil {
+		fmt.Fprintln(os.Stderr, "ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE must be set")
+		return 2
+	}
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	n, err := db.RotateEncryption(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rotate: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(map[string]any{"primaryKey": keys.PrimaryKeyID(), "rowsRewritten": n})
+	return 0
+}
+
+// runDBMigrate applies, rolls back or lists schema migrations without
+// starting the server: `vitals db migrate [up]`, `vitals db migrate down
+// [-steps N]` or `vitals db migrate status`.
+func runDBMigrate(args []string) int {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("db migrate "+action, flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "number of migrations to roll back (down only)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *steps < 1 {
+		fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; the in-memory store has no schema to migrate")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := postgres.Connect(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var ran []postgres.Migration
+	switch action {
+	case "up":
+		ran, err = db.Migrate(ctx)
+	case "down":
+		ran, err = db.Rollback(ctx, *steps)
+	case "status":
+		states, err := db.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(states)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q\n", action)
+		return 2
+	}
+	for _, m := range ran {
+		fmt.Printf("%s %04d_%s\n", action, m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", action, err)
+		return 1
+	}
+	if len(ran) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return 0
+}
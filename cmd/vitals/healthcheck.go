@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"vitals/internal/adapter/postgres"
+)
+
+// runHealthcheck probes the running server's health endpoint (or, with -db,
+// pings PostgreSQL directly) and returns a process exit code. It lets
+// container runtimes health-check the image without shipping curl. The probe
+// sends HEALTH_TOKEN, if set, for servers with HEALTH_ACCESS=token.
+func runHealthcheck(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	url := fs.String("url", "", "health endpoint to probe (default derived from ADDR)")
+	db := fs.Bool("db", false, "ping POSTGRES_URL directly instead of the HTTP endpoint")
+	timeout := fs.Duration("timeout", 3*time.Second, "maximum time to wait for a response")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var err error
+	if *db {
+		err = pingDB(ctx)
+	} else {
+		target := *url
+		if target == "" {
+			target = healthURL(env("ADDR", ":8080"))
+		}
+		err = probeHTTP(ctx, target)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// healthURL builds the loopback health endpoint URL for a listen address.
+// Wildcard hosts (":8080", "0.0.0.0:8080") are probed via 127.0.0.1.
+func healthURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://127.0.0.1:8080/api/v1/health"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/api/v1/health"
+}
+
+func probeHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token := os.Getenv("HEALTH_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func pingDB(ctx context.Context) error {
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		return errors.New("POSTGRES_URL is not set")
+	}
+	applyPostgresEnv()
+	return postgres.Ping(ctx, connStr)
+}
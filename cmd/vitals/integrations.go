@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+	"vitals/internal/integration/googlefit"
+)
+
+// googleFitSource returns the Google Fit client configured by
+// GOOGLE_FIT_CLIENT_ID and GOOGLE_FIT_CLIENT_SECRET, or nil if the
+// integration is not configured.
+func googleFitSource() (*googlefit.Client, error) {
+	id := os.Getenv("GOOGLE_FIT_CLIENT_ID")
+	if id == "" {
+		return nil, nil
+	}
+	secret := os.Getenv("GOOGLE_FIT_CLIENT_SECRET")
+	if secret == "" {
+		return nil, errors.New("GOOGLE_FIT_CLIENT_SECRET is required with GOOGLE_FIT_CLIENT_ID")
+	}
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		return nil, errors.New("PUBLIC_URL is required with GOOGLE_FIT_CLIENT_ID, for the OAuth2 callback")
+	}
+	return googlefit.New(id, secret, publicURL+"/api/integrations/"+domain.IntegrationGoogleFit+"/callback"), nil
+}
+
+// withIntegrations adds the configured providers to svc.
+func withIntegrations(svc *app.IntegrationService) (*app.IntegrationService, error) {
+	fit, err := googleFitSource()
+	if err != nil {
+		return nil, err
+	}
+	if fit != nil {
+		svc.WithSource(domain.IntegrationGoogleFit, fit)
+	}
+	return svc, nil
+}
+
+// runIntegrations dispatches `vitals integrations <subcommand>`.
+func runIntegrations(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals integrations sync")
+		return 2
+	}
+	switch args[0] {
+	case "sync":
+		return runIntegrationsSync(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown integrations command %q\n", args[0])
+		return 2
+	}
+}
+
+// runIntegrationsSync pulls new readings for every connected account;
+// schedule it hourly.
+func runIntegrationsSync(args []string) int {
+	fs := flag.NewFlagSet("integrations sync", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; with the in-memory store, sync from the server with POST /api/integrations/{provider}/sync")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	svc, err := withIntegrations(app.NewIntegrationService(db, db, db))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integrations: %v\n", err)
+		return 2
+	}
+	if len(svc.Providers()) == 0 {
+		fmt.Println("no integrations configured; nothing to sync")
+		return 0
+	}
+
+	unlock, ok, err := db.TryLock(context.Background(), "vitals integrations sync")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lock: %v\n", err)
+		return 1
+	}
+	if !ok {
+		fmt.Println("another instance is running integrations sync; skipping")
+		return 0
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	synced, err := svc.SyncAll(ctx)
+	fmt.Printf("synced %d connection(s)\n", synced)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "integrations: %v\n", err)
+		return 1
+	}
+	return 0
+}
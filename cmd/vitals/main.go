@@ -2,29 +2,92 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"vitals/internal/adapter/fieldcrypt"
 	adapthttp "vitals/internal/adapter/http"
 	"vitals/internal/adapter/memory"
+	"vitals/internal/adapter/mqtt"
+	"vitals/internal/adapter/openweather"
 	"vitals/internal/adapter/postgres"
+	"vitals/internal/adapter/pow"
+	"vitals/internal/adapter/smtp"
+	"vitals/internal/adapter/tracing"
+	"vitals/internal/adapter/webhook"
 	"vitals/internal/app"
 	"vitals/internal/domain"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	addr := env("ADDR", ":8080")
 	webDir := env("WEB_DIR", "web")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "vitals")
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+	if tracing.Enabled() {
+		log.Println("Exporting traces over OTLP")
+	}
+
 	var (
 		weightRepo       domain.WeightRepository
 		waterRepo        domain.WaterRepository
+		foodRepo         domain.FoodRepository
+		importRepo       domain.ImportRepository
 		chartsWeightRepo domain.WeightRepository
 		chartsWaterRepo  domain.WaterRepository
 		userRepo         domain.UserRepository
 		sessionRepo      domain.SessionRepository
+		profileRepo      domain.ProfileRepository
+		shareRepo        domain.ShareRepository
+		tokenRepo        domain.APITokenRepository
+		changeRepo       domain.ChangeRepository
+		activityRepo     domain.ActivityRepository
+		retentionRepo    domain.RetentionRepository
+		batchRepo        domain.BatchRepository
+		alertRepo        domain.AlertRuleRepository
+		ruleRepo         domain.RuleRepository
+		planRepo         domain.PlanRepository
+		identityRepo     domain.IdentityRepository
+		accountRepo      domain.AccountRepository
+		recoveryRepo     domain.RecoveryRepository
+		revocationRepo   domain.RevocationRepository
+		hydrationRepo    domain.HydrationSettingsRepository
+		goalHistoryRepo  domain.GoalHistoryRepository
+		weightGoalRepo   domain.WeightGoalRepository
+		settingsRepo     domain.SettingsRepository
+		tagRepo          domain.TagRepository
+		journalRepo      domain.JournalRepository
+		moodRepo         domain.MoodRepository
+		stepsRepo        domain.StepsRepository
+		medicationRepo   domain.MedicationRepository
+		temperatureRepo  domain.TemperatureRepository
+		metricRepo       domain.CustomMetricRepository
+		summaryRepo      domain.WeeklySummaryRepository
+		maintenanceRepo  domain.MaintenanceRepository
+		oauthTokenRepo   domain.OAuthTokenRepository
+		usageRepo        domain.UsageRepository
+		outboxRepo       domain.OutboxRepository
+		webhookRepo      domain.WebhookRepository
+		// pg is the primary Postgres database, nil when data is kept in
+		// memory.
+		pg *postgres.DB
+		// cluster coordinates instances sharing a Postgres database.
+		cluster domain.Cluster
 	)
 
 	useMemory := os.Getenv("POSTGRES_URL") == ""
@@ -35,42 +98,363 @@ func main() {
 		mem := memory.New()
 		weightRepo = mem
 		waterRepo = mem
+		foodRepo = mem
+		importRepo = mem
 		chartsWeightRepo = mem
 		chartsWaterRepo = mem
 		userRepo = mem
 		sessionRepo = mem.NewSessionRepo()
+		profileRepo = mem
+		shareRepo = mem
+		tokenRepo = mem
+		changeRepo = mem
+		activityRepo = mem
+		retentionRepo = mem
+		batchRepo = mem
+		alertRepo = mem
+		ruleRepo = mem
+		planRepo = mem
+		identityRepo = mem
+		accountRepo = mem
+		recoveryRepo = mem
+		revocationRepo = mem
+		hydrationRepo = mem
+		goalHistoryRepo = mem
+		weightGoalRepo = mem
+		settingsRepo = mem
+		tagRepo = mem
+		journalRepo = mem
+		moodRepo = mem
+		stepsRepo = mem
+		medicationRepo = mem
+		temperatureRepo = mem
+		metricRepo = mem
+		summaryRepo = mem
+		maintenanceRepo = mem
+		oauthTokenRepo = mem
+		usageRepo = mem
+		outboxRepo = mem
+		webhookRepo = mem
 	} else {
 		log.Println("Using PostgreSQL database")
 		connStr := os.Getenv("POSTGRES_URL")
+		applyPostgresEnv()
 
-		// Map custom env vars to lib/pq standard vars if provided
-		if v := os.Getenv("POSTGRES_USER"); v != "" {
-			_ = os.Setenv("PGUSER", v)
-		}
-		if v := os.Getenv("POSTGRES_PASSWORD"); v != "" {
-			_ = os.Setenv("PGPASSWORD", v)
-		}
-
-		db, err := postgres.Open(connStr)
+		db, err := openPostgres(connStr)
 		if err != nil {
 			log.Fatalf("db open: %v", err)
 		}
 		defer func() { _ = db.Close() }()
+		pg = db
+		if db.RowLevelSecurity() {
+			log.Println("Postgres row-level security enabled")
+		}
+		if c, err := postgres.NewCluster(db, connStr); err != nil {
+			log.Printf("Multi-instance coordination disabled: %v", err)
+		} else {
+			defer func() { _ = c.Close() }()
+			cluster = c
+		}
 
 		weightRepo = db
 		waterRepo = db
+		foodRepo = db
+		importRepo = db
 		chartsWeightRepo = db
 		chartsWaterRepo = db
 		userRepo = db
 		sessionRepo = postgres.NewSessionRepo(db)
+		profileRepo = db
+		shareRepo = db
+		tokenRepo = db
+		changeRepo = db
+		activityRepo = db
+		retentionRepo = db
+		batchRepo = db
+		alertRepo = db
+		ruleRepo = db
+		planRepo = db
+		identityRepo = db
+		accountRepo = db
+		recoveryRepo = db
+		revocationRepo = db
+		hydrationRepo = db
+		goalHistoryRepo = db
+		weightGoalRepo = db
+		settingsRepo = db
+		tagRepo = db
+		journalRepo = db
+		moodRepo = db
+		stepsRepo = db
+		medicationRepo = db
+		temperatureRepo = db
+		metricRepo = db
+		summaryRepo = db
+		maintenanceRepo = db
+		oauthTokenRepo = db
+		usageRepo = db
+		outboxRepo = db
+		webhookRepo = db
 	}
 
-	weightSvc := app.NewWeightService(weightRepo)
-	waterSvc := app.NewWaterService(waterRepo)
-	chartsSvc := app.NewChartsService(chartsWeightRepo, chartsWaterRepo)
-	authSvc := app.NewAuthService(userRepo, sessionRepo)
+	if kind := os.Getenv("SESSION_STORE"); kind != "" {
+		repo, err := openSessionStore(kind, pg)
+		if err != nil {
+			log.Fatalf("session store: %v", err)
+		}
+		if kind == sessionStoreMemory && cluster != nil {
+			log.Println("Warning: in-memory sessions are not shared between instances; users must sign in to each one")
+		}
+		log.Printf("Keeping sessions in %s", kind)
+		sessionRepo = repo
+	}
+
+	if os.Getenv("SEED_DEMO_DATA") == "true" {
+		seedDemoData(userRepo, weightRepo, waterRepo)
+	}
+
+	weightSvc := app.NewWeightService(weightRepo).WithTags(tagRepo).WithSettings(settingsRepo)
+	waterSvc := app.NewWaterService(waterRepo).WithTags(tagRepo)
+	if v := os.Getenv("WATER_DUPLICATE_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("invalid WATER_DUPLICATE_WINDOW %q", v)
+		}
+		mode := env("WATER_DUPLICATE_MODE", "flag")
+		if mode != "flag" && mode != "merge" {
+			log.Fatalf("invalid WATER_DUPLICATE_MODE %q: must be flag or merge", mode)
+		}
+		waterSvc.WithDuplicateGuard(d, mode == "merge")
+	}
+	notifier := newNotifier()
+	alertSvc := app.NewAlertService(alertRepo, weightRepo).
+		WithNotifier(domain.AlertChannelWebhook, notifier).
+		WithOutbox(outboxRepo)
+	ruleSvc := app.NewRuleService(ruleRepo, weightRepo, waterRepo).
+		WithNotifier(domain.AlertChannelWebhook, notifier).
+		WithOutbox(outboxRepo)
+	planSvc := app.NewPlanService(planRepo, weightRepo, waterRepo).
+		WithNotifier(domain.AlertChannelWebhook, notifier).
+		WithOutbox(outboxRepo)
+	outboxSvc := app.NewOutboxService(outboxRepo).
+		WithNotifier(domain.AlertChannelWebhook, notifier)
+	sender := webhook.NewSender()
+	if allowPrivateWebhooks() {
+		sender.AllowPrivateNetworks()
+	}
+	webhookSvc := app.NewWebhookService(webhookRepo, sender)
+	syncSvc := app.NewSyncService(changeRepo)
+	weightPubs := domain.Publishers{alertSvc, webhookSvc, syncSvc}
+	waterPubs := domain.Publishers{webhookSvc, syncSvc}
+	if pub, err := connectMQTT(); err != nil {
+		log.Printf("MQTT publishing disabled: %v", err)
+	} else if pub != nil {
+		defer pub.Close()
+		log.Printf("Publishing events to MQTT broker %s", os.Getenv("MQTT_BROKER_URL"))
+		weightPubs = append(weightPubs, pub)
+		waterPubs = append(waterPubs, pub)
+		alertSvc.WithNotifier(domain.AlertChannelMQTT, pub)
+		ruleSvc.WithNotifier(domain.AlertChannelMQTT, pub)
+		planSvc.WithNotifier(domain.AlertChannelMQTT, pub)
+		outboxSvc.WithNotifier(domain.AlertChannelMQTT, pub)
+	}
+	weightSvc.WithPublisher(weightPubs)
+	waterSvc.WithPublisher(waterPubs)
+	go outboxSvc.Run(context.Background(), outboxInterval)
+	go webhookSvc.Run(context.Background(), outboxInterval)
+	chartsSvc := app.NewChartsService(chartsWeightRepo, chartsWaterRepo).
+		WithTags(tagRepo).
+		WithJournal(journalRepo).
+		WithMood(moodRepo).
+		WithSteps(stepsRepo).
+		WithSettings(settingsRepo).
+		WithGoalHistory(goalHistoryRepo).
+		WithWeightGoals(weightGoalRepo).
+		WithPlans(planRepo)
+	journalSvc := app.NewJournalService(journalRepo)
+	moodSvc := app.NewMoodService(moodRepo)
+	stepsSvc := app.NewStepsService(stepsRepo)
+	medicationSvc := app.NewMedicationService(medicationRepo)
+	temperatureSvc := app.NewTemperatureService(temperatureRepo)
+	metricsSvc := app.NewMetricsService(metricRepo)
+	nutritionSvc := app.NewNutritionService(foodRepo)
+	authSvc := app.NewAuthService(userRepo, sessionRepo).
+		WithIdentities(identityRepo).
+		WithAccounts(accountRepo).
+		WithRecovery(recoveryRepo).
+		WithAPITokens(tokenRepo).
+		WithRevocations(revocationRepo)
+	authSvc.WithLoginLimits(envInt("LOGIN_LOCKOUT_AFTER", 10), 0)
+	switch kind := os.Getenv("LOGIN_CHALLENGE"); kind {
+	case "":
+	case "pow":
+		challenge, err := pow.New(envInt("LOGIN_CHALLENGE_DIFFICULTY", pow.DefaultDifficulty))
+		if err != nil {
+			log.Fatalf("login challenge: %v", err)
+		}
+		authSvc.WithLoginChallenge(challenge, envInt("LOGIN_CHALLENGE_AFTER", 3))
+	default:
+		log.Fatalf("invalid LOGIN_CHALLENGE %q: must be pow", kind)
+	}
+	accountSvc := app.NewAccountService(userRepo, accountRepo).WithIdentities(identityRepo)
+	if mailer, err := connectSMTP(); err != nil {
+		log.Fatalf("invalid SMTP configuration: %v", err)
+	} else if mailer != nil {
+		publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+		if publicURL == "" {
+			log.Fatal("PUBLIC_URL is required with SMTP_ADDR, for email verification links")
+		}
+		accountSvc.WithMailer(mailer, publicURL+"/api/v1/auth/verify-email")
+	}
+	profileSvc := app.NewProfileService(profileRepo)
+	shareSvc := app.NewShareService(shareRepo, userRepo)
+	tokenSvc := app.NewTokenService(tokenRepo, userRepo)
+	summarySvc := app.NewSummaryService(weightRepo, waterRepo).
+		WithGoals(hydrationRepo).
+		WithGoalHistory(goalHistoryRepo).
+		WithCache(summaryRepo).
+		WithSettings(settingsRepo)
+	maintenanceSvc := app.NewMaintenanceService(maintenanceRepo).
+		WithSummaries(summarySvc).
+		WithRetention(retentionRepo, envInt("WATER_RETENTION_DAYS", app.DefaultWaterRetentionDays)).
+		WithChangeRetention(envInt("CHANGE_RETENTION_DAYS", app.DefaultChangeRetentionDays))
+	if os.Getenv("MAINTENANCE_MODE") == "true" {
+		log.Println("Starting in maintenance mode: writes are refused until an admin turns it off")
+		maintenanceSvc.WithMaintenanceMode(os.Getenv("MAINTENANCE_MESSAGE"))
+	}
+	feedSvc := app.NewFeedService(weightRepo, waterRepo).WithSummaries(summarySvc)
+	importSvc := app.NewImportService(weightRepo, waterRepo).WithBatches(importRepo).WithMetrics(metricsSvc)
+	if cluster != nil {
+		authSvc.WithCluster(cluster)
+		importSvc.WithCluster(cluster)
+		maintenanceSvc.WithCluster(cluster)
+		syncSvc.WithCluster(cluster)
+	}
+	batchSvc := app.NewBatchService(batchRepo)
+	statsSvc := app.NewStatsService(weightRepo).WithTags(tagRepo)
+	tagSvc := app.NewTagService(tagRepo)
+	hydrationSvc := app.NewHydrationService(hydrationRepo).WithGoalHistory(goalHistoryRepo)
+	webhookSvc.WithHydration(hydrationSvc)
+	goalSvc := app.NewGoalService(goalHistoryRepo, weightGoalRepo)
+	settingsSvc := app.NewSettingsService(settingsRepo)
+	if v := os.Getenv("DEFAULT_LOCALE"); v != "" {
+		locale, err := app.ParseLocale(v)
+		if err != nil {
+			log.Fatalf("invalid DEFAULT_LOCALE %q: %v", v, err)
+		}
+		settingsSvc.WithDefaultLocale(locale)
+	}
+	configSvc := app.NewConfigService(settingsSvc, hydrationSvc, alertSvc)
+	archiveSvc := app.NewArchiveService(weightRepo, waterRepo).WithConfig(configSvc)
+	portabilitySvc := app.NewPortabilityService(accountSvc, authSvc, weightRepo, waterRepo, importSvc).WithConfig(configSvc)
+	if publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"); publicURL != "" {
+		archiveSvc.WithNotifications(alertSvc, publicURL+"/api/v1")
+	}
+	integrationSvc, err := withIntegrations(app.NewIntegrationService(oauthTokenRepo, weightRepo, waterRepo))
+	if err != nil {
+		log.Fatalf("invalid integration configuration: %v", err)
+	}
+	for _, p := range integrationSvc.Providers() {
+		log.Printf("Integration %s enabled", p)
+	}
+	if key := os.Getenv("OPENWEATHER_API_KEY"); key != "" {
+		log.Println("Weather-aware hydration goals enabled")
+		hydrationSvc.WithWeather(openweather.New(key))
+	}
 
-	srv := adapthttp.New(weightSvc, waterSvc, chartsSvc, authSvc, webDir)
+	srv := adapthttp.New(weightSvc, waterSvc, chartsSvc, authSvc, webDir).
+		WithNutrition(nutritionSvc).
+		WithProfiles(profileSvc).
+		WithShares(shareSvc).
+		WithTokens(tokenSvc).
+		WithAccounts(accountSvc).
+		WithFeeds(feedSvc).
+		WithImports(importSvc).
+		WithArchives(archiveSvc).
+		WithPortability(portabilitySvc).
+		WithIntegrations(integrationSvc).
+		WithSync(syncSvc).
+		WithActivity(app.NewActivityService(activityRepo)).
+		WithBatch(batchSvc).
+		WithAlerts(alertSvc).
+		WithRules(ruleSvc).
+		WithPlans(planSvc).
+		WithWebhooks(webhookSvc).
+		WithHydration(hydrationSvc).
+		WithSettings(settingsSvc).
+		WithGoals(goalSvc).
+		WithConfig(configSvc).
+		WithTags(tagSvc).
+		WithJournal(journalSvc).
+		WithMood(moodSvc).
+		WithSteps(stepsSvc).
+		WithMedications(medicationSvc).
+		WithTemperature(temperatureSvc).
+		WithMetrics(metricsSvc).
+		WithSummaries(summarySvc).
+		WithStats(statsSvc).
+		WithSnapshots(app.NewSnapshotService(chartsSvc).WithHydration(hydrationSvc)).
+		WithMaintenance(maintenanceSvc)
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid REQUEST_TIMEOUT %q: %v", v, err)
+		}
+		srv.WithRequestTimeout(d)
+	}
+	if v := os.Getenv("QUERY_LIMITS"); v != "" {
+		limits, err := adapthttp.ParseQueryLimits(v)
+		if err != nil {
+			log.Fatalf("invalid QUERY_LIMITS: %v", err)
+		}
+		srv.WithQueryLimits(limits)
+	}
+	if v := os.Getenv("UNVERSIONED_API_SUNSET"); v != "" {
+		sunset, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			log.Fatalf("invalid UNVERSIONED_API_SUNSET %q: must be YYYY-MM-DD", v)
+		}
+		srv.WithSunset(sunset)
+	}
+	if v := os.Getenv("HEALTH_ACCESS"); v != "" {
+		access, err := adapthttp.ParseHealthAccess(v, os.Getenv("HEALTH_TOKEN"))
+		if err != nil {
+			log.Fatalf("invalid HEALTH_ACCESS: %v", err)
+		}
+		srv.WithHealthAccess(access)
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		proxies, err := adapthttp.ParseTrustedProxies(v)
+		if err != nil {
+			log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+		}
+		srv.WithTrustedProxies(proxies)
+	}
+	if v := os.Getenv("EMBED_SECRET"); v != "" {
+		srv.WithEmbeds(app.NewEmbedService([]byte(v)).WithRevocations(revocationRepo))
+	}
+	if v := os.Getenv("FRAME_ANCESTORS"); v != "" {
+		origins, err := adapthttp.ParseFrameAncestors(v)
+		if err != nil {
+			log.Fatalf("invalid FRAME_ANCESTORS: %v", err)
+		}
+		srv.WithFrameAncestors(origins)
+	}
+	if v := os.Getenv("SPA_PAGES"); v != "" {
+		pages, err := adapthttp.ParsePages(v)
+		if err != nil {
+			log.Fatalf("invalid SPA_PAGES: %v", err)
+		}
+		srv.WithPages(pages)
+	}
+	if os.Getenv("USAGE_STATS") == "true" {
+		log.Println("Anonymized usage statistics enabled for admins")
+		srv.WithUsage(app.NewUsageService(usageRepo))
+	}
+	if guest := os.Getenv("GUEST_MODE_USER"); guest != "" {
+		log.Printf("Guest mode enabled: unauthenticated visitors get read-only access as %q", guest)
+		srv.WithGuestUser(guest)
+	}
 	h := srv.Handler()
 
 	log.Printf("listening on %s", addr)
@@ -80,9 +464,192 @@ func main() {
 	}
 }
 
+// runCommand dispatches a CLI subcommand and returns the process exit code.
+func runCommand(name string, args []string) int {
+	switch name {
+	case "healthcheck":
+		return runHealthcheck(args)
+	case "db":
+		return runDB(args)
+	case "seed":
+		return runSeed(args)
+	case "alerts":
+		return runAlerts(args)
+	case "summaries":
+		return runSummaries(args)
+	case "integrations":
+		return runIntegrations(args)
+	case "users":
+		return runUsers(args)
+	default:
+		log.Printf("unknown command %q", name)
+		return 2
+	}
+}
+
+// allowPrivateWebhooks reports whether WEBHOOK_ALLOW_PRIVATE lets webhooks
+// and webhook notifications reach loopback and private addresses.
+func allowPrivateWebhooks() bool {
+	return os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+}
+
+// newNotifier returns the webhook notifier for alerts, rules and plans.
+func newNotifier() *webhook.Notifier {
+	n := webhook.New()
+	if allowPrivateWebhooks() {
+		n.AllowPrivateNetworks()
+	}
+	return n
+}
+
+// connectMQTT connects to MQTT_BROKER_URL, returning nil when it is unset.
+func connectMQTT() (*mqtt.Publisher, error) {
+	broker := os.Getenv("MQTT_BROKER_URL")
+	if broker == "" {
+		return nil, nil
+	}
+	return mqtt.Connect(mqtt.Config{
+		BrokerURL:   broker,
+		Username:    os.Getenv("MQTT_USERNAME"),
+		Password:    os.Getenv("MQTT_PASSWORD"),
+		ClientID:    env("MQTT_CLIENT_ID", "vitals"),
+		TopicPrefix: env("MQTT_TOPIC_PREFIX", "vitals"),
+	})
+}
+
+// connectSMTP configures the mailer for SMTP_ADDR, returning nil when it is
+// unset.
+func connectSMTP() (*smtp.Mailer, error) {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	return smtp.New(smtp.Config{
+		Addr:     addr,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	})
+}
+
+// outboxInterval is how often the server delivers queued notifications and
+// webhook events.
+const outboxInterval = 15 * time.Second
+
+// Session stores selectable with SESSION_STORE.
+const (
+	sessionStoreMemory   = "memory"
+	sessionStorePostgres = "postgres"
+)
+
+// openSessionStore returns the session repository of the given kind,
+// independent of where the rest of the data is kept. pg is the primary
+// Postgres database, or nil when data is kept in memory.
+func openSessionStore(kind string, pg *postgres.DB) (domain.SessionRepository, error) {
+	switch kind {
+	case sessionStoreMemory:
+		return memory.New().NewSessionRepo(), nil
+	case sessionStorePostgres:
+		// Sessions reference the users table, so they can only live in
+		// the database that holds the accounts.
+		if pg == nil {
+			return nil, errors.New("postgres sessions need POSTGRES_URL, whose users they belong to")
+		}
+		return postgres.NewSessionRepo(pg), nil
+	}
+	return nil, fmt.Errorf("unknown SESSION_STORE %q: must be %s or %s", kind, sessionStoreMemory, sessionStorePostgres)
+}
+
+// openPostgres connects to connStr and enables column encryption when
+// ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE is set. Pending migrations are
+// applied unless POSTGRES_AUTO_MIGRATE is false, in which case a schema that
+// is behind is an error. POSTGRES_RLS switches the row-level security mode
+// on or off; when unset the database keeps its current mode.
+func openPostgres(connStr string) (*postgres.DB, error) {
+	keys, err := fieldcrypt.Load()
+	if err != nil {
+		return nil, fmt.Errorf("encryption keys: %w", err)
+	}
+	autoMigrate := true
+	if v := os.Getenv("POSTGRES_AUTO_MIGRATE"); v != "" {
+		if autoMigrate, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid POSTGRES_AUTO_MIGRATE %q: %w", v, err)
+		}
+	}
+	var db *postgres.DB
+	if autoMigrate {
+		db, err = postgres.Open(connStr)
+	} else {
+		db, err = connectMigrated(connStr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if keys != nil {
+		db.WithCipher(keys)
+	}
+	if v := os.Getenv("POSTGRES_RLS"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("invalid POSTGRES_RLS %q: %w", v, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.SetRowLevelSecurity(ctx, enabled); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// connectMigrated connects without migrating and fails if migrations are
+// pending, so a deploy that migrates separately never serves an old schema.
+func connectMigrated(connStr string) (*postgres.DB, error) {
+	db, err := postgres.Connect(connStr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	n, err := db.PendingMigrations(ctx)
+	if err == nil && n > 0 {
+		err = fmt.Errorf("%d migration(s) pending; run `vitals db migrate`", n)
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// applyPostgresEnv maps custom env vars to lib/pq standard vars if provided.
+func applyPostgresEnv() {
+	if v := os.Getenv("POSTGRES_USER"); v != "" {
+		_ = os.Setenv("PGUSER", v)
+	}
+	if v := os.Getenv("POSTGRES_PASSWORD"); v != "" {
+		_ = os.Setenv("PGPASSWORD", v)
+	}
+}
+
 func env(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+// envInt reads a non-negative integer setting, exiting if it is invalid.
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid %s %q", key, v)
+	}
+	return n
+}
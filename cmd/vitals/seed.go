@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// runSeed implements `vitals seed`, populating a Postgres database with a
+// demo account and several months of history.
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	opts := demoFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; use SEED_DEMO_DATA=true to seed the in-memory store")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	summary, err := app.NewDemoSeeder(db, db, db).Seed(context.Background(), *opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(summary)
+	return 0
+}
+
+func demoFlags(fs *flag.FlagSet) *app.DemoOptions {
+	opts := &app.DemoOptions{}
+	fs.StringVar(&opts.Username, "username", env("DEMO_USERNAME", "demo"), "demo account username")
+	fs.StringVar(&opts.Password, "password", env("DEMO_PASSWORD", "demo"), "demo account password")
+	fs.IntVar(&opts.Days, "days", 120, "days of history to generate")
+	fs.Uint64Var(&opts.Seed, "seed", 1, "random seed for reproducible data")
+	return opts
+}
+
+// seedDemoData seeds the demo account at server startup when
+// SEED_DEMO_DATA=true. An already-seeded account is left untouched.
+func seedDemoData(users domain.UserRepository, weight domain.WeightRepository, water domain.WaterRepository) {
+	opts := app.DemoOptions{
+		Username: env("DEMO_USERNAME", "demo"),
+		Password: env("DEMO_PASSWORD", "demo"),
+		Seed:     1,
+	}
+	summary, err := app.NewDemoSeeder(users, weight, water).Seed(context.Background(), opts)
+	if errors.Is(err, app.ErrDemoUserExists) {
+		log.Printf("demo user %q already exists; skipping seed", opts.Username)
+		return
+	}
+	if err != nil {
+		log.Printf("seed demo data: %v", err)
+		return
+	}
+	log.Printf("seeded demo user %q: %d weight and %d water events", opts.Username, summary.WeightEvents, summary.WaterEvents)
+}
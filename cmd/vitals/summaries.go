@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"vitals/internal/app"
+)
+
+// runSummaries dispatches `vitals summaries <subcommand>`.
+func runSummaries(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals summaries refresh")
+		return 2
+	}
+	switch args[0] {
+	case "refresh":
+		return runSummariesRefresh(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown summaries command %q\n", args[0])
+		return 2
+	}
+}
+
+// runSummariesRefresh precomputes recent weekly summaries for every user
+// with new data; schedule it nightly so reports read cached rows.
+func runSummariesRefresh(args []string) int {
+	fs := flag.NewFlagSet("summaries refresh", flag.ContinueOnError)
+	weeks := fs.Int("weeks", 4, "completed weeks to recompute (up to 52), covering late and backdated entries")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum run time")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; the in-memory store computes summaries on demand")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	unlock, ok, err := db.TryLock(context.Background(), "vitals summaries refresh")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lock: %v\n", err)
+		return 1
+	}
+	if !ok {
+		fmt.Println("another instance is running summaries refresh; skipping")
+		return 0
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	svc := app.NewSummaryService(db, db).WithGoals(db).WithGoalHistory(db).WithCache(db)
+	users, err := svc.Refresh(ctx, *weeks, time.Now())
+	fmt.Printf("refreshed weekly summaries for %d user(s)\n", users)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "summaries: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"vitals/internal/adapter/postgres"
+	"vitals/internal/app"
+)
+
+// runUsers dispatches `vitals users <subcommand>`.
+func runUsers(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals users recover <username>")
+		return 2
+	}
+	switch args[0] {
+	case "recover":
+		return runUsersRecover(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown users command %q\n", args[0])
+		return 2
+	}
+}
+
+// runUsersRecover prints a one-time code with which a user without a
+// password can set one at /login. It is the way back in when single
+// sign-on is gone and no admin can sign in either.
+func runUsersRecover(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: vitals users recover <username>")
+		return 2
+	}
+
+	connStr := os.Getenv("POSTGRES_URL")
+	if connStr == "" {
+		fmt.Fprintln(os.Stderr, "POSTGRES_URL is not set; the in-memory store has no users to recover")
+		return 2
+	}
+	applyPostgresEnv()
+
+	db, err := openPostgres(connStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	svc := app.NewAuthService(db, postgres.NewSessionRepo(db)).WithRecovery(db)
+	code, expiresAt, err := svc.IssueRecoveryCode(ctx, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "recover: %v\n", err)
+		return 1
+	}
+	fmt.Printf("recovery code for %s: %s\n", args[0], code)
+	fmt.Printf("redeem it at /login before %s to set a password\n", expiresAt.Format(time.RFC3339))
+	return 0
+}
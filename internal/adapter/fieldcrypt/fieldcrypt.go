@@ -0,0 +1,173 @@
+// Package fieldcrypt encrypts individual column values with AES-256-GCM so
+// sensitive data (profile names, notification targets, notes) is unreadable
+// in database dumps and backups.
+//
+// Ciphertexts are self-describing strings of the form
+// "enc:v1:<keyID>:<base64(nonce|sealed)>". A Keyring holds one primary key,
+// used for new writes, plus any number of older keys kept for decryption, so
+// keys can be rotated without downtime: add a new primary, re-encrypt, then
+// drop the old key. Values without the prefix are treated as plaintext,
+// which lets encryption be switched on for an existing database.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const prefix = "enc:v1:"
+
+// KeySize is the required key length in bytes (AES-256).
+const KeySize = 32
+
+// ErrUnknownKey is returned when a value was sealed with a key that is not
+// in the keyring.
+var ErrUnknownKey = errors.New("fieldcrypt: unknown key")
+
+// Keyring encrypts with its primary key and decrypts with any of its keys.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// Key is a named encryption key.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// New returns a Keyring whose first key is the primary.
+func New(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("fieldcrypt: no keys")
+	}
+	k := &Keyring{primary: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("fieldcrypt: invalid key id %q", key.ID)
+		}
+		if len(key.Secret) != KeySize {
+			return nil, fmt.Errorf("fieldcrypt: key %q must be %d bytes, got %d", key.ID, KeySize, len(key.Secret))
+		}
+		if _, dup := k.aeads[key.ID]; dup {
+			return nil, fmt.Errorf("fieldcrypt: duplicate key id %q", key.ID)
+		}
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.ID] = aead
+	}
+	return k, nil
+}
+
+// Parse builds a Keyring from a comma- or newline-separated list of
+// "<id>:<base64 key>" entries, primary first.
+func Parse(spec string) (*Keyring, error) {
+	var keys []Key
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, b64, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("fieldcrypt: key entry must be <id>:<base64 key>")
+		}
+		secret, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q: %w", id, err)
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return New(keys...)
+}
+
+// Load reads the keyring from the ENCRYPTION_KEYS variable, or from the file
+// named by ENCRYPTION_KEYS_FILE (e.g. a mounted Kubernetes or KMS-managed
+// secret). It returns nil when neither is set.
+func Load() (*Keyring, error) {
+	spec := os.Getenv("ENCRYPTION_KEYS")
+	if path := os.Getenv("ENCRYPTION_KEYS_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(b)
+	}
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	return Parse(spec)
+}
+
+// PrimaryKeyID returns the ID of the key used for new encryptions.
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Encrypt seals plaintext with the primary key. The empty string is returned
+// unchanged so optional columns stay empty.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.primary))
+	return prefix + k.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with any key in the ring.
+// Values that are not encrypted are returned as is.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, payload, ok := split(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("fieldcrypt: malformed ciphertext")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("fieldcrypt: decrypt with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// NeedsRotation reports whether value is non-empty and not yet sealed with
+// the primary key, i.e. whether it is plaintext or uses an older key.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	id, _, ok := split(value)
+	return !ok || id != k.primary
+}
+
+// split extracts the key ID and payload from an encrypted value.
+func split(value string) (id, payload string, ok bool) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
@@ -0,0 +1,103 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func key(id string, b byte) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte{b}, KeySize)}
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k, err := New(key("k1", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.Encrypt("Sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "Sam") {
+		t.Fatalf("unexpected ciphertext %q", sealed)
+	}
+	again, _ := k.Encrypt("Sam")
+	if again == sealed {
+		t.Error("expected a fresh nonce per encryption")
+	}
+	plain, err := k.Decrypt(sealed)
+	if err != nil || plain != "Sam" {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+
+	if got, _ := k.Encrypt(""); got != "" {
+		t.Errorf("Encrypt(\"\") = %q, want empty", got)
+	}
+	if got, err := k.Decrypt("legacy plaintext"); err != nil || got != "legacy plaintext" {
+		t.Errorf("plaintext passthrough = %q, %v", got, err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, _ := New(key("k1", 1))
+	sealed, _ := old.Encrypt("https://ntfy.sh/secret-topic")
+
+	rotated, err := New(key("k2", 2), key("k1", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated.NeedsRotation(sealed) || !rotated.NeedsRotation("plain") || rotated.NeedsRotation("") {
+		t.Error("NeedsRotation should flag old-key and plaintext values only")
+	}
+	plain, err := rotated.Decrypt(sealed)
+	if err != nil || plain != "https://ntfy.sh/secret-topic" {
+		t.Fatalf("Decrypt with old key = %q, %v", plain, err)
+	}
+	resealed, _ := rotated.Encrypt(plain)
+	if rotated.NeedsRotation(resealed) {
+		t.Error("value sealed with the primary key should not need rotation")
+	}
+
+	if _, err := old.Decrypt(resealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with missing key: err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestKeyringTamper(t *testing.T) {
+	k, _ := New(key("k1", 1))
+	sealed, _ := k.Encrypt("Sam")
+	other, _ := New(key("k1", 9))
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Error("expected decrypting with a different secret to fail")
+	}
+	if _, err := k.Decrypt("enc:v1:k1:!!!"); err == nil {
+		t.Error("expected malformed ciphertext to fail")
+	}
+}
+
+func TestParse(t *testing.T) {
+	b64 := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, KeySize)) }
+
+	k, err := Parse("# rotated 2026-10\nk2:" + b64(2) + "\nk1:" + b64(1) + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.PrimaryKeyID() != "k2" {
+		t.Errorf("primary = %q, want k2", k.PrimaryKeyID())
+	}
+
+	for _, spec := range []string{
+		"",
+		"k1",
+		"k1:not-base64!",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:" + b64(1) + ",k1:" + b64(2),
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package adapthttp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// checkToday sets ETag and Last-Modified on a today endpoint's response and
+// answers 304 Not Modified when the request's validators still match.
+//
+// The validators derive from the subject's latest change to entity in the
+// change log, so they move on every write, undo and backdated offline entry,
+// and from the local day, so they also move at midnight. extra covers parts
+// of the response that the change log does not track (e.g. a weather-
+// dependent goal); since those have no modification time, Last-Modified is
+// omitted when extra is set. Without the sync service, no validators are
+// sent.
+func (s *Server) checkToday(w http.ResponseWriter, r *http.Request, entity, today, extra string) (bool, error) {
+	if s.sync == nil {
+		return false, nil
+	}
+	c, err := s.sync.LatestChange(r.Context(), subjectFromContext(r), entity)
+	if err != nil {
+		return false, err
+	}
+
+	dayStart, _ := time.ParseInLocation("2006-01-02", today, time.Local)
+	lastModified := dayStart
+	var seq int64
+	if c != nil {
+		seq = c.Seq
+		if c.ChangedAt.After(lastModified) {
+			lastModified = c.ChangedAt
+		}
+	}
+
+	tag := fmt.Sprintf("%s-%s-%d", entity, today, seq)
+	if extra != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(extra))
+		tag = fmt.Sprintf("%s-%08x", tag, h.Sum32())
+	}
+	etag := `W/"` + tag + `"`
+
+	// Let clients store the response but revalidate it on every use.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", etag)
+	if extra == "" {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false, nil
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && extra == "" {
+		t, err := http.ParseTime(ims)
+		if err != nil || lastModified.Truncate(time.Second).After(t) {
+			return false, nil
+		}
+	} else {
+		return false, nil
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true, nil
+}
+
+// etagMatches applies the weak comparison of an If-None-Match header.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
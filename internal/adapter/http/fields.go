@@ -0,0 +1,129 @@
+package adapthttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"vitals/internal/domain"
+)
+
+// writeList writes items under "items" alongside extra, applying the list
+// shaping options:
+//
+//   - ?fields=day,weight keeps only the named JSON fields of each item.
+//   - ?format=compact sends each item as an array of values and lists the
+//     column names once under "fields".
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, extra map[string]any) {
+	resp := maps.Clone(extra)
+	if resp == nil {
+		resp = map[string]any{}
+	}
+	resp["items"] = items
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "compact" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be \"compact\""))
+		return
+	}
+	if q.Get("fields") == "" && format == "" {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	all := jsonFields(reflect.TypeFor[T]())
+	fields := all
+	if v := q.Get("fields"); v != "" {
+		fields = nil
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if !slices.Contains(all, f) {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("unknown field %q; available: %s", f, strings.Join(all, ",")))
+				return
+			}
+			if !slices.Contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	objects := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err := json.Unmarshal(b, &objects[i]); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if format == "compact" {
+		rows := make([][]json.RawMessage, len(objects))
+		for i, obj := range objects {
+			row := make([]json.RawMessage, len(fields))
+			for j, f := range fields {
+				if row[j] = obj[f]; row[j] == nil {
+					row[j] = json.RawMessage("null")
+				}
+			}
+			rows[i] = row
+		}
+		resp["fields"] = fields
+		resp["items"] = rows
+	} else {
+		for _, obj := range objects {
+			for k := range obj {
+				if !slices.Contains(fields, k) {
+					delete(obj, k)
+				}
+			}
+		}
+		resp["items"] = objects
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// summaryFields returns sum as extra fields for writeList: "total",
+// "hasMore", "firstAt" and "lastAt". It returns nil when sum is.
+func summaryFields(sum *domain.ListSummary) map[string]any {
+	if sum == nil {
+		return nil
+	}
+	extra := map[string]any{"total": sum.Total, "hasMore": sum.HasMore}
+	if sum.FirstAt != nil {
+		extra["firstAt"], extra["lastAt"] = sum.FirstAt, sum.LastAt
+	}
+	return extra
+}
+
+// jsonFields returns the JSON names of a struct type's exported fields, in
+// declaration order.
+func jsonFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
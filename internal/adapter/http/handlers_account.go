@@ -0,0 +1,113 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+
+	"vitals/internal/app"
+)
+
+// handleAccount returns the signed-in user's username, verified email, any
+// email awaiting verification and the hints for formatting their numbers
+// and dates.
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userID := userFromContext(r).ID
+	acct, err := s.accounts.Get(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if s.settings != nil {
+		format, err := s.settings.Format(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		acct.Format = &format
+	}
+	writeJSON(w, http.StatusOK, acct)
+}
+
+// handleAccountUsername renames the signed-in user: PUT { "username" }.
+// The session is kept.
+func (s *Server) handleAccountUsername(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	acct, err := s.accounts.ChangeUsername(r.Context(), userFromContext(r).ID, body.Username)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, acct)
+}
+
+// handleAccountEmail starts an email change: PUT { "email" } mails a
+// verification link to the new address and answers 202 with the account,
+// whose email only changes once the link is followed.
+func (s *Server) handleAccountEmail(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	acct, err := s.accounts.RequestEmailChange(r.Context(), userFromContext(r).ID, body.Email)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, acct)
+}
+
+// handleVerifyEmail is the link mailed by an email change. It needs no
+// session, since it may be opened on another device.
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	_, err := s.accounts.VerifyEmail(r.Context(), r.URL.Query().Get("token"))
+	switch {
+	case errors.Is(err, app.ErrVerificationInvalid), errors.Is(err, app.ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/?emailVerified=1", http.StatusFound)
+}
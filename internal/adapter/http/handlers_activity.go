@@ -0,0 +1,27 @@
+package adapthttp
+
+import "net/http"
+
+// handleActivity returns a page of the user's activity timeline, newest
+// first, after ?cursor= from the previous page.
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	if s.activity == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "activity", "limit", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := s.activity.List(r.Context(), subjectFromContext(r), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
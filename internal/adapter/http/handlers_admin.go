@@ -0,0 +1,110 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"vitals/internal/app"
+)
+
+// handleAdminMaintenance runs a maintenance action now (POST { "action",
+// options }), for operators who cannot wait for the scheduled jobs.
+func (s *Server) handleAdminMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.maintenance == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req app.MaintenanceRequest
+	if err := parseJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.maintenance.Run(r.Context(), req, time.Now())
+	if errors.Is(err, app.ErrUnknownMaintenanceAction) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleAdminMaintenanceMode reads (GET) or sets (PUT { "enabled",
+// "message" }) maintenance mode, which pauses writes on every instance.
+func (s *Server) handleAdminMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	if s.maintenance == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.maintenance.Mode())
+
+	case http.MethodPut:
+		var body struct {
+			Enabled bool   `json:"enabled"`
+			Message string `json:"message"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		mode, err := s.maintenance.SetMode(r.Context(), body.Enabled, strings.TrimSpace(body.Message))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, mode)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminStats reports anonymized usage across the instance for the
+// last ?weeks= (default 12) weeks: active users and entries per day, never
+// who is active or what they logged.
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	if s.usage == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	weeks, err := s.intQuery(r, "admin/stats", "weeks", 12)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := s.usage.Weekly(r.Context(), weeks)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleAdminDeprecatedUsage reports how often each route was called
+// through the deprecated unversioned /api paths since this instance
+// started, so operators can tell when removing them is safe.
+func (s *Server) handleAdminDeprecatedUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	resp := map[string]any{"since": s.unversioned.since, "items": s.unversioned.snapshot()}
+	if !s.sunset.IsZero() {
+		resp["sunset"] = s.sunset
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
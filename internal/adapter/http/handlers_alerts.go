@@ -0,0 +1,146 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vitals/internal/domain"
+)
+
+// handleWeightChangeAlert reads or replaces the rapid weight-change alert rule.
+func (s *Server) handleWeightChangeAlert(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		rule, err := s.alerts.GetRule(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule, "channels": s.alerts.Channels()})
+
+	case http.MethodPut:
+		var body struct {
+			MaxWeeklyChangePct float64 `json:"maxWeeklyChangePct"`
+			Channel            string  `json:"channel"`
+			Target             string  `json:"target"`
+			Enabled            bool    `json:"enabled"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rule, err := s.alerts.SaveRule(r.Context(), domain.AlertRule{
+			UserID:             subject,
+			MaxWeeklyChangePct: body.MaxWeeklyChangePct,
+			Channel:            body.Channel,
+			Target:             body.Target,
+			Enabled:            body.Enabled,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ruleBody is the request body for creating or replacing a rule.
+type ruleBody struct {
+	Name      string               `json:"name"`
+	Condition domain.RuleCondition `json:"condition"`
+	Channel   string               `json:"channel"`
+	Target    string               `json:"target"`
+	Enabled   bool                 `json:"enabled"`
+}
+
+func (b ruleBody) rule(userID, id int64) domain.Rule {
+	return domain.Rule{
+		ID: id, UserID: userID, Name: b.Name, Condition: b.Condition,
+		Channel: b.Channel, Target: b.Target, Enabled: b.Enabled,
+	}
+}
+
+// handleRules lists or creates user-defined alert rules.
+func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
+	if s.rules == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.rules.List(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items, "channels": s.rules.Channels()})
+
+	case http.MethodPost:
+		var body ruleBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rule, err := s.rules.Create(r.Context(), body.rule(subject, 0))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"rule": rule})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRule replaces or deletes the rule addressed by the {id} path
+// segment.
+func (s *Server) handleRule(w http.ResponseWriter, r *http.Request) {
+	if s.rules == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body ruleBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rule, err := s.rules.Update(r.Context(), body.rule(subject, id))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+
+	case http.MethodDelete:
+		if err := s.rules.Delete(r.Context(), subject, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
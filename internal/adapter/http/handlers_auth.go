@@ -5,9 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"vitals/internal/app"
+	"vitals/internal/domain"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 )
@@ -21,6 +24,9 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		// Challenge is the solution to the challenge of the previous
+		// failed response, once one is required.
+		Challenge string `json:"challenge"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -28,9 +34,9 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := s.authSvc.Login(r.Context(), req.Username, req.Password, r.UserAgent(), r.RemoteAddr)
-	if err == app.ErrInvalidCredentials {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	token, err := s.authSvc.LoginWithChallenge(r.Context(), req.Username, req.Password, req.Challenge, r.UserAgent(), s.clientAddr(r))
+	if errors.Is(err, app.ErrInvalidCredentials) || errors.Is(err, app.ErrChallengeRequired) || errors.Is(err, app.ErrLoginLocked) {
+		s.writeLoginFailure(w, r, err)
 		return
 	}
 	if err != nil {
@@ -51,6 +57,35 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// writeLoginFailure responds to a failed sign-in with the reason, how many
+// attempts the client's address has left and, once it must solve one, a
+// challenge for its next attempt. A locked out address gets 429 and how many
+// seconds to wait.
+func (s *Server) writeLoginFailure(w http.ResponseWriter, r *http.Request, loginErr error) {
+	st, err := s.authSvc.LoginStatus(r.Context(), s.clientAddr(r))
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if errors.Is(loginErr, app.ErrLoginLocked) || st.RetryAfter > 0 {
+		retryAfter := int(st.RetryAfter.Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{
+			"error":      app.ErrLoginLocked.Error(),
+			"retryAfter": retryAfter,
+		})
+		return
+	}
+	msg := "invalid credentials"
+	if errors.Is(loginErr, app.ErrChallengeRequired) {
+		msg = loginErr.Error()
+	}
+	writeJSON(w, http.StatusUnauthorized, struct {
+		Error string `json:"error"`
+		*app.LoginStatus
+	}{msg, st})
+}
+
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -102,9 +137,20 @@ func (s *Server) handleSetupUser(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{
 		"sso_enabled": s.oidcConfig.Enabled,
+		"guest_mode":  s.guestUser != "",
+		"format":      s.defaultFormat(),
 	})
 }
 
+// defaultFormat returns the formatting hints of the instance's default
+// locale, for pages shown before sign-in.
+func (s *Server) defaultFormat() app.FormatHints {
+	if s.settings == nil {
+		return app.LocaleFormat(app.DefaultLocale)
+	}
+	return s.settings.DefaultFormat()
+}
+
 func (s *Server) handleSSOLogin(w http.ResponseWriter, r *http.Request) {
 	if !s.oidcConfig.Enabled {
 		http.Error(w, "sso disabled", http.StatusNotFound)
@@ -157,34 +203,188 @@ func (s *Server) handleSSOCallback(w http.ResponseWriter, r *http.Request) {
 
 	var claims struct {
 		Email string `json:"email"`
-		Sub   string `json:"sub"`
+		// EmailVerified is a boolean, or the string "true" from some
+		// providers.
+		EmailVerified any    `json:"email_verified"`
+		Sub           string `json:"sub"`
 	}
 	if err = idToken.Claims(&claims); err != nil {
 		http.Error(w, "failed to parse claims", http.StatusInternalServerError)
 		return
 	}
 
-	username := claims.Email
-	if username == "" {
-		username = claims.Sub
+	identity := domain.LinkedIdentity{
+		Issuer:        idToken.Issuer,
+		Subject:       claims.Sub,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+	}
+	sessionToken, err := s.authSvc.LoginWithIdentity(r.Context(), identity, r.UserAgent(), s.clientAddr(r))
+	if errors.Is(err, app.ErrLinkRequired) {
+		// sessionToken is the pending link token; the login page offers to
+		// link an existing account or create a new one.
+		http.SetCookie(w, &http.Cookie{
+			Name:     ssoLinkCookie,
+			Value:    sessionToken,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   600,
+		})
+		http.Redirect(w, r, "/login?link=1", http.StatusFound)
+		return
 	}
-
-	sessionToken, err := s.authSvc.LoginWithUser(r.Context(), username, r.UserAgent(), r.RemoteAddr)
 	if err != nil {
 		http.Error(w, "login failed", http.StatusInternalServerError)
 		return
 	}
 
+	setSessionCookie(w, sessionToken)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// ssoLinkCookie holds the token of an SSO identity awaiting a linking
+// decision.
+const ssoLinkCookie = "sso_link"
+
+// handleSSOLink shows (GET) or resolves (POST) a pending SSO link. POST
+// takes the existing account's { "username", "password" }, or
+// { "createAccount": true } to create a new account for the identity.
+func (s *Server) handleSSOLink(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(ssoLinkCookie)
+	if err != nil {
+		http.Error(w, app.ErrLinkExpired.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		identity, err := s.authSvc.PendingLink(cookie.Value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"email": identity.Email, "subject": identity.Subject})
+
+	case http.MethodPost:
+		var req struct {
+			Username      string `json:"username"`
+			Password      string `json:"password"`
+			CreateAccount bool   `json:"createAccount"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		var token string
+		if req.CreateAccount {
+			token, err = s.authSvc.CreateLinkedAccount(r.Context(), cookie.Value, r.UserAgent(), s.clientAddr(r))
+		} else {
+			token, err = s.authSvc.CompleteLink(r.Context(), cookie.Value, req.Username, req.Password, r.UserAgent(), s.clientAddr(r))
+		}
+		switch {
+		case errors.Is(err, app.ErrInvalidCredentials):
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		case errors.Is(err, app.ErrLinkExpired):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, app.ErrUsernameTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: ssoLinkCookie, MaxAge: -1, Path: "/"})
+		setSessionCookie(w, token)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleChangePassword changes the signed-in user's password: POST {
+// "currentPassword", "password", "challenge" }. It signs the user out
+// everywhere else and answers with a new session cookie. Wrong current
+// passwords fail like wrong passwords on /auth/login.
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		Password        string `json:"password"`
+		Challenge       string `json:"challenge"`
+	}
+	if err := parseJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	token, err := s.authSvc.ChangePassword(r.Context(), userFromContext(r).ID, req.CurrentPassword, req.Password, req.Challenge, r.UserAgent(), s.clientAddr(r))
+	switch {
+	case errors.Is(err, app.ErrInvalidCredentials), errors.Is(err, app.ErrChallengeRequired), errors.Is(err, app.ErrLoginLocked):
+		s.writeLoginFailure(w, r, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	setSessionCookie(w, token)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleDeleteAccount erases the signed-in user with all their data:
+// DELETE { "password" }, confirmed like a login, and clears the session
+// cookie.
+func (s *Server) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Password  string `json:"password"`
+		Challenge string `json:"challenge"`
+	}
+	if err := parseJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.authSvc.DeleteAccount(r.Context(), userFromContext(r).ID, req.Password, req.Challenge, s.clientAddr(r))
+	switch {
+	case errors.Is(err, app.ErrInvalidCredentials), errors.Is(err, app.ErrChallengeRequired), errors.Is(err, app.ErrLoginLocked):
+		s.writeLoginFailure(w, r, err)
+		return
+	case errors.Is(err, app.ErrAdminDelete):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: "", Path: "/", HttpOnly: true, MaxAge: -1})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func setSessionCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session",
-		Value:    sessionToken,
+		Value:    token,
 		Path:     "/",
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
 		MaxAge:   86400,
 	})
-
-	http.Redirect(w, r, "/", http.StatusFound)
 }
 
 func generateState() string {
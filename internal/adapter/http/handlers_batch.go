@@ -0,0 +1,33 @@
+package adapthttp
+
+import (
+	"net/http"
+
+	"vitals/internal/domain"
+)
+
+// handleBatch applies a mixed list of weight and water writes atomically.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if s.batch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Ops []domain.BatchOp `json:"ops"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	results, err := s.batch.Apply(r.Context(), subjectFromContext(r), body.Ops)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
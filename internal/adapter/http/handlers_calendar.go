@@ -0,0 +1,44 @@
+package adapthttp
+
+import (
+	"net/http"
+
+	"vitals/internal/domain"
+)
+
+// handleCalendarMonth returns a month grid of weights, water totals and goal
+// status for /api/calendar/{month}.
+func (s *Server) handleCalendarMonth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	subject := subjectFromContext(r)
+	month := r.PathValue("month")
+	unit := r.URL.Query().Get("unit")
+	if unit == "" {
+		unit = "lb"
+	}
+
+	goal := domain.DefaultHydrationGoalLiters
+	if s.hydration != nil {
+		hs, err := s.hydration.Settings(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		goal = hs.BaseGoalLiters
+	}
+
+	days, err := s.charts.Month(r.Context(), subject, month, unit, goal, r.URL.Query().Get("weight"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"month":      month,
+		"unit":       unit,
+		"goalLiters": goal,
+		"days":       days,
+	})
+}
@@ -2,7 +2,10 @@ package adapthttp
 
 import (
 	"net/http"
+	"strconv"
 	"time"
+
+	"vitals/internal/app"
 )
 
 func (s *Server) handleChartsDaily(w http.ResponseWriter, r *http.Request) {
@@ -10,24 +13,109 @@ func (s *Server) handleChartsDaily(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-
-	user := userFromContext(r)
-	days := intQuery(r, "days", 90)
-	unit := r.URL.Query().Get("unit")
-	if unit == "" {
-		unit = "lb"
-	}
-
-	points, err := s.charts.GetDaily(r.Context(), user.ID, days, unit)
+	points, days, unit, err := s.dailyPoints(r, "charts/daily", 90, "lb")
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	today := localDayString(time.Now())
+	w.Header().Set("Vary", "Accept")
+	if wantsProtobuf(r) {
+		writeProto(w, http.StatusOK, dailyChartToProto(days, unit, today, points))
+		return
+	}
+	writeList(w, r, points, map[string]any{
 		"days":  days,
 		"unit":  unit,
-		"today": localDayString(time.Now()),
-		"items": points,
+		"today": today,
 	})
 }
+
+// handleChartsDailyCSV downloads the /charts/daily points as CSV, one row
+// per day.
+func (s *Server) handleChartsDailyCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	points, _, _, err := s.dailyPoints(r, "charts/daily", 90, "lb")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeCSV(w, "charts", chartHeader, chartRows(points))
+}
+
+// handleChartsDailyXLSX downloads the /charts/daily points as a one-sheet
+// Excel workbook laid out like the CSV.
+func (s *Server) handleChartsDailyXLSX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	points, _, _, err := s.dailyPoints(r, "charts/daily", 90, "lb")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeXLSX(w, "charts", chartHeader, chartRows(points))
+}
+
+// dailyPoints reads the ?days=, ?unit=, ?tag=, ?weight= and ?fill=
+// parameters of the daily chart endpoints and returns the points. days is
+// capped by the query limit of endpoint. Dashboards get no journal notes.
+func (s *Server) dailyPoints(r *http.Request, endpoint string, defaultDays int, defaultUnit string) ([]app.DayPoint, int, string, error) {
+	days, err := s.intQuery(r, endpoint, "days", defaultDays)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	filter, err := tagFilter(r)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	unit := r.URL.Query().Get("unit")
+	if unit == "" {
+		unit = defaultUnit
+	}
+
+	points, err := s.charts.GetDaily(r.Context(), subjectFromContext(r), days, unit, filter, r.URL.Query().Get("weight"), r.URL.Query().Get("fill"))
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if isDashboard(r) {
+		// Journal notes are personal; a wall display only gets the numbers.
+		for i := range points {
+			points[i].Note = ""
+		}
+	}
+	return points, days, unit, nil
+}
+
+// chartHeader names the columns of chartRows.
+var chartHeader = []string{"day", "waterLiters", "goalLiters", "goalMet", "weight", "weightUnit", "mood", "steps", "note"}
+
+// chartRows writes one row per point; empty cells mean nothing was recorded
+// that day.
+func chartRows(points []app.DayPoint) func(row func(...string) error) error {
+	return func(row func(...string) error) error {
+		for _, p := range points {
+			var weight, weightUnit, mood, steps string
+			if p.Weight != nil {
+				weight = strconv.FormatFloat(p.Weight.Value, 'f', -1, 64)
+				weightUnit = p.Weight.Unit
+			}
+			if p.Mood != nil {
+				mood = strconv.Itoa(*p.Mood)
+			}
+			if p.Steps != nil {
+				steps = strconv.Itoa(*p.Steps)
+			}
+			if err := row(p.Day, strconv.FormatFloat(p.WaterLiters, 'f', -1, 64), strconv.FormatFloat(p.GoalLiters, 'f', -1, 64),
+				strconv.FormatBool(p.GoalMet), weight, weightUnit, mood, steps, p.Note); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package adapthttp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// handleConfigExport downloads the user's configuration bundle.
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bundle, err := s.config.Export(r.Context(), subjectFromContext(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	filename := fmt.Sprintf("vitals-config-%s.json", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+// handleConfigImport applies an uploaded configuration bundle. With
+// ?dryRun=true it only validates the bundle.
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var bundle domain.ConfigBundle
+	if err := parseJSON(r, &bundle); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	dryRun, err := parseDryRun(r.URL.Query().Get("dryRun"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if dryRun {
+		if err := s.config.Validate(bundle); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"valid": true})
+		return
+	}
+	res, err := s.config.Import(r.Context(), subjectFromContext(r), bundle)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"imported": res})
+}
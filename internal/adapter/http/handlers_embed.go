@@ -0,0 +1,186 @@
+package adapthttp
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// handleEmbed signs a link to the caller's embeddable chart.
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	if s.embeds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Days          int    `json:"days"`
+		Unit          string `json:"unit"`
+		ExpiresInDays int    `json:"expiresInDays"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	user := userFromContext(r)
+	ttl := time.Duration(body.ExpiresInDays) * 24 * time.Hour
+	token, err := s.embeds.Sign(r.Context(), app.EmbedChart{UserID: user.ID, Days: body.Days, Unit: body.Unit}, ttl)
+	if errors.Is(err, app.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"token":     token,
+		"url":       "/embed/charts?t=" + url.QueryEscape(token),
+		"expiresAt": time.Now().Add(ttl).UTC(),
+	})
+}
+
+// handleEmbedCharts renders the chart an embed token grants as a
+// self-contained page, with no script and no further requests, for
+// dashboards to show in an iframe. It refreshes itself every five minutes.
+func (s *Server) handleEmbedCharts(w http.ResponseWriter, r *http.Request) {
+	if s.embeds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	grant, err := s.embeds.Verify(r.Context(), r.URL.Query().Get("t"))
+	if errors.Is(err, app.ErrInvalidEmbed) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "chart unavailable", http.StatusInternalServerError)
+		return
+	}
+	points, err := s.charts.GetDaily(r.Context(), grant.UserID, grant.Days, grant.Unit, domain.TagFilter{}, "", "")
+	if err != nil {
+		http.Error(w, "chart unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = embedChartPage.Execute(w, newEmbedChartView(points, grant.Unit))
+}
+
+// Dimensions of the embedded chart's SVG viewBox.
+const (
+	embedWidth  = 600
+	embedHeight = 200
+)
+
+type embedBar struct {
+	X, Y, Width, Height float64
+}
+
+type embedChartView struct {
+	Days   int
+	Latest string
+	Water  string
+	Weight string
+	Bars   []embedBar
+}
+
+// newEmbedChartView lays out points, oldest first: water as bars scaled to
+// the larger of the day's goal and the busiest day, and weight as a line
+// across its own range.
+func newEmbedChartView(points []app.DayPoint, unit string) embedChartView {
+	v := embedChartView{Days: len(points)}
+	if len(points) == 0 {
+		return v
+	}
+	step := float64(embedWidth) / float64(len(points))
+
+	maxLiters := 0.0
+	for _, p := range points {
+		maxLiters = max(maxLiters, p.WaterLiters, p.GoalLiters)
+	}
+	if maxLiters > 0 {
+		for i, p := range points {
+			h := p.WaterLiters / maxLiters * embedHeight
+			v.Bars = append(v.Bars, embedBar{X: float64(i)*step + step*0.15, Y: embedHeight - h, Width: step * 0.7, Height: h})
+		}
+	}
+
+	lo, hi := 0.0, 0.0
+	seen := false
+	for _, p := range points {
+		if p.Weight == nil {
+			continue
+		}
+		if !seen || p.Weight.Value < lo {
+			lo = p.Weight.Value
+		}
+		if !seen || p.Weight.Value > hi {
+			hi = p.Weight.Value
+		}
+		seen = true
+		v.Latest = fmt.Sprintf("%.1f %s", p.Weight.Value, unit)
+	}
+	if seen {
+		// Keep a margin so the line does not touch the edges, and a flat
+		// line in the middle.
+		pad := max((hi-lo)*0.1, 0.5)
+		lo, hi = lo-pad, hi+pad
+		var line []string
+		for i, p := range points {
+			if p.Weight == nil {
+				continue
+			}
+			x := float64(i)*step + step/2
+			y := (hi - p.Weight.Value) / (hi - lo) * embedHeight
+			line = append(line, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		v.Weight = strings.Join(line, " ")
+	}
+	v.Water = fmt.Sprintf("%.2f L", points[len(points)-1].WaterLiters)
+	return v
+}
+
+var embedChartPage = template.Must(template.New("embed").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="300">
+<title>Vitals</title>
+<style>
+html,body{margin:0;height:100%;background:transparent;color:#e8eefc;font:14px system-ui,sans-serif}
+@media (prefers-color-scheme: light){html,body{color:#0b1220}}
+body{display:flex;flex-direction:column;padding:8px;box-sizing:border-box}
+header{display:flex;gap:16px;opacity:.85}
+svg{flex:1;width:100%;min-height:0}
+.water{fill:#4f8cff;opacity:.45}
+.weight{fill:none;stroke:#ff9f43;stroke-width:2;vector-effect:non-scaling-stroke}
+</style>
+</head>
+<body>
+<header>
+{{if .Latest}}<span>Weight {{.Latest}}</span>{{end}}
+{{if .Water}}<span>Water today {{.Water}}</span>{{end}}
+<span>Last {{.Days}} days</span>
+</header>
+<svg viewBox="0 0 600 200" preserveAspectRatio="none" role="img" aria-label="Daily weight and water">
+{{range .Bars}}<rect class="water" x="{{printf "%.1f" .X}}" y="{{printf "%.1f" .Y}}" width="{{printf "%.1f" .Width}}" height="{{printf "%.1f" .Height}}"/>
+{{end}}{{if .Weight}}<polyline class="weight" points="{{.Weight}}"/>{{end}}
+</svg>
+</body>
+</html>
+`))
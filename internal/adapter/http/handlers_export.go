@@ -0,0 +1,306 @@
+package adapthttp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// lineTagEscaper escapes tag values per the InfluxDB line protocol.
+var lineTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// handleExportInflux emits one datapoint per day in InfluxDB line protocol,
+// suitable for a Telegraf http input or `influx write`. Days without a
+// weigh-in produce only the water point.
+func (s *Server) handleExportInflux(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	subject := subjectFromContext(r)
+	days, err := s.intQuery(r, "export/influx", "days", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := tagFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	unit := r.URL.Query().Get("unit")
+	if unit == "" {
+		unit = "kg"
+	}
+
+	points, err := s.charts.GetDaily(r.Context(), subject, days, unit, filter, r.URL.Query().Get("weight"), "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tags := "user_id=" + strconv.FormatInt(subject, 10)
+	if user := userFromContext(r); user.ID == subject && user.Username != "" {
+		tags += ",user=" + lineTagEscaper.Replace(user.Username)
+	}
+
+	var b strings.Builder
+	for _, p := range points {
+		day, err := time.ParseInLocation("2006-01-02", p.Day, time.Local)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		ts := day.UnixNano()
+		fmt.Fprintf(&b, "water,%s liters=%g %d\n", tags, p.WaterLiters, ts)
+		if p.Weight != nil {
+			fmt.Fprintf(&b, "weight,%s,unit=%s value=%g %d\n", tags, p.Weight.Unit, p.Weight.Value, ts)
+		}
+		if p.Mood != nil {
+			fmt.Fprintf(&b, "mood,%s score=%di %d\n", tags, *p.Mood, ts)
+		}
+		if p.Steps != nil {
+			fmt.Fprintf(&b, "steps,%s count=%di %d\n", tags, *p.Steps, ts)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// handleExportWeightCSV downloads the user's weight events of the ?from=
+// through ?to= days (default: all) as CSV, oldest first, in the unit each
+// was recorded in.
+func (s *Server) handleExportWeightCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	subject := subjectFromContext(r)
+	filter, err := exportFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeCSV(w, "weight", []string{"id", "createdAt", "day", "value", "unit"}, func(row func(...string) error) error {
+		return s.weight.Export(r.Context(), subject, filter, func(e domain.WeightEntry) error {
+			return row(strconv.FormatInt(e.ID, 10), e.CreatedAt.In(time.Local).Format(time.RFC3339), e.Day,
+				strconv.FormatFloat(e.Value, 'f', -1, 64), e.Unit)
+		})
+	})
+}
+
+// handleExportWaterCSV downloads the user's water events of the ?from=
+// through ?to= days (default: all) as CSV, oldest first.
+func (s *Server) handleExportWaterCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	subject := subjectFromContext(r)
+	filter, err := exportFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeCSV(w, "water", []string{"id", "createdAt", "deltaLiters"}, func(row func(...string) error) error {
+		return s.water.Export(r.Context(), subject, filter, func(e domain.WaterEvent) error {
+			return row(strconv.FormatInt(e.ID, 10), e.CreatedAt.In(time.Local).Format(time.RFC3339),
+				strconv.FormatFloat(e.DeltaLiters, 'f', -1, 64))
+		})
+	})
+}
+
+// handleExportEventsJSON downloads the user's events selected by ?from=,
+// ?to= and ?metrics= as one JSON document with an array per metric, oldest
+// first, shaped like the files of an export archive.
+func (s *Server) handleExportEventsJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	subject := subjectFromContext(r)
+	filter, err := exportFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body := map[string]any{"from": filter.From, "to": filter.To}
+	if filter.Includes(domain.ExportMetricWeight) {
+		items := []domain.WeightEntry{}
+		if err := s.weight.Export(r.Context(), subject, filter, func(e domain.WeightEntry) error {
+			items = append(items, e)
+			return nil
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		body[domain.ExportMetricWeight] = items
+	}
+	if filter.Includes(domain.ExportMetricWater) {
+		items := []domain.WaterEvent{}
+		if err := s.water.Export(r.Context(), subject, filter, func(e domain.WaterEvent) error {
+			items = append(items, e)
+			return nil
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		body[domain.ExportMetricWater] = items
+	}
+
+	filename := fmt.Sprintf("vitals-events-%s.json", time.Now().In(time.Local).Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeJSON(w, http.StatusOK, body)
+}
+
+// handleExportChartsCSV downloads the daily chart data of the last ?days=
+// days as CSV, one row per day. It takes the same parameters as
+// /charts/daily; empty cells mean nothing was recorded that day.
+func (s *Server) handleExportChartsCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	points, _, _, err := s.dailyPoints(r, "export/charts", 30, "kg")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeCSV(w, "charts", chartHeader, chartRows(points))
+}
+
+// handleExportArchive starts building a ZIP archive of the user's history
+// (POST), limited by ?from=, ?to= and ?metrics=, and returns its job with
+// 202; poll /export/archive/{id} or wait for the notification, then fetch
+// /export/archive/{id}/download.
+func (s *Server) handleExportArchive(w http.ResponseWriter, r *http.Request) {
+	if s.archives == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	filter, err := exportFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job, err := s.archives.Start(r.Context(), subjectFromContext(r), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"job": job})
+}
+
+func (s *Server) handleExportArchiveJob(w http.ResponseWriter, r *http.Request) {
+	if s.archives == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	job, err := s.archives.Get(subjectFromContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": job})
+}
+
+// handleExportArchiveDownload sends a finished archive as a ZIP download.
+func (s *Server) handleExportArchiveDownload(w http.ResponseWriter, r *http.Request) {
+	if s.archives == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	job, data, err := s.archives.Download(subjectFromContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	filename := fmt.Sprintf("vitals-export-%s.zip", job.CreatedAt.In(time.Local).Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// handleExportAll downloads the signed-in user's whole account as one JSON
+// document, restorable with POST /api/import/all.
+func (s *Server) handleExportAll(w http.ResponseWriter, r *http.Request) {
+	if s.portability == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	bundle, err := s.portability.Export(r.Context(), userFromContext(r).ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	filename := fmt.Sprintf("vitals-account-%s.json", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+// startedWriter records whether any of the response body has been sent.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// writeCSV streams a CSV download named vitals-<name>-<today>.csv: header,
+// then the rows rows emits. An error before any of the body has been sent
+// becomes a 500; after that the status is already out, so the error is
+// logged and the download ends early.
+func writeCSV(w http.ResponseWriter, name string, header []string, rows func(row func(...string) error) error) {
+	filename := fmt.Sprintf("vitals-%s-%s.csv", name, time.Now().In(time.Local).Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	sw := &startedWriter{ResponseWriter: w}
+	cw := csv.NewWriter(sw)
+	err := cw.Write(header)
+	if err == nil {
+		err = rows(func(fields ...string) error { return cw.Write(fields) })
+	}
+	if err == nil {
+		cw.Flush()
+		err = cw.Error()
+	}
+	if err == nil {
+		return
+	}
+	if !sw.started {
+		w.Header().Del("Content-Disposition")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("[HTTP] export %s: %v", name, err)
+}
@@ -0,0 +1,147 @@
+package adapthttp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+
+	"vitals/internal/domain"
+)
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// handleCalendarFeed serves the user's weigh-ins and milestones as an
+// iCalendar feed of all-day events, for subscription from calendar apps.
+func (s *Server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := userFromContext(r)
+
+	cal, err := s.feeds.Calendar(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+	event := func(uid, day, summary string) {
+		d := strings.ReplaceAll(day, "-", "")
+		line("BEGIN:VEVENT")
+		line("UID:%s", uid)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART;VALUE=DATE:%s", d)
+		line("SUMMARY:%s", icsEscaper.Replace(summary))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//vitals//feed//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", icsEscaper.Replace("Vitals - "+user.Username))
+	for _, e := range cal.WeighIns {
+		event(fmt.Sprintf("weight-%d@vitals", e.ID), e.Day, fmt.Sprintf("Weigh-in: %g %s", e.Value, e.Unit))
+	}
+	for i, m := range cal.Milestones {
+		event(fmt.Sprintf("milestone-%d-%d@vitals", user.ID, i), m.Day, m.Title)
+	}
+	line("END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string `xml:"title"`
+	ID      string `xml:"id"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+}
+
+// handleSummaryFeed serves an Atom feed with one entry per completed week.
+func (s *Server) handleSummaryFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := userFromContext(r)
+	weeks, err := s.intQuery(r, "feeds/weekly", "weeks", 12)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	summaries, err := s.feeds.WeeklySummaries(r.Context(), user.ID, weeks, time.Now())
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	format := s.defaultFormat()
+	if s.settings != nil {
+		if format, err = s.settings.Format(r.Context(), user.ID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+	p := format.Printer()
+
+	feedID := fmt.Sprintf("urn:vitals:user:%d:weekly", user.ID)
+	feed := atomFeed{
+		Title:   "Vitals weekly summary - " + user.Username,
+		ID:      feedID,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: user.Username},
+	}
+	for _, sum := range summaries {
+		end, err := time.ParseInLocation("2006-01-02", sum.WeekEnd, time.Local)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   "Week of " + sum.WeekStart,
+			ID:      feedID + ":" + sum.WeekStart,
+			Updated: end.AddDate(0, 0, 1).UTC().Format(time.RFC3339),
+			Summary: weeklySummaryText(p, sum),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	_ = enc.Encode(feed)
+}
+
+// weeklySummaryText describes a week, writing numbers as p's locale does.
+func weeklySummaryText(p *message.Printer, sum domain.WeeklySummary) string {
+	weight := p.Sprintf("%d weigh-ins", sum.WeighIns)
+	if sum.ChangeKg != nil {
+		weight = p.Sprintf("Weight %.1f kg → %.1f kg (%+.1f kg) over %d weigh-ins", *sum.StartKg, *sum.EndKg, *sum.ChangeKg, sum.WeighIns)
+	}
+	return p.Sprintf("%s. Average hydration %.2f L/day, goal met on %d of 7 days.", weight, sum.AvgWaterLiters, sum.GoalDays)
+}
@@ -0,0 +1,105 @@
+package adapthttp
+
+import (
+	"net/http"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// handleFoodToday returns today's energy and macronutrient totals.
+func (s *Server) handleFoodToday(w http.ResponseWriter, r *http.Request) {
+	if s.nutrition == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	today := localDayString(time.Now())
+	totals, err := s.nutrition.DayTotals(r.Context(), subjectFromContext(r), today)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"today": today, "totals": totals})
+}
+
+// handleFoodEvent logs a food entry.
+func (s *Server) handleFoodEvent(w http.ResponseWriter, r *http.Request) {
+	if s.nutrition == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Description string    `json:"description"`
+		Kcal        float64   `json:"kcal"`
+		ProteinG    float64   `json:"proteinG"`
+		CarbsG      float64   `json:"carbsG"`
+		FatG        float64   `json:"fatG"`
+		ClientID    string    `json:"clientId"`
+		CreatedAt   time.Time `json:"createdAt"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entry, created, err := s.nutrition.RecordEntry(r.Context(), subjectFromContext(r), domain.FoodEntry{
+		Description: body.Description,
+		Kcal:        body.Kcal,
+		ProteinG:    body.ProteinG,
+		CarbsG:      body.CarbsG,
+		FatG:        body.FatG,
+		ClientID:    body.ClientID,
+		CreatedAt:   body.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": entry.ID, "entry": entry, "created": created})
+}
+
+func (s *Server) handleFoodRecent(w http.ResponseWriter, r *http.Request) {
+	if s.nutrition == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "food/recent", "limit", 20)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := s.nutrition.ListRecent(r.Context(), subjectFromContext(r), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeList(w, r, items, nil)
+}
+
+func (s *Server) handleFoodUndoLast(w http.ResponseWriter, r *http.Request) {
+	if s.nutrition == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	undone, id, err := s.nutrition.UndoLast(r.Context(), subjectFromContext(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"undone": undone, "id": id})
+}
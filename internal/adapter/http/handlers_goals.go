@@ -0,0 +1,69 @@
+package adapthttp
+
+import (
+	"net/http"
+)
+
+// handleGoalHistory lists every water goal and target weight the user set,
+// each with the day it took effect.
+func (s *Server) handleGoalHistory(w http.ResponseWriter, r *http.Request) {
+	if s.goals == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	goals, err := s.goals.History(r.Context(), subjectFromContext(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, goals)
+}
+
+// handleWeightGoal sets (PUT { "value", "unit", "effectiveFrom" }) or clears
+// (DELETE, ?effectiveFrom=) the user's target weight and replies with the
+// goal history.
+func (s *Server) handleWeightGoal(w http.ResponseWriter, r *http.Request) {
+	if s.goals == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Value         float64 `json:"value"`
+			Unit          string  `json:"unit"`
+			EffectiveFrom string  `json:"effectiveFrom"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.goals.SetWeightGoal(r.Context(), subject, &body.Value, body.Unit, body.EffectiveFrom); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+	case http.MethodDelete:
+		if err := s.goals.SetWeightGoal(r.Context(), subject, nil, "", r.URL.Query().Get("effectiveFrom")); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	goals, err := s.goals.History(r.Context(), subject)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, goals)
+}
@@ -0,0 +1,262 @@
+package adapthttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vitals/internal/app"
+)
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 256 << 20
+
+// maxImportMemory is how much of a multipart upload is held in memory; the
+// rest is buffered on disk while the form is parsed.
+const maxImportMemory = 32 << 20
+
+// handleImport accepts an import file as the raw request body and starts a
+// background job for it, replying 202 with the job. The format is the
+// {source} path segment, or ?format= on /api/import. A multipart upload to
+// /api/import/csv is a CSV file with a column mapping instead. With
+// ?dryRun=true it replies with a validation report and stores nothing.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if s.imports == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" && r.PathValue("source") == app.ImportFormatCSV {
+		s.handleImportMappedCSV(w, r)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New("import file too large"))
+		return
+	}
+	format := r.PathValue("source")
+	if format == "" {
+		format = r.URL.Query().Get("format")
+	}
+	dryRun, err := parseDryRun(r.URL.Query().Get("dryRun"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if dryRun {
+		report, err := s.imports.DryRun(format, data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"report": report})
+		return
+	}
+	job, err := s.imports.Start(r.Context(), subjectFromContext(r), format, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"job": job})
+}
+
+// handleImportMappedCSV reads a multipart form with the CSV as "file" and
+// its column mapping as JSON in "mapping". With dryRun=true (a form field or
+// query parameter) it replies with a validation report and stores nothing;
+// otherwise it starts an import job like handleImport.
+func (s *Server) handleImportMappedCSV(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, errors.New("import file too large"))
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %w", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll() //nolint:errcheck
+
+	var mapping app.CSVMapping
+	dec := json.NewDecoder(strings.NewReader(r.FormValue("mapping")))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&mapping); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid mapping: %w", err))
+		return
+	}
+	dryRun, err := parseDryRun(r.FormValue("dryRun"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("a file field with the CSV is required"))
+		return
+	}
+	defer f.Close() //nolint:errcheck
+	data, err := io.ReadAll(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if dryRun {
+		report, err := s.imports.DryRunCSV(mapping, data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"report": report})
+		return
+	}
+	job, err := s.imports.StartCSV(r.Context(), subjectFromContext(r), mapping, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"job": job})
+}
+
+// handleImportAll restores an account bundle from GET /api/export/all into
+// the signed-in user's account. Configuration is applied before replying;
+// the events are imported by the job in the 202 response. With ?dryRun=true
+// it replies with a validation report of the events and stores nothing.
+func (s *Server) handleImportAll(w http.ResponseWriter, r *http.Request) {
+	if s.portability == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var bundle app.AccountBundle
+	if err := parseJSON(r, &bundle); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, errors.New("import file too large"))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	dryRun, err := parseDryRun(r.URL.Query().Get("dryRun"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if dryRun {
+		report, err := s.portability.DryRun(bundle)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"report": report})
+		return
+	}
+	res, err := s.portability.Import(r.Context(), userFromContext(r).ID, bundle)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, res)
+}
+
+// parseDryRun reads a dryRun parameter; empty means false.
+func parseDryRun(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("dryRun must be true or false")
+	}
+	return dryRun, nil
+}
+
+func (s *Server) handleImportJob(w http.ResponseWriter, r *http.Request) {
+	if s.imports == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	job, err := s.imports.Get(subjectFromContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": job})
+}
+
+// handleImportBatch rolls back an import (DELETE), removing every event it
+// stored. The batch ID is the job's batchId.
+func (s *Server) handleImportBatch(w http.ResponseWriter, r *http.Request) {
+	if s.imports == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n, err := s.imports.Rollback(r.Context(), subjectFromContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": n})
+}
+
+// handleImportJobEvents streams job progress as Server-Sent Events: one
+// "progress" event per update and a final "done" event.
+func (s *Server) handleImportJobEvents(w http.ResponseWriter, r *http.Request) {
+	if s.imports == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	updates, err := s.imports.Watch(r.Context(), subjectFromContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for job := range updates {
+		event := "progress"
+		if job.Done() {
+			event = "done"
+		}
+		data, _ := json.Marshal(job)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
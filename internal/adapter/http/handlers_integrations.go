@@ -0,0 +1,118 @@
+package adapthttp
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// integrationStateCookie carries the OAuth2 state of a connect flow to its
+// callback.
+const integrationStateCookie = "integration_state"
+
+// handleIntegration reports (GET) or removes (DELETE) the user's connection
+// to a provider.
+func (s *Server) handleIntegration(w http.ResponseWriter, r *http.Request) {
+	if s.integration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	userID, provider := userFromContext(r).ID, r.PathValue("provider")
+
+	switch r.Method {
+	case http.MethodGet:
+		in, err := s.integration.Status(r.Context(), userID, provider)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, in)
+
+	case http.MethodDelete:
+		if err := s.integration.Disconnect(r.Context(), userID, provider); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleIntegrationConnect sends the user to the provider's consent page,
+// which returns them to handleIntegrationCallback.
+func (s *Server) handleIntegrationConnect(w http.ResponseWriter, r *http.Request) {
+	if s.integration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	state := generateState()
+	target, err := s.integration.AuthCodeURL(r.PathValue("provider"), state)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     integrationStateCookie,
+		Value:    state,
+		Path:     "/api/integrations/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // Lax required for cross-site redirect returns
+		MaxAge:   300,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleIntegrationCallback completes a connect flow and returns the user to
+// the app.
+func (s *Server) handleIntegrationCallback(w http.ResponseWriter, r *http.Request) {
+	if s.integration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	state, err := r.Cookie(integrationStateCookie)
+	if err != nil || r.URL.Query().Get("state") != state.Value {
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: integrationStateCookie, MaxAge: -1, Path: "/api/integrations/"})
+
+	provider := r.PathValue("provider")
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		http.Redirect(w, r, "/?integration="+url.QueryEscape(provider)+"&error="+url.QueryEscape(reason), http.StatusFound)
+		return
+	}
+	if err := s.integration.Connect(r.Context(), userFromContext(r).ID, provider, r.URL.Query().Get("code")); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	http.Redirect(w, r, "/?integration="+url.QueryEscape(provider), http.StatusFound)
+}
+
+// handleIntegrationSync pulls the user's new readings from a provider now,
+// rather than at the next scheduled sync.
+func (s *Server) handleIntegrationSync(w http.ResponseWriter, r *http.Request) {
+	if s.integration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	res, err := s.integration.Sync(r.Context(), userFromContext(r).ID, r.PathValue("provider"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,51 @@
+package adapthttp
+
+import (
+	"net/http"
+)
+
+// handleJournal reads, writes or deletes the journal note for
+// /api/journal/{date}.
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	if s.journal == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	day := r.PathValue("date")
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := s.journal.Get(r.Context(), subject, day)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"entry": entry})
+
+	case http.MethodPut:
+		var body struct {
+			Note string `json:"note"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entry, err := s.journal.Save(r.Context(), subject, day, body.Note)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"entry": entry})
+
+	case http.MethodDelete:
+		if _, err := s.journal.Delete(r.Context(), subject, day); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
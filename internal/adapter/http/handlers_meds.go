@@ -0,0 +1,162 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// medicationBody is the request body for creating or replacing a
+// medication.
+type medicationBody struct {
+	Name     string   `json:"name"`
+	Dose     string   `json:"dose"`
+	Schedule []string `json:"schedule"`
+}
+
+func (b medicationBody) medication(userID, id int64) domain.Medication {
+	return domain.Medication{ID: id, UserID: userID, Name: b.Name, Dose: b.Dose, Schedule: b.Schedule}
+}
+
+// handleMedications lists or creates the user's medications.
+func (s *Server) handleMedications(w http.ResponseWriter, r *http.Request) {
+	if s.meds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.meds.List(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeList(w, r, items, nil)
+
+	case http.MethodPost:
+		var body medicationBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := s.meds.Create(r.Context(), body.medication(subject, 0))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"medication": m})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMedication replaces or deletes the medication addressed by the {id}
+// path segment.
+func (s *Server) handleMedication(w http.ResponseWriter, r *http.Request) {
+	if s.meds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body medicationBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := s.meds.Update(r.Context(), body.medication(subject, id))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"medication": m})
+
+	case http.MethodDelete:
+		if err := s.meds.Delete(r.Context(), subject, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMedicationEvent logs a dose as taken or skipped.
+func (s *Server) handleMedicationEvent(w http.ResponseWriter, r *http.Request) {
+	if s.meds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		MedicationID int64     `json:"medicationId"`
+		Status       string    `json:"status"`
+		ClientID     string    `json:"clientId"`
+		CreatedAt    time.Time `json:"createdAt"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	event, created, err := s.meds.Log(r.Context(), subjectFromContext(r), domain.MedicationEvent{
+		MedicationID: body.MedicationID,
+		Status:       body.Status,
+		ClientID:     body.ClientID,
+		CreatedAt:    body.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": event.ID, "event": event, "created": created})
+}
+
+// handleMedicationRecent lists the latest logged doses, optionally for one
+// medication (?medicationId=).
+func (s *Server) handleMedicationRecent(w http.ResponseWriter, r *http.Request) {
+	if s.meds == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "meds/recent", "limit", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var medicationID int64
+	if v := r.URL.Query().Get("medicationId"); v != "" {
+		medicationID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || medicationID <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("medicationId must be a positive integer"))
+			return
+		}
+	}
+	items, err := s.meds.ListRecent(r.Context(), subjectFromContext(r), medicationID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeList(w, r, items, nil)
+}
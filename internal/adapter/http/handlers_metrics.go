@@ -0,0 +1,145 @@
+package adapthttp
+
+import (
+	"net/http"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// handleCustomMetrics lists or defines the user's custom metrics.
+func (s *Server) handleCustomMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.metrics.List(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeList(w, r, items, nil)
+
+	case http.MethodPost:
+		var body struct {
+			Name        string `json:"name"`
+			Slug        string `json:"slug"`
+			Unit        string `json:"unit"`
+			Aggregation string `json:"aggregation"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := s.metrics.Define(r.Context(), domain.CustomMetric{
+			UserID:      subject,
+			Slug:        body.Slug,
+			Name:        body.Name,
+			Unit:        body.Unit,
+			Aggregation: body.Aggregation,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"metric": m})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCustomMetric deletes the metric addressed by the {slug} path
+// segment along with its events.
+func (s *Server) handleCustomMetric(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.metrics.Delete(r.Context(), subjectFromContext(r), r.PathValue("slug")); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleCustomMetricToday returns today's aggregated value of a metric.
+func (s *Server) handleCustomMetricToday(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	today := localDayString(time.Now())
+	m, day, err := s.metrics.Day(r.Context(), subjectFromContext(r), r.PathValue("slug"), today)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"today": today, "metric": m, "value": day.Value, "count": day.Count})
+}
+
+// handleCustomMetricEvent records a value of a metric.
+func (s *Server) handleCustomMetricEvent(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Value     float64   `json:"value"`
+		ClientID  string    `json:"clientId"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	event, created, err := s.metrics.Record(r.Context(), subjectFromContext(r), r.PathValue("slug"), domain.CustomMetricEvent{
+		Value:     body.Value,
+		ClientID:  body.ClientID,
+		CreatedAt: body.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": event.ID, "event": event, "created": created})
+}
+
+// handleCustomMetricRecent lists a metric's latest events.
+func (s *Server) handleCustomMetricRecent(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "metrics/recent", "limit", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := s.metrics.ListRecent(r.Context(), subjectFromContext(r), r.PathValue("slug"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeList(w, r, items, nil)
+}
@@ -0,0 +1,68 @@
+package adapthttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// handleMoodToday reads (GET) or records (PUT { "score", "note" }) today's
+// mood.
+func (s *Server) handleMoodToday(w http.ResponseWriter, r *http.Request) {
+	if s.mood == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	today := localDayString(time.Now())
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := s.mood.Get(r.Context(), subject, today)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry})
+
+	case http.MethodPut:
+		var body struct {
+			Score int    `json:"score"`
+			Note  string `json:"note"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entry, err := s.mood.Record(r.Context(), subject, today, body.Score, body.Note)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleMoodRecent(w http.ResponseWriter, r *http.Request) {
+	if s.mood == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "mood/recent", "limit", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := s.mood.ListRecent(r.Context(), subjectFromContext(r), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeList(w, r, items, nil)
+}
@@ -0,0 +1,103 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vitals/internal/domain"
+)
+
+// planBody is the request body for creating or replacing a planned entry.
+type planBody struct {
+	Kind    string  `json:"kind"`
+	Day     string  `json:"day"`
+	At      string  `json:"at"`
+	Liters  float64 `json:"liters"`
+	Note    string  `json:"note"`
+	Channel string  `json:"channel"`
+	Target  string  `json:"target"`
+}
+
+func (b planBody) entry(userID, id int64) domain.PlannedEntry {
+	return domain.PlannedEntry{
+		ID: id, UserID: userID, Kind: b.Kind, Day: b.Day, At: b.At, Liters: b.Liters,
+		Note: b.Note, Channel: b.Channel, Target: b.Target,
+	}
+}
+
+// handlePlan lists planned entries for ?from through ?to (GET) or plans a
+// new one (POST).
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	if s.plans == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.plans.List(r.Context(), subject, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items, "channels": s.plans.Channels()})
+
+	case http.MethodPost:
+		var body planBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entry, err := s.plans.Create(r.Context(), body.entry(subject, 0))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"entry": entry})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePlannedEntry replaces or deletes the planned entry addressed by the
+// {id} path segment.
+func (s *Server) handlePlannedEntry(w http.ResponseWriter, r *http.Request) {
+	if s.plans == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body planBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entry, err := s.plans.Update(r.Context(), body.entry(subject, id))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"entry": entry})
+
+	case http.MethodDelete:
+		if err := s.plans.Delete(r.Context(), subject, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
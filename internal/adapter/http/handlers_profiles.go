@@ -0,0 +1,41 @@
+package adapthttp
+
+import (
+	"net/http"
+)
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if s.profiles == nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := userFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.profiles.List(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items})
+
+	case http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		p, err := s.profiles.Create(r.Context(), user.ID, body.Name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"profile": p})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,72 @@
+package adapthttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// The quick endpoints serve one-tap automations (Apple Shortcuts, Tasker):
+// parameters come from the query string and replies are a single plain-text
+// line meant to be shown as a notification.
+
+func (s *Server) handleQuickWater(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := userFromContext(r)
+
+	liters, err := domain.ParseVolume(r.URL.Query().Get("amount"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec, err := s.water.RecordEvent(r.Context(), user.ID, liters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	total, err := s.water.GetTodayTotal(r.Context(), user.ID, localDayString(time.Now()))
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if rec.Merged {
+		writeText(w, fmt.Sprintf("Already logged %g ml of water a moment ago. Today: %.2f L", liters*1000, total))
+		return
+	}
+	writeText(w, fmt.Sprintf("Logged %g ml of water. Today: %.2f L", liters*1000, total))
+}
+
+func (s *Server) handleQuickWeight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := userFromContext(r)
+
+	q := r.URL.Query()
+	value, err := strconv.ParseFloat(q.Get("value"), 64)
+	if err != nil {
+		http.Error(w, "value must be a number", http.StatusBadRequest)
+		return
+	}
+	unit := q.Get("unit")
+	if unit == "" {
+		unit = "kg"
+	}
+	if _, _, err := s.weight.RecordWeight(r.Context(), user.ID, value, unit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeText(w, fmt.Sprintf("Logged %g %s.", value, unit))
+}
+
+func writeText(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(w, msg)
+}
@@ -0,0 +1,121 @@
+package adapthttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"vitals/internal/app"
+)
+
+// handleRecover redeems a recovery code: POST { "username", "code",
+// "password", "challenge" } sets the account's local password and signs
+// it in. Wrong codes fail like wrong passwords on /auth/login.
+func (s *Server) handleRecover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Username  string `json:"username"`
+		Code      string `json:"code"`
+		Password  string `json:"password"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.authSvc.Recover(r.Context(), req.Username, req.Code, req.Password, req.Challenge, r.UserAgent(), s.clientAddr(r))
+	switch {
+	case errors.Is(err, app.ErrInvalidCredentials), errors.Is(err, app.ErrChallengeRequired), errors.Is(err, app.ErrLoginLocked):
+		s.writeLoginFailure(w, r, err)
+		return
+	case errors.Is(err, app.ErrRecoveryUnavailable):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setSessionCookie(w, token)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleRecoveryCodes reports (GET) whether the signed-in user has a local
+// password and how many emergency codes they have left, or replaces their
+// emergency codes (POST), answering with the new codes once.
+func (s *Server) handleRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := userFromContext(r).ID
+
+	switch r.Method {
+	case http.MethodGet:
+		st, err := s.authSvc.RecoveryStatus(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	case http.MethodPost:
+		codes, err := s.authSvc.GenerateRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"codes": codes})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminRecovery issues a one-time recovery code (POST) for the user
+// named by the {username} path segment, so a user without a password can
+// set one when single sign-on is unavailable.
+func (s *Server) handleAdminRecovery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	code, expiresAt, err := s.authSvc.IssueRecoveryCode(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"code": code, "expiresAt": expiresAt})
+}
+
+// handleAdminUserRole sets the role (PUT { "role" }) of the user named by
+// the {username} path segment. Taking admin away signs the user out of
+// every session and revokes their API tokens.
+func (s *Server) handleAdminUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	user, err := s.authSvc.SetRole(r.Context(), userFromContext(r).ID, r.PathValue("username"), body.Role)
+	switch {
+	case errors.Is(err, app.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, app.ErrOwnRole):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"username": user.Username, "role": user.Role})
+}
@@ -0,0 +1,63 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// handleSessions lists the signed-in user's sessions, marking the one the
+// request was made with as current.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var current string
+	if cookie, err := r.Cookie("session"); err == nil {
+		current = cookie.Value
+	}
+	items, err := s.authSvc.Sessions(r.Context(), userFromContext(r).ID, current)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// handleSession names (PUT { "name" }) or revokes (DELETE) one of the
+// signed-in user's sessions.
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.authSvc.RenameSession(r.Context(), user.ID, id, body.Name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case http.MethodDelete:
+		if err := s.authSvc.RevokeSession(r.Context(), user.ID, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,42 @@
+package adapthttp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleSettings reads or patches the user's preferences. PUT takes a flat
+// object of namespaced keys; a null value deletes its key.
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if s.settings == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err := s.settings.Get(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": settings})
+
+	case http.MethodPut:
+		var patch map[string]json.RawMessage
+		if err := parseJSON(r, &patch); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		settings, err := s.settings.Update(r.Context(), subject, patch)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": settings})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
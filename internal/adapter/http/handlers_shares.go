@@ -0,0 +1,55 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleShares(w http.ResponseWriter, r *http.Request) {
+	if s.shares == nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := userFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		granted, received, err := s.shares.List(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"granted": granted, "received": received})
+
+	case http.MethodPost:
+		var body struct {
+			Username string `json:"username"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		share, err := s.shares.Grant(r.Context(), user.ID, body.Username)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"share": share})
+
+	case http.MethodDelete:
+		viewerID, err := strconv.ParseInt(r.URL.Query().Get("viewer"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("viewer must be a user id"))
+			return
+		}
+		if err := s.shares.Revoke(r.Context(), user.ID, viewerID); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,80 @@
+package adapthttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitals/internal/app"
+)
+
+// snapshotMaxAge is how long clients and proxies may cache a snapshot.
+// Displays that poll every few minutes would drain their battery for
+// numbers that rarely change.
+const snapshotMaxAge = 15 * time.Minute
+
+// snapshotBarWidth is the number of cells in the text water bar.
+const snapshotBarWidth = 10
+
+// handleSnapshot returns today's snapshot as JSON or, with ?format=text or
+// an Accept header preferring text/plain, as a few lines of ASCII ready to
+// print on a small display. ?unit= sets the weight unit (default kg).
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if s.snapshots == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	unit := r.URL.Query().Get("unit")
+	switch unit {
+	case "":
+		unit = "kg"
+	case "kg", "lb":
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("unit must be \"kg\" or \"lb\""))
+		return
+	}
+	snap, err := s.snapshots.Snapshot(r.Context(), subjectFromContext(r), unit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(snapshotMaxAge.Seconds())))
+	w.Header().Set("Vary", "Accept")
+	format := r.URL.Query().Get("format")
+	if format == "text" || format == "" && strings.HasPrefix(r.Header.Get("Accept"), "text/plain") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprint(w, snapshotText(snap))
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+// snapshotText lays snap out as short ASCII lines, e.g.
+//
+//	2026-10-15
+//	Weight 80.2 kg
+//	Water 1.5/2.5 L
+//	[######----] 60%
+//	Streak 12d weigh-in, 4d water
+func snapshotText(snap *app.Snapshot) string {
+	var b strings.Builder
+	b.WriteString(snap.Day + "\n")
+	if snap.Weight != nil {
+		fmt.Fprintf(&b, "Weight %.1f %s\n", *snap.Weight, snap.WeightUnit)
+	} else {
+		b.WriteString("Weight -\n")
+	}
+	fmt.Fprintf(&b, "Water %.1f/%.1f L\n", snap.WaterLiters, snap.GoalLiters)
+	filled := int(snap.WaterFraction*snapshotBarWidth + 0.5)
+	fmt.Fprintf(&b, "[%s%s] %d%%\n", strings.Repeat("#", filled), strings.Repeat("-", snapshotBarWidth-filled), int(snap.WaterFraction*100+0.5))
+	fmt.Fprintf(&b, "Streak %dd weigh-in, %dd water\n", snap.WeighInStreak, snap.WaterStreak)
+	return b.String()
+}
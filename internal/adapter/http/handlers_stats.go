@@ -0,0 +1,67 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// handleCompliance reports weigh-in consistency over ?days= (default 90).
+func (s *Server) handleCompliance(w http.ResponseWriter, r *http.Request) {
+	if s.stats == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	days, err := s.intQuery(r, "stats/compliance", "days", 90)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := tagFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	report, err := s.stats.Compliance(r.Context(), subjectFromContext(r), days, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleWeeklyStats returns summaries of the last ?weeks= (default 12)
+// completed weeks, newest first, with ?weight=average averaging each day's
+// weigh-ins.
+func (s *Server) handleWeeklyStats(w http.ResponseWriter, r *http.Request) {
+	if s.summaries == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	weeks, err := s.intQuery(r, "stats/weekly", "weeks", 12)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	weight := r.URL.Query().Get("weight")
+	if weight != "" && !domain.ValidDailyWeight(weight) {
+		writeError(w, http.StatusBadRequest, errors.New("weight must be \"latest\" or \"average\""))
+		return
+	}
+	summaries, err := s.summaries.Weekly(r.Context(), subjectFromContext(r), weeks, time.Now(), weight)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"weeks": summaries})
+}
@@ -0,0 +1,45 @@
+package adapthttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// handleStepsToday reads (GET) or records (PUT { "steps" }) today's step
+// count.
+func (s *Server) handleStepsToday(w http.ResponseWriter, r *http.Request) {
+	if s.steps == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	today := localDayString(time.Now())
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := s.steps.Get(r.Context(), subject, today)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry})
+
+	case http.MethodPut:
+		var body struct {
+			Steps int `json:"steps"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entry, err := s.steps.Record(r.Context(), subject, today, body.Steps)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,117 @@
+package adapthttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// eventStreamKeepAlive is how often an idle event stream sends a comment,
+// so proxies do not close it.
+const eventStreamKeepAlive = 30 * time.Second
+
+// handleSync returns the changes after ?since=<cursor> (0 for a full sync).
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	if s.sync == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("since must be a non-negative cursor"))
+			return
+		}
+		since = n
+	}
+
+	limit, err := s.intQuery(r, "sync", "limit", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := s.sync.Changes(r.Context(), subjectFromContext(r), since, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Vary", "Accept")
+	if wantsProtobuf(r) {
+		writeProto(w, http.StatusOK, syncPageToProto(page))
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleEventStream streams the user's weight and water changes as
+// Server-Sent Events: each is a "change" event whose data is a sync change
+// and whose id is its cursor. A reconnecting EventSource resumes after its
+// Last-Event-ID; a new stream (or ?since=) starts after the latest change.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if s.sync == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	since := int64(-1)
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("since")
+	}
+	if v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("since must be a non-negative cursor"))
+			return
+		}
+		since = n
+	}
+	changes, err := s.sync.Watch(r.Context(), subjectFromContext(r), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(c)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", c.Seq, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,58 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// handleEntryTags returns a handler that replaces the tags on one entry of
+// entity, addressed by the {id} path segment.
+func (s *Server) handleEntryTags(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.tags == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+			return
+		}
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		tags, err := s.tags.SetTags(r.Context(), subjectFromContext(r), entity, id, body.Tags)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "tags": tags})
+	}
+}
+
+// handleTags lists the tags in use with their counts.
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	if s.tags == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	tags, err := s.tags.List(r.Context(), subjectFromContext(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
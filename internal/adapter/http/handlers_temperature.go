@@ -0,0 +1,95 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// handleTemperatureEvent records a body temperature reading.
+func (s *Server) handleTemperatureEvent(w http.ResponseWriter, r *http.Request) {
+	if s.temperature == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Value     float64   `json:"value"`
+		Unit      string    `json:"unit"`
+		ClientID  string    `json:"clientId"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	reading, created, err := s.temperature.Record(r.Context(), subjectFromContext(r), domain.TemperatureReading{
+		Value:     body.Value,
+		Unit:      body.Unit,
+		ClientID:  body.ClientID,
+		CreatedAt: body.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": reading.ID, "reading": reading, "created": created})
+}
+
+// handleTemperatureRecent lists the latest readings, optionally converted
+// to a display unit (?unit=c|f).
+func (s *Server) handleTemperatureRecent(w http.ResponseWriter, r *http.Request) {
+	if s.temperature == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := s.intQuery(r, "temperature/recent", "limit", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	unit := r.URL.Query().Get("unit")
+	if unit != "" && unit != domain.TemperatureCelsius && unit != domain.TemperatureFahrenheit {
+		writeError(w, http.StatusBadRequest, errors.New("unit must be \"c\" or \"f\""))
+		return
+	}
+	items, err := s.temperature.ListRecent(r.Context(), subjectFromContext(r), limit, unit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeList(w, r, items, nil)
+}
+
+// handleTemperatureReading deletes the reading addressed by the {id} path
+// segment.
+func (s *Server) handleTemperatureReading(w http.ResponseWriter, r *http.Request) {
+	if s.temperature == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+	if err := s.temperature.Delete(r.Context(), subjectFromContext(r), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -1,19 +1,38 @@
 package adapthttp_test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	adapthttp "vitals/internal/adapter/http"
+	"vitals/internal/adapter/http/pb"
+	"vitals/internal/adapter/memory"
+	"vitals/internal/adapter/pow"
 	"vitals/internal/app"
 	"vitals/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/proto"
 )
 
 // ---------------------------------------------------------------------------
@@ -21,10 +40,12 @@ import (
 // ---------------------------------------------------------------------------
 
 type mockWeightRepo struct {
-	addFn    func(ctx context.Context, userID int64, value float64, unit string, createdAt time.Time) (int64, error)
-	deleteFn func(ctx context.Context, userID int64) (bool, error)
-	latestFn func(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error)
-	listFn   func(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error)
+	addClientFn func(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error)
+	addFn       func(ctx context.Context, userID int64, value float64, unit string, createdAt time.Time) (int64, error)
+	deleteFn    func(ctx context.Context, userID int64) (bool, error)
+	latestFn    func(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error)
+	listFn      func(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error)
+	eachFn      func(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error
 }
 
 func (m *mockWeightRepo) AddWeightEvent(ctx context.Context, userID int64, value float64, unit string, createdAt time.Time) (int64, error) {
@@ -34,6 +55,13 @@ func (m *mockWeightRepo) AddWeightEvent(ctx context.Context, userID int64, value
 	return 1, nil
 }
 
+func (m *mockWeightRepo) AddWeightEventWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error) {
+	if m.addClientFn != nil {
+		return m.addClientFn(ctx, userID, clientID, value, unit, createdAt)
+	}
+	return &domain.WeightEntry{ID: 1, UserID: userID, Value: value, Unit: unit, ClientID: clientID, CreatedAt: createdAt}, true, nil
+}
+
 func (m *mockWeightRepo) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
 	if m.deleteFn != nil {
 		return m.deleteFn(ctx, userID)
@@ -51,6 +79,14 @@ func (m *mockWeightRepo) LatestWeightForLocalDay(ctx context.Context, userID int
 	}, nil
 }
 
+func (m *mockWeightRepo) AverageWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error) {
+	return m.LatestWeightForLocalDay(ctx, userID, localDay)
+}
+
+func (m *mockWeightRepo) WeightSpanSince(ctx context.Context, userID int64, since time.Time) (*domain.WeightSpan, error) {
+	return nil, nil
+}
+
 func (m *mockWeightRepo) ListRecentWeightEvents(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error) {
 	if m.listFn != nil {
 		return m.listFn(ctx, userID, limit)
@@ -60,11 +96,26 @@ func (m *mockWeightRepo) ListRecentWeightEvents(ctx context.Context, userID int6
 	}, nil
 }
 
+func (m *mockWeightRepo) ListRecentWeightPage(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, domain.ListSummary, error) {
+	items, err := m.ListRecentWeightEvents(ctx, userID, limit)
+	return items, domain.ListSummary{Total: len(items)}, err
+}
+
+func (m *mockWeightRepo) EachWeightEvent(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error {
+	if m.eachFn != nil {
+		return m.eachFn(ctx, userID, fn)
+	}
+	return nil
+}
+
 type mockWaterRepo struct {
-	addFn   func(ctx context.Context, userID int64, deltaLiters float64, createdAt time.Time) (int64, error)
-	delFn   func(ctx context.Context, userID int64, id int64) error
-	listFn  func(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error)
-	totalFn func(ctx context.Context, userID int64, localDay string) (float64, error)
+	addClientFn func(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error)
+	addFn       func(ctx context.Context, userID int64, deltaLiters float64, createdAt time.Time) (int64, error)
+	delFn       func(ctx context.Context, userID int64, id int64) error
+	delLatestFn func(ctx context.Context, userID int64) (int64, error)
+	listFn      func(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error)
+	totalFn     func(ctx context.Context, userID int64, localDay string) (float64, error)
+	eachFn      func(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error
 }
 
 func (m *mockWaterRepo) AddWaterEvent(ctx context.Context, userID int64, deltaLiters float64, createdAt time.Time) (int64, error) {
@@ -74,6 +125,13 @@ func (m *mockWaterRepo) AddWaterEvent(ctx context.Context, userID int64, deltaLi
 	return 42, nil
 }
 
+func (m *mockWaterRepo) AddWaterEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error) {
+	if m.addClientFn != nil {
+		return m.addClientFn(ctx, userID, clientID, deltaLiters, createdAt)
+	}
+	return &domain.WaterEvent{ID: 42, UserID: userID, DeltaLiters: deltaLiters, ClientID: clientID, CreatedAt: createdAt}, true, nil
+}
+
 func (m *mockWaterRepo) DeleteWaterEvent(ctx context.Context, userID int64, id int64) error {
 	if m.delFn != nil {
 		return m.delFn(ctx, userID, id)
@@ -81,6 +139,17 @@ func (m *mockWaterRepo) DeleteWaterEvent(ctx context.Context, userID int64, id i
 	return nil
 }
 
+func (m *mockWaterRepo) DeleteLatestWaterEvent(ctx context.Context, userID int64) (int64, error) {
+	if m.delLatestFn != nil {
+		return m.delLatestFn(ctx, userID)
+	}
+	return 0, nil
+}
+
+func (m *mockWaterRepo) UpdateWaterEvent(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+	return nil, nil
+}
+
 func (m *mockWaterRepo) ListRecentWaterEvents(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error) {
 	if m.listFn != nil {
 		return m.listFn(ctx, userID, limit)
@@ -90,6 +159,18 @@ func (m *mockWaterRepo) ListRecentWaterEvents(ctx context.Context, userID int64,
 	}, nil
 }
 
+func (m *mockWaterRepo) ListRecentWaterPage(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, domain.ListSummary, error) {
+	items, err := m.ListRecentWaterEvents(ctx, userID, limit)
+	return items, domain.ListSummary{Total: len(items)}, err
+}
+
+func (m *mockWaterRepo) EachWaterEvent(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error {
+	if m.eachFn != nil {
+		return m.eachFn(ctx, userID, fn)
+	}
+	return nil
+}
+
 func (m *mockWaterRepo) WaterTotalForLocalDay(ctx context.Context, userID int64, localDay string) (float64, error) {
 	if m.totalFn != nil {
 		return m.totalFn(ctx, userID, localDay)
@@ -97,13 +178,25 @@ func (m *mockWaterRepo) WaterTotalForLocalDay(ctx context.Context, userID int64,
 	return 2.5, nil
 }
 
-type mockUserRepo struct{}
+type mockUserRepo struct {
+	users []*domain.User
+}
 
 func (m *mockUserRepo) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	for _, u := range m.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
 	return nil, nil
 }
 
 func (m *mockUserRepo) GetByID(ctx context.Context, id int64) (*domain.User, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
 	return nil, nil
 }
 
@@ -115,6 +208,14 @@ func (m *mockUserRepo) Count(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	return nil
+}
+
+func (m *mockUserRepo) DeleteUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
 type mockSessionRepo struct{}
 
 func (m *mockSessionRepo) Create(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
@@ -133,6 +234,26 @@ func (m *mockSessionRepo) DeleteExpired(ctx context.Context) error {
 	return nil
 }
 
+func (m *mockSessionRepo) ListByUser(ctx context.Context, userID int64) ([]domain.Session, error) {
+	return nil, nil
+}
+
+func (m *mockSessionRepo) Rename(ctx context.Context, userID, id int64, name string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockSessionRepo) DeleteByID(ctx context.Context, userID, id int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockSessionRepo) DeleteByUser(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockSessionRepo) Touch(ctx context.Context, token string, at time.Time) error {
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // Test-server helper
 // ---------------------------------------------------------------------------
@@ -196,6 +317,190 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestHealthAccess(t *testing.T) {
+	if _, err := adapthttp.ParseHealthAccess("token", ""); err == nil {
+		t.Error("expected token access without a token to fail")
+	}
+	if _, err := adapthttp.ParseHealthAccess("10.0.0.0/8,intranet", ""); err == nil {
+		t.Error("expected an invalid network to fail")
+	}
+	if _, err := adapthttp.ParseHealthAccess(" , ", ""); err == nil {
+		t.Error("expected a list without networks to fail")
+	}
+	networks, err := adapthttp.ParseHealthAccess("10.0.0.0/8, 192.168.1.5", "")
+	if err != nil {
+		t.Fatalf("ParseHealthAccess: %v", err)
+	}
+	token, _ := adapthttp.ParseHealthAccess("token", "s3cret")
+
+	local, _ := adapthttp.ParseHealthAccess("127.0.0.1,::1", "")
+	proxies, _ := adapthttp.ParseTrustedProxies("127.0.0.1")
+
+	status := func(a adapthttp.HealthAccess, remote, forwarded, auth string) int {
+		t.Helper()
+		h := adapthttp.New(nil, nil, nil, nil, t.TempDir()).
+			WithHealthAccess(a).
+			WithTrustedProxies(proxies).
+			Handler()
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, tc := range []struct {
+		name      string
+		access    adapthttp.HealthAccess
+		remote    string
+		forwarded string
+		auth      string
+		want      int
+	}{
+		{"public", adapthttp.HealthAccess{}, "203.0.113.9:4000", "", "", http.StatusOK},
+		{"in network", networks, "10.1.2.3:4000", "", "", http.StatusOK},
+		{"listed address", networks, "192.168.1.5:4000", "", "", http.StatusOK},
+		{"unlisted loopback", networks, "[::1]:4000", "", "", http.StatusNotFound},
+		{"listed loopback", local, "[::1]:4000", "", "", http.StatusOK},
+		{"outside", networks, "203.0.113.9:4000", "", "", http.StatusNotFound},
+		{"proxied from network", networks, "127.0.0.1:4000", "10.1.2.3", "", http.StatusOK},
+		{"proxied from outside", networks, "127.0.0.1:4000", "203.0.113.9", "", http.StatusNotFound},
+		{"proxied to loopback", local, "127.0.0.1:4000", "203.0.113.9", "", http.StatusNotFound},
+		{"token", token, "203.0.113.9:4000", "", "Bearer s3cret", http.StatusOK},
+		{"wrong token", token, "127.0.0.1:4000", "", "Bearer guess", http.StatusNotFound},
+		{"no token", token, "127.0.0.1:4000", "", "", http.StatusNotFound},
+	} {
+		if got := status(tc.access, tc.remote, tc.forwarded, tc.auth); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestUnversionedDeprecation(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSunset(time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, map[string]any) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp, decodeBody(t, resp)
+	}
+
+	resp, _ := get("/api/v1/weight/recent")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Deprecation") != "" {
+		t.Errorf("expected /api/v1 served without deprecation, got %d %v", resp.StatusCode, resp.Header)
+	}
+	for range 2 {
+		resp, _ = get("/api/weight/recent?limit=3")
+	}
+	h := resp.Header
+	if resp.StatusCode != http.StatusOK || h.Get("Deprecation") != "@1792022400" ||
+		h.Get("Sunset") != "Thu, 01 Apr 2027 00:00:00 GMT" || h.Get("Link") != `</api/v1/weight/recent>; rel="successor-version"` {
+		t.Errorf("expected deprecation headers on the unversioned path, got %d %v", resp.StatusCode, h)
+	}
+
+	_, body := get("/api/v1/admin/deprecated-usage")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 || body["sunset"] != "2027-04-01T00:00:00Z" {
+		t.Fatalf("expected one deprecated route, got %v", body)
+	}
+	if item := items[0].(map[string]any); item["route"] != "GET /api/weight/recent" || item["count"] != 2.0 || item["lastSeenAt"] == nil {
+		t.Errorf("unexpected usage %v", item)
+	}
+}
+
+func TestEmbedCharts(t *testing.T) {
+	if _, err := adapthttp.ParseFrameAncestors("https://dash.example.com/path"); err == nil {
+		t.Error("expected an ancestor with a path to fail")
+	}
+	if _, err := adapthttp.ParseFrameAncestors("dash.example.com"); err == nil {
+		t.Error("expected an ancestor without a scheme to fail")
+	}
+	origins, err := adapthttp.ParseFrameAncestors("https://dash.example.com/, http://homepage.lan:3000")
+	if err != nil || len(origins) != 2 {
+		t.Fatalf("ParseFrameAncestors = %v, %v", origins, err)
+	}
+
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithEmbeds(app.NewEmbedService([]byte("secret"))).
+		WithFrameAncestors(origins)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(path, payload string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/v1/weight/today", strings.NewReader(`{"value": 75.4, "unit": "kg"}`))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("logging weight failed: %v", err)
+	}
+	for name, payload := range map[string]string{
+		"too many days":  `{"days": 400, "unit": "kg", "expiresInDays": 30}`,
+		"no expiry":      `{"days": 14, "unit": "kg"}`,
+		"never expiring": `{"days": 14, "unit": "kg", "expiresInDays": 0}`,
+		"too long":       `{"days": 14, "unit": "kg", "expiresInDays": 366}`,
+	} {
+		if resp := post("/api/v1/embed", payload); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+	resp := post("/api/v1/embed", `{"days": 14, "unit": "kg", "expiresInDays": 30}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	body := decodeBody(t, resp)
+	link, _ := body["url"].(string)
+	if !strings.HasPrefix(link, "/embed/charts?t=") || body["expiresAt"] == nil {
+		t.Fatalf("unexpected embed link %v", body)
+	}
+
+	resp, err = http.Get(ts.URL + link)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "Weight 75.4 kg") || !strings.Contains(string(page), "<polyline") {
+		t.Errorf("unexpected embed page %d: %s", resp.StatusCode, page)
+	}
+	if got := resp.Header.Get("Content-Security-Policy"); got != "frame-ancestors 'self' https://dash.example.com http://homepage.lan:3000" {
+		t.Errorf("embed CSP = %q", got)
+	}
+	if got := resp.Header.Get("X-Frame-Options"); got != "" {
+		t.Errorf("expected no X-Frame-Options with frame ancestors, got %q", got)
+	}
+
+	resp, _ = http.Get(ts.URL + "/embed/charts?t=" + link[len(link)-10:])
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a forged token, got %d", resp.StatusCode)
+	}
+	resp, _ = http.Get(ts.URL + "/api/v1/health")
+	if resp.Header.Get("X-Frame-Options") != "SAMEORIGIN" || resp.Header.Get("Content-Security-Policy") != "frame-ancestors 'self'" {
+		t.Errorf("expected other responses to be same-origin only, got %v", resp.Header)
+	}
+}
+
 func TestWeightTodayGet(t *testing.T) {
 	ts := newTestServer(t, &mockWeightRepo{
 		latestFn: func(_ context.Context, _ int64, localDay string) (*domain.WeightEntry, error) {
@@ -224,6 +529,9 @@ func TestWeightTodayGet(t *testing.T) {
 	if _, ok := body["entry"]; !ok {
 		t.Fatal("response missing 'entry' field")
 	}
+	if trend, ok := body["trend"].([]any); !ok || len(trend) != 2 {
+		t.Fatalf("expected 7- and 30-day trends, got %v", body["trend"])
+	}
 }
 
 func TestWeightTodayPut(t *testing.T) {
@@ -327,165 +635,192 @@ func TestWeightRecent(t *testing.T) {
 	}
 }
 
-func TestWeightUndoLast(t *testing.T) {
+func TestWeightRecentDisplayUnit(t *testing.T) {
 	ts := newTestServer(t, &mockWeightRepo{
-		deleteFn: func(_ context.Context, _ int64) (bool, error) {
-			return true, nil
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{{ID: 2, Value: 176, Unit: "lb"}, {ID: 1, Value: 80, Unit: "kg"}}, nil
 		},
 	}, nil)
 	defer ts.Close()
 
-	resp, err := http.Post(ts.URL+"/api/weight/undo-last", "application/json", nil)
+	resp, err := http.Get(ts.URL + "/api/weight/recent?unit=kg")
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
 	}
-	defer resp.Body.Close() //nolint:errcheck
-
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	body := decodeBody(t, resp)
+	_ = resp.Body.Close()
+	items, _ := body["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %v", body)
+	}
+	first, second := items[0].(map[string]any), items[1].(map[string]any)
+	if first["unit"] != "lb" || first["value"] != 176.0 || first["displayUnit"] != "kg" || math.Abs(first["displayValue"].(float64)-79.83) > 0.01 {
+		t.Errorf("expected the lb entry converted to kg with its original kept, got %v", first)
+	}
+	if second["displayUnit"] != "kg" || second["displayValue"] != 80.0 {
+		t.Errorf("expected the kg entry unchanged, got %v", second)
 	}
 
-	body := decodeBody(t, resp)
-	if body["ok"] != true {
-		t.Fatalf("expected ok=true, got %v", body["ok"])
+	resp, err = http.Get(ts.URL + "/api/weight/recent?unit=stone")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
 	}
-	if body["deleted"] != true {
-		t.Fatalf("expected deleted=true, got %v", body["deleted"])
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusBadRequest || body["code"] != "invalid_unit" {
+		t.Errorf("unknown unit: expected 400 with invalid_unit, got %d %v", resp.StatusCode, body)
 	}
 }
 
-func TestWaterTodayGet(t *testing.T) {
-	ts := newTestServer(t, nil, &mockWaterRepo{
-		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) {
-			return 3.0, nil
+func TestWeightRecentFieldsAndCompact(t *testing.T) {
+	created := time.Date(2026, 2, 8, 7, 30, 0, 0, time.UTC)
+	ts := newTestServer(t, &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{{ID: 1, Day: "2026-02-08", Value: 80.0, Unit: "kg", CreatedAt: created}}, nil
 		},
-	})
+	}, nil)
 	defer ts.Close()
 
-	resp, err := http.Get(ts.URL + "/api/water/today")
+	resp, err := http.Get(ts.URL + "/api/weight/recent?fields=day,value")
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
 	}
-	defer resp.Body.Close() //nolint:errcheck
-
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	body := decodeBody(t, resp)
+	_ = resp.Body.Close()
+	item := body["items"].([]any)[0].(map[string]any)
+	if len(item) != 2 || item["day"] != "2026-02-08" || item["value"] != 80.0 {
+		t.Errorf("fields: unexpected item %v", item)
 	}
 
-	body := decodeBody(t, resp)
-	if _, ok := body["today"]; !ok {
-		t.Fatal("response missing 'today' field")
+	resp, err = http.Get(ts.URL + "/api/weight/recent?format=compact&fields=value,clientId")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
 	}
-	total, ok := body["totalLiters"].(float64)
-	if !ok {
-		t.Fatal("response missing 'totalLiters' field")
+	body = decodeBody(t, resp)
+	_ = resp.Body.Close()
+	if got := fmt.Sprint(body["fields"]); got != "[value clientId]" {
+		t.Errorf("compact fields = %s", got)
 	}
-	if total != 3.0 {
-		t.Fatalf("expected totalLiters=3.0, got %v", total)
+	if got := fmt.Sprint(body["items"]); got != "[[80 <nil>]]" {
+		t.Errorf("compact items = %s", got)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/weight/recent?fields=bogus")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown field: expected 400, got %d", resp.StatusCode)
 	}
 }
 
-func TestWaterEvent(t *testing.T) {
-	tests := []struct {
-		name       string
-		payload    map[string]any
-		wantStatus int
-	}{
-		{
-			name:       "valid positive",
-			payload:    map[string]any{"deltaLiters": 0.5},
-			wantStatus: http.StatusOK,
-		},
-		{
-			name:       "valid negative",
-			payload:    map[string]any{"deltaLiters": -0.25},
-			wantStatus: http.StatusOK,
-		},
-		{
-			name:       "zero deltaLiters",
-			payload:    map[string]any{"deltaLiters": 0},
-			wantStatus: http.StatusBadRequest,
-		},
-		{
-			name:       "too large",
-			payload:    map[string]any{"deltaLiters": 11.0},
-			wantStatus: http.StatusBadRequest,
-		},
+func TestRecentListSummary(t *testing.T) {
+	db := memory.New()
+	ctx := context.Background()
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, _ = db.AddWeightEvent(ctx, 0, 80+float64(i), "kg", first.AddDate(0, 0, i))
+		_, _ = db.AddWaterEvent(ctx, 0, 0.5, first.AddDate(0, 0, i))
 	}
 
-	ts := newTestServer(t, nil, nil)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
 	defer ts.Close()
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			b, _ := json.Marshal(tc.payload)
-			resp, err := http.Post(ts.URL+"/api/water/event", "application/json", bytes.NewReader(b))
-			if err != nil {
-				t.Fatalf("request failed: %v", err)
-			}
-			defer resp.Body.Close() //nolint:errcheck
-
-			if resp.StatusCode != tc.wantStatus {
-				body := decodeBody(t, resp)
-				t.Fatalf("expected %d, got %d; body: %v", tc.wantStatus, resp.StatusCode, body)
-			}
+	for _, path := range []string{"/api/weight/recent?limit=2", "/api/water/recent?limit=2"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body := decodeBody(t, resp)
+		_ = resp.Body.Close()
+		if items, _ := body["items"].([]any); len(items) != 2 {
+			t.Errorf("%s: expected 2 items, got %v", path, body)
+		}
+		if body["total"] != 3.0 || body["hasMore"] != true ||
+			body["firstAt"] != "2026-03-01T12:00:00Z" || body["lastAt"] != "2026-03-03T12:00:00Z" {
+			t.Errorf("%s: unexpected summary %v", path, body)
+		}
+	}
 
-			if tc.wantStatus == http.StatusOK {
-				body := decodeBody(t, resp)
-				if _, ok := body["id"]; !ok {
-					t.Fatal("response missing 'id' field")
-				}
-			}
-		})
+	resp, err := http.Get(ts.URL + "/api/weight/recent?limit=5&format=compact")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := decodeBody(t, resp)
+	_ = resp.Body.Close()
+	if body["total"] != 3.0 || body["hasMore"] != false {
+		t.Errorf("expected the summary kept in compact form, got %v", body)
 	}
 }
 
-func TestWaterRecent(t *testing.T) {
-	events := []domain.WaterEvent{
-		{ID: 10, DeltaLiters: 0.5, CreatedAt: time.Now()},
-		{ID: 11, DeltaLiters: 0.3, CreatedAt: time.Now()},
-	}
-	ts := newTestServer(t, nil, &mockWaterRepo{
-		listFn: func(_ context.Context, _ int64, limit int) ([]domain.WaterEvent, error) {
-			if limit < len(events) {
-				return events[:limit], nil
-			}
-			return events, nil
+func TestWeightRecentRejectsInvalidLimit(t *testing.T) {
+	ts := newTestServer(t, &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			t.Error("repository should not be queried for an invalid limit")
+			return nil, nil
 		},
-	})
+	}, nil)
 	defer ts.Close()
 
-	resp, err := http.Get(ts.URL + "/api/water/recent?limit=10")
+	for _, limit := range []string{"100000", "0", "-3", "abc"} {
+		resp, err := http.Get(ts.URL + "/api/weight/recent?limit=" + limit)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body := decodeBody(t, resp)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("limit=%s: expected 400, got %d", limit, resp.StatusCode)
+		}
+		if body["param"] != "limit" || body["value"] != limit || body["max"] != float64(500) {
+			t.Errorf("limit=%s: unexpected error body %v", limit, body)
+		}
+	}
+}
+
+func TestChartsDailyProtobuf(t *testing.T) {
+	ts := newTestServer(t, nil, nil)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/charts/daily?days=3&unit=kg", nil)
+	req.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
 	}
 	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Fatalf("expected protobuf content type, got %q", ct)
 	}
-
-	body := decodeBody(t, resp)
-	arr, ok := body["items"].([]any)
-	if !ok {
-		t.Fatal("response missing 'items' array")
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if len(arr) != 2 {
-		t.Fatalf("expected 2 items, got %d", len(arr))
+	var chart pb.DailyChart
+	if err := proto.Unmarshal(b, &chart); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if chart.GetDays() != 3 || chart.GetUnit() != "kg" || len(chart.GetItems()) != 3 {
+		t.Fatalf("unexpected chart: %v", &chart)
+	}
+	if w := chart.GetItems()[2].GetWeight(); w.GetValue() != 80.0 || chart.GetItems()[2].GetWaterLiters() != 2.5 {
+		t.Errorf("unexpected point: %v", chart.GetItems()[2])
 	}
 }
 
-func TestWaterUndoLast(t *testing.T) {
-	ts := newTestServer(t, nil, &mockWaterRepo{
-		listFn: func(_ context.Context, _ int64, limit int) ([]domain.WaterEvent, error) {
-			return []domain.WaterEvent{
-				{ID: 99, DeltaLiters: 0.5, CreatedAt: time.Now()},
-			}, nil
+func TestWeightUndoLast(t *testing.T) {
+	ts := newTestServer(t, &mockWeightRepo{
+		deleteFn: func(_ context.Context, _ int64) (bool, error) {
+			return true, nil
 		},
-	})
+	}, nil)
 	defer ts.Close()
 
-	resp, err := http.Post(ts.URL+"/api/water/undo-last", "application/json", nil)
+	resp, err := http.Post(ts.URL+"/api/weight/undo-last", "application/json", nil)
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
 	}
@@ -496,47 +831,3142 @@ func TestWaterUndoLast(t *testing.T) {
 	}
 
 	body := decodeBody(t, resp)
-	if body["undone"] != true {
-		t.Fatalf("expected undone=true, got %v", body["undone"])
+	if body["ok"] != true {
+		t.Fatalf("expected ok=true, got %v", body["ok"])
 	}
-	if id, ok := body["id"].(float64); !ok || id != 99 {
-		t.Fatalf("expected id=99, got %v", body["id"])
+	if body["deleted"] != true {
+		t.Fatalf("expected deleted=true, got %v", body["deleted"])
 	}
 }
 
-func TestMethodNotAllowed(t *testing.T) {
-	ts := newTestServer(t, nil, nil)
+func TestWeightCrossUserIsolation(t *testing.T) {
+	db := memory.New()
+	users := &mockUserRepo{users: []*domain.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}}}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(users, &mockSessionRepo{}), t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
 	defer ts.Close()
 
-	tests := []struct {
-		name   string
+	do := func(user, method, path string, payload any) map[string]any {
+		t.Helper()
+		var body io.Reader
+		if payload != nil {
+			b, _ := json.Marshal(payload)
+			body = bytes.NewReader(b)
+		}
+		req, _ := http.NewRequest(method, ts.URL+path, body)
+		req.Header.Set("Remote-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != http.StatusOK {
+			raw, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s %s as %s: expected 200, got %d: %s", method, path, user, resp.StatusCode, raw)
+		}
+		return decodeBody(t, resp)
+	}
+	todayValue := func(user string) any {
+		t.Helper()
+		entry, _ := do(user, http.MethodGet, "/api/weight/today", nil)["entry"].(map[string]any)
+		if entry == nil {
+			return nil
+		}
+		return entry["value"]
+	}
+	recent := func(user string) []any {
+		t.Helper()
+		items, _ := do(user, http.MethodGet, "/api/weight/recent", nil)["items"].([]any)
+		return items
+	}
+
+	do("alice", http.MethodPut, "/api/weight/today", map[string]any{"value": 61.5, "unit": "kg"})
+	if v := todayValue("bob"); v != nil {
+		t.Fatalf("bob saw alice's weight %v", v)
+	}
+	if items := recent("bob"); len(items) != 0 {
+		t.Fatalf("bob saw alice's entries: %v", items)
+	}
+
+	do("bob", http.MethodPut, "/api/weight/today", map[string]any{"value": 90, "unit": "kg"})
+	if v := todayValue("alice"); v != 61.5 {
+		t.Fatalf("expected alice's weight 61.5, got %v", v)
+	}
+	if v := todayValue("bob"); v != 90.0 {
+		t.Fatalf("expected bob's weight 90, got %v", v)
+	}
+
+	// Undoing twice empties bob's history and never reaches alice's.
+	do("bob", http.MethodPost, "/api/weight/undo-last", nil)
+	if body := do("bob", http.MethodPost, "/api/weight/undo-last", nil); body["deleted"] != false {
+		t.Fatalf("expected nothing left to undo for bob, got %v", body)
+	}
+	if items := recent("alice"); len(items) != 1 {
+		t.Fatalf("expected alice's entry to survive bob's undo, got %v", items)
+	}
+
+	// Naming another user without a share is refused.
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/weight/recent?user=1", nil)
+	req.Header.Set("Remote-User", "bob")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 reading another user's weights, got %d", resp.StatusCode)
+	}
+}
+
+func TestWaterTodayGet(t *testing.T) {
+	ts := newTestServer(t, nil, &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) {
+			return 3.0, nil
+		},
+	})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/water/today")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := decodeBody(t, resp)
+	if _, ok := body["today"]; !ok {
+		t.Fatal("response missing 'today' field")
+	}
+	total, ok := body["totalLiters"].(float64)
+	if !ok {
+		t.Fatal("response missing 'totalLiters' field")
+	}
+	if total != 3.0 {
+		t.Fatalf("expected totalLiters=3.0, got %v", total)
+	}
+}
+
+func TestWaterTodayPace(t *testing.T) {
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) {
+			return 1.0, nil
+		},
+	}
+	wr := &mockWeightRepo{}
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithHydration(app.NewHydrationService(memory.New()))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/water/today")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body := decodeBody(t, resp)
+
+	pace, ok := body["pace"].(map[string]any)
+	if !ok {
+		t.Fatalf("response missing 'pace' with a goal: %v", body)
+	}
+	switch pace["status"] {
+	case domain.PaceAhead, domain.PaceOnTrack, domain.PaceBehind:
+	default:
+		t.Errorf("unexpected pace status %v", pace["status"])
+	}
+	expected, _ := pace["expectedLiters"].(float64)
+	if delta, _ := pace["deltaLiters"].(float64); math.Abs(1.0-expected-delta) > 0.001 {
+		t.Errorf("expected deltaLiters to be intake minus expectedLiters, got %v", pace)
+	}
+}
+
+func TestRequestSchemaValidation(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithNutrition(app.NewNutritionService(db)).
+		WithBatch(app.NewBatchService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(path, payload string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+	fieldsOf := func(body map[string]any) map[string]string {
+		out := map[string]string{}
+		fields, _ := body["fields"].([]any)
+		for _, f := range fields {
+			f, _ := f.(map[string]any)
+			out[f["field"].(string)], _ = f["message"].(string)
+		}
+		return out
+	}
+
+	code, body := post("/api/food/event", `{"kcal":20000,"fatG":"lots","extra":1}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %v", code, body)
+	}
+	fields := fieldsOf(body)
+	for _, f := range []string{"/description", "/kcal", "/fatG", "/extra"} {
+		if fields[f] == "" {
+			t.Errorf("expected an error for %s, got %v", f, body)
+		}
+	}
+	if fields["/description"] != "is required" || fields["/extra"] != "is not allowed" {
+		t.Errorf("unexpected messages %v", fields)
+	}
+
+	code, body = post("/api/batch", `{"ops":[{"op":"weight","value":80,"unit":"kg"},{"op":"weight","value":80},{"op":"deleteWater","id":0}]}`)
+	fields = fieldsOf(body)
+	if code != http.StatusBadRequest || fields["/ops/1/unit"] != "is required" || fields["/ops/2/id"] == "" || len(fields) != 2 {
+		t.Errorf("expected errors for the second and third ops only, got %d %v", code, body)
+	}
+
+	if code, body := post("/api/food/event", `{"description":"eggs",`); code != http.StatusBadRequest || body["fields"] != nil {
+		t.Errorf("expected a plain 400 for malformed JSON, got %d %v", code, body)
+	}
+	if code, body := post("/api/food/event", `{"description":"eggs","kcal":140}`); code != http.StatusOK {
+		t.Errorf("expected a valid body to pass, got %d %v", code, body)
+	}
+	if code, body := post("/api/food/event", `{"description":"`+strings.Repeat("x", 2<<20)+`"}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized body, got %d %v", code, body)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	doc := decodeBody(t, resp)
+	paths, _ := doc["paths"].(map[string]any)
+	food, _ := paths["/api/v1/food/event"].(map[string]any)
+	if doc["openapi"] != "3.1.0" || food["post"] == nil {
+		t.Errorf("expected the food schema in the OpenAPI document, got %v", paths["/api/v1/food/event"])
+	}
+	recent, _ := paths["/api/v1/weight/recent"].(map[string]any)
+	get, _ := recent["get"].(map[string]any)
+	params, _ := get["parameters"].([]any)
+	if len(params) != 1 || fmt.Sprint(params[0]) != "map[in:query name:limit schema:map[maximum:500 minimum:1 type:integer]]" {
+		t.Errorf("expected the limit parameter of weight/recent, got %v", recent)
+	}
+	tags, _ := paths["/api/v1/weight/{id}/tags"].(map[string]any)
+	put, _ := tags["put"].(map[string]any)
+	if params, _ := put["parameters"].([]any); len(params) != 1 || params[0].(map[string]any)["in"] != "path" {
+		t.Errorf("expected the id path parameter, got %v", tags)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/export/weight.csv?from=1%20March")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = decodeBody(t, resp)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || body["param"] != "from" || body["error"] != "from must be YYYY-MM-DD" || body["max"] != nil {
+		t.Errorf("expected the malformed date rejected, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestFoodEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithNutrition(app.NewNutritionService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, _ := do(http.MethodPost, "/api/food/event", `{"description":"","kcal":100}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a description, got %d", code)
+	}
+	do(http.MethodPost, "/api/food/event", `{"description":"eggs","kcal":140,"proteinG":12,"fatG":10}`)
+	code, body := do(http.MethodPost, "/api/food/event", `{"description":"toast","kcal":80,"carbsG":15}`)
+	if code != http.StatusOK || body["created"] != true {
+		t.Fatalf("expected the entry to be created, got %d %v", code, body)
+	}
+
+	_, body = do(http.MethodGet, "/api/food/today", "")
+	totals, _ := body["totals"].(map[string]any)
+	if totals["kcal"] != 220.0 || totals["proteinG"] != 12.0 || totals["entries"] != 2.0 {
+		t.Errorf("unexpected totals %v", body)
+	}
+
+	_, body = do(http.MethodPost, "/api/food/undo-last", "")
+	if body["undone"] != true {
+		t.Fatalf("expected undo, got %v", body)
+	}
+	_, body = do(http.MethodGet, "/api/food/recent", "")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["description"] != "eggs" {
+		t.Errorf("expected only eggs left, got %v", body["items"])
+	}
+}
+
+func TestMoodEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db).WithMood(db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithMood(app.NewMoodService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if _, body := do(http.MethodGet, "/api/mood/today", ""); body["entry"] != nil {
+		t.Errorf("expected no mood yet, got %v", body)
+	}
+	if code, _ := do(http.MethodPut, "/api/mood/today", `{"score":6}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a score above 5, got %d", code)
+	}
+	do(http.MethodPut, "/api/mood/today", `{"score":2}`)
+	code, body := do(http.MethodPut, "/api/mood/today", `{"score":4,"note":"good run"}`)
+	entry, _ := body["entry"].(map[string]any)
+	if code != http.StatusOK || entry["score"] != 4.0 || entry["note"] != "good run" {
+		t.Fatalf("expected the mood recorded, got %d %v", code, body)
+	}
+
+	_, body = do(http.MethodGet, "/api/mood/recent", "")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["score"] != 4.0 {
+		t.Errorf("expected today's mood replaced, got %v", body["items"])
+	}
+
+	_, body = do(http.MethodGet, "/api/charts/daily?days=1", "")
+	items, _ = body["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["mood"] != 4.0 {
+		t.Errorf("expected today's mood in the chart, got %v", body["items"])
+	}
+}
+
+func TestStepsEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db).WithSteps(db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSteps(app.NewStepsService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if _, body := do(http.MethodGet, "/api/steps/today", ""); body["entry"] != nil {
+		t.Errorf("expected no steps yet, got %v", body)
+	}
+	if code, _ := do(http.MethodPut, "/api/steps/today", `{"steps":-5}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative count, got %d", code)
+	}
+	do(http.MethodPut, "/api/steps/today", `{"steps":3000}`)
+	code, body := do(http.MethodPut, "/api/steps/today", `{"steps":8432}`)
+	entry, _ := body["entry"].(map[string]any)
+	if code != http.StatusOK || entry["steps"] != 8432.0 {
+		t.Fatalf("expected the steps recorded, got %d %v", code, body)
+	}
+
+	_, body = do(http.MethodGet, "/api/charts/daily?days=1", "")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["steps"] != 8432.0 {
+		t.Errorf("expected today's steps in the chart, got %v", body["items"])
+	}
+}
+
+func TestTemperatureEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithTemperature(app.NewTemperatureService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, _ := do(http.MethodPost, "/api/temperature/event", `{"value":98.6,"unit":"c"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an implausible reading, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, "/api/temperature/event", `{"value":37,"unit":"kelvin"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown unit, got %d", code)
+	}
+	code, body := do(http.MethodPost, "/api/temperature/event", `{"value":100.4,"unit":"f"}`)
+	if code != http.StatusOK || body["created"] != true {
+		t.Fatalf("expected the reading recorded, got %d %v", code, body)
+	}
+	id := int64(body["id"].(float64))
+
+	if code, _ := do(http.MethodGet, "/api/temperature/recent?unit=k", ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown display unit, got %d", code)
+	}
+	_, body = do(http.MethodGet, "/api/temperature/recent?unit=c", "")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected one reading, got %v", body)
+	}
+	reading := items[0].(map[string]any)
+	if reading["value"] != 100.4 || reading["displayUnit"] != "c" || math.Abs(reading["displayValue"].(float64)-38) > 0.001 {
+		t.Errorf("expected 100.4 °F displayed as 38 °C, got %v", reading)
+	}
+
+	if code, _ := do(http.MethodDelete, fmt.Sprintf("/api/temperature/%d", id), ""); code != http.StatusOK {
+		t.Errorf("expected 200 deleting the reading, got %d", code)
+	}
+	if code, _ := do(http.MethodDelete, fmt.Sprintf("/api/temperature/%d", id), ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting it again, got %d", code)
+	}
+}
+
+func TestCustomMetricEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithMetrics(app.NewMetricsService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, _ := do(http.MethodPost, "/api/metrics", `{"name":"Coffee","aggregation":"max"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown aggregation, got %d", code)
+	}
+	code, body := do(http.MethodPost, "/api/metrics", `{"name":"Coffee (cups)","unit":"cups","aggregation":"sum"}`)
+	metric, _ := body["metric"].(map[string]any)
+	if code != http.StatusCreated || metric["slug"] != "coffee-cups" {
+		t.Fatalf("expected the metric defined, got %d %v", code, body)
+	}
+	if code, _ := do(http.MethodPost, "/api/metrics", `{"name":"Coffee","slug":"coffee-cups","aggregation":"sum"}`); code != http.StatusConflict {
+		t.Errorf("expected 409 for a taken slug, got %d", code)
+	}
+
+	if _, body := do(http.MethodGet, "/api/metrics/coffee-cups/today", ""); body["value"] != nil || body["count"] != 0.0 {
+		t.Errorf("expected no value yet, got %v", body)
+	}
+	do(http.MethodPost, "/api/metrics/coffee-cups/event", `{"value":1}`)
+	if code, body := do(http.MethodPost, "/api/metrics/coffee-cups/event", `{"value":2}`); code != http.StatusOK || body["created"] != true {
+		t.Fatalf("expected the value recorded, got %d %v", code, body)
+	}
+	if code, _ := do(http.MethodPost, "/api/metrics/tea/event", `{"value":1}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown metric, got %d", code)
+	}
+	_, body = do(http.MethodGet, "/api/metrics/coffee-cups/today", "")
+	if body["value"] != 3.0 || body["count"] != 2.0 {
+		t.Errorf("expected today's sum of 3 over 2 events, got %v", body)
+	}
+	_, body = do(http.MethodGet, "/api/metrics/coffee-cups/recent?limit=1", "")
+	if items, _ := body["items"].([]any); len(items) != 1 || items[0].(map[string]any)["value"] != 2.0 {
+		t.Errorf("expected the latest value, got %v", body)
+	}
+
+	if code, _ := do(http.MethodDelete, "/api/metrics/coffee-cups", ""); code != http.StatusOK {
+		t.Errorf("expected 200 deleting the metric, got %d", code)
+	}
+	if _, body := do(http.MethodGet, "/api/metrics", ""); len(body["items"].([]any)) != 0 {
+		t.Errorf("expected no metrics left, got %v", body)
+	}
+}
+
+func TestMedicationEndpoints(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithMedications(app.NewMedicationService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, _ := do(http.MethodPost, "/api/meds/definitions", `{"name":"Vitamin D","schedule":["8am"]}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed schedule time, got %d", code)
+	}
+	code, body := do(http.MethodPost, "/api/meds/definitions", `{"name":"Vitamin D","dose":"1000 IU","schedule":["20:00","08:00"]}`)
+	med, _ := body["medication"].(map[string]any)
+	if code != http.StatusCreated || fmt.Sprint(med["schedule"]) != "[08:00 20:00]" {
+		t.Fatalf("expected the medication created with a sorted schedule, got %d %v", code, body)
+	}
+	id := int64(med["id"].(float64))
+
+	code, body = do(http.MethodPut, fmt.Sprintf("/api/meds/definitions/%d", id), `{"name":"Vitamin D3","dose":"2000 IU"}`)
+	med, _ = body["medication"].(map[string]any)
+	if code != http.StatusOK || med["name"] != "Vitamin D3" || fmt.Sprint(med["schedule"]) != "[]" {
+		t.Errorf("expected the medication replaced, got %d %v", code, body)
+	}
+	if code, _ := do(http.MethodPut, "/api/meds/definitions/999", `{"name":"Iron"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown medication, got %d", code)
+	}
+
+	if code, _ := do(http.MethodPost, "/api/meds/event", `{"medicationId":999,"status":"taken"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 when logging an unknown medication, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, "/api/meds/event", fmt.Sprintf(`{"medicationId":%d,"status":"forgot"}`, id)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown status, got %d", code)
+	}
+	event := fmt.Sprintf(`{"medicationId":%d,"status":"taken","clientId":"6f1c2f4e-8a7b-4c1d-9e2f-3a4b5c6d7e8f"}`, id)
+	if _, body := do(http.MethodPost, "/api/meds/event", event); body["created"] != true {
+		t.Errorf("expected the dose logged, got %v", body)
+	}
+	if _, body := do(http.MethodPost, "/api/meds/event", event); body["created"] != false {
+		t.Errorf("expected a retry to be deduplicated, got %v", body)
+	}
+
+	_, body = do(http.MethodGet, fmt.Sprintf("/api/meds/recent?medicationId=%d", id), "")
+	items, _ := body["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["status"] != "taken" {
+		t.Errorf("expected one logged dose, got %v", body["items"])
+	}
+
+	if code, _ := do(http.MethodDelete, fmt.Sprintf("/api/meds/definitions/%d", id), ""); code != http.StatusOK {
+		t.Errorf("expected the medication deleted, got %d", code)
+	}
+	_, body = do(http.MethodGet, "/api/meds/recent", "")
+	if items, _ := body["items"].([]any); len(items) != 0 {
+		t.Errorf("expected its doses deleted with it, got %v", body["items"])
+	}
+}
+
+type mockChangeRepo struct {
+	latest *domain.Change
+}
+
+func (m *mockChangeRepo) ListChanges(ctx context.Context, userID, since int64, limit int) ([]domain.Change, error) {
+	return nil, nil
+}
+
+func (m *mockChangeRepo) LatestChange(ctx context.Context, userID int64, entity string) (*domain.Change, error) {
+	return m.latest, nil
+}
+
+func TestTodayConditionalRequests(t *testing.T) {
+	var totals int
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) {
+			totals++
+			return 1.5, nil
+		},
+	}
+	wr := &mockWeightRepo{}
+	changes := &mockChangeRepo{latest: &domain.Change{Seq: 7, Entity: domain.ChangeEntityWater, ChangedAt: time.Now().Add(-time.Minute)}}
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSync(app.NewSyncService(changes))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(header, value string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/water/today", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	first := get("", "")
+	etag, lastModified := first.Header.Get("ETag"), first.Header.Get("Last-Modified")
+	if first.StatusCode != http.StatusOK || etag == "" || lastModified == "" {
+		t.Fatalf("expected 200 with validators, got %d etag=%q last-modified=%q", first.StatusCode, etag, lastModified)
+	}
+
+	if resp := get("If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("If-None-Match: expected 304, got %d", resp.StatusCode)
+	}
+	if resp := get("If-Modified-Since", lastModified); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: expected 304, got %d", resp.StatusCode)
+	}
+	if totals != 1 {
+		t.Errorf("expected the total to be computed once, got %d", totals)
+	}
+
+	changes.latest = &domain.Change{Seq: 8, Entity: domain.ChangeEntityWater, ChangedAt: time.Now()}
+	if resp := get("If-None-Match", etag); resp.StatusCode != http.StatusOK {
+		t.Fatalf("after a change: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	db := memory.New()
+	syncSvc := app.NewSyncService(db)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db).WithPublisher(syncSvc), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSync(syncSvc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func() {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/water/event", "application/json", strings.NewReader(`{"deltaLiters":0.25}`))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close() //nolint:errcheck
+	}
+	post()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events/stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	// Only changes after the stream opened are sent.
+	post()
+	lines := bufio.NewScanner(resp.Body)
+	var event []string
+	for lines.Scan() && lines.Text() != "" {
+		event = append(event, lines.Text())
+	}
+	if len(event) != 3 || event[0] != "id: 2" || event[1] != "event: change" || !strings.Contains(event[2], `"deltaLiters":0.25`) {
+		t.Fatalf("unexpected event %q", event)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/events/stream?since=x")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cursor, got %d", resp.StatusCode)
+	}
+}
+
+func TestSyncCursorExpired(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSync(app.NewSyncService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	_, _ = db.AddWaterEvent(ctx, 0, 0.5, time.Now())
+	gone, _ := db.AddWaterEvent(ctx, 0, 0.25, time.Now())
+	_ = db.DeleteWaterEvent(ctx, 0, gone)
+	if _, err := db.PurgeOldChanges(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client that synced before the purged deletion must start over.
+	for _, path := range []string{"/api/sync?since=1", "/api/events/stream?since=1"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body struct {
+			Code string `json:"code"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusGone || body.Code != "cursor_expired" {
+			t.Errorf("%s: expected 410 cursor_expired, got %d %q", path, resp.StatusCode, body.Code)
+		}
+	}
+	resp, err := http.Get(ts.URL + "/api/sync?since=0")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a full sync to work, got %d", resp.StatusCode)
+	}
+}
+
+func TestWaterEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    map[string]any
+		wantStatus int
+	}{
+		{
+			name:       "valid positive",
+			payload:    map[string]any{"deltaLiters": 0.5},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid negative",
+			payload:    map[string]any{"deltaLiters": -0.25},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "zero deltaLiters",
+			payload:    map[string]any{"deltaLiters": 0},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "too large",
+			payload:    map[string]any{"deltaLiters": 11.0},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "with client id",
+			payload:    map[string]any{"deltaLiters": 0.5, "clientId": "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "malformed client id",
+			payload:    map[string]any{"deltaLiters": 0.5, "clientId": "not-a-uuid"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	ts := newTestServer(t, nil, nil)
+	defer ts.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _ := json.Marshal(tc.payload)
+			resp, err := http.Post(ts.URL+"/api/water/event", "application/json", bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.wantStatus {
+				body := decodeBody(t, resp)
+				t.Fatalf("expected %d, got %d; body: %v", tc.wantStatus, resp.StatusCode, body)
+			}
+
+			if tc.wantStatus == http.StatusOK {
+				body := decodeBody(t, resp)
+				if _, ok := body["id"]; !ok {
+					t.Fatal("response missing 'id' field")
+				}
+			}
+		})
+	}
+}
+
+func TestWaterEventDuplicate(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db).WithDuplicateGuard(5*time.Second, true),
+		app.NewChartsService(db, db), app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func() map[string]any {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/water/event", "application/json", strings.NewReader(`{"deltaLiters": 0.25}`))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return decodeBody(t, resp)
+	}
+	first := post()
+	if _, ok := first["duplicate"]; ok {
+		t.Fatalf("expected the first tap not flagged, got %v", first)
+	}
+	second := post()
+	if second["duplicate"] != true || second["merged"] != true || second["id"] != first["id"] {
+		t.Fatalf("expected the double tap merged into the first event, got %v", second)
+	}
+	if total, _ := db.WaterTotalForLocalDay(context.Background(), 0, time.Now().Format("2006-01-02")); total != 0.25 {
+		t.Errorf("expected one event stored, got a total of %v L", total)
+	}
+}
+
+func TestWaterEventEdit(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	_, body := do(http.MethodPost, "/api/water/event", `{"deltaLiters":0.5}`)
+	path := fmt.Sprintf("/api/water/%v", body["id"])
+
+	code, body := do(http.MethodPatch, path, `{"deltaLiters":0.3}`)
+	event, _ := body["event"].(map[string]any)
+	if code != http.StatusOK || event["deltaLiters"] != 0.3 {
+		t.Fatalf("expected the volume corrected, got %d %v", code, body)
+	}
+	if _, body := do(http.MethodGet, "/api/water/today", ""); body["totalLiters"] != 0.3 {
+		t.Errorf("expected today's total to follow the edit, got %v", body)
+	}
+
+	for _, tc := range []struct {
+		path, payload string
+		want          int
+	}{
+		{path, `{}`, http.StatusBadRequest},
+		{path, `{"deltaLiters":0}`, http.StatusBadRequest},
+		{path, `{"clientId":"0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c"}`, http.StatusBadRequest},
+		{"/api/water/abc", `{"deltaLiters":0.2}`, http.StatusBadRequest},
+		{"/api/water/999", `{"deltaLiters":0.2}`, http.StatusNotFound},
+	} {
+		if code, body := do(http.MethodPatch, tc.path, tc.payload); code != tc.want {
+			t.Errorf("PATCH %s %s: expected %d, got %d %v", tc.path, tc.payload, tc.want, code, body)
+		}
+	}
+	if _, body := do(http.MethodPatch, "/api/water/999", `{"deltaLiters":0.2}`); body["code"] != "not_found" || body["error"] != "entry not found" {
+		t.Errorf("expected the not_found code with the message, got %v", body)
+	}
+}
+
+func TestWaterRecent(t *testing.T) {
+	events := []domain.WaterEvent{
+		{ID: 10, DeltaLiters: 0.5, CreatedAt: time.Now()},
+		{ID: 11, DeltaLiters: 0.3, CreatedAt: time.Now()},
+	}
+	ts := newTestServer(t, nil, &mockWaterRepo{
+		listFn: func(_ context.Context, _ int64, limit int) ([]domain.WaterEvent, error) {
+			if limit < len(events) {
+				return events[:limit], nil
+			}
+			return events, nil
+		},
+	})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/water/recent?limit=10")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := decodeBody(t, resp)
+	arr, ok := body["items"].([]any)
+	if !ok {
+		t.Fatal("response missing 'items' array")
+	}
+	if len(arr) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(arr))
+	}
+}
+
+func TestWaterUndoLast(t *testing.T) {
+	ts := newTestServer(t, nil, &mockWaterRepo{
+		delLatestFn: func(_ context.Context, _ int64) (int64, error) {
+			return 99, nil
+		},
+	})
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/water/undo-last", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	body := decodeBody(t, resp)
+	if body["undone"] != true {
+		t.Fatalf("expected undone=true, got %v", body["undone"])
+	}
+	if id, ok := body["id"].(float64); !ok || id != 99 {
+		t.Fatalf("expected id=99, got %v", body["id"])
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	ts := newTestServer(t, nil, nil)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
 		method string
 		path   string
 	}{
-		{"DELETE weight/today", http.MethodDelete, "/api/weight/today"},
-		{"POST weight/recent", http.MethodPost, "/api/weight/recent"},
-		{"GET weight/undo-last", http.MethodGet, "/api/weight/undo-last"},
-		{"PUT water/today", http.MethodPut, "/api/water/today"},
-		{"GET water/event", http.MethodGet, "/api/water/event"},
-		{"POST water/recent", http.MethodPost, "/api/water/recent"},
-		{"GET water/undo-last", http.MethodGet, "/api/water/undo-last"},
+		{"DELETE weight/today", http.MethodDelete, "/api/weight/today"},
+		{"POST weight/recent", http.MethodPost, "/api/weight/recent"},
+		{"GET weight/undo-last", http.MethodGet, "/api/weight/undo-last"},
+		{"PUT water/today", http.MethodPut, "/api/water/today"},
+		{"GET water/event", http.MethodGet, "/api/water/event"},
+		{"POST water/recent", http.MethodPost, "/api/water/recent"},
+		{"GET water/undo-last", http.MethodGet, "/api/water/undo-last"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Fatalf("expected 405, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestGuestMode(t *testing.T) {
+	wr := &mockWeightRepo{
+		addFn: func(_ context.Context, _ int64, _ float64, _ string, _ time.Time) (int64, error) {
+			t.Fatal("guest write reached the repository")
+			return 0, nil
+		},
+	}
+	wa := &mockWaterRepo{}
+	users := &mockUserRepo{users: []*domain.User{{ID: 7, Username: "demo"}}}
+	authSvc := app.NewAuthService(users, &mockSessionRepo{})
+
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa), authSvc, t.TempDir()).
+		WithGuestUser("demo")
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/water/today")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected guest read to succeed, got %d", resp.StatusCode)
+	}
+
+	b, _ := json.Marshal(map[string]any{"value": 80, "unit": "kg"})
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/weight/today", bytes.NewReader(b))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for guest write, got %d", resp.StatusCode)
+	}
+}
+
+type mockProfileRepo struct{}
+
+func (m *mockProfileRepo) CreateProfile(ctx context.Context, ownerID int64, name string) (*domain.Profile, error) {
+	return &domain.Profile{ID: 5, OwnerID: ownerID, Name: name}, nil
+}
+
+func (m *mockProfileRepo) ListProfiles(ctx context.Context, ownerID int64) ([]domain.Profile, error) {
+	return []domain.Profile{{ID: 5, OwnerID: ownerID, Name: "Sam"}}, nil
+}
+
+func (m *mockProfileRepo) GetProfile(ctx context.Context, ownerID, id int64) (*domain.Profile, error) {
+	if id != 5 {
+		return nil, nil
+	}
+	return &domain.Profile{ID: 5, OwnerID: ownerID, Name: "Sam"}, nil
+}
+
+func TestProfileSelector(t *testing.T) {
+	var gotUserID int64 = -1
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, userID int64, _ string) (float64, error) {
+			gotUserID = userID
+			return 1.0, nil
+		},
+	}
+	wr := &mockWeightRepo{}
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithProfiles(app.NewProfileService(&mockProfileRepo{}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/water/today?profile=5")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if gotUserID != 5 {
+		t.Fatalf("expected query scoped to profile 5, got %d", gotUserID)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/water/today?profile=6")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown profile, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/profiles")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body := decodeBody(t, resp)
+	if arr, ok := body["items"].([]any); !ok || len(arr) != 1 {
+		t.Fatalf("expected 1 profile, got %v", body["items"])
+	}
+}
+
+type mockShareRepo struct{}
+
+func (m *mockShareRepo) CreateShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	return &domain.Share{OwnerID: ownerID, ViewerID: viewerID}, nil
+}
+
+func (m *mockShareRepo) DeleteShare(ctx context.Context, ownerID, viewerID int64) (bool, error) {
+	return true, nil
+}
+
+func (m *mockShareRepo) GetShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	if ownerID != 9 {
+		return nil, nil
+	}
+	return &domain.Share{OwnerID: ownerID, ViewerID: viewerID}, nil
+}
+
+func (m *mockShareRepo) ListShares(ctx context.Context, userID int64) ([]domain.Share, error) {
+	return nil, nil
+}
+
+func TestSharedReadOnlyAccess(t *testing.T) {
+	var gotUserID int64 = -1
+	wr := &mockWeightRepo{
+		listFn: func(_ context.Context, userID int64, _ int) ([]domain.WeightEntry, error) {
+			gotUserID = userID
+			return nil, nil
+		},
+		addFn: func(_ context.Context, _ int64, _ float64, _ string, _ time.Time) (int64, error) {
+			t.Fatal("shared write reached the repository")
+			return 0, nil
+		},
+	}
+	wa := &mockWaterRepo{}
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithShares(app.NewShareService(&mockShareRepo{}, &mockUserRepo{}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/weight/recent?user=9")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || gotUserID != 9 {
+		t.Fatalf("expected shared read of user 9, got status %d user %d", resp.StatusCode, gotUserID)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/weight/recent?user=8")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 without a grant, got %d", resp.StatusCode)
+	}
+
+	b, _ := json.Marshal(map[string]any{"value": 80, "unit": "kg"})
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/weight/today?user=9", bytes.NewReader(b))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for shared write, got %d", resp.StatusCode)
+	}
+
+	// Shares cover charts and recent entries only.
+	for _, path := range []string{
+		"/api/weight/today", "/api/water/today", "/api/stats/weekly",
+		"/api/webhooks", "/api/webhooks/1", "/api/webhooks/1/deliveries",
+		"/api/alerts/rules", "/api/alerts/rules/1", "/api/settings", "/api/config/export",
+		"/api/export/influx", "/api/export/weight.csv", "/api/export/water.csv",
+		"/api/export/events.json", "/api/export/charts.csv", "/api/export/archive/1",
+	} {
+		resp, err = http.Get(ts.URL + path + "?user=9")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: expected 403 for an unshared endpoint, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestExportInflux(t *testing.T) {
+	ts := newTestServer(t, nil, nil)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/export/influx?days=2")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines (water+weight for 2 days), got %d: %q", len(lines), raw)
+	}
+	if !strings.HasPrefix(lines[0], "water,user_id=0,user=dev liters=2.5 ") {
+		t.Fatalf("unexpected water line: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "weight,user_id=0,user=dev,unit=kg value=80 ") {
+		t.Fatalf("unexpected weight line: %q", lines[1])
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	db := memory.New()
+	ctx := context.Background()
+	start := time.Now().Add(-2000 * time.Hour)
+	// More events than any list endpoint returns.
+	for i := 0; i < 600; i++ {
+		_, _ = db.AddWaterEvent(ctx, 0, 0.25, start.Add(time.Duration(i)*time.Hour))
+	}
+	_, _ = db.AddWeightEvent(ctx, 0, 80.5, "kg", start)
+	_, _ = db.AddWeightEvent(ctx, 0, 178, "lb", start.Add(time.Hour))
+	_, _ = db.AddWeightEvent(ctx, 1, 60, "kg", start)
+
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(path string) [][]string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("%s: Content-Type = %q", path, ct)
+		}
+		if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="vitals-`) || !strings.HasSuffix(cd, `.csv"`) {
+			t.Errorf("%s: Content-Disposition = %q", path, cd)
+		}
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: invalid CSV: %v", path, err)
+		}
+		return records
+	}
+
+	weights := get("/api/export/weight.csv")
+	if len(weights) != 3 || strings.Join(weights[0], ",") != "id,createdAt,day,value,unit" {
+		t.Fatalf("expected a header and the user's 2 weigh-ins, got %q", weights)
+	}
+	if weights[1][3] != "80.5" || weights[1][4] != "kg" || weights[2][3] != "178" || weights[2][4] != "lb" {
+		t.Errorf("expected weigh-ins oldest first in their recorded units, got %q", weights[1:])
+	}
+
+	water := get("/api/export/water.csv")
+	if len(water) != 601 {
+		t.Fatalf("expected a header and all 600 water events, got %d rows", len(water))
+	}
+	if water[1][2] != "0.25" || water[1][1] >= water[600][1] {
+		t.Errorf("expected water events oldest first, got %q ... %q", water[1], water[600])
+	}
+
+	charts := get("/api/export/charts.csv?days=3")
+	if len(charts) != 4 || charts[0][0] != "day" || charts[3][0] != time.Now().Format("2006-01-02") {
+		t.Errorf("expected a header and 3 days ending today, got %q", charts)
+	}
+	if daily := get("/api/charts/daily.csv?days=2"); len(daily) != 3 || strings.Join(daily[0], ",") != strings.Join(charts[0], ",") {
+		t.Errorf("expected the chart columns for 2 days, got %q", daily)
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	db := memory.New()
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		at := time.Date(2026, 3, 1+i, 12, 0, 0, 0, time.Local)
+		_, _ = db.AddWaterEvent(ctx, 0, 0.5, at)
+		_, _ = db.AddWeightEvent(ctx, 0, 80+float64(i), "kg", at)
+	}
+
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/export/weight.csv?from=2026-03-02&to=2026-03-03")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	_ = resp.Body.Close()
+	if err != nil || len(records) != 3 || records[1][2] != "2026-03-02" || records[2][3] != "82" {
+		t.Errorf("expected a header and the weigh-ins of 2 and 3 March, got %q, %v", records, err)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/export/events.json?from=2026-03-04&metrics=water")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := decodeBody(t, resp)
+	_ = resp.Body.Close()
+	water, _ := body["water"].([]any)
+	if _, ok := body["weight"]; ok || len(water) != 2 || body["from"] != "2026-03-04" {
+		t.Errorf("expected only the water of 4 and 5 March, got %v", body)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="vitals-events-`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	for _, path := range []string{
+		"/api/export/events.json?metrics=steps",
+		"/api/export/water.csv?from=2026-03-05&to=2026-03-01",
+		"/api/export/weight.csv?to=March",
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestChartsDailyXLSX(t *testing.T) {
+	db := memory.New()
+	_, _ = db.AddWeightEvent(context.Background(), 0, 80, "kg", time.Now())
+	_ = db.SaveJournalEntry(context.Background(), 0, domain.JournalEntry{Day: time.Now().Format("2006-01-02"), Note: "fish & chips", UpdatedAt: time.Now()})
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db).WithJournal(db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/charts/daily.xlsx?days=2&unit=kg")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasSuffix(resp.Header.Get("Content-Disposition"), `.xlsx"`) {
+		t.Fatalf("expected an xlsx download, got %d %q", resp.StatusCode, resp.Header.Get("Content-Disposition"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("invalid workbook: %v", err)
+	}
+	var sheet []byte
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			sheet, _ = io.ReadAll(rc)
+			_ = rc.Close()
+		}
+	}
+	var parsed struct {
+		Rows []struct {
+			Cells []struct {
+				Type  string `xml:"t,attr"`
+				Value string `xml:"v"`
+				Text  string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &parsed); err != nil {
+		t.Fatalf("invalid sheet: %v", err)
+	}
+	if len(parsed.Rows) != 3 || parsed.Rows[0].Cells[0].Text != "day" {
+		t.Fatalf("expected a header and 2 days, got %s", sheet)
+	}
+	today := parsed.Rows[2].Cells
+	if today[4].Type != "" || today[4].Value != "80" || today[8].Text != "fish & chips" {
+		t.Errorf("expected a numeric weight and the note as text, got %+v", today)
+	}
+}
+
+func TestExportCSV_Error(t *testing.T) {
+	wa := &mockWaterRepo{eachFn: func(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error {
+		return errors.New("connection reset")
+	}}
+	ts := newTestServer(t, nil, wa)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/export/water.csv")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || resp.Header.Get("Content-Disposition") != "" {
+		t.Errorf("expected a plain 500 when nothing was sent yet, got %d %q", resp.StatusCode, resp.Header.Get("Content-Disposition"))
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	db := memory.New()
+	_, _ = db.AddWeightEvent(context.Background(), 0, 80, "kg", time.Now().Add(-time.Hour))
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithArchives(app.NewArchiveService(db, db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/export/archive", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var started struct {
+		Job app.ArchiveJob `json:"job"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&started)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || started.Job.ID == "" {
+		t.Fatalf("expected 202 with a job, got %d %+v", resp.StatusCode, started.Job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err = http.Get(ts.URL + "/api/export/archive/" + started.Job.ID + "/download")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != http.StatusConflict || time.Now().After(deadline) {
+			break
+		}
+		_ = resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Disposition"), `attachment; filename="vitals-export-`) {
+		t.Fatalf("expected the zip download, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if raw, _ := io.ReadAll(resp.Body); !bytes.HasPrefix(raw, []byte("PK")) {
+		t.Errorf("expected a zip body, got %q", raw)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/export/archive/missing")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown archive, got %d", resp.StatusCode)
+	}
+}
+
+type mockTokenRepo struct {
+	tokens []domain.APIToken
+}
+
+func (m *mockTokenRepo) CreateAPIToken(ctx context.Context, userID int64, name, scope, tokenHash string) (*domain.APIToken, error) {
+	t := domain.APIToken{ID: int64(len(m.tokens) + 1), UserID: userID, Name: name, Scope: scope, TokenHash: tokenHash}
+	m.tokens = append(m.tokens, t)
+	return &t, nil
+}
+
+func (m *mockTokenRepo) GetAPITokenByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockTokenRepo) ListAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	return m.tokens, nil
+}
+
+func (m *mockTokenRepo) DeleteAPIToken(ctx context.Context, userID, id int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockTokenRepo) DeleteAPITokens(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockTokenRepo) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	for i := range m.tokens {
+		if m.tokens[i].ID == id {
+			m.tokens[i].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func TestQuickWater(t *testing.T) {
+	var gotUserID int64
+	var gotDelta float64
+	wa := &mockWaterRepo{
+		addFn: func(_ context.Context, userID int64, delta float64, _ time.Time) (int64, error) {
+			gotUserID, gotDelta = userID, delta
+			return 1, nil
+		},
+	}
+	wr := &mockWeightRepo{}
+	users := &mockUserRepo{users: []*domain.User{{ID: 7, Username: "alice"}}}
+	tokenSvc := app.NewTokenService(&mockTokenRepo{}, users)
+	secret, _, err := tokenSvc.Create(context.Background(), 7, "phone", domain.TokenScopeQuick)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(users, &mockSessionRepo{}), t.TempDir()).
+		WithTokens(tokenSvc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/quick/water?amount=250ml&token="+url.QueryEscape(secret), "", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, raw)
+	}
+	if gotUserID != 7 || gotDelta != 0.25 {
+		t.Fatalf("expected 0.25 L for user 7, got %v L for user %d", gotDelta, gotUserID)
+	}
+	if !strings.HasPrefix(string(raw), "Logged 250 ml of water.") {
+		t.Fatalf("unexpected confirmation: %q", raw)
+	}
+
+	resp2, err := http.Post(ts.URL+"/api/quick/water?amount=250ml&token=wrong", "", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp2.Body.Close()
+	if resp2.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad token, got %d", resp2.StatusCode)
+	}
+}
+
+func TestDashboardToken(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	alice, _ := db.Create(ctx, "alice", "x")
+	_, _ = db.AddWaterEvent(ctx, alice.ID, 1.5, time.Now())
+	_ = db.SaveJournalEntry(ctx, alice.ID, domain.JournalEntry{Day: time.Now().Format("2006-01-02"), Note: "felt rough", UpdatedAt: time.Now()})
+
+	tokenSvc := app.NewTokenService(db, db)
+	dash, _, err := tokenSvc.Create(ctx, alice.ID, "kitchen", domain.TokenScopeDashboard)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	quick, _, _ := tokenSvc.Create(ctx, alice.ID, "phone", domain.TokenScopeQuick)
+
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db).WithJournal(db),
+		app.NewAuthService(db, &mockSessionRepo{}), t.TempDir()).
+		WithTokens(tokenSvc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, secret string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	code, body := do(http.MethodGet, "/api/water/today", dash)
+	if code != http.StatusOK || body["totalLiters"] != 1.5 {
+		t.Fatalf("expected today's water for the token's owner, got %d %v", code, body)
+	}
+	code, body = do(http.MethodGet, "/api/charts/daily?days=1&token="+url.QueryEscape(dash), "")
+	items, _ := body["items"].([]any)
+	if code != http.StatusOK || len(items) != 1 {
+		t.Fatalf("expected a chart via ?token=, got %d %v", code, body)
+	}
+	if note := items[0].(map[string]any)["note"]; note != nil {
+		t.Errorf("expected journal notes left out for a dashboard, got %v", note)
+	}
+
+	for _, tc := range []struct {
+		name, method, path, secret string
+		want                       int
+	}{
+		{"raw entries", http.MethodGet, "/api/water/recent", dash, http.StatusUnauthorized},
+		{"change stream", http.MethodGet, "/api/events/stream", dash, http.StatusUnauthorized},
+		{"account", http.MethodGet, "/api/account", dash, http.StatusUnauthorized},
+		{"write", http.MethodPut, "/api/steps/today", dash, http.StatusForbidden},
+		{"quick-scoped token", http.MethodGet, "/api/water/today", quick, http.StatusUnauthorized},
+		{"no credentials", http.MethodGet, "/api/water/today", "", http.StatusUnauthorized},
+	} {
+		if code, _ := do(tc.method, tc.path, tc.secret); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, code)
+		}
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	alice, _ := db.Create(ctx, "alice", "x")
+
+	tokenSvc := app.NewTokenService(db, db)
+	script, _, err := tokenSvc.Create(ctx, alice.ID, "backfill", domain.TokenScopeAPI)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(db, &mockSessionRepo{}), t.TempDir()).
+		WithTokens(tokenSvc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	var doWith func(secret, method, path, body string) int
+	do := func(method, path, body string) int {
+		t.Helper()
+		return doWith(script, method, path, body)
+	}
+	doWith = func(secret, method, path, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do(http.MethodPost, "/api/water/event", `{"deltaLiters": 0.25}`); code != http.StatusOK {
+		t.Fatalf("expected an API token to log water, got %d", code)
+	}
+	if items, _ := db.ListRecentWaterEvents(ctx, alice.ID, 10); len(items) != 1 {
+		t.Errorf("expected the event logged for the token's owner, got %+v", items)
+	}
+	for _, tc := range []struct {
+		name, method, path string
+		want               int
+	}{
+		{"raw entries", http.MethodGet, "/api/water/recent", http.StatusOK},
+		{"aggregates", http.MethodGet, "/api/water/today", http.StatusOK},
+		{"tokens", http.MethodGet, "/api/tokens", http.StatusUnauthorized},
+		{"account", http.MethodGet, "/api/account", http.StatusUnauthorized},
+		{"delete account", http.MethodDelete, "/api/auth/account", http.StatusUnauthorized},
+	} {
+		if code := do(tc.method, tc.path, ""); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, code)
+		}
+	}
+
+	items, _ := tokenSvc.List(ctx, alice.ID)
+	if len(items) != 1 || items[0].LastUsedAt == nil {
+		t.Errorf("expected the token's last use recorded, got %+v", items)
+	}
+
+	readOnly, _, _ := tokenSvc.Create(ctx, alice.ID, "dashboard", domain.TokenScopeReadOnly)
+	bottle, _, _ := tokenSvc.Create(ctx, alice.ID, "bottle", domain.TokenScopeWaterWrite)
+	for _, tc := range []struct {
+		name, secret, method, path, body string
+		want                             int
+	}{
+		{"read-only reads entries", readOnly, http.MethodGet, "/api/v1/water/recent", "", http.StatusOK},
+		{"read-only logs", readOnly, http.MethodPost, "/api/v1/water/event", `{"deltaLiters": 0.25}`, http.StatusForbidden},
+		{"bottle logs water", bottle, http.MethodPost, "/api/v1/water/event", `{"deltaLiters": 0.5}`, http.StatusOK},
+		{"bottle logs quickly", bottle, http.MethodPost, "/api/v1/quick/water?amount=250ml", "", http.StatusOK},
+		{"bottle reads water", bottle, http.MethodGet, "/api/v1/water/recent", "", http.StatusUnauthorized},
+		{"bottle reads today", bottle, http.MethodGet, "/api/v1/water/today", "", http.StatusUnauthorized},
+		{"bottle logs weight", bottle, http.MethodPut, "/api/v1/weight/today", `{"value": 80, "unit": "kg"}`, http.StatusUnauthorized},
+		{"bottle quick weight", bottle, http.MethodPost, "/api/v1/quick/weight?value=80&unit=kg", "", http.StatusUnauthorized},
+	} {
+		if code := doWith(tc.secret, tc.method, tc.path, tc.body); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, code)
+		}
+	}
+	if items, _ := db.ListRecentWaterEvents(ctx, alice.ID, 10); len(items) != 3 {
+		t.Errorf("expected only the bottle's two events added, got %+v", items)
+	}
+}
+
+func TestGoals(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithHydration(app.NewHydrationService(db).WithGoalHistory(db)).
+		WithGoals(app.NewGoalService(db, db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, _ := do(http.MethodPut, "/api/water/settings", `{"baseGoalLiters": 3}`); code != http.StatusOK {
+		t.Fatalf("expected 200 saving the water goal, got %d", code)
+	}
+	code, body := do(http.MethodPut, "/api/goals/weight", `{"value": 75, "unit": "kg", "effectiveFrom": "2026-01-05"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, body)
+	}
+	if code, body := do(http.MethodPut, "/api/goals/weight", `{"value": 75, "unit": "kg", "effectiveFrom": "2999-01-01"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a future date, got %d: %v", code, body)
+	}
+
+	code, body = do(http.MethodGet, "/api/goals/history", "")
+	water, _ := body["water"].([]any)
+	weight, _ := body["weight"].([]any)
+	if code != http.StatusOK || len(water) != 1 || len(weight) != 1 {
+		t.Fatalf("expected one change of each goal, got %d: %v", code, body)
+	}
+	if first, _ := weight[0].(map[string]any); first["day"] != "2026-01-05" || first["targetKg"] != float64(75) {
+		t.Errorf("unexpected weight goal change: %v", first)
+	}
+
+	code, body = do(http.MethodDelete, "/api/goals/weight", "")
+	weight, _ = body["weight"].([]any)
+	if last, _ := weight[len(weight)-1].(map[string]any); code != http.StatusOK || len(weight) != 2 || last["targetKg"] != nil {
+		t.Errorf("expected the target cleared from today, got %d: %v", code, body)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	db := memory.New()
+	charts := app.NewChartsService(db, db)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), charts,
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithSnapshots(app.NewSnapshotService(charts))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/snapshot", nil)
+	req.Header.Set("Accept", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	text, _ := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(text), "Weight -\n") {
+		t.Fatalf("expected a text snapshot, got %d: %q", resp.StatusCode, text)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "private, max-age=900" {
+		t.Errorf("expected the snapshot to be cacheable, got %q", cc)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/snapshot?unit=lb")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := decodeBody(t, resp)
+	resp.Body.Close() //nolint:errcheck
+	if body["weightUnit"] != "lb" || body["weight"] != nil {
+		t.Errorf("expected an empty snapshot in lb, got %v", body)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/snapshot?unit=st")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown unit, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithWebhooks(app.NewWebhookService(db, &stubWebhookSender{}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	if code, body := do(http.MethodPost, "/api/webhooks", `{"url": "https://example.com/hook", "events": ["weight.deleted"]}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown event, got %d: %v", code, body)
+	}
+	code, body := do(http.MethodPost, "/api/webhooks", `{"url": "https://example.com/hook", "events": ["weight.recorded"]}`)
+	hook, _ := body["webhook"].(map[string]any)
+	if code != http.StatusCreated || hook["secret"] == nil || hook["enabled"] != true {
+		t.Fatalf("expected the webhook created with its secret, got %d: %v", code, body)
+	}
+	id := fmt.Sprint(hook["id"])
+
+	code, body = do(http.MethodGet, "/api/webhooks", "")
+	items, _ := body["items"].([]any)
+	if code != http.StatusOK || len(items) != 1 || items[0].(map[string]any)["secret"] != nil {
+		t.Fatalf("expected one webhook without its secret, got %d: %v", code, body)
+	}
+	if code, body := do(http.MethodGet, "/api/webhooks/"+id+"/deliveries", ""); code != http.StatusOK || body["items"] == nil {
+		t.Errorf("expected an empty delivery log, got %d: %v", code, body)
+	}
+	if code, _ := do(http.MethodGet, "/api/webhooks/99/deliveries", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown webhook, got %d", code)
+	}
+	if code, body := do(http.MethodPut, "/api/webhooks/"+id, `{"url": "https://example.com/v2", "events": ["goal.reached"], "enabled": false}`); code != http.StatusOK {
+		t.Errorf("expected 200 updating the webhook, got %d: %v", code, body)
+	}
+	if code, _ := do(http.MethodDelete, "/api/webhooks/"+id, ""); code != http.StatusOK {
+		t.Errorf("expected 200 deleting the webhook, got %d", code)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db).WithPlans(db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithPlans(app.NewPlanService(db, db, db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+	day := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	if code, body := do(http.MethodPost, "/api/plan", `{"kind": "steps", "day": "`+day+`"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown kind, got %d: %v", code, body)
+	}
+	if code, body := do(http.MethodPost, "/api/plan", `{"kind": "weight", "day": "`+day+`", "at": "07:00", "channel": "mqtt"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unavailable channel, got %d: %v", code, body)
+	}
+	code, body := do(http.MethodPost, "/api/plan", `{"kind": "weight", "day": "`+day+`", "note": "after the run"}`)
+	entry, _ := body["entry"].(map[string]any)
+	if code != http.StatusCreated || entry["day"] != day {
+		t.Fatalf("expected the entry created, got %d: %v", code, body)
+	}
+	id := fmt.Sprint(entry["id"])
+
+	code, body = do(http.MethodGet, "/api/plan", "")
+	if items, _ := body["items"].([]any); code != http.StatusOK || len(items) != 1 {
+		t.Fatalf("expected one planned entry, got %d: %v", code, body)
+	}
+	if code, _ := do(http.MethodGet, "/api/plan?from=tomorrow", ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed day, got %d", code)
+	}
+
+	// The calendar shows planned entries apart from logged ones.
+	code, body = do(http.MethodGet, "/api/calendar/"+day[:7], "")
+	days, _ := body["days"].([]any)
+	found := false
+	for _, d := range days {
+		d := d.(map[string]any)
+		if d["day"] == day {
+			planned, _ := d["planned"].([]any)
+			found = len(planned) == 1 && d["weight"] == nil
+		}
+	}
+	if code != http.StatusOK || !found {
+		t.Errorf("expected the planned weigh-in on %s, got %d: %v", day, code, body)
+	}
+
+	if code, body := do(http.MethodPut, "/api/plan/"+id, `{"kind": "water", "day": "`+day+`", "liters": 2.5}`); code != http.StatusOK {
+		t.Errorf("expected 200 updating the entry, got %d: %v", code, body)
+	}
+	if code, _ := do(http.MethodDelete, "/api/plan/"+id, ""); code != http.StatusOK {
+		t.Errorf("expected 200 deleting the entry, got %d", code)
+	}
+	if code, _ := do(http.MethodDelete, "/api/plan/"+id, ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting it again, got %d", code)
+	}
+}
+
+// stubWebhookSender accepts every delivery.
+type stubWebhookSender struct{}
+
+func (stubWebhookSender) Send(ctx context.Context, url, secret string, d domain.WebhookDelivery) (int, error) {
+	return http.StatusOK, nil
+}
+
+func TestCalendarFeed(t *testing.T) {
+	wr := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{
+				{ID: 2, Day: "2026-02-08", Value: 87.0, Unit: "kg", CreatedAt: time.Date(2026, 2, 8, 7, 0, 0, 0, time.UTC)},
+				{ID: 1, Day: "2026-02-01", Value: 90.0, Unit: "kg", CreatedAt: time.Date(2026, 2, 1, 7, 0, 0, 0, time.UTC)},
+			}, nil
+		},
+	}
+	wa := &mockWaterRepo{}
+	users := &mockUserRepo{users: []*domain.User{{ID: 7, Username: "alice"}}}
+	tokenSvc := app.NewTokenService(&mockTokenRepo{}, users)
+	quick, _, _ := tokenSvc.Create(context.Background(), 7, "phone", domain.TokenScopeQuick)
+	feed, _, _ := tokenSvc.Create(context.Background(), 7, "calendar", domain.TokenScopeFeed)
+
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(users, &mockSessionRepo{}), t.TempDir()).
+		WithTokens(tokenSvc).
+		WithFeeds(app.NewFeedService(wr, wa))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/feeds/calendar.ics?token=" + url.QueryEscape(quick))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a quick-scoped token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/feeds/calendar.ics?token=" + url.QueryEscape(feed))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, raw)
+	}
+	body := string(raw)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:weight-2@vitals\r\n",
+		"DTSTART;VALUE=DATE:20260208\r\n",
+		"SUMMARY:Weigh-in: 87 kg\r\n",
+		"SUMMARY:Lost 2.5 kg\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("feed missing %q", want)
+		}
+	}
+
+	resp2, err := http.Get(ts.URL + "/api/feeds/weekly.atom?weeks=2&token=" + url.QueryEscape(feed))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp2.Body.Close() //nolint:errcheck
+	raw, _ = io.ReadAll(resp2.Body)
+	if resp2.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp2.StatusCode, raw)
+	}
+	if got := strings.Count(string(raw), "<entry>"); got != 2 {
+		t.Fatalf("expected 2 weekly entries, got %d: %s", got, raw)
+	}
+}
+
+func TestImportJob(t *testing.T) {
+	wr, wa := &mockWeightRepo{}, &mockWaterRepo{}
+	srv := adapthttp.New(app.NewWeightService(wr), app.NewWaterService(wa), app.NewChartsService(wr, wa),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithImports(app.NewImportService(wr, wa))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	csv := "type,value,unit,timestamp\nweight,80,kg,2026-01-05\nwater,250,ml,2026-01-05\n"
+	resp, err := http.Post(ts.URL+"/api/import?format=csv", "text/csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	job, _ := decodeBody(t, resp)["job"].(map[string]any)
+	id, _ := job["id"].(string)
+	if id == "" {
+		t.Fatalf("response missing job id: %v", job)
+	}
+
+	events, err := http.Get(ts.URL + "/api/import/jobs/" + id + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer events.Body.Close() //nolint:errcheck
+	if ct := events.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	raw, _ := io.ReadAll(events.Body)
+	if !strings.Contains(string(raw), "event: done\n") || !strings.Contains(string(raw), `"rowsImported":2`) {
+		t.Fatalf("unexpected event stream: %s", raw)
+	}
+
+	status, err := http.Get(ts.URL + "/api/import/jobs/" + id)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer status.Body.Close() //nolint:errcheck
+	if status.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status.StatusCode)
+	}
+
+	resp2, err := http.Post(ts.URL+"/api/import/wger", "application/json", strings.NewReader(`[{"date": "2026-01-05", "weight": "80.5"}]`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp2.Body.Close() //nolint:errcheck
+	if resp2.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for a source import, got %d", resp2.StatusCode)
+	}
+	if job, _ := decodeBody(t, resp2)["job"].(map[string]any); job["format"] != "wger" {
+		t.Fatalf("expected a wger job, got %v", job)
+	}
+
+	dry, err := http.Post(ts.URL+"/api/import/csv?dryRun=true", "text/csv", strings.NewReader(csv+"weight,80,stone,2026-01-06\n"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer dry.Body.Close() //nolint:errcheck
+	report, _ := decodeBody(t, dry)["report"].(map[string]any)
+	if dry.StatusCode != http.StatusOK || report["rowsValid"] != 2.0 || report["errorCount"] != 1.0 || report["warningCount"] != 0.0 {
+		t.Fatalf("expected a dry-run report with two valid rows, got %d %v", dry.StatusCode, report)
+	}
+
+	missing, err := http.Get(ts.URL + "/api/import/jobs/nope")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown job, got %d", missing.StatusCode)
+	}
+}
+
+func TestImportMappedCSV(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithImports(app.NewImportService(db, db).WithBatches(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	upload := func(mapping, dryRun string) *http.Response {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("mapping", mapping)
+		if dryRun != "" {
+			_ = mw.WriteField("dryRun", dryRun)
+		}
+		fw, _ := mw.CreateFormFile("file", "export.csv")
+		_, _ = io.WriteString(fw, "Date,Kilograms\n2026-01-05 07:00,80.4\n2026-01-06 07:00,-1\n")
+		_ = mw.Close()
+		resp, err := http.Post(ts.URL+"/api/import/csv", mw.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	const mapping = `{"kind": "weight", "value": "Kilograms", "time": "Date", "defaultUnit": "kg"}`
+
+	resp := upload(mapping, "true")
+	defer resp.Body.Close() //nolint:errcheck
+	report, _ := decodeBody(t, resp)["report"].(map[string]any)
+	if resp.StatusCode != http.StatusOK || report["rowsValid"] != 1.0 || report["errorCount"] != 1.0 {
+		t.Fatalf("expected a dry-run report with one valid row, got %d %v", resp.StatusCode, report)
+	}
+	if items, _ := db.ListRecentWeightEvents(context.Background(), 0, 10); len(items) != 0 {
+		t.Fatalf("expected the dry run to store nothing, got %v", items)
+	}
+
+	resp2 := upload(mapping, "")
+	defer resp2.Body.Close() //nolint:errcheck
+	job, _ := decodeBody(t, resp2)["job"].(map[string]any)
+	if resp2.StatusCode != http.StatusAccepted || job["format"] != "csv" || job["batchId"] == "" {
+		t.Fatalf("expected 202 with a batch import job, got %d %v", resp2.StatusCode, job)
+	}
+
+	for name, bad := range map[string]string{
+		"unknown field":  `{"kind": "weight", "value": "Kilograms", "time": "Date", "color": "red"}`,
+		"missing column": `{"kind": "weight", "value": "Pounds", "time": "Date"}`,
+	} {
+		resp := upload(bad, "")
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+	resp3 := upload(mapping, "maybe")
+	_ = resp3.Body.Close()
+	if resp3.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid dryRun, got %d", resp3.StatusCode)
+	}
+}
+
+func TestAccountExportImport(t *testing.T) {
+	ctx := context.Background()
+	users := &mockUserRepo{users: []*domain.User{{ID: 0, Username: "dev"}}}
+	// newServer serves an instance whose only account is the dev user.
+	newServer := func(db *memory.DB, sessions domain.SessionRepository) *httptest.Server {
+		auth := app.NewAuthService(users, sessions)
+		imports := app.NewImportService(db, db)
+		srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db), auth, t.TempDir()).
+			WithoutAuth().
+			WithImports(imports).
+			WithPortability(app.NewPortabilityService(app.NewAccountService(users, db), auth, db, db, imports))
+		return httptest.NewServer(srv.Handler())
+	}
+
+	src := memory.New()
+	sessions := src.NewSessionRepo()
+	at := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	_, _ = src.AddWeightEvent(ctx, 0, 80.4, "kg", at)
+	_, _ = src.AddWaterEvent(ctx, 0, 0.25, at.Add(time.Hour))
+	_ = sessions.Create(ctx, 0, "secret-token", "Firefox", "10.0.0.1", time.Now().Add(time.Hour))
+	srcTS := newServer(src, sessions)
+	defer srcTS.Close()
+
+	resp, err := http.Get(srcTS.URL + "/api/export/all")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	bundle, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Fatalf("expected a 200 download, got %d: %s", resp.StatusCode, bundle)
+	}
+	if strings.Contains(string(bundle), "secret-token") {
+		t.Fatal("export leaked a session token")
+	}
+	var got app.AccountBundle
+	if err := json.Unmarshal(bundle, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Profile.Username != "dev" || len(got.Weight) != 1 || len(got.Water) != 1 || len(got.Sessions) != 1 {
+		t.Fatalf("unexpected bundle: %s", bundle)
+	}
+
+	dst := memory.New()
+	dstTS := newServer(dst, &mockSessionRepo{})
+	defer dstTS.Close()
+	restore := func() map[string]any {
+		t.Helper()
+		resp, err := http.Post(dstTS.URL+"/api/import/all", "application/json", bytes.NewReader(bundle))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", resp.StatusCode)
+		}
+		job, _ := decodeBody(t, resp)["job"].(map[string]any)
+		events, err := http.Get(dstTS.URL + "/api/import/jobs/" + job["id"].(string) + "/events")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(events.Body)
+		_ = events.Body.Close()
+		status, err := http.Get(dstTS.URL + "/api/import/jobs/" + job["id"].(string))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer status.Body.Close() //nolint:errcheck
+		final, _ := decodeBody(t, status)["job"].(map[string]any)
+		return final
+	}
+
+	dry, err := http.Post(dstTS.URL+"/api/import/all?dryRun=true", "application/json", bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer dry.Body.Close() //nolint:errcheck
+	report, _ := decodeBody(t, dry)["report"].(map[string]any)
+	if dry.StatusCode != http.StatusOK || report["weight"] != 1.0 || report["water"] != 1.0 {
+		t.Fatalf("expected a dry-run report of both events, got %d %v", dry.StatusCode, report)
+	}
+	if items, _ := dst.ListRecentWeightEvents(ctx, 0, 10); len(items) != 0 {
+		t.Fatalf("expected the dry run to store nothing, got %v", items)
+	}
+
+	if job := restore(); job["rowsImported"] != float64(2) {
+		t.Fatalf("expected both events restored, got %v", job)
+	}
+	if job := restore(); job["rowsSkipped"] != float64(2) {
+		t.Fatalf("expected a second restore to skip both events, got %v", job)
+	}
+	if total, _ := dst.WaterTotalForLocalDay(ctx, 0, at.Add(time.Hour).In(time.Local).Format("2006-01-02")); total != 0.25 {
+		t.Errorf("expected the water event restored once, got a total of %v L", total)
+	}
+
+	bad, err := http.Post(dstTS.URL+"/api/import/all", "application/json", strings.NewReader(`{"version": 9}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported version, got %d", bad.StatusCode)
+	}
+}
+
+func TestImportBatchRollback(t *testing.T) {
+	db := memory.New()
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithImports(app.NewImportService(db, db).WithBatches(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// importCSV runs an import to completion and returns its final state.
+	importCSV := func() map[string]any {
+		t.Helper()
+		csv := "type,value,unit,timestamp\nweight,80,kg,2026-01-05\nwater,250,ml,2026-01-05\n"
+		resp, err := http.Post(ts.URL+"/api/import?format=csv", "text/csv", strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		job, _ := decodeBody(t, resp)["job"].(map[string]any)
+		events, err := http.Get(ts.URL + "/api/import/jobs/" + job["id"].(string) + "/events")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(events.Body)
+		_ = events.Body.Close()
+		status, err := http.Get(ts.URL + "/api/import/jobs/" + job["id"].(string))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer status.Body.Close() //nolint:errcheck
+		final, _ := decodeBody(t, status)["job"].(map[string]any)
+		return final
+	}
+	rollback := func(batch string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/import/batches/"+batch, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	first := importCSV()
+	batch, _ := first["batchId"].(string)
+	if batch == "" || first["rowsImported"] != 2.0 {
+		t.Fatalf("expected two rows imported into a batch, got %v", first)
+	}
+	if again := importCSV(); again["rowsImported"] != 0.0 || again["rowsSkipped"] != 2.0 {
+		t.Fatalf("expected the re-import to skip both rows, got %v", again)
+	}
+
+	if code, body := rollback(batch); code != http.StatusOK || body["deleted"] != 2.0 {
+		t.Fatalf("expected the batch rolled back, got %d %v", code, body)
+	}
+	recent, err := http.Get(ts.URL + "/api/weight/recent")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer recent.Body.Close() //nolint:errcheck
+	if items, _ := decodeBody(t, recent)["items"].([]any); len(items) != 0 {
+		t.Fatalf("expected the imported weight removed, got %v", items)
+	}
+	if code, _ := rollback(batch); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a rolled-back batch, got %d", code)
 	}
+}
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			req, err := http.NewRequest(tc.method, ts.URL+tc.path, nil)
-			if err != nil {
-				t.Fatalf("new request: %v", err)
-			}
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				t.Fatalf("request failed: %v", err)
+func TestActivity(t *testing.T) {
+	db := memory.New()
+	ctx := context.Background()
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	if _, err := db.AddWeightEvent(ctx, 0, 80, "kg", base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddWaterEvent(ctx, 0, 0.5, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordGoal(ctx, 0, "2026-03-01", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordWeightGoal(ctx, 0, "2026-02-20", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if _, err := db.ImportWaterEvent(ctx, 0, "b1", "", 0.25, base.AddDate(0, 0, -30+i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithActivity(app.NewActivityService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(query string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/activity" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode, decodeBody(t, resp)
+	}
+
+	// The import ran just now, so it is the newest item; its entries are
+	// not listed on their own.
+	var types []string
+	var items []map[string]any
+	query := "?limit=2"
+	for range 5 {
+		code, body := get(query)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", code, body)
+		}
+		page, _ := body["items"].([]any)
+		for _, it := range page {
+			item := it.(map[string]any)
+			items = append(items, item)
+			types = append(types, item["type"].(string))
+		}
+		cursor, _ := body["cursor"].(string)
+		if cursor == "" {
+			break
+		}
+		query = "?limit=2&cursor=" + url.QueryEscape(cursor)
+	}
+	want := []string{"import", "water", "weight", "goal.water", "goal.weight"}
+	if !slices.Equal(types, want) {
+		t.Fatalf("got types %v, want %v", types, want)
+	}
+	if imp, _ := items[0]["import"].(map[string]any); imp["batch"] != "b1" || imp["waters"] != 2.0 || imp["weights"] != 0.0 {
+		t.Errorf("unexpected import item: %v", items[0])
+	}
+	if w, _ := items[2]["weight"].(map[string]any); w["value"] != 80.0 {
+		t.Errorf("unexpected weight item: %v", items[2])
+	}
+	if g, _ := items[4]["weightGoal"].(map[string]any); g["day"] != "2026-02-20" || g["targetKg"] != nil {
+		t.Errorf("unexpected weight goal item: %v", items[4])
+	}
+
+	if code, body := get("?cursor=bogus"); code != http.StatusBadRequest || body["code"] != "invalid_argument" {
+		t.Errorf("expected 400 invalid_argument for a bad cursor, got %d %v", code, body)
+	}
+	if code, _ := get("?limit=1000"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a limit over the cap, got %d", code)
+	}
+}
+
+func TestAdminMaintenanceRequiresAdmin(t *testing.T) {
+	db := memory.New()
+	users := &mockUserRepo{users: []*domain.User{
+		{ID: 1, Username: "owner", Role: domain.RoleAdmin},
+		{ID: 2, Username: "bob", Role: domain.RoleUser},
+	}}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(users, &mockSessionRepo{}), t.TempDir()).
+		WithMaintenance(app.NewMaintenanceService(db).WithRetention(db, 30))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(user, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Remote-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("bob", `{"action":"cleanup-sessions"}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", resp.StatusCode)
+	}
+
+	resp = post("owner", `{"action":"rebuild-world"}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown action, got %d", resp.StatusCode)
+	}
+
+	resp = post("owner", `{"action":"cleanup-sessions","dryRun":true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for an admin, got %d", resp.StatusCode)
+	}
+	body := decodeBody(t, resp)
+	if body["action"] != "cleanup-sessions" || body["cleanup"] == nil {
+		t.Errorf("unexpected result %v", body)
+	}
+
+	ctx := context.Background()
+	y, m, d := time.Now().AddDate(0, 0, -60).Date()
+	old := time.Date(y, m, d, 12, 0, 0, 0, time.Local)
+	_, _ = db.AddWaterEvent(ctx, 2, 0.25, old)
+	_, _ = db.AddWaterEvent(ctx, 2, 0.5, old.Add(time.Minute))
+	_, _ = db.AddWaterEvent(ctx, 2, 0.5, time.Now())
+	resp = post("owner", `{"action":"downsample-water"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for downsample-water, got %d", resp.StatusCode)
+	}
+	report, _ := decodeBody(t, resp)["downsample"].(map[string]any)
+	if report["days"] != 1.0 || report["eventsRemoved"] != 1.0 {
+		t.Errorf("expected one day collapsed, got %v", report)
+	}
+	events, _ := db.ListRecentWaterEvents(ctx, 2, 10)
+	if len(events) != 2 || events[1].DeltaLiters != 0.75 {
+		t.Errorf("expected the old day collapsed into 0.75 L, got %+v", events)
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	db := memory.New()
+	_, _ = db.AddWeightEvent(context.Background(), 2, 80.4, "kg", time.Now())
+	users := &mockUserRepo{users: []*domain.User{
+		{ID: 1, Username: "owner", Role: domain.RoleAdmin},
+		{ID: 2, Username: "bob", Role: domain.RoleUser},
+	}}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(users, &mockSessionRepo{}), t.TempDir()).
+		WithUsage(app.NewUsageService(db))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(user, query string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/admin/stats"+query, nil)
+		req.Header.Set("Remote-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("bob", "")
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", resp.StatusCode)
+	}
+	resp = get("owner", "?weeks=500")
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 past the weeks limit, got %d", resp.StatusCode)
+	}
+
+	resp = get("owner", "?weeks=2")
+	defer resp.Body.Close() //nolint:errcheck
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(raw), "80.4") || strings.Contains(string(raw), "bob") {
+		t.Fatalf("expected an anonymous 200 report, got %d: %s", resp.StatusCode, raw)
+	}
+	var stats app.UsageStats
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Weeks) != 2 || stats.Weeks[1].ActiveUsers != 1 || stats.Weeks[1].Entries != 1 || stats.PeakActiveUsers != 1 {
+		t.Errorf("unexpected report %s", raw)
+	}
+}
+
+func TestMaintenanceModeRefusesWrites(t *testing.T) {
+	db := memory.New()
+	maintenance := app.NewMaintenanceService(db).WithMaintenanceMode("Restoring a backup")
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithMaintenance(maintenance)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, accept, payload string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/api/water/event", "", `{"deltaLiters":0.25}`)
+	body := decodeBody(t, resp)
+	if resp.StatusCode != http.StatusServiceUnavailable || body["error"] != "Restoring a backup" || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("expected 503 with the maintenance message, got %d %v", resp.StatusCode, body)
+	}
+
+	resp = do(http.MethodPost, "/login", "text/html", "")
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(page), "Restoring a backup") {
+		t.Errorf("expected an HTML maintenance page, got %d %s", resp.StatusCode, page)
+	}
+
+	resp = do(http.MethodGet, "/api/water/today", "", "")
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected reads to be served, got %d", resp.StatusCode)
+	}
+
+	// Admins can turn it off; the dev user of WithoutAuth is an admin.
+	resp = do(http.MethodPut, "/api/admin/maintenance-mode", "", `{"enabled":false}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the admin endpoint to stay writable, got %d", resp.StatusCode)
+	}
+	resp = do(http.MethodPost, "/api/water/event", "", `{"deltaLiters":0.25}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected writes after maintenance mode ends, got %d", resp.StatusCode)
+	}
+}
+
+// stubHealthSource grants any code and serves one weigh-in.
+type stubHealthSource struct{}
+
+func (stubHealthSource) AuthCodeURL(state string) string {
+	return "https://consent.example/auth?state=" + state
+}
+
+func (stubHealthSource) Exchange(_ context.Context, code string) (*domain.OAuthToken, error) {
+	return &domain.OAuthToken{AccessToken: "access-" + code}, nil
+}
+
+func (stubHealthSource) Readings(_ context.Context, tok domain.OAuthToken, _, to time.Time) ([]domain.ExternalReading, *domain.OAuthToken, error) {
+	return []domain.ExternalReading{{ID: "weight:1", Kind: "weight", Value: 81, At: to.Add(-time.Hour)}}, &tok, nil
+}
+
+func TestIntegrations(t *testing.T) {
+	db := memory.New()
+	svc := app.NewIntegrationService(db, db, db).WithSource(domain.IntegrationGoogleFit, stubHealthSource{})
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), t.TempDir()).
+		WithoutAuth().
+		WithIntegrations(svc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	do := func(method, path string, cookies ...*http.Cookie) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	if resp := do(http.MethodGet, "/api/integrations/strava/connect"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unconfigured provider, got %d", resp.StatusCode)
+	}
+	resp := do(http.MethodGet, "/api/integrations/googlefit/connect")
+	target, _ := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || target.Host != "consent.example" {
+		t.Fatalf("expected a redirect to the consent page, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	var state *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "integration_state" {
+			state = c
+		}
+	}
+	if state == nil || state.Value != target.Query().Get("state") {
+		t.Fatalf("expected the state in a cookie, got %+v", resp.Cookies())
+	}
+
+	if resp := do(http.MethodGet, "/api/integrations/googlefit/callback?code=abc&state=forged", state); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a mismatched state, got %d", resp.StatusCode)
+	}
+	resp = do(http.MethodGet, "/api/integrations/googlefit/callback?code=abc&state="+url.QueryEscape(state.Value), state)
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/?integration=googlefit" {
+		t.Fatalf("expected a redirect back to the app, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp = do(http.MethodPost, "/api/integrations/googlefit/sync")
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusOK || body["weight"] != 1.0 {
+		t.Fatalf("expected one weigh-in pulled, got %d: %v", resp.StatusCode, body)
+	}
+	if latest, _ := db.ListRecentWeightEvents(context.Background(), 0, 1); len(latest) != 1 || latest[0].ClientID != "googlefit:weight:1" {
+		t.Errorf("expected the pulled weigh-in stored, got %+v", latest)
+	}
+	resp = do(http.MethodGet, "/api/integrations/googlefit")
+	if body := decodeBody(t, resp); body["connected"] != true || body["syncedAt"] == nil {
+		t.Errorf("unexpected status: %v", body)
+	}
+
+	if resp := do(http.MethodDelete, "/api/integrations/googlefit"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/api/integrations/googlefit/sync"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 syncing a disconnected provider, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoginFailureFeedback(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	users := &mockUserRepo{users: []*domain.User{{ID: 1, Username: "alice", PasswordHash: string(hash)}}}
+	challenge, err := pow.New(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSvc := app.NewAuthService(users, &mockSessionRepo{}).
+		WithLoginLimits(3, time.Minute).
+		WithLoginChallenge(challenge, 1)
+	srv := adapthttp.New(app.NewWeightService(&mockWeightRepo{}), app.NewWaterService(&mockWaterRepo{}),
+		app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}), authSvc, t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	login := func(password, solution string) (*http.Response, map[string]any) {
+		t.Helper()
+		b, _ := json.Marshal(map[string]string{"username": "alice", "password": password, "challenge": solution})
+		resp, err := http.Post(ts.URL+"/api/auth/login", "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp, decodeBody(t, resp)
+	}
+	solve := func(c any) string {
+		t.Helper()
+		issued, _ := c.(map[string]any)
+		challenge, _ := issued["challenge"].(string)
+		if issued["kind"] != "pow" || challenge == "" {
+			t.Fatalf("expected a pow challenge, got %v", c)
+		}
+		for i := 0; ; i++ {
+			s := fmt.Sprintf("%s:%d", challenge, i)
+			if sum := sha256.Sum256([]byte(s)); sum[0]>>4 == 0 {
+				return s
 			}
-			defer resp.Body.Close() //nolint:errcheck
+		}
+	}
 
-			if resp.StatusCode != http.StatusMethodNotAllowed {
-				t.Fatalf("expected 405, got %d", resp.StatusCode)
+	resp, body := login("wrong", "")
+	if resp.StatusCode != http.StatusUnauthorized || body["error"] != "invalid credentials" || body["remainingAttempts"] != 2.0 {
+		t.Fatalf("first failure: %d %v", resp.StatusCode, body)
+	}
+	first := body["challenge"]
+
+	// Without a solution the login is refused but not counted.
+	resp, body = login("secret", "")
+	if resp.StatusCode != http.StatusUnauthorized || body["error"] != app.ErrChallengeRequired.Error() || body["remainingAttempts"] != 2.0 {
+		t.Fatalf("missing solution: %d %v", resp.StatusCode, body)
+	}
+	resp, body = login("wrong", solve(first))
+	if resp.StatusCode != http.StatusUnauthorized || body["remainingAttempts"] != 1.0 {
+		t.Fatalf("second failure: %d %v", resp.StatusCode, body)
+	}
+	resp, body = login("secret", solve(body["challenge"]))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("solved login: %d %v", resp.StatusCode, body)
+	}
+
+	// The successful login reset the count.
+	for range 3 {
+		solution := ""
+		if body["challenge"] != nil {
+			solution = solve(body["challenge"])
+		}
+		resp, body = login("wrong", solution)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" || body["retryAfter"] == nil {
+		t.Fatalf("lockout: %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestLoginLockoutBehindProxy(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	users := &mockUserRepo{users: []*domain.User{{ID: 1, Username: "alice", PasswordHash: string(hash)}}}
+	authSvc := app.NewAuthService(users, &mockSessionRepo{}).WithLoginLimits(2, time.Minute)
+	proxies, err := adapthttp.ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	if _, err := adapthttp.ParseTrustedProxies("10.0.0.0/8,proxy"); err == nil {
+		t.Error("expected an invalid network to fail")
+	}
+	h := adapthttp.New(app.NewWeightService(&mockWeightRepo{}), app.NewWaterService(&mockWaterRepo{}),
+		app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}), authSvc, t.TempDir()).
+		WithTrustedProxies(proxies).
+		Handler()
+
+	login := func(remote, forwarded string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"alice","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Clients behind the proxy are counted apart.
+	login("10.0.0.1:4000", "203.0.113.5")
+	if code := login("10.0.0.1:4001", "203.0.113.5"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the failing client locked out, got %d", code)
+	}
+	if code := login("10.0.0.1:4002", "198.51.100.1, 203.0.113.6"); code != http.StatusUnauthorized {
+		t.Fatalf("expected another client through the proxy unaffected, got %d", code)
+	}
+	// An untrusted peer cannot pick whom it counts against.
+	login("192.0.2.9:5000", "203.0.113.7")
+	login("192.0.2.9:5001", "203.0.113.7")
+	if code := login("10.0.0.1:4003", "203.0.113.7"); code != http.StatusUnauthorized {
+		t.Fatalf("expected a spoofed X-Forwarded-For ignored, got %d", code)
+	}
+}
+
+func TestAccountRecovery(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	owner, _ := db.Create(ctx, "owner", "")
+	_ = db.SetRole(ctx, owner.ID, domain.RoleAdmin)
+	_, _ = db.Create(ctx, "sso", "")
+	authSvc := app.NewAuthService(db, db.NewSessionRepo()).WithRecovery(db)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db), authSvc, t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(user, method, path, payload string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		if user != "" {
+			req.Header.Set("Remote-User", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do("sso", http.MethodGet, "/api/account/recovery-codes", "")
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusOK || body["hasPassword"] != false || body["remainingCodes"] != 0.0 {
+		t.Fatalf("recovery status: %d %v", resp.StatusCode, body)
+	}
+	resp = do("sso", http.MethodPost, "/api/account/recovery-codes", "")
+	body := decodeBody(t, resp)
+	codes, _ := body["codes"].([]any)
+	if resp.StatusCode != http.StatusCreated || len(codes) != domain.RecoveryCodeCount {
+		t.Fatalf("generate codes: %d %v", resp.StatusCode, body)
+	}
+
+	resp = do("", http.MethodPost, "/api/auth/recover", `{"username":"sso","code":"wrong","password":"newpassword"}`)
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusUnauthorized || body["remainingAttempts"] == nil {
+		t.Errorf("wrong code: %d %v", resp.StatusCode, body)
+	}
+	resp = do("", http.MethodPost, "/api/auth/recover", fmt.Sprintf(`{"username":"sso","code":%q,"password":"newpassword"}`, codes[0]))
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK || len(resp.Cookies()) == 0 || resp.Cookies()[0].Name != "session" {
+		t.Fatalf("recover: %d %v", resp.StatusCode, resp.Cookies())
+	}
+	if _, err := authSvc.Login(ctx, "sso", "newpassword", "test", "10.0.0.1"); err != nil {
+		t.Errorf("expected the new password to sign in, got %v", err)
+	}
+
+	resp = do("sso", http.MethodPost, "/api/admin/users/owner/recovery", "")
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", resp.StatusCode)
+	}
+	resp = do("owner", http.MethodPost, "/api/admin/users/sso/recovery", "")
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for an account with a password, got %d", resp.StatusCode)
+	}
+	resp = do("owner", http.MethodPost, "/api/admin/users/owner/recovery", "")
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusCreated || body["code"] == nil || body["expiresAt"] == nil {
+		t.Errorf("issue code: %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestAdminUserRole(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	owner, _ := db.Create(ctx, "owner", "")
+	_ = db.SetRole(ctx, owner.ID, domain.RoleAdmin)
+	sam, _ := db.Create(ctx, "sam", "")
+	_ = db.SetRole(ctx, sam.ID, domain.RoleAdmin)
+	sessions := db.NewSessionRepo()
+	if err := sessions.Create(ctx, sam.ID, "sam-session", "test", "10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateAPIToken(ctx, sam.ID, "script", domain.TokenScopeAPI, "sam-hash"); err != nil {
+		t.Fatal(err)
+	}
+	authSvc := app.NewAuthService(db, sessions).WithAccounts(db).WithAPITokens(db)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db), authSvc, t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	put := func(user, path, payload string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+path, strings.NewReader(payload))
+		req.Header.Set("Remote-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := put("owner", "/api/v1/admin/users/owner/role", `{"role":"user"}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for changing your own role, got %d", resp.StatusCode)
+	}
+	resp = put("owner", "/api/v1/admin/users/nobody/role", `{"role":"user"}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", resp.StatusCode)
+	}
+	resp = put("owner", "/api/v1/admin/users/sam/role", `{"role":"user"}`)
+	if body := decodeBody(t, resp); resp.StatusCode != http.StatusOK || body["role"] != domain.RoleUser {
+		t.Fatalf("demote: %d %v", resp.StatusCode, body)
+	}
+	if s, _ := sessions.GetByToken(ctx, "sam-session"); s != nil {
+		t.Errorf("expected the demoted user's sessions revoked, got %+v", s)
+	}
+	if list, _ := db.ListAPITokens(ctx, sam.ID); len(list) != 0 {
+		t.Errorf("expected the demoted user's tokens revoked, got %+v", list)
+	}
+	resp = put("sam", "/api/v1/admin/users/owner/role", `{"role":"user"}`)
+	resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 once demoted, got %d", resp.StatusCode)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	sam, _ := db.Create(ctx, "sam", string(hash))
+	sessions := db.NewSessionRepo()
+	for _, token := range []string{"this-session", "other-session"} {
+		if err := sessions.Create(ctx, sam.ID, token, "test", "10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.CreateAPIToken(ctx, sam.ID, "script", domain.TokenScopeAPI, "sam-hash"); err != nil {
+		t.Fatal(err)
+	}
+	authSvc := app.NewAuthService(db, sessions).WithAPITokens(db)
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db), authSvc, t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(payload string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/auth/password", strings.NewReader(payload))
+		req.Header.Set("User-Agent", "test")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "this-session"})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp
+	}
+
+	if resp := post(`{"currentPassword":"wrong","password":"new-password"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong current password, got %d", resp.StatusCode)
+	}
+	if resp := post(`{"currentPassword":"old-password","password":"short"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a short password, got %d", resp.StatusCode)
+	}
+	if s, _ := sessions.GetByToken(ctx, "other-session"); s == nil {
+		t.Fatal("expected failed changes to leave sessions alone")
+	}
+
+	resp := post(`{"currentPassword":"old-password","password":"new-password"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value == "this-session" {
+		t.Fatalf("expected a new session cookie, got %v", cookie)
+	}
+	if s, _ := sessions.GetByToken(ctx, cookie.Value); s == nil || s.UserID != sam.ID {
+		t.Errorf("expected the new session to be sam's, got %+v", s)
+	}
+	for _, token := range []string{"this-session", "other-session"} {
+		if s, _ := sessions.GetByToken(ctx, token); s != nil {
+			t.Errorf("expected session %s revoked", token)
+		}
+	}
+	if list, _ := db.ListAPITokens(ctx, sam.ID); len(list) != 0 {
+		t.Errorf("expected tokens revoked, got %+v", list)
+	}
+	if _, err := authSvc.Login(ctx, "sam", "new-password", "test", "10.0.0.2"); err != nil {
+		t.Errorf("expected the new password to work: %v", err)
+	}
+	if _, err := authSvc.Login(ctx, "sam", "old-password", "test", "10.0.0.2"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected the old password to fail, got %v", err)
+	}
+}
+
+func TestSPAPages(t *testing.T) {
+	webDir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":    "index",
+		"charts.html":   "charts",
+		"settings.html": "settings",
+		"goals.html":    "goals",
+		"history.html":  "history",
+		"styles.css":    "css",
+	} {
+		if err := os.WriteFile(filepath.Join(webDir, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages, err := adapthttp.ParsePages("/history=history.html, /goals/=goals.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := adapthttp.New(app.NewWeightService(&mockWeightRepo{}), app.NewWaterService(&mockWaterRepo{}),
+		app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}),
+		app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}), webDir).
+		WithoutAuth().
+		WithPages(pages)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/":             "index",
+		"/charts":       "charts",
+		"/settings":     "settings",
+		"/history":      "history",
+		"/goals":        "goals",
+		"/goals/12":     "goals",
+		"/styles.css":   "css",
+		"/unknown/page": "index",
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("GET %s = %q, want %q", path, body, want)
+		}
+	}
+
+	for _, spec := range []string{"history=history.html", "/x=../secret.html", "/x=/etc/passwd", "/api/x=x.html", "/x="} {
+		if _, err := adapthttp.ParsePages(spec); err == nil {
+			t.Errorf("ParsePages(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestFormatHints(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	sam, _ := db.Create(ctx, "sam", "")
+	sessions := db.NewSessionRepo()
+	if err := sessions.Create(ctx, sam.ID, "sam-session", "test", "10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(db, sessions), t.TempDir()).
+		WithAccounts(app.NewAccountService(db, db)).
+		WithSettings(app.NewSettingsService(db).WithDefaultLocale("en-GB"))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, payload string, out any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(payload))
+		req.Header.Set("User-Agent", "test")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "sam-session"})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d", method, path, resp.StatusCode)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
 			}
-		})
+		}
+	}
+
+	var config struct {
+		Format app.FormatHints `json:"format"`
+	}
+	do(http.MethodGet, "/api/auth/config", "", &config)
+	if want := (app.FormatHints{Locale: "en-GB", DecimalSeparator: ".", FirstDayOfWeek: "monday"}); config.Format != want {
+		t.Errorf("config format = %+v, want %+v", config.Format, want)
+	}
+
+	do(http.MethodPut, "/api/settings", `{"ui.locale": "de"}`, nil)
+	var acct app.Account
+	do(http.MethodGet, "/api/account", "", &acct)
+	if want := (app.FormatHints{Locale: "de", DecimalSeparator: ",", FirstDayOfWeek: "monday"}); acct.Format == nil || *acct.Format != want {
+		t.Errorf("account format = %+v, want %+v", acct.Format, want)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	sam, _ := db.Create(ctx, "sam", string(hash))
+	alex, _ := db.Create(ctx, "alex", string(hash))
+	kid, _ := db.CreateProfile(ctx, sam.ID, "kid")
+	sessions := db.NewSessionRepo()
+	now := time.Now()
+	for _, id := range []int64{sam.ID, kid.ID, alex.ID} {
+		if _, err := db.AddWaterEvent(ctx, id, 0.5, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.AddWeightEvent(ctx, id, 80, "kg", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for token, userID := range map[string]int64{"sam-session": sam.ID, "alex-session": alex.ID} {
+		if err := sessions.Create(ctx, userID, token, "test", "10.0.0.1", now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.SetRole(ctx, alex.ID, domain.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(db, sessions), t.TempDir())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	del := func(session, payload string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/auth/account", strings.NewReader(payload))
+		req.Header.Set("User-Agent", "test")
+		req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		return resp
+	}
+
+	if resp := del("sam-session", `{"password":"wrong"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong password, got %d", resp.StatusCode)
+	}
+	if u, _ := db.GetByID(ctx, sam.ID); u == nil {
+		t.Fatal("expected a wrong password to keep the account")
+	}
+	if resp := del("alex-session", `{"password":"password"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for an admin, got %d", resp.StatusCode)
+	}
+
+	resp := del("sam-session", `{"password":"password"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if c := resp.Cookies(); len(c) != 1 || c[0].Name != "session" || c[0].MaxAge >= 0 {
+		t.Errorf("expected the session cookie cleared, got %v", c)
+	}
+	if u, _ := db.GetByID(ctx, sam.ID); u != nil {
+		t.Error("expected the account deleted")
+	}
+	if s, _ := sessions.GetByToken(ctx, "sam-session"); s != nil {
+		t.Error("expected the session deleted")
+	}
+	for _, id := range []int64{sam.ID, kid.ID} {
+		water, _ := db.ListRecentWaterEvents(ctx, id, 10)
+		weights, _ := db.ListRecentWeightEvents(ctx, id, 10)
+		if len(water) != 0 || len(weights) != 0 {
+			t.Errorf("user %d: expected no events left, got %v and %v", id, water, weights)
+		}
+	}
+	if water, _ := db.ListRecentWaterEvents(ctx, alex.ID, 10); len(water) != 1 {
+		t.Errorf("expected other users' data kept, got %v", water)
+	}
+}
+
+// mailerFunc adapts a function to domain.Mailer.
+type mailerFunc func(to, subject, body string) error
+
+func (f mailerFunc) SendMail(_ context.Context, to, subject, body string) error {
+	return f(to, subject, body)
+}
+
+func TestAuthEmailAlias(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	sam, _ := db.Create(ctx, "sam", "")
+	sessions := db.NewSessionRepo()
+	if err := sessions.Create(ctx, sam.ID, "sam-session", "test", "10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	mailer := mailerFunc(func(to, _, _ string) error {
+		sent = append(sent, to)
+		return nil
+	})
+	srv := adapthttp.New(app.NewWeightService(db), app.NewWaterService(db), app.NewChartsService(db, db),
+		app.NewAuthService(db, sessions), t.TempDir()).
+		WithAccounts(app.NewAccountService(db, db).WithMailer(mailer, "https://vitals.example/api/auth/verify-email"))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for path, email := range map[string]string{"/api/account/email": "sam@example.com", "/api/auth/email": "sam@example.org"} {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+path, strings.NewReader(`{"email": "`+email+`"}`))
+		req.Header.Set("User-Agent", "test")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "sam-session"})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted || !slices.Contains(sent, email) {
+			t.Errorf("PUT %s: expected a verification mail to %s, got %d %v", path, email, resp.StatusCode, sent)
+		}
 	}
 }
@@ -0,0 +1,56 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if s.tokens == nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := userFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.tokens.List(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items})
+
+	case http.MethodPost:
+		var body struct {
+			Name  string `json:"name"`
+			Scope string `json:"scope"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		secret, tok, err := s.tokens.Create(r.Context(), user.ID, body.Name, body.Scope)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"token": secret, "item": tok})
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("id must be a token id"))
+			return
+		}
+		if err := s.tokens.Revoke(r.Context(), user.ID, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -1,8 +1,13 @@
 package adapthttp
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
+
+	"vitals/internal/domain"
 )
 
 func (s *Server) handleWaterToday(w http.ResponseWriter, r *http.Request) {
@@ -10,14 +15,85 @@ func (s *Server) handleWaterToday(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
-	today := localDayString(time.Now())
-	total, err := s.water.GetTodayTotal(r.Context(), user.ID, today)
+	subject := subjectFromContext(r)
+	now := time.Now()
+	today := localDayString(now)
+	resp := map[string]any{"today": today}
+	var extra string
+	var goal *domain.HydrationGoal
+	if s.hydration != nil {
+		var err error
+		if goal, err = s.hydration.TodayGoal(r.Context(), subject); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp["goal"] = goal
+		// The pace moves with the clock as well as with intake.
+		b, _ := json.Marshal(goal)
+		extra = fmt.Sprintf("%s %g", b, domain.ExpectedWaterFraction(now))
+	}
+	notModified, err := s.checkToday(w, r, domain.ChangeEntityWater, today, extra)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if notModified {
+		return
+	}
+	total, err := s.water.GetTodayTotal(r.Context(), subject, today)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"today": today, "totalLiters": total})
+	resp["totalLiters"] = total
+	if goal != nil {
+		resp["pace"] = domain.PaceAt(total, goal.Liters, now)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleWaterSettings reads or replaces the daily goal and location.
+func (s *Server) handleWaterSettings(w http.ResponseWriter, r *http.Request) {
+	if s.hydration == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		hs, err := s.hydration.Settings(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": hs})
+
+	case http.MethodPut:
+		var body struct {
+			BaseGoalLiters float64  `json:"baseGoalLiters"`
+			Latitude       *float64 `json:"latitude"`
+			Longitude      *float64 `json:"longitude"`
+		}
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		hs, err := s.hydration.SaveSettings(r.Context(), domain.HydrationSettings{
+			UserID:         subject,
+			BaseGoalLiters: body.BaseGoalLiters,
+			Latitude:       body.Latitude,
+			Longitude:      body.Longitude,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"settings": hs})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
 }
 
 func (s *Server) handleWaterEvent(w http.ResponseWriter, r *http.Request) {
@@ -25,20 +101,67 @@ func (s *Server) handleWaterEvent(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
+	subject := subjectFromContext(r)
 	var body struct {
-		DeltaLiters float64 `json:"deltaLiters"`
+		DeltaLiters float64   `json:"deltaLiters"`
+		ClientID    string    `json:"clientId"`
+		CreatedAt   time.Time `json:"createdAt"`
 	}
 	if err := parseJSON(r, &body); err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	id, err := s.water.RecordEvent(r.Context(), user.ID, body.DeltaLiters)
+	if body.ClientID != "" {
+		event, created, err := s.water.RecordEventWithClientID(r.Context(), subject, body.ClientID, body.DeltaLiters, body.CreatedAt)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": event.ID, "event": event, "created": created})
+		return
+	}
+	rec, err := s.water.RecordEvent(r.Context(), subject, body.DeltaLiters)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"id": id})
+	resp := map[string]any{"id": rec.ID}
+	if rec.Duplicate {
+		resp["duplicate"] = true
+		resp["merged"] = rec.Merged
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleWaterEventEdit corrects the volume or time of the water event
+// addressed by the {id} path segment.
+func (s *Server) handleWaterEventEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, domain.Errorf(domain.ErrInvalidArgument, "id must be an integer"))
+		return
+	}
+	var body struct {
+		DeltaLiters *float64   `json:"deltaLiters"`
+		CreatedAt   *time.Time `json:"createdAt"`
+	}
+	if err := parseJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	event, err := s.water.EditEvent(r.Context(), subjectFromContext(r), id, domain.WaterEventPatch{
+		DeltaLiters: body.DeltaLiters,
+		CreatedAt:   body.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"event": event})
 }
 
 func (s *Server) handleWaterRecent(w http.ResponseWriter, r *http.Request) {
@@ -46,14 +169,23 @@ func (s *Server) handleWaterRecent(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
-	limit := intQuery(r, "limit", 20)
-	items, err := s.water.ListRecent(r.Context(), user.ID, limit)
+	subject := subjectFromContext(r)
+	limit, err := s.intQuery(r, "water/recent", "limit", 20)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := tagFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, sum, err := s.water.ListRecent(r.Context(), subject, limit, filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	writeList(w, r, items, summaryFields(sum))
 }
 
 func (s *Server) handleWaterUndoLast(w http.ResponseWriter, r *http.Request) {
@@ -61,8 +193,8 @@ func (s *Server) handleWaterUndoLast(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
-	undone, id, err := s.water.UndoLast(r.Context(), user.ID)
+	subject := subjectFromContext(r)
+	undone, id, err := s.water.UndoLast(r.Context(), subject)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
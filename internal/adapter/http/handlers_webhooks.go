@@ -0,0 +1,130 @@
+package adapthttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vitals/internal/domain"
+)
+
+// webhookBody is the request body for creating or replacing a webhook.
+type webhookBody struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// webhook returns the webhook b describes; it is enabled unless b says
+// otherwise.
+func (b webhookBody) webhook(userID, id int64) domain.Webhook {
+	return domain.Webhook{
+		ID: id, UserID: userID, URL: b.URL, Events: b.Events,
+		Enabled: b.Enabled == nil || *b.Enabled,
+	}
+}
+
+// handleWebhooks lists or registers webhooks. The response to a
+// registration is the only one to include the webhook's signing secret.
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.webhooks.List(r.Context(), subject)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items, "events": domain.WebhookEvents})
+
+	case http.MethodPost:
+		var body webhookBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		hook, err := s.webhooks.Create(r.Context(), body.webhook(subject, 0))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"webhook": hook})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhook replaces or deletes the webhook addressed by the {id} path
+// segment.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject := subjectFromContext(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body webhookBody
+		if err := parseJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		hook, err := s.webhooks.Update(r.Context(), body.webhook(subject, id))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhook": hook})
+
+	case http.MethodDelete:
+		if err := s.webhooks.Delete(r.Context(), subject, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhookDeliveries returns the delivery log of the webhook addressed
+// by the {id} path segment, newest first; ?limit= caps it (default 50).
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+	limit, err := s.intQuery(r, "webhooks/deliveries", "limit", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := s.webhooks.Deliveries(r.Context(), subjectFromContext(r), id, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
@@ -3,32 +3,58 @@ package adapthttp
 import (
 	"net/http"
 	"time"
+
+	"vitals/internal/domain"
 )
 
 func (s *Server) handleWeightToday(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	user := userFromContext(r)
+	subject := subjectFromContext(r)
 	today := localDayString(time.Now())
 
 	switch r.Method {
 	case http.MethodGet:
-		entry, err := s.weight.GetTodayWeight(ctx, user.ID, today)
+		notModified, err := s.checkToday(w, r, domain.ChangeEntityWeight, today, "")
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry})
+		if notModified {
+			return
+		}
+		entry, err := s.weight.GetTodayWeight(ctx, subject, today)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		trend, err := s.weight.Trends(ctx, subject, today)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry, "trend": trend})
 
 	case http.MethodPut:
 		var body struct {
-			Value float64 `json:"value"`
-			Unit  string  `json:"unit"`
+			Value     float64   `json:"value"`
+			Unit      string    `json:"unit"`
+			ClientID  string    `json:"clientId"`
+			CreatedAt time.Time `json:"createdAt"`
 		}
 		if err := parseJSON(r, &body); err != nil {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
-		entry, _, err := s.weight.RecordWeight(ctx, user.ID, body.Value, body.Unit)
+		if body.ClientID != "" {
+			entry, created, err := s.weight.RecordWeightWithClientID(ctx, subject, body.ClientID, body.Value, body.Unit, body.CreatedAt)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"today": today, "entry": entry, "created": created})
+			return
+		}
+		entry, _, err := s.weight.RecordWeight(ctx, subject, body.Value, body.Unit)
 		if err != nil {
 			writeError(w, http.StatusBadRequest, err)
 			return
@@ -45,14 +71,28 @@ func (s *Server) handleWeightRecent(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
-	limit := intQuery(r, "limit", 14)
-	items, err := s.weight.ListRecent(r.Context(), user.ID, limit)
+	subject := subjectFromContext(r)
+	limit, err := s.intQuery(r, "weight/recent", "limit", 14)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := tagFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	unit := r.URL.Query().Get("unit")
+	if unit != "" && unit != "kg" && unit != "lb" {
+		writeError(w, http.StatusBadRequest, domain.Errorf(domain.ErrInvalidUnit, "unit must be \"kg\" or \"lb\""))
+		return
+	}
+	items, sum, err := s.weight.ListRecent(r.Context(), subject, limit, filter, unit)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	writeList(w, r, items, summaryFields(sum))
 }
 
 func (s *Server) handleWeightUndoLast(w http.ResponseWriter, r *http.Request) {
@@ -60,8 +100,8 @@ func (s *Server) handleWeightUndoLast(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	user := userFromContext(r)
-	deleted, entry, today, err := s.weight.UndoLast(r.Context(), user.ID)
+	subject := subjectFromContext(r)
+	deleted, entry, today, err := s.weight.UndoLast(r.Context(), subject)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
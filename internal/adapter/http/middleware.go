@@ -1,18 +1,36 @@
 package adapthttp
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"html"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	"vitals/internal/app"
 	"vitals/internal/domain"
 )
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey      contextKey = "user"
+	subjectContextKey   contextKey = "subject"
+	dashboardContextKey contextKey = "dashboard"
+)
 
 // userFromContext returns the authenticated user from the request context.
 func userFromContext(r *http.Request) *domain.User {
@@ -22,44 +40,265 @@ func userFromContext(r *http.Request) *domain.User {
 	return nil
 }
 
-// authMiddleware validates session tokens and forward auth headers.
-func (s *Server) authMiddleware(next http.Handler) http.Handler {
+// subjectFromContext returns the ID whose metrics the request addresses, as
+// resolved by authorize. It falls back to the authenticated user.
+func subjectFromContext(r *http.Request) int64 {
+	if id, ok := r.Context().Value(subjectContextKey).(int64); ok {
+		return id
+	}
+	return userFromContext(r).ID
+}
+
+// isDashboard reports whether the request was authenticated with a
+// dashboard-scoped token rather than a session.
+func isDashboard(r *http.Request) bool {
+	ok, _ := r.Context().Value(dashboardContextKey).(bool)
+	return ok
+}
+
+// authorize is the API's authorization middleware. It authenticates the
+// caller with a credential policy accepts, resolves whose data a metric
+// request addresses and lets the request through only if policy allows it
+// and it passes validateRequest. Read-only callers run under a read-only
+// context, so services reject their writes too.
+func (s *Server) authorize(policy app.Policy, next http.HandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth if disabled (for tests / dev) — inject a default user
-		if s.disableAuth {
-			ctx := context.WithValue(r.Context(), userContextKey, &domain.User{ID: 0, Username: "dev"})
-			next.ServeHTTP(w, r.WithContext(ctx))
+		var (
+			pr app.Principal
+			ok bool
+		)
+		if policy.TokenOnly || len(policy.Scopes) > 0 && hasToken(r) {
+			pr, ok = s.authenticateToken(w, r, policy)
+		} else {
+			pr, ok = s.authenticateSession(w, r)
+		}
+		if !ok {
 			return
 		}
 
-		// Check for Authelia forward auth header first
-		if remoteUser := r.Header.Get("Remote-User"); remoteUser != "" {
-			user, err := s.authSvc.ValidateForwardAuth(r.Context(), remoteUser)
-			if err == nil && user != nil {
-				ctx := context.WithValue(r.Context(), userContextKey, user)
-				next.ServeHTTP(w, r.WithContext(ctx))
+		ctx := r.Context()
+		subject := pr.User.ID
+		switch {
+		case pr.Credential == app.CredentialToken:
+		case policy.Subject:
+			if subject, ok = s.resolveSubject(w, r, &pr); !ok {
 				return
 			}
+		case r.URL.Query().Get("user") != "":
+			// Only the caller's own data is reachable here; refuse
+			// rather than quietly answer for the caller instead.
+			writeError(w, http.StatusForbidden, app.ErrNotShared)
+			return
 		}
-
-		// Fall back to cookie-based session
-		cookie, err := r.Cookie("session")
-		if err != nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		if err := policy.Authorize(pr, !isSafeMethod(r.Method)); err != nil {
+			writeError(w, http.StatusForbidden, err)
 			return
 		}
+		if err := s.validateRequest(w, r); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeError(w, status, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, userContextKey, pr.User)
+		ctx = context.WithValue(ctx, subjectContextKey, subject)
+		if pr.ReadOnly() {
+			ctx = app.WithReadOnly(ctx)
+		}
+		if pr.Scope == domain.TokenScopeDashboard {
+			ctx = context.WithValue(ctx, dashboardContextKey, true)
+		}
+		next(w, r.WithContext(ctx))
+	})
+}
 
+// isSafeMethod reports whether requests with method only read.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// hasToken reports whether the request carries an API token, as ?token= or
+// an "Authorization: Bearer" header.
+func hasToken(r *http.Request) bool {
+	return r.URL.Query().Has("token") || strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authenticateSession authenticates the caller by forward-auth header or
+// session cookie, falling back to the guest account in guest mode. It
+// responds 401 and reports false when there is no valid credential.
+func (s *Server) authenticateSession(w http.ResponseWriter, r *http.Request) (app.Principal, bool) {
+	// Skip auth if disabled (for tests / dev) — act as a default user
+	if s.disableAuth {
+		return app.Principal{User: &domain.User{ID: 0, Username: "dev", Role: domain.RoleAdmin}, Credential: app.CredentialSession}, true
+	}
+
+	// Check for Authelia forward auth header first
+	if remoteUser := r.Header.Get("Remote-User"); remoteUser != "" {
+		user, err := s.authSvc.ValidateForwardAuth(r.Context(), remoteUser)
+		if err == nil && user != nil {
+			return app.Principal{User: user, Credential: app.CredentialSession}, true
+		}
+	}
+
+	// Fall back to cookie-based session
+	if cookie, err := r.Cookie("session"); err == nil {
 		user, err := s.authSvc.ValidateSession(r.Context(), cookie.Value, r.UserAgent())
-		if err == app.ErrSessionNotFound || err == app.ErrSessionExpired {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
+		if err == nil {
+			return app.Principal{User: user, Credential: app.CredentialSession}, true
 		}
-		if err != nil {
+		if err != app.ErrSessionNotFound && err != app.ErrSessionExpired {
 			http.Error(w, "internal error", http.StatusInternalServerError)
+			return app.Principal{}, false
+		}
+	}
+
+	// In guest mode, requests without valid credentials browse the
+	// read-only demo account.
+	if s.guestUser != "" {
+		if user, err := s.authSvc.GuestUser(r.Context(), s.guestUser); err == nil {
+			return app.Principal{User: user, Credential: app.CredentialGuest}, true
+		}
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return app.Principal{}, false
+}
+
+// authenticateToken authenticates the caller by an API token with one of
+// the scopes policy accepts. Failures are reported in plain text for the
+// automation clients that use tokens.
+func (s *Server) authenticateToken(w http.ResponseWriter, r *http.Request, policy app.Policy) (app.Principal, bool) {
+	if s.tokens == nil {
+		http.NotFound(w, r)
+		return app.Principal{}, false
+	}
+	secret := r.URL.Query().Get("token")
+	if secret == "" {
+		secret = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	user, scope, err := s.tokens.AuthenticateAny(r.Context(), secret, policy.Scopes)
+	if errors.Is(err, app.ErrInvalidToken) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return app.Principal{}, false
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return app.Principal{}, false
+	}
+	return app.Principal{User: user, Credential: app.CredentialToken, Scope: scope}, true
+}
+
+// resolveSubject resolves which data a metric request addresses: the
+// caller's own (the default), one of their profiles via ?profile=<id>, or
+// another user's via ?user=<id> when that user has shared with the caller,
+// which it records as pr's OwnerID. It responds and reports false when the
+// subject is not the caller's to read.
+func (s *Server) resolveSubject(w http.ResponseWriter, r *http.Request, pr *app.Principal) (int64, bool) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	profile, owner := q.Get("profile"), q.Get("user")
+	switch {
+	case profile != "" && owner != "":
+		writeError(w, http.StatusBadRequest, errors.New("profile and user are mutually exclusive"))
+		return 0, false
+
+	case profile != "":
+		id, err := strconv.ParseInt(profile, 10, 64)
+		if err != nil || s.profiles == nil {
+			writeError(w, http.StatusNotFound, app.ErrProfileNotFound)
+			return 0, false
+		}
+		subject, err := s.profiles.Resolve(ctx, pr.User.ID, id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return 0, false
+		}
+		return subject, true
+
+	case owner != "":
+		id, err := strconv.ParseInt(owner, 10, 64)
+		if err != nil || s.shares == nil {
+			writeError(w, http.StatusNotFound, app.ErrShareNotFound)
+			return 0, false
+		}
+		if id == pr.User.ID {
+			return id, true
+		}
+		if _, err := s.shares.Authorize(ctx, pr.User.ID, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return 0, false
+		}
+		pr.OwnerID = id
+		return id, true
+	}
+	return pr.User.ID, true
+}
+
+// maintenanceWritePaths stay writable in maintenance mode: signing in and
+// out, and the admin endpoints that turn the mode off. They are matched
+// under /api/v1 too.
+var maintenanceWritePaths = []string{"/api/auth/login", "/api/auth/logout", "/api/auth/oidc/", "/api/admin/"}
+
+// maintenanceMiddleware answers writes with 503 while maintenance mode is
+// on; reads are served as usual. Browsers asking for HTML get a page, other
+// clients JSON.
+func (s *Server) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maintenance == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		mode := s.maintenance.Mode()
+		path := strings.Replace(r.URL.Path, "/api/v1/", "/api/", 1)
+		if !mode.Enabled || slices.ContainsFunc(maintenanceWritePaths, func(p string) bool {
+			return strings.HasPrefix(path, p)
+		}) {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userContextKey, user)
+		w.Header().Set("Retry-After", "120")
+		if !strings.HasPrefix(r.URL.Path, "/api/") && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintf(w, maintenancePage, html.EscapeString(mode.Message))
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": mode.Message, "maintenance": mode})
+	})
+}
+
+const maintenancePage = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Maintenance · Vitals</title></head>
+<body style="font-family: system-ui, sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem;">
+<h1>Back soon</h1>
+<p>%s</p>
+<p><a href="/">Return to Vitals</a>; your data is still available to view.</p>
+</body>
+</html>
+`
+
+// timeoutMiddleware bounds each request's context by s.requestTimeout so
+// that repository calls give up instead of waiting forever on a stuck
+// database. Requests matching a route in streams, the Server-Sent Event
+// streams that are long-lived by design, are exempt; what the client
+// accepts plays no part.
+func (s *Server) timeoutMiddleware(streams *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := streams.Handler(r); s.requestTimeout <= 0 || pattern != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -86,6 +325,151 @@ func (rw *loggingResponseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes through to the underlying writer so streaming responses work.
+func (rw *loggingResponseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+var tracer = otel.Tracer("vitals/internal/adapter/http")
+
+// traceMiddleware starts a server span for each request, continuing the
+// trace of the caller's traceparent header, if any. routeSpan names it.
+func (s *Server) traceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		))
+		defer span.End()
+
+		rw := &loggingResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rw.code))
+		if rw.code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.code))
+		}
+	})
+}
+
+// routeSpan renames the request's span after the mux pattern it matches,
+// e.g. "GET /api/weights/{id}", so spans group by route rather than by
+// path. prefix is what was stripped before mux sees the request.
+func routeSpan(mux *http.ServeMux, prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			if _, p, ok := strings.Cut(pattern, " "); ok {
+				pattern = p
+			}
+			route := prefix + pattern
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(attribute.String("http.route", route))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnversionedDeprecatedAt is when /api/v1 superseded the unversioned /api
+// paths, as announced in their Deprecation header.
+var UnversionedDeprecatedAt = time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+// RouteUsage counts the requests to one unversioned API route.
+type RouteUsage struct {
+	Route      string    `json:"route"`
+	Count      int64     `json:"count"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// usageCounter counts requests per route since the server started.
+type usageCounter struct {
+	mu     sync.Mutex
+	since  time.Time
+	routes map[string]*RouteUsage
+}
+
+func newUsageCounter() *usageCounter {
+	return &usageCounter{since: time.Now().UTC(), routes: map[string]*RouteUsage{}}
+}
+
+func (c *usageCounter) add(route string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.routes[route]
+	if !ok {
+		u = &RouteUsage{Route: route}
+		c.routes[route] = u
+	}
+	u.Count++
+	u.LastSeenAt = at.UTC()
+}
+
+// snapshot returns the counts, most used first.
+func (c *usageCounter) snapshot() []RouteUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]RouteUsage, 0, len(c.routes))
+	for _, u := range c.routes {
+		out = append(out, *u)
+	}
+	slices.SortFunc(out, func(a, b RouteUsage) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Route, b.Route)
+	})
+	return out
+}
+
+// deprecateUnversioned marks responses to the unversioned /api paths as
+// deprecated in favour of /api/v1 (RFC 9745), with a Sunset header (RFC
+// 8594) once one is configured, and counts their use per route, for
+// operators to tell when old clients are gone. r.URL.Path has had /api
+// stripped.
+func (s *Server) deprecateUnversioned(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Deprecation", "@"+strconv.FormatInt(UnversionedDeprecatedAt.Unix(), 10))
+		h.Set("Link", "</api/v1"+r.URL.EscapedPath()+`>; rel="successor-version"`)
+		if !s.sunset.IsZero() {
+			h.Set("Sunset", s.sunset.UTC().Format(http.TimeFormat))
+		}
+		if _, pattern := mux.Handler(r); pattern != "" {
+			if _, p, ok := strings.Cut(pattern, " "); ok {
+				pattern = p
+			}
+			s.unversioned.add(r.Method+" /api"+pattern, time.Now())
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.Bool("http.deprecated", true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// frameOptions tells browsers who may show responses in a frame: /embed
+// pages the app's own origin and the frame ancestors configured with
+// WithFrameAncestors, everything else only the app's own origin.
+func (s *Server) frameOptions(next http.Handler) http.Handler {
+	embed := "frame-ancestors " + strings.Join(append([]string{"'self'"}, s.frameAncestors...), " ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if strings.HasPrefix(r.URL.Path, "/embed/") {
+			h.Set("Content-Security-Policy", embed)
+			// X-Frame-Options cannot name other origins; browsers that
+			// understand frame-ancestors ignore it anyway.
+			if len(s.frameAncestors) == 0 {
+				h.Set("X-Frame-Options", "SAMEORIGIN")
+			}
+		} else {
+			h.Set("Content-Security-Policy", "frame-ancestors 'self'")
+			h.Set("X-Frame-Options", "SAMEORIGIN")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // requireAuthHTML enforces authentication for HTML pages, redirecting to login if needed.
 func (s *Server) requireAuthHTML(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -105,14 +489,17 @@ func (s *Server) requireAuthHTML(next http.Handler) http.Handler {
 		}
 
 		// Check session cookie
+		var user *domain.User
 		cookie, err := r.Cookie("session")
-		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusFound)
-			return
+		if err == nil {
+			user, err = s.authSvc.ValidateSession(r.Context(), cookie.Value, r.UserAgent())
 		}
-
-		user, err := s.authSvc.ValidateSession(r.Context(), cookie.Value, r.UserAgent())
 		if err != nil {
+			if s.guestUser != "" {
+				// Guests browse the SPA; the API enforces read-only access.
+				next.ServeHTTP(w, r)
+				return
+			}
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
@@ -0,0 +1,56 @@
+package adapthttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	s := &Server{requestTimeout: 20 * time.Millisecond}
+	streams := http.NewServeMux()
+	streams.Handle("/api/import/jobs/{id}/events", http.NotFoundHandler())
+	handler := s.timeoutMiddleware(streams, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// Simulate a repository call stuck on the database.
+		<-r.Context().Done()
+		writeError(w, http.StatusInternalServerError, r.Context().Err())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/weight/today", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after the deadline, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/import/jobs/1/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected event streams to have no deadline, got %d", w.Code)
+	}
+
+	// Asking for an event stream does not lift the deadline elsewhere.
+	req = httptest.NewRequest("GET", "/api/weight/today", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after the deadline despite the Accept header, got %d", w.Code)
+	}
+}
+
+func TestTimeoutMiddleware_Disabled(t *testing.T) {
+	s := &Server{}
+	handler := s.timeoutMiddleware(http.NewServeMux(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline when the timeout is zero")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
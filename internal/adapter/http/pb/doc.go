@@ -0,0 +1,5 @@
+// Package pb holds the protobuf messages served to clients that send
+// "Accept: application/x-protobuf".
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative vitals.proto
@@ -0,0 +1,657 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: vitals.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WeightEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Day           string                 `protobuf:"bytes,3,opt,name=day,proto3" json:"day,omitempty"`
+	Value         float64                `protobuf:"fixed64,4,opt,name=value,proto3" json:"value,omitempty"`
+	Unit          string                 `protobuf:"bytes,5,opt,name=unit,proto3" json:"unit,omitempty"`
+	ClientId      string                 `protobuf:"bytes,6,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	CreatedAtMs   int64                  `protobuf:"varint,7,opt,name=created_at_ms,json=createdAtMs,proto3" json:"created_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WeightEntry) Reset() {
+	*x = WeightEntry{}
+	mi := &file_vitals_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WeightEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WeightEntry) ProtoMessage() {}
+
+func (x *WeightEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WeightEntry.ProtoReflect.Descriptor instead.
+func (*WeightEntry) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WeightEntry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WeightEntry) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WeightEntry) GetDay() string {
+	if x != nil {
+		return x.Day
+	}
+	return ""
+}
+
+func (x *WeightEntry) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *WeightEntry) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *WeightEntry) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *WeightEntry) GetCreatedAtMs() int64 {
+	if x != nil {
+		return x.CreatedAtMs
+	}
+	return 0
+}
+
+type WaterEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	DeltaLiters   float64                `protobuf:"fixed64,3,opt,name=delta_liters,json=deltaLiters,proto3" json:"delta_liters,omitempty"`
+	ClientId      string                 `protobuf:"bytes,4,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	CreatedAtMs   int64                  `protobuf:"varint,5,opt,name=created_at_ms,json=createdAtMs,proto3" json:"created_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WaterEvent) Reset() {
+	*x = WaterEvent{}
+	mi := &file_vitals_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WaterEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaterEvent) ProtoMessage() {}
+
+func (x *WaterEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaterEvent.ProtoReflect.Descriptor instead.
+func (*WaterEvent) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WaterEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WaterEvent) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WaterEvent) GetDeltaLiters() float64 {
+	if x != nil {
+		return x.DeltaLiters
+	}
+	return 0
+}
+
+func (x *WaterEvent) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *WaterEvent) GetCreatedAtMs() int64 {
+	if x != nil {
+		return x.CreatedAtMs
+	}
+	return 0
+}
+
+type Change struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Entity        string                 `protobuf:"bytes,2,opt,name=entity,proto3" json:"entity,omitempty"`
+	Id            int64                  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Op            string                 `protobuf:"bytes,4,opt,name=op,proto3" json:"op,omitempty"`
+	ChangedAtMs   int64                  `protobuf:"varint,5,opt,name=changed_at_ms,json=changedAtMs,proto3" json:"changed_at_ms,omitempty"`
+	Weight        *WeightEntry           `protobuf:"bytes,6,opt,name=weight,proto3" json:"weight,omitempty"`
+	Water         *WaterEvent            `protobuf:"bytes,7,opt,name=water,proto3" json:"water,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Change) Reset() {
+	*x = Change{}
+	mi := &file_vitals_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Change) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Change) ProtoMessage() {}
+
+func (x *Change) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Change.ProtoReflect.Descriptor instead.
+func (*Change) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Change) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Change) GetEntity() string {
+	if x != nil {
+		return x.Entity
+	}
+	return ""
+}
+
+func (x *Change) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Change) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *Change) GetChangedAtMs() int64 {
+	if x != nil {
+		return x.ChangedAtMs
+	}
+	return 0
+}
+
+func (x *Change) GetWeight() *WeightEntry {
+	if x != nil {
+		return x.Weight
+	}
+	return nil
+}
+
+func (x *Change) GetWater() *WaterEvent {
+	if x != nil {
+		return x.Water
+	}
+	return nil
+}
+
+// SyncPage is the response of GET /api/sync.
+type SyncPage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cursor        int64                  `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	HasMore       bool                   `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	Changes       []*Change              `protobuf:"bytes,3,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncPage) Reset() {
+	*x = SyncPage{}
+	mi := &file_vitals_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncPage) ProtoMessage() {}
+
+func (x *SyncPage) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncPage.ProtoReflect.Descriptor instead.
+func (*SyncPage) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SyncPage) GetCursor() int64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *SyncPage) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *SyncPage) GetChanges() []*Change {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type WeightPoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	Unit          string                 `protobuf:"bytes,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WeightPoint) Reset() {
+	*x = WeightPoint{}
+	mi := &file_vitals_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WeightPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WeightPoint) ProtoMessage() {}
+
+func (x *WeightPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WeightPoint.ProtoReflect.Descriptor instead.
+func (*WeightPoint) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WeightPoint) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *WeightPoint) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+type DayPoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Day           string                 `protobuf:"bytes,1,opt,name=day,proto3" json:"day,omitempty"`
+	WaterLiters   float64                `protobuf:"fixed64,2,opt,name=water_liters,json=waterLiters,proto3" json:"water_liters,omitempty"`
+	Weight        *WeightPoint           `protobuf:"bytes,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	Note          string                 `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
+	GoalLiters    float64                `protobuf:"fixed64,5,opt,name=goal_liters,json=goalLiters,proto3" json:"goal_liters,omitempty"`
+	GoalMet       bool                   `protobuf:"varint,6,opt,name=goal_met,json=goalMet,proto3" json:"goal_met,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DayPoint) Reset() {
+	*x = DayPoint{}
+	mi := &file_vitals_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DayPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DayPoint) ProtoMessage() {}
+
+func (x *DayPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DayPoint.ProtoReflect.Descriptor instead.
+func (*DayPoint) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DayPoint) GetDay() string {
+	if x != nil {
+		return x.Day
+	}
+	return ""
+}
+
+func (x *DayPoint) GetWaterLiters() float64 {
+	if x != nil {
+		return x.WaterLiters
+	}
+	return 0
+}
+
+func (x *DayPoint) GetWeight() *WeightPoint {
+	if x != nil {
+		return x.Weight
+	}
+	return nil
+}
+
+func (x *DayPoint) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *DayPoint) GetGoalLiters() float64 {
+	if x != nil {
+		return x.GoalLiters
+	}
+	return 0
+}
+
+func (x *DayPoint) GetGoalMet() bool {
+	if x != nil {
+		return x.GoalMet
+	}
+	return false
+}
+
+// DailyChart is the response of GET /api/charts/daily.
+type DailyChart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Days          int32                  `protobuf:"varint,1,opt,name=days,proto3" json:"days,omitempty"`
+	Unit          string                 `protobuf:"bytes,2,opt,name=unit,proto3" json:"unit,omitempty"`
+	Today         string                 `protobuf:"bytes,3,opt,name=today,proto3" json:"today,omitempty"`
+	Items         []*DayPoint            `protobuf:"bytes,4,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DailyChart) Reset() {
+	*x = DailyChart{}
+	mi := &file_vitals_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DailyChart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyChart) ProtoMessage() {}
+
+func (x *DailyChart) ProtoReflect() protoreflect.Message {
+	mi := &file_vitals_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyChart.ProtoReflect.Descriptor instead.
+func (*DailyChart) Descriptor() ([]byte, []int) {
+	return file_vitals_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DailyChart) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+func (x *DailyChart) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *DailyChart) GetToday() string {
+	if x != nil {
+		return x.Today
+	}
+	return ""
+}
+
+func (x *DailyChart) GetItems() []*DayPoint {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+var File_vitals_proto protoreflect.FileDescriptor
+
+const file_vitals_proto_rawDesc = "" +
+	"\n" +
+	"\fvitals.proto\x12\tvitals.v1\"\xb3\x01\n" +
+	"\vWeightEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x10\n" +
+	"\x03day\x18\x03 \x01(\tR\x03day\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\x01R\x05value\x12\x12\n" +
+	"\x04unit\x18\x05 \x01(\tR\x04unit\x12\x1b\n" +
+	"\tclient_id\x18\x06 \x01(\tR\bclientId\x12\"\n" +
+	"\rcreated_at_ms\x18\a \x01(\x03R\vcreatedAtMs\"\x99\x01\n" +
+	"\n" +
+	"WaterEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12!\n" +
+	"\fdelta_liters\x18\x03 \x01(\x01R\vdeltaLiters\x12\x1b\n" +
+	"\tclient_id\x18\x04 \x01(\tR\bclientId\x12\"\n" +
+	"\rcreated_at_ms\x18\x05 \x01(\x03R\vcreatedAtMs\"\xd3\x01\n" +
+	"\x06Change\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12\x16\n" +
+	"\x06entity\x18\x02 \x01(\tR\x06entity\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\x03R\x02id\x12\x0e\n" +
+	"\x02op\x18\x04 \x01(\tR\x02op\x12\"\n" +
+	"\rchanged_at_ms\x18\x05 \x01(\x03R\vchangedAtMs\x12.\n" +
+	"\x06weight\x18\x06 \x01(\v2\x16.vitals.v1.WeightEntryR\x06weight\x12+\n" +
+	"\x05water\x18\a \x01(\v2\x15.vitals.v1.WaterEventR\x05water\"j\n" +
+	"\bSyncPage\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\x03R\x06cursor\x12\x19\n" +
+	"\bhas_more\x18\x02 \x01(\bR\ahasMore\x12+\n" +
+	"\achanges\x18\x03 \x03(\v2\x11.vitals.v1.ChangeR\achanges\"7\n" +
+	"\vWeightPoint\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x01R\x05value\x12\x12\n" +
+	"\x04unit\x18\x02 \x01(\tR\x04unit\"\xbf\x01\n" +
+	"\bDayPoint\x12\x10\n" +
+	"\x03day\x18\x01 \x01(\tR\x03day\x12!\n" +
+	"\fwater_liters\x18\x02 \x01(\x01R\vwaterLiters\x12.\n" +
+	"\x06weight\x18\x03 \x01(\v2\x16.vitals.v1.WeightPointR\x06weight\x12\x12\n" +
+	"\x04note\x18\x04 \x01(\tR\x04note\x12\x1f\n" +
+	"\vgoal_liters\x18\x05 \x01(\x01R\n" +
+	"goalLiters\x12\x19\n" +
+	"\bgoal_met\x18\x06 \x01(\bR\agoalMet\"u\n" +
+	"\n" +
+	"DailyChart\x12\x12\n" +
+	"\x04days\x18\x01 \x01(\x05R\x04days\x12\x12\n" +
+	"\x04unit\x18\x02 \x01(\tR\x04unit\x12\x14\n" +
+	"\x05today\x18\x03 \x01(\tR\x05today\x12)\n" +
+	"\x05items\x18\x04 \x03(\v2\x13.vitals.v1.DayPointR\x05itemsB!Z\x1fvitals/internal/adapter/http/pbb\x06proto3"
+
+var (
+	file_vitals_proto_rawDescOnce sync.Once
+	file_vitals_proto_rawDescData []byte
+)
+
+func file_vitals_proto_rawDescGZIP() []byte {
+	file_vitals_proto_rawDescOnce.Do(func() {
+		file_vitals_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vitals_proto_rawDesc), len(file_vitals_proto_rawDesc)))
+	})
+	return file_vitals_proto_rawDescData
+}
+
+var file_vitals_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_vitals_proto_goTypes = []any{
+	(*WeightEntry)(nil), // 0: vitals.v1.WeightEntry
+	(*WaterEvent)(nil),  // 1: vitals.v1.WaterEvent
+	(*Change)(nil),      // 2: vitals.v1.Change
+	(*SyncPage)(nil),    // 3: vitals.v1.SyncPage
+	(*WeightPoint)(nil), // 4: vitals.v1.WeightPoint
+	(*DayPoint)(nil),    // 5: vitals.v1.DayPoint
+	(*DailyChart)(nil),  // 6: vitals.v1.DailyChart
+}
+var file_vitals_proto_depIdxs = []int32{
+	0, // 0: vitals.v1.Change.weight:type_name -> vitals.v1.WeightEntry
+	1, // 1: vitals.v1.Change.water:type_name -> vitals.v1.WaterEvent
+	2, // 2: vitals.v1.SyncPage.changes:type_name -> vitals.v1.Change
+	4, // 3: vitals.v1.DayPoint.weight:type_name -> vitals.v1.WeightPoint
+	5, // 4: vitals.v1.DailyChart.items:type_name -> vitals.v1.DayPoint
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_vitals_proto_init() }
+func file_vitals_proto_init() {
+	if File_vitals_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vitals_proto_rawDesc), len(file_vitals_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_vitals_proto_goTypes,
+		DependencyIndexes: file_vitals_proto_depIdxs,
+		MessageInfos:      file_vitals_proto_msgTypes,
+	}.Build()
+	File_vitals_proto = out.File
+	file_vitals_proto_goTypes = nil
+	file_vitals_proto_depIdxs = nil
+}
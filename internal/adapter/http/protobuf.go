@@ -0,0 +1,82 @@
+package adapthttp
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"vitals/internal/adapter/http/pb"
+	"vitals/internal/app"
+	"vitals/internal/domain"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const protobufContentType = "application/x-protobuf"
+
+// wantsProtobuf reports whether the Accept header asks for protobuf. The
+// sync and chart endpoints honour it; everything else is JSON only.
+func wantsProtobuf(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && (mt == protobufContentType || mt == "application/protobuf") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeProto(w http.ResponseWriter, status int, m proto.Message) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", protobufContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func syncPageToProto(p *app.SyncPage) *pb.SyncPage {
+	out := &pb.SyncPage{Cursor: p.Cursor, HasMore: p.HasMore, Changes: make([]*pb.Change, len(p.Changes))}
+	for i, c := range p.Changes {
+		out.Changes[i] = changeToProto(c)
+	}
+	return out
+}
+
+func changeToProto(c domain.Change) *pb.Change {
+	out := &pb.Change{Seq: c.Seq, Entity: c.Entity, Id: c.EntityID, Op: c.Op, ChangedAtMs: unixMilli(c.ChangedAt)}
+	if e := c.Weight; e != nil {
+		out.Weight = &pb.WeightEntry{
+			Id: e.ID, UserId: e.UserID, Day: e.Day, Value: e.Value, Unit: e.Unit,
+			ClientId: e.ClientID, CreatedAtMs: unixMilli(e.CreatedAt),
+		}
+	}
+	if e := c.Water; e != nil {
+		out.Water = &pb.WaterEvent{
+			Id: e.ID, UserId: e.UserID, DeltaLiters: e.DeltaLiters,
+			ClientId: e.ClientID, CreatedAtMs: unixMilli(e.CreatedAt),
+		}
+	}
+	return out
+}
+
+func dailyChartToProto(days int, unit, today string, points []app.DayPoint) *pb.DailyChart {
+	out := &pb.DailyChart{Days: int32(days), Unit: unit, Today: today, Items: make([]*pb.DayPoint, len(points))} //nolint:gosec // days is capped by the query limit
+	for i, p := range points {
+		out.Items[i] = &pb.DayPoint{Day: p.Day, WaterLiters: p.WaterLiters, Note: p.Note, GoalLiters: p.GoalLiters, GoalMet: p.GoalMet}
+		if p.Weight != nil {
+			out.Items[i].Weight = &pb.WeightPoint{Value: p.Weight.Value, Unit: p.Weight.Unit}
+		}
+	}
+	return out
+}
@@ -0,0 +1,368 @@
+package adapthttp
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemaRoutes maps each API route that takes a JSON body, as "METHOD
+// pattern" relative to /api, to the schema in schemas/ its body must
+// satisfy. The schemas check shape and ranges at the edge; services still
+// enforce their invariants for the callers that do not come through HTTP,
+// such as batch, import and config bundles.
+var schemaRoutes = map[string]string{
+	"PUT /weight/today":                "weight-today.json",
+	"PUT /weight/{id}/tags":            "entry-tags.json",
+	"POST /water/event":                "water-event.json",
+	"PATCH /water/{id}":                "water-event-patch.json",
+	"PUT /water/settings":              "water-settings.json",
+	"PUT /water/{id}/tags":             "entry-tags.json",
+	"POST /food/event":                 "food-event.json",
+	"PUT /journal/{date}":              "journal.json",
+	"PUT /mood/today":                  "mood-today.json",
+	"PUT /steps/today":                 "steps-today.json",
+	"POST /meds/definitions":           "meds-definition.json",
+	"PUT /meds/definitions/{id}":       "meds-definition.json",
+	"POST /meds/event":                 "meds-event.json",
+	"POST /temperature/event":          "temperature-event.json",
+	"POST /metrics":                    "metrics-definition.json",
+	"POST /metrics/{slug}/event":       "metrics-event.json",
+	"POST /batch":                      "batch.json",
+	"PUT /alerts/weight-change":        "alerts-weight-change.json",
+	"POST /alerts/rules":               "alerts-rule.json",
+	"PUT /alerts/rules/{id}":           "alerts-rule.json",
+	"POST /webhooks":                   "webhook.json",
+	"PUT /webhooks/{id}":               "webhook.json",
+	"POST /plan":                       "plan.json",
+	"PUT /plan/{id}":                   "plan.json",
+	"PUT /settings":                    "settings.json",
+	"POST /config/import":              "config-import.json",
+	"POST /profiles":                   "profile.json",
+	"POST /shares":                     "share.json",
+	"POST /tokens":                     "token.json",
+	"POST /embed":                      "embed.json",
+	"PUT /sessions/{id}":               "session.json",
+	"PUT /account/username":            "account-username.json",
+	"POST /auth/password":              "auth-password.json",
+	"DELETE /auth/account":             "auth-account-delete.json",
+	"PUT /account/email":               "account-email.json",
+	"PUT /auth/email":                  "account-email.json",
+	"POST /admin/maintenance":          "admin-maintenance.json",
+	"PUT /admin/maintenance-mode":      "admin-maintenance-mode.json",
+	"PUT /admin/users/{username}/role": "admin-user-role.json",
+}
+
+// maxBodyBytes bounds the JSON body of the routes in schemaRoutes.
+const maxBodyBytes = 1 << 20
+
+// errBodyTooLarge indicates a JSON body over maxBodyBytes.
+var errBodyTooLarge = fmt.Errorf("request body must be at most %d bytes", maxBodyBytes)
+
+// queryParam describes a query parameter of a route for the OpenAPI
+// document and validateRequest. Integer parameters are positive and capped
+// by the query limit of Limit, a key of DefaultQueryLimits; date parameters
+// are "YYYY-MM-DD".
+type queryParam struct {
+	Name  string
+	Type  string
+	Limit string
+}
+
+// Query parameter types.
+const (
+	paramInteger = "integer"
+	paramDate    = "date"
+)
+
+func limitParam(name, endpoint string) queryParam {
+	return queryParam{Name: name, Type: paramInteger, Limit: endpoint}
+}
+
+var dateRangeParams = []queryParam{{Name: "from", Type: paramDate}, {Name: "to", Type: paramDate}}
+
+// queryRoutes maps routes, keyed like schemaRoutes, to the query parameters
+// validated before their handler runs. Parameters not listed here reach the
+// handler unchecked.
+var queryRoutes = map[string][]queryParam{
+	"GET /weight/recent":            {limitParam("limit", "weight/recent")},
+	"GET /water/recent":             {limitParam("limit", "water/recent")},
+	"GET /food/recent":              {limitParam("limit", "food/recent")},
+	"GET /mood/recent":              {limitParam("limit", "mood/recent")},
+	"GET /meds/recent":              {limitParam("limit", "meds/recent")},
+	"GET /temperature/recent":       {limitParam("limit", "temperature/recent")},
+	"GET /metrics/{slug}/recent":    {limitParam("limit", "metrics/recent")},
+	"GET /charts/daily":             {limitParam("days", "charts/daily")},
+	"GET /charts/daily.csv":         {limitParam("days", "charts/daily")},
+	"GET /charts/daily.xlsx":        {limitParam("days", "charts/daily")},
+	"GET /stats/compliance":         {limitParam("days", "stats/compliance")},
+	"GET /stats/weekly":             {limitParam("weeks", "stats/weekly")},
+	"GET /export/influx":            {limitParam("days", "export/influx")},
+	"GET /export/charts.csv":        {limitParam("days", "export/charts")},
+	"GET /export/weight.csv":        dateRangeParams,
+	"GET /export/water.csv":         dateRangeParams,
+	"GET /export/events.json":       dateRangeParams,
+	"POST /export/archive":          dateRangeParams,
+	"GET /plan":                     dateRangeParams,
+	"GET /feeds/weekly.atom":        {limitParam("weeks", "feeds/weekly")},
+	"GET /admin/stats":              {limitParam("weeks", "admin/stats")},
+	"GET /webhooks/{id}/deliveries": {limitParam("limit", "webhooks/deliveries")},
+	"GET /sync":                     {limitParam("limit", "sync")},
+	"GET /activity":                 {limitParam("limit", "activity")},
+}
+
+// requestSchemas holds the compiled schemas keyed like schemaRoutes. The
+// schemas are embedded, so one that does not compile is a bug and panics at
+// startup.
+var requestSchemas = compileSchemas()
+
+func compileSchemas() map[string]*jsonschema.Schema {
+	files, err := schemaFS.ReadDir("schemas")
+	if err != nil {
+		panic(err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	for _, f := range files {
+		raw, err := schemaFS.ReadFile(path.Join("schemas", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			panic(fmt.Sprintf("schema %s: %v", f.Name(), err))
+		}
+		if err := c.AddResource(f.Name(), doc); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", f.Name(), err))
+		}
+	}
+	out := make(map[string]*jsonschema.Schema, len(schemaRoutes))
+	for route, name := range schemaRoutes {
+		out[route] = c.MustCompile(name)
+	}
+	return out
+}
+
+// validateRequest checks r's query parameters and JSON body against the
+// declarations of the route it was matched to in queryRoutes and
+// schemaRoutes, the same ones the OpenAPI document publishes. The body is
+// buffered, up to maxBodyBytes, and put back for the handler to decode;
+// longer bodies fail with errBodyTooLarge.
+func (s *Server) validateRequest(w http.ResponseWriter, r *http.Request) error {
+	route := r.Method + " " + r.Pattern
+	q := r.URL.Query()
+	for _, p := range queryRoutes[route] {
+		if v := q.Get(p.Name); v != "" {
+			if err := s.checkParam(p, v); err != nil {
+				return err
+			}
+		}
+	}
+	sch := requestSchemas[route]
+	if sch == nil {
+		return nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errBodyTooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return validateBody(sch, body)
+}
+
+// checkParam checks the value v of the query parameter p, returning a
+// *queryError when it does not fit.
+func (s *Server) checkParam(p queryParam, v string) error {
+	switch p.Type {
+	case paramInteger:
+		max := s.queryLimits[p.Limit]
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 || (max > 0 && n > max) {
+			return &queryError{Param: p.Name, Value: v, Max: max}
+		}
+	case paramDate:
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return &queryError{Param: p.Name, Value: v, Want: "YYYY-MM-DD"}
+		}
+	}
+	return nil
+}
+
+// paramSchema returns the JSON Schema of the query parameter p.
+func (s *Server) paramSchema(p queryParam) map[string]any {
+	if p.Type == paramDate {
+		return map[string]any{"type": "string", "format": "date"}
+	}
+	sch := map[string]any{"type": "integer", "minimum": 1}
+	if max := s.queryLimits[p.Limit]; max > 0 {
+		sch["maximum"] = max
+	}
+	return sch
+}
+
+// FieldError is one schema violation in a request body. Field is a JSON
+// Pointer to the offending value, e.g. "/ops/0/unit"; it is empty when the
+// body as a whole is at fault.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// schemaError reports every schema violation in a request body.
+type schemaError struct {
+	Fields []FieldError
+}
+
+func (e *schemaError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.Field == "" {
+			msgs[i] = f.Message
+		} else {
+			msgs[i] = strings.TrimPrefix(f.Field, "/") + ": " + f.Message
+		}
+	}
+	return "invalid request body: " + strings.Join(msgs, "; ")
+}
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// validateBody checks body against sch, returning a *schemaError listing
+// each violation.
+func validateBody(sch *jsonschema.Schema, body []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	err = sch.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	se := &schemaError{}
+	collectFieldErrors(ve, &se.Fields)
+	slices.SortStableFunc(se.Fields, func(a, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+	return se
+}
+
+// collectFieldErrors appends the leaf violations under ve, splitting missing
+// and unexpected properties into one error per property.
+func collectFieldErrors(ve *jsonschema.ValidationError, out *[]FieldError) {
+	if len(ve.Causes) > 0 {
+		for _, c := range ve.Causes {
+			collectFieldErrors(c, out)
+		}
+		return
+	}
+	at := pointer(ve.InstanceLocation)
+	switch k := ve.ErrorKind.(type) {
+	case *kind.Required:
+		for _, p := range k.Missing {
+			*out = append(*out, FieldError{Field: at + "/" + p, Message: "is required"})
+		}
+	case *kind.AdditionalProperties:
+		for _, p := range k.Properties {
+			*out = append(*out, FieldError{Field: at + "/" + p, Message: "is not allowed"})
+		}
+	default:
+		*out = append(*out, FieldError{Field: at, Message: k.LocalizedString(schemaPrinter)})
+	}
+}
+
+// pointer encodes instance location tokens as a JSON Pointer.
+func pointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(t))
+	}
+	return b.String()
+}
+
+// handleOpenAPI describes the API's JSON request bodies and validated
+// query parameters as an OpenAPI 3.1 document, built from the same
+// declarations validateRequest enforces.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	paths := map[string]map[string]any{}
+	operation := func(route string) map[string]any {
+		method, pattern, _ := strings.Cut(route, " ")
+		p := "/api/v1" + pattern
+		if paths[p] == nil {
+			paths[p] = map[string]any{}
+		}
+		op, ok := paths[p][strings.ToLower(method)].(map[string]any)
+		if !ok {
+			op = map[string]any{
+				"parameters": pathParams(pattern),
+				"responses": map[string]any{
+					"400": map[string]any{"description": "The request does not match this description: \"param\" names a bad query parameter, \"fields\" lists each body violation."},
+				},
+			}
+			paths[p][strings.ToLower(method)] = op
+		}
+		return op
+	}
+	for route, name := range schemaRoutes {
+		raw, err := schemaFS.ReadFile(path.Join("schemas", name))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		operation(route)["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": json.RawMessage(raw)}},
+		}
+	}
+	for route, params := range queryRoutes {
+		op := operation(route)
+		list := op["parameters"].([]map[string]any)
+		for _, p := range params {
+			list = append(list, map[string]any{"name": p.Name, "in": "query", "schema": s.paramSchema(p)})
+		}
+		op["parameters"] = list
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": "vitals", "version": "1"},
+		"paths":   paths,
+	})
+}
+
+// pathParams describes the {name} segments of a route pattern as required
+// path parameters.
+func pathParams(pattern string) []map[string]any {
+	out := []map[string]any{}
+	for _, seg := range strings.Split(pattern, "/") {
+		if name, ok := strings.CutPrefix(seg, "{"); ok {
+			out = append(out, map[string]any{"name": strings.TrimSuffix(name, "}"), "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+	}
+	return out
+}
@@ -3,11 +3,15 @@ package adapthttp
 import (
 	"context"
 	"log"
+	"maps"
 	"net/http"
+	"net/netip"
 	"os"
 	"path"
+	"time"
 
 	"vitals/internal/app"
+	"vitals/internal/domain"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
@@ -25,16 +29,69 @@ type OIDCConfig struct {
 type Server struct {
 	weight      *app.WeightService
 	water       *app.WaterService
+	nutrition   *app.NutritionService
+	mood        *app.MoodService
+	steps       *app.StepsService
+	meds        *app.MedicationService
+	temperature *app.TemperatureService
+	metrics     *app.MetricsService
 	charts      *app.ChartsService
+	profiles    *app.ProfileService
+	shares      *app.ShareService
+	embeds      *app.EmbedService
+	tokens      *app.TokenService
+	accounts    *app.AccountService
+	feeds       *app.FeedService
+	imports     *app.ImportService
+	archives    *app.ArchiveService
+	portability *app.PortabilityService
+	integration *app.IntegrationService
+	sync        *app.SyncService
+	activity    *app.ActivityService
+	batch       *app.BatchService
+	alerts      *app.AlertService
+	rules       *app.RuleService
+	plans       *app.PlanService
+	hydration   *app.HydrationService
+	goals       *app.GoalService
+	settings    *app.SettingsService
+	config      *app.ConfigService
+	tags        *app.TagService
+	journal     *app.JournalService
+	summaries   *app.SummaryService
+	stats       *app.StatsService
+	maintenance *app.MaintenanceService
+	usage       *app.UsageService
+	snapshots   *app.SnapshotService
+	webhooks    *app.WebhookService
 	authSvc     *app.AuthService
 	webDir      string
 	disableAuth bool
+	guestUser   string
 	oidcConfig  OIDCConfig
+	// requestTimeout bounds each request's context; zero disables it.
+	requestTimeout time.Duration
+	// queryLimits caps numeric query parameters per endpoint.
+	queryLimits map[string]int
+	// pages maps page routes to files in webDir; see ParsePages.
+	pages map[string]string
+	// healthAccess restricts /api/health; see ParseHealthAccess.
+	healthAccess HealthAccess
+	// trustedProxies are the reverse proxies whose X-Forwarded-For names
+	// the client; see clientAddr.
+	trustedProxies []netip.Prefix
+	// sunset is when the unversioned /api paths go away, if announced.
+	sunset time.Time
+	// unversioned counts requests to the unversioned /api paths.
+	unversioned *usageCounter
+	// frameAncestors are the origins besides the app's own that may frame
+	// /embed pages; see ParseFrameAncestors.
+	frameAncestors []string
 }
 
 // New creates a Server wired to the given application services.
 func New(ws *app.WeightService, wa *app.WaterService, cs *app.ChartsService, as *app.AuthService, webDir string) *Server {
-	s := &Server{weight: ws, water: wa, charts: cs, authSvc: as, webDir: webDir, disableAuth: false}
+	s := &Server{weight: ws, water: wa, charts: cs, authSvc: as, webDir: webDir, disableAuth: false, requestTimeout: DefaultRequestTimeout, queryLimits: maps.Clone(DefaultQueryLimits), unversioned: newUsageCounter()}
 
 	// Initialize OIDC (SSO) if configured
 	if issuer := os.Getenv("SSO_ISSUER_URL"); issuer != "" {
@@ -66,19 +123,329 @@ func backgroundContext() context.Context {
 	return context.Background()
 }
 
+// DefaultRequestTimeout is the request deadline used unless overridden with
+// WithRequestTimeout.
+const DefaultRequestTimeout = 15 * time.Second
+
+// WithRequestTimeout sets the deadline attached to each request's context;
+// zero disables it.
+func (s *Server) WithRequestTimeout(d time.Duration) *Server {
+	s.requestTimeout = d
+	return s
+}
+
+// WithQueryLimits overrides the per-endpoint maximums from
+// DefaultQueryLimits.
+func (s *Server) WithQueryLimits(limits map[string]int) *Server {
+	maps.Copy(s.queryLimits, limits)
+	return s
+}
+
+// WithPages serves the web directory's files pages maps routes to, on top
+// of the "/name" to name.html convention. Routes ending in "/" also serve
+// the paths under them, for pages with client-side routes.
+func (s *Server) WithPages(pages map[string]string) *Server {
+	s.pages = pages
+	return s
+}
+
+// WithHealthAccess restricts who may call /api/health.
+func (s *Server) WithHealthAccess(a HealthAccess) *Server {
+	s.healthAccess = a
+	return s
+}
+
+// WithTrustedProxies counts sign-in attempts relayed by proxies in
+// networks against the client named in X-Forwarded-For rather than the
+// proxy.
+func (s *Server) WithTrustedProxies(networks []netip.Prefix) *Server {
+	s.trustedProxies = networks
+	return s
+}
+
+// WithSunset announces when the deprecated unversioned /api paths will be
+// removed, in the Sunset header of their responses.
+func (s *Server) WithSunset(t time.Time) *Server {
+	s.sunset = t
+	return s
+}
+
+// WithFrameAncestors lets the given origins show /embed pages in a frame.
+func (s *Server) WithFrameAncestors(origins []string) *Server {
+	s.frameAncestors = origins
+	return s
+}
+
 // WithoutAuth disables authentication (for testing).
 func (s *Server) WithoutAuth() *Server {
 	s.disableAuth = true
 	return s
 }
 
+// WithGuestUser enables read-only guest access: requests without valid
+// credentials act as the named account and every write is rejected with 403.
+func (s *Server) WithGuestUser(username string) *Server {
+	s.guestUser = username
+	return s
+}
+
+// WithProfiles enables household profiles: the /api/profiles endpoints and
+// the ?profile=<id> selector on metric endpoints.
+func (s *Server) WithProfiles(ps *app.ProfileService) *Server {
+	s.profiles = ps
+	return s
+}
+
+// WithShares enables read-only sharing: the /api/shares endpoints and the
+// ?user=<id> selector on metric endpoints.
+func (s *Server) WithShares(ss *app.ShareService) *Server {
+	s.shares = ss
+	return s
+}
+
+// WithEmbeds enables embeddable charts: the /api/embed endpoint, which
+// signs links, and the /embed/charts page they open.
+func (s *Server) WithEmbeds(es *app.EmbedService) *Server {
+	s.embeds = es
+	return s
+}
+
+// WithTokens enables scoped API tokens: the /api/tokens management endpoints
+// and the token-authenticated /api/quick endpoints.
+func (s *Server) WithTokens(ts *app.TokenService) *Server {
+	s.tokens = ts
+	return s
+}
+
+// WithFeeds enables the token-authenticated calendar and news feeds.
+func (s *Server) WithFeeds(fs *app.FeedService) *Server {
+	s.feeds = fs
+	return s
+}
+
+// WithImports enables background imports under /api/import.
+func (s *Server) WithImports(is *app.ImportService) *Server {
+	s.imports = is
+	return s
+}
+
+// WithArchives enables ZIP export archives under /api/export/archive.
+func (s *Server) WithArchives(as *app.ArchiveService) *Server {
+	s.archives = as
+	return s
+}
+
+// WithPortability enables full-account export and restore under
+// /api/export/all and /api/import/all.
+func (s *Server) WithPortability(ps *app.PortabilityService) *Server {
+	s.portability = ps
+	return s
+}
+
+// WithSync enables the incremental /api/sync change feed.
+func (s *Server) WithSync(ss *app.SyncService) *Server {
+	s.sync = ss
+	return s
+}
+
+// WithActivity enables the /api/activity timeline.
+func (s *Server) WithActivity(as *app.ActivityService) *Server {
+	s.activity = as
+	return s
+}
+
+// WithBatch enables atomic multi-op writes under /api/batch.
+func (s *Server) WithBatch(bs *app.BatchService) *Server {
+	s.batch = bs
+	return s
+}
+
+// WithAlerts enables configuring rapid weight-change alerts under
+// /api/alerts.
+func (s *Server) WithAlerts(as *app.AlertService) *Server {
+	s.alerts = as
+	return s
+}
+
+// WithRules enables user-defined alert rules under /api/alerts/rules.
+func (s *Server) WithRules(rs *app.RuleService) *Server {
+	s.rules = rs
+	return s
+}
+
+// WithPlans enables planned entries under /api/plan.
+func (s *Server) WithPlans(ps *app.PlanService) *Server {
+	s.plans = ps
+	return s
+}
+
+// WithWebhooks enables user-registered webhooks under /api/webhooks.
+func (s *Server) WithWebhooks(ws *app.WebhookService) *Server {
+	s.webhooks = ws
+	return s
+}
+
+// WithAccounts enables the username and email endpoints under /api/account
+// and the email verification link.
+func (s *Server) WithAccounts(as *app.AccountService) *Server {
+	s.accounts = as
+	return s
+}
+
+// WithNutrition enables food and calorie logging under /api/food.
+func (s *Server) WithNutrition(ns *app.NutritionService) *Server {
+	s.nutrition = ns
+	return s
+}
+
+// WithMood enables daily mood tracking under /api/mood.
+func (s *Server) WithMood(ms *app.MoodService) *Server {
+	s.mood = ms
+	return s
+}
+
+// WithSteps enables daily step counts under /api/steps.
+func (s *Server) WithSteps(ss *app.StepsService) *Server {
+	s.steps = ss
+	return s
+}
+
+// WithMedications enables medication and supplement logging under
+// /api/meds.
+func (s *Server) WithMedications(ms *app.MedicationService) *Server {
+	s.meds = ms
+	return s
+}
+
+// WithTemperature enables body temperature readings under
+// /api/temperature.
+func (s *Server) WithTemperature(ts *app.TemperatureService) *Server {
+	s.temperature = ts
+	return s
+}
+
+// WithMetrics enables user-defined numeric trackers under /api/metrics.
+func (s *Server) WithMetrics(ms *app.MetricsService) *Server {
+	s.metrics = ms
+	return s
+}
+
+// WithHydration enables daily water goals: the goal in /api/water/today and
+// the /api/water/settings endpoint.
+func (s *Server) WithHydration(hs *app.HydrationService) *Server {
+	s.hydration = hs
+	return s
+}
+
+// WithIntegrations enables connecting third-party health services under
+// /api/integrations.
+func (s *Server) WithIntegrations(is *app.IntegrationService) *Server {
+	s.integration = is
+	return s
+}
+
+// WithGoals enables the goal history under /api/goals.
+func (s *Server) WithGoals(gs *app.GoalService) *Server {
+	s.goals = gs
+	return s
+}
+
+// WithSettings enables the per-user preferences API under /api/settings.
+func (s *Server) WithSettings(ss *app.SettingsService) *Server {
+	s.settings = ss
+	return s
+}
+
+// WithConfig enables exporting and importing configuration bundles under
+// /api/config.
+func (s *Server) WithConfig(cs *app.ConfigService) *Server {
+	s.config = cs
+	return s
+}
+
+// WithTags enables tagging entries under /api/weight/{id}/tags and
+// /api/water/{id}/tags, and listing tags under /api/tags.
+func (s *Server) WithTags(ts *app.TagService) *Server {
+	s.tags = ts
+	return s
+}
+
+// WithJournal enables daily journal notes under /api/journal/{date}.
+func (s *Server) WithJournal(js *app.JournalService) *Server {
+	s.journal = js
+	return s
+}
+
+// WithSnapshots enables the /api/snapshot endpoint for low-power displays.
+func (s *Server) WithSnapshots(ss *app.SnapshotService) *Server {
+	s.snapshots = ss
+	return s
+}
+
+// WithSummaries enables the /api/stats/weekly report.
+func (s *Server) WithSummaries(ss *app.SummaryService) *Server {
+	s.summaries = ss
+	return s
+}
+
+// WithStats enables the /api/stats reports.
+func (s *Server) WithStats(ss *app.StatsService) *Server {
+	s.stats = ss
+	return s
+}
+
+// WithMaintenance enables the /api/admin/maintenance endpoints for admins
+// and, while maintenance mode is on, refuses writes with 503.
+func (s *Server) WithMaintenance(ms *app.MaintenanceService) *Server {
+	s.maintenance = ms
+	return s
+}
+
+// WithUsage enables the /api/admin/stats usage report for admins. It is
+// opt-in for the operator: without it the endpoint does not exist.
+func (s *Server) WithUsage(us *app.UsageService) *Server {
+	s.usage = us
+	return s
+}
+
+// feed wraps a feed handler, which only exists when feeds are configured.
+func (s *Server) feed(h http.HandlerFunc) http.Handler {
+	return s.authorize(app.PolicyFeed, func(w http.ResponseWriter, r *http.Request) {
+		if s.feeds == nil {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	})
+}
+
 // Handler returns the root http.Handler for the application.
 func (s *Server) Handler() http.Handler {
 	api := http.NewServeMux()
+	// Event streams stay open by design, so their routes, and only those,
+	// are exempt from the request timeout.
+	streams := http.NewServeMux()
+	stream := func(pattern string, h http.Handler) {
+		api.Handle(pattern, h)
+		streams.Handle("/api"+pattern, h)
+		streams.Handle("/api/v1"+pattern, h)
+	}
 	api.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		if !s.healthAccess.allows(r, s.clientAddr(r)) {
+			http.NotFound(w, r)
+			return
+		}
+		resp := map[string]any{"ok": true}
+		if s.maintenance != nil {
+			if mode := s.maintenance.Mode(); mode.Enabled {
+				resp["maintenance"] = mode
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
 	})
 
+	api.HandleFunc("/openapi.json", s.handleOpenAPI)
+
 	// Auth endpoints (public)
 	api.HandleFunc("/auth/login", s.handleLogin)
 	api.HandleFunc("/auth/logout", s.handleLogout)
@@ -86,21 +453,129 @@ func (s *Server) Handler() http.Handler {
 	api.HandleFunc("/auth/config", s.handleConfig)
 	api.HandleFunc("/auth/oidc/login", s.handleSSOLogin)
 	api.HandleFunc("/auth/oidc/callback", s.handleSSOCallback)
+	api.HandleFunc("/auth/oidc/link", s.handleSSOLink)
+	api.HandleFunc("/auth/verify-email", s.handleVerifyEmail)
+	api.HandleFunc("/auth/recover", s.handleRecover)
+
+	// Protected API endpoints - each states its access policy
+	api.Handle("/weight/today", s.authorize(app.PolicyWeightLog, s.handleWeightToday))
+	api.Handle("/weight/recent", s.authorize(app.PolicySharedEntries, s.handleWeightRecent))
+	api.Handle("/weight/undo-last", s.authorize(app.PolicyWeightLog, s.handleWeightUndoLast))
+	api.Handle("/weight/{id}/tags", s.authorize(app.PolicyMetric, s.handleEntryTags(domain.ChangeEntityWeight)))
+
+	api.Handle("/water/today", s.authorize(app.PolicyDashboard, s.handleWaterToday))
+	api.Handle("/water/event", s.authorize(app.PolicyWaterLog, s.handleWaterEvent))
+	api.Handle("/water/recent", s.authorize(app.PolicySharedEntries, s.handleWaterRecent))
+	api.Handle("/water/undo-last", s.authorize(app.PolicyWaterLog, s.handleWaterUndoLast))
+	api.Handle("/water/settings", s.authorize(app.PolicyMetric, s.handleWaterSettings))
+	api.Handle("/water/{id}", s.authorize(app.PolicyMetric, s.handleWaterEventEdit))
+	api.Handle("/water/{id}/tags", s.authorize(app.PolicyMetric, s.handleEntryTags(domain.ChangeEntityWater)))
+
+	api.Handle("/food/today", s.authorize(app.PolicyDashboard, s.handleFoodToday))
+	api.Handle("/food/event", s.authorize(app.PolicyMetric, s.handleFoodEvent))
+	api.Handle("/food/recent", s.authorize(app.PolicySharedEntries, s.handleFoodRecent))
+	api.Handle("/food/undo-last", s.authorize(app.PolicyMetric, s.handleFoodUndoLast))
 
-	// Protected API endpoints - wrap each handler with auth middleware
-	api.Handle("/weight/today", s.authMiddleware(http.HandlerFunc(s.handleWeightToday)))
-	api.Handle("/weight/recent", s.authMiddleware(http.HandlerFunc(s.handleWeightRecent)))
-	api.Handle("/weight/undo-last", s.authMiddleware(http.HandlerFunc(s.handleWeightUndoLast)))
+	api.Handle("/mood/today", s.authorize(app.PolicyMetric, s.handleMoodToday))
+	api.Handle("/mood/recent", s.authorize(app.PolicySharedEntries, s.handleMoodRecent))
 
-	api.Handle("/water/today", s.authMiddleware(http.HandlerFunc(s.handleWaterToday)))
-	api.Handle("/water/event", s.authMiddleware(http.HandlerFunc(s.handleWaterEvent)))
-	api.Handle("/water/recent", s.authMiddleware(http.HandlerFunc(s.handleWaterRecent)))
-	api.Handle("/water/undo-last", s.authMiddleware(http.HandlerFunc(s.handleWaterUndoLast)))
+	api.Handle("/steps/today", s.authorize(app.PolicyDashboard, s.handleStepsToday))
 
-	api.Handle("/charts/daily", s.authMiddleware(http.HandlerFunc(s.handleChartsDaily)))
+	api.Handle("/meds/definitions", s.authorize(app.PolicyMetric, s.handleMedications))
+	api.Handle("/meds/definitions/{id}", s.authorize(app.PolicyMetric, s.handleMedication))
+	api.Handle("/meds/event", s.authorize(app.PolicyMetric, s.handleMedicationEvent))
+	api.Handle("/meds/recent", s.authorize(app.PolicySharedEntries, s.handleMedicationRecent))
+
+	api.Handle("/temperature/event", s.authorize(app.PolicyMetric, s.handleTemperatureEvent))
+	api.Handle("/temperature/recent", s.authorize(app.PolicySharedEntries, s.handleTemperatureRecent))
+	api.Handle("/temperature/{id}", s.authorize(app.PolicyMetric, s.handleTemperatureReading))
+
+	api.Handle("/metrics", s.authorize(app.PolicyMetric, s.handleCustomMetrics))
+	api.Handle("/metrics/{slug}", s.authorize(app.PolicyMetric, s.handleCustomMetric))
+	api.Handle("/metrics/{slug}/today", s.authorize(app.PolicyDashboard, s.handleCustomMetricToday))
+	api.Handle("/metrics/{slug}/event", s.authorize(app.PolicyMetric, s.handleCustomMetricEvent))
+	api.Handle("/metrics/{slug}/recent", s.authorize(app.PolicySharedEntries, s.handleCustomMetricRecent))
+
+	api.Handle("/tags", s.authorize(app.PolicyMetric, s.handleTags))
+
+	api.Handle("/charts/daily", s.authorize(app.PolicySharedCharts, s.handleChartsDaily))
+	api.Handle("/charts/daily.csv", s.authorize(app.PolicySharedCharts, s.handleChartsDailyCSV))
+	api.Handle("/charts/daily.xlsx", s.authorize(app.PolicySharedCharts, s.handleChartsDailyXLSX))
+	api.Handle("/calendar/{month}", s.authorize(app.PolicyMetric, s.handleCalendarMonth))
+	api.Handle("/journal/{date}", s.authorize(app.PolicyMetric, s.handleJournal))
+	api.Handle("/stats/compliance", s.authorize(app.PolicyDashboard, s.handleCompliance))
+	api.Handle("/stats/weekly", s.authorize(app.PolicyDashboard, s.handleWeeklyStats))
+	api.Handle("/snapshot", s.authorize(app.PolicyDashboard, s.handleSnapshot))
+	api.Handle("/export/influx", s.authorize(app.PolicyOwner, s.handleExportInflux))
+	api.Handle("/export/weight.csv", s.authorize(app.PolicyOwner, s.handleExportWeightCSV))
+	api.Handle("/export/water.csv", s.authorize(app.PolicyOwner, s.handleExportWaterCSV))
+	api.Handle("/export/events.json", s.authorize(app.PolicyOwner, s.handleExportEventsJSON))
+	api.Handle("/export/charts.csv", s.authorize(app.PolicyOwner, s.handleExportChartsCSV))
+	api.Handle("/export/archive", s.authorize(app.PolicyOwner, s.handleExportArchive))
+	api.Handle("/export/archive/{id}", s.authorize(app.PolicyOwner, s.handleExportArchiveJob))
+	api.Handle("/export/archive/{id}/download", s.authorize(app.PolicyOwner, s.handleExportArchiveDownload))
+
+	api.Handle("/sync", s.authorize(app.PolicyMetric, s.handleSync))
+	api.Handle("/activity", s.authorize(app.PolicyMetric, s.handleActivity))
+	stream("/events/stream", s.authorize(app.PolicyMetric, s.handleEventStream))
+	api.Handle("/batch", s.authorize(app.PolicyMetric, s.handleBatch))
+	api.Handle("/alerts/weight-change", s.authorize(app.PolicyMetric, s.handleWeightChangeAlert))
+	api.Handle("/alerts/rules", s.authorize(app.PolicyOwner, s.handleRules))
+	api.Handle("/alerts/rules/{id}", s.authorize(app.PolicyOwner, s.handleRule))
+	api.Handle("/webhooks", s.authorize(app.PolicyOwner, s.handleWebhooks))
+	api.Handle("/webhooks/{id}", s.authorize(app.PolicyOwner, s.handleWebhook))
+	api.Handle("/webhooks/{id}/deliveries", s.authorize(app.PolicyOwner, s.handleWebhookDeliveries))
+	api.Handle("/plan", s.authorize(app.PolicyMetric, s.handlePlan))
+	api.Handle("/plan/{id}", s.authorize(app.PolicyMetric, s.handlePlannedEntry))
+	api.Handle("/settings", s.authorize(app.PolicyOwner, s.handleSettings))
+	api.Handle("/goals/history", s.authorize(app.PolicyMetric, s.handleGoalHistory))
+	api.Handle("/goals/weight", s.authorize(app.PolicyMetric, s.handleWeightGoal))
+	api.Handle("/config/export", s.authorize(app.PolicyOwner, s.handleConfigExport))
+	api.Handle("/config/import", s.authorize(app.PolicyOwner, s.handleConfigImport))
+
+	api.Handle("/import", s.authorize(app.PolicyMetric, s.handleImport))
+	api.Handle("/import/{source}", s.authorize(app.PolicyMetric, s.handleImport))
+	api.Handle("/import/jobs/{id}", s.authorize(app.PolicyMetric, s.handleImportJob))
+	stream("/import/jobs/{id}/events", s.authorize(app.PolicyMetric, s.handleImportJobEvents))
+	api.Handle("/import/batches/{id}", s.authorize(app.PolicyMetric, s.handleImportBatch))
+	api.Handle("/export/all", s.authorize(app.PolicyAccount, s.handleExportAll))
+	api.Handle("/import/all", s.authorize(app.PolicyAccount, s.handleImportAll))
+	api.Handle("/integrations/{provider}", s.authorize(app.PolicyAccount, s.handleIntegration))
+	api.Handle("/integrations/{provider}/connect", s.authorize(app.PolicyAccount, s.handleIntegrationConnect))
+	api.Handle("/integrations/{provider}/callback", s.authorize(app.PolicyAccount, s.handleIntegrationCallback))
+	api.Handle("/integrations/{provider}/sync", s.authorize(app.PolicyAccount, s.handleIntegrationSync))
+
+	api.Handle("/profiles", s.authorize(app.PolicyAccount, s.handleProfiles))
+	api.Handle("/shares", s.authorize(app.PolicyAccount, s.handleShares))
+	api.Handle("/tokens", s.authorize(app.PolicyAccount, s.handleTokens))
+	api.Handle("/embed", s.authorize(app.PolicyAccount, s.handleEmbed))
+	api.Handle("/auth/password", s.authorize(app.PolicyAccount, s.handleChangePassword))
+	api.Handle("/auth/account", s.authorize(app.PolicyAccount, s.handleDeleteAccount))
+	api.Handle("/auth/email", s.authorize(app.PolicyAccount, s.handleAccountEmail))
+	api.Handle("/sessions", s.authorize(app.PolicyAccount, s.handleSessions))
+	api.Handle("/sessions/{id}", s.authorize(app.PolicyAccount, s.handleSession))
+	api.Handle("/account", s.authorize(app.PolicyAccount, s.handleAccount))
+	api.Handle("/account/username", s.authorize(app.PolicyAccount, s.handleAccountUsername))
+	api.Handle("/account/email", s.authorize(app.PolicyAccount, s.handleAccountEmail))
+	api.Handle("/account/recovery-codes", s.authorize(app.PolicyAccount, s.handleRecoveryCodes))
+	api.Handle("/admin/maintenance", s.authorize(app.PolicyAdmin, s.handleAdminMaintenance))
+	api.Handle("/admin/maintenance-mode", s.authorize(app.PolicyAdmin, s.handleAdminMaintenanceMode))
+	api.Handle("/admin/stats", s.authorize(app.PolicyAdmin, s.handleAdminStats))
+	api.Handle("/admin/deprecated-usage", s.authorize(app.PolicyAdmin, s.handleAdminDeprecatedUsage))
+	api.Handle("/admin/users/{username}/recovery", s.authorize(app.PolicyAdmin, s.handleAdminRecovery))
+	api.Handle("/admin/users/{username}/role", s.authorize(app.PolicyAdmin, s.handleAdminUserRole))
+
+	// Token-authenticated endpoints for one-tap automations
+	api.Handle("/quick/water", s.authorize(app.PolicyQuickWater, s.handleQuickWater))
+	api.Handle("/quick/weight", s.authorize(app.PolicyQuickWeight, s.handleQuickWeight))
+	api.Handle("/feeds/calendar.ics", s.feed(s.handleCalendarFeed))
+	api.Handle("/feeds/weekly.atom", s.feed(s.handleSummaryFeed))
 
 	root := http.NewServeMux()
-	root.Handle("/api/", http.StripPrefix("/api", api))
+	// /api/v1 is the current API; the unversioned /api paths serve the same
+	// routes for older clients, marked deprecated.
+	root.Handle("/api/v1/", http.StripPrefix("/api/v1", routeSpan(api, "/api/v1", api)))
+	root.Handle("/api/", http.StripPrefix("/api", routeSpan(api, "/api", s.deprecateUnversioned(api, api))))
 
 	// Server HTML files for login/signup directly to ensure they are found and public
 	root.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
@@ -110,8 +585,11 @@ func (s *Server) Handler() http.Handler {
 		http.ServeFile(w, r, path.Join(s.webDir, "signup.html"))
 	})
 
+	// Embedded charts authenticate with the signed token in their URL.
+	root.HandleFunc("/embed/charts", s.handleEmbedCharts)
+
 	// Apply HTML auth middleware to SPA catch-all
-	root.Handle("/", s.requireAuthHTML(spaFromDisk(s.webDir)))
+	root.Handle("/", s.requireAuthHTML(spaFromDisk(s.webDir, s.pages)))
 
-	return s.loggingMiddleware(withNoCache(root))
+	return s.loggingMiddleware(s.traceMiddleware(routeSpan(root, "", s.timeoutMiddleware(streams, withNoCache(s.frameOptions(s.maintenanceMiddleware(root)))))))
 }
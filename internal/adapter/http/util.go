@@ -1,13 +1,23 @@
 package adapthttp
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"path"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -16,10 +26,81 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// errorKinds maps the kinds of domain errors to their status and to the
+// code sent alongside the message, which clients can switch on or
+// translate instead of parsing the message.
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{domain.ErrNotFound, http.StatusNotFound, "not_found"},
+	{domain.ErrValueOutOfRange, http.StatusBadRequest, "value_out_of_range"},
+	{domain.ErrInvalidUnit, http.StatusBadRequest, "invalid_unit"},
+	{domain.ErrInvalidArgument, http.StatusBadRequest, "invalid_argument"},
+	{domain.ErrCursorExpired, http.StatusGone, "cursor_expired"},
+}
+
+// writeError responds with err as { "error": message }. Domain errors
+// override status with their kind's and add its "code"; the other
+// sentinels of app map to their statuses below.
 func writeError(w http.ResponseWriter, status int, err error) {
+	var qe *queryError
+	if errors.As(err, &qe) {
+		resp := map[string]any{"error": err.Error(), "param": qe.Param, "value": qe.Value}
+		if qe.Want == "" {
+			resp["min"], resp["max"] = 1, qe.Max
+		}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	var se *schemaError
+	if errors.As(err, &se) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error(), "fields": se.Fields})
+		return
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			writeJSON(w, k.status, map[string]any{"error": err.Error(), "code": k.code})
+			return
+		}
+	}
+	switch {
+	case errors.Is(err, app.ErrReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, app.ErrProfileNotFound),
+		errors.Is(err, app.ErrShareNotFound),
+		errors.Is(err, app.ErrUserNotFound),
+		errors.Is(err, app.ErrTokenNotFound),
+		errors.Is(err, app.ErrJobNotFound),
+		errors.Is(err, app.ErrBatchNotFound),
+		errors.Is(err, app.ErrRuleNotFound),
+		errors.Is(err, app.ErrWebhookNotFound),
+		errors.Is(err, app.ErrPlannedEntryNotFound),
+		errors.Is(err, app.ErrMedicationNotFound),
+		errors.Is(err, app.ErrMetricNotFound),
+		errors.Is(err, app.ErrArchiveNotFound),
+		errors.Is(err, app.ErrSessionNotFound),
+		errors.Is(err, app.ErrIntegrationNotFound),
+		errors.Is(err, app.ErrIntegrationNotConnected),
+		errors.Is(err, app.ErrEmailUnavailable),
+		errors.Is(err, app.ErrRecoveryUnavailable):
+		status = http.StatusNotFound
+	case errors.Is(err, app.ErrUsernameTaken),
+		errors.Is(err, app.ErrEmailTaken),
+		errors.Is(err, app.ErrImportRunning),
+		errors.Is(err, app.ErrMetricExists),
+		errors.Is(err, app.ErrArchiveNotReady),
+		errors.Is(err, app.ErrHasPassword):
+		status = http.StatusConflict
+	}
 	writeJSON(w, status, map[string]any{"error": err.Error()})
 }
 
+// parseJSON decodes the request body into dst. Bodies of the routes in
+// schemaRoutes were validated by authorize before the handler ran.
 func parseJSON(r *http.Request, dst any) error {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -29,16 +110,92 @@ func parseJSON(r *http.Request, dst any) error {
 	return nil
 }
 
-func intQuery(r *http.Request, key string, fallback int) int {
+// DefaultQueryLimits caps the numeric query parameter of each endpoint that
+// takes one, keyed by endpoint: limit for the list and sync endpoints, days
+// for charts, export and stats, and weeks for the weekly feed.
+var DefaultQueryLimits = map[string]int{
+	"weight/recent":       500,
+	"water/recent":        500,
+	"food/recent":         500,
+	"mood/recent":         366,
+	"meds/recent":         500,
+	"temperature/recent":  500,
+	"metrics/recent":      500,
+	"charts/daily":        366,
+	"export/influx":       366,
+	"export/charts":       366,
+	"stats/compliance":    366,
+	"stats/weekly":        52,
+	"feeds/weekly":        52,
+	"admin/stats":         104,
+	"webhooks/deliveries": 200,
+	"sync":                1000,
+	"activity":            500,
+}
+
+// ParseQueryLimits parses comma-separated endpoint=max overrides, e.g.
+// "weight/recent=1000,charts/daily=90".
+func ParseQueryLimits(spec string) (map[string]int, error) {
+	out := map[string]int{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		endpoint, v, ok := strings.Cut(entry, "=")
+		if _, known := DefaultQueryLimits[endpoint]; !ok || !known {
+			return nil, fmt.Errorf("unknown query limit %q", entry)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("query limit for %s must be a positive integer", endpoint)
+		}
+		out[endpoint] = n
+	}
+	return out, nil
+}
+
+// queryError reports an out-of-range or malformed query parameter. Want
+// describes the expected format of non-integer parameters.
+type queryError struct {
+	Param string
+	Value string
+	Max   int
+	Want  string
+}
+
+func (e *queryError) Error() string {
+	if e.Want != "" {
+		return fmt.Sprintf("%s must be %s", e.Param, e.Want)
+	}
+	return fmt.Sprintf("%s must be an integer between 1 and %d", e.Param, e.Max)
+}
+
+// intQuery returns the positive integer query parameter key, or fallback when
+// it is absent. Values that are malformed or exceed the endpoint's limit are
+// rejected with a *queryError, as validateRequest does for routes that
+// declare the parameter.
+func (s *Server) intQuery(r *http.Request, endpoint, key string, fallback int) (int, error) {
 	v := r.URL.Query().Get(key)
 	if v == "" {
-		return fallback
+		return fallback, nil
 	}
-	n, err := strconv.Atoi(v)
-	if err != nil || n <= 0 {
-		return fallback
+	if err := s.checkParam(limitParam(key, endpoint), v); err != nil {
+		return 0, err
 	}
-	return n
+	return strconv.Atoi(v)
+}
+
+// tagFilter parses the ?tag= filter, e.g. ?tag=travel&tag=-sick.
+func tagFilter(r *http.Request) (domain.TagFilter, error) {
+	return domain.ParseTagFilter(r.URL.Query()["tag"])
+}
+
+// exportFilter parses the ?from=, ?to= and ?metrics= parameters of the
+// export endpoints.
+func exportFilter(r *http.Request) (domain.ExportFilter, error) {
+	q := r.URL.Query()
+	return domain.ParseExportFilter(q.Get("from"), q.Get("to"), q.Get("metrics"))
 }
 
 func localDayString(t time.Time) string {
@@ -52,20 +209,30 @@ func withNoCache(next http.Handler) http.Handler {
 	})
 }
 
-func spaFromDisk(dir string) http.Handler {
+// spaFromDisk serves the web directory. Page routes resolve, in order, to
+// the file pages maps them to, "/name" to name.html when it exists, and
+// "/" to index.html. Other paths serve the static file, falling back to
+// index.html so client-side routes load the app.
+func spaFromDisk(dir string, pages map[string]string) http.Handler {
 	fileServer := http.FileServer(http.Dir(dir))
 	indexPath := path.Join(dir, "index.html")
-	chartsPath := path.Join(dir, "charts.html")
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		reqPath := path.Clean(r.URL.Path)
+		if file, ok := pageFor(pages, reqPath); ok {
+			http.ServeFile(w, r, path.Join(dir, file))
+			return
+		}
 		if reqPath == "/" {
 			http.ServeFile(w, r, indexPath)
 			return
 		}
-		if reqPath == "/charts" {
-			http.ServeFile(w, r, chartsPath)
-			return
+		if path.Ext(reqPath) == "" {
+			pagePath := path.Join(dir, reqPath+".html")
+			if fi, err := os.Stat(pagePath); err == nil && !fi.IsDir() {
+				http.ServeFile(w, r, pagePath)
+				return
+			}
 		}
 
 		staticPath := path.Join(dir, reqPath)
@@ -77,3 +244,179 @@ func spaFromDisk(dir string) http.Handler {
 		http.ServeFile(w, r, indexPath)
 	})
 }
+
+// pageFor returns the file pages maps reqPath to: an exact route, or else
+// the longest route ending in "/" that reqPath is under, as in
+// http.ServeMux.
+func pageFor(pages map[string]string, reqPath string) (string, bool) {
+	if file, ok := pages[reqPath]; ok {
+		return file, true
+	}
+	best, file := "", ""
+	for route, f := range pages {
+		if strings.HasSuffix(route, "/") && len(route) > len(best) &&
+			(strings.HasPrefix(reqPath, route) || reqPath == strings.TrimSuffix(route, "/")) {
+			best, file = route, f
+		}
+	}
+	return file, best != ""
+}
+
+// ParsePages parses a comma-separated list of route=file pairs, such as
+// "/history=history.html,/goals/=goals.html", into page routes for
+// WithPages. Files are relative to the web directory.
+func ParsePages(spec string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, file, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(route, "/") || strings.HasPrefix(route, "/api/") {
+			return nil, fmt.Errorf("invalid page route %q", entry)
+		}
+		if file == "" || path.IsAbs(file) || path.Clean(file) != file || strings.HasPrefix(file, "../") || file == ".." {
+			return nil, fmt.Errorf("page file for %s must be a path inside the web directory", route)
+		}
+		out[route] = file
+	}
+	return out, nil
+}
+
+// HealthAccess restricts who may call /api/health, whose answer reveals
+// maintenance state. The zero value leaves it public. With Token set only
+// requests bearing "Authorization: Bearer <Token>" get through; with
+// Networks only callers in those networks, as resolved by clientAddr, so
+// behind trusted proxies the forwarded client is checked. Loopback gets no
+// exemption: list 127.0.0.1 and ::1 for `vitals healthcheck` to keep
+// working. Others get 404.
+type HealthAccess struct {
+	Networks []netip.Prefix
+	Token    string
+}
+
+// ParseHealthAccess parses HEALTH_ACCESS: "public" (or empty), "token",
+// which requires token, or a comma-separated list of addresses and CIDR
+// prefixes, e.g. "10.0.0.0/8,192.168.1.5".
+func ParseHealthAccess(spec, token string) (HealthAccess, error) {
+	switch strings.TrimSpace(spec) {
+	case "", "public":
+		return HealthAccess{}, nil
+	case "token":
+		if token == "" {
+			return HealthAccess{}, errors.New("HEALTH_TOKEN must be set for token access")
+		}
+		return HealthAccess{Token: token}, nil
+	}
+	networks, err := parseNetworks(spec)
+	if err != nil {
+		return HealthAccess{}, fmt.Errorf("invalid health access network %w", err)
+	}
+	if len(networks) == 0 {
+		return HealthAccess{}, fmt.Errorf("health access %q lists no networks", spec)
+	}
+	return HealthAccess{Networks: networks}, nil
+}
+
+// ParseTrustedProxies parses TRUSTED_PROXIES: a comma-separated list of
+// addresses and CIDR prefixes of the reverse proxies in front of the
+// server, e.g. "10.0.0.0/8,192.168.1.5".
+func ParseTrustedProxies(spec string) ([]netip.Prefix, error) {
+	networks, err := parseNetworks(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy %w", err)
+	}
+	return networks, nil
+}
+
+// parseNetworks parses a comma-separated list of addresses and CIDR
+// prefixes; an error quotes the entry it could not parse.
+func parseNetworks(spec string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(entry)
+			if aerr != nil {
+				return nil, fmt.Errorf("%q", entry)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		networks = append(networks, p.Masked())
+	}
+	return networks, nil
+}
+
+// clientAddr returns the address sign-in limits count against and health
+// access checks: the connection's peer or, when that is a trusted proxy,
+// the last address in X-Forwarded-For that is not one. Without trusted proxies the header is
+// ignored, since any client could set it.
+func (s *Server) clientAddr(r *http.Request) string {
+	trusted := func(addr netip.Addr) bool {
+		return slices.ContainsFunc(s.trustedProxies, func(p netip.Prefix) bool { return p.Contains(addr) })
+	}
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !trusted(ap.Addr().Unmap()) {
+		return r.RemoteAddr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if addr = addr.Unmap(); !trusted(addr) {
+			return addr.String()
+		}
+	}
+	return r.RemoteAddr
+}
+
+// allows reports whether a may see r, sent by client as returned by
+// clientAddr.
+func (a HealthAccess) allows(r *http.Request, client string) bool {
+	if a.Token != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(got), []byte(a.Token)) == 1
+	}
+	if len(a.Networks) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(client)
+	if err != nil {
+		ap, err := netip.ParseAddrPort(client)
+		if err != nil {
+			return false
+		}
+		addr = ap.Addr()
+	}
+	addr = addr.Unmap()
+	for _, n := range a.Networks {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFrameAncestors parses FRAME_ANCESTORS: a comma- or space-separated
+// list of origins, e.g. "https://organizr.example.com", allowed to show
+// /embed pages in a frame besides the app's own origin. Hosts may start
+// with a "*." wildcard.
+func ParseFrameAncestors(spec string) ([]string, error) {
+	var origins []string
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' }) {
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid frame ancestor %q: want an origin like https://dash.example.com", entry)
+		}
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+	return origins, nil
+}
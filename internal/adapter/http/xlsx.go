@@ -0,0 +1,145 @@
+package adapthttp
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The fixed parts of a workbook with a single worksheet.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)
+
+// writeXLSX streams an Excel workbook named after name, with header and the
+// rows produced by rows in its only sheet. Decimal numbers are stored as
+// numbers, everything else as text. Errors are handled like writeCSV's.
+func writeXLSX(w http.ResponseWriter, name string, header []string, rows func(row func(...string) error) error) {
+	filename := fmt.Sprintf("vitals-%s-%s.xlsx", name, time.Now().In(time.Local).Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	sw := &startedWriter{ResponseWriter: w}
+	err := writeWorkbook(sw, name, header, rows)
+	if err == nil {
+		return
+	}
+	if !sw.started {
+		w.Header().Del("Content-Disposition")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("[HTTP] export %s: %v", name, err)
+}
+
+func writeWorkbook(w io.Writer, name string, header []string, rows func(row func(...string) error) error) error {
+	sheetName, err := xmlEscape(name)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ path, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheetName)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(part.path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	_, _ = bw.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	row := func(fields ...string) error {
+		_, _ = bw.WriteString("<row>")
+		for _, v := range fields {
+			if err := writeCell(bw, v); err != nil {
+				return err
+			}
+		}
+		_, err := bw.WriteString("</row>")
+		return err
+	}
+	if err := row(header...); err != nil {
+		return err
+	}
+	if err := rows(row); err != nil {
+		return err
+	}
+	if _, err := bw.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeCell writes v as an inline string or, if it is a plain decimal
+// number, a numeric cell. Empty values are written as empty cells, keeping
+// the columns aligned.
+func writeCell(w *bufio.Writer, v string) error {
+	if v == "" {
+		_, err := w.WriteString("<c/>")
+		return err
+	}
+	if isDecimal(v) {
+		_, err := fmt.Fprintf(w, "<c><v>%s</v></c>", v)
+		return err
+	}
+	escaped, err := xmlEscape(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `<c t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, escaped)
+	return err
+}
+
+// isDecimal reports whether v is a number as strconv.FormatFloat(x, 'f',
+// -1, 64) or strconv.Itoa write it.
+func isDecimal(v string) bool {
+	if _, err := strconv.ParseFloat(v, 64); err != nil {
+		return false
+	}
+	return strings.Trim(v, "-.0123456789") == ""
+}
+
+func xmlEscape(s string) (string, error) {
+	var b strings.Builder
+	err := xml.EscapeText(&b, []byte(s))
+	return b.String(), err
+}
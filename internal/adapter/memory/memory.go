@@ -4,7 +4,11 @@ package memory
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,29 +17,147 @@ import (
 
 // DB implements an in-memory database storage.
 type DB struct {
-	mu          sync.Mutex
-	weights     []domain.WeightEntry
-	waterEvents []domain.WaterEvent
-	users       []*domain.User
-	sessions    map[string]*domain.Session
+	mu                sync.Mutex
+	weights           []domain.WeightEntry
+	waterEvents       []domain.WaterEvent
+	food              []domain.FoodEntry
+	users             []*domain.User
+	profiles          []domain.Profile
+	shares            []domain.Share
+	apiTokens         []domain.APIToken
+	changes           []change
+	alertRules        map[int64]domain.AlertRule
+	rules             []domain.Rule
+	plans             []domain.PlannedEntry
+	meds              []domain.Medication
+	medEvents         []domain.MedicationEvent
+	temps             []domain.TemperatureReading
+	metrics           []domain.CustomMetric
+	metricEvents      []domain.CustomMetricEvent
+	hydration         map[int64]domain.HydrationSettings
+	goals             map[int64]domain.GoalHistory
+	weightGoals       map[int64]domain.WeightGoalHistory
+	settings          map[int64]domain.UserSettings
+	tags              map[tagKey][]string
+	imports           map[tagKey]string
+	journal           map[int64]map[string]domain.JournalEntry
+	mood              map[int64]map[string]domain.MoodEntry
+	steps             map[int64]map[string]domain.StepsEntry
+	summaries         map[int64]map[string]domain.WeeklySummary
+	sessions          map[string]*domain.Session
+	revocations       map[int64]int64
+	changesPurged     map[int64]int64
+	identities        map[identityKey]domain.LinkedIdentity
+	emails            map[int64]domain.EmailChange
+	recoveryCodes     []recoveryCode
+	oauthTokens       map[oauthKey]domain.OAuthToken
+	outbox            []domain.OutboxMessage
+	webhooks          []domain.Webhook
+	webhookDeliveries []domain.WebhookDelivery
 
-	weightIDCounter int64
-	waterIDCounter  int64
-	userIDCounter   int64
+	weightIDCounter    int64
+	waterIDCounter     int64
+	foodIDCounter      int64
+	userIDCounter      int64
+	tokenIDCounter     int64
+	ruleIDCounter      int64
+	planIDCounter      int64
+	medIDCounter       int64
+	medEventCounter    int64
+	tempIDCounter      int64
+	metricIDCounter    int64
+	metricEventCounter int64
+	sessionIDCounter   int64
+	outboxIDCounter    int64
+	webhookIDCounter   int64
+	deliveryIDCounter  int64
+	changeSeq          int64
+}
+
+// change is a change log entry; entity state is attached when listing.
+type change struct {
+	domain.Change
+	userID int64
+}
+
+// identityKey addresses a linked SSO identity.
+type identityKey struct {
+	issuer, subject string
+}
+
+// oauthKey addresses a user's integration token.
+type oauthKey struct {
+	userID   int64
+	provider string
+}
+
+// tagKey addresses a tagged entry.
+type tagKey struct {
+	entity string
+	id     int64
 }
 
 // New creates a new in-memory database.
 func New() *DB {
 	return &DB{
-		sessions: make(map[string]*domain.Session),
+		sessions:      make(map[string]*domain.Session),
+		revocations:   make(map[int64]int64),
+		changesPurged: make(map[int64]int64),
+		identities:    make(map[identityKey]domain.LinkedIdentity),
+		emails:        make(map[int64]domain.EmailChange),
+		alertRules:    make(map[int64]domain.AlertRule),
+		hydration:     make(map[int64]domain.HydrationSettings),
+		goals:         make(map[int64]domain.GoalHistory),
+		weightGoals:   make(map[int64]domain.WeightGoalHistory),
+		settings:      make(map[int64]domain.UserSettings),
+		tags:          make(map[tagKey][]string),
+		imports:       make(map[tagKey]string),
+		journal:       make(map[int64]map[string]domain.JournalEntry),
+		mood:          make(map[int64]map[string]domain.MoodEntry),
+		steps:         make(map[int64]map[string]domain.StepsEntry),
+		summaries:     make(map[int64]map[string]domain.WeeklySummary),
+		oauthTokens:   make(map[oauthKey]domain.OAuthToken),
 	}
 }
 
 // Ensure interfaces are met.
 var _ domain.WeightRepository = (*DB)(nil)
 var _ domain.WaterRepository = (*DB)(nil)
+var _ domain.FoodRepository = (*DB)(nil)
+var _ domain.ImportRepository = (*DB)(nil)
 var _ domain.UserRepository = (*DB)(nil)
+var _ domain.IdentityRepository = (*DB)(nil)
+var _ domain.AccountRepository = (*DB)(nil)
+var _ domain.RecoveryRepository = (*DB)(nil)
+var _ domain.RevocationRepository = (*DB)(nil)
 var _ domain.SessionRepository = (*SessionRepo)(nil)
+var _ domain.MaintenanceRepository = (*DB)(nil)
+var _ domain.UsageRepository = (*DB)(nil)
+var _ domain.ProfileRepository = (*DB)(nil)
+var _ domain.ShareRepository = (*DB)(nil)
+var _ domain.APITokenRepository = (*DB)(nil)
+var _ domain.ChangeRepository = (*DB)(nil)
+var _ domain.BatchRepository = (*DB)(nil)
+var _ domain.AlertRuleRepository = (*DB)(nil)
+var _ domain.RuleRepository = (*DB)(nil)
+var _ domain.PlanRepository = (*DB)(nil)
+var _ domain.OutboxRepository = (*DB)(nil)
+var _ domain.WebhookRepository = (*DB)(nil)
+var _ domain.MedicationRepository = (*DB)(nil)
+var _ domain.TemperatureRepository = (*DB)(nil)
+var _ domain.CustomMetricRepository = (*DB)(nil)
+var _ domain.HydrationSettingsRepository = (*DB)(nil)
+var _ domain.GoalHistoryRepository = (*DB)(nil)
+var _ domain.WeightGoalRepository = (*DB)(nil)
+var _ domain.ActivityRepository = (*DB)(nil)
+var _ domain.RetentionRepository = (*DB)(nil)
+var _ domain.OAuthTokenRepository = (*DB)(nil)
+var _ domain.SettingsRepository = (*DB)(nil)
+var _ domain.TagRepository = (*DB)(nil)
+var _ domain.JournalRepository = (*DB)(nil)
+var _ domain.MoodRepository = (*DB)(nil)
+var _ domain.StepsRepository = (*DB)(nil)
+var _ domain.WeeklySummaryRepository = (*DB)(nil)
 
 // --- WeightRepository ---
 
@@ -55,38 +177,92 @@ func (db *DB) AddWeightEvent(ctx context.Context, userID int64, value float64, u
 		CreatedAt: createdAt.UTC(),
 	}
 	db.weights = append(db.weights, entry)
+	db.logChange(userID, domain.ChangeEntityWeight, id, domain.ChangeOpUpsert)
 	return id, nil
 }
 
-// DeleteLatestWeightEvent deletes the most recent weight event for a user.
-func (db *DB) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
+// AddWeightEventWithClientID adds a weight event unless the client ID has
+// already been used by the user.
+func (db *DB) AddWeightEventWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	if len(db.weights) == 0 {
-		return false, nil
+	entry, created := db.insertWeight(userID, clientID, value, unit, createdAt)
+	return entry, created, nil
+}
+
+// insertWeight adds a weight event, deduplicating on a non-empty clientID.
+// Callers must hold db.mu.
+func (db *DB) insertWeight(userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool) {
+	if clientID != "" {
+		for _, w := range db.weights {
+			if w.UserID == userID && w.ClientID == clientID {
+				w.Day = w.CreatedAt.In(time.Local).Format("2006-01-02")
+				return &w, false
+			}
+		}
 	}
 
-	// Find index of latest created_at for this user
-	lastIdx := -1
-	var lastTime time.Time
+	db.weightIDCounter++
+	entry := domain.WeightEntry{
+		ID:        db.weightIDCounter,
+		UserID:    userID,
+		Value:     value,
+		Unit:      unit,
+		ClientID:  clientID,
+		CreatedAt: createdAt.UTC(),
+	}
+	db.weights = append(db.weights, entry)
+	db.logChange(userID, domain.ChangeEntityWeight, entry.ID, domain.ChangeOpUpsert)
+	entry.Day = entry.CreatedAt.In(time.Local).Format("2006-01-02")
+	return &entry, true
+}
 
+// deleteWeight removes a weight event by ID, scoped to a user. Callers must
+// hold db.mu.
+func (db *DB) deleteWeight(userID, id int64) bool {
 	for i, w := range db.weights {
-		if w.UserID != userID {
-			continue
+		if w.ID == id && w.UserID == userID {
+			db.weights = append(db.weights[:i], db.weights[i+1:]...)
+			delete(db.tags, tagKey{domain.ChangeEntityWeight, id})
+			delete(db.imports, tagKey{domain.ChangeEntityWeight, id})
+			db.logChange(userID, domain.ChangeEntityWeight, id, domain.ChangeOpDelete)
+			return true
 		}
-		if lastIdx == -1 || w.CreatedAt.After(lastTime) {
-			lastIdx = i
-			lastTime = w.CreatedAt
+	}
+	return false
+}
+
+// DeleteLatestWeightEvent deletes the most recent weight event for a user.
+// The lookup and delete happen under one lock, so concurrent undos each
+// remove a different event.
+func (db *DB) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var (
+		id     int64
+		latest time.Time
+	)
+	for _, w := range db.weights {
+		if w.UserID == userID && (id == 0 || newer(w.CreatedAt, w.ID, latest, id)) {
+			id, latest = w.ID, w.CreatedAt
 		}
 	}
+	if id == 0 {
+		return false, nil
+	}
+	return db.deleteWeight(userID, id), nil
+}
 
-	if lastIdx != -1 {
-		// remove element
-		db.weights = append(db.weights[:lastIdx], db.weights[lastIdx+1:]...)
-		return true, nil
+// newer reports whether an entry created at a with ID aID sorts after one
+// created at b with ID bID; ties on time go to the higher ID, as in the
+// postgres adapter.
+func newer(a time.Time, aID int64, b time.Time, bID int64) bool {
+	if !a.Equal(b) {
+		return a.After(b)
 	}
-	return false, nil
+	return aID > bID
 }
 
 // LatestWeightForLocalDay returns the latest weight for the given day for a user.
@@ -94,11 +270,10 @@ func (db *DB) LatestWeightForLocalDay(ctx context.Context, userID int64, localDa
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	dayStart, err := time.ParseInLocation("2006-01-02", localDay, time.Local)
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
 	if err != nil {
 		return nil, err
 	}
-	dayEnd := dayStart.Add(24 * time.Hour)
 
 	var latest *domain.WeightEntry
 
@@ -124,6 +299,62 @@ func (db *DB) LatestWeightForLocalDay(ctx context.Context, userID int64, localDa
 	return nil, nil
 }
 
+// AverageWeightForLocalDay returns the mean of the day's weigh-ins in kg.
+func (db *DB) AverageWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sum    float64
+		n      int
+		latest time.Time
+	)
+	for _, w := range db.weights {
+		if w.UserID != userID || w.CreatedAt.Before(dayStart.UTC()) || !w.CreatedAt.Before(dayEnd.UTC()) {
+			continue
+		}
+		sum += domain.ConvertWeight(w.Value, w.Unit, "kg")
+		n++
+		if w.CreatedAt.After(latest) {
+			latest = w.CreatedAt
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return &domain.WeightEntry{UserID: userID, Day: localDay, Value: sum / float64(n), Unit: "kg", CreatedAt: latest}, nil
+}
+
+// WeightSpanSince aggregates the user's weigh-ins at or after since.
+func (db *DB) WeightSpanSince(ctx context.Context, userID int64, since time.Time) (*domain.WeightSpan, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var span *domain.WeightSpan
+	for _, w := range db.weights {
+		if w.UserID != userID || w.CreatedAt.Before(since) {
+			continue
+		}
+		kg := domain.ConvertWeight(w.Value, w.Unit, "kg")
+		if span == nil {
+			span = &domain.WeightSpan{FirstKg: kg, LastKg: kg, FirstAt: w.CreatedAt, LastAt: w.CreatedAt}
+		}
+		span.Count++
+		if w.CreatedAt.Before(span.FirstAt) {
+			span.FirstKg, span.FirstAt = kg, w.CreatedAt
+		}
+		if !w.CreatedAt.Before(span.LastAt) {
+			span.LastKg, span.LastAt = kg, w.CreatedAt
+		}
+	}
+	return span, nil
+}
+
 // ListRecentWeightEvents lists the most recent weight events for a user.
 func (db *DB) ListRecentWeightEvents(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error) {
 	db.mu.Lock()
@@ -154,6 +385,68 @@ func (db *DB) ListRecentWeightEvents(ctx context.Context, userID int64, limit in
 	return filtered, nil
 }
 
+// ListRecentWeightPage lists the most recent weight events for a user along
+// with a summary of all of them.
+func (db *DB) ListRecentWeightPage(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, domain.ListSummary, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var (
+		filtered []domain.WeightEntry
+		sum      domain.ListSummary
+	)
+	for _, e := range db.weights {
+		if e.UserID != userID {
+			continue
+		}
+		filtered = append(filtered, e)
+		if sum.FirstAt == nil || e.CreatedAt.Before(*sum.FirstAt) {
+			sum.FirstAt = &e.CreatedAt
+		}
+		if sum.LastAt == nil || e.CreatedAt.After(*sum.LastAt) {
+			sum.LastAt = &e.CreatedAt
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
+	})
+
+	sum.Total = len(filtered)
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	for i := range filtered {
+		filtered[i].Day = filtered[i].CreatedAt.In(time.Local).Format("2006-01-02")
+	}
+	sum.HasMore = sum.Total > len(filtered)
+	return filtered, sum, nil
+}
+
+// EachWeightEvent calls fn with a snapshot of the user's weight events,
+// oldest first. The lock is released before fn runs so that fn may use the
+// database.
+func (db *DB) EachWeightEvent(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error {
+	db.mu.Lock()
+	var events []domain.WeightEntry
+	for _, w := range db.weights {
+		if w.UserID == userID {
+			events = append(events, w)
+		}
+	}
+	db.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return newer(events[j].CreatedAt, events[j].ID, events[i].CreatedAt, events[i].ID)
+	})
+	for _, e := range events {
+		e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --- WaterRepository ---
 
 // AddWaterEvent adds a water event.
@@ -171,21 +464,109 @@ func (db *DB) AddWaterEvent(ctx context.Context, userID int64, deltaLiters float
 		CreatedAt:   createdAt.UTC(),
 	}
 	db.waterEvents = append(db.waterEvents, event)
+	db.logChange(userID, domain.ChangeEntityWater, id, domain.ChangeOpUpsert)
 	return id, nil
 }
 
+// AddWaterEventWithClientID adds a water event unless the client ID has
+// already been used by the user.
+func (db *DB) AddWaterEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	event, created := db.insertWater(userID, clientID, deltaLiters, createdAt)
+	return event, created, nil
+}
+
+// insertWater adds a water event, deduplicating on a non-empty clientID.
+// Callers must hold db.mu.
+func (db *DB) insertWater(userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool) {
+	if clientID != "" {
+		for _, w := range db.waterEvents {
+			if w.UserID == userID && w.ClientID == clientID {
+				return &w, false
+			}
+		}
+	}
+
+	db.waterIDCounter++
+	event := domain.WaterEvent{
+		ID:          db.waterIDCounter,
+		UserID:      userID,
+		DeltaLiters: deltaLiters,
+		ClientID:    clientID,
+		CreatedAt:   createdAt.UTC(),
+	}
+	db.waterEvents = append(db.waterEvents, event)
+	db.logChange(userID, domain.ChangeEntityWater, event.ID, domain.ChangeOpUpsert)
+	return &event, true
+}
+
 // DeleteWaterEvent deletes a water event by ID, scoped to a user.
 func (db *DB) DeleteWaterEvent(ctx context.Context, userID int64, id int64) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	db.deleteWater(userID, id)
+	return nil
+}
+
+// DeleteLatestWaterEvent deletes the user's most recent water event under
+// one lock and returns its ID, or 0 if there was none.
+func (db *DB) DeleteLatestWaterEvent(ctx context.Context, userID int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var (
+		id     int64
+		latest time.Time
+	)
+	for _, w := range db.waterEvents {
+		if w.UserID == userID && (id == 0 || newer(w.CreatedAt, w.ID, latest, id)) {
+			id, latest = w.ID, w.CreatedAt
+		}
+	}
+	if id == 0 {
+		return 0, nil
+	}
+	db.deleteWater(userID, id)
+	return id, nil
+}
+
+// deleteWater removes a water event by ID, scoped to a user. Callers must
+// hold db.mu.
+func (db *DB) deleteWater(userID, id int64) bool {
 	for i, w := range db.waterEvents {
 		if w.ID == id && w.UserID == userID {
 			db.waterEvents = append(db.waterEvents[:i], db.waterEvents[i+1:]...)
-			return nil
+			delete(db.tags, tagKey{domain.ChangeEntityWater, id})
+			delete(db.imports, tagKey{domain.ChangeEntityWater, id})
+			db.logChange(userID, domain.ChangeEntityWater, id, domain.ChangeOpDelete)
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+// UpdateWaterEvent changes a water event's volume or time, scoped to a user.
+func (db *DB) UpdateWaterEvent(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, w := range db.waterEvents {
+		if w.ID == id && w.UserID == userID {
+			if p.DeltaLiters != nil {
+				w.DeltaLiters = *p.DeltaLiters
+			}
+			if p.CreatedAt != nil {
+				w.CreatedAt = p.CreatedAt.UTC()
+			}
+			db.waterEvents[i] = w
+			db.logChange(userID, domain.ChangeEntityWater, id, domain.ChangeOpUpsert)
+			return &w, nil
+		}
+	}
+	return nil, nil
 }
 
 // ListRecentWaterEvents lists the most recent water events for a user.
@@ -210,16 +591,73 @@ func (db *DB) ListRecentWaterEvents(ctx context.Context, userID int64, limit int
 	return filtered, nil
 }
 
+// ListRecentWaterPage lists the most recent water events for a user along
+// with a summary of all of them.
+func (db *DB) ListRecentWaterPage(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, domain.ListSummary, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var (
+		filtered []domain.WaterEvent
+		sum      domain.ListSummary
+	)
+	for _, e := range db.waterEvents {
+		if e.UserID != userID {
+			continue
+		}
+		filtered = append(filtered, e)
+		if sum.FirstAt == nil || e.CreatedAt.Before(*sum.FirstAt) {
+			sum.FirstAt = &e.CreatedAt
+		}
+		if sum.LastAt == nil || e.CreatedAt.After(*sum.LastAt) {
+			sum.LastAt = &e.CreatedAt
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
+	})
+
+	sum.Total = len(filtered)
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	sum.HasMore = sum.Total > len(filtered)
+	return filtered, sum, nil
+}
+
+// EachWaterEvent calls fn with a snapshot of the user's water events, oldest
+// first. The lock is released before fn runs so that fn may use the
+// database.
+func (db *DB) EachWaterEvent(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error {
+	db.mu.Lock()
+	var events []domain.WaterEvent
+	for _, w := range db.waterEvents {
+		if w.UserID == userID {
+			events = append(events, w)
+		}
+	}
+	db.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return newer(events[j].CreatedAt, events[j].ID, events[i].CreatedAt, events[i].ID)
+	})
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WaterTotalForLocalDay returns the total water intake for the given day for a user.
 func (db *DB) WaterTotalForLocalDay(ctx context.Context, userID int64, localDay string) (float64, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	dayStart, err := time.ParseInLocation("2006-01-02", localDay, time.Local)
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
 	if err != nil {
 		return 0, err
 	}
-	dayEnd := dayStart.Add(24 * time.Hour)
 
 	var total float64
 	for _, w := range db.waterEvents {
@@ -233,109 +671,2341 @@ func (db *DB) WaterTotalForLocalDay(ctx context.Context, userID int64, localDay
 	return total, nil
 }
 
-// --- UserRepository ---
+// --- ImportRepository ---
 
-// GetByUsername retrieves a user by username.
-func (db *DB) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+// ImportWeightEvent adds a weight event as part of an import batch unless
+// the user already has an identical one or one with the same client ID.
+func (db *DB) ImportWeightEvent(ctx context.Context, userID int64, batchID, clientID string, value float64, unit string, createdAt time.Time) (bool, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	for _, u := range db.users {
-		if u.Username == username {
-			return u, nil
+	for _, w := range db.weights {
+		if w.UserID == userID && w.CreatedAt.Equal(createdAt) && w.Value == value && w.Unit == unit {
+			return false, nil
 		}
 	}
-	// Return nil if not found
-	return nil, nil
+	entry, created := db.insertWeight(userID, clientID, value, unit, createdAt)
+	if created {
+		db.imports[tagKey{domain.ChangeEntityWeight, entry.ID}] = batchID
+	}
+	return created, nil
 }
 
-// GetByID retrieves a user by ID.
-func (db *DB) GetByID(ctx context.Context, id int64) (*domain.User, error) {
+// ImportWaterEvent adds a water event as part of an import batch unless the
+// user already has an identical one or one with the same client ID.
+func (db *DB) ImportWaterEvent(ctx context.Context, userID int64, batchID, clientID string, deltaLiters float64, createdAt time.Time) (bool, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	for _, u := range db.users {
-		if u.ID == id {
-			return u, nil
+	for _, w := range db.waterEvents {
+		if w.UserID == userID && w.CreatedAt.Equal(createdAt) && w.DeltaLiters == deltaLiters {
+			return false, nil
 		}
 	}
-	return nil, nil
+	event, created := db.insertWater(userID, clientID, deltaLiters, createdAt)
+	if created {
+		db.imports[tagKey{domain.ChangeEntityWater, event.ID}] = batchID
+	}
+	return created, nil
 }
 
-// Create creates a new user.
-func (db *DB) Create(ctx context.Context, username, passwordHash string) (*domain.User, error) {
+// DeleteImportBatch deletes the user's events imported as part of batchID.
+func (db *DB) DeleteImportBatch(ctx context.Context, userID int64, batchID string) (int, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	for _, u := range db.users {
-		if u.Username == username {
-			return nil, errors.New("user already exists")
+	n := 0
+	for key, batch := range db.imports {
+		if batch != batchID {
+			continue
+		}
+		var deleted bool
+		switch key.entity {
+		case domain.ChangeEntityWeight:
+			deleted = db.deleteWeight(userID, key.id)
+		case domain.ChangeEntityWater:
+			deleted = db.deleteWater(userID, key.id)
+		}
+		if deleted {
+			n++
 		}
 	}
-
-	db.userIDCounter++
-	u := &domain.User{
-		ID:           db.userIDCounter,
-		Username:     username,
-		PasswordHash: passwordHash,
-		CreatedAt:    time.Now().UTC(),
-	}
-	db.users = append(db.users, u)
-	return u, nil
+	return n, nil
 }
 
-// Count returns the total number of users.
-func (db *DB) Count(ctx context.Context) (int, error) {
+// --- FoodRepository ---
+
+// AddFoodEntry adds a food entry unless its client ID has already been used
+// by the user.
+func (db *DB) AddFoodEntry(ctx context.Context, userID int64, e domain.FoodEntry) (*domain.FoodEntry, bool, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	return len(db.users), nil
-}
 
-// --- SessionRepository ---
+	if e.ClientID != "" {
+		for _, f := range db.food {
+			if f.UserID == userID && f.ClientID == e.ClientID {
+				return &f, false, nil
+			}
+		}
+	}
 
-// SessionRepo implements session persistence.
-type SessionRepo struct {
-	db *DB
+	db.foodIDCounter++
+	e.ID = db.foodIDCounter
+	e.UserID = userID
+	e.CreatedAt = e.CreatedAt.UTC()
+	db.food = append(db.food, e)
+	return &e, true, nil
 }
 
-// NewSessionRepo creates a new session repository.
-func (db *DB) NewSessionRepo() *SessionRepo {
-	return &SessionRepo{db: db}
+// DeleteFoodEntry deletes a food entry by ID, scoped to a user.
+func (db *DB) DeleteFoodEntry(ctx context.Context, userID int64, id int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.food = slices.DeleteFunc(db.food, func(f domain.FoodEntry) bool {
+		return f.ID == id && f.UserID == userID
+	})
+	return nil
 }
 
-// Create creates a new session.
-func (r *SessionRepo) Create(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
+// DeleteLatestFoodEntry deletes the user's most recent food entry under one
+// lock and returns its ID, or 0 if there was none.
+func (db *DB) DeleteLatestFoodEntry(ctx context.Context, userID int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	r.db.sessions[token] = &domain.Session{
-		Token:     token,
-		UserID:    userID,
-		UserAgent: userAgent,
-		IP:        ip,
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now().UTC(),
+	idx := -1
+	for i, f := range db.food {
+		if f.UserID == userID && (idx == -1 || newer(f.CreatedAt, f.ID, db.food[idx].CreatedAt, db.food[idx].ID)) {
+			idx = i
+		}
 	}
-	return nil
+	if idx == -1 {
+		return 0, nil
+	}
+	id := db.food[idx].ID
+	db.food = slices.Delete(db.food, idx, idx+1)
+	return id, nil
 }
 
-// GetByToken retrieves a session by token.
-func (r *SessionRepo) GetByToken(ctx context.Context, token string) (*domain.Session, error) {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
+// ListRecentFoodEntries lists the most recent food entries for a user.
+func (db *DB) ListRecentFoodEntries(ctx context.Context, userID int64, limit int) ([]domain.FoodEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	if s, ok := r.db.sessions[token]; ok {
-		if time.Now().After(s.ExpiresAt) {
-			delete(r.db.sessions, token)
-			return nil, nil
+	var filtered []domain.FoodEntry
+	for _, f := range db.food {
+		if f.UserID == userID {
+			filtered = append(filtered, f)
 		}
-		return s, nil
 	}
-	return nil, nil
-}
 
-// Delete deletes a session.
-func (r *SessionRepo) Delete(ctx context.Context, token string) error {
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.After(filtered[j].CreatedAt)
+	})
+
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	return filtered, nil
+}
+
+// FoodTotalsForLocalDay sums a user's food entries on the given day.
+func (db *DB) FoodTotalsForLocalDay(ctx context.Context, userID int64, localDay string) (domain.NutritionTotals, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var totals domain.NutritionTotals
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
+	if err != nil {
+		return totals, err
+	}
+
+	for _, f := range db.food {
+		if f.UserID == userID && !f.CreatedAt.Before(dayStart.UTC()) && f.CreatedAt.Before(dayEnd.UTC()) {
+			totals.Add(f)
+		}
+	}
+	return totals, nil
+}
+
+// --- BatchRepository ---
+
+// ApplyBatch applies ops in order under a single lock, so no reader observes
+// a partially applied batch.
+func (db *DB) ApplyBatch(ctx context.Context, userID int64, ops []domain.BatchOp) ([]domain.BatchResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, op := range ops {
+		switch op.Op {
+		case domain.BatchOpWeight, domain.BatchOpWater, domain.BatchOpDeleteWeight, domain.BatchOpDeleteWater:
+		default:
+			return nil, fmt.Errorf("op %d: unknown op %q", i, op.Op)
+		}
+	}
+
+	results := make([]domain.BatchResult, len(ops))
+	for i, op := range ops {
+		res := domain.BatchResult{Op: op.Op, ID: op.ID}
+		switch op.Op {
+		case domain.BatchOpWeight:
+			res.Weight, res.Created = db.insertWeight(userID, op.ClientID, op.Value, op.Unit, op.CreatedAt)
+			res.ID = res.Weight.ID
+		case domain.BatchOpWater:
+			res.Water, res.Created = db.insertWater(userID, op.ClientID, op.DeltaLiters, op.CreatedAt)
+			res.ID = res.Water.ID
+		case domain.BatchOpDeleteWeight:
+			res.Deleted = db.deleteWeight(userID, op.ID)
+		case domain.BatchOpDeleteWater:
+			res.Deleted = db.deleteWater(userID, op.ID)
+		}
+		results[i] = res
+	}
+	return results, nil
+}
+
+// --- ChangeRepository ---
+
+// logChange appends to the change log. Callers must hold db.mu.
+func (db *DB) logChange(userID int64, entity string, id int64, op string) {
+	db.changeSeq++
+	db.changes = append(db.changes, change{
+		Change: domain.Change{
+			Seq:       db.changeSeq,
+			Entity:    entity,
+			EntityID:  id,
+			Op:        op,
+			ChangedAt: time.Now().UTC(),
+		},
+		userID: userID,
+	})
+}
+
+// LatestChange returns the user's most recent change to entity, or nil.
+func (db *DB) LatestChange(ctx context.Context, userID int64, entity string) (*domain.Change, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := len(db.changes) - 1; i >= 0; i-- {
+		if c := db.changes[i]; c.userID == userID && c.Entity == entity {
+			out := c.Change
+			return &out, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListChanges returns the latest change per entity after since, oldest first.
+func (db *DB) ListChanges(ctx context.Context, userID, since int64, limit int) ([]domain.Change, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	type key struct {
+		entity string
+		id     int64
+	}
+	latest := make(map[key]int)
+	for i, c := range db.changes {
+		if c.userID == userID && c.Seq > since {
+			latest[key{c.Entity, c.EntityID}] = i
+		}
+	}
+
+	out := make([]domain.Change, 0, len(latest))
+	for i, c := range db.changes {
+		if latest[key{c.Entity, c.EntityID}] != i || c.userID != userID || c.Seq <= since {
+			continue
+		}
+		ch := c.Change
+		if ch.Op == domain.ChangeOpUpsert {
+			db.attachEntity(&ch)
+		}
+		out = append(out, ch)
+		if len(out) == limit {
+			break
+		}
+	}
+	if since > 0 && since < db.changesPurged[userID] {
+		return nil, domain.Errorf(domain.ErrCursorExpired, "cursor %d predates purged deletions; sync again from 0", since)
+	}
+	return out, nil
+}
+
+// attachEntity fills in the current state of an upserted entity. Callers
+// must hold db.mu.
+func (db *DB) attachEntity(ch *domain.Change) {
+	switch ch.Entity {
+	case domain.ChangeEntityWeight:
+		for _, w := range db.weights {
+			if w.ID == ch.EntityID {
+				w.Day = w.CreatedAt.In(time.Local).Format("2006-01-02")
+				ch.Weight = &w
+				return
+			}
+		}
+	case domain.ChangeEntityWater:
+		for _, w := range db.waterEvents {
+			if w.ID == ch.EntityID {
+				ch.Water = &w
+				return
+			}
+		}
+	}
+}
+
+// --- UserRepository ---
+
+// GetByUsername retrieves a user by username.
+func (db *DB) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	// Return nil if not found
+	return nil, nil
+}
+
+// GetByID retrieves a user by ID.
+func (db *DB) GetByID(ctx context.Context, id int64) (*domain.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetLinkedIdentity returns the link for issuer and subject, or nil.
+func (db *DB) GetLinkedIdentity(ctx context.Context, issuer, subject string) (*domain.LinkedIdentity, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	id, ok := db.identities[identityKey{issuer, subject}]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+// LinkIdentity stores a link, replacing any for the same identity.
+func (db *DB) LinkIdentity(ctx context.Context, id domain.LinkedIdentity) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.identities[identityKey{id.Issuer, id.Subject}] = id
+	return nil
+}
+
+// Create creates a new user.
+func (db *DB) Create(ctx context.Context, username, passwordHash string) (*domain.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.Username == username {
+			return nil, errors.New("user already exists")
+		}
+	}
+
+	db.userIDCounter++
+	u := &domain.User{
+		ID:           db.userIDCounter,
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         domain.RoleUser,
+		CreatedAt:    time.Now().UTC(),
+	}
+	db.users = append(db.users, u)
+	return u, nil
+}
+
+// Count returns the total number of users.
+func (db *DB) Count(ctx context.Context) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.users), nil
+}
+
+// UpdatePassword replaces a user's password hash.
+func (db *DB) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.ID == userID {
+			u.PasswordHash = passwordHash
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+// RevocationEpoch returns the user's revocation epoch.
+func (db *DB) RevocationEpoch(ctx context.Context, userID int64) (int64, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !slices.ContainsFunc(db.users, func(u *domain.User) bool { return u.ID == userID }) {
+		return 0, false, nil
+	}
+	return db.revocations[userID], true, nil
+}
+
+// BumpRevocationEpoch moves the user's revocation epoch on.
+func (db *DB) BumpRevocationEpoch(ctx context.Context, userID int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.revocations[userID]++
+	return nil
+}
+
+// DeleteUser removes the user, their profiles and everything either owns.
+func (db *DB) DeleteUser(ctx context.Context, userID int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !slices.ContainsFunc(db.users, func(u *domain.User) bool { return u.ID == userID }) {
+		return errors.New("user not found")
+	}
+	gone := map[int64]bool{userID: true}
+	for _, p := range db.profiles {
+		if p.OwnerID == userID {
+			gone[p.ID] = true
+		}
+	}
+	for _, w := range db.weights {
+		if gone[w.UserID] {
+			delete(db.tags, tagKey{domain.ChangeEntityWeight, w.ID})
+			delete(db.imports, tagKey{domain.ChangeEntityWeight, w.ID})
+		}
+	}
+	for _, w := range db.waterEvents {
+		if gone[w.UserID] {
+			delete(db.tags, tagKey{domain.ChangeEntityWater, w.ID})
+			delete(db.imports, tagKey{domain.ChangeEntityWater, w.ID})
+		}
+	}
+	db.weights = keep(db.weights, func(w domain.WeightEntry) bool { return !gone[w.UserID] })
+	db.waterEvents = keep(db.waterEvents, func(w domain.WaterEvent) bool { return !gone[w.UserID] })
+	db.food = keep(db.food, func(f domain.FoodEntry) bool { return !gone[f.UserID] })
+	db.users = keep(db.users, func(u *domain.User) bool { return !gone[u.ID] })
+	db.profiles = keep(db.profiles, func(p domain.Profile) bool { return !gone[p.ID] })
+	db.shares = keep(db.shares, func(sh domain.Share) bool { return !gone[sh.OwnerID] && !gone[sh.ViewerID] })
+	db.apiTokens = keep(db.apiTokens, func(t domain.APIToken) bool { return !gone[t.UserID] })
+	db.changes = keep(db.changes, func(c change) bool { return !gone[c.userID] })
+	db.rules = keep(db.rules, func(r domain.Rule) bool { return !gone[r.UserID] })
+	db.plans = keep(db.plans, func(p domain.PlannedEntry) bool { return !gone[p.UserID] })
+	db.meds = keep(db.meds, func(m domain.Medication) bool { return !gone[m.UserID] })
+	db.medEvents = keep(db.medEvents, func(e domain.MedicationEvent) bool { return !gone[e.UserID] })
+	db.temps = keep(db.temps, func(r domain.TemperatureReading) bool { return !gone[r.UserID] })
+	db.metrics = keep(db.metrics, func(m domain.CustomMetric) bool { return !gone[m.UserID] })
+	db.metricEvents = keep(db.metricEvents, func(e domain.CustomMetricEvent) bool { return !gone[e.UserID] })
+	db.recoveryCodes = keep(db.recoveryCodes, func(c recoveryCode) bool { return !gone[c.userID] })
+	db.outbox = keep(db.outbox, func(m domain.OutboxMessage) bool { return !gone[m.Notification.UserID] })
+	db.webhooks = keep(db.webhooks, func(h domain.Webhook) bool { return !gone[h.UserID] })
+	db.webhookDeliveries = keep(db.webhookDeliveries, func(d domain.WebhookDelivery) bool { return !gone[d.UserID] })
+	for id := range gone {
+		delete(db.alertRules, id)
+		delete(db.hydration, id)
+		delete(db.goals, id)
+		delete(db.weightGoals, id)
+		delete(db.settings, id)
+		delete(db.journal, id)
+		delete(db.mood, id)
+		delete(db.steps, id)
+		delete(db.summaries, id)
+		delete(db.emails, id)
+		delete(db.revocations, id)
+		delete(db.changesPurged, id)
+	}
+	maps.DeleteFunc(db.sessions, func(_ string, s *domain.Session) bool { return gone[s.UserID] })
+	maps.DeleteFunc(db.identities, func(_ identityKey, id domain.LinkedIdentity) bool { return gone[id.UserID] })
+	maps.DeleteFunc(db.oauthTokens, func(k oauthKey, _ domain.OAuthToken) bool { return gone[k.userID] })
+	return nil
+}
+
+// keep returns the elements of s for which ok reports true, in a new slice
+// so slices handed out earlier are left alone.
+func keep[T any](s []T, ok func(T) bool) []T {
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		if ok(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// --- AccountRepository ---
+
+// GetByEmail retrieves a user by verified email.
+func (db *DB) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.Email != "" && u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateUsername renames a user.
+func (db *DB) UpdateUsername(ctx context.Context, userID int64, username string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var user *domain.User
+	for _, u := range db.users {
+		if u.Username == username && u.ID != userID {
+			return errors.New("user already exists")
+		}
+		if u.ID == userID {
+			user = u
+		}
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	user.Username = username
+	return nil
+}
+
+// SetEmail stores a user's verified email.
+func (db *DB) SetEmail(ctx context.Context, userID int64, email string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var user *domain.User
+	for _, u := range db.users {
+		if email != "" && u.Email == email && u.ID != userID {
+			return errors.New("email already in use")
+		}
+		if u.ID == userID {
+			user = u
+		}
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	user.Email = email
+	return nil
+}
+
+// SetRole sets a user's role.
+func (db *DB) SetRole(ctx context.Context, userID int64, role string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.ID == userID {
+			u.Role = role
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+// ClaimAdmin makes a user an admin if no user is one yet.
+func (db *DB) ClaimAdmin(ctx context.Context, userID int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var user *domain.User
+	for _, u := range db.users {
+		if u.IsAdmin() {
+			return false, nil
+		}
+		if u.ID == userID {
+			user = u
+		}
+	}
+	if user == nil {
+		return false, errors.New("user not found")
+	}
+	user.Role = domain.RoleAdmin
+	return true, nil
+}
+
+// SaveEmailChange stores a pending email change, replacing the user's last.
+func (db *DB) SaveEmailChange(ctx context.Context, c domain.EmailChange) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.emails[c.UserID] = c
+	return nil
+}
+
+// GetEmailChange returns the user's pending email change, or nil.
+func (db *DB) GetEmailChange(ctx context.Context, userID int64) (*domain.EmailChange, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	c, ok := db.emails[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// TakeEmailChange removes and returns the pending change with tokenHash.
+func (db *DB) TakeEmailChange(ctx context.Context, tokenHash string) (*domain.EmailChange, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for userID, c := range db.emails {
+		if c.TokenHash == tokenHash {
+			delete(db.emails, userID)
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// --- RecoveryRepository ---
+
+// recoveryCode is a stored recovery code; emergency codes never expire.
+type recoveryCode struct {
+	userID    int64
+	hash      string
+	expiresAt *time.Time
+}
+
+// ReplaceRecoveryCodes replaces the user's emergency codes with hashes.
+func (db *DB) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.recoveryCodes = slices.DeleteFunc(db.recoveryCodes, func(c recoveryCode) bool {
+		return c.userID == userID && c.expiresAt == nil
+	})
+	for _, h := range hashes {
+		db.recoveryCodes = append(db.recoveryCodes, recoveryCode{userID: userID, hash: h})
+	}
+	return nil
+}
+
+// IssueRecoveryCode stores a code that works until expiresAt, replacing the
+// user's previously issued one.
+func (db *DB) IssueRecoveryCode(ctx context.Context, userID int64, hash string, expiresAt time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.recoveryCodes = slices.DeleteFunc(db.recoveryCodes, func(c recoveryCode) bool {
+		return c.userID == userID && c.expiresAt != nil
+	})
+	db.recoveryCodes = append(db.recoveryCodes, recoveryCode{userID: userID, hash: hash, expiresAt: &expiresAt})
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused emergency codes the user has.
+func (db *DB) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	for _, c := range db.recoveryCodes {
+		if c.userID == userID && c.expiresAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RedeemRecoveryCode removes the user's unexpired code with hash and sets
+// their password hash.
+func (db *DB) RedeemRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time, passwordHash string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := slices.IndexFunc(db.recoveryCodes, func(c recoveryCode) bool {
+		return c.userID == userID && c.hash == hash && (c.expiresAt == nil || c.expiresAt.After(now))
+	})
+	if i < 0 {
+		return false, nil
+	}
+	for _, u := range db.users {
+		if u.ID == userID {
+			u.PasswordHash = passwordHash
+			db.recoveryCodes = slices.Delete(db.recoveryCodes, i, i+1)
+			return true, nil
+		}
+	}
+	return false, errors.New("user not found")
+}
+
+// --- ProfileRepository ---
+
+// CreateProfile creates a profile owned by ownerID. Profile IDs are drawn from
+// the user ID sequence so they never collide with an account's data.
+func (db *DB) CreateProfile(ctx context.Context, ownerID int64, name string) (*domain.Profile, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, p := range db.profiles {
+		if p.OwnerID == ownerID && p.Name == name {
+			return nil, errors.New("profile already exists")
+		}
+	}
+
+	db.userIDCounter++
+	p := domain.Profile{
+		ID:        db.userIDCounter,
+		OwnerID:   ownerID,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	db.profiles = append(db.profiles, p)
+	return &p, nil
+}
+
+// ListProfiles returns the profiles owned by ownerID, oldest first.
+func (db *DB) ListProfiles(ctx context.Context, ownerID int64) ([]domain.Profile, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.Profile{}
+	for _, p := range db.profiles {
+		if p.OwnerID == ownerID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// GetProfile returns the profile with the given ID if ownerID owns it.
+func (db *DB) GetProfile(ctx context.Context, ownerID, id int64) (*domain.Profile, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, p := range db.profiles {
+		if p.ID == id && p.OwnerID == ownerID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// --- ShareRepository ---
+
+// CreateShare grants viewerID read access to ownerID's data.
+func (db *DB) CreateShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, sh := range db.shares {
+		if sh.OwnerID == ownerID && sh.ViewerID == viewerID {
+			return db.withUsernames(sh), nil
+		}
+	}
+	sh := domain.Share{OwnerID: ownerID, ViewerID: viewerID, CreatedAt: time.Now().UTC()}
+	db.shares = append(db.shares, sh)
+	return db.withUsernames(sh), nil
+}
+
+// DeleteShare revokes a grant and reports whether one existed.
+func (db *DB) DeleteShare(ctx context.Context, ownerID, viewerID int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, sh := range db.shares {
+		if sh.OwnerID == ownerID && sh.ViewerID == viewerID {
+			db.shares = append(db.shares[:i], db.shares[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetShare returns the grant from ownerID to viewerID, or nil.
+func (db *DB) GetShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, sh := range db.shares {
+		if sh.OwnerID == ownerID && sh.ViewerID == viewerID {
+			return db.withUsernames(sh), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListShares returns grants where userID is the owner or the viewer.
+func (db *DB) ListShares(ctx context.Context, userID int64) ([]domain.Share, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.Share
+	for _, sh := range db.shares {
+		if sh.OwnerID == userID || sh.ViewerID == userID {
+			out = append(out, *db.withUsernames(sh))
+		}
+	}
+	return out, nil
+}
+
+// withUsernames fills in the usernames of a share. Callers must hold db.mu.
+func (db *DB) withUsernames(sh domain.Share) *domain.Share {
+	for _, u := range db.users {
+		switch u.ID {
+		case sh.OwnerID:
+			sh.OwnerUsername = u.Username
+		case sh.ViewerID:
+			sh.ViewerUsername = u.Username
+		}
+	}
+	return &sh
+}
+
+// --- APITokenRepository ---
+
+// CreateAPIToken stores a new API token hash.
+func (db *DB) CreateAPIToken(ctx context.Context, userID int64, name, scope, tokenHash string) (*domain.APIToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.tokenIDCounter++
+	t := domain.APIToken{
+		ID:        db.tokenIDCounter,
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		TokenHash: tokenHash,
+		CreatedAt: time.Now().UTC(),
+	}
+	db.apiTokens = append(db.apiTokens, t)
+	return &t, nil
+}
+
+// GetAPITokenByHash returns the token with the given hash, or nil.
+func (db *DB) GetAPITokenByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, t := range db.apiTokens {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListAPITokens returns a user's tokens, newest first.
+func (db *DB) ListAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.APIToken{}
+	for i := len(db.apiTokens) - 1; i >= 0; i-- {
+		if db.apiTokens[i].UserID == userID {
+			out = append(out, db.apiTokens[i])
+		}
+	}
+	return out, nil
+}
+
+// DeleteAPIToken removes one of a user's tokens and reports whether it existed.
+func (db *DB) DeleteAPIToken(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, t := range db.apiTokens {
+		if t.ID == id && t.UserID == userID {
+			db.apiTokens = append(db.apiTokens[:i], db.apiTokens[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteAPITokens revokes all of the user's tokens.
+func (db *DB) DeleteAPITokens(ctx context.Context, userID int64) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	before := len(db.apiTokens)
+	db.apiTokens = slices.DeleteFunc(db.apiTokens, func(t domain.APIToken) bool { return t.UserID == userID })
+	return before - len(db.apiTokens), nil
+}
+
+// TouchAPIToken records that the token with id was used at at.
+func (db *DB) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.apiTokens {
+		if db.apiTokens[i].ID == id {
+			at := at.UTC()
+			db.apiTokens[i].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+// --- AlertRuleRepository ---
+
+// GetAlertRule returns the user's alert rule, or nil.
+func (db *DB) GetAlertRule(ctx context.Context, userID int64) (*domain.AlertRule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.alertRules[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// SaveAlertRule creates or replaces the user's alert rule.
+func (db *DB) SaveAlertRule(ctx context.Context, rule domain.AlertRule) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.alertRules[rule.UserID] = rule
+	return nil
+}
+
+// ListAlertRules returns every enabled alert rule, ordered by user.
+func (db *DB) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.AlertRule
+	for _, r := range db.alertRules {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out, nil
+}
+
+// MarkAlerted records when the user was last alerted and queues the
+// notifications.
+func (db *DB) MarkAlerted(ctx context.Context, userID int64, at time.Time, queue ...domain.OutboxMessage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if r, ok := db.alertRules[userID]; ok {
+		at := at.UTC()
+		r.LastAlertedAt = &at
+		db.alertRules[userID] = r
+	}
+	db.enqueueOutboxLocked(queue)
+	return nil
+}
+
+// --- RuleRepository ---
+
+// CreateRule stores a new rule and returns it with its ID.
+func (db *DB) CreateRule(ctx context.Context, rule domain.Rule) (*domain.Rule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.ruleIDCounter++
+	rule.ID = db.ruleIDCounter
+	db.rules = append(db.rules, rule)
+	return &rule, nil
+}
+
+// GetRule returns the user's rule with id, or nil.
+func (db *DB) GetRule(ctx context.Context, userID, id int64) (*domain.Rule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, r := range db.rules {
+		if r.ID == id && r.UserID == userID {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListRules returns the user's rules, oldest first.
+func (db *DB) ListRules(ctx context.Context, userID int64) ([]domain.Rule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.Rule{}
+	for _, r := range db.rules {
+		if r.UserID == userID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// UpdateRule replaces a rule's definition, keeping its history.
+func (db *DB) UpdateRule(ctx context.Context, rule domain.Rule) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, r := range db.rules {
+		if r.ID == rule.ID && r.UserID == rule.UserID {
+			rule.LastFiredAt, rule.CreatedAt = r.LastFiredAt, r.CreatedAt
+			db.rules[i] = rule
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteRule removes one of a user's rules and reports whether it existed.
+func (db *DB) DeleteRule(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, r := range db.rules {
+		if r.ID == id && r.UserID == userID {
+			db.rules = append(db.rules[:i], db.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListEnabledRules returns every user's enabled rules.
+func (db *DB) ListEnabledRules(ctx context.Context) ([]domain.Rule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.Rule
+	for _, r := range db.rules {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// MarkRuleFired records when a rule last fired and queues the
+// notifications.
+func (db *DB) MarkRuleFired(ctx context.Context, userID, id int64, at time.Time, queue ...domain.OutboxMessage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, r := range db.rules {
+		if r.ID == id && r.UserID == userID {
+			at := at.UTC()
+			db.rules[i].LastFiredAt = &at
+		}
+	}
+	db.enqueueOutboxLocked(queue)
+	return nil
+}
+
+// --- PlanRepository ---
+
+// CreatePlannedEntry stores a new planned entry and returns it with its ID.
+func (db *DB) CreatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (*domain.PlannedEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.planIDCounter++
+	p.ID = db.planIDCounter
+	db.plans = append(db.plans, p)
+	return &p, nil
+}
+
+// GetPlannedEntry returns the user's planned entry with id, or nil.
+func (db *DB) GetPlannedEntry(ctx context.Context, userID, id int64) (*domain.PlannedEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, p := range db.plans {
+		if p.ID == id && p.UserID == userID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListPlannedEntries returns the user's entries for days from through to,
+// by day and time.
+func (db *DB) ListPlannedEntries(ctx context.Context, userID int64, from, to string) ([]domain.PlannedEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.PlannedEntry{}
+	for _, p := range db.plans {
+		if p.UserID == userID && p.Day >= from && p.Day <= to {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Day != out[j].Day {
+			return out[i].Day < out[j].Day
+		}
+		return out[i].At < out[j].At
+	})
+	return out, nil
+}
+
+// CountPlannedEntries returns how many entries the user has planned for
+// from or later.
+func (db *DB) CountPlannedEntries(ctx context.Context, userID int64, from string) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	for _, p := range db.plans {
+		if p.UserID == userID && p.Day >= from {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// UpdatePlannedEntry replaces a planned entry, re-arming its reminder.
+func (db *DB) UpdatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, old := range db.plans {
+		if old.ID == p.ID && old.UserID == p.UserID {
+			p.RemindedAt, p.CreatedAt = nil, old.CreatedAt
+			db.plans[i] = p
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeletePlannedEntry removes one of a user's planned entries and reports
+// whether it existed.
+func (db *DB) DeletePlannedEntry(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := len(db.plans)
+	db.plans = slices.DeleteFunc(db.plans, func(p domain.PlannedEntry) bool { return p.ID == id && p.UserID == userID })
+	return len(db.plans) < n, nil
+}
+
+// ListPlanReminders returns every user's pending reminders for day.
+func (db *DB) ListPlanReminders(ctx context.Context, day string) ([]domain.PlannedEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.PlannedEntry
+	for _, p := range db.plans {
+		if p.Day == day && p.Channel != "" && p.RemindedAt == nil {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// MarkPlanReminded records when a reminder was sent and queues the
+// notifications.
+func (db *DB) MarkPlanReminded(ctx context.Context, userID, id int64, at time.Time, queue ...domain.OutboxMessage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, p := range db.plans {
+		if p.ID == id && p.UserID == userID {
+			at := at.UTC()
+			db.plans[i].RemindedAt = &at
+		}
+	}
+	db.enqueueOutboxLocked(queue)
+	return nil
+}
+
+// --- OutboxRepository ---
+
+// EnqueueOutbox stores msgs, due at once.
+func (db *DB) EnqueueOutbox(ctx context.Context, msgs ...domain.OutboxMessage) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.enqueueOutboxLocked(msgs)
+	return nil
+}
+
+func (db *DB) enqueueOutboxLocked(msgs []domain.OutboxMessage) {
+	now := time.Now().UTC()
+	for _, m := range msgs {
+		db.outboxIDCounter++
+		m.ID = db.outboxIDCounter
+		m.Attempts, m.LastError = 0, ""
+		m.CreatedAt, m.NextAttemptAt = now, &now
+		db.outbox = append(db.outbox, m)
+	}
+}
+
+// ClaimOutbox returns up to limit due messages, leasing them until until.
+func (db *DB) ClaimOutbox(ctx context.Context, now, until time.Time, limit int) ([]domain.OutboxMessage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.OutboxMessage{}
+	until = until.UTC()
+	for i, m := range db.outbox {
+		if len(out) == limit {
+			break
+		}
+		if m.NextAttemptAt == nil || m.NextAttemptAt.After(now) {
+			continue
+		}
+		db.outbox[i].NextAttemptAt = &until
+		out = append(out, db.outbox[i])
+	}
+	return out, nil
+}
+
+// DeleteOutbox removes a delivered message.
+func (db *DB) DeleteOutbox(ctx context.Context, id int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.outbox = slices.DeleteFunc(db.outbox, func(m domain.OutboxMessage) bool { return m.ID == id })
+	return nil
+}
+
+// FailOutbox records a failed delivery and schedules the next one.
+func (db *DB) FailOutbox(ctx context.Context, id int64, lastErr string, next *time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, m := range db.outbox {
+		if m.ID == id {
+			m.Attempts++
+			m.LastError = lastErr
+			m.NextAttemptAt = nil
+			if next != nil {
+				at := next.UTC()
+				m.NextAttemptAt = &at
+			}
+			db.outbox[i] = m
+		}
+	}
+	return nil
+}
+
+// --- WebhookRepository ---
+
+// CreateWebhook stores a new webhook and returns it with its ID.
+func (db *DB) CreateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.webhookIDCounter++
+	h.ID = db.webhookIDCounter
+	h.Events = slices.Clone(h.Events)
+	db.webhooks = append(db.webhooks, h)
+	return &h, nil
+}
+
+// GetWebhook returns the user's webhook with id, or nil.
+func (db *DB) GetWebhook(ctx context.Context, userID, id int64) (*domain.Webhook, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, h := range db.webhooks {
+		if h.ID == id && h.UserID == userID {
+			h.Events = slices.Clone(h.Events)
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first.
+func (db *DB) ListWebhooks(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.Webhook{}
+	for _, h := range db.webhooks {
+		if h.UserID == userID {
+			h.Events = slices.Clone(h.Events)
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+// UpdateWebhook replaces a webhook's URL, events and enabled flag.
+func (db *DB) UpdateWebhook(ctx context.Context, h domain.Webhook) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, old := range db.webhooks {
+		if old.ID == h.ID && old.UserID == h.UserID {
+			db.webhooks[i].URL = h.URL
+			db.webhooks[i].Events = slices.Clone(h.Events)
+			db.webhooks[i].Enabled = h.Enabled
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries.
+func (db *DB) DeleteWebhook(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := len(db.webhooks)
+	db.webhooks = slices.DeleteFunc(db.webhooks, func(h domain.Webhook) bool { return h.ID == id && h.UserID == userID })
+	if len(db.webhooks) == n {
+		return false, nil
+	}
+	db.webhookDeliveries = slices.DeleteFunc(db.webhookDeliveries, func(d domain.WebhookDelivery) bool { return d.WebhookID == id })
+	return true, nil
+}
+
+// EnqueueWebhookDeliveries stores ds, due at once.
+func (db *DB) EnqueueWebhookDeliveries(ctx context.Context, ds ...domain.WebhookDelivery) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, d := range ds {
+		db.deliveryIDCounter++
+		d.ID = db.deliveryIDCounter
+		d.Attempts, d.StatusCode, d.LastError = 0, 0, ""
+		d.CreatedAt, d.DeliveredAt, d.NextAttemptAt = now, nil, &now
+		db.webhookDeliveries = append(db.webhookDeliveries, d)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit due deliveries, leasing them
+// until until.
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.WebhookDelivery{}
+	until = until.UTC()
+	for i, d := range db.webhookDeliveries {
+		if len(out) == limit {
+			break
+		}
+		if d.NextAttemptAt == nil || d.NextAttemptAt.After(now) {
+			continue
+		}
+		db.webhookDeliveries[i].NextAttemptAt = &until
+		out = append(out, db.webhookDeliveries[i])
+	}
+	return out, nil
+}
+
+// CompleteWebhookDelivery records a successful attempt.
+func (db *DB) CompleteWebhookDelivery(ctx context.Context, id int64, status int, at time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, d := range db.webhookDeliveries {
+		if d.ID == id {
+			at := at.UTC()
+			d.Attempts++
+			d.StatusCode, d.LastError = status, ""
+			d.DeliveredAt, d.NextAttemptAt = &at, nil
+			db.webhookDeliveries[i] = d
+		}
+	}
+	return nil
+}
+
+// FailWebhookDelivery records a failed attempt and schedules the next one.
+func (db *DB) FailWebhookDelivery(ctx context.Context, id int64, status int, lastErr string, next *time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, d := range db.webhookDeliveries {
+		if d.ID == id {
+			d.Attempts++
+			d.StatusCode, d.LastError = status, lastErr
+			d.NextAttemptAt = nil
+			if next != nil {
+				at := next.UTC()
+				d.NextAttemptAt = &at
+			}
+			db.webhookDeliveries[i] = d
+		}
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the webhook's latest deliveries, newest
+// first.
+func (db *DB) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]domain.WebhookDelivery, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.WebhookDelivery{}
+	for i := len(db.webhookDeliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if d := db.webhookDeliveries[i]; d.WebhookID == webhookID && d.UserID == userID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// PruneWebhookDeliveries removes settled deliveries created before before.
+func (db *DB) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := len(db.webhookDeliveries)
+	db.webhookDeliveries = slices.DeleteFunc(db.webhookDeliveries, func(d domain.WebhookDelivery) bool {
+		return d.NextAttemptAt == nil && d.CreatedAt.Before(before)
+	})
+	return int64(n - len(db.webhookDeliveries)), nil
+}
+
+// --- MedicationRepository ---
+
+// CreateMedication stores a new medication and returns it with its ID.
+func (db *DB) CreateMedication(ctx context.Context, m domain.Medication) (*domain.Medication, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.medIDCounter++
+	m.ID = db.medIDCounter
+	m.Schedule = slices.Clone(m.Schedule)
+	db.meds = append(db.meds, m)
+	return &m, nil
+}
+
+// GetMedication returns the user's medication with id, or nil.
+func (db *DB) GetMedication(ctx context.Context, userID, id int64) (*domain.Medication, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, m := range db.meds {
+		if m.ID == id && m.UserID == userID {
+			m.Schedule = slices.Clone(m.Schedule)
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListMedications returns the user's medications, oldest first.
+func (db *DB) ListMedications(ctx context.Context, userID int64) ([]domain.Medication, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.Medication{}
+	for _, m := range db.meds {
+		if m.UserID == userID {
+			m.Schedule = slices.Clone(m.Schedule)
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// UpdateMedication replaces a medication's name, dose and schedule.
+func (db *DB) UpdateMedication(ctx context.Context, m domain.Medication) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, old := range db.meds {
+		if old.ID == m.ID && old.UserID == m.UserID {
+			m.CreatedAt = old.CreatedAt
+			m.Schedule = slices.Clone(m.Schedule)
+			db.meds[i] = m
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteMedication removes one of a user's medications and its events.
+func (db *DB) DeleteMedication(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, m := range db.meds {
+		if m.ID == id && m.UserID == userID {
+			db.meds = append(db.meds[:i], db.meds[i+1:]...)
+			db.medEvents = slices.DeleteFunc(db.medEvents, func(e domain.MedicationEvent) bool {
+				return e.MedicationID == id
+			})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddMedicationEvent stores a taken or skipped dose, deduplicated by client ID.
+func (db *DB) AddMedicationEvent(ctx context.Context, userID int64, e domain.MedicationEvent) (*domain.MedicationEvent, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if e.ClientID != "" {
+		for _, existing := range db.medEvents {
+			if existing.UserID == userID && existing.ClientID == e.ClientID {
+				return &existing, false, nil
+			}
+		}
+	}
+	db.medEventCounter++
+	e.ID = db.medEventCounter
+	e.UserID = userID
+	db.medEvents = append(db.medEvents, e)
+	return &e, true, nil
+}
+
+// ListRecentMedicationEvents returns the user's latest events, newest first.
+func (db *DB) ListRecentMedicationEvents(ctx context.Context, userID, medicationID int64, limit int) ([]domain.MedicationEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.MedicationEvent{}
+	for _, e := range db.medEvents {
+		if e.UserID == userID && (medicationID == 0 || e.MedicationID == medicationID) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// --- TemperatureRepository ---
+
+// AddTemperatureReading stores a body temperature, deduplicated by client ID.
+func (db *DB) AddTemperatureReading(ctx context.Context, userID int64, r domain.TemperatureReading) (*domain.TemperatureReading, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if r.ClientID != "" {
+		for _, existing := range db.temps {
+			if existing.UserID == userID && existing.ClientID == r.ClientID {
+				return &existing, false, nil
+			}
+		}
+	}
+	db.tempIDCounter++
+	r.ID = db.tempIDCounter
+	r.UserID = userID
+	db.temps = append(db.temps, r)
+	return &r, true, nil
+}
+
+// ListRecentTemperatureReadings returns the user's latest readings, newest
+// first.
+func (db *DB) ListRecentTemperatureReadings(ctx context.Context, userID int64, limit int) ([]domain.TemperatureReading, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.TemperatureReading{}
+	for _, r := range db.temps {
+		if r.UserID == userID {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// DeleteTemperatureReading removes one of the user's readings.
+func (db *DB) DeleteTemperatureReading(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, r := range db.temps {
+		if r.UserID == userID && r.ID == id {
+			db.temps = append(db.temps[:i], db.temps[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// --- CustomMetricRepository ---
+
+// CreateCustomMetric stores a new metric definition and returns it with its
+// ID.
+func (db *DB) CreateCustomMetric(ctx context.Context, m domain.CustomMetric) (*domain.CustomMetric, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, existing := range db.metrics {
+		if existing.UserID == m.UserID && existing.Slug == m.Slug {
+			return nil, fmt.Errorf("metric %q already exists", m.Slug)
+		}
+	}
+	db.metricIDCounter++
+	m.ID = db.metricIDCounter
+	db.metrics = append(db.metrics, m)
+	return &m, nil
+}
+
+// GetCustomMetric returns the user's metric with slug, or nil.
+func (db *DB) GetCustomMetric(ctx context.Context, userID int64, slug string) (*domain.CustomMetric, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, m := range db.metrics {
+		if m.UserID == userID && m.Slug == slug {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListCustomMetrics returns the user's metrics, oldest first.
+func (db *DB) ListCustomMetrics(ctx context.Context, userID int64) ([]domain.CustomMetric, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.CustomMetric{}
+	for _, m := range db.metrics {
+		if m.UserID == userID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// DeleteCustomMetric removes one of a user's metrics and its events.
+func (db *DB) DeleteCustomMetric(ctx context.Context, userID, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, m := range db.metrics {
+		if m.UserID == userID && m.ID == id {
+			db.metrics = append(db.metrics[:i], db.metrics[i+1:]...)
+			db.metricEvents = slices.DeleteFunc(db.metricEvents, func(e domain.CustomMetricEvent) bool {
+				return e.MetricID == id
+			})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddCustomMetricEvent stores a metric value, deduplicated by client ID.
+func (db *DB) AddCustomMetricEvent(ctx context.Context, userID int64, e domain.CustomMetricEvent) (*domain.CustomMetricEvent, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if e.ClientID != "" {
+		for _, existing := range db.metricEvents {
+			if existing.UserID == userID && existing.ClientID == e.ClientID {
+				return &existing, false, nil
+			}
+		}
+	}
+	db.metricEventCounter++
+	e.ID = db.metricEventCounter
+	e.UserID = userID
+	db.metricEvents = append(db.metricEvents, e)
+	return &e, true, nil
+}
+
+// ListRecentCustomMetricEvents returns the metric's latest events, newest
+// first.
+func (db *DB) ListRecentCustomMetricEvents(ctx context.Context, userID, metricID int64, limit int) ([]domain.CustomMetricEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.CustomMetricEvent{}
+	for _, e := range db.metricEvents {
+		if e.UserID == userID && e.MetricID == metricID {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return newer(out[i].CreatedAt, out[i].ID, out[j].CreatedAt, out[j].ID)
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// ListCustomMetricEventsBetween returns the metric's events in [from, to),
+// oldest first.
+func (db *DB) ListCustomMetricEventsBetween(ctx context.Context, userID, metricID int64, from, to time.Time) ([]domain.CustomMetricEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.CustomMetricEvent{}
+	for _, e := range db.metricEvents {
+		if e.UserID == userID && e.MetricID == metricID && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return newer(out[j].CreatedAt, out[j].ID, out[i].CreatedAt, out[i].ID)
+	})
+	return out, nil
+}
+
+// --- HydrationSettingsRepository ---
+
+// GetHydrationSettings returns the user's hydration settings, or nil.
+func (db *DB) GetHydrationSettings(ctx context.Context, userID int64) (*domain.HydrationSettings, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	hs, ok := db.hydration[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &hs, nil
+}
+
+// SaveHydrationSettings creates or replaces the user's hydration settings.
+func (db *DB) SaveHydrationSettings(ctx context.Context, hs domain.HydrationSettings) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.hydration[hs.UserID] = hs
+	return nil
+}
+
+// --- GoalHistoryRepository ---
+
+// RecordGoal sets the goal in effect from day.
+func (db *DB) RecordGoal(ctx context.Context, userID int64, day string, liters float64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	h := db.goals[userID]
+	i := sort.Search(len(h), func(i int) bool { return h[i].Day >= day })
+	if i < len(h) && h[i].Day == day {
+		h[i].Liters = liters
+		return nil
+	}
+	h = append(h, domain.GoalChange{})
+	copy(h[i+1:], h[i:])
+	h[i] = domain.GoalChange{Day: day, Liters: liters}
+	db.goals[userID] = h
+	return nil
+}
+
+// GoalHistory returns a copy of the user's goal changes, oldest first.
+func (db *DB) GoalHistory(ctx context.Context, userID int64) (domain.GoalHistory, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append(domain.GoalHistory(nil), db.goals[userID]...), nil
+}
+
+// --- WeightGoalRepository ---
+
+// RecordWeightGoal sets the target weight in effect from day.
+func (db *DB) RecordWeightGoal(ctx context.Context, userID int64, day string, targetKg *float64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if targetKg != nil {
+		kg := *targetKg
+		targetKg = &kg
+	}
+	h := db.weightGoals[userID]
+	i := sort.Search(len(h), func(i int) bool { return h[i].Day >= day })
+	if i < len(h) && h[i].Day == day {
+		h[i].TargetKg = targetKg
+		return nil
+	}
+	h = append(h, domain.WeightGoalChange{})
+	copy(h[i+1:], h[i:])
+	h[i] = domain.WeightGoalChange{Day: day, TargetKg: targetKg}
+	db.weightGoals[userID] = h
+	return nil
+}
+
+// WeightGoalHistory returns a copy of the user's target weight changes,
+// oldest first.
+func (db *DB) WeightGoalHistory(ctx context.Context, userID int64) (domain.WeightGoalHistory, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append(domain.WeightGoalHistory(nil), db.weightGoals[userID]...), nil
+}
+
+// --- RetentionRepository ---
+
+// WaterUsersBefore returns the users with water events created before t.
+func (db *DB) WaterUsersBefore(ctx context.Context, t time.Time) ([]int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []int64
+	for _, w := range db.waterEvents {
+		if w.CreatedAt.Before(t) && !slices.Contains(out, w.UserID) {
+			out = append(out, w.UserID)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// ListWaterEventsBefore returns the user's water events created before t,
+// oldest first.
+func (db *DB) ListWaterEventsBefore(ctx context.Context, userID int64, t time.Time) ([]domain.WaterEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.WaterEvent
+	for _, w := range db.waterEvents {
+		if w.UserID == userID && w.CreatedAt.Before(t) {
+			out = append(out, w)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// CollapseWaterEvents sets the volume of the user's event keepID to the
+// rounded total of it and removeIDs, detaches it from its import batch,
+// and deletes removeIDs. It returns false, changing nothing, if any of the
+// events is gone.
+func (db *DB) CollapseWaterEvents(ctx context.Context, userID, keepID int64, removeIDs []int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	want := make(map[int64]bool, len(removeIDs)+1)
+	want[keepID] = true
+	for _, id := range removeIDs {
+		want[id] = true
+	}
+	keep, found, total := -1, 0, 0.0
+	for i, w := range db.waterEvents {
+		if w.UserID == userID && want[w.ID] {
+			found++
+			total += w.DeltaLiters
+			if w.ID == keepID {
+				keep = i
+			}
+		}
+	}
+	if found != len(want) {
+		return false, nil
+	}
+	db.waterEvents[keep].DeltaLiters = domain.RoundLiters(total)
+	delete(db.imports, tagKey{domain.ChangeEntityWater, keepID})
+	db.logChange(userID, domain.ChangeEntityWater, keepID, domain.ChangeOpUpsert)
+	for _, id := range removeIDs {
+		db.deleteWater(userID, id)
+	}
+	return true, nil
+}
+
+// --- ActivityRepository ---
+
+// ListActivity returns up to limit of the user's activity items after
+// before, newest first.
+func (db *DB) ListActivity(ctx context.Context, userID int64, before *domain.ActivityCursor, limit int) ([]domain.Activity, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var items []domain.Activity
+	imports := make(map[string]*domain.ImportActivity)
+	importAt := make(map[string]time.Time)
+	imported := func(entity string, id int64) bool {
+		batch, ok := db.imports[tagKey{entity, id}]
+		if !ok {
+			return false
+		}
+		imp := imports[batch]
+		if imp == nil {
+			imp = &domain.ImportActivity{Batch: batch}
+			imports[batch] = imp
+		}
+		if entity == domain.ChangeEntityWeight {
+			imp.Weights++
+		} else {
+			imp.Waters++
+		}
+		// The import ran when its first entry was logged.
+		for _, c := range db.changes {
+			if c.Entity == entity && c.EntityID == id {
+				if at, ok := importAt[batch]; !ok || c.ChangedAt.Before(at) {
+					importAt[batch] = c.ChangedAt
+				}
+				break
+			}
+		}
+		return true
+	}
+	for _, w := range db.weights {
+		if w.UserID != userID || imported(domain.ChangeEntityWeight, w.ID) {
+			continue
+		}
+		w.Day = w.CreatedAt.In(time.Local).Format("2006-01-02")
+		w.Tags = nil
+		items = append(items, domain.Activity{Type: domain.ActivityWeight, At: w.CreatedAt, ID: fmt.Sprint(w.ID), Weight: &w})
+	}
+	for _, e := range db.waterEvents {
+		if e.UserID != userID || imported(domain.ChangeEntityWater, e.ID) {
+			continue
+		}
+		e.Tags = nil
+		items = append(items, domain.Activity{Type: domain.ActivityWater, At: e.CreatedAt, ID: fmt.Sprint(e.ID), Water: &e})
+	}
+	for batch, imp := range imports {
+		items = append(items, domain.Activity{Type: domain.ActivityImport, At: importAt[batch], ID: batch, Import: imp})
+	}
+	for _, c := range db.goals[userID] {
+		at, err := time.Parse("2006-01-02", c.Day)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, domain.Activity{Type: domain.ActivityWaterGoal, At: at, ID: c.Day, WaterGoal: &c})
+	}
+	for _, c := range db.weightGoals[userID] {
+		at, err := time.Parse("2006-01-02", c.Day)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, domain.Activity{Type: domain.ActivityWeightGoal, At: at, ID: c.Day, WeightGoal: &c})
+	}
+
+	if before != nil {
+		items = slices.DeleteFunc(items, func(a domain.Activity) bool { return !before.Admits(a) })
+	}
+	slices.SortFunc(items, func(a, b domain.Activity) int {
+		if c := b.At.Compare(a.At); c != 0 {
+			return c
+		}
+		if a.Type != b.Type {
+			return strings.Compare(b.Type, a.Type)
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// --- OAuthTokenRepository ---
+
+// SaveOAuthToken creates or replaces the user's token for t.Provider.
+func (db *DB) SaveOAuthToken(ctx context.Context, t domain.OAuthToken) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.oauthTokens[oauthKey{t.UserID, t.Provider}] = t
+	return nil
+}
+
+// GetOAuthToken returns a copy of the user's token for provider, or nil.
+func (db *DB) GetOAuthToken(ctx context.Context, userID int64, provider string) (*domain.OAuthToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, ok := db.oauthTokens[oauthKey{userID, provider}]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// DeleteOAuthToken removes the user's token for provider.
+func (db *DB) DeleteOAuthToken(ctx context.Context, userID int64, provider string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := oauthKey{userID, provider}
+	_, ok := db.oauthTokens[key]
+	delete(db.oauthTokens, key)
+	return ok, nil
+}
+
+// ListOAuthTokens returns every user's token for provider, ordered by user.
+func (db *DB) ListOAuthTokens(ctx context.Context, provider string) ([]domain.OAuthToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var out []domain.OAuthToken
+	for k, t := range db.oauthTokens {
+		if k.provider == provider {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out, nil
+}
+
+// --- SettingsRepository ---
+
+// GetSettings returns a copy of the user's settings.
+func (db *DB) GetSettings(ctx context.Context, userID int64) (domain.UserSettings, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := domain.UserSettings{}
+	for k, v := range db.settings[userID] {
+		out[k] = slices.Clone(v)
+	}
+	return out, nil
+}
+
+// UpdateSettings stores set and deletes the keys in remove.
+func (db *DB) UpdateSettings(ctx context.Context, userID int64, set domain.UserSettings, remove []string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	settings := db.settings[userID]
+	if settings == nil {
+		settings = domain.UserSettings{}
+		db.settings[userID] = settings
+	}
+	for k, v := range set {
+		settings[k] = slices.Clone(v)
+	}
+	for _, k := range remove {
+		delete(settings, k)
+	}
+	return nil
+}
+
+// --- TagRepository ---
+
+// SetEntryTags replaces the tags on one of the user's entries.
+func (db *DB) SetEntryTags(ctx context.Context, userID int64, entity string, entryID int64, tags []string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !db.ownsEntry(userID, entity, entryID) {
+		return false, nil
+	}
+	key := tagKey{entity, entryID}
+	if len(tags) == 0 {
+		delete(db.tags, key)
+	} else {
+		db.tags[key] = slices.Clone(tags)
+	}
+	return true, nil
+}
+
+// ownsEntry reports whether the user has an entry of entity with id. Callers
+// must hold db.mu.
+func (db *DB) ownsEntry(userID int64, entity string, id int64) bool {
+	switch entity {
+	case domain.ChangeEntityWeight:
+		return slices.ContainsFunc(db.weights, func(w domain.WeightEntry) bool { return w.ID == id && w.UserID == userID })
+	case domain.ChangeEntityWater:
+		return slices.ContainsFunc(db.waterEvents, func(w domain.WaterEvent) bool { return w.ID == id && w.UserID == userID })
+	}
+	return false
+}
+
+// EntryTags returns the tags of the given entries, keyed by entry ID.
+func (db *DB) EntryTags(ctx context.Context, userID int64, entity string, ids []int64) (map[int64][]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := map[int64][]string{}
+	for _, id := range ids {
+		if tags, ok := db.tags[tagKey{entity, id}]; ok && db.ownsEntry(userID, entity, id) {
+			out[id] = slices.Clone(tags)
+		}
+	}
+	return out, nil
+}
+
+// ListTags returns the user's tags with their use counts, by tag.
+func (db *DB) ListTags(ctx context.Context, userID int64) ([]domain.TagCount, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	counts := map[string]int{}
+	for key, tags := range db.tags {
+		if !db.ownsEntry(userID, key.entity, key.id) {
+			continue
+		}
+		for _, t := range tags {
+			counts[t]++
+		}
+	}
+	out := make([]domain.TagCount, 0, len(counts))
+	for t, n := range counts {
+		out = append(out, domain.TagCount{Tag: t, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out, nil
+}
+
+// --- JournalRepository ---
+
+// GetJournalEntry returns the note for day, or nil.
+func (db *DB) GetJournalEntry(ctx context.Context, userID int64, day string) (*domain.JournalEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	e, ok := db.journal[userID][day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// SaveJournalEntry creates or replaces the note for e.Day.
+func (db *DB) SaveJournalEntry(ctx context.Context, userID int64, e domain.JournalEntry) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.journal[userID] == nil {
+		db.journal[userID] = make(map[string]domain.JournalEntry)
+	}
+	db.journal[userID][e.Day] = e
+	return nil
+}
+
+// DeleteJournalEntry removes the note for day.
+func (db *DB) DeleteJournalEntry(ctx context.Context, userID int64, day string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.journal[userID][day]; !ok {
+		return false, nil
+	}
+	delete(db.journal[userID], day)
+	return true, nil
+}
+
+// ListJournalEntries returns the notes for days from through to, oldest
+// first.
+func (db *DB) ListJournalEntries(ctx context.Context, userID int64, from, to string) ([]domain.JournalEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.JournalEntry{}
+	for day, e := range db.journal[userID] {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day < out[j].Day })
+	return out, nil
+}
+
+// --- MoodRepository ---
+
+// GetMoodEntry returns the mood for day, or nil.
+func (db *DB) GetMoodEntry(ctx context.Context, userID int64, day string) (*domain.MoodEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	e, ok := db.mood[userID][day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// SaveMoodEntry creates or replaces the mood for e.Day.
+func (db *DB) SaveMoodEntry(ctx context.Context, userID int64, e domain.MoodEntry) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.mood[userID] == nil {
+		db.mood[userID] = make(map[string]domain.MoodEntry)
+	}
+	db.mood[userID][e.Day] = e
+	return nil
+}
+
+// ListMoodEntries returns the moods for days from through to, oldest first.
+func (db *DB) ListMoodEntries(ctx context.Context, userID int64, from, to string) ([]domain.MoodEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.MoodEntry{}
+	for day, e := range db.mood[userID] {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day < out[j].Day })
+	return out, nil
+}
+
+// ListRecentMoodEntries returns the user's latest moods, newest day first.
+func (db *DB) ListRecentMoodEntries(ctx context.Context, userID int64, limit int) ([]domain.MoodEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.MoodEntry{}
+	for _, e := range db.mood[userID] {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day > out[j].Day })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// --- StepsRepository ---
+
+// GetStepsEntry returns the step count for day, or nil.
+func (db *DB) GetStepsEntry(ctx context.Context, userID int64, day string) (*domain.StepsEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	e, ok := db.steps[userID][day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// SaveStepsEntry creates or replaces the step count for e.Day.
+func (db *DB) SaveStepsEntry(ctx context.Context, userID int64, e domain.StepsEntry) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.steps[userID] == nil {
+		db.steps[userID] = make(map[string]domain.StepsEntry)
+	}
+	db.steps[userID][e.Day] = e
+	return nil
+}
+
+// ListStepsEntries returns the step counts for days from through to, oldest
+// first.
+func (db *DB) ListStepsEntries(ctx context.Context, userID int64, from, to string) ([]domain.StepsEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.StepsEntry{}
+	for day, e := range db.steps[userID] {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day < out[j].Day })
+	return out, nil
+}
+
+// --- WeeklySummaryRepository ---
+
+// SaveWeeklySummaries creates or replaces the user's summaries.
+func (db *DB) SaveWeeklySummaries(ctx context.Context, userID int64, sums []domain.WeeklySummary) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.summaries[userID] == nil {
+		db.summaries[userID] = make(map[string]domain.WeeklySummary)
+	}
+	for _, sum := range sums {
+		db.summaries[userID][sum.WeekStart] = sum
+	}
+	return nil
+}
+
+// ListWeeklySummaries returns the user's summaries for weeks starting on or
+// after from, newest first.
+func (db *DB) ListWeeklySummaries(ctx context.Context, userID int64, from string) ([]domain.WeeklySummary, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	out := []domain.WeeklySummary{}
+	for start, sum := range db.summaries[userID] {
+		if start >= from {
+			out = append(out, sum)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].WeekStart > out[j].WeekStart })
+	return out, nil
+}
+
+// ListActiveUserIDs returns the users with changes since the given time.
+func (db *DB) ListActiveUserIDs(ctx context.Context, since time.Time) ([]int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	seen := map[int64]bool{}
+	var out []int64
+	for _, c := range db.changes {
+		if !c.ChangedAt.Before(since) && !seen[c.userID] {
+			seen[c.userID] = true
+			out = append(out, c.userID)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// --- SessionRepository ---
+
+// SessionRepo implements session persistence.
+type SessionRepo struct {
+	db *DB
+}
+
+// NewSessionRepo creates a new session repository.
+func (db *DB) NewSessionRepo() *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+// Create creates a new session.
+func (r *SessionRepo) Create(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.sessionIDCounter++
+	now := time.Now().UTC()
+	r.db.sessions[token] = &domain.Session{
+		ID:         r.db.sessionIDCounter,
+		Token:      token,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	return nil
+}
+
+// GetByToken retrieves a session by token.
+func (r *SessionRepo) GetByToken(ctx context.Context, token string) (*domain.Session, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if s, ok := r.db.sessions[token]; ok {
+		if time.Now().After(s.ExpiresAt) {
+			delete(r.db.sessions, token)
+			return nil, nil
+		}
+		cp := *s
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+// Delete deletes a session.
+func (r *SessionRepo) Delete(ctx context.Context, token string) error {
 	r.db.mu.Lock()
 	defer r.db.mu.Unlock()
 	delete(r.db.sessions, token)
@@ -354,3 +3024,233 @@ func (r *SessionRepo) DeleteExpired(ctx context.Context) error {
 	}
 	return nil
 }
+
+// ListByUser returns the user's sessions, most recently seen first.
+func (r *SessionRepo) ListByUser(ctx context.Context, userID int64) ([]domain.Session, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var out []domain.Session
+	for _, s := range r.db.sessions {
+		if s.UserID == userID {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastSeenAt.Equal(out[j].LastSeenAt) {
+			return out[i].LastSeenAt.After(out[j].LastSeenAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// Rename sets the name of one of the user's sessions.
+func (r *SessionRepo) Rename(ctx context.Context, userID, id int64, name string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, s := range r.db.sessions {
+		if s.UserID == userID && s.ID == id {
+			s.Name = name
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteByID revokes one of the user's sessions.
+func (r *SessionRepo) DeleteByID(ctx context.Context, userID, id int64) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for k, s := range r.db.sessions {
+		if s.UserID == userID && s.ID == id {
+			delete(r.db.sessions, k)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteByUser revokes all of the user's sessions.
+func (r *SessionRepo) DeleteByUser(ctx context.Context, userID int64) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n := 0
+	for k, s := range r.db.sessions {
+		if s.UserID == userID {
+			delete(r.db.sessions, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Touch records activity on a session.
+func (r *SessionRepo) Touch(ctx context.Context, token string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if s, ok := r.db.sessions[token]; ok {
+		s.LastSeenAt = at.UTC()
+	}
+	return nil
+}
+
+// --- MaintenanceRepository ---
+
+// CountExpiredSessions returns the number of sessions past their expiry.
+func (db *DB) CountExpiredSessions(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now()
+	var n int64
+	for _, s := range db.sessions {
+		if now.After(s.ExpiresAt) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// oldChange reports whether db.changes[i] was recorded before before and a
+// full sync no longer needs it: a deletion, or a change superseded by a
+// later one. Callers must hold db.mu.
+func (db *DB) oldChange(i int, before time.Time) bool {
+	c := db.changes[i]
+	if !c.ChangedAt.Before(before) {
+		return false
+	}
+	if c.Op == domain.ChangeOpDelete {
+		return true
+	}
+	for _, n := range db.changes[i+1:] {
+		if n.userID == c.userID && n.Entity == c.Entity && n.EntityID == c.EntityID {
+			return true
+		}
+	}
+	return false
+}
+
+// CountOldChanges counts the change log entries PurgeOldChanges would remove.
+func (db *DB) CountOldChanges(ctx context.Context, before time.Time) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var n int64
+	for i := range db.changes {
+		if db.oldChange(i, before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// PurgeOldChanges deletes the change log entries before before that a full
+// sync no longer needs and moves each user's purge horizon past the
+// deletions removed.
+func (db *DB) PurgeOldChanges(ctx context.Context, before time.Time) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	kept := db.changes[:0:0]
+	for i, c := range db.changes {
+		if !db.oldChange(i, before) {
+			kept = append(kept, c)
+			continue
+		}
+		if c.Op == domain.ChangeOpDelete && c.Seq > db.changesPurged[c.userID] {
+			db.changesPurged[c.userID] = c.Seq
+		}
+	}
+	n := int64(len(db.changes) - len(kept))
+	db.changes = kept
+	return n, nil
+}
+
+// PurgeExpiredSessions deletes expired sessions and returns how many were removed.
+func (db *DB) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now()
+	var n int64
+	for k, s := range db.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(db.sessions, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CountOrphanedEvents counts events whose user no longer exists.
+func (db *DB) CountOrphanedEvents(ctx context.Context) (domain.OrphanCounts, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	known := make(map[int64]bool, len(db.users)+len(db.profiles))
+	for _, u := range db.users {
+		known[u.ID] = true
+	}
+	for _, p := range db.profiles {
+		known[p.ID] = true
+	}
+	var c domain.OrphanCounts
+	for _, w := range db.weights {
+		if !known[w.UserID] {
+			c.WeightEvents++
+		}
+	}
+	for _, w := range db.waterEvents {
+		if !known[w.UserID] {
+			c.WaterEvents++
+		}
+	}
+	return c, nil
+}
+
+// Vacuum is a no-op for the in-memory store.
+func (db *DB) Vacuum(ctx context.Context) error {
+	return nil
+}
+
+// --- UsageRepository ---
+
+// WeeklyUsage counts weight and water events and the users logging them
+// per week since the given time.
+func (db *DB) WeeklyUsage(ctx context.Context, since time.Time) ([]domain.UsageWeek, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	type week struct {
+		users   map[int64]bool
+		entries int
+	}
+	weeks := map[time.Time]*week{}
+	count := func(userID int64, at time.Time) {
+		if at.Before(since) {
+			return
+		}
+		start := domain.UsageWeekOf(at)
+		w, ok := weeks[start]
+		if !ok {
+			w = &week{users: map[int64]bool{}}
+			weeks[start] = w
+		}
+		w.users[userID] = true
+		w.entries++
+	}
+	for _, e := range db.weights {
+		count(e.UserID, e.CreatedAt)
+	}
+	for _, e := range db.waterEvents {
+		count(e.UserID, e.CreatedAt)
+	}
+
+	out := make([]domain.UsageWeek, 0, len(weeks))
+	for start, w := range weeks {
+		out = append(out, domain.UsageWeek{WeekStart: start.Format("2006-01-02"), ActiveUsers: len(w.users), Entries: w.entries})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].WeekStart < out[j].WeekStart })
+	return out, nil
+}
@@ -2,8 +2,14 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sync"
 	"testing"
 	"time"
+
+	"vitals/internal/domain"
+	"vitals/internal/testdoubles"
 )
 
 func TestWeightRepository(t *testing.T) {
@@ -67,6 +73,32 @@ func TestWeightRepository(t *testing.T) {
 	if len(events) != 0 {
 		t.Error("expected 0 events")
 	}
+
+	// Average for day, in kg across units
+	if avg, _ := db.AverageWeightForLocalDay(ctx, userID, localDay); avg != nil {
+		t.Errorf("expected no average without weigh-ins, got %+v", avg)
+	}
+	_, _ = db.AddWeightEvent(ctx, userID, 70.0, "kg", now)
+	_, _ = db.AddWeightEvent(ctx, userID, domain.ConvertWeight(72, "kg", "lb"), "lb", now)
+	avg, err := db.AverageWeightForLocalDay(ctx, userID, localDay)
+	if err != nil {
+		t.Fatalf("AverageWeightForLocalDay: %v", err)
+	}
+	if avg == nil || avg.Unit != "kg" || math.Abs(avg.Value-71) > 1e-9 {
+		t.Errorf("expected 71 kg average, got %+v", avg)
+	}
+
+	// Span since a point in time
+	span, err := db.WeightSpanSince(ctx, userID, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("WeightSpanSince: %v", err)
+	}
+	if span == nil || span.Count != 2 || span.FirstKg != 70 || math.Abs(span.LastKg-72) > 1e-9 {
+		t.Errorf("unexpected span: %+v", span)
+	}
+	if span, _ := db.WeightSpanSince(ctx, userID, now.Add(time.Hour)); span != nil {
+		t.Errorf("expected no span after the last weigh-in, got %+v", span)
+	}
 }
 
 func TestWaterRepository(t *testing.T) {
@@ -74,7 +106,9 @@ func TestWaterRepository(t *testing.T) {
 	ctx := context.Background()
 	userID := int64(1)
 
-	now := time.Now()
+	// Midday, so the events below fall on the same local day whenever the
+	// test runs.
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
 	_, err := db.AddWaterEvent(ctx, userID, 0.25, now)
 	if err != nil {
 		t.Fatalf("AddWaterEvent: %v", err)
@@ -90,11 +124,23 @@ func TestWaterRepository(t *testing.T) {
 		t.Errorf("expected 2 events, got %d", len(events))
 	}
 
+	// A page summarises every event, not just those returned
+	page, sum, err := db.ListRecentWaterPage(ctx, userID, 1)
+	if err != nil || len(page) != 1 || page[0].DeltaLiters != 0.5 {
+		t.Fatalf("ListRecentWaterPage = %+v, %v", page, err)
+	}
+	if sum.Total != 2 || !sum.HasMore || sum.FirstAt == nil || !sum.FirstAt.Equal(now) || sum.LastAt == nil || !sum.LastAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected summary %+v", sum)
+	}
+
 	// Other user sees nothing
 	events2, _ := db.ListRecentWaterEvents(ctx, 999, 10)
 	if len(events2) != 0 {
 		t.Error("expected 0 events for other user")
 	}
+	if _, sum, _ := db.ListRecentWaterPage(ctx, 999, 10); sum.Total != 0 || sum.HasMore || sum.FirstAt != nil {
+		t.Errorf("expected an empty summary for other user, got %+v", sum)
+	}
 
 	// Total for day
 	localDay := now.Format("2006-01-02")
@@ -105,6 +151,87 @@ func TestWaterRepository(t *testing.T) {
 	if total != 0.75 {
 		t.Errorf("expected 0.75, got %f", total)
 	}
+
+	// Edit
+	liters, earlier := 0.3, now.Add(-time.Hour)
+	if e, _ := db.UpdateWaterEvent(ctx, 999, events[0].ID, domain.WaterEventPatch{DeltaLiters: &liters}); e != nil {
+		t.Error("expected other user's edit to miss")
+	}
+	e, err := db.UpdateWaterEvent(ctx, userID, events[0].ID, domain.WaterEventPatch{DeltaLiters: &liters, CreatedAt: &earlier})
+	if err != nil || e == nil || e.DeltaLiters != 0.3 || !e.CreatedAt.Equal(earlier) {
+		t.Fatalf("UpdateWaterEvent = %+v, %v", e, err)
+	}
+	if c, _ := db.LatestChange(ctx, userID, domain.ChangeEntityWater); c == nil || c.EntityID != e.ID || c.Op != domain.ChangeOpUpsert {
+		t.Errorf("expected the edit in the change log, got %+v", c)
+	}
+}
+
+// TestLocalDayAcrossClockChanges checks that day totals cover whole local
+// days when clocks change, rather than 24 hours from midnight.
+func TestLocalDayAcrossClockChanges(t *testing.T) {
+	utc := func(s string) time.Time {
+		at, _ := time.Parse(time.RFC3339, s)
+		return at
+	}
+	tests := []struct {
+		zone, day             string
+		before, inside, after time.Time
+	}{
+		// A 25-hour day: 23:30 is 24.5 hours after midnight.
+		{"America/New_York", "2024-11-03", utc("2024-11-03T03:59:00Z"), utc("2024-11-04T04:30:00Z"), utc("2024-11-04T05:00:00Z")},
+		// A 23-hour day starting at 01:00, as midnight is skipped.
+		{"America/Santiago", "2024-09-08", utc("2024-09-08T03:59:00Z"), utc("2024-09-08T04:00:00Z"), utc("2024-09-09T03:00:00Z")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			testdoubles.InZone(t, tt.zone)
+			db := New()
+			ctx := context.Background()
+			_, _ = db.AddWaterEvent(ctx, 1, 0.125, tt.before)
+			_, _ = db.AddWaterEvent(ctx, 1, 0.5, tt.inside)
+			_, _ = db.AddWaterEvent(ctx, 1, 0.25, tt.after)
+
+			if total, err := db.WaterTotalForLocalDay(ctx, 1, tt.day); err != nil || total != 0.5 {
+				t.Errorf("WaterTotalForLocalDay(%s) = %v, %v; want 0.5", tt.day, total, err)
+			}
+		})
+	}
+}
+
+func TestFoodRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	clientID := "0b1c7a36-54e2-4f1a-9d3e-6f2b8c4a1e07"
+
+	_, _, _ = db.AddFoodEntry(ctx, 1, domain.FoodEntry{Description: "oats", Kcal: 350, ProteinG: 12, CarbsG: 60, FatG: 6, CreatedAt: now})
+	first, created, err := db.AddFoodEntry(ctx, 1, domain.FoodEntry{Description: "apple", Kcal: 95, CarbsG: 25, ClientID: clientID, CreatedAt: now.Add(time.Minute)})
+	if err != nil || !created {
+		t.Fatalf("AddFoodEntry: %v, created %v", err, created)
+	}
+	again, created, _ := db.AddFoodEntry(ctx, 1, domain.FoodEntry{Description: "apple", Kcal: 95, ClientID: clientID, CreatedAt: now})
+	if created || again.ID != first.ID {
+		t.Errorf("expected the retry to return entry %d, got %+v (created %v)", first.ID, again, created)
+	}
+	_, _, _ = db.AddFoodEntry(ctx, 2, domain.FoodEntry{Description: "other user", Kcal: 500, CreatedAt: now})
+
+	totals, err := db.FoodTotalsForLocalDay(ctx, 1, now.Format("2006-01-02"))
+	if err != nil {
+		t.Fatalf("FoodTotalsForLocalDay: %v", err)
+	}
+	if totals != (domain.NutritionTotals{Kcal: 445, ProteinG: 12, CarbsG: 85, FatG: 6, Entries: 2}) {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+
+	entries, _ := db.ListRecentFoodEntries(ctx, 1, 10)
+	if len(entries) != 2 || entries[0].Description != "apple" {
+		t.Fatalf("expected 2 entries, newest first, got %+v", entries)
+	}
+	_ = db.DeleteFoodEntry(ctx, 2, entries[0].ID)
+	_ = db.DeleteFoodEntry(ctx, 1, entries[1].ID)
+	if entries, _ = db.ListRecentFoodEntries(ctx, 1, 10); len(entries) != 1 || entries[0].Description != "apple" {
+		t.Errorf("expected only the other user's delete to be ignored, got %+v", entries)
+	}
 }
 
 func TestUserRepository(t *testing.T) {
@@ -148,7 +275,10 @@ func TestSessionRepository(t *testing.T) {
 		t.Fatalf("GetByToken: %v", err)
 	}
 	if sess == nil {
-		t.Error("expected session, got nil")
+		t.Fatal("expected session, got nil")
+	}
+	if sess.UserAgent != "test-agent" || sess.IP != "127.0.0.1" || sess.ID == 0 {
+		t.Errorf("expected session metadata to be kept, got %+v", sess)
 	}
 
 	_ = repo.Delete(ctx, "token123")
@@ -156,4 +286,1082 @@ func TestSessionRepository(t *testing.T) {
 	if sess != nil {
 		t.Error("expected nil (deleted)")
 	}
+
+	_ = repo.Create(ctx, 1, "ipad", "ipad-agent", "10.0.0.2", time.Now().Add(time.Hour))
+	_ = repo.Create(ctx, 1, "phone", "phone-agent", "10.0.0.3", time.Now().Add(time.Hour))
+	_ = repo.Create(ctx, 2, "other", "agent", "10.0.0.4", time.Now().Add(time.Hour))
+	ipad, _ := repo.GetByToken(ctx, "ipad")
+	_ = repo.Touch(ctx, "ipad", time.Now().Add(time.Minute))
+
+	if ok, err := repo.Rename(ctx, 2, ipad.ID, "stolen"); err != nil || ok {
+		t.Errorf("expected rename of another user's session to fail, got %v, %v", ok, err)
+	}
+	if ok, _ := repo.Rename(ctx, 1, ipad.ID, "iPad kitchen"); !ok {
+		t.Error("expected rename to succeed")
+	}
+	list, _ := repo.ListByUser(ctx, 1)
+	if len(list) != 2 || list[0].Token != "ipad" || list[0].Name != "iPad kitchen" {
+		t.Fatalf("expected the renamed, most recently seen session first, got %+v", list)
+	}
+	if list[0].UserAgent != "ipad-agent" || list[0].IP != "10.0.0.2" || list[1].UserAgent != "phone-agent" {
+		t.Errorf("expected listed sessions to carry their metadata, got %+v", list)
+	}
+
+	if ok, _ := repo.DeleteByID(ctx, 2, ipad.ID); ok {
+		t.Error("expected revoking another user's session to fail")
+	}
+	if ok, _ := repo.DeleteByID(ctx, 1, ipad.ID); !ok {
+		t.Error("expected revoke to succeed")
+	}
+	if sess, _ := repo.GetByToken(ctx, "ipad"); sess != nil {
+		t.Error("expected the revoked session to be gone")
+	}
+}
+
+func TestMaintenanceRepository(t *testing.T) {
+	db := New()
+	repo := db.NewSessionRepo()
+	ctx := context.Background()
+
+	u, _ := db.Create(ctx, "bob", "hash")
+	_, _ = db.AddWeightEvent(ctx, u.ID, 80, "kg", time.Now())
+	_, _ = db.AddWaterEvent(ctx, 999, 0.5, time.Now())
+	_ = repo.Create(ctx, u.ID, "live", "agent", "127.0.0.1", time.Now().Add(time.Hour))
+	_ = repo.Create(ctx, u.ID, "stale", "agent", "127.0.0.1", time.Now().Add(-time.Hour))
+
+	n, _ := db.CountExpiredSessions(ctx)
+	if n != 1 {
+		t.Fatalf("expected 1 expired session, got %d", n)
+	}
+	n, _ = db.PurgeExpiredSessions(ctx)
+	if n != 1 {
+		t.Fatalf("expected 1 purged session, got %d", n)
+	}
+	if n, _ = db.CountExpiredSessions(ctx); n != 0 {
+		t.Fatalf("expected 0 expired sessions after purge, got %d", n)
+	}
+
+	orphans, _ := db.CountOrphanedEvents(ctx)
+	if orphans.WeightEvents != 0 || orphans.WaterEvents != 1 {
+		t.Fatalf("unexpected orphan counts: %+v", orphans)
+	}
+}
+
+func TestPurgeOldChanges(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	kept, _ := db.AddWaterEvent(ctx, 1, 0.5, time.Now())
+	gone, _ := db.AddWaterEvent(ctx, 1, 0.25, time.Now())
+	_ = db.DeleteWaterEvent(ctx, 1, gone)
+	changes, _ := db.ListChanges(ctx, 1, 0, 10)
+	cursor := changes[0].Seq
+
+	// The deletion and the upsert it superseded go; the live entry stays.
+	later := time.Now().Add(time.Minute)
+	if n, err := db.CountOldChanges(ctx, later); err != nil || n != 2 {
+		t.Fatalf("CountOldChanges = %d, %v", n, err)
+	}
+	if n, err := db.PurgeOldChanges(ctx, later); err != nil || n != 2 {
+		t.Fatalf("PurgeOldChanges = %d, %v", n, err)
+	}
+	if changes, err := db.ListChanges(ctx, 1, 0, 10); err != nil || len(changes) != 1 || changes[0].EntityID != kept {
+		t.Errorf("expected a full sync to return the live entry, got %+v, %v", changes, err)
+	}
+	if _, err := db.ListChanges(ctx, 1, cursor, 10); !errors.Is(err, domain.ErrCursorExpired) {
+		t.Errorf("expected a cursor before the purged deletion to expire, got %v", err)
+	}
+	if n, _ := db.CountOldChanges(ctx, later); n != 0 {
+		t.Errorf("expected nothing left to purge, got %d", n)
+	}
+}
+
+func TestWeeklyUsage(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	monday := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	_, _ = db.AddWeightEvent(ctx, 1, 80, "kg", monday.Add(-time.Hour))
+	_, _ = db.AddWeightEvent(ctx, 1, 80, "kg", monday.Add(time.Hour))
+	_, _ = db.AddWaterEvent(ctx, 1, 0.5, monday.Add(2*time.Hour))
+	_, _ = db.AddWaterEvent(ctx, 2, 0.5, monday.AddDate(0, 0, 6))
+	_, _ = db.AddWaterEvent(ctx, 2, 0.5, monday.AddDate(0, 0, 7))
+
+	weeks, err := db.WeeklyUsage(ctx, monday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.UsageWeek{
+		{WeekStart: "2026-01-05", ActiveUsers: 2, Entries: 3},
+		{WeekStart: "2026-01-12", ActiveUsers: 1, Entries: 1},
+	}
+	if len(weeks) != len(want) || weeks[0] != want[0] || weeks[1] != want[1] {
+		t.Fatalf("WeeklyUsage = %+v, want %+v", weeks, want)
+	}
+}
+
+func TestProfileRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	owner, _ := db.Create(ctx, "bob", "hash")
+	p, err := db.CreateProfile(ctx, owner.ID, "Sam")
+	if err != nil {
+		t.Fatalf("CreateProfile: %v", err)
+	}
+	if p.ID == owner.ID {
+		t.Fatal("profile ID must not collide with the owner's user ID")
+	}
+	if _, err := db.CreateProfile(ctx, owner.ID, "Sam"); err == nil {
+		t.Error("expected duplicate profile name to fail")
+	}
+
+	if got, _ := db.GetProfile(ctx, owner.ID, p.ID); got == nil || got.Name != "Sam" {
+		t.Errorf("expected profile Sam, got %+v", got)
+	}
+	if got, _ := db.GetProfile(ctx, 999, p.ID); got != nil {
+		t.Error("expected nil for another owner")
+	}
+
+	list, _ := db.ListProfiles(ctx, owner.ID)
+	if len(list) != 1 {
+		t.Errorf("expected 1 profile, got %d", len(list))
+	}
+	if count, _ := db.Count(ctx); count != 1 {
+		t.Errorf("profiles must not count as users, got %d", count)
+	}
+}
+
+func TestShareRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	alice, _ := db.Create(ctx, "alice", "hash")
+	coach, _ := db.Create(ctx, "coach", "hash")
+
+	sh, err := db.CreateShare(ctx, alice.ID, coach.ID)
+	if err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+	if sh.OwnerUsername != "alice" || sh.ViewerUsername != "coach" {
+		t.Errorf("expected usernames to be populated, got %+v", sh)
+	}
+	_, _ = db.CreateShare(ctx, alice.ID, coach.ID)
+	if list, _ := db.ListShares(ctx, coach.ID); len(list) != 1 {
+		t.Errorf("expected re-granting to be a no-op, got %d shares", len(list))
+	}
+
+	if got, _ := db.GetShare(ctx, coach.ID, alice.ID); got != nil {
+		t.Error("expected no share in the reverse direction")
+	}
+
+	ok, _ := db.DeleteShare(ctx, alice.ID, coach.ID)
+	if !ok {
+		t.Error("expected DeleteShare to report a deletion")
+	}
+	if got, _ := db.GetShare(ctx, alice.ID, coach.ID); got != nil {
+		t.Error("expected share to be gone")
+	}
+}
+
+func TestChangeRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	w1, _ := db.AddWeightEvent(ctx, 1, 80, "kg", time.Now().Add(-time.Minute))
+	a1, _ := db.AddWaterEvent(ctx, 1, 0.5, time.Now())
+	_, _ = db.AddWaterEvent(ctx, 2, 0.5, time.Now())
+
+	all, _ := db.ListChanges(ctx, 1, 0, 10)
+	if len(all) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(all))
+	}
+	if all[0].EntityID != w1 || all[0].Weight == nil || all[0].Weight.Value != 80 {
+		t.Errorf("unexpected weight change: %+v", all[0])
+	}
+	cursor := all[1].Seq
+
+	_ = db.DeleteWaterEvent(ctx, 1, a1)
+	_, _ = db.DeleteLatestWeightEvent(ctx, 1)
+
+	delta, _ := db.ListChanges(ctx, 1, cursor, 10)
+	if len(delta) != 2 {
+		t.Fatalf("expected 2 changes after cursor, got %d", len(delta))
+	}
+	for _, c := range delta {
+		if c.Op != domain.ChangeOpDelete || c.Weight != nil || c.Water != nil {
+			t.Errorf("expected bare delete, got %+v", c)
+		}
+	}
+
+	// From scratch, each entity collapses to its latest change.
+	all, _ = db.ListChanges(ctx, 1, 0, 10)
+	if len(all) != 2 || all[0].Op != domain.ChangeOpDelete {
+		t.Fatalf("expected 2 collapsed deletes, got %+v", all)
+	}
+}
+
+func TestClientIDDeduplication(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	const clientID = "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c"
+
+	first, created, err := db.AddWaterEventWithClientID(ctx, 1, clientID, 0.5, time.Now())
+	if err != nil || !created {
+		t.Fatalf("expected first write to create, got created=%v err=%v", created, err)
+	}
+	retry, created, _ := db.AddWaterEventWithClientID(ctx, 1, clientID, 0.75, time.Now())
+	if created || retry.ID != first.ID || retry.DeltaLiters != 0.5 {
+		t.Fatalf("expected retry to return the original event, got %+v created=%v", retry, created)
+	}
+	// Client IDs are scoped per user.
+	if _, created, _ := db.AddWaterEventWithClientID(ctx, 2, clientID, 0.5, time.Now()); !created {
+		t.Error("expected another user's write with the same client id to create")
+	}
+	if items, _ := db.ListRecentWaterEvents(ctx, 1, 10); len(items) != 1 {
+		t.Errorf("expected 1 water event, got %d", len(items))
+	}
+
+	w, created, _ := db.AddWeightEventWithClientID(ctx, 1, clientID, 80, "kg", time.Now())
+	if !created || w.ClientID != clientID {
+		t.Fatalf("expected weight write to create, got %+v", w)
+	}
+	if _, created, _ := db.AddWeightEventWithClientID(ctx, 1, clientID, 81, "kg", time.Now()); created {
+		t.Error("expected weight retry to be deduplicated")
+	}
+}
+
+func TestBatchRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	now := time.Now()
+
+	waterID, _ := db.AddWaterEvent(ctx, 1, 0.25, now)
+
+	results, err := db.ApplyBatch(ctx, 1, []domain.BatchOp{
+		{Op: domain.BatchOpWeight, Value: 80, Unit: "kg", CreatedAt: now},
+		{Op: domain.BatchOpWater, DeltaLiters: 0.5, CreatedAt: now},
+		{Op: domain.BatchOpDeleteWater, ID: waterID},
+		{Op: domain.BatchOpDeleteWeight, ID: 999},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch failed: %v", err)
+	}
+	if len(results) != 4 || !results[0].Created || results[0].Weight == nil || !results[1].Created {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if !results[2].Deleted || results[3].Deleted {
+		t.Errorf("unexpected delete results: %+v, %+v", results[2], results[3])
+	}
+	if items, _ := db.ListRecentWaterEvents(ctx, 1, 10); len(items) != 1 || items[0].DeltaLiters != 0.5 {
+		t.Errorf("unexpected water events: %+v", items)
+	}
+
+	// An unknown op rejects the whole batch.
+	if _, err := db.ApplyBatch(ctx, 1, []domain.BatchOp{
+		{Op: domain.BatchOpWater, DeltaLiters: 1, CreatedAt: now},
+		{Op: "bogus"},
+	}); err == nil {
+		t.Fatal("expected error for unknown op")
+	}
+	if items, _ := db.ListRecentWaterEvents(ctx, 1, 10); len(items) != 1 {
+		t.Errorf("expected rejected batch to write nothing, got %d events", len(items))
+	}
+}
+
+func TestAlertRuleRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	if r, _ := db.GetAlertRule(ctx, 1); r != nil {
+		t.Fatalf("expected no rule, got %+v", r)
+	}
+	_ = db.SaveAlertRule(ctx, domain.AlertRule{UserID: 1, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelMQTT, Enabled: true})
+	_ = db.SaveAlertRule(ctx, domain.AlertRule{UserID: 2, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelMQTT})
+
+	rules, _ := db.ListAlertRules(ctx)
+	if len(rules) != 1 || rules[0].UserID != 1 {
+		t.Fatalf("expected only the enabled rule, got %+v", rules)
+	}
+
+	at := time.Now()
+	_ = db.MarkAlerted(ctx, 1, at)
+	r, _ := db.GetAlertRule(ctx, 1)
+	if r == nil || r.LastAlertedAt == nil || !r.LastAlertedAt.Equal(at) {
+		t.Fatalf("expected LastAlertedAt to be set, got %+v", r)
+	}
+}
+
+func TestOutboxRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	_ = db.SaveAlertRule(ctx, domain.AlertRule{UserID: 1, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelMQTT, Enabled: true})
+	n := domain.Notification{UserID: 1, Title: "alert", Target: "https://example.com/hook"}
+	_ = db.MarkAlerted(ctx, 1, time.Now(), domain.OutboxMessage{Channel: domain.AlertChannelWebhook, Notification: n})
+	_ = db.EnqueueOutbox(ctx, domain.OutboxMessage{Channel: domain.AlertChannelMQTT, Notification: domain.Notification{UserID: 2}})
+
+	now := time.Now()
+	claimed, _ := db.ClaimOutbox(ctx, now, now.Add(time.Minute), 10)
+	if len(claimed) != 2 || claimed[0].Notification != n || claimed[0].Channel != domain.AlertChannelWebhook {
+		t.Fatalf("expected both messages claimed in order, got %+v", claimed)
+	}
+	// Leased messages are not claimed again until the lease ends.
+	if again, _ := db.ClaimOutbox(ctx, now, now.Add(time.Minute), 10); len(again) != 0 {
+		t.Fatalf("expected leased messages skipped, got %+v", again)
+	}
+	if again, _ := db.ClaimOutbox(ctx, now.Add(2*time.Minute), now.Add(3*time.Minute), 1); len(again) != 1 || again[0].ID != claimed[0].ID {
+		t.Fatalf("expected an expired lease claimed again, got %+v", again)
+	}
+
+	_ = db.DeleteOutbox(ctx, claimed[0].ID)
+	_ = db.FailOutbox(ctx, claimed[1].ID, "boom", nil)
+	if left, _ := db.ClaimOutbox(ctx, now.Add(time.Hour), now.Add(2*time.Hour), 10); len(left) != 0 {
+		t.Fatalf("expected nothing due, got %+v", left)
+	}
+	if len(db.outbox) != 1 || db.outbox[0].Attempts != 1 || db.outbox[0].LastError != "boom" {
+		t.Errorf("expected the given up message kept, got %+v", db.outbox)
+	}
+}
+
+func TestWebhookRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	hook, _ := db.CreateWebhook(ctx, domain.Webhook{UserID: 1, URL: "https://example.com/hook", Secret: "s", Events: []string{"weight.recorded"}, Enabled: true})
+	if other, _ := db.GetWebhook(ctx, 2, hook.ID); other != nil {
+		t.Fatalf("expected another user's webhook hidden, got %+v", other)
+	}
+	_ = db.EnqueueWebhookDeliveries(ctx,
+		domain.WebhookDelivery{WebhookID: hook.ID, UserID: 1, Event: "weight.recorded", Payload: []byte(`{}`)},
+		domain.WebhookDelivery{WebhookID: hook.ID, UserID: 1, Event: "weight.recorded", Payload: []byte(`{}`)},
+	)
+
+	now := time.Now()
+	claimed, _ := db.ClaimWebhookDeliveries(ctx, now, now.Add(time.Minute), 10)
+	if len(claimed) != 2 {
+		t.Fatalf("expected both deliveries claimed, got %+v", claimed)
+	}
+	if again, _ := db.ClaimWebhookDeliveries(ctx, now, now.Add(time.Minute), 10); len(again) != 0 {
+		t.Fatalf("expected leased deliveries skipped, got %+v", again)
+	}
+	_ = db.CompleteWebhookDelivery(ctx, claimed[0].ID, 204, now)
+	_ = db.FailWebhookDelivery(ctx, claimed[1].ID, 500, "boom", nil)
+
+	log, _ := db.ListWebhookDeliveries(ctx, 1, hook.ID, 10)
+	if len(log) != 2 || log[0].LastError != "boom" || log[1].DeliveredAt == nil || log[1].StatusCode != 204 {
+		t.Fatalf("expected the log newest first, got %+v", log)
+	}
+	if n, _ := db.PruneWebhookDeliveries(ctx, now.Add(time.Hour)); n != 2 {
+		t.Errorf("expected both settled deliveries pruned, got %d", n)
+	}
+
+	_ = db.EnqueueWebhookDeliveries(ctx, domain.WebhookDelivery{WebhookID: hook.ID, UserID: 1, Event: "weight.recorded"})
+	if ok, _ := db.DeleteWebhook(ctx, 1, hook.ID); !ok || len(db.webhookDeliveries) != 0 {
+		t.Errorf("expected the webhook deleted with its deliveries, got %+v", db.webhookDeliveries)
+	}
+}
+
+func TestHydrationSettingsRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	if hs, _ := db.GetHydrationSettings(ctx, 1); hs != nil {
+		t.Fatalf("expected no settings, got %+v", hs)
+	}
+	lat, lon := 1.5, 2.5
+	_ = db.SaveHydrationSettings(ctx, domain.HydrationSettings{UserID: 1, BaseGoalLiters: 3, Latitude: &lat, Longitude: &lon})
+	hs, _ := db.GetHydrationSettings(ctx, 1)
+	if hs == nil || hs.BaseGoalLiters != 3 || !hs.HasLocation() {
+		t.Fatalf("unexpected settings: %+v", hs)
+	}
+}
+
+func TestGoalHistoryRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	_ = db.RecordGoal(ctx, 1, "2026-05-01", 3)
+	_ = db.RecordGoal(ctx, 1, "2026-03-01", 2)
+	_ = db.RecordGoal(ctx, 1, "2026-05-01", 3.5)
+	_ = db.RecordGoal(ctx, 2, "2026-04-01", 4)
+
+	h, _ := db.GoalHistory(ctx, 1)
+	want := domain.GoalHistory{{Day: "2026-03-01", Liters: 2}, {Day: "2026-05-01", Liters: 3.5}}
+	if len(h) != len(want) || h[0] != want[0] || h[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, h)
+	}
+}
+
+func TestWeightGoalRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	target := 80.0
+	_ = db.RecordWeightGoal(ctx, 1, "2026-05-01", &target)
+	target = 75 // the stored target must not alias the caller's value
+	_ = db.RecordWeightGoal(ctx, 1, "2026-03-01", &target)
+	_ = db.RecordWeightGoal(ctx, 1, "2026-06-01", &target)
+	_ = db.RecordWeightGoal(ctx, 1, "2026-06-01", nil)
+
+	h, _ := db.WeightGoalHistory(ctx, 1)
+	if len(h) != 3 || h[0].Day != "2026-03-01" || *h[0].TargetKg != 75 || *h[1].TargetKg != 80 || h[2].TargetKg != nil {
+		t.Fatalf("unexpected history: %+v", h)
+	}
+	if other, _ := db.WeightGoalHistory(ctx, 2); len(other) != 0 {
+		t.Errorf("expected no history for another user, got %+v", other)
+	}
+}
+
+func TestOAuthTokenRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	for _, user := range []int64{2, 1} {
+		_ = db.SaveOAuthToken(ctx, domain.OAuthToken{UserID: user, Provider: domain.IntegrationGoogleFit, AccessToken: "a"})
+	}
+	_ = db.SaveOAuthToken(ctx, domain.OAuthToken{UserID: 1, Provider: domain.IntegrationGoogleFit, AccessToken: "b"})
+	_ = db.SaveOAuthToken(ctx, domain.OAuthToken{UserID: 1, Provider: "other", AccessToken: "c"})
+
+	if tok, _ := db.GetOAuthToken(ctx, 1, domain.IntegrationGoogleFit); tok == nil || tok.AccessToken != "b" {
+		t.Fatalf("expected the replaced token, got %+v", tok)
+	}
+	list, _ := db.ListOAuthTokens(ctx, domain.IntegrationGoogleFit)
+	if len(list) != 2 || list[0].UserID != 1 || list[1].UserID != 2 {
+		t.Fatalf("unexpected tokens: %+v", list)
+	}
+	if ok, _ := db.DeleteOAuthToken(ctx, 1, domain.IntegrationGoogleFit); !ok {
+		t.Error("expected the token deleted")
+	}
+	if tok, _ := db.GetOAuthToken(ctx, 1, domain.IntegrationGoogleFit); tok != nil {
+		t.Errorf("expected no token after delete, got %+v", tok)
+	}
+	if tok, _ := db.GetOAuthToken(ctx, 1, "other"); tok == nil {
+		t.Error("expected the other provider's token kept")
+	}
+}
+
+func TestTagRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	id, _ := db.AddWeightEvent(ctx, 1, 70, "kg", time.Now())
+	waterID, _ := db.AddWaterEvent(ctx, 1, 0.5, time.Now())
+
+	if ok, _ := db.SetEntryTags(ctx, 2, domain.ChangeEntityWeight, id, []string{"sick"}); ok {
+		t.Fatal("expected tagging another user's entry to fail")
+	}
+	if ok, _ := db.SetEntryTags(ctx, 1, domain.ChangeEntityWeight, id, []string{"sick", "travel"}); !ok {
+		t.Fatal("expected tagging to succeed")
+	}
+	_, _ = db.SetEntryTags(ctx, 1, domain.ChangeEntityWater, waterID, []string{"travel"})
+
+	tags, _ := db.EntryTags(ctx, 1, domain.ChangeEntityWeight, []int64{id, waterID})
+	if len(tags) != 1 || len(tags[id]) != 2 {
+		t.Fatalf("unexpected weight tags: %v", tags)
+	}
+	counts, _ := db.ListTags(ctx, 1)
+	if len(counts) != 2 || counts[1] != (domain.TagCount{Tag: "travel", Count: 2}) {
+		t.Fatalf("unexpected tag counts: %v", counts)
+	}
+
+	// Deleting an entry drops its tags.
+	_, _ = db.DeleteLatestWeightEvent(ctx, 1)
+	if counts, _ := db.ListTags(ctx, 1); len(counts) != 1 {
+		t.Fatalf("expected only the water tag to remain, got %v", counts)
+	}
+}
+
+func TestImportRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	at := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	if created, _ := db.ImportWeightEvent(ctx, 1, "a", "", 80, "kg", at); !created {
+		t.Fatal("expected the first weight to be stored")
+	}
+	// The same timestamp and value is a duplicate, even in another batch.
+	if created, _ := db.ImportWeightEvent(ctx, 1, "b", "", 80, "kg", at); created {
+		t.Fatal("expected an identical weight to be skipped")
+	}
+	if created, _ := db.ImportWeightEvent(ctx, 1, "b", "", 80, "lb", at); !created {
+		t.Fatal("expected a different unit to be stored")
+	}
+	if created, _ := db.ImportWeightEvent(ctx, 2, "b", "", 80, "kg", at); !created {
+		t.Fatal("expected another user's identical weight to be stored")
+	}
+	_, _ = db.ImportWaterEvent(ctx, 1, "a", "", 0.5, at)
+	if created, _ := db.ImportWaterEvent(ctx, 1, "a", "", 0.5, at); created {
+		t.Fatal("expected an identical water event to be skipped")
+	}
+	_, _ = db.AddWaterEvent(ctx, 1, 0.25, at)
+
+	if n, _ := db.DeleteImportBatch(ctx, 2, "a"); n != 0 {
+		t.Fatalf("expected another user's batch to be untouched, removed %d", n)
+	}
+	if n, _ := db.DeleteImportBatch(ctx, 1, "a"); n != 2 {
+		t.Fatalf("expected batch a's two events removed, removed %d", n)
+	}
+	weights, _ := db.ListRecentWeightEvents(ctx, 1, 10)
+	water, _ := db.ListRecentWaterEvents(ctx, 1, 10)
+	if len(weights) != 1 || weights[0].Unit != "lb" || len(water) != 1 || water[0].DeltaLiters != 0.25 {
+		t.Fatalf("expected only batch b and the logged event to remain, got %+v %+v", weights, water)
+	}
+	if changes, _ := db.ListChanges(ctx, 1, 0, 100); len(changes) != 4 {
+		t.Fatalf("expected one change per entry, with deletes for the rollback, got %+v", changes)
+	}
+}
+
+func TestJournalRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	for _, day := range []string{"2026-01-03", "2026-01-01", "2026-01-02"} {
+		_ = db.SaveJournalEntry(ctx, 1, domain.JournalEntry{Day: day, Note: "note " + day})
+	}
+	_ = db.SaveJournalEntry(ctx, 2, domain.JournalEntry{Day: "2026-01-02", Note: "other user"})
+
+	entries, _ := db.ListJournalEntries(ctx, 1, "2026-01-02", "2026-01-03")
+	if len(entries) != 2 || entries[0].Day != "2026-01-02" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if ok, _ := db.DeleteJournalEntry(ctx, 1, "2026-01-02"); !ok {
+		t.Fatal("expected the entry to be deleted")
+	}
+	if e, _ := db.GetJournalEntry(ctx, 1, "2026-01-02"); e != nil {
+		t.Fatalf("expected no entry, got %+v", e)
+	}
+	if e, _ := db.GetJournalEntry(ctx, 2, "2026-01-02"); e == nil || e.Note != "other user" {
+		t.Fatalf("expected the other user's entry to remain, got %+v", e)
+	}
+}
+
+func TestMoodRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	for i, day := range []string{"2026-01-03", "2026-01-01", "2026-01-02"} {
+		_ = db.SaveMoodEntry(ctx, 1, domain.MoodEntry{Day: day, Score: i + 1})
+	}
+	_ = db.SaveMoodEntry(ctx, 1, domain.MoodEntry{Day: "2026-01-03", Score: 5, Note: "better"})
+	_ = db.SaveMoodEntry(ctx, 2, domain.MoodEntry{Day: "2026-01-02", Score: 1})
+
+	if e, _ := db.GetMoodEntry(ctx, 1, "2026-01-03"); e == nil || e.Score != 5 || e.Note != "better" {
+		t.Fatalf("expected the replaced entry, got %+v", e)
+	}
+	entries, _ := db.ListMoodEntries(ctx, 1, "2026-01-02", "2026-01-03")
+	if len(entries) != 2 || entries[0].Day != "2026-01-02" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	recent, _ := db.ListRecentMoodEntries(ctx, 1, 2)
+	if len(recent) != 2 || recent[0].Day != "2026-01-03" || recent[1].Day != "2026-01-02" {
+		t.Fatalf("expected the two latest days, newest first, got %+v", recent)
+	}
+	if recent, _ := db.ListRecentMoodEntries(ctx, 2, 10); len(recent) != 1 || recent[0].Score != 1 {
+		t.Fatalf("expected only the other user's entry, got %+v", recent)
+	}
+}
+
+func TestStepsRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	for i, day := range []string{"2026-01-03", "2026-01-01", "2026-01-02"} {
+		_ = db.SaveStepsEntry(ctx, 1, domain.StepsEntry{Day: day, Steps: (i + 1) * 1000})
+	}
+	_ = db.SaveStepsEntry(ctx, 1, domain.StepsEntry{Day: "2026-01-03", Steps: 9000})
+	_ = db.SaveStepsEntry(ctx, 2, domain.StepsEntry{Day: "2026-01-02", Steps: 500})
+
+	if e, _ := db.GetStepsEntry(ctx, 1, "2026-01-03"); e == nil || e.Steps != 9000 {
+		t.Fatalf("expected the replaced count, got %+v", e)
+	}
+	if e, _ := db.GetStepsEntry(ctx, 2, "2026-01-03"); e != nil {
+		t.Errorf("expected no count for the other user, got %+v", e)
+	}
+	entries, _ := db.ListStepsEntries(ctx, 1, "2026-01-02", "2026-01-03")
+	if len(entries) != 2 || entries[0].Day != "2026-01-02" || entries[1].Steps != 9000 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestWeeklySummaryRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	start := time.Now()
+
+	_, _ = db.AddWeightEvent(ctx, 2, 70, "kg", start.AddDate(0, -6, 0))
+	ids, _ := db.ListActiveUserIDs(ctx, start.Add(-time.Minute))
+	if len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("expected a backdated entry to mark its user active, got %v", ids)
+	}
+
+	_ = db.SaveWeeklySummaries(ctx, 2, []domain.WeeklySummary{{WeekStart: "2026-01-05"}, {WeekStart: "2026-01-12"}})
+	_ = db.SaveWeeklySummaries(ctx, 2, []domain.WeeklySummary{{WeekStart: "2026-01-12", WeighIns: 3}})
+	sums, _ := db.ListWeeklySummaries(ctx, 2, "2026-01-01")
+	if len(sums) != 2 || sums[0].WeekStart != "2026-01-12" || sums[0].WeighIns != 3 {
+		t.Fatalf("unexpected summaries: %+v", sums)
+	}
+}
+
+func TestRuleRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	rule := domain.Rule{
+		UserID: 1, Name: "Drink up", Channel: domain.AlertChannelMQTT, Enabled: true,
+		Condition: domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00"},
+	}
+	created, err := db.CreateRule(ctx, rule)
+	if err != nil || created.ID == 0 {
+		t.Fatalf("CreateRule = %+v, %v", created, err)
+	}
+	if got, _ := db.GetRule(ctx, 2, created.ID); got != nil {
+		t.Error("expected other user not to see the rule")
+	}
+
+	at := time.Now()
+	if err := db.MarkRuleFired(ctx, 1, created.ID, at); err != nil {
+		t.Fatalf("MarkRuleFired: %v", err)
+	}
+	created.Name, created.Enabled = "Hydrate", false
+	if ok, err := db.UpdateRule(ctx, *created); err != nil || !ok {
+		t.Fatalf("UpdateRule = %v, %v", ok, err)
+	}
+	got, _ := db.GetRule(ctx, 1, created.ID)
+	if got == nil || got.Name != "Hydrate" || got.LastFiredAt == nil {
+		t.Errorf("expected updated rule keeping its history, got %+v", got)
+	}
+	if enabled, _ := db.ListEnabledRules(ctx); len(enabled) != 0 {
+		t.Errorf("expected no enabled rules, got %d", len(enabled))
+	}
+
+	if ok, _ := db.DeleteRule(ctx, 2, created.ID); ok {
+		t.Error("expected other user's delete to fail")
+	}
+	if ok, _ := db.DeleteRule(ctx, 1, created.ID); !ok {
+		t.Error("expected delete to succeed")
+	}
+	if rules, _ := db.ListRules(ctx, 1); len(rules) != 0 {
+		t.Errorf("expected no rules, got %d", len(rules))
+	}
+}
+
+func TestMedicationRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	med, err := db.CreateMedication(ctx, domain.Medication{UserID: 1, Name: "Iron", Schedule: []string{"08:00"}})
+	if err != nil || med.ID == 0 {
+		t.Fatalf("CreateMedication = %+v, %v", med, err)
+	}
+	if got, _ := db.GetMedication(ctx, 2, med.ID); got != nil {
+		t.Error("expected other user not to see the medication")
+	}
+
+	now := time.Now()
+	for i, status := range []string{domain.MedicationTaken, domain.MedicationSkipped} {
+		e := domain.MedicationEvent{MedicationID: med.ID, Status: status, CreatedAt: now.Add(time.Duration(i) * time.Hour)}
+		if _, created, err := db.AddMedicationEvent(ctx, 1, e); err != nil || !created {
+			t.Fatalf("AddMedicationEvent = %v, %v", created, err)
+		}
+	}
+	retry := domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationTaken, ClientID: "a", CreatedAt: now}
+	first, _, _ := db.AddMedicationEvent(ctx, 1, retry)
+	again, created, _ := db.AddMedicationEvent(ctx, 1, retry)
+	if created || again.ID != first.ID {
+		t.Errorf("expected a retried client ID to return event %d, got %d (created %v)", first.ID, again.ID, created)
+	}
+
+	events, _ := db.ListRecentMedicationEvents(ctx, 1, med.ID, 2)
+	if len(events) != 2 || events[0].Status != domain.MedicationSkipped {
+		t.Errorf("expected the latest two events newest first, got %+v", events)
+	}
+	if events, _ := db.ListRecentMedicationEvents(ctx, 2, 0, 10); len(events) != 0 {
+		t.Errorf("expected other user to see no events, got %d", len(events))
+	}
+
+	med.Name = "Iron bisglycinate"
+	if ok, _ := db.UpdateMedication(ctx, domain.Medication{ID: med.ID, UserID: 2, Name: "x"}); ok {
+		t.Error("expected other user's update to fail")
+	}
+	if ok, err := db.UpdateMedication(ctx, *med); err != nil || !ok {
+		t.Fatalf("UpdateMedication = %v, %v", ok, err)
+	}
+	if got, _ := db.GetMedication(ctx, 1, med.ID); got == nil || got.Name != "Iron bisglycinate" || !got.CreatedAt.Equal(med.CreatedAt) {
+		t.Errorf("expected the medication renamed, got %+v", got)
+	}
+
+	if ok, _ := db.DeleteMedication(ctx, 1, med.ID); !ok {
+		t.Error("expected delete to succeed")
+	}
+	if events, _ := db.ListRecentMedicationEvents(ctx, 1, 0, 10); len(events) != 0 {
+		t.Errorf("expected the events deleted with the medication, got %d", len(events))
+	}
+}
+
+func TestIdentityRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	if id, err := db.GetLinkedIdentity(ctx, "https://sso", "sub"); err != nil || id != nil {
+		t.Fatalf("expected no link, got %+v, %v", id, err)
+	}
+	if err := db.LinkIdentity(ctx, domain.LinkedIdentity{UserID: 1, Issuer: "https://sso", Subject: "sub", Email: "a@example.com"}); err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+	if err := db.LinkIdentity(ctx, domain.LinkedIdentity{UserID: 2, Issuer: "https://sso", Subject: "sub"}); err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+	id, _ := db.GetLinkedIdentity(ctx, "https://sso", "sub")
+	if id == nil || id.UserID != 2 {
+		t.Errorf("expected relink to user 2, got %+v", id)
+	}
+	if id, _ := db.GetLinkedIdentity(ctx, "https://other", "sub"); id != nil {
+		t.Errorf("expected links to be per issuer, got %+v", id)
+	}
+}
+
+func TestRevocationRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	sam, _ := db.Create(ctx, "sam", "")
+	if epoch, ok, err := db.RevocationEpoch(ctx, sam.ID); err != nil || !ok || epoch != 0 {
+		t.Fatalf("RevocationEpoch = %d, %v, %v", epoch, ok, err)
+	}
+	if err := db.BumpRevocationEpoch(ctx, sam.ID); err != nil {
+		t.Fatalf("BumpRevocationEpoch: %v", err)
+	}
+	if epoch, _, _ := db.RevocationEpoch(ctx, sam.ID); epoch != 1 {
+		t.Errorf("expected epoch 1, got %d", epoch)
+	}
+	if err := db.DeleteUser(ctx, sam.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, ok, _ := db.RevocationEpoch(ctx, sam.ID); ok {
+		t.Error("expected no epoch for a deleted user")
+	}
+}
+
+func TestAccountRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	sam, _ := db.Create(ctx, "sam", "")
+	alex, _ := db.Create(ctx, "alex", "")
+
+	if err := db.UpdateUsername(ctx, sam.ID, "alex"); err == nil {
+		t.Error("expected a taken username to be rejected")
+	}
+	if err := db.UpdateUsername(ctx, sam.ID, "samantha"); err != nil {
+		t.Fatalf("UpdateUsername: %v", err)
+	}
+	if u, _ := db.GetByUsername(ctx, "samantha"); u == nil || u.ID != sam.ID {
+		t.Errorf("expected rename to stick, got %+v", u)
+	}
+
+	if err := db.SetEmail(ctx, alex.ID, "a@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	if err := db.SetEmail(ctx, sam.ID, "a@example.com"); err == nil {
+		t.Error("expected a verified email to be unique")
+	}
+	if u, _ := db.GetByEmail(ctx, "a@example.com"); u == nil || u.ID != alex.ID {
+		t.Errorf("expected alex by email, got %+v", u)
+	}
+	if u, _ := db.GetByEmail(ctx, ""); u != nil {
+		t.Errorf("expected no match for an empty email, got %+v", u)
+	}
+
+	if ok, err := db.ClaimAdmin(ctx, alex.ID); err != nil || !ok {
+		t.Fatalf("ClaimAdmin = %v, %v", ok, err)
+	}
+	if ok, _ := db.ClaimAdmin(ctx, sam.ID); ok {
+		t.Error("expected a second claim to fail once there is an admin")
+	}
+	if u, _ := db.GetByID(ctx, sam.ID); u == nil || u.IsAdmin() {
+		t.Errorf("expected sam to stay a plain user, got %+v", u)
+	}
+
+	change := domain.EmailChange{UserID: sam.ID, Email: "s@example.com", TokenHash: "h1", ExpiresAt: time.Now().Add(time.Hour)}
+	_ = db.SaveEmailChange(ctx, change)
+	change.TokenHash = "h2"
+	_ = db.SaveEmailChange(ctx, change)
+	if c, _ := db.GetEmailChange(ctx, sam.ID); c == nil || c.TokenHash != "h2" {
+		t.Errorf("expected the latest change to replace the first, got %+v", c)
+	}
+	if c, _ := db.TakeEmailChange(ctx, "h1"); c != nil {
+		t.Errorf("expected the replaced token to be gone, got %+v", c)
+	}
+	if c, _ := db.TakeEmailChange(ctx, "h2"); c == nil || c.Email != "s@example.com" {
+		t.Errorf("expected to take the change, got %+v", c)
+	}
+	if c, _ := db.GetEmailChange(ctx, sam.ID); c != nil {
+		t.Errorf("expected the change to be consumed, got %+v", c)
+	}
+}
+
+func TestRecoveryRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	sam, _ := db.Create(ctx, "sam", "")
+	now := time.Now()
+
+	_ = db.ReplaceRecoveryCodes(ctx, sam.ID, []string{"a", "b"})
+	_ = db.ReplaceRecoveryCodes(ctx, sam.ID, []string{"c", "d"})
+	_ = db.IssueRecoveryCode(ctx, sam.ID, "issued", now.Add(time.Hour))
+	if n, _ := db.CountRecoveryCodes(ctx, sam.ID); n != 2 {
+		t.Errorf("expected the new emergency codes to replace the old, got %d", n)
+	}
+	if ok, _ := db.RedeemRecoveryCode(ctx, sam.ID, "a", now, "hash"); ok {
+		t.Error("expected a replaced code to be rejected")
+	}
+	if ok, _ := db.RedeemRecoveryCode(ctx, sam.ID, "issued", now.Add(2*time.Hour), "hash"); ok {
+		t.Error("expected an expired code to be rejected")
+	}
+	if ok, err := db.RedeemRecoveryCode(ctx, sam.ID, "c", now, "hash"); err != nil || !ok {
+		t.Fatalf("RedeemRecoveryCode = %v, %v", ok, err)
+	}
+	if u, _ := db.GetByID(ctx, sam.ID); u.PasswordHash != "hash" {
+		t.Errorf("expected the password hash set, got %q", u.PasswordHash)
+	}
+	if n, _ := db.CountRecoveryCodes(ctx, sam.ID); n != 1 {
+		t.Errorf("expected the redeemed code spent, got %d left", n)
+	}
+}
+
+func TestPlanRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, p := range []domain.PlannedEntry{
+		{UserID: 1, Kind: domain.PlanKindWater, Day: "2026-03-02", At: "12:00", Channel: domain.AlertChannelWebhook},
+		{UserID: 1, Kind: domain.PlanKindWeight, Day: "2026-03-01", At: "08:00", Channel: domain.AlertChannelWebhook},
+		{UserID: 1, Kind: domain.PlanKindWeight, Day: "2026-03-02"},
+		{UserID: 2, Kind: domain.PlanKindWeight, Day: "2026-03-02", At: "07:00", Channel: domain.AlertChannelWebhook},
+	} {
+		if _, err := db.CreatePlannedEntry(ctx, p); err != nil {
+			t.Fatalf("CreatePlannedEntry: %v", err)
+		}
+	}
+	items, _ := db.ListPlannedEntries(ctx, 1, "2026-03-01", "2026-03-02")
+	if len(items) != 3 || items[0].Day != "2026-03-01" || items[1].At != "" || items[2].At != "12:00" {
+		t.Fatalf("expected entries by day and time, got %+v", items)
+	}
+	if n, _ := db.CountPlannedEntries(ctx, 1, "2026-03-02"); n != 2 {
+		t.Errorf("CountPlannedEntries = %d, want 2", n)
+	}
+
+	due, _ := db.ListPlanReminders(ctx, "2026-03-02")
+	if len(due) != 2 {
+		t.Fatalf("expected the two reminders of every user, got %+v", due)
+	}
+	msg := domain.OutboxMessage{Channel: domain.AlertChannelWebhook, Notification: domain.Notification{UserID: 1}}
+	if err := db.MarkPlanReminded(ctx, 1, items[2].ID, now, msg); err != nil {
+		t.Fatalf("MarkPlanReminded: %v", err)
+	}
+	if due, _ := db.ListPlanReminders(ctx, "2026-03-02"); len(due) != 1 || due[0].UserID != 2 {
+		t.Errorf("expected the reminded entry skipped, got %+v", due)
+	}
+	if queued, _ := db.ClaimOutbox(ctx, time.Now(), time.Now().Add(time.Minute), 10); len(queued) != 1 {
+		t.Errorf("expected the reminder queued, got %+v", queued)
+	}
+
+	// Moving an entry re-arms its reminder.
+	moved := items[2]
+	moved.Day = "2026-03-03"
+	if ok, err := db.UpdatePlannedEntry(ctx, moved); err != nil || !ok {
+		t.Fatalf("UpdatePlannedEntry = %v, %v", ok, err)
+	}
+	if got, _ := db.GetPlannedEntry(ctx, 1, moved.ID); got == nil || got.RemindedAt != nil || got.Day != "2026-03-03" {
+		t.Errorf("GetPlannedEntry = %+v", got)
+	}
+	if ok, _ := db.DeletePlannedEntry(ctx, 2, moved.ID); ok {
+		t.Error("expected another user's entry not to be deleted")
+	}
+}
+
+func TestTemperatureRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	now := time.Now()
+	for i, v := range []float64{36.9, 38.1} {
+		r := domain.TemperatureReading{Value: v, Unit: domain.TemperatureCelsius, CreatedAt: now.Add(time.Duration(i) * time.Hour)}
+		if _, created, err := db.AddTemperatureReading(ctx, 1, r); err != nil || !created {
+			t.Fatalf("AddTemperatureReading = %v, %v", created, err)
+		}
+	}
+	retry := domain.TemperatureReading{Value: 99.1, Unit: domain.TemperatureFahrenheit, ClientID: "a", CreatedAt: now.Add(-time.Hour)}
+	first, _, _ := db.AddTemperatureReading(ctx, 1, retry)
+	again, created, _ := db.AddTemperatureReading(ctx, 1, retry)
+	if created || again.ID != first.ID {
+		t.Errorf("expected a retried client ID to return reading %d, got %d (created %v)", first.ID, again.ID, created)
+	}
+
+	readings, _ := db.ListRecentTemperatureReadings(ctx, 1, 2)
+	if len(readings) != 2 || readings[0].Value != 38.1 {
+		t.Errorf("expected the latest two readings newest first, got %+v", readings)
+	}
+	if readings, _ := db.ListRecentTemperatureReadings(ctx, 2, 10); len(readings) != 0 {
+		t.Errorf("expected other user to see no readings, got %d", len(readings))
+	}
+
+	if ok, _ := db.DeleteTemperatureReading(ctx, 2, first.ID); ok {
+		t.Error("expected other user's delete to fail")
+	}
+	if ok, err := db.DeleteTemperatureReading(ctx, 1, first.ID); err != nil || !ok {
+		t.Fatalf("DeleteTemperatureReading = %v, %v", ok, err)
+	}
+	if readings, _ := db.ListRecentTemperatureReadings(ctx, 1, 10); len(readings) != 2 {
+		t.Errorf("expected two readings left, got %d", len(readings))
+	}
+}
+
+func TestDeleteLatest(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	// Entries at the same instant are undone newest ID first.
+	at := time.Now()
+	for i := 0; i < 3; i++ {
+		_, _ = db.AddWeightEvent(ctx, 1, 80, "kg", at)
+		_, _ = db.AddWaterEvent(ctx, 1, 0.25, at)
+		_, _, _ = db.AddFoodEntry(ctx, 1, domain.FoodEntry{Description: "snack", CreatedAt: at})
+	}
+	_, _ = db.SetEntryTags(ctx, 1, domain.ChangeEntityWeight, 3, []string{"sick"})
+	if ok, _ := db.DeleteLatestWeightEvent(ctx, 1); !ok {
+		t.Fatal("expected a weight undone")
+	}
+	if tags, _ := db.EntryTags(ctx, 1, domain.ChangeEntityWeight, []int64{3}); len(tags[3]) != 0 {
+		t.Errorf("expected the undone weight's tags removed, got %v", tags)
+	}
+	if id, _ := db.DeleteLatestWaterEvent(ctx, 1); id != 3 {
+		t.Errorf("expected water event 3 undone, got %d", id)
+	}
+	if id, _ := db.DeleteLatestFoodEntry(ctx, 1); id != 3 {
+		t.Errorf("expected food entry 3 undone, got %d", id)
+	}
+	if id, _ := db.DeleteLatestWaterEvent(ctx, 2); id != 0 {
+		t.Errorf("expected nothing to undo for another user, got %d", id)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		undone  int
+		removed = map[int64]bool{}
+	)
+	for i := 0; i < 2; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ok, _ := db.DeleteLatestWeightEvent(ctx, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				undone++
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			id, _ := db.DeleteLatestWaterEvent(ctx, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			removed[id] = true
+		}()
+	}
+	wg.Wait()
+	if undone != 2 || len(removed) != 2 || removed[0] {
+		t.Errorf("expected concurrent undos to remove distinct entries, got %d weights and water %v", undone, removed)
+	}
+}
+
+func TestCustomMetricRepository(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	m, err := db.CreateCustomMetric(ctx, domain.CustomMetric{UserID: 1, Slug: "pain", Name: "Pain", Aggregation: domain.MetricAvg})
+	if err != nil || m.ID == 0 {
+		t.Fatalf("CreateCustomMetric = %+v, %v", m, err)
+	}
+	if _, err := db.CreateCustomMetric(ctx, domain.CustomMetric{UserID: 1, Slug: "pain", Name: "Pain"}); err == nil {
+		t.Error("expected a duplicate slug to be rejected")
+	}
+	if got, _ := db.GetCustomMetric(ctx, 2, "pain"); got != nil {
+		t.Error("expected other user not to see the metric")
+	}
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []float64{4, 2, 6} {
+		e := domain.CustomMetricEvent{MetricID: m.ID, Value: v, CreatedAt: start.Add(time.Duration(i*12) * time.Hour)}
+		if _, created, err := db.AddCustomMetricEvent(ctx, 1, e); err != nil || !created {
+			t.Fatalf("AddCustomMetricEvent = %v, %v", created, err)
+		}
+	}
+	retry := domain.CustomMetricEvent{MetricID: m.ID, Value: 1, ClientID: "a", CreatedAt: start}
+	first, _, _ := db.AddCustomMetricEvent(ctx, 1, retry)
+	again, created, _ := db.AddCustomMetricEvent(ctx, 1, retry)
+	if created || again.ID != first.ID {
+		t.Errorf("expected a retried client ID to return event %d, got %d (created %v)", first.ID, again.ID, created)
+	}
+
+	day, _ := db.ListCustomMetricEventsBetween(ctx, 1, m.ID, start, start.Add(24*time.Hour))
+	if len(day) != 3 || day[0].Value != 4 || day[2].Value != 2 {
+		t.Errorf("expected the day's three events oldest first, got %+v", day)
+	}
+	recent, _ := db.ListRecentCustomMetricEvents(ctx, 1, m.ID, 2)
+	if len(recent) != 2 || recent[0].Value != 6 {
+		t.Errorf("expected the latest two events newest first, got %+v", recent)
+	}
+
+	if ok, _ := db.DeleteCustomMetric(ctx, 2, m.ID); ok {
+		t.Error("expected other user's delete to fail")
+	}
+	if ok, err := db.DeleteCustomMetric(ctx, 1, m.ID); err != nil || !ok {
+		t.Fatalf("DeleteCustomMetric = %v, %v", ok, err)
+	}
+	if events, _ := db.ListRecentCustomMetricEvents(ctx, 1, m.ID, 10); len(events) != 0 {
+		t.Errorf("expected the metric's events deleted with it, got %d", len(events))
+	}
+}
+
+func TestEachEvent(t *testing.T) {
+	db := New()
+	ctx := context.Background()
+
+	at := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		_, _ = db.AddWeightEvent(ctx, 1, 80+float64(i), "kg", at.Add(time.Duration(-i)*time.Minute))
+		_, _ = db.AddWaterEvent(ctx, 1, 0.25, at)
+	}
+	_, _ = db.AddWaterEvent(ctx, 2, 1, at)
+
+	var weights []float64
+	err := db.EachWeightEvent(ctx, 1, func(e domain.WeightEntry) error {
+		if e.Day == "" {
+			t.Errorf("expected the local day set, got %+v", e)
+		}
+		// The callback may use the database.
+		_, err := db.ListRecentWeightEvents(ctx, 1, 1)
+		weights = append(weights, e.Value)
+		return err
+	})
+	if err != nil || len(weights) != 3 || weights[0] != 82 || weights[2] != 80 {
+		t.Errorf("expected user 1's weigh-ins oldest first, got %v, %v", weights, err)
+	}
+
+	var ids []int64
+	stop := errors.New("stop")
+	err = db.EachWaterEvent(ctx, 1, func(e domain.WaterEvent) error {
+		ids = append(ids, e.ID)
+		if len(ids) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || len(ids) != 2 || ids[0] >= ids[1] {
+		t.Errorf("expected two events in ID order and the stop error, got %v, %v", ids, err)
+	}
 }
@@ -0,0 +1,113 @@
+// Package mqtt publishes metric events to an MQTT broker, e.g. for Home
+// Assistant automations or e-ink dashboards.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"vitals/internal/domain"
+)
+
+// Config holds broker connection settings.
+type Config struct {
+	// BrokerURL is the broker address, e.g. tcp://mqtt.local:1883.
+	BrokerURL string
+	Username  string
+	Password  string
+	ClientID  string
+	// TopicPrefix is prepended to every topic; defaults to "vitals".
+	TopicPrefix string
+}
+
+// Publisher implements domain.EventPublisher over MQTT.
+//
+// Each event goes to <prefix>/<userID>/weight or <prefix>/<userID>/water.
+// Water events additionally update the retained <prefix>/<userID>/water/today
+// topic with the running daily total so late subscribers see current state.
+// As a domain.Notifier it publishes alerts to <prefix>/<userID>/alerts.
+type Publisher struct {
+	client paho.Client
+	prefix string
+}
+
+var _ domain.EventPublisher = (*Publisher)(nil)
+var _ domain.Notifier = (*Publisher)(nil)
+
+// Connect dials the broker and returns a Publisher. The client reconnects
+// automatically after the initial connection succeeds.
+func Connect(cfg Config) (*Publisher, error) {
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "vitals"
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "vitals"
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(5 * time.Second)
+
+	client := paho.NewClient(opts)
+	tok := client.Connect()
+	if !tok.WaitTimeout(10 * time.Second) {
+		return nil, fmt.Errorf("mqtt: connect to %s timed out", cfg.BrokerURL)
+	}
+	if err := tok.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt: connect: %w", err)
+	}
+	return &Publisher{client: client, prefix: cfg.TopicPrefix}, nil
+}
+
+// Publish sends e without waiting for broker acknowledgement.
+func (p *Publisher) Publish(_ context.Context, e domain.MetricEvent) {
+	var metric string
+	switch e.Type {
+	case domain.EventWeightRecorded:
+		metric = "weight"
+	case domain.EventWaterRecorded:
+		metric = "water"
+	default:
+		return
+	}
+
+	base := fmt.Sprintf("%s/%d/%s", p.prefix, e.UserID, metric)
+	p.send(base, false, e)
+	if e.Type == domain.EventWaterRecorded {
+		p.send(base+"/today", true, map[string]any{"day": e.Day, "totalLiters": e.DayTotal})
+	}
+}
+
+// Notify publishes n to the user's alerts topic.
+func (p *Publisher) Notify(_ context.Context, n domain.Notification) error {
+	p.send(fmt.Sprintf("%s/%d/alerts", p.prefix, n.UserID), false, n)
+	return nil
+}
+
+func (p *Publisher) send(topic string, retained bool, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("mqtt: marshal %s: %v", topic, err)
+		return
+	}
+	tok := p.client.Publish(topic, 1, retained, payload)
+	go func() {
+		if tok.WaitTimeout(10*time.Second) && tok.Error() != nil {
+			log.Printf("mqtt: publish %s: %v", topic, tok.Error())
+		}
+	}()
+}
+
+// Close disconnects from the broker, allowing in-flight messages to drain.
+func (p *Publisher) Close() {
+	p.client.Disconnect(250)
+}
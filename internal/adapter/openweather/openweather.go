@@ -0,0 +1,141 @@
+// Package openweather implements domain.WeatherProvider with the
+// OpenWeather 5-day forecast API.
+package openweather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	defaultBaseURL = "https://api.openweathermap.org/data/2.5"
+	// cacheTTL is how long a location's forecast is reused; forecasts only
+	// update every few hours.
+	cacheTTL = time.Hour
+)
+
+// Client fetches and caches forecasts.
+type Client struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	weather domain.Weather
+	expires time.Time
+}
+
+var _ domain.WeatherProvider = (*Client)(nil)
+
+// New returns a Client authenticating with apiKey.
+func New(apiKey string) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		baseURL: defaultBaseURL,
+		http:    &http.Client{Timeout: 5 * time.Second},
+		now:     time.Now,
+		cache:   map[string]cached{},
+	}
+}
+
+// forecast is the subset of the /forecast response used here.
+type forecast struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			TempMax float64 `json:"temp_max"`
+		} `json:"main"`
+	} `json:"list"`
+	City struct {
+		// Timezone is the location's UTC offset in seconds.
+		Timezone int `json:"timezone"`
+	} `json:"city"`
+}
+
+// Current returns today's expected high at lat/lon: the maximum over the
+// remaining 3-hourly forecast slots of the location's local day.
+func (c *Client) Current(ctx context.Context, lat, lon float64) (*domain.Weather, error) {
+	// Round to ~1 km so nearby users share a cache entry.
+	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+	now := c.now()
+
+	c.mu.Lock()
+	if e, ok := c.cache[key]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		w := e.weather
+		return &w, nil
+	}
+	c.mu.Unlock()
+
+	q := url.Values{}
+	q.Set("lat", strconv.FormatFloat(lat, 'f', 4, 64))
+	q.Set("lon", strconv.FormatFloat(lon, 'f', 4, 64))
+	q.Set("units", "metric")
+	q.Set("appid", c.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/forecast?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openweather: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openweather: %s", resp.Status)
+	}
+
+	var f forecast
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		return nil, fmt.Errorf("openweather: decode: %w", err)
+	}
+	w, err := dailyHigh(f, now)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cached{weather: *w, expires: now.Add(cacheTTL)}
+	c.mu.Unlock()
+	return w, nil
+}
+
+// dailyHigh picks the highest temperature forecast for the location's
+// current local day, falling back to the next slot late in the evening.
+func dailyHigh(f forecast, now time.Time) (*domain.Weather, error) {
+	if len(f.List) == 0 {
+		return nil, errors.New("openweather: empty forecast")
+	}
+	loc := time.FixedZone("", f.City.Timezone)
+	today := now.In(loc).Format("2006-01-02")
+
+	found := false
+	var high float64
+	for _, slot := range f.List {
+		if time.Unix(slot.Dt, 0).In(loc).Format("2006-01-02") != today {
+			continue
+		}
+		if !found || slot.Main.TempMax > high {
+			high = slot.Main.TempMax
+			found = true
+		}
+	}
+	if !found {
+		high = f.List[0].Main.TempMax
+	}
+	return &domain.Weather{TempMaxC: high}, nil
+}
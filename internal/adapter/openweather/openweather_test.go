@@ -0,0 +1,45 @@
+package openweather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCurrent(t *testing.T) {
+	// 2026-07-01 in UTC+2; the 01:00 local slot belongs to the next day.
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Query().Get("appid") != "key" || r.URL.Query().Get("units") != "metric" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"city":{"timezone":7200},"list":[
+			{"dt":1782892800,"main":{"temp_max":24.0}},
+			{"dt":1782903600,"main":{"temp_max":31.5}},
+			{"dt":1782946800,"main":{"temp_max":35.0}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c := New("key")
+	c.baseURL = srv.URL
+	c.now = func() time.Time { return time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC) }
+
+	w, err := c.Current(context.Background(), 52.52, 13.405)
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if w.TempMaxC != 31.5 {
+		t.Errorf("expected today's high of 31.5, got %v", w.TempMaxC)
+	}
+	if _, err := c.Current(context.Background(), 52.521, 13.4049); err != nil {
+		t.Fatalf("cached Current failed: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected nearby lookups to hit the cache, got %d calls", calls.Load())
+	}
+}
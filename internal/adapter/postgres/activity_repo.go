@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// activityAfter restricts items whose timestamp, type and ID are the given
+// expressions to those after the cursor in $2, $3 and $4, if there is one.
+// Types and IDs compare bytewise, as in Go.
+func activityAfter(at, typ, id string) string {
+	return `($2::timestamptz IS NULL OR (` + at + `, ` + typ + ` COLLATE "C", ` + id + ` COLLATE "C") < ($2, $3::text, $4::text))`
+}
+
+// ListActivity returns up to limit of the user's activity items after
+// before, newest first, as one union over entries, goal changes and import
+// batches. Entries are limited per table first, so a page reads at most
+// limit rows of each.
+func (d *DB) ListActivity(ctx context.Context, userID int64, before *domain.ActivityCursor, limit int) ([]domain.Activity, error) {
+	var (
+		at       any
+		typ, cid string
+	)
+	if before != nil {
+		at, typ, cid = before.At.UTC(), before.Type, before.ID
+	}
+	out := make([]domain.Activity, 0, limit)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`WITH weights AS (
+				SELECT 'weight' AS type, created_at AS at, id::text AS id, value, unit, COALESCE(client_id, '') AS client_id,
+					NULL::double precision AS liters, NULL::double precision AS target_kg, 0 AS weights, 0 AS waters
+				FROM weight_events
+				WHERE user_id=$1 AND import_batch IS NULL AND `+activityAfter("created_at", "'weight'", "id::text")+`
+				ORDER BY created_at DESC, id::text COLLATE "C" DESC LIMIT $5
+			), waters AS (
+				SELECT 'water', created_at, id::text, NULL::double precision, '', COALESCE(client_id, ''),
+					delta_liters, NULL::double precision, 0, 0
+				FROM water_events
+				WHERE user_id=$1 AND import_batch IS NULL AND `+activityAfter("created_at", "'water'", "id::text")+`
+				ORDER BY created_at DESC, id::text COLLATE "C" DESC LIMIT $5
+			), imported AS (
+				SELECT 'weight' AS entity, id, import_batch, created_at FROM weight_events WHERE user_id=$1 AND import_batch IS NOT NULL
+				UNION ALL
+				SELECT 'water', id, import_batch, created_at FROM water_events WHERE user_id=$1 AND import_batch IS NOT NULL
+			), imports AS (
+				-- An import ran when its entries were first logged.
+				SELECT 'import', COALESCE(MIN(c.changed_at), MIN(i.created_at)), i.import_batch, NULL::double precision, '', '',
+					NULL::double precision, NULL::double precision,
+					COUNT(DISTINCT i.id) FILTER (WHERE i.entity = 'weight'), COUNT(DISTINCT i.id) FILTER (WHERE i.entity = 'water')
+				FROM imported i
+				LEFT JOIN changes c ON c.user_id=$1 AND c.entity=i.entity AND c.entity_id=i.id
+				GROUP BY i.import_batch
+			), goals AS (
+				-- Goals carried over from before history was kept have no day
+				-- of their own.
+				SELECT 'goal.water', day::timestamp AT TIME ZONE 'UTC', to_char(day, 'YYYY-MM-DD'), NULL::double precision, '', '',
+					liters, NULL::double precision, 0, 0
+				FROM hydration_goal_history WHERE user_id=$1 AND day > DATE '1970-01-01'
+				UNION ALL
+				SELECT 'goal.weight', day::timestamp AT TIME ZONE 'UTC', to_char(day, 'YYYY-MM-DD'), NULL::double precision, '', '',
+					NULL::double precision, target_kg, 0, 0
+				FROM weight_goal_history WHERE user_id=$1
+			), items AS (
+				SELECT * FROM weights
+				UNION ALL SELECT * FROM waters
+				UNION ALL SELECT * FROM imports
+				UNION ALL SELECT * FROM goals
+			)
+			SELECT type, at, id, value, unit, client_id, liters, target_kg, weights, waters
+			FROM items
+			WHERE `+activityAfter("at", "type", "id")+`
+			ORDER BY at DESC, type COLLATE "C" DESC, id COLLATE "C" DESC
+			LIMIT $5;`,
+			userID, at, typ, cid, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				a               domain.Activity
+				value, liters   *float64
+				targetKg        *float64
+				unit, clientID  string
+				weights, waters int
+			)
+			if err := rows.Scan(&a.Type, &a.At, &a.ID, &value, &unit, &clientID, &liters, &targetKg, &weights, &waters); err != nil {
+				return err
+			}
+			switch a.Type {
+			case domain.ActivityWeight:
+				a.Weight = &domain.WeightEntry{UserID: userID, Value: *value, Unit: unit, ClientID: clientID, CreatedAt: a.At}
+				a.Weight.ID, err = strconv.ParseInt(a.ID, 10, 64)
+				a.Weight.Day = a.At.In(time.Local).Format("2006-01-02")
+			case domain.ActivityWater:
+				a.Water = &domain.WaterEvent{UserID: userID, DeltaLiters: *liters, ClientID: clientID, CreatedAt: a.At}
+				a.Water.ID, err = strconv.ParseInt(a.ID, 10, 64)
+			case domain.ActivityImport:
+				a.Import = &domain.ImportActivity{Batch: a.ID, Weights: weights, Waters: waters}
+			case domain.ActivityWaterGoal:
+				a.WaterGoal = &domain.GoalChange{Day: a.ID, Liters: *liters}
+			case domain.ActivityWeightGoal:
+				a.WeightGoal = &domain.WeightGoalChange{Day: a.ID, TargetKg: targetKg}
+			}
+			if err != nil {
+				return err
+			}
+			out = append(out, a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
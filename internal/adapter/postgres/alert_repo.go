@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetAlertRule returns the user's alert rule, or nil.
+func (d *DB) GetAlertRule(ctx context.Context, userID int64) (*domain.AlertRule, error) {
+	r := domain.AlertRule{UserID: userID}
+	var last sql.NullTime
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT max_weekly_change_pct, channel, target, enabled, last_alerted_at FROM alert_rules WHERE user_id=$1;", userID,
+		).Scan(&r.MaxWeeklyChangePct, &r.Channel, &r.Target, &r.Enabled, &last)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Target, err = d.open(r.Target); err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		r.LastAlertedAt = &last.Time
+	}
+	return &r, nil
+}
+
+// SaveAlertRule creates or replaces the user's alert rule.
+func (d *DB) SaveAlertRule(ctx context.Context, rule domain.AlertRule) error {
+	var last sql.NullTime
+	if rule.LastAlertedAt != nil {
+		last = sql.NullTime{Time: *rule.LastAlertedAt, Valid: true}
+	}
+	target, err := d.seal(rule.Target)
+	if err != nil {
+		return err
+	}
+	return d.asUser(ctx, rule.UserID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO alert_rules (user_id, max_weekly_change_pct, channel, target, enabled, last_alerted_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id) DO UPDATE SET
+				max_weekly_change_pct = EXCLUDED.max_weekly_change_pct,
+				channel = EXCLUDED.channel,
+				target = EXCLUDED.target,
+				enabled = EXCLUDED.enabled,
+				last_alerted_at = EXCLUDED.last_alerted_at;`,
+			rule.UserID, rule.MaxWeeklyChangePct, rule.Channel, target, rule.Enabled, last)
+		return err
+	})
+}
+
+// ListAlertRules returns every enabled alert rule, ordered by user.
+func (d *DB) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	var out []domain.AlertRule
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT user_id, max_weekly_change_pct, channel, target, enabled, last_alerted_at FROM alert_rules WHERE enabled ORDER BY user_id;")
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				r    domain.AlertRule
+				last sql.NullTime
+			)
+			if err := rows.Scan(&r.UserID, &r.MaxWeeklyChangePct, &r.Channel, &r.Target, &r.Enabled, &last); err != nil {
+				return err
+			}
+			if r.Target, err = d.open(r.Target); err != nil {
+				return err
+			}
+			if last.Valid {
+				r.LastAlertedAt = &last.Time
+			}
+			out = append(out, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkAlerted records when the user was last alerted and queues the
+// notifications, in one transaction.
+func (d *DB) MarkAlerted(ctx context.Context, userID int64, at time.Time, queue ...domain.OutboxMessage) error {
+	return d.userTx(ctx, userID, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "UPDATE alert_rules SET last_alerted_at=$2 WHERE user_id=$1;", userID, at.UTC()); err != nil {
+			return err
+		}
+		return d.insertOutbox(ctx, q, queue)
+	})
+}
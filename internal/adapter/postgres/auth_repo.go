@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"vitals/internal/domain"
@@ -13,9 +14,9 @@ import (
 func (d *DB) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
 	var u domain.User
 	err := d.sql.QueryRowContext(ctx,
-		"SELECT id, username, password_hash, created_at FROM users WHERE username = $1",
+		"SELECT id, username, password_hash, COALESCE(email, ''), role, created_at FROM users WHERE username = $1",
 		username,
-	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
+	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -29,9 +30,9 @@ func (d *DB) GetByUsername(ctx context.Context, username string) (*domain.User,
 func (d *DB) GetByID(ctx context.Context, id int64) (*domain.User, error) {
 	var u domain.User
 	err := d.sql.QueryRowContext(ctx,
-		"SELECT id, username, password_hash, created_at FROM users WHERE id = $1",
+		"SELECT id, username, password_hash, COALESCE(email, ''), role, created_at FROM users WHERE id = $1",
 		id,
-	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
+	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -45,22 +46,203 @@ func (d *DB) GetByID(ctx context.Context, id int64) (*domain.User, error) {
 func (d *DB) Create(ctx context.Context, username, passwordHash string) (*domain.User, error) {
 	var u domain.User
 	err := d.sql.QueryRowContext(ctx,
-		"INSERT INTO users (username, password_hash, created_at) VALUES ($1, $2, $3) RETURNING id, username, password_hash, created_at",
+		"INSERT INTO users (username, password_hash, created_at) VALUES ($1, $2, $3) RETURNING id, username, password_hash, role, created_at",
 		username, passwordHash, time.Now(),
-	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
+	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-// Count returns the total number of users.
+// Count returns the total number of users, excluding profiles.
 func (d *DB) Count(ctx context.Context) (int, error) {
 	var count int
-	err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE owner_id IS NULL").Scan(&count)
 	return count, err
 }
 
+// UpdatePassword replaces a user's password hash.
+func (d *DB) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE users SET password_hash = $2 WHERE id = $1", userID, passwordHash)
+	return err
+}
+
+// RevocationEpoch returns the user's revocation epoch.
+func (d *DB) RevocationEpoch(ctx context.Context, userID int64) (int64, bool, error) {
+	var epoch int64
+	err := d.sql.QueryRowContext(ctx, "SELECT revocation_epoch FROM users WHERE id = $1", userID).Scan(&epoch)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return epoch, true, nil
+}
+
+// BumpRevocationEpoch moves the user's revocation epoch on.
+func (d *DB) BumpRevocationEpoch(ctx context.Context, userID int64) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE users SET revocation_epoch = revocation_epoch + 1 WHERE id = $1", userID)
+	return err
+}
+
+// DeleteUser removes the user and their profiles in one transaction. Weight
+// and water events and the change log do not cascade from users, so they
+// are deleted first; every other table cascades.
+func (d *DB) DeleteUser(ctx context.Context, userID int64) error {
+	return d.systemTx(ctx, func(q querier) error {
+		for _, stmt := range []string{
+			"DELETE FROM weight_events WHERE user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1);",
+			"DELETE FROM water_events WHERE user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1);",
+			"DELETE FROM changes WHERE user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1);",
+		} {
+			if _, err := q.ExecContext(ctx, stmt, userID); err != nil {
+				return err
+			}
+		}
+		res, err := q.ExecContext(ctx, "DELETE FROM users WHERE id = $1;", userID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("user not found")
+		}
+		return nil
+	})
+}
+
+// GetByEmail retrieves a user by verified email.
+func (d *DB) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var u domain.User
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT id, username, password_hash, email, role, created_at FROM users WHERE email = $1",
+		email,
+	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UpdateUsername renames a user; the unique constraint rejects taken names.
+func (d *DB) UpdateUsername(ctx context.Context, userID int64, username string) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE users SET username = $2 WHERE id = $1", userID, username)
+	return err
+}
+
+// SetEmail stores a user's verified email; the unique index rejects one
+// already in use.
+func (d *DB) SetEmail(ctx context.Context, userID int64, email string) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE users SET email = NULLIF($2, '') WHERE id = $1", userID, email)
+	return err
+}
+
+// SetRole sets a user's role.
+func (d *DB) SetRole(ctx context.Context, userID int64, role string) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE users SET role = $2 WHERE id = $1", userID, role)
+	return err
+}
+
+// ClaimAdmin makes a user an admin if no user is one yet. The advisory
+// lock serialises claims, so of two sign-ups racing on a fresh instance
+// exactly one is promoted.
+func (d *DB) ClaimAdmin(ctx context.Context, userID int64) (bool, error) {
+	var claimed bool
+	err := d.systemTx(ctx, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('vitals admin'))"); err != nil {
+			return err
+		}
+		res, err := q.ExecContext(ctx,
+			`UPDATE users SET role = 'admin'
+			WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin')`,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		claimed = n > 0
+		return err
+	})
+	return claimed, err
+}
+
+// SaveEmailChange stores a pending email change, replacing the user's last.
+func (d *DB) SaveEmailChange(ctx context.Context, c domain.EmailChange) error {
+	_, err := d.sql.ExecContext(ctx,
+		`INSERT INTO email_changes (user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET email = EXCLUDED.email, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at`,
+		c.UserID, c.Email, c.TokenHash, c.ExpiresAt,
+	)
+	return err
+}
+
+// GetEmailChange returns the user's pending email change, or nil.
+func (d *DB) GetEmailChange(ctx context.Context, userID int64) (*domain.EmailChange, error) {
+	c := domain.EmailChange{UserID: userID}
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT email, token_hash, expires_at FROM email_changes WHERE user_id = $1",
+		userID,
+	).Scan(&c.Email, &c.TokenHash, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// TakeEmailChange removes and returns the pending change with tokenHash.
+func (d *DB) TakeEmailChange(ctx context.Context, tokenHash string) (*domain.EmailChange, error) {
+	c := domain.EmailChange{TokenHash: tokenHash}
+	err := d.sql.QueryRowContext(ctx,
+		"DELETE FROM email_changes WHERE token_hash = $1 RETURNING user_id, email, expires_at",
+		tokenHash,
+	).Scan(&c.UserID, &c.Email, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetLinkedIdentity returns the link for issuer and subject, or nil.
+func (d *DB) GetLinkedIdentity(ctx context.Context, issuer, subject string) (*domain.LinkedIdentity, error) {
+	id := domain.LinkedIdentity{Issuer: issuer, Subject: subject}
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT user_id, email, linked_at FROM linked_identities WHERE issuer = $1 AND subject = $2",
+		issuer, subject,
+	).Scan(&id.UserID, &id.Email, &id.LinkedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// LinkIdentity stores a link, replacing any for the same identity.
+func (d *DB) LinkIdentity(ctx context.Context, id domain.LinkedIdentity) error {
+	_, err := d.sql.ExecContext(ctx,
+		`INSERT INTO linked_identities (issuer, subject, user_id, email, linked_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (issuer, subject) DO UPDATE SET user_id = EXCLUDED.user_id, email = EXCLUDED.email, linked_at = EXCLUDED.linked_at`,
+		id.Issuer, id.Subject, id.UserID, id.Email, id.LinkedAt,
+	)
+	return err
+}
+
 // SessionRepo implements session repository operations on DB.
 type SessionRepo struct {
 	db *DB
@@ -74,7 +256,7 @@ func NewSessionRepo(db *DB) *SessionRepo {
 // Create creates a new session.
 func (r *SessionRepo) Create(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
 	_, err := r.db.sql.ExecContext(ctx,
-		"INSERT INTO sessions (user_id, token, user_agent, ip, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		"INSERT INTO sessions (user_id, token, user_agent, ip, expires_at, created_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $6, $6)",
 		userID, token, userAgent, ip, expiresAt, time.Now(),
 	)
 	return err
@@ -84,9 +266,9 @@ func (r *SessionRepo) Create(ctx context.Context, userID int64, token, userAgent
 func (r *SessionRepo) GetByToken(ctx context.Context, token string) (*domain.Session, error) {
 	var s domain.Session
 	err := r.db.sql.QueryRowContext(ctx,
-		"SELECT token, user_id, user_agent, ip, expires_at, created_at FROM sessions WHERE token = $1",
+		"SELECT "+sessionColumns+" FROM sessions WHERE token = $1",
 		token,
-	).Scan(&s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.ExpiresAt, &s.CreatedAt)
+	).Scan(&s.ID, &s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.Name, &s.ExpiresAt, &s.CreatedAt, &s.LastSeenAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -107,3 +289,64 @@ func (r *SessionRepo) DeleteExpired(ctx context.Context) error {
 	_, err := r.db.sql.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < $1", time.Now())
 	return err
 }
+
+// sessionColumns selects a session in the order its callers scan it.
+const sessionColumns = "id, token, user_id, user_agent, ip, name, expires_at, created_at, COALESCE(last_seen_at, created_at)"
+
+// ListByUser returns the user's sessions, most recently seen first.
+func (r *SessionRepo) ListByUser(ctx context.Context, userID int64) ([]domain.Session, error) {
+	rows, err := r.db.sql.QueryContext(ctx,
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = $1 ORDER BY COALESCE(last_seen_at, created_at) DESC, id DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []domain.Session
+	for rows.Next() {
+		var s domain.Session
+		if err := rows.Scan(&s.ID, &s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.Name, &s.ExpiresAt, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Rename sets the name of one of the user's sessions.
+func (r *SessionRepo) Rename(ctx context.Context, userID, id int64, name string) (bool, error) {
+	res, err := r.db.sql.ExecContext(ctx, "UPDATE sessions SET name = $3 WHERE user_id = $1 AND id = $2", userID, id, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteByID revokes one of the user's sessions.
+func (r *SessionRepo) DeleteByID(ctx context.Context, userID, id int64) (bool, error) {
+	res, err := r.db.sql.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1 AND id = $2", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteByUser revokes all of the user's sessions.
+func (r *SessionRepo) DeleteByUser(ctx context.Context, userID int64) (int, error) {
+	res, err := r.db.sql.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Touch records activity on a session.
+func (r *SessionRepo) Touch(ctx context.Context, token string, at time.Time) error {
+	_, err := r.db.sql.ExecContext(ctx, "UPDATE sessions SET last_seen_at = $2 WHERE token = $1", token, at)
+	return err
+}
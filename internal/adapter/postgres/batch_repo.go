@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"vitals/internal/domain"
+)
+
+// ApplyBatch applies ops in order inside one transaction.
+func (d *DB) ApplyBatch(ctx context.Context, userID int64, ops []domain.BatchOp) ([]domain.BatchResult, error) {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if d.rls {
+		if err := setLocal(ctx, tx, "vitals.user_id", strconv.FormatInt(userID, 10)); err != nil {
+			return nil, err
+		}
+	}
+
+	q := traced{tx}
+	results := make([]domain.BatchResult, len(ops))
+	for i, op := range ops {
+		res := domain.BatchResult{Op: op.Op, ID: op.ID}
+		switch op.Op {
+		case domain.BatchOpWeight:
+			res.Weight, res.Created, err = insertWeight(ctx, q, userID, op.ClientID, op.Value, op.Unit, op.CreatedAt)
+			if err == nil {
+				res.ID = res.Weight.ID
+			}
+		case domain.BatchOpWater:
+			res.Water, res.Created, err = insertWater(ctx, q, userID, op.ClientID, op.DeltaLiters, op.CreatedAt)
+			if err == nil {
+				res.ID = res.Water.ID
+			}
+		case domain.BatchOpDeleteWeight:
+			res.Deleted, err = deleteWeight(ctx, q, userID, op.ID)
+		case domain.BatchOpDeleteWater:
+			res.Deleted, err = deleteWater(ctx, q, userID, op.ID)
+		default:
+			err = fmt.Errorf("unknown op %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+		results[i] = res
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
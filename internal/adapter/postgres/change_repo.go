@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ListChanges returns the latest change per entity after since, oldest first,
+// with the current row attached to upserts. A user's changes commit in seq
+// order (see migration 0020), so a change is never committed behind a seq
+// a client has already synced past.
+func (d *DB) ListChanges(ctx context.Context, userID, since int64, limit int) ([]domain.Change, error) {
+	var out []domain.Change
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT c.seq, c.entity, c.entity_id, c.op, c.changed_at,
+				w.value, w.unit, w.client_id, w.created_at,
+				a.delta_liters, a.client_id, a.created_at
+			FROM changes c
+			JOIN (
+				SELECT MAX(seq) AS seq FROM changes WHERE user_id=$1 AND seq > $2 GROUP BY entity, entity_id
+			) latest ON latest.seq = c.seq
+			LEFT JOIN weight_events w ON c.entity = 'weight' AND c.op = 'upsert' AND w.id = c.entity_id
+			LEFT JOIN water_events a ON c.entity = 'water' AND c.op = 'upsert' AND a.id = c.entity_id
+			ORDER BY c.seq
+			LIMIT $3;`,
+			userID, since, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				c            domain.Change
+				wValue       sql.NullFloat64
+				wUnit        sql.NullString
+				wClientID    sql.NullString
+				wCreatedAt   sql.NullTime
+				aDeltaLiters sql.NullFloat64
+				aClientID    sql.NullString
+				aCreatedAt   sql.NullTime
+			)
+			if err := rows.Scan(&c.Seq, &c.Entity, &c.EntityID, &c.Op, &c.ChangedAt,
+				&wValue, &wUnit, &wClientID, &wCreatedAt, &aDeltaLiters, &aClientID, &aCreatedAt); err != nil {
+				return err
+			}
+			if wValue.Valid {
+				c.Weight = &domain.WeightEntry{
+					ID: c.EntityID, UserID: userID, Value: wValue.Float64, Unit: wUnit.String, ClientID: wClientID.String,
+					CreatedAt: wCreatedAt.Time, Day: wCreatedAt.Time.In(time.Local).Format("2006-01-02"),
+				}
+			}
+			if aDeltaLiters.Valid {
+				c.Water = &domain.WaterEvent{
+					ID: c.EntityID, UserID: userID, DeltaLiters: aDeltaLiters.Float64, ClientID: aClientID.String, CreatedAt: aCreatedAt.Time,
+				}
+			}
+			out = append(out, c)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return checkCursor(ctx, q, userID, since)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// checkCursor fails with ErrCursorExpired if a deletion after since has been
+// purged. It runs after the changes are read, so a purge committing in
+// between can only make it refuse a cursor, never pass a stale one.
+func checkCursor(ctx context.Context, q querier, userID, since int64) error {
+	if since == 0 {
+		return nil
+	}
+	var purged int64
+	err := q.QueryRowContext(ctx, "SELECT changes_purged_seq FROM users WHERE id=$1;", userID).Scan(&purged)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if since < purged {
+		return domain.Errorf(domain.ErrCursorExpired, "cursor %d predates purged deletions; sync again from 0", since)
+	}
+	return nil
+}
+
+// LatestChange returns the user's most recent change to entity, or nil.
+func (d *DB) LatestChange(ctx context.Context, userID int64, entity string) (*domain.Change, error) {
+	var c domain.Change
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT seq, entity, entity_id, op, changed_at FROM changes WHERE user_id=$1 AND entity=$2 ORDER BY seq DESC LIMIT 1;",
+			userID, entity,
+		).Scan(&c.Seq, &c.Entity, &c.EntityID, &c.Op, &c.ChangedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"vitals/internal/domain"
+)
+
+// clusterChannel is the NOTIFY channel instances exchange messages on.
+const clusterChannel = "vitals_cluster"
+
+// maxNotifyPayload keeps messages under Postgres' 8000-byte NOTIFY limit.
+const maxNotifyPayload = 7900
+
+// Cluster implements domain.Cluster with LISTEN/NOTIFY, so instances behind
+// a load balancer share state through the database they already use.
+type Cluster struct {
+	db       *DB
+	listener *pq.Listener
+	// origin tags this instance's messages so it skips its own.
+	origin string
+
+	mu       sync.Mutex
+	handlers map[string][]func([]byte)
+}
+
+var _ domain.Cluster = (*Cluster)(nil)
+
+// clusterMessage is the NOTIFY payload.
+type clusterMessage struct {
+	Origin  string          `json:"origin"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewCluster listens for other instances' messages on a dedicated
+// connection to connStr, reconnecting as needed, until Close.
+func NewCluster(db *DB, connStr string) (*Cluster, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	c := &Cluster{db: db, origin: hex.EncodeToString(b), handlers: map[string][]func([]byte){}}
+	c.listener = pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("cluster: listener %v: %v", ev, err)
+		}
+	})
+	if err := c.listener.Listen(clusterChannel); err != nil {
+		_ = c.listener.Close()
+		return nil, fmt.Errorf("cluster: listen: %w", err)
+	}
+	go c.dispatch()
+	return c, nil
+}
+
+// Close stops listening.
+func (c *Cluster) Close() error {
+	return c.listener.Close()
+}
+
+// Publish sends payload, which must be JSON, to the other instances.
+func (c *Cluster) Publish(ctx context.Context, topic string, payload []byte) error {
+	msg, err := json.Marshal(clusterMessage{Origin: c.origin, Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+	if len(msg) > maxNotifyPayload {
+		return fmt.Errorf("cluster: %s message of %d bytes exceeds the NOTIFY limit", topic, len(msg))
+	}
+	_, err = c.db.sql.ExecContext(ctx, "SELECT pg_notify($1, $2)", clusterChannel, string(msg))
+	return err
+}
+
+// Subscribe registers fn for topic.
+func (c *Cluster) Subscribe(topic string, fn func(payload []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[topic] = append(c.handlers[topic], fn)
+}
+
+// dispatch delivers notifications until the listener closes. A nil
+// notification marks a reconnect, after which missed messages are gone.
+func (c *Cluster) dispatch() {
+	for n := range c.listener.Notify {
+		if n == nil {
+			continue
+		}
+		var msg clusterMessage
+		if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil || msg.Origin == c.origin {
+			continue
+		}
+		c.mu.Lock()
+		handlers := c.handlers[msg.Topic]
+		c.mu.Unlock()
+		for _, fn := range handlers {
+			fn(msg.Payload)
+		}
+	}
+}
+
+// TryLock takes the cluster-wide advisory lock name on a connection of its
+// own, reporting false if another instance holds it. Call unlock to release
+// it; the lock also goes away if the process dies.
+func (d *DB) TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error) {
+	conn, err := d.sql.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&ok); err != nil || !ok {
+		_ = conn.Close()
+		return nil, false, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", name)
+		_ = conn.Close()
+	}, true, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// Cipher encrypts sensitive column values. It is satisfied by
+// *fieldcrypt.Keyring.
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(value string) (string, error)
+	NeedsRotation(value string) bool
+}
+
+// WithCipher enables encryption at rest for sensitive columns: profile
+// names, alert notification targets, journal notes, food descriptions,
+// mood notes, medication names and doses, integration tokens, and webhook
+// URLs and secrets. Existing plaintext stays readable until
+// RotateEncryption rewrites it.
+func (d *DB) WithCipher(c Cipher) *DB {
+	d.cipher = c
+	return d
+}
+
+// seal encrypts a value for storage; it is a no-op without a cipher.
+func (d *DB) seal(s string) (string, error) {
+	if d.cipher == nil {
+		return s, nil
+	}
+	return d.cipher.Encrypt(s)
+}
+
+// open decrypts a stored value; it is a no-op without a cipher.
+func (d *DB) open(s string) (string, error) {
+	if d.cipher == nil {
+		return s, nil
+	}
+	return d.cipher.Decrypt(s)
+}
+
+// encryptedColumns lists the columns holding sealed values, keyed by a
+// single-column primary key.
+var encryptedColumns = []struct{ table, key, column string }{
+	{"users", "id", "display_name"},
+	{"alert_rules", "user_id", "target"},
+	{"journal_entries", "id", "note"},
+	{"user_rules", "id", "target"},
+	{"food_entries", "id", "description"},
+	{"mood_entries", "id", "note"},
+	{"medications", "id", "name"},
+	{"medications", "id", "dose"},
+	{"oauth_tokens", "id", "access_token"},
+	{"oauth_tokens", "id", "refresh_token"},
+	{"notification_outbox", "id", "target"},
+	{"webhooks", "id", "url"},
+	{"webhooks", "id", "secret"},
+	{"planned_entries", "id", "note"},
+	{"planned_entries", "id", "target"},
+}
+
+// RotateEncryption re-encrypts every sensitive value that is still plaintext
+// or sealed with a non-primary key, and returns the number of rows
+// rewritten. Run it after adding a new primary key and before removing the
+// old one. Profile usernames that still embed the profile name are replaced
+// with opaque ones.
+func (d *DB) RotateEncryption(ctx context.Context) (int64, error) {
+	if d.cipher == nil {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+	var total int64
+	err := d.asSystem(ctx, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			"UPDATE users SET username = owner_id || '/profile-' || id WHERE owner_id IS NOT NULL AND username NOT LIKE owner_id || '/profile-%';")
+		if err != nil {
+			return fmt.Errorf("rename profiles: %w", err)
+		}
+		if total, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		for _, col := range encryptedColumns {
+			n, err := d.rotateColumn(ctx, q, col.table, col.key, col.column)
+			if err != nil {
+				return fmt.Errorf("rotate %s.%s: %w", col.table, col.column, err)
+			}
+			total += n
+		}
+		return nil
+	})
+	return total, err
+}
+
+func (d *DB) rotateColumn(ctx context.Context, q querier, table, key, column string) (int64, error) {
+	//nolint:gosec // identifiers come from encryptedColumns, not user input
+	rows, err := q.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s <> '';", key, column, table, column, column))
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		id    int64
+		value string
+	}
+	var stale []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if d.cipher.NeedsRotation(p.value) {
+			stale = append(stale, p)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, p := range stale {
+		plain, err := d.cipher.Decrypt(p.value)
+		if err != nil {
+			return n, fmt.Errorf("row %d: %w", p.id, err)
+		}
+		sealed, err := d.cipher.Encrypt(plain)
+		if err != nil {
+			return n, err
+		}
+		// Compare-and-swap so a concurrent write is not overwritten.
+		//nolint:gosec // identifiers come from encryptedColumns, not user input
+		res, err := q.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET %s=$1 WHERE %s=$2 AND %s=$3;", table, column, key, column),
+			sealed, p.id, p.value)
+		if err != nil {
+			return n, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return n, err
+		}
+		n += affected
+	}
+	return n, nil
+}
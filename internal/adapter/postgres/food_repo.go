@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// AddFoodEntry inserts a food entry unless the user has already stored one
+// with the same non-empty client ID, in which case the existing row is
+// returned.
+func (d *DB) AddFoodEntry(ctx context.Context, userID int64, e domain.FoodEntry) (*domain.FoodEntry, bool, error) {
+	description, err := d.seal(e.Description)
+	if err != nil {
+		return nil, false, err
+	}
+	var (
+		out     = domain.FoodEntry{UserID: userID, ClientID: e.ClientID}
+		created bool
+	)
+	err = d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO food_entries(user_id, client_id, description, kcal, protein_g, carbs_g, fat_g, created_at)
+				VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id, description, kcal, protein_g, carbs_g, fat_g, created_at
+			)
+			SELECT id, description, kcal, protein_g, carbs_g, fat_g, created_at, true FROM ins
+			UNION ALL
+			SELECT id, description, kcal, protein_g, carbs_g, fat_g, created_at, false FROM food_entries WHERE user_id=$1 AND client_id=$2;`,
+			userID, nullString(e.ClientID), description, e.Kcal, e.ProteinG, e.CarbsG, e.FatG, e.CreatedAt.UTC(),
+		).Scan(&out.ID, &out.Description, &out.Kcal, &out.ProteinG, &out.CarbsG, &out.FatG, &out.CreatedAt, &created)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if out.Description, err = d.open(out.Description); err != nil {
+		return nil, false, err
+	}
+	return &out, created, nil
+}
+
+// DeleteFoodEntry removes a food entry by ID, scoped to a user.
+func (d *DB) DeleteFoodEntry(ctx context.Context, userID int64, id int64) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx, "DELETE FROM food_entries WHERE id=$1 AND user_id=$2;", id, userID)
+		return err
+	})
+}
+
+// DeleteLatestFoodEntry removes the user's most recent food entry in one
+// statement, locking the row as DeleteLatestWeightEvent does, and returns
+// its ID, or 0 if there was none or a concurrent undo deleted it first.
+func (d *DB) DeleteLatestFoodEntry(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`DELETE FROM food_entries WHERE id IN (
+				SELECT id FROM food_entries WHERE user_id=$1
+				ORDER BY created_at DESC, id DESC LIMIT 1 FOR UPDATE
+			) RETURNING id;`,
+			userID,
+		).Scan(&id)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// ListRecentFoodEntries returns the most recent food entries up to limit for
+// a user.
+func (d *DB) ListRecentFoodEntries(ctx context.Context, userID int64, limit int) ([]domain.FoodEntry, error) {
+	out := make([]domain.FoodEntry, 0, limit)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT id, description, kcal, protein_g, carbs_g, fat_g, COALESCE(client_id, ''), created_at
+			FROM food_entries WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;`, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.FoodEntry{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.Description, &e.Kcal, &e.ProteinG, &e.CarbsG, &e.FatG, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			if e.Description, err = d.open(e.Description); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FoodTotalsForLocalDay sums a user's food entries on a local calendar day.
+func (d *DB) FoodTotalsForLocalDay(ctx context.Context, userID int64, localDay string) (domain.NutritionTotals, error) {
+	var t domain.NutritionTotals
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
+	if err != nil {
+		return t, err
+	}
+
+	err = d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`SELECT COALESCE(SUM(kcal), 0), COALESCE(SUM(protein_g), 0), COALESCE(SUM(carbs_g), 0), COALESCE(SUM(fat_g), 0), COUNT(*)
+			FROM food_entries WHERE user_id=$1 AND created_at >= $2 AND created_at < $3;`,
+			userID, dayStart.UTC(), dayEnd.UTC(),
+		).Scan(&t.Kcal, &t.ProteinG, &t.CarbsG, &t.FatG, &t.Entries)
+	})
+	return t, err
+}
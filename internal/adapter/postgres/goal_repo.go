@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// RecordWeightGoal sets the target weight in effect from day.
+func (d *DB) RecordWeightGoal(ctx context.Context, userID int64, day string, targetKg *float64) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO weight_goal_history (user_id, day, target_kg) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, day) DO UPDATE SET target_kg = EXCLUDED.target_kg;`,
+			userID, day, targetKg)
+		return err
+	})
+}
+
+// WeightGoalHistory returns the user's target weight changes, oldest first.
+func (d *DB) WeightGoalHistory(ctx context.Context, userID int64) (domain.WeightGoalHistory, error) {
+	var out domain.WeightGoalHistory
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT day, target_kg FROM weight_goal_history WHERE user_id=$1 ORDER BY day;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				c   domain.WeightGoalChange
+				day time.Time
+			)
+			if err := rows.Scan(&day, &c.TargetKg); err != nil {
+				return err
+			}
+			c.Day = day.Format("2006-01-02")
+			out = append(out, c)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetHydrationSettings returns the user's hydration settings, or nil.
+func (d *DB) GetHydrationSettings(ctx context.Context, userID int64) (*domain.HydrationSettings, error) {
+	hs := domain.HydrationSettings{UserID: userID}
+	var lat, lon sql.NullFloat64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT base_goal_liters, latitude, longitude FROM hydration_settings WHERE user_id=$1;", userID,
+		).Scan(&hs.BaseGoalLiters, &lat, &lon)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lat.Valid && lon.Valid {
+		hs.Latitude, hs.Longitude = &lat.Float64, &lon.Float64
+	}
+	return &hs, nil
+}
+
+// SaveHydrationSettings creates or replaces the user's hydration settings.
+func (d *DB) SaveHydrationSettings(ctx context.Context, hs domain.HydrationSettings) error {
+	return d.asUser(ctx, hs.UserID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO hydration_settings (user_id, base_goal_liters, latitude, longitude) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET
+				base_goal_liters = EXCLUDED.base_goal_liters,
+				latitude = EXCLUDED.latitude,
+				longitude = EXCLUDED.longitude;`,
+			hs.UserID, hs.BaseGoalLiters, hs.Latitude, hs.Longitude)
+		return err
+	})
+}
+
+// RecordGoal sets the goal in effect from day.
+func (d *DB) RecordGoal(ctx context.Context, userID int64, day string, liters float64) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO hydration_goal_history (user_id, day, liters) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, day) DO UPDATE SET liters = EXCLUDED.liters;`,
+			userID, day, liters)
+		return err
+	})
+}
+
+// GoalHistory returns the user's goal changes, oldest first.
+func (d *DB) GoalHistory(ctx context.Context, userID int64) (domain.GoalHistory, error) {
+	var out domain.GoalHistory
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT day, liters FROM hydration_goal_history WHERE user_id=$1 ORDER BY day;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				c   domain.GoalChange
+				day time.Time
+			)
+			if err := rows.Scan(&day, &c.Liters); err != nil {
+				return err
+			}
+			c.Day = day.Format("2006-01-02")
+			out = append(out, c)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
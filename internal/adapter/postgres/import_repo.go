@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ImportWeightEvent stores a weight event as part of an import batch unless
+// the user already has one with the same timestamp, value and unit, or with
+// the same client ID.
+func (d *DB) ImportWeightEvent(ctx context.Context, userID int64, batchID, clientID string, value float64, unit string, createdAt time.Time) (bool, error) {
+	var n int
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO weight_events(user_id, client_id, value, unit, created_at, import_batch)
+				SELECT $1, $2, $3, $4, $5, $6
+				WHERE NOT EXISTS (SELECT 1 FROM weight_events WHERE user_id=$1 AND created_at=$5 AND value=$3 AND unit=$4)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id
+			), log AS (
+				INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+				SELECT $1, 'weight', id, 'upsert', now() FROM ins
+			)
+			SELECT COUNT(*) FROM ins;`,
+			userID, nullString(clientID), value, unit, createdAt.UTC(), batchID,
+		).Scan(&n)
+	})
+	return n > 0, err
+}
+
+// ImportWaterEvent stores a water event as part of an import batch unless
+// the user already has one with the same timestamp and amount, or with the
+// same client ID.
+func (d *DB) ImportWaterEvent(ctx context.Context, userID int64, batchID, clientID string, deltaLiters float64, createdAt time.Time) (bool, error) {
+	var n int
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO water_events(user_id, client_id, delta_liters, created_at, import_batch)
+				SELECT $1, $2, $3, $4, $5
+				WHERE NOT EXISTS (SELECT 1 FROM water_events WHERE user_id=$1 AND created_at=$4 AND delta_liters=$3)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id
+			), log AS (
+				INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+				SELECT $1, 'water', id, 'upsert', now() FROM ins
+			)
+			SELECT COUNT(*) FROM ins;`,
+			userID, nullString(clientID), deltaLiters, createdAt.UTC(), batchID,
+		).Scan(&n)
+	})
+	return n > 0, err
+}
+
+// DeleteImportBatch removes the user's events imported as part of batchID,
+// with their tags, and logs a delete for each.
+func (d *DB) DeleteImportBatch(ctx context.Context, userID int64, batchID string) (int, error) {
+	var total int64
+	err := d.userTx(ctx, userID, func(q querier) error {
+		for _, entity := range []string{domain.ChangeEntityWeight, domain.ChangeEntityWater} {
+			res, err := q.ExecContext(ctx,
+				`WITH del AS (
+					DELETE FROM `+entryTables[entity]+` WHERE user_id=$1 AND import_batch=$2 RETURNING id
+				), untag AS (
+					DELETE FROM entry_tags WHERE entity=$3 AND entry_id IN (SELECT id FROM del)
+				)
+				INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+				SELECT $1, $3, id, 'delete', now() FROM del;`,
+				userID, batchID, entity)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			total += n
+		}
+		return nil
+	})
+	return int(total), err
+}
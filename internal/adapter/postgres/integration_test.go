@@ -0,0 +1,319 @@
+//go:build integration
+
+// The integration suite runs the repositories against a real Postgres. It is
+// left out of the default `go test ./...`; run it with
+//
+//	make test-integration
+//
+// which starts a disposable postgres container through docker and removes it
+// afterwards. Set VITALS_TEST_POSTGRES_URL to use an existing server instead;
+// the suite creates and drops its own databases and role there, so point it
+// at a superuser connection on a scratch server. VITALS_TEST_POSTGRES_IMAGE
+// overrides the container image.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	defaultTestImage = "postgres:17-alpine"
+	// testRole owns the per-test databases. It is an ordinary role, so
+	// row-level security applies to it; superusers bypass every policy.
+	testRole     = "vitals_test"
+	testPassword = "vitals_test"
+)
+
+var (
+	// adminURL is a superuser connection used to create and drop the
+	// per-test databases.
+	adminURL string
+	dbSeq    atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	// A local zone away from UTC makes the day-boundary tests meaningful:
+	// local midnight is not UTC midnight.
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+
+	adminURL = os.Getenv("VITALS_TEST_POSTGRES_URL")
+	stop := func() {}
+	if adminURL == "" {
+		var err error
+		if adminURL, stop, err = startPostgres(); err != nil {
+			fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := createTestRole(); err != nil {
+		stop()
+		fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startPostgres runs a throwaway postgres container on a random local port
+// and waits for it to accept connections.
+func startPostgres() (connStr string, stop func(), err error) {
+	image := os.Getenv("VITALS_TEST_POSTGRES_IMAGE")
+	if image == "" {
+		image = defaultTestImage
+	}
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=postgres",
+		"-p", "127.0.0.1::5432",
+		image).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("start %s (is docker running?): %w", image, err)
+	}
+	id := strings.TrimSpace(string(out))
+	stop = func() { _ = exec.Command("docker", "rm", "-f", id).Run() }
+
+	out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("find the postgres port: %w", err)
+	}
+	// docker port may list an IPv6 binding too; the first line is enough.
+	hostPort, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	connStr = fmt.Sprintf("postgres://postgres:postgres@%s/postgres?sslmode=disable", hostPort)
+
+	deadline := time.Now().Add(time.Minute)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err = Ping(ctx, connStr)
+		cancel()
+		if err == nil {
+			return connStr, stop, nil
+		}
+		if time.Now().After(deadline) {
+			stop()
+			return "", nil, fmt.Errorf("postgres did not come up: %w", err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func adminExec(query string) error {
+	db, err := sql.Open("postgres", adminURL)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
+	return err
+}
+
+func createTestRole() error {
+	return adminExec(fmt.Sprintf(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '%[1]s') THEN
+			CREATE ROLE %[1]s LOGIN PASSWORD '%[2]s';
+		END IF;
+	END $$;`, testRole, testPassword))
+}
+
+// newTestDB creates an empty database owned by testRole, opens it (which
+// applies every migration) and drops it when the test ends.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	connStr := createTestDatabase(t)
+	d, err := Open(connStr)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+// createTestDatabase creates an empty database owned by testRole, returns a
+// connection string for it and drops it when the test ends.
+func createTestDatabase(t *testing.T) string {
+	t.Helper()
+	name := fmt.Sprintf("vitals_it_%d_%d", os.Getpid(), dbSeq.Add(1))
+	if err := adminExec(fmt.Sprintf("CREATE DATABASE %s OWNER %s;", name, testRole)); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := adminExec(fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE);", name)); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+	})
+
+	u, err := url.Parse(adminURL)
+	if err != nil {
+		t.Fatalf("parse VITALS_TEST_POSTGRES_URL: %v", err)
+	}
+	u.User = url.UserPassword(testRole, testPassword)
+	u.Path = "/" + name
+	return u.String()
+}
+
+// newTestUser creates a login user and fails the test on error.
+func newTestUser(t *testing.T, d *DB, username string) int64 {
+	t.Helper()
+	u, err := d.Create(context.Background(), username, "hash")
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return u.ID
+}
+
+// localTime returns the wall-clock time on day (YYYY-MM-DD) in time.Local.
+func localTime(t *testing.T, day string, hour, minute, sec, nsec int) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, minute, sec, nsec, time.Local)
+}
+
+func TestIntegrationMigrations(t *testing.T) {
+	ctx := context.Background()
+	connStr := createTestDatabase(t)
+	d, err := Connect(connStr)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	all, err := migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.PendingMigrations(ctx); err != nil || n != len(all) {
+		t.Fatalf("expected %d pending migrations on an empty database, got %d, %v", len(all), n, err)
+	}
+	if applied, err := d.Migrate(ctx); err != nil || len(applied) != len(all) {
+		t.Fatalf("migrate: applied %d of %d, %v", len(applied), len(all), err)
+	}
+	if applied, err := d.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("expected a second migrate to be a no-op, got %d, %v", len(applied), err)
+	}
+
+	// Every down migration must undo its up migration cleanly enough for
+	// the up migration to run again.
+	if rolled, err := d.Rollback(ctx, len(all)); err != nil || len(rolled) != len(all) {
+		t.Fatalf("rollback: rolled back %d of %d, %v", len(rolled), len(all), err)
+	}
+	if _, err := d.Migrate(ctx); err != nil {
+		t.Fatalf("migrate after rollback: %v", err)
+	}
+	status, err := d.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Errorf("expected migration %04d_%s to be applied", s.Version, s.Name)
+		}
+	}
+}
+
+func TestIntegrationRowLevelSecurity(t *testing.T) {
+	ctx := context.Background()
+	connStr := createTestDatabase(t)
+	d, err := Open(connStr)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	if err := d.SetRowLevelSecurity(ctx, true); err != nil {
+		t.Fatalf("enable row-level security: %v", err)
+	}
+	if !d.RowLevelSecurity() {
+		t.Fatal("expected row-level security to be reported on")
+	}
+	if _, err := d.AddWeightEvent(ctx, alice, 70, "kg", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddWaterEvent(ctx, bob, 0.5, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A query that forgets its user scope sees nothing rather than
+	// everyone's rows.
+	var n int
+	if err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM weight_events;").Scan(&n); err != nil || n != 0 {
+		t.Errorf("expected an unscoped read to see no rows, got %d, %v", n, err)
+	}
+	// Even with the wrong WHERE clause, a user-scoped connection only
+	// reaches its own rows.
+	err = d.asUser(ctx, bob, func(q querier) error {
+		return q.QueryRowContext(ctx, "SELECT COUNT(*) FROM weight_events WHERE user_id=$1;", alice).Scan(&n)
+	})
+	if err != nil || n != 0 {
+		t.Errorf("expected bob to see none of alice's weights, got %d, %v", n, err)
+	}
+	if items, err := d.ListRecentWeightEvents(ctx, alice, 10); err != nil || len(items) != 1 {
+		t.Errorf("expected alice to see her weight, got %v, %v", items, err)
+	}
+	// Cross-user maintenance paths still span everyone.
+	if ids, err := d.ListActiveUserIDs(ctx, time.Now().Add(-time.Hour)); err != nil || len(ids) != 2 {
+		t.Errorf("expected both users active, got %v, %v", ids, err)
+	}
+
+	// The mode persists in the schema for later connections.
+	d2, err := Connect(connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = d2.Close() }()
+	if !d2.RowLevelSecurity() {
+		t.Error("expected a new connection to detect row-level security")
+	}
+
+	// Deleting an account spans its profiles, so it bypasses the policy.
+	if err := d.DeleteUser(ctx, bob); err != nil {
+		t.Errorf("expected bob's account deleted, got %v", err)
+	}
+
+	if err := d.SetRowLevelSecurity(ctx, false); err != nil {
+		t.Fatalf("disable row-level security: %v", err)
+	}
+	if err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM weight_events;").Scan(&n); err != nil || n != 1 {
+		t.Errorf("expected unscoped reads to work again, got %d, %v", n, err)
+	}
+}
+
+func TestIntegrationTryLock(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+
+	unlock, ok, err := d.TryLock(ctx, "maintenance")
+	if err != nil || !ok {
+		t.Fatalf("expected the lock, got %v, %v", ok, err)
+	}
+	if _, ok, err := d.TryLock(ctx, "maintenance"); err != nil || ok {
+		t.Fatalf("expected a second holder to be refused, got %v, %v", ok, err)
+	}
+	if other, ok, err := d.TryLock(ctx, "other"); err != nil || !ok {
+		t.Fatalf("expected an unrelated lock to be free, got %v, %v", ok, err)
+	} else {
+		other()
+	}
+	unlock()
+	relock, ok, err := d.TryLock(ctx, "maintenance")
+	if err != nil || !ok {
+		t.Fatalf("expected the lock after unlock, got %v, %v", ok, err)
+	}
+	relock()
+}
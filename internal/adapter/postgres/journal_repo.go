@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetJournalEntry returns the note for day, or nil.
+func (d *DB) GetJournalEntry(ctx context.Context, userID int64, day string) (*domain.JournalEntry, error) {
+	e := domain.JournalEntry{Day: day}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT note, updated_at FROM journal_entries WHERE user_id=$1 AND day=$2;", userID, day,
+		).Scan(&e.Note, &e.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Note, err = d.open(e.Note); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveJournalEntry creates or replaces the note for e.Day.
+func (d *DB) SaveJournalEntry(ctx context.Context, userID int64, e domain.JournalEntry) error {
+	note, err := d.seal(e.Note)
+	if err != nil {
+		return err
+	}
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO journal_entries (user_id, day, note, updated_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, day) DO UPDATE SET note = EXCLUDED.note, updated_at = EXCLUDED.updated_at;`,
+			userID, e.Day, note, e.UpdatedAt.UTC())
+		return err
+	})
+}
+
+// DeleteJournalEntry removes the note for day.
+func (d *DB) DeleteJournalEntry(ctx context.Context, userID int64, day string) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM journal_entries WHERE user_id=$1 AND day=$2;", userID, day)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// ListJournalEntries returns the notes for days from through to, oldest
+// first.
+func (d *DB) ListJournalEntries(ctx context.Context, userID int64, from, to string) ([]domain.JournalEntry, error) {
+	out := []domain.JournalEntry{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT day, note, updated_at FROM journal_entries WHERE user_id=$1 AND day BETWEEN $2 AND $3 ORDER BY day;",
+			userID, from, to)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				e   domain.JournalEntry
+				day time.Time
+			)
+			if err := rows.Scan(&day, &e.Note, &e.UpdatedAt); err != nil {
+				return err
+			}
+			e.Day = day.Format("2006-01-02")
+			if e.Note, err = d.open(e.Note); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// CountExpiredSessions returns the number of sessions past their expiry.
+func (d *DB) CountExpiredSessions(ctx context.Context) (int64, error) {
+	var n int64
+	err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions WHERE expires_at < $1;", time.Now()).Scan(&n)
+	return n, err
+}
+
+// PurgeExpiredSessions deletes expired sessions and returns how many were removed.
+func (d *DB) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := d.sql.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < $1;", time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CountOrphanedEvents counts events without an owning user.
+func (d *DB) CountOrphanedEvents(ctx context.Context) (domain.OrphanCounts, error) {
+	var c domain.OrphanCounts
+	err := d.asSystem(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`SELECT
+				(SELECT COUNT(*) FROM weight_events e WHERE e.user_id IS NULL OR NOT EXISTS (SELECT 1 FROM users u WHERE u.id = e.user_id)),
+				(SELECT COUNT(*) FROM water_events e WHERE e.user_id IS NULL OR NOT EXISTS (SELECT 1 FROM users u WHERE u.id = e.user_id));`,
+		).Scan(&c.WeightEvents, &c.WaterEvents)
+	})
+	return c, err
+}
+
+// oldChanges selects the change log entries before $1 that a full sync no
+// longer needs: deletions, and changes superseded by a later one.
+const oldChanges = `changes c WHERE c.changed_at < $1 AND (c.op = 'delete' OR EXISTS (
+	SELECT 1 FROM changes n WHERE n.user_id = c.user_id AND n.entity = c.entity AND n.entity_id = c.entity_id AND n.seq > c.seq))`
+
+// CountOldChanges counts the change log entries PurgeOldChanges would remove.
+func (d *DB) CountOldChanges(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := d.asSystem(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+oldChanges+";", before).Scan(&n)
+	})
+	return n, err
+}
+
+// PurgeOldChanges deletes the change log entries before before that a full
+// sync no longer needs, and moves each user's purge horizon past the
+// deletions removed, in one statement.
+func (d *DB) PurgeOldChanges(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := d.asSystem(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH gone AS (
+				DELETE FROM `+oldChanges+` RETURNING c.user_id, c.seq, c.op
+			), horizon AS (
+				UPDATE users u SET changes_purged_seq = GREATEST(u.changes_purged_seq, p.seq)
+				FROM (SELECT user_id, MAX(seq) AS seq FROM gone WHERE op = 'delete' GROUP BY user_id) p
+				WHERE u.id = p.user_id
+			)
+			SELECT COUNT(*) FROM gone;`,
+			before,
+		).Scan(&n)
+	})
+	return n, err
+}
+
+// Vacuum runs VACUUM ANALYZE over the whole database.
+func (d *DB) Vacuum(ctx context.Context) error {
+	_, err := d.sql.ExecContext(ctx, "VACUUM ANALYZE;")
+	return err
+}
@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"vitals/internal/domain"
+)
+
+const medicationColumns = "id, user_id, name, dose, schedule, created_at"
+
+// CreateMedication stores a new medication and returns it with its ID.
+func (d *DB) CreateMedication(ctx context.Context, m domain.Medication) (*domain.Medication, error) {
+	name, dose, err := d.sealMedication(m)
+	if err != nil {
+		return nil, err
+	}
+	err = d.asUser(ctx, m.UserID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`INSERT INTO medications (user_id, name, dose, schedule, created_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id;`,
+			m.UserID, name, dose, pq.Array(schedule(m.Schedule)), m.CreatedAt.UTC(),
+		).Scan(&m.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMedication returns the user's medication with id, or nil.
+func (d *DB) GetMedication(ctx context.Context, userID, id int64) (*domain.Medication, error) {
+	var m *domain.Medication
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		m, err = d.scanMedication(q.QueryRowContext(ctx,
+			"SELECT "+medicationColumns+" FROM medications WHERE user_id=$1 AND id=$2;", userID, id))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// ListMedications returns the user's medications, oldest first.
+func (d *DB) ListMedications(ctx context.Context, userID int64) ([]domain.Medication, error) {
+	out := []domain.Medication{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+medicationColumns+" FROM medications WHERE user_id=$1 ORDER BY id;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			m, err := d.scanMedication(rows)
+			if err != nil {
+				return err
+			}
+			out = append(out, *m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateMedication replaces a medication's name, dose and schedule.
+func (d *DB) UpdateMedication(ctx context.Context, m domain.Medication) (bool, error) {
+	name, dose, err := d.sealMedication(m)
+	if err != nil {
+		return false, err
+	}
+	var n int64
+	err = d.asUser(ctx, m.UserID, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			"UPDATE medications SET name=$3, dose=$4, schedule=$5 WHERE user_id=$1 AND id=$2;",
+			m.UserID, m.ID, name, dose, pq.Array(schedule(m.Schedule)))
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// DeleteMedication removes one of a user's medications; its events go with
+// it through the foreign key.
+func (d *DB) DeleteMedication(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM medications WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// AddMedicationEvent inserts a logged dose unless the user has already
+// stored one with the same non-empty client ID, in which case the existing
+// row is returned.
+func (d *DB) AddMedicationEvent(ctx context.Context, userID int64, e domain.MedicationEvent) (*domain.MedicationEvent, bool, error) {
+	var (
+		out     = domain.MedicationEvent{UserID: userID, ClientID: e.ClientID}
+		created bool
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO medication_events(user_id, medication_id, status, client_id, created_at)
+				VALUES($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id, medication_id, status, created_at
+			)
+			SELECT id, medication_id, status, created_at, true FROM ins
+			UNION ALL
+			SELECT id, medication_id, status, created_at, false FROM medication_events WHERE user_id=$1 AND client_id=$4;`,
+			userID, e.MedicationID, e.Status, nullString(e.ClientID), e.CreatedAt.UTC(),
+		).Scan(&out.ID, &out.MedicationID, &out.Status, &out.CreatedAt, &created)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &out, created, nil
+}
+
+// ListRecentMedicationEvents returns the user's latest logged doses, newest
+// first, for one medication or, with medicationID 0, all of them.
+func (d *DB) ListRecentMedicationEvents(ctx context.Context, userID, medicationID int64, limit int) ([]domain.MedicationEvent, error) {
+	out := []domain.MedicationEvent{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT id, medication_id, status, COALESCE(client_id, ''), created_at
+			FROM medication_events WHERE user_id=$1 AND ($2::bigint = 0 OR medication_id=$2)
+			ORDER BY created_at DESC, id DESC LIMIT $3;`, userID, medicationID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.MedicationEvent{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.MedicationID, &e.Status, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sealMedication encrypts the medication's name and dose.
+func (d *DB) sealMedication(m domain.Medication) (name, dose string, err error) {
+	if name, err = d.seal(m.Name); err != nil {
+		return "", "", err
+	}
+	if dose, err = d.seal(m.Dose); err != nil {
+		return "", "", err
+	}
+	return name, dose, nil
+}
+
+// scanMedication reads one row selected with medicationColumns.
+func (d *DB) scanMedication(row interface{ Scan(...any) error }) (*domain.Medication, error) {
+	var m domain.Medication
+	if err := row.Scan(&m.ID, &m.UserID, &m.Name, &m.Dose, pq.Array(&m.Schedule), &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if m.Name, err = d.open(m.Name); err != nil {
+		return nil, err
+	}
+	if m.Dose, err = d.open(m.Dose); err != nil {
+		return nil, err
+	}
+	m.Schedule = schedule(m.Schedule)
+	return &m, nil
+}
+
+// schedule returns times, or an empty slice if it is nil, so that an as
+// needed medication stores '{}' and encodes as [].
+func schedule(times []string) []string {
+	if times == nil {
+		return []string{}
+	}
+	return times
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const customMetricColumns = "id, user_id, slug, name, unit, aggregation, created_at"
+
+// CreateCustomMetric stores a new metric definition and returns it with its
+// ID.
+func (d *DB) CreateCustomMetric(ctx context.Context, m domain.CustomMetric) (*domain.CustomMetric, error) {
+	err := d.asUser(ctx, m.UserID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`INSERT INTO custom_metrics (user_id, slug, name, unit, aggregation, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;`,
+			m.UserID, m.Slug, m.Name, m.Unit, m.Aggregation, m.CreatedAt.UTC(),
+		).Scan(&m.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetCustomMetric returns the user's metric with slug, or nil.
+func (d *DB) GetCustomMetric(ctx context.Context, userID int64, slug string) (*domain.CustomMetric, error) {
+	var m domain.CustomMetric
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return scanCustomMetric(q.QueryRowContext(ctx,
+			"SELECT "+customMetricColumns+" FROM custom_metrics WHERE user_id=$1 AND slug=$2;", userID, slug), &m)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListCustomMetrics returns the user's metrics, oldest first.
+func (d *DB) ListCustomMetrics(ctx context.Context, userID int64) ([]domain.CustomMetric, error) {
+	out := []domain.CustomMetric{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+customMetricColumns+" FROM custom_metrics WHERE user_id=$1 ORDER BY id;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var m domain.CustomMetric
+			if err := scanCustomMetric(rows, &m); err != nil {
+				return err
+			}
+			out = append(out, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteCustomMetric removes one of a user's metrics; its events go with it
+// through the foreign key.
+func (d *DB) DeleteCustomMetric(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM custom_metrics WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// AddCustomMetricEvent inserts a metric value unless the user has already
+// stored one with the same non-empty client ID, in which case the existing
+// row is returned.
+func (d *DB) AddCustomMetricEvent(ctx context.Context, userID int64, e domain.CustomMetricEvent) (*domain.CustomMetricEvent, bool, error) {
+	var (
+		out     = domain.CustomMetricEvent{UserID: userID, ClientID: e.ClientID}
+		created bool
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO metric_events(user_id, metric_id, value, client_id, created_at)
+				VALUES($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id, metric_id, value, created_at
+			)
+			SELECT id, metric_id, value, created_at, true FROM ins
+			UNION ALL
+			SELECT id, metric_id, value, created_at, false FROM metric_events WHERE user_id=$1 AND client_id=$4;`,
+			userID, e.MetricID, e.Value, nullString(e.ClientID), e.CreatedAt.UTC(),
+		).Scan(&out.ID, &out.MetricID, &out.Value, &out.CreatedAt, &created)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &out, created, nil
+}
+
+// ListRecentCustomMetricEvents returns the metric's latest values, newest
+// first.
+func (d *DB) ListRecentCustomMetricEvents(ctx context.Context, userID, metricID int64, limit int) ([]domain.CustomMetricEvent, error) {
+	return d.listCustomMetricEvents(ctx, userID,
+		`SELECT id, metric_id, value, COALESCE(client_id, ''), created_at
+		FROM metric_events WHERE user_id=$1 AND metric_id=$2
+		ORDER BY created_at DESC, id DESC LIMIT $3;`, userID, metricID, limit)
+}
+
+// ListCustomMetricEventsBetween returns the metric's values created in
+// [from, to), oldest first.
+func (d *DB) ListCustomMetricEventsBetween(ctx context.Context, userID, metricID int64, from, to time.Time) ([]domain.CustomMetricEvent, error) {
+	return d.listCustomMetricEvents(ctx, userID,
+		`SELECT id, metric_id, value, COALESCE(client_id, ''), created_at
+		FROM metric_events WHERE user_id=$1 AND metric_id=$2 AND created_at >= $3 AND created_at < $4
+		ORDER BY created_at, id;`, userID, metricID, from.UTC(), to.UTC())
+}
+
+// listCustomMetricEvents runs a query selecting metric_events rows.
+func (d *DB) listCustomMetricEvents(ctx context.Context, userID int64, query string, args ...any) ([]domain.CustomMetricEvent, error) {
+	out := []domain.CustomMetricEvent{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.CustomMetricEvent{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.MetricID, &e.Value, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// scanCustomMetric reads one row selected with customMetricColumns into m.
+func scanCustomMetric(row interface{ Scan(...any) error }, m *domain.CustomMetric) error {
+	return row.Scan(&m.ID, &m.UserID, &m.Slug, &m.Name, &m.Unit, &m.Aggregation, &m.CreatedAt)
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schema changes are numbered SQL files in migrations/, named
+// NNNN_description.up.sql with a matching .down.sql that undoes them. Each
+// file runs in its own transaction and its version is recorded in
+// schema_migrations, so a migration runs once per database. Never edit a
+// migration that has shipped; add a new one.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrateLock serializes migration runs across instances.
+const migrateLock = "vitals.migrate"
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration and when it was applied to this database,
+// nil if it is pending.
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// loadMigrations reads the migrations in fsys, ordered by version. Every
+// version needs both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			return nil, fmt.Errorf("migrations: unexpected file %q", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrations: version %d is both %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d (%s) needs both up and down files", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// migrations returns the migrations built into the binary.
+func migrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrations(sub)
+}
+
+// Migrate applies every pending migration in order and returns those it
+// applied. Concurrent runs from other instances wait for this one.
+func (d *DB) Migrate(ctx context.Context) ([]Migration, error) {
+	all, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	err = d.withMigrateLock(ctx, func(conn *sql.Conn, done map[int]time.Time) error {
+		for _, m := range all {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m, m.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", m.Version, m.Name, time.Now().UTC()); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Rollback undoes the latest steps applied migrations, newest first, and
+// returns those it undid.
+func (d *DB) Rollback(ctx context.Context, steps int) ([]Migration, error) {
+	all, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+	var undone []Migration
+	err = d.withMigrateLock(ctx, func(conn *sql.Conn, done map[int]time.Time) error {
+		for i := len(all) - 1; i >= 0 && len(undone) < steps; i-- {
+			m := all[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m, m.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", m.Version); err != nil {
+				return err
+			}
+			undone = append(undone, m)
+		}
+		return nil
+	})
+	return undone, err
+}
+
+// MigrationStatus lists every known migration with when it was applied.
+func (d *DB) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	all, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedMigrations(ctx, d.sql)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationState, len(all))
+	for i, m := range all {
+		out[i] = MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := done[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// PendingMigrations counts the migrations not yet applied.
+func (d *DB) PendingMigrations(ctx context.Context) (int, error) {
+	states, err := d.MigrationStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// withMigrateLock runs fn on a dedicated connection holding the migration
+// advisory lock, with the versions applied so far.
+func (d *DB) withMigrateLock(ctx context.Context, fn func(conn *sql.Conn, done map[int]time.Time) error) error {
+	conn, err := d.sql.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", migrateLock); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", migrateLock)
+	}()
+
+	if _, err := conn.ExecContext(ctx,
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version INT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL);"); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// runMigration executes body and the bookkeeping statement in one
+// transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, m Migration, body, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migrate: %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("migrate: record %04d: %w", m.Version, err)
+	}
+	return tx.Commit()
+}
+
+// appliedMigrations reads schema_migrations, which may not exist yet.
+func appliedMigrations(ctx context.Context, q querier) (map[int]time.Time, error) {
+	done := map[int]time.Time{}
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	if !exists {
+		return done, nil
+	}
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		done[v] = at
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := migrations()
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Errorf("expected version %d, got %d (%s); versions must be contiguous", i+1, m.Version, m.Name)
+		}
+	}
+	if len(all) == 0 || !strings.Contains(all[0].Up, "CREATE TABLE IF NOT EXISTS users") {
+		t.Fatalf("expected the baseline first, got %+v", all)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_notes.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN notes TEXT;")},
+		"0002_add_notes.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN notes;")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE t (id INT);")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE t;")},
+	}
+	all, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "init" || all[1].Version != 2 || all[1].Down != "ALTER TABLE t DROP COLUMN notes;" {
+		t.Fatalf("unexpected migrations: %+v", all)
+	}
+
+	for name, bad := range map[string]fstest.MapFS{
+		"missing down": {"0001_init.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad name":     {"init.sql": {Data: []byte("SELECT 1;")}},
+		"name clash": {
+			"0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	} {
+		if _, err := loadMigrations(bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
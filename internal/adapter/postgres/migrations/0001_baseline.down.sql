@@ -0,0 +1,19 @@
+-- Drops every table, and with them all data.
+
+DROP TABLE IF EXISTS email_changes CASCADE;
+DROP TABLE IF EXISTS linked_identities CASCADE;
+DROP TABLE IF EXISTS user_rules CASCADE;
+DROP TABLE IF EXISTS weekly_summaries CASCADE;
+DROP TABLE IF EXISTS journal_entries CASCADE;
+DROP TABLE IF EXISTS entry_tags CASCADE;
+DROP TABLE IF EXISTS user_settings CASCADE;
+DROP TABLE IF EXISTS alert_rules CASCADE;
+DROP TABLE IF EXISTS hydration_settings CASCADE;
+DROP TABLE IF EXISTS changes CASCADE;
+DROP TABLE IF EXISTS api_tokens CASCADE;
+DROP TABLE IF EXISTS shares CASCADE;
+DROP TABLE IF EXISTS sessions CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+DROP TABLE IF EXISTS water_events CASCADE;
+DROP TABLE IF EXISTS weight_events CASCADE;
+DROP TABLE IF EXISTS weights CASCADE;
@@ -0,0 +1,53 @@
+-- Baseline: the schema as the unversioned migrate() left it. Every
+-- statement is idempotent so databases created before versioning adopt it.
+
+CREATE TABLE IF NOT EXISTS weights (day TEXT PRIMARY KEY, value DOUBLE PRECISION NOT NULL, unit TEXT NOT NULL CHECK(unit IN ('kg','lb')), created_at TIMESTAMPTZ NOT NULL);
+CREATE TABLE IF NOT EXISTS weight_events (id BIGSERIAL PRIMARY KEY, value DOUBLE PRECISION NOT NULL, unit TEXT NOT NULL CHECK(unit IN ('kg','lb')), created_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_weight_events_created_at ON weight_events(created_at);
+CREATE TABLE IF NOT EXISTS water_events (id BIGSERIAL PRIMARY KEY, delta_liters DOUBLE PRECISION NOT NULL, created_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_water_events_created_at ON water_events(created_at);
+CREATE TABLE IF NOT EXISTS users (id BIGSERIAL PRIMARY KEY, username TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL, created_at TIMESTAMPTZ NOT NULL);
+CREATE TABLE IF NOT EXISTS sessions (token TEXT PRIMARY KEY, user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, user_agent TEXT NOT NULL DEFAULT '', ip TEXT NOT NULL DEFAULT '', expires_at TIMESTAMPTZ NOT NULL, created_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE TABLE IF NOT EXISTS shares (owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, viewer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, created_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (owner_id, viewer_id));
+CREATE INDEX IF NOT EXISTS idx_shares_viewer_id ON shares(viewer_id);
+CREATE TABLE IF NOT EXISTS api_tokens (id BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, name TEXT NOT NULL, scope TEXT NOT NULL, token_hash TEXT UNIQUE NOT NULL, created_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+CREATE TABLE IF NOT EXISTS changes (seq BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL, entity TEXT NOT NULL, entity_id BIGINT NOT NULL, op TEXT NOT NULL, changed_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_changes_user_seq ON changes(user_id, seq);
+CREATE TABLE IF NOT EXISTS hydration_settings (user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE, base_goal_liters DOUBLE PRECISION NOT NULL, latitude DOUBLE PRECISION, longitude DOUBLE PRECISION);
+CREATE TABLE IF NOT EXISTS alert_rules (user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE, max_weekly_change_pct DOUBLE PRECISION NOT NULL, channel TEXT NOT NULL, target TEXT NOT NULL DEFAULT '', enabled BOOLEAN NOT NULL, last_alerted_at TIMESTAMPTZ);
+CREATE TABLE IF NOT EXISTS user_settings (user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, key TEXT NOT NULL, value JSONB NOT NULL, updated_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (user_id, key));
+CREATE TABLE IF NOT EXISTS entry_tags (user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, entity TEXT NOT NULL, entry_id BIGINT NOT NULL, tag TEXT NOT NULL, PRIMARY KEY (entity, entry_id, tag));
+CREATE INDEX IF NOT EXISTS idx_entry_tags_user_tag ON entry_tags(user_id, tag);
+CREATE TABLE IF NOT EXISTS journal_entries (id BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, day DATE NOT NULL, note TEXT NOT NULL, updated_at TIMESTAMPTZ NOT NULL, UNIQUE (user_id, day));
+CREATE TABLE IF NOT EXISTS weekly_summaries (user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, week_start DATE NOT NULL, week_end DATE NOT NULL, weigh_ins INT NOT NULL, start_kg DOUBLE PRECISION, end_kg DOUBLE PRECISION, change_kg DOUBLE PRECISION, avg_kg DOUBLE PRECISION, total_water_liters DOUBLE PRECISION NOT NULL, avg_water_liters DOUBLE PRECISION NOT NULL, goal_days INT NOT NULL, computed_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (user_id, week_start));
+CREATE TABLE IF NOT EXISTS user_rules (id BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, name TEXT NOT NULL, kind TEXT NOT NULL, threshold DOUBLE PRECISION NOT NULL DEFAULT 0, at_time TEXT NOT NULL DEFAULT '', days INT NOT NULL DEFAULT 0, channel TEXT NOT NULL, target TEXT NOT NULL DEFAULT '', enabled BOOLEAN NOT NULL, last_fired_at TIMESTAMPTZ, created_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_user_rules_user ON user_rules(user_id);
+CREATE TABLE IF NOT EXISTS linked_identities (issuer TEXT NOT NULL, subject TEXT NOT NULL, user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, email TEXT NOT NULL DEFAULT '', linked_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (issuer, subject));
+CREATE INDEX IF NOT EXISTS idx_linked_identities_user ON linked_identities(user_id);
+CREATE TABLE IF NOT EXISTS email_changes (user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE, email TEXT NOT NULL, token_hash TEXT UNIQUE NOT NULL, expires_at TIMESTAMPTZ NOT NULL);
+
+-- Columns added after the tables first shipped.
+ALTER TABLE weight_events ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id);
+ALTER TABLE water_events ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id);
+CREATE INDEX IF NOT EXISTS idx_weight_events_user_id ON weight_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_water_events_user_id ON water_events(user_id);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip TEXT;
+UPDATE sessions SET user_agent = COALESCE(user_agent, ''), ip = COALESCE(ip, '') WHERE user_agent IS NULL OR ip IS NULL;
+ALTER TABLE sessions ALTER COLUMN user_agent SET DEFAULT '', ALTER COLUMN user_agent SET NOT NULL, ALTER COLUMN ip SET DEFAULT '', ALTER COLUMN ip SET NOT NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_id ON sessions(id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name TEXT;
+CREATE INDEX IF NOT EXISTS idx_users_owner_id ON users(owner_id);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
+ALTER TABLE weight_events ADD COLUMN IF NOT EXISTS client_id TEXT;
+ALTER TABLE water_events ADD COLUMN IF NOT EXISTS client_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_weight_events_client_id ON weight_events(user_id, client_id) WHERE client_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_water_events_client_id ON water_events(user_id, client_id) WHERE client_id IS NOT NULL;
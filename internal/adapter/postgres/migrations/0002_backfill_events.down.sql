@@ -0,0 +1 @@
+-- The backfilled rows are ordinary data by now; there is nothing to undo.
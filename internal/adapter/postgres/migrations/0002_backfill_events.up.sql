@@ -0,0 +1,23 @@
+-- One-off data fixups from before events carried a user: copy the legacy
+-- weights table into weight_events, give ownerless events to the first
+-- user, and seed the change log so a first sync sees existing events.
+-- They span users, so they run past row-level security.
+SET LOCAL vitals.bypass_rls = 'on';
+
+INSERT INTO weight_events (value, unit, created_at)
+SELECT value, unit, created_at FROM weights
+WHERE NOT EXISTS (SELECT 1 FROM weight_events);
+
+UPDATE weight_events SET user_id = (SELECT id FROM users ORDER BY id LIMIT 1)
+WHERE user_id IS NULL AND EXISTS (SELECT 1 FROM users);
+UPDATE water_events SET user_id = (SELECT id FROM users ORDER BY id LIMIT 1)
+WHERE user_id IS NULL AND EXISTS (SELECT 1 FROM users);
+
+INSERT INTO changes (user_id, entity, entity_id, op, changed_at)
+SELECT user_id, entity, id, 'upsert', created_at FROM (
+	SELECT user_id, 'weight' AS entity, id, created_at FROM weight_events WHERE user_id IS NOT NULL
+	UNION ALL
+	SELECT user_id, 'water' AS entity, id, created_at FROM water_events WHERE user_id IS NOT NULL
+) AS events
+WHERE NOT EXISTS (SELECT 1 FROM changes)
+ORDER BY created_at;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hydration_goal_history;
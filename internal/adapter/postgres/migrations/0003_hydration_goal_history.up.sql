@@ -0,0 +1,12 @@
+-- Each base water goal a user sets, in effect from day until the next one.
+CREATE TABLE hydration_goal_history (
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	liters DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (user_id, day)
+);
+
+-- Goals set before history was kept apply to every earlier day.
+SET LOCAL vitals.bypass_rls = 'on';
+INSERT INTO hydration_goal_history (user_id, day, liters)
+SELECT user_id, DATE '1970-01-01', base_goal_liters FROM hydration_settings;
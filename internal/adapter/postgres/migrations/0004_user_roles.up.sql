@@ -0,0 +1,6 @@
+-- Admins may run instance-wide operations such as on-demand maintenance.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- The first account, created by setup, administers the instance.
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT MIN(id) FROM users WHERE owner_id IS NULL);
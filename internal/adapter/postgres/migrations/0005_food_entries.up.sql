@@ -0,0 +1,14 @@
+-- Logged food and meals; description may be sealed by the field cipher.
+CREATE TABLE food_entries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	client_id TEXT,
+	description TEXT NOT NULL,
+	kcal DOUBLE PRECISION NOT NULL,
+	protein_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+	carbs_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+	fat_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_food_entries_user_created ON food_entries (user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_food_entries_client_id ON food_entries (user_id, client_id) WHERE client_id IS NOT NULL;
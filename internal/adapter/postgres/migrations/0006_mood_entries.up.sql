@@ -0,0 +1,10 @@
+-- Daily mood on a 1-5 scale; note may be sealed by the field cipher.
+CREATE TABLE mood_entries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+	note TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (user_id, day)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE water_events DROP COLUMN IF EXISTS import_batch;
+ALTER TABLE weight_events DROP COLUMN IF EXISTS import_batch;
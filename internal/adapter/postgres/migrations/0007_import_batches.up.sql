@@ -0,0 +1,5 @@
+-- The import that wrote each event, so a whole import can be rolled back.
+ALTER TABLE weight_events ADD COLUMN import_batch TEXT;
+ALTER TABLE water_events ADD COLUMN import_batch TEXT;
+CREATE INDEX idx_weight_events_import_batch ON weight_events (user_id, import_batch) WHERE import_batch IS NOT NULL;
+CREATE INDEX idx_water_events_import_batch ON water_events (user_id, import_batch) WHERE import_batch IS NOT NULL;
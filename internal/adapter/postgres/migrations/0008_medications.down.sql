@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS medication_events;
+DROP TABLE IF EXISTS medications;
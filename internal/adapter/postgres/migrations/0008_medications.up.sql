@@ -0,0 +1,24 @@
+-- Medications and supplements; name and dose may be sealed by the field
+-- cipher. Schedule holds local "HH:MM" times, empty for as needed.
+CREATE TABLE medications (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	dose TEXT NOT NULL DEFAULT '',
+	schedule TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_medications_user ON medications (user_id);
+
+-- Doses taken or skipped.
+CREATE TABLE medication_events (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	medication_id BIGINT NOT NULL REFERENCES medications(id) ON DELETE CASCADE,
+	status TEXT NOT NULL CHECK (status IN ('taken', 'skipped')),
+	client_id TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_medication_events_user_created ON medication_events (user_id, created_at DESC);
+CREATE INDEX idx_medication_events_medication ON medication_events (medication_id, created_at DESC);
+CREATE UNIQUE INDEX idx_medication_events_client_id ON medication_events (user_id, client_id) WHERE client_id IS NOT NULL;
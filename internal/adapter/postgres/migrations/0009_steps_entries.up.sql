@@ -0,0 +1,9 @@
+-- Daily step counts, one per user and day.
+CREATE TABLE steps_entries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	steps INTEGER NOT NULL CHECK (steps >= 0),
+	updated_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (user_id, day)
+);
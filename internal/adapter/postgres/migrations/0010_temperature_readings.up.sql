@@ -0,0 +1,11 @@
+-- Body temperature readings, kept in the unit they were taken in.
+CREATE TABLE temperature_readings (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	value DOUBLE PRECISION NOT NULL,
+	unit TEXT NOT NULL CHECK (unit IN ('c', 'f')),
+	client_id TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_temperature_readings_user_created ON temperature_readings (user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_temperature_readings_client_id ON temperature_readings (user_id, client_id) WHERE client_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS metric_events;
+DROP TABLE IF EXISTS custom_metrics;
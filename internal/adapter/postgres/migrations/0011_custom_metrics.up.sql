@@ -0,0 +1,23 @@
+-- User-defined numeric trackers, addressed by a per-user slug.
+CREATE TABLE custom_metrics (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	slug TEXT NOT NULL,
+	name TEXT NOT NULL,
+	unit TEXT NOT NULL DEFAULT '',
+	aggregation TEXT NOT NULL CHECK (aggregation IN ('latest', 'sum', 'avg')),
+	created_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (user_id, slug)
+);
+
+-- Values recorded for a custom metric.
+CREATE TABLE metric_events (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	metric_id BIGINT NOT NULL REFERENCES custom_metrics(id) ON DELETE CASCADE,
+	value DOUBLE PRECISION NOT NULL,
+	client_id TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_metric_events_metric_created ON metric_events (metric_id, created_at DESC);
+CREATE UNIQUE INDEX idx_metric_events_client_id ON metric_events (user_id, client_id) WHERE client_id IS NOT NULL;
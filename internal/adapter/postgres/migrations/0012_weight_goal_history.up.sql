@@ -0,0 +1,8 @@
+-- Each target weight a user sets, in effect from day until the next one. A
+-- NULL target clears it.
+CREATE TABLE weight_goal_history (
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	target_kg DOUBLE PRECISION,
+	PRIMARY KEY (user_id, day)
+);
@@ -0,0 +1,13 @@
+-- Each user's authorization to pull data from an integration provider. The
+-- tokens are sealed when field encryption is configured.
+CREATE TABLE oauth_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider TEXT NOT NULL,
+	access_token TEXT NOT NULL,
+	refresh_token TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ,
+	connected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	synced_at TIMESTAMPTZ,
+	UNIQUE (user_id, provider)
+);
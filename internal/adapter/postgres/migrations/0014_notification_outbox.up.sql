@@ -0,0 +1,17 @@
+-- Notifications awaiting delivery, written in the same transaction as the
+-- alert or rule that triggered them and deleted once delivered. Rows with
+-- no next_attempt_at were given up on after repeated failures. The target
+-- is sealed when field encryption is configured.
+CREATE TABLE notification_outbox (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	channel TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	notification JSONB NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	next_attempt_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX notification_outbox_due ON notification_outbox (next_attempt_at) WHERE next_attempt_at IS NOT NULL;
@@ -0,0 +1,31 @@
+-- Webhooks users registered for their events. The URL and signing secret
+-- are sealed when field encryption is configured.
+CREATE TABLE webhooks (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT[] NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_webhooks_user ON webhooks (user_id);
+
+-- Events queued for a webhook, kept after delivery as its delivery log.
+-- Rows with no next_attempt_at were delivered or given up on.
+CREATE TABLE webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	status_code INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	delivered_at TIMESTAMPTZ,
+	next_attempt_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE next_attempt_at IS NOT NULL;
+CREATE INDEX idx_webhook_deliveries_created ON webhook_deliveries (created_at);
@@ -0,0 +1,11 @@
+-- One-time codes that let a user set a local password, e.g. after their
+-- identity provider went away. Emergency codes the user generated have no
+-- expires_at; a code an admin issued for them does.
+CREATE TABLE recovery_codes (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_recovery_codes_user ON recovery_codes (user_id);
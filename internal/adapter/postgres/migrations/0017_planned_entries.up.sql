@@ -0,0 +1,18 @@
+-- Measurements users plan to log on a local day, kept apart from logged
+-- events. Rows with a channel become reminders sent at at_time that day.
+-- The note and target are sealed when field encryption is configured.
+CREATE TABLE planned_entries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	day DATE NOT NULL,
+	at_time TEXT NOT NULL DEFAULT '',
+	liters DOUBLE PRECISION NOT NULL DEFAULT 0,
+	note TEXT NOT NULL DEFAULT '',
+	channel TEXT NOT NULL DEFAULT '',
+	target TEXT NOT NULL DEFAULT '',
+	reminded_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_planned_entries_user_day ON planned_entries (user_id, day);
+CREATE INDEX idx_planned_entries_reminders ON planned_entries (day) WHERE channel <> '' AND reminded_at IS NULL;
@@ -0,0 +1,3 @@
+-- When each API token last authenticated a request, so users can spot
+-- and revoke the ones no script uses any more.
+ALTER TABLE api_tokens ADD COLUMN last_used_at TIMESTAMPTZ;
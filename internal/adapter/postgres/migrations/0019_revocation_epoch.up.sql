@@ -0,0 +1,3 @@
+-- Moves on whenever a user's sessions and tokens are revoked, so grants
+-- kept outside the database, such as embed links, can be revoked too.
+ALTER TABLE users ADD COLUMN revocation_epoch BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS changes_in_order ON changes;
+DROP FUNCTION IF EXISTS changes_in_order();
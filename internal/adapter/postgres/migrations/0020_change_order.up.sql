@@ -0,0 +1,14 @@
+-- A sequence value is taken at insert time but becomes visible at commit,
+-- so two writers could commit changes out of seq order and a client that
+-- synced in between would skip one. Each insert now waits for the user's
+-- other change-log writers to finish and only then draws its seq, so a
+-- user's changes commit in seq order.
+CREATE FUNCTION changes_in_order() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	PERFORM pg_advisory_xact_lock(hashtext('vitals changes'), hashtext(NEW.user_id::text));
+	NEW.seq := nextval(pg_get_serial_sequence('changes', 'seq'));
+	RETURN NEW;
+END;
+$$;
+CREATE TRIGGER changes_in_order BEFORE INSERT ON changes
+	FOR EACH ROW EXECUTE FUNCTION changes_in_order();
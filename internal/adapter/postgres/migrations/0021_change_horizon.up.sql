@@ -0,0 +1,4 @@
+-- The highest seq of a deletion purged from the user's change log. A
+-- client whose sync cursor is below it has missed that deletion for good
+-- and must sync again from the start.
+ALTER TABLE users ADD COLUMN changes_purged_seq BIGINT NOT NULL DEFAULT 0;
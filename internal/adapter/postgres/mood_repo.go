@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetMoodEntry returns the mood for day, or nil.
+func (d *DB) GetMoodEntry(ctx context.Context, userID int64, day string) (*domain.MoodEntry, error) {
+	e := domain.MoodEntry{Day: day}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT score, note, updated_at FROM mood_entries WHERE user_id=$1 AND day=$2;", userID, day,
+		).Scan(&e.Score, &e.Note, &e.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Note, err = d.open(e.Note); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveMoodEntry creates or replaces the mood for e.Day.
+func (d *DB) SaveMoodEntry(ctx context.Context, userID int64, e domain.MoodEntry) error {
+	note, err := d.seal(e.Note)
+	if err != nil {
+		return err
+	}
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO mood_entries (user_id, day, score, note, updated_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, day) DO UPDATE SET score = EXCLUDED.score, note = EXCLUDED.note, updated_at = EXCLUDED.updated_at;`,
+			userID, e.Day, e.Score, note, e.UpdatedAt.UTC())
+		return err
+	})
+}
+
+// ListMoodEntries returns the moods for days from through to, oldest first.
+func (d *DB) ListMoodEntries(ctx context.Context, userID int64, from, to string) ([]domain.MoodEntry, error) {
+	return d.listMoodEntries(ctx, userID,
+		"SELECT day, score, note, updated_at FROM mood_entries WHERE user_id=$1 AND day BETWEEN $2 AND $3 ORDER BY day;",
+		userID, from, to)
+}
+
+// ListRecentMoodEntries returns the user's latest moods, newest day first.
+func (d *DB) ListRecentMoodEntries(ctx context.Context, userID int64, limit int) ([]domain.MoodEntry, error) {
+	return d.listMoodEntries(ctx, userID,
+		"SELECT day, score, note, updated_at FROM mood_entries WHERE user_id=$1 ORDER BY day DESC LIMIT $2;",
+		userID, limit)
+}
+
+func (d *DB) listMoodEntries(ctx context.Context, userID int64, query string, args ...any) ([]domain.MoodEntry, error) {
+	out := []domain.MoodEntry{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				e   domain.MoodEntry
+				day time.Time
+			)
+			if err := rows.Scan(&day, &e.Score, &e.Note, &e.UpdatedAt); err != nil {
+				return err
+			}
+			e.Day = day.Format("2006-01-02")
+			if e.Note, err = d.open(e.Note); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
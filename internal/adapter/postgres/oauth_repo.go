@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// SaveOAuthToken creates or replaces the user's token for t.Provider.
+func (d *DB) SaveOAuthToken(ctx context.Context, t domain.OAuthToken) error {
+	access, err := d.seal(t.AccessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := d.seal(t.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return d.asUser(ctx, t.UserID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO oauth_tokens (user_id, provider, access_token, refresh_token, expires_at, connected_at, synced_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (user_id, provider) DO UPDATE SET
+				access_token = EXCLUDED.access_token,
+				refresh_token = EXCLUDED.refresh_token,
+				expires_at = EXCLUDED.expires_at,
+				connected_at = EXCLUDED.connected_at,
+				synced_at = EXCLUDED.synced_at;`,
+			t.UserID, t.Provider, access, refresh, nullTime(t.Expiry), t.ConnectedAt.UTC(), nullTime(t.SyncedAt))
+		return err
+	})
+}
+
+// GetOAuthToken returns the user's token for provider, or nil.
+func (d *DB) GetOAuthToken(ctx context.Context, userID int64, provider string) (*domain.OAuthToken, error) {
+	var t *domain.OAuthToken
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		t, err = d.scanOAuthToken(q.QueryRowContext(ctx,
+			"SELECT user_id, provider, access_token, refresh_token, expires_at, connected_at, synced_at FROM oauth_tokens WHERE user_id=$1 AND provider=$2;",
+			userID, provider))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// DeleteOAuthToken removes the user's token for provider.
+func (d *DB) DeleteOAuthToken(ctx context.Context, userID int64, provider string) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM oauth_tokens WHERE user_id=$1 AND provider=$2;", userID, provider)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// ListOAuthTokens returns every user's token for provider, ordered by user.
+func (d *DB) ListOAuthTokens(ctx context.Context, provider string) ([]domain.OAuthToken, error) {
+	var out []domain.OAuthToken
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT user_id, provider, access_token, refresh_token, expires_at, connected_at, synced_at FROM oauth_tokens WHERE provider=$1 ORDER BY user_id;",
+			provider)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			t, err := d.scanOAuthToken(rows)
+			if err != nil {
+				return err
+			}
+			out = append(out, *t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *DB) scanOAuthToken(row interface{ Scan(...any) error }) (*domain.OAuthToken, error) {
+	var (
+		t              domain.OAuthToken
+		expiry, synced sql.NullTime
+	)
+	if err := row.Scan(&t.UserID, &t.Provider, &t.AccessToken, &t.RefreshToken, &expiry, &t.ConnectedAt, &synced); err != nil {
+		return nil, err
+	}
+	var err error
+	if t.AccessToken, err = d.open(t.AccessToken); err != nil {
+		return nil, err
+	}
+	if t.RefreshToken, err = d.open(t.RefreshToken); err != nil {
+		return nil, err
+	}
+	t.Expiry, t.SyncedAt = expiry.Time, synced.Time
+	return &t, nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
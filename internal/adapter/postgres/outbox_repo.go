@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// EnqueueOutbox stores msgs, due at once.
+func (d *DB) EnqueueOutbox(ctx context.Context, msgs ...domain.OutboxMessage) error {
+	return d.asSystem(ctx, func(q querier) error {
+		return d.insertOutbox(ctx, q, msgs)
+	})
+}
+
+// insertOutbox adds msgs to the outbox through q, which callers make the
+// transaction of the write that triggered them.
+func (d *DB) insertOutbox(ctx context.Context, q querier, msgs []domain.OutboxMessage) error {
+	for _, m := range msgs {
+		payload, err := json.Marshal(m.Notification)
+		if err != nil {
+			return err
+		}
+		target, err := d.seal(m.Notification.Target)
+		if err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx,
+			"INSERT INTO notification_outbox (user_id, channel, target, notification) VALUES ($1, $2, $3, $4);",
+			m.Notification.UserID, m.Channel, target, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimOutbox leases up to limit due messages until until. Rows locked by
+// another worker's claim are skipped rather than waited for.
+func (d *DB) ClaimOutbox(ctx context.Context, now, until time.Time, limit int) ([]domain.OutboxMessage, error) {
+	out := []domain.OutboxMessage{}
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`UPDATE notification_outbox SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM notification_outbox
+				WHERE next_attempt_at <= $1
+				ORDER BY id LIMIT $3
+				FOR UPDATE SKIP LOCKED)
+			RETURNING id, channel, target, notification, attempts, last_error, created_at, next_attempt_at;`,
+			now.UTC(), until.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				m       domain.OutboxMessage
+				target  string
+				payload []byte
+				next    sql.NullTime
+			)
+			if err := rows.Scan(&m.ID, &m.Channel, &target, &payload, &m.Attempts, &m.LastError, &m.CreatedAt, &next); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(payload, &m.Notification); err != nil {
+				return err
+			}
+			if m.Notification.Target, err = d.open(target); err != nil {
+				return err
+			}
+			if next.Valid {
+				m.NextAttemptAt = &next.Time
+			}
+			out = append(out, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the subquery's order.
+	slices.SortFunc(out, func(a, b domain.OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return out, nil
+}
+
+// DeleteOutbox removes a delivered message.
+func (d *DB) DeleteOutbox(ctx context.Context, id int64) error {
+	return d.asSystem(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx, "DELETE FROM notification_outbox WHERE id=$1;", id)
+		return err
+	})
+}
+
+// FailOutbox records a failed delivery and schedules the next one, or none.
+func (d *DB) FailOutbox(ctx context.Context, id int64, lastErr string, next *time.Time) error {
+	var at sql.NullTime
+	if next != nil {
+		at = sql.NullTime{Time: next.UTC(), Valid: true}
+	}
+	return d.asSystem(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			"UPDATE notification_outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id=$1;",
+			id, lastErr, at)
+		return err
+	})
+}
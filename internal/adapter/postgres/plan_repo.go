@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const planColumns = "id, user_id, kind, day, at_time, liters, note, channel, target, reminded_at, created_at"
+
+// CreatePlannedEntry stores a new planned entry and returns it with its ID.
+func (d *DB) CreatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (*domain.PlannedEntry, error) {
+	note, target, err := d.sealPlan(p)
+	if err != nil {
+		return nil, err
+	}
+	err = d.asUser(ctx, p.UserID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`INSERT INTO planned_entries (user_id, kind, day, at_time, liters, note, channel, target, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;`,
+			p.UserID, p.Kind, p.Day, p.At, p.Liters, note, p.Channel, target, p.CreatedAt,
+		).Scan(&p.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPlannedEntry returns the user's planned entry with id, or nil.
+func (d *DB) GetPlannedEntry(ctx context.Context, userID, id int64) (*domain.PlannedEntry, error) {
+	var p *domain.PlannedEntry
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		p, err = d.scanPlannedEntry(q.QueryRowContext(ctx,
+			"SELECT "+planColumns+" FROM planned_entries WHERE user_id=$1 AND id=$2;", userID, id))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListPlannedEntries returns the user's entries for days from through to,
+// by day and time.
+func (d *DB) ListPlannedEntries(ctx context.Context, userID int64, from, to string) ([]domain.PlannedEntry, error) {
+	out := []domain.PlannedEntry{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+planColumns+" FROM planned_entries WHERE user_id=$1 AND day BETWEEN $2 AND $3 ORDER BY day, at_time, id;",
+			userID, from, to)
+		if err != nil {
+			return err
+		}
+		out, err = d.scanPlannedEntries(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CountPlannedEntries returns how many entries the user has planned for
+// from or later.
+func (d *DB) CountPlannedEntries(ctx context.Context, userID int64, from string) (int, error) {
+	var n int
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM planned_entries WHERE user_id=$1 AND day >= $2;", userID, from).Scan(&n)
+	})
+	return n, err
+}
+
+// UpdatePlannedEntry replaces an entry's plan and re-arms its reminder.
+func (d *DB) UpdatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (bool, error) {
+	note, target, err := d.sealPlan(p)
+	if err != nil {
+		return false, err
+	}
+	var n int64
+	err = d.asUser(ctx, p.UserID, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			`UPDATE planned_entries SET kind=$3, day=$4, at_time=$5, liters=$6, note=$7, channel=$8, target=$9, reminded_at=NULL
+			WHERE user_id=$1 AND id=$2;`,
+			p.UserID, p.ID, p.Kind, p.Day, p.At, p.Liters, note, p.Channel, target)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// DeletePlannedEntry removes one of a user's planned entries and reports
+// whether it existed.
+func (d *DB) DeletePlannedEntry(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM planned_entries WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// ListPlanReminders returns every user's entries for day with a pending
+// reminder, ordered by user.
+func (d *DB) ListPlanReminders(ctx context.Context, day string) ([]domain.PlannedEntry, error) {
+	var out []domain.PlannedEntry
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+planColumns+" FROM planned_entries WHERE day=$1 AND channel <> '' AND reminded_at IS NULL ORDER BY user_id, at_time, id;", day)
+		if err != nil {
+			return err
+		}
+		out, err = d.scanPlannedEntries(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkPlanReminded records when an entry's reminder was sent and queues
+// the notifications, in one transaction.
+func (d *DB) MarkPlanReminded(ctx context.Context, userID, id int64, at time.Time, queue ...domain.OutboxMessage) error {
+	return d.userTx(ctx, userID, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "UPDATE planned_entries SET reminded_at=$3 WHERE user_id=$1 AND id=$2;", userID, id, at.UTC()); err != nil {
+			return err
+		}
+		return d.insertOutbox(ctx, q, queue)
+	})
+}
+
+// sealPlan returns p's note and target sealed for storage.
+func (d *DB) sealPlan(p domain.PlannedEntry) (note, target string, err error) {
+	if note, err = d.seal(p.Note); err != nil {
+		return "", "", err
+	}
+	if target, err = d.seal(p.Target); err != nil {
+		return "", "", err
+	}
+	return note, target, nil
+}
+
+func (d *DB) scanPlannedEntries(rows *sql.Rows) ([]domain.PlannedEntry, error) {
+	defer rows.Close() //nolint:errcheck
+
+	out := []domain.PlannedEntry{}
+	for rows.Next() {
+		p, err := d.scanPlannedEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// scanPlannedEntry reads one row selected with planColumns.
+func (d *DB) scanPlannedEntry(row interface{ Scan(...any) error }) (*domain.PlannedEntry, error) {
+	var (
+		p        domain.PlannedEntry
+		day      time.Time
+		reminded sql.NullTime
+	)
+	if err := row.Scan(&p.ID, &p.UserID, &p.Kind, &day, &p.At, &p.Liters, &p.Note, &p.Channel, &p.Target, &reminded, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	p.Day = day.Format("2006-01-02")
+	var err error
+	if p.Note, err = d.open(p.Note); err != nil {
+		return nil, err
+	}
+	if p.Target, err = d.open(p.Target); err != nil {
+		return nil, err
+	}
+	if reminded.Valid {
+		p.RemindedAt = &reminded.Time
+	}
+	return &p, nil
+}
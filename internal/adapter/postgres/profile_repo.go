@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Profiles are stored as credential-less rows in users with owner_id set, so
+// the existing user_id foreign keys on event tables cover them unchanged.
+
+// CreateProfile inserts a profile owned by ownerID. With a cipher configured
+// the name is stored encrypted and the username is opaque, so it does not
+// leak the name either.
+func (d *DB) CreateProfile(ctx context.Context, ownerID int64, name string) (*domain.Profile, error) {
+	p := domain.Profile{OwnerID: ownerID, Name: name}
+	username := fmt.Sprintf("%d/%s", ownerID, name)
+	if d.cipher != nil {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		username = fmt.Sprintf("%d/profile-%s", ownerID, hex.EncodeToString(b))
+	}
+	stored, err := d.seal(name)
+	if err != nil {
+		return nil, err
+	}
+	err = d.sql.QueryRowContext(ctx,
+		"INSERT INTO users (username, password_hash, owner_id, display_name, created_at) VALUES ($1, '', $2, $3, $4) RETURNING id, created_at;",
+		username, ownerID, stored, time.Now(),
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProfiles returns the profiles owned by ownerID, oldest first.
+func (d *DB) ListProfiles(ctx context.Context, ownerID int64) ([]domain.Profile, error) {
+	rows, err := d.sql.QueryContext(ctx,
+		"SELECT id, display_name, created_at FROM users WHERE owner_id=$1 ORDER BY id;", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []domain.Profile{}
+	for rows.Next() {
+		p := domain.Profile{OwnerID: ownerID}
+		if err := rows.Scan(&p.ID, &p.Name, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		if p.Name, err = d.open(p.Name); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetProfile returns the profile with the given ID if ownerID owns it.
+func (d *DB) GetProfile(ctx context.Context, ownerID, id int64) (*domain.Profile, error) {
+	p := domain.Profile{ID: id, OwnerID: ownerID}
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT display_name, created_at FROM users WHERE id=$1 AND owner_id=$2;", id, ownerID,
+	).Scan(&p.Name, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Name, err = d.open(p.Name); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ReplaceRecoveryCodes replaces the user's emergency codes with hashes.
+func (d *DB) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := traced{tx}
+	if _, err := q.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1 AND expires_at IS NULL", userID); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx,
+		"INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])",
+		userID, pq.Array(hashes),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IssueRecoveryCode stores a code that works until expiresAt, replacing the
+// user's previously issued one.
+func (d *DB) IssueRecoveryCode(ctx context.Context, userID int64, hash string, expiresAt time.Time) error {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := traced{tx}
+	if _, err := q.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1 AND expires_at IS NOT NULL", userID); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx,
+		"INSERT INTO recovery_codes (user_id, code_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, hash, expiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRecoveryCodes returns how many unused emergency codes the user has.
+func (d *DB) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND expires_at IS NULL",
+		userID,
+	).Scan(&n)
+	return n, err
+}
+
+// RedeemRecoveryCode deletes the user's unexpired code with hash and sets
+// their password hash in the same transaction.
+func (d *DB) RedeemRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time, passwordHash string) (bool, error) {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := traced{tx}
+	res, err := q.ExecContext(ctx,
+		`DELETE FROM recovery_codes WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND (expires_at IS NULL OR expires_at > $3)
+			LIMIT 1 FOR UPDATE
+		)`,
+		userID, hash, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE users SET password_hash = $2 WHERE id = $1", userID, passwordHash); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,2097 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vitals/internal/adapter/fieldcrypt"
+	"vitals/internal/domain"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestIntegrationUsers(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+	if _, err := d.Create(ctx, "alice", "hash"); err == nil {
+		t.Error("expected a duplicate username to be rejected")
+	}
+	if _, err := d.CreateProfile(ctx, alice, "kid"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.Count(ctx); err != nil || n != 2 {
+		t.Errorf("expected profiles left out of the count, got %d, %v", n, err)
+	}
+
+	u, err := d.GetByUsername(ctx, "alice")
+	if err != nil || u == nil || u.ID != alice || u.Role != domain.RoleUser {
+		t.Fatalf("GetByUsername: %+v, %v", u, err)
+	}
+	if u, err := d.GetByUsername(ctx, "nobody"); err != nil || u != nil {
+		t.Errorf("expected nil for an unknown username, got %+v, %v", u, err)
+	}
+	if u, err := d.GetByID(ctx, 9999); err != nil || u != nil {
+		t.Errorf("expected nil for an unknown id, got %+v, %v", u, err)
+	}
+
+	if err := d.UpdateUsername(ctx, alice, "bob"); err == nil {
+		t.Error("expected renaming onto a taken username to fail")
+	}
+	if err := d.UpdateUsername(ctx, alice, "alice2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetRole(ctx, alice, domain.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := d.GetByID(ctx, alice); u == nil || u.Username != "alice2" || !u.IsAdmin() {
+		t.Errorf("expected alice2 as an admin, got %+v", u)
+	}
+	if err := d.UpdatePassword(ctx, alice, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := d.GetByID(ctx, alice); u == nil || u.PasswordHash != "new-hash" {
+		t.Errorf("expected the new password hash, got %+v", u)
+	}
+
+	if err := d.SetEmail(ctx, alice, "a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetEmail(ctx, bob, "a@example.com"); err == nil {
+		t.Error("expected a duplicate email to be rejected")
+	}
+	if u, err := d.GetByEmail(ctx, "a@example.com"); err != nil || u == nil || u.ID != alice {
+		t.Errorf("GetByEmail: %+v, %v", u, err)
+	}
+	if err := d.SetEmail(ctx, alice, ""); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := d.GetByEmail(ctx, "a@example.com"); err != nil || u != nil {
+		t.Errorf("expected a cleared email to be gone, got %+v, %v", u, err)
+	}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	for _, email := range []string{"old@example.com", "new@example.com"} {
+		if err := d.SaveEmailChange(ctx, domain.EmailChange{UserID: bob, Email: email, TokenHash: "hash-" + email, ExpiresAt: expires}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, err := d.GetEmailChange(ctx, bob); err != nil || c == nil || c.Email != "new@example.com" || !c.ExpiresAt.Equal(expires) {
+		t.Errorf("expected the latest email change to replace the first, got %+v, %v", c, err)
+	}
+	if c, err := d.TakeEmailChange(ctx, "hash-old@example.com"); err != nil || c != nil {
+		t.Errorf("expected the replaced token to be gone, got %+v, %v", c, err)
+	}
+	if c, err := d.TakeEmailChange(ctx, "hash-new@example.com"); err != nil || c == nil || c.UserID != bob {
+		t.Errorf("TakeEmailChange: %+v, %v", c, err)
+	}
+	if c, err := d.GetEmailChange(ctx, bob); err != nil || c != nil {
+		t.Errorf("expected a taken change to be removed, got %+v, %v", c, err)
+	}
+
+	link := domain.LinkedIdentity{UserID: alice, Issuer: "https://idp", Subject: "sub", Email: "a@idp", LinkedAt: time.Now()}
+	if err := d.LinkIdentity(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	link.UserID = bob
+	if err := d.LinkIdentity(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := d.GetLinkedIdentity(ctx, "https://idp", "sub"); err != nil || id == nil || id.UserID != bob {
+		t.Errorf("expected the relink to replace the first, got %+v, %v", id, err)
+	}
+	if id, err := d.GetLinkedIdentity(ctx, "https://idp", "other"); err != nil || id != nil {
+		t.Errorf("expected nil for an unknown identity, got %+v, %v", id, err)
+	}
+}
+
+func TestIntegrationClaimAdmin(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+
+	// Sign-ups racing on a fresh instance promote exactly one of them.
+	const n = 8
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = newTestUser(t, d, fmt.Sprintf("user%d", i))
+	}
+	var (
+		wg      sync.WaitGroup
+		claimed atomic.Int32
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := d.ClaimAdmin(ctx, id)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := claimed.Load(); got != 1 {
+		t.Errorf("expected one claim to succeed, got %d", got)
+	}
+	var admins int
+	if err := d.sql.QueryRowContext(ctx, "SELECT count(*) FROM users WHERE role = 'admin'").Scan(&admins); err != nil {
+		t.Fatal(err)
+	}
+	if admins != 1 {
+		t.Errorf("expected one admin, got %d", admins)
+	}
+	if ok, err := d.ClaimAdmin(ctx, newTestUser(t, d, "late")); err != nil || ok {
+		t.Errorf("expected a later claim to fail, got %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	sessions := NewSessionRepo(d)
+
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+	kid, err := d.CreateProfile(ctx, alice, "kid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, id := range []int64{alice, kid.ID, bob} {
+		w, err := d.AddWeightEvent(ctx, id, 70, "kg", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.SetEntryTags(ctx, id, domain.ChangeEntityWeight, w, []string{"x"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.AddWaterEvent(ctx, id, 0.5, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sessions.Create(ctx, alice, "alice-session", "ua", "ip", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateAPIToken(ctx, alice, "script", domain.TokenScopeAPI, "alice-hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.DeleteUser(ctx, alice); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if u, _ := d.GetByID(ctx, alice); u != nil {
+		t.Error("expected the user deleted")
+	}
+	if s, _ := sessions.GetByToken(ctx, "alice-session"); s != nil {
+		t.Error("expected the session deleted")
+	}
+	var n int
+	if err := d.sql.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM weight_events) + (SELECT COUNT(*) FROM water_events)
+			+ (SELECT COUNT(*) FROM entry_tags) + (SELECT COUNT(*) FROM api_tokens);`).Scan(&n); err != nil || n != 3 {
+		t.Errorf("expected only bob's weight, water and tag left, got %d rows, %v", n, err)
+	}
+	if items, _ := d.ListRecentWaterEvents(ctx, bob, 10); len(items) != 1 {
+		t.Errorf("expected bob's data kept, got %v", items)
+	}
+	if changes, err := d.ListChanges(ctx, alice, 0, 100); err != nil || len(changes) != 0 {
+		t.Errorf("expected alice's change log deleted, got %v, %v", changes, err)
+	}
+	if err := d.DeleteUser(ctx, alice); err == nil {
+		t.Error("expected deleting an unknown user to fail")
+	}
+}
+
+func TestIntegrationRevocationEpoch(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	if epoch, ok, err := d.RevocationEpoch(ctx, alice); err != nil || !ok || epoch != 0 {
+		t.Fatalf("RevocationEpoch = %d, %v, %v", epoch, ok, err)
+	}
+	if err := d.BumpRevocationEpoch(ctx, alice); err != nil {
+		t.Fatalf("BumpRevocationEpoch: %v", err)
+	}
+	if epoch, _, _ := d.RevocationEpoch(ctx, alice); epoch != 1 {
+		t.Errorf("expected epoch 1, got %d", epoch)
+	}
+	if _, ok, err := d.RevocationEpoch(ctx, alice+1000); err != nil || ok {
+		t.Errorf("expected no epoch for an unknown user, got %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationSessions(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	repo := NewSessionRepo(d)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	now := time.Now()
+	for _, s := range []struct {
+		user    int64
+		token   string
+		expires time.Time
+	}{
+		{alice, "a1", now.Add(time.Hour)},
+		{alice, "a2", now.Add(time.Hour)},
+		{alice, "expired", now.Add(-time.Minute)},
+		{bob, "b1", now.Add(time.Hour)},
+	} {
+		if err := repo.Create(ctx, s.user, s.token, "curl", "127.0.0.1", s.expires); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := repo.GetByToken(ctx, "a1")
+	if err != nil || s == nil || s.UserID != alice || s.UserAgent != "curl" {
+		t.Fatalf("GetByToken: %+v, %v", s, err)
+	}
+	if s, err := repo.GetByToken(ctx, "missing"); err != nil || s != nil {
+		t.Errorf("expected nil for an unknown token, got %+v, %v", s, err)
+	}
+
+	if err := repo.Touch(ctx, "a1", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	list, err := repo.ListByUser(ctx, alice)
+	if err != nil || len(list) != 3 || list[0].Token != "a1" {
+		t.Fatalf("expected alice's three sessions, most recently seen first, got %+v, %v", list, err)
+	}
+
+	// Renaming and revoking are scoped to the session's owner.
+	if ok, err := repo.Rename(ctx, bob, s.ID, "stolen"); err != nil || ok {
+		t.Errorf("expected bob not to rename alice's session, got %v, %v", ok, err)
+	}
+	if ok, err := repo.Rename(ctx, alice, s.ID, "laptop"); err != nil || !ok {
+		t.Errorf("Rename: %v, %v", ok, err)
+	}
+	if s, _ := repo.GetByToken(ctx, "a1"); s == nil || s.Name != "laptop" {
+		t.Errorf("expected the session named, got %+v", s)
+	}
+	if ok, err := repo.DeleteByID(ctx, bob, s.ID); err != nil || ok {
+		t.Errorf("expected bob not to revoke alice's session, got %v, %v", ok, err)
+	}
+	if ok, err := repo.DeleteByID(ctx, alice, s.ID); err != nil || !ok {
+		t.Errorf("DeleteByID: %v, %v", ok, err)
+	}
+	if err := repo.Delete(ctx, "a2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.CountExpiredSessions(ctx); err != nil || n != 1 {
+		t.Errorf("expected one expired session, got %d, %v", n, err)
+	}
+	if n, err := d.PurgeExpiredSessions(ctx); err != nil || n != 1 {
+		t.Errorf("expected one session purged, got %d, %v", n, err)
+	}
+	if err := repo.Create(ctx, bob, "b2", "", "", now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := repo.ListByUser(ctx, bob); len(list) != 1 || list[0].Token != "b1" {
+		t.Errorf("expected only bob's live session left, got %+v", list)
+	}
+	if list, _ := repo.ListByUser(ctx, alice); len(list) != 0 {
+		t.Errorf("expected alice to have no sessions left, got %+v", list)
+	}
+	if n, err := repo.DeleteByUser(ctx, bob); err != nil || n != 1 {
+		t.Errorf("DeleteByUser: %d, %v", n, err)
+	}
+	if s, _ := repo.GetByToken(ctx, "b1"); s != nil {
+		t.Errorf("expected bob signed out everywhere, got %+v", s)
+	}
+}
+
+func TestIntegrationWeight(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	// The last microsecond of 2026-03-01 and the first of 2026-03-02, in
+	// local time, straddle the day boundary but not UTC midnight.
+	lastOfDay := localTime(t, "2026-03-01", 23, 59, 59, 999999000)
+	firstOfNext := localTime(t, "2026-03-02", 0, 0, 0, 0)
+	if _, err := d.AddWeightEvent(ctx, alice, 80, "kg", localTime(t, "2026-03-01", 7, 0, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddWeightEvent(ctx, alice, 176.37, "lb", lastOfDay); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddWeightEvent(ctx, alice, 79, "kg", firstOfNext); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddWeightEvent(ctx, bob, 60, "kg", localTime(t, "2026-03-01", 12, 0, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := d.LatestWeightForLocalDay(ctx, alice, "2026-03-01")
+	if err != nil || latest == nil || latest.Unit != "lb" || !latest.CreatedAt.Equal(lastOfDay) || latest.Day != "2026-03-01" {
+		t.Fatalf("expected the 23:59:59.999999 weigh-in, got %+v, %v", latest, err)
+	}
+	if next, err := d.LatestWeightForLocalDay(ctx, alice, "2026-03-02"); err != nil || next == nil || next.Value != 79 {
+		t.Errorf("expected the midnight weigh-in on the next day, got %+v, %v", next, err)
+	}
+	if none, err := d.LatestWeightForLocalDay(ctx, alice, "2026-03-03"); err != nil || none != nil {
+		t.Errorf("expected nil for a day without weigh-ins, got %+v, %v", none, err)
+	}
+	if _, err := d.LatestWeightForLocalDay(ctx, alice, "March 1"); err == nil {
+		t.Error("expected a malformed day to be rejected")
+	}
+
+	avg, err := d.AverageWeightForLocalDay(ctx, alice, "2026-03-01")
+	wantAvg := (80 + domain.ConvertWeight(176.37, "lb", "kg")) / 2
+	if err != nil || avg == nil || avg.Unit != "kg" || math.Abs(avg.Value-wantAvg) > 1e-6 || !avg.CreatedAt.Equal(lastOfDay) {
+		t.Errorf("expected the day's mean %.4f kg, got %+v, %v", wantAvg, avg, err)
+	}
+	if none, err := d.AverageWeightForLocalDay(ctx, bob, "2026-03-02"); err != nil || none != nil {
+		t.Errorf("expected nil for a day without weigh-ins, got %+v, %v", none, err)
+	}
+
+	span, err := d.WeightSpanSince(ctx, alice, localTime(t, "2026-03-01", 0, 0, 0, 0))
+	if err != nil || span == nil || span.Count != 3 || span.FirstKg != 80 || span.LastKg != 79 || !span.LastAt.Equal(firstOfNext) {
+		t.Errorf("WeightSpanSince: %+v, %v", span, err)
+	}
+	if span, err := d.WeightSpanSince(ctx, alice, time.Now()); err != nil || span != nil {
+		t.Errorf("expected nil for an empty span, got %+v, %v", span, err)
+	}
+
+	// A retried offline write returns the stored entry instead of adding a
+	// second one, per user.
+	const clientID = "6f1c2d3e-4b5a-4c7d-8e9f-0a1b2c3d4e5f"
+	first, created, err := d.AddWeightEventWithClientID(ctx, alice, clientID, 78.5, "kg", firstOfNext.Add(time.Hour))
+	if err != nil || !created {
+		t.Fatalf("AddWeightEventWithClientID: %v, created %v", err, created)
+	}
+	again, created, err := d.AddWeightEventWithClientID(ctx, alice, clientID, 99, "kg", time.Now())
+	if err != nil || created || again.ID != first.ID || again.Value != 78.5 {
+		t.Errorf("expected the retry to return the stored entry, got %+v, created %v, %v", again, created, err)
+	}
+	if _, created, err := d.AddWeightEventWithClientID(ctx, bob, clientID, 61, "kg", time.Now()); err != nil || !created {
+		t.Errorf("expected client IDs to be scoped per user, got created %v, %v", created, err)
+	}
+
+	items, err := d.ListRecentWeightEvents(ctx, alice, 2)
+	if err != nil || len(items) != 2 || items[0].ClientID != clientID || items[1].Value != 79 {
+		t.Fatalf("expected alice's two newest weigh-ins, got %+v, %v", items, err)
+	}
+	if items, _ := d.ListRecentWeightEvents(ctx, bob, 10); len(items) != 2 {
+		t.Errorf("expected bob to see only his own weigh-ins, got %+v", items)
+	}
+	page, sum, err := d.ListRecentWeightPage(ctx, alice, 2)
+	if err != nil || len(page) != 2 || page[0].ClientID != clientID {
+		t.Fatalf("ListRecentWeightPage = %+v, %v", page, err)
+	}
+	if sum.Total != 4 || !sum.HasMore || sum.FirstAt == nil || sum.LastAt == nil || !sum.LastAt.Equal(page[0].CreatedAt) {
+		t.Errorf("expected a summary of alice's four weigh-ins, got %+v", sum)
+	}
+	if _, sum, err := d.ListRecentWeightPage(ctx, newTestUser(t, d, "dave"), 10); err != nil || sum.Total != 0 || sum.FirstAt != nil {
+		t.Errorf("expected an empty summary, got %+v, %v", sum, err)
+	}
+
+	if ok, err := d.DeleteLatestWeightEvent(ctx, alice); err != nil || !ok {
+		t.Fatalf("DeleteLatestWeightEvent: %v, %v", ok, err)
+	}
+	if items, _ := d.ListRecentWeightEvents(ctx, alice, 10); len(items) != 3 || items[0].Value != 79 {
+		t.Errorf("expected the newest weigh-in removed, got %+v", items)
+	}
+	empty := newTestUser(t, d, "carol")
+	if ok, err := d.DeleteLatestWeightEvent(ctx, empty); err != nil || ok {
+		t.Errorf("expected nothing to delete, got %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationWater(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	// 19:00 local is already the next day in UTC; the total must follow
+	// the local day.
+	for _, e := range []struct {
+		user  int64
+		liter float64
+		at    time.Time
+	}{
+		{alice, 0.5, localTime(t, "2026-03-01", 0, 0, 0, 0)},
+		{alice, 0.25, localTime(t, "2026-03-01", 19, 0, 0, 0)},
+		{alice, -0.1, localTime(t, "2026-03-01", 23, 59, 59, 999999000)},
+		{alice, 2, localTime(t, "2026-03-02", 0, 0, 0, 0)},
+		{alice, 3, localTime(t, "2026-02-28", 23, 59, 59, 999999000)},
+		{bob, 1, localTime(t, "2026-03-01", 12, 0, 0, 0)},
+	} {
+		if _, err := d.AddWaterEvent(ctx, e.user, e.liter, e.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total, err := d.WaterTotalForLocalDay(ctx, alice, "2026-03-01"); err != nil || !approx(total, 0.65) {
+		t.Errorf("expected 0.65 L on 2026-03-01, got %v, %v", total, err)
+	}
+	if total, err := d.WaterTotalForLocalDay(ctx, bob, "2026-03-02"); err != nil || total != 0 {
+		t.Errorf("expected no water for bob on 2026-03-02, got %v, %v", total, err)
+	}
+
+	const clientID = "0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e"
+	e, created, err := d.AddWaterEventWithClientID(ctx, alice, clientID, 0.3, time.Now())
+	if err != nil || !created {
+		t.Fatalf("AddWaterEventWithClientID: %v, created %v", err, created)
+	}
+	if again, created, err := d.AddWaterEventWithClientID(ctx, alice, clientID, 0.9, time.Now()); err != nil || created || again.ID != e.ID || again.DeltaLiters != 0.3 {
+		t.Errorf("expected the retry to return the stored event, got %+v, created %v, %v", again, created, err)
+	}
+
+	items, err := d.ListRecentWaterEvents(ctx, alice, 10)
+	if err != nil || len(items) != 6 || items[0].ID != e.ID || items[0].ClientID != clientID {
+		t.Fatalf("expected alice's six events, newest first, got %+v, %v", items, err)
+	}
+	if page, sum, err := d.ListRecentWaterPage(ctx, alice, 4); err != nil || len(page) != 4 || sum.Total != 6 || !sum.HasMore || sum.FirstAt == nil || !sum.FirstAt.Equal(items[5].CreatedAt) {
+		t.Errorf("expected a page of four with a summary of six, got %+v, %+v, %v", page, sum, err)
+	}
+	// Editing is scoped to the owner and logged.
+	liters, earlier := 0.2, e.CreatedAt.Add(-time.Hour)
+	if got, err := d.UpdateWaterEvent(ctx, bob, e.ID, domain.WaterEventPatch{DeltaLiters: &liters}); err != nil || got != nil {
+		t.Errorf("expected bob's edit to miss, got %+v, %v", got, err)
+	}
+	edited, err := d.UpdateWaterEvent(ctx, alice, e.ID, domain.WaterEventPatch{DeltaLiters: &liters})
+	if err != nil || edited == nil || edited.DeltaLiters != 0.2 || !edited.CreatedAt.Equal(e.CreatedAt) || edited.ClientID != clientID {
+		t.Errorf("expected only the volume changed, got %+v, %v", edited, err)
+	}
+	if edited, err = d.UpdateWaterEvent(ctx, alice, e.ID, domain.WaterEventPatch{CreatedAt: &earlier}); err != nil || edited == nil || edited.DeltaLiters != 0.2 || !edited.CreatedAt.Equal(earlier) {
+		t.Errorf("expected only the time changed, got %+v, %v", edited, err)
+	}
+	if c, err := d.LatestChange(ctx, alice, domain.ChangeEntityWater); err != nil || c == nil || c.EntityID != e.ID || c.Op != domain.ChangeOpUpsert {
+		t.Errorf("expected the edit in the change log, got %+v, %v", c, err)
+	}
+
+	// Deleting is scoped to the owner.
+	if err := d.DeleteWaterEvent(ctx, bob, e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := d.ListRecentWaterEvents(ctx, alice, 10); len(items) != 6 {
+		t.Errorf("expected bob's delete to leave alice's event, got %d events", len(items))
+	}
+	if err := d.DeleteWaterEvent(ctx, alice, e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := d.ListRecentWaterEvents(ctx, alice, 10); len(items) != 5 {
+		t.Errorf("expected the event deleted, got %d events", len(items))
+	}
+}
+
+func TestIntegrationFood(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	for _, e := range []struct {
+		user int64
+		e    domain.FoodEntry
+	}{
+		{alice, domain.FoodEntry{Description: "porridge", Kcal: 300, ProteinG: 10, CreatedAt: localTime(t, "2026-03-01", 0, 0, 0, 0)}},
+		{alice, domain.FoodEntry{Description: "late snack", Kcal: 150, FatG: 8, CreatedAt: localTime(t, "2026-03-01", 23, 59, 59, 999999000)}},
+		{alice, domain.FoodEntry{Description: "midnight toast", Kcal: 80, CreatedAt: localTime(t, "2026-03-02", 0, 0, 0, 0)}},
+		{bob, domain.FoodEntry{Description: "salad", Kcal: 200, CreatedAt: localTime(t, "2026-03-01", 12, 0, 0, 0)}},
+	} {
+		if _, created, err := d.AddFoodEntry(ctx, e.user, e.e); err != nil || !created {
+			t.Fatalf("AddFoodEntry: %v, created %v", err, created)
+		}
+	}
+	totals, err := d.FoodTotalsForLocalDay(ctx, alice, "2026-03-01")
+	if err != nil || totals.Kcal != 450 || totals.ProteinG != 10 || totals.FatG != 8 || totals.Entries != 2 {
+		t.Errorf("expected two entries and 450 kcal on 2026-03-01, got %+v, %v", totals, err)
+	}
+
+	const clientID = "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+	first, _, err := d.AddFoodEntry(ctx, alice, domain.FoodEntry{Description: "eggs", Kcal: 140, ClientID: clientID, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, created, err := d.AddFoodEntry(ctx, alice, domain.FoodEntry{Description: "other", Kcal: 1, ClientID: clientID, CreatedAt: time.Now()})
+	if err != nil || created || again.ID != first.ID || again.Description != "eggs" {
+		t.Errorf("expected the retry to return the stored entry, got %+v, created %v, %v", again, created, err)
+	}
+
+	items, err := d.ListRecentFoodEntries(ctx, alice, 2)
+	if err != nil || len(items) != 2 || items[0].ClientID != clientID || items[1].Description != "midnight toast" {
+		t.Fatalf("expected alice's two newest entries, got %+v, %v", items, err)
+	}
+	if err := d.DeleteFoodEntry(ctx, bob, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteFoodEntry(ctx, alice, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := d.ListRecentFoodEntries(ctx, alice, 10); len(items) != 3 {
+		t.Errorf("expected three entries after the delete, got %+v", items)
+	}
+	if items, _ := d.ListRecentFoodEntries(ctx, bob, 10); len(items) != 1 {
+		t.Errorf("expected bob's delete of alice's entry to do nothing to his own, got %+v", items)
+	}
+}
+
+func TestIntegrationBatchAndChanges(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	if c, err := d.LatestChange(ctx, alice, domain.ChangeEntityWeight); err != nil || c != nil {
+		t.Fatalf("expected no changes yet, got %+v, %v", c, err)
+	}
+
+	now := time.Now()
+	results, err := d.ApplyBatch(ctx, alice, []domain.BatchOp{
+		{Op: domain.BatchOpWeight, Value: 80, Unit: "kg", CreatedAt: now},
+		{Op: domain.BatchOpWater, DeltaLiters: 0.5, ClientID: "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", CreatedAt: now},
+		{Op: domain.BatchOpWater, DeltaLiters: 0.5, ClientID: "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", CreatedAt: now},
+	})
+	if err != nil || len(results) != 3 || !results[1].Created || results[2].Created || results[2].ID != results[1].ID {
+		t.Fatalf("ApplyBatch: %+v, %v", results, err)
+	}
+	weightID := results[0].ID
+
+	// A failing op rolls back the whole batch.
+	if _, err := d.ApplyBatch(ctx, alice, []domain.BatchOp{
+		{Op: domain.BatchOpWater, DeltaLiters: 1, CreatedAt: now},
+		{Op: domain.BatchOpWeight, Value: 80, Unit: "stone", CreatedAt: now},
+	}); err == nil {
+		t.Fatal("expected an invalid unit to fail the batch")
+	}
+	if items, _ := d.ListRecentWaterEvents(ctx, alice, 10); len(items) != 1 {
+		t.Errorf("expected the failed batch to leave no water behind, got %+v", items)
+	}
+
+	// Deletes are scoped to the user and report whether anything went.
+	if results, err := d.ApplyBatch(ctx, bob, []domain.BatchOp{{Op: domain.BatchOpDeleteWeight, ID: weightID}}); err != nil || results[0].Deleted {
+		t.Errorf("expected bob not to delete alice's weight, got %+v, %v", results, err)
+	}
+	if ok, err := d.SetEntryTags(ctx, alice, domain.ChangeEntityWeight, weightID, []string{"travel"}); err != nil || !ok {
+		t.Fatal(err)
+	}
+	if results, err := d.ApplyBatch(ctx, alice, []domain.BatchOp{{Op: domain.BatchOpDeleteWeight, ID: weightID}}); err != nil || !results[0].Deleted {
+		t.Errorf("expected alice's weight deleted, got %+v, %v", results, err)
+	}
+	if tags, _ := d.ListTags(ctx, alice); len(tags) != 0 {
+		t.Errorf("expected the deleted entry's tags removed, got %+v", tags)
+	}
+
+	// The change feed collapses each entity to its latest change.
+	changes, err := d.ListChanges(ctx, alice, 0, 100)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected one change per entity, got %+v, %v", changes, err)
+	}
+	if c := changes[0]; c.Entity != domain.ChangeEntityWater || c.Op != domain.ChangeOpUpsert || c.Water == nil || c.Water.DeltaLiters != 0.5 {
+		t.Errorf("expected the water upsert first, got %+v", c)
+	}
+	if c := changes[1]; c.Entity != domain.ChangeEntityWeight || c.Op != domain.ChangeOpDelete || c.Weight != nil {
+		t.Errorf("expected the weight delete last, without a payload, got %+v", c)
+	}
+	if later, err := d.ListChanges(ctx, alice, changes[0].Seq, 100); err != nil || len(later) != 1 {
+		t.Errorf("expected only changes after the cursor, got %+v, %v", later, err)
+	}
+	if limited, _ := d.ListChanges(ctx, alice, 0, 1); len(limited) != 1 {
+		t.Errorf("expected the limit applied, got %+v", limited)
+	}
+	if c, err := d.LatestChange(ctx, alice, domain.ChangeEntityWeight); err != nil || c == nil || c.Op != domain.ChangeOpDelete {
+		t.Errorf("LatestChange: %+v, %v", c, err)
+	}
+	if changes, _ := d.ListChanges(ctx, bob, 0, 100); len(changes) != 0 {
+		t.Errorf("expected bob's feed to be empty, got %+v", changes)
+	}
+
+	if ids, err := d.ListActiveUserIDs(ctx, now.Add(-time.Minute)); err != nil || !slices.Equal(ids, []int64{alice}) {
+		t.Errorf("expected only alice active, got %v, %v", ids, err)
+	}
+}
+
+func TestIntegrationChangesCommitInOrder(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	logChange := func(q querier, entityID int64) (int64, error) {
+		var seq int64
+		err := q.QueryRowContext(ctx,
+			"INSERT INTO changes(user_id, entity, entity_id, op, changed_at) VALUES ($1, 'weight', $2, 'delete', now()) RETURNING seq;",
+			alice, entityID).Scan(&seq)
+		return seq, err
+	}
+
+	// The first writer logs a change and keeps its transaction open.
+	first, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = first.Rollback() }()
+	firstSeq, err := logChange(first, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An overlapping writer must wait for it rather than take a later seq
+	// and commit first.
+	secondSeq := make(chan int64, 1)
+	go func() {
+		second, err := d.sql.BeginTx(ctx, nil)
+		if err != nil {
+			t.Error(err)
+			secondSeq <- 0
+			return
+		}
+		defer func() { _ = second.Rollback() }()
+		seq, err := logChange(second, 2)
+		if err == nil {
+			err = second.Commit()
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		secondSeq <- seq
+	}()
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var waiting int
+		if err := d.sql.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM pg_stat_activity WHERE datname=current_database() AND wait_event_type='Lock';",
+		).Scan(&waiting); err != nil {
+			t.Fatal(err)
+		}
+		if waiting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the second writer to wait for the first")
+		}
+	}
+	if changes, err := d.ListChanges(ctx, alice, 0, 10); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes visible yet, got %+v, %v", changes, err)
+	}
+
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if seq := <-secondSeq; seq <= firstSeq {
+		t.Fatalf("expected the second change after seq %d, got %d", firstSeq, seq)
+	}
+	changes, err := d.ListChanges(ctx, alice, 0, 10)
+	if err != nil || len(changes) != 2 || changes[0].EntityID != 1 || changes[1].EntityID != 2 {
+		t.Errorf("expected both changes in commit order, got %+v, %v", changes, err)
+	}
+}
+
+func TestIntegrationImportBatches(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+	at := localTime(t, "2026-03-01", 8, 0, 0, 0)
+
+	if created, err := d.ImportWeightEvent(ctx, alice, "a", "", 80, "kg", at); err != nil || !created {
+		t.Fatalf("ImportWeightEvent: %v, %v", created, err)
+	}
+	if created, err := d.ImportWeightEvent(ctx, alice, "b", "", 80, "kg", at); err != nil || created {
+		t.Errorf("expected an identical weight skipped, got %v, %v", created, err)
+	}
+	if created, err := d.ImportWeightEvent(ctx, alice, "b", "", 80, "lb", at); err != nil || !created {
+		t.Errorf("expected a different unit stored, got %v, %v", created, err)
+	}
+	if created, err := d.ImportWeightEvent(ctx, bob, "a", "", 80, "kg", at); err != nil || !created {
+		t.Errorf("expected bob's identical weight stored, got %v, %v", created, err)
+	}
+	const clientID = "3c4d5e6f-7a8b-4c9d-8e0f-1a2b3c4d5e6f"
+	if created, err := d.ImportWaterEvent(ctx, alice, "a", clientID, 0.5, at); err != nil || !created {
+		t.Fatalf("ImportWaterEvent: %v, %v", created, err)
+	}
+	if created, err := d.ImportWaterEvent(ctx, alice, "a", clientID, 0.75, at.Add(time.Hour)); err != nil || created {
+		t.Errorf("expected a reused client ID skipped, got %v, %v", created, err)
+	}
+	if _, err := d.AddWaterEvent(ctx, alice, 0.25, at); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.DeleteImportBatch(ctx, alice, "a"); err != nil || n != 2 {
+		t.Fatalf("expected batch a's two events removed, got %d, %v", n, err)
+	}
+	if weights, _ := d.ListRecentWeightEvents(ctx, alice, 10); len(weights) != 1 || weights[0].Unit != "lb" {
+		t.Errorf("expected only batch b's weight left, got %+v", weights)
+	}
+	if water, _ := d.ListRecentWaterEvents(ctx, alice, 10); len(water) != 1 || water[0].DeltaLiters != 0.25 {
+		t.Errorf("expected only the logged water left, got %+v", water)
+	}
+	if weights, _ := d.ListRecentWeightEvents(ctx, bob, 10); len(weights) != 1 {
+		t.Errorf("expected bob's batch a untouched, got %+v", weights)
+	}
+	if c, _ := d.LatestChange(ctx, alice, domain.ChangeEntityWater); c == nil || c.Op != domain.ChangeOpDelete {
+		t.Errorf("expected the rollback logged as a delete, got %+v", c)
+	}
+	if n, err := d.DeleteImportBatch(ctx, alice, "a"); err != nil || n != 0 {
+		t.Errorf("expected nothing left to roll back, got %d, %v", n, err)
+	}
+}
+
+func TestIntegrationTags(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	w, _ := d.AddWeightEvent(ctx, alice, 80, "kg", time.Now())
+	a, _ := d.AddWaterEvent(ctx, alice, 0.5, time.Now())
+
+	if ok, err := d.SetEntryTags(ctx, bob, domain.ChangeEntityWeight, w, []string{"x"}); err != nil || ok {
+		t.Errorf("expected bob not to tag alice's entry, got %v, %v", ok, err)
+	}
+	if ok, err := d.SetEntryTags(ctx, alice, domain.ChangeEntityWeight, w+1000, []string{"x"}); err != nil || ok {
+		t.Errorf("expected a missing entry to report false, got %v, %v", ok, err)
+	}
+	if _, err := d.SetEntryTags(ctx, alice, "food", w, nil); err == nil {
+		t.Error("expected an unknown entity to be rejected")
+	}
+	if _, err := d.SetEntryTags(ctx, alice, domain.ChangeEntityWeight, w, []string{"sick", "travel"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.SetEntryTags(ctx, alice, domain.ChangeEntityWeight, w, []string{"travel"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.SetEntryTags(ctx, alice, domain.ChangeEntityWater, a, []string{"travel"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := d.EntryTags(ctx, alice, domain.ChangeEntityWeight, []int64{w, w + 1000})
+	if err != nil || len(tags) != 1 || !slices.Equal(tags[w], []string{"travel"}) {
+		t.Errorf("expected the replaced tags, got %v, %v", tags, err)
+	}
+	if tags, err := d.EntryTags(ctx, alice, domain.ChangeEntityWeight, nil); err != nil || len(tags) != 0 {
+		t.Errorf("expected no tags for no ids, got %v, %v", tags, err)
+	}
+	counts, err := d.ListTags(ctx, alice)
+	if err != nil || len(counts) != 1 || counts[0] != (domain.TagCount{Tag: "travel", Count: 2}) {
+		t.Errorf("expected travel twice, got %+v, %v", counts, err)
+	}
+	if counts, _ := d.ListTags(ctx, bob); len(counts) != 0 {
+		t.Errorf("expected bob to have no tags, got %+v", counts)
+	}
+}
+
+func TestIntegrationSettingsAndJournal(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	set := domain.UserSettings{"ui.theme": json.RawMessage(`"dark"`), "ui.units": json.RawMessage(`{"weight": "kg"}`)}
+	if err := d.UpdateSettings(ctx, alice, set, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UpdateSettings(ctx, alice, domain.UserSettings{"ui.theme": json.RawMessage(`"light"`)}, []string{"ui.units", "ui.absent"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetSettings(ctx, alice)
+	if err != nil || len(got) != 1 || string(got["ui.theme"]) != `"light"` {
+		t.Errorf("expected only the updated theme, got %s, %v", got, err)
+	}
+	if got, err := d.GetSettings(ctx, bob); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("expected empty, non-nil settings for bob, got %v, %v", got, err)
+	}
+
+	updated := time.Now().Truncate(time.Microsecond)
+	for _, e := range []domain.JournalEntry{
+		{Day: "2026-02-28", Note: "first", UpdatedAt: updated},
+		{Day: "2026-03-01", Note: "draft", UpdatedAt: updated},
+		{Day: "2026-03-01", Note: "edited", UpdatedAt: updated},
+		{Day: "2026-03-02", Note: "last", UpdatedAt: updated},
+	} {
+		if err := d.SaveJournalEntry(ctx, alice, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SaveJournalEntry(ctx, bob, domain.JournalEntry{Day: "2026-03-01", Note: "bob's", UpdatedAt: updated}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := d.GetJournalEntry(ctx, alice, "2026-03-01")
+	if err != nil || e == nil || e.Note != "edited" || !e.UpdatedAt.Equal(updated) {
+		t.Errorf("expected the edited note, got %+v, %v", e, err)
+	}
+	if e, err := d.GetJournalEntry(ctx, alice, "2026-03-05"); err != nil || e != nil {
+		t.Errorf("expected nil for a day without a note, got %+v, %v", e, err)
+	}
+	// The range is inclusive at both ends.
+	list, err := d.ListJournalEntries(ctx, alice, "2026-03-01", "2026-03-02")
+	if err != nil || len(list) != 2 || list[0].Day != "2026-03-01" || list[1].Day != "2026-03-02" {
+		t.Errorf("ListJournalEntries: %+v, %v", list, err)
+	}
+	if ok, err := d.DeleteJournalEntry(ctx, alice, "2026-03-01"); err != nil || !ok {
+		t.Errorf("DeleteJournalEntry: %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteJournalEntry(ctx, alice, "2026-03-01"); err != nil || ok {
+		t.Errorf("expected a second delete to report false, got %v, %v", ok, err)
+	}
+	if e, _ := d.GetJournalEntry(ctx, bob, "2026-03-01"); e == nil || e.Note != "bob's" {
+		t.Errorf("expected bob's note untouched, got %+v", e)
+	}
+}
+
+func TestIntegrationMood(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	updated := time.Now().Truncate(time.Microsecond)
+	for _, e := range []domain.MoodEntry{
+		{Day: "2026-02-28", Score: 3, UpdatedAt: updated},
+		{Day: "2026-03-01", Score: 1, UpdatedAt: updated},
+		{Day: "2026-03-01", Score: 4, Note: "better", UpdatedAt: updated},
+		{Day: "2026-03-02", Score: 5, UpdatedAt: updated},
+	} {
+		if err := d.SaveMoodEntry(ctx, alice, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SaveMoodEntry(ctx, alice, domain.MoodEntry{Day: "2026-03-03", Score: 6, UpdatedAt: updated}); err == nil {
+		t.Error("expected a score outside 1-5 to be rejected")
+	}
+	if err := d.SaveMoodEntry(ctx, bob, domain.MoodEntry{Day: "2026-03-01", Score: 2, UpdatedAt: updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := d.GetMoodEntry(ctx, alice, "2026-03-01")
+	if err != nil || e == nil || e.Score != 4 || e.Note != "better" || !e.UpdatedAt.Equal(updated) {
+		t.Errorf("expected the replaced mood, got %+v, %v", e, err)
+	}
+	if e, err := d.GetMoodEntry(ctx, alice, "2026-03-05"); err != nil || e != nil {
+		t.Errorf("expected nil for a day without a mood, got %+v, %v", e, err)
+	}
+	list, err := d.ListMoodEntries(ctx, alice, "2026-03-01", "2026-03-02")
+	if err != nil || len(list) != 2 || list[0].Day != "2026-03-01" || list[1].Score != 5 {
+		t.Errorf("ListMoodEntries: %+v, %v", list, err)
+	}
+	recent, err := d.ListRecentMoodEntries(ctx, alice, 2)
+	if err != nil || len(recent) != 2 || recent[0].Day != "2026-03-02" || recent[1].Day != "2026-03-01" {
+		t.Errorf("expected the two latest days, newest first, got %+v, %v", recent, err)
+	}
+	if recent, _ := d.ListRecentMoodEntries(ctx, bob, 10); len(recent) != 1 || recent[0].Score != 2 {
+		t.Errorf("expected only bob's mood, got %+v", recent)
+	}
+}
+
+func TestIntegrationSteps(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	updated := time.Now().Truncate(time.Microsecond)
+	for _, e := range []domain.StepsEntry{
+		{Day: "2026-02-28", Steps: 7000, UpdatedAt: updated},
+		{Day: "2026-03-01", Steps: 2000, UpdatedAt: updated},
+		{Day: "2026-03-01", Steps: 11000, UpdatedAt: updated},
+		{Day: "2026-03-02", Steps: 500, UpdatedAt: updated},
+	} {
+		if err := d.SaveStepsEntry(ctx, alice, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SaveStepsEntry(ctx, alice, domain.StepsEntry{Day: "2026-03-03", Steps: -1, UpdatedAt: updated}); err == nil {
+		t.Error("expected a negative count to be rejected")
+	}
+	if err := d.SaveStepsEntry(ctx, bob, domain.StepsEntry{Day: "2026-03-01", Steps: 42, UpdatedAt: updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := d.GetStepsEntry(ctx, alice, "2026-03-01")
+	if err != nil || e == nil || e.Steps != 11000 || !e.UpdatedAt.Equal(updated) {
+		t.Errorf("expected the replaced count, got %+v, %v", e, err)
+	}
+	if e, err := d.GetStepsEntry(ctx, alice, "2026-03-05"); err != nil || e != nil {
+		t.Errorf("expected nil for a day without steps, got %+v, %v", e, err)
+	}
+	list, err := d.ListStepsEntries(ctx, alice, "2026-03-01", "2026-03-02")
+	if err != nil || len(list) != 2 || list[0].Day != "2026-03-01" || list[1].Steps != 500 {
+		t.Errorf("ListStepsEntries: %+v, %v", list, err)
+	}
+	if list, _ := d.ListStepsEntries(ctx, bob, "2026-01-01", "2026-12-31"); len(list) != 1 || list[0].Steps != 42 {
+		t.Errorf("expected only bob's count, got %+v", list)
+	}
+}
+
+func TestIntegrationConcurrentUndo(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	const n = 8
+	at := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		if _, err := d.AddWeightEvent(ctx, alice, 80, "kg", at); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.AddWaterEvent(ctx, alice, 0.25, at); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.AddFoodEntry(ctx, alice, domain.FoodEntry{Description: "snack", Kcal: 100, CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Undos racing for the same latest row never delete it twice; one that
+	// loses the race deletes nothing. Whatever the race left, sequential
+	// undos then remove, so every event goes exactly once.
+	var (
+		wg                sync.WaitGroup
+		mu                sync.Mutex
+		weights           int
+		waterIDs, foodIDs = map[int64]bool{}, map[int64]bool{}
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			ok, err := d.DeleteLatestWeightEvent(ctx, alice)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				weights++
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			id, err := d.DeleteLatestWaterEvent(ctx, alice)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if id != 0 && waterIDs[id] {
+				t.Errorf("water event %d undone twice", id)
+			}
+			waterIDs[id] = id != 0
+		}()
+		go func() {
+			defer wg.Done()
+			id, err := d.DeleteLatestFoodEntry(ctx, alice)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if id != 0 && foodIDs[id] {
+				t.Errorf("food entry %d undone twice", id)
+			}
+			foodIDs[id] = id != 0
+		}()
+	}
+	wg.Wait()
+
+	for ok, err := d.DeleteLatestWeightEvent(ctx, alice); ok || err != nil; ok, err = d.DeleteLatestWeightEvent(ctx, alice) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		weights++
+	}
+	for id, err := d.DeleteLatestWaterEvent(ctx, alice); id != 0 || err != nil; id, err = d.DeleteLatestWaterEvent(ctx, alice) {
+		if err != nil || waterIDs[id] {
+			t.Fatalf("expected a fresh water event, got %d, %v", id, err)
+		}
+		waterIDs[id] = true
+	}
+	for id, err := d.DeleteLatestFoodEntry(ctx, alice); id != 0 || err != nil; id, err = d.DeleteLatestFoodEntry(ctx, alice) {
+		if err != nil || foodIDs[id] {
+			t.Fatalf("expected a fresh food entry, got %d, %v", id, err)
+		}
+		foodIDs[id] = true
+	}
+	delete(waterIDs, 0)
+	delete(foodIDs, 0)
+	if weights != n || len(waterIDs) != n || len(foodIDs) != n {
+		t.Errorf("expected %d undos each, got %d weights, water %v, food %v", n, weights, waterIDs, foodIDs)
+	}
+}
+
+func TestIntegrationConcurrentUndoSameRow(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	at := time.Now().Add(-time.Hour)
+	older, err := d.AddWeightEvent(ctx, alice, 80, "kg", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := d.AddWeightEvent(ctx, alice, 81, "kg", at.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hold the latest row so both undos pick it and queue on its lock.
+	hold, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = hold.Rollback() }()
+	if _, err := hold.ExecContext(ctx, "SELECT id FROM weight_events WHERE id=$1 FOR UPDATE;", latest); err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ok, err := d.DeleteLatestWeightEvent(ctx, alice)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- ok
+		}()
+	}
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var waiting int
+		if err := d.sql.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM pg_stat_activity WHERE datname=current_database() AND wait_event_type='Lock';",
+		).Scan(&waiting); err != nil {
+			t.Fatal(err)
+		}
+		if waiting == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected both undos waiting on the lock, got %d", waiting)
+		}
+	}
+	if err := hold.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Under READ COMMITTED the undo that waited longer finds its row gone
+	// and deletes nothing rather than taking the older event.
+	first, second := <-results, <-results
+	if first == second {
+		t.Errorf("expected exactly one undo to report success, got %v and %v", first, second)
+	}
+	items, err := d.ListRecentWeightEvents(ctx, alice, 10)
+	if err != nil || len(items) != 1 || items[0].ID != older {
+		t.Errorf("expected only the older event left, got %+v, %v", items, err)
+	}
+}
+
+func TestIntegrationExport(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	at := localTime(t, "2026-03-01", 8, 0, 0, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := d.AddWeightEvent(ctx, alice, 80+float64(i), "kg", at.Add(time.Duration(2-i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.AddWaterEvent(ctx, alice, 0.25, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.AddWaterEvent(ctx, bob, 1, at); err != nil {
+		t.Fatal(err)
+	}
+
+	var weights []float64
+	err := d.EachWeightEvent(ctx, alice, func(e domain.WeightEntry) error {
+		if e.Day != "2026-03-01" {
+			t.Errorf("expected the local day set, got %+v", e)
+		}
+		weights = append(weights, e.Value)
+		return nil
+	})
+	if err != nil || len(weights) != 3 || weights[0] != 82 || weights[2] != 80 {
+		t.Errorf("expected alice's weigh-ins oldest first, got %v, %v", weights, err)
+	}
+
+	// Events at the same instant come in insertion order, and an error from
+	// fn stops the iteration.
+	var ids []int64
+	stop := errors.New("stop")
+	err = d.EachWaterEvent(ctx, alice, func(e domain.WaterEvent) error {
+		ids = append(ids, e.ID)
+		if len(ids) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || len(ids) != 2 || ids[0] >= ids[1] {
+		t.Errorf("expected two events in ID order and the stop error, got %v, %v", ids, err)
+	}
+}
+
+func TestIntegrationTemperature(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	now := time.Now().Truncate(time.Microsecond)
+	for i, r := range []domain.TemperatureReading{
+		{Value: 36.8, Unit: domain.TemperatureCelsius},
+		{Value: 100.2, Unit: domain.TemperatureFahrenheit},
+	} {
+		r.CreatedAt = now.Add(time.Duration(i) * time.Hour)
+		if _, created, err := d.AddTemperatureReading(ctx, alice, r); err != nil || !created {
+			t.Fatalf("AddTemperatureReading = %v, %v", created, err)
+		}
+	}
+	if _, _, err := d.AddTemperatureReading(ctx, alice, domain.TemperatureReading{Value: 300, Unit: "k", CreatedAt: now}); err == nil {
+		t.Error("expected an unknown unit to be rejected")
+	}
+	retry := domain.TemperatureReading{Value: 37.2, Unit: domain.TemperatureCelsius, ClientID: "t-1", CreatedAt: now.Add(-time.Hour)}
+	first, _, err := d.AddTemperatureReading(ctx, alice, retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, created, err := d.AddTemperatureReading(ctx, alice, retry)
+	if err != nil || created || again.ID != first.ID || again.ClientID != "t-1" {
+		t.Errorf("expected the retry to return reading %d, got %+v (created %v, %v)", first.ID, again, created, err)
+	}
+
+	list, err := d.ListRecentTemperatureReadings(ctx, alice, 2)
+	if err != nil || len(list) != 2 || list[0].Unit != domain.TemperatureFahrenheit || !approx(list[0].Value, 100.2) {
+		t.Errorf("expected the latest two readings newest first, got %+v, %v", list, err)
+	}
+	if list, _ := d.ListRecentTemperatureReadings(ctx, bob, 10); len(list) != 0 {
+		t.Errorf("expected bob to see no readings, got %+v", list)
+	}
+
+	if ok, _ := d.DeleteTemperatureReading(ctx, bob, first.ID); ok {
+		t.Error("expected bob's delete of alice's reading to fail")
+	}
+	if ok, err := d.DeleteTemperatureReading(ctx, alice, first.ID); err != nil || !ok {
+		t.Fatalf("DeleteTemperatureReading = %v, %v", ok, err)
+	}
+	if list, _ := d.ListRecentTemperatureReadings(ctx, alice, 10); len(list) != 2 {
+		t.Errorf("expected two readings left, got %d", len(list))
+	}
+}
+
+func TestIntegrationCustomMetrics(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	created := time.Now().Truncate(time.Microsecond)
+	m, err := d.CreateCustomMetric(ctx, domain.CustomMetric{UserID: alice, Slug: "coffee", Name: "Coffee", Unit: "cups", Aggregation: domain.MetricSum, CreatedAt: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateCustomMetric(ctx, domain.CustomMetric{UserID: alice, Slug: "coffee", Name: "Coffee", Aggregation: domain.MetricSum, CreatedAt: created}); err == nil {
+		t.Error("expected a duplicate slug to be rejected")
+	}
+	if _, err := d.CreateCustomMetric(ctx, domain.CustomMetric{UserID: bob, Slug: "coffee", Name: "Coffee", Aggregation: domain.MetricLatest, CreatedAt: created}); err != nil {
+		t.Errorf("expected bob to reuse the slug, got %v", err)
+	}
+	got, err := d.GetCustomMetric(ctx, alice, "coffee")
+	if err != nil || got == nil || got.ID != m.ID || got.Unit != "cups" || got.Aggregation != domain.MetricSum || !got.CreatedAt.Equal(created) {
+		t.Errorf("GetCustomMetric = %+v, %v", got, err)
+	}
+	if got, err := d.GetCustomMetric(ctx, alice, "tea"); err != nil || got != nil {
+		t.Errorf("expected nil for an unknown slug, got %+v, %v", got, err)
+	}
+
+	dayStart := localTime(t, "2026-03-01", 0, 0, 0, 0)
+	for i, v := range []float64{1, 2, 3} {
+		e := domain.CustomMetricEvent{MetricID: m.ID, Value: v, CreatedAt: dayStart.Add(time.Duration(i*12-1) * time.Hour)}
+		if _, created, err := d.AddCustomMetricEvent(ctx, alice, e); err != nil || !created {
+			t.Fatalf("AddCustomMetricEvent = %v, %v", created, err)
+		}
+	}
+	retry := domain.CustomMetricEvent{MetricID: m.ID, Value: 1, ClientID: "m-1", CreatedAt: dayStart.Add(time.Hour)}
+	first, _, err := d.AddCustomMetricEvent(ctx, alice, retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, created, err := d.AddCustomMetricEvent(ctx, alice, retry); err != nil || created || again.ID != first.ID {
+		t.Errorf("expected the retry to return event %d, got %+v (created %v, %v)", first.ID, again, created, err)
+	}
+
+	day, err := d.ListCustomMetricEventsBetween(ctx, alice, m.ID, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil || len(day) != 3 || day[0].Value != 1 || day[1].Value != 2 || day[2].Value != 3 {
+		t.Errorf("expected the three events from 2026-03-01 oldest first, got %+v, %v", day, err)
+	}
+	recent, err := d.ListRecentCustomMetricEvents(ctx, alice, m.ID, 2)
+	if err != nil || len(recent) != 2 || recent[0].Value != 3 {
+		t.Errorf("expected the latest two events newest first, got %+v, %v", recent, err)
+	}
+	if list, _ := d.ListCustomMetrics(ctx, bob); len(list) != 1 || list[0].Aggregation != domain.MetricLatest {
+		t.Errorf("expected only bob's metric, got %+v", list)
+	}
+
+	if ok, _ := d.DeleteCustomMetric(ctx, bob, m.ID); ok {
+		t.Error("expected bob's delete of alice's metric to fail")
+	}
+	if ok, err := d.DeleteCustomMetric(ctx, alice, m.ID); err != nil || !ok {
+		t.Fatalf("DeleteCustomMetric = %v, %v", ok, err)
+	}
+	if events, _ := d.ListRecentCustomMetricEvents(ctx, alice, m.ID, 10); len(events) != 0 {
+		t.Errorf("expected the events deleted with the metric, got %d", len(events))
+	}
+}
+
+func TestIntegrationMedications(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	created := time.Now().Truncate(time.Microsecond)
+	med, err := d.CreateMedication(ctx, domain.Medication{UserID: alice, Name: "Vitamin D", Dose: "1000 IU", Schedule: []string{"08:00", "20:00"}, CreatedAt: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetMedication(ctx, alice, med.ID)
+	if err != nil || got == nil || got.Dose != "1000 IU" || len(got.Schedule) != 2 || !got.CreatedAt.Equal(created) {
+		t.Errorf("GetMedication: %+v, %v", got, err)
+	}
+	if got, err := d.GetMedication(ctx, bob, med.ID); err != nil || got != nil {
+		t.Errorf("expected bob not to see alice's medication, got %+v, %v", got, err)
+	}
+
+	med.Name, med.Schedule = "Vitamin D3", []string{}
+	if ok, err := d.UpdateMedication(ctx, domain.Medication{ID: med.ID, UserID: bob, Name: "x"}); err != nil || ok {
+		t.Errorf("expected bob's update to miss, got %v, %v", ok, err)
+	}
+	if ok, err := d.UpdateMedication(ctx, *med); err != nil || !ok {
+		t.Fatalf("UpdateMedication: %v, %v", ok, err)
+	}
+	list, err := d.ListMedications(ctx, alice)
+	if err != nil || len(list) != 1 || list[0].Name != "Vitamin D3" || list[0].Schedule == nil || len(list[0].Schedule) != 0 {
+		t.Errorf("ListMedications: %+v, %v", list, err)
+	}
+
+	at := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	const clientID = "6f1c2f4e-8a7b-4c1d-9e2f-3a4b5c6d7e8f"
+	first, ok, err := d.AddMedicationEvent(ctx, alice, domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationTaken, ClientID: clientID, CreatedAt: at})
+	if err != nil || !ok {
+		t.Fatalf("AddMedicationEvent: %v, %v", ok, err)
+	}
+	again, ok, err := d.AddMedicationEvent(ctx, alice, domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationSkipped, ClientID: clientID, CreatedAt: at})
+	if err != nil || ok || again.ID != first.ID || again.Status != domain.MedicationTaken {
+		t.Errorf("expected the retry to return the stored event, got %+v, %v, %v", again, ok, err)
+	}
+	if _, _, err := d.AddMedicationEvent(ctx, alice, domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationSkipped, CreatedAt: at.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	events, err := d.ListRecentMedicationEvents(ctx, alice, med.ID, 10)
+	if err != nil || len(events) != 2 || events[0].Status != domain.MedicationSkipped || events[1].ClientID != clientID {
+		t.Errorf("ListRecentMedicationEvents: %+v, %v", events, err)
+	}
+	if events, _ := d.ListRecentMedicationEvents(ctx, bob, 0, 10); len(events) != 0 {
+		t.Errorf("expected bob to see no events, got %+v", events)
+	}
+
+	if ok, err := d.DeleteMedication(ctx, alice, med.ID); err != nil || !ok {
+		t.Fatalf("DeleteMedication: %v, %v", ok, err)
+	}
+	if events, _ := d.ListRecentMedicationEvents(ctx, alice, 0, 10); len(events) != 0 {
+		t.Errorf("expected the events deleted with the medication, got %+v", events)
+	}
+}
+
+func TestIntegrationHydration(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	if hs, err := d.GetHydrationSettings(ctx, alice); err != nil || hs != nil {
+		t.Fatalf("expected no settings yet, got %+v, %v", hs, err)
+	}
+	lat, lon := 51.5, -0.12
+	if err := d.SaveHydrationSettings(ctx, domain.HydrationSettings{UserID: alice, BaseGoalLiters: 2, Latitude: &lat, Longitude: &lon}); err != nil {
+		t.Fatal(err)
+	}
+	hs, err := d.GetHydrationSettings(ctx, alice)
+	if err != nil || hs == nil || hs.BaseGoalLiters != 2 || hs.Latitude == nil || *hs.Latitude != lat {
+		t.Fatalf("GetHydrationSettings: %+v, %v", hs, err)
+	}
+	if err := d.SaveHydrationSettings(ctx, domain.HydrationSettings{UserID: alice, BaseGoalLiters: 2.5}); err != nil {
+		t.Fatal(err)
+	}
+	if hs, _ := d.GetHydrationSettings(ctx, alice); hs == nil || hs.BaseGoalLiters != 2.5 || hs.Latitude != nil {
+		t.Errorf("expected the location cleared, got %+v", hs)
+	}
+
+	for _, g := range []struct {
+		day    string
+		liters float64
+	}{{"2026-03-02", 2.5}, {"2026-03-01", 2}, {"2026-03-02", 3}} {
+		if err := d.RecordGoal(ctx, alice, g.day, g.liters); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := d.GoalHistory(ctx, alice)
+	want := domain.GoalHistory{{Day: "2026-03-01", Liters: 2}, {Day: "2026-03-02", Liters: 3}}
+	if err != nil || !slices.Equal(history, want) {
+		t.Errorf("expected %v, got %v, %v", want, history, err)
+	}
+
+	target := 72.5
+	if err := d.RecordWeightGoal(ctx, alice, "2026-03-01", &target); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordWeightGoal(ctx, alice, "2026-03-05", nil); err != nil {
+		t.Fatal(err)
+	}
+	weightGoals, err := d.WeightGoalHistory(ctx, alice)
+	if err != nil || len(weightGoals) != 2 || *weightGoals.TargetOn("2026-03-04") != 72.5 || weightGoals.TargetOn("2026-03-05") != nil {
+		t.Errorf("expected the target set and then cleared, got %+v, %v", weightGoals, err)
+	}
+}
+
+func TestIntegrationOutbox(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	if err := d.SaveAlertRule(ctx, domain.AlertRule{UserID: alice, MaxWeeklyChangePct: 2, Channel: "webhook", Target: "https://hook", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Truncate(time.Microsecond)
+	n := domain.Notification{UserID: alice, Kind: "weight.rapid-change", Title: "Rapid weight loss", At: at, Target: "https://hook"}
+	if err := d.MarkAlerted(ctx, alice, at, domain.OutboxMessage{Channel: "webhook", Notification: n}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.EnqueueOutbox(ctx, domain.OutboxMessage{Channel: "mqtt", Notification: domain.Notification{UserID: bob, Title: "ready"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claimed, err := d.ClaimOutbox(ctx, now, now.Add(time.Minute), 10)
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("ClaimOutbox: %+v, %v", claimed, err)
+	}
+	got := claimed[0].Notification
+	if claimed[0].Channel != "webhook" || got.Target != "https://hook" || got.Title != n.Title || !got.At.Equal(at) || got.UserID != alice {
+		t.Errorf("unexpected message %+v", claimed[0])
+	}
+	if again, err := d.ClaimOutbox(ctx, now, now.Add(time.Minute), 10); err != nil || len(again) != 0 {
+		t.Errorf("expected leased messages skipped, got %+v, %v", again, err)
+	}
+
+	if err := d.DeleteOutbox(ctx, claimed[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	retry := now.Add(30 * time.Second)
+	if err := d.FailOutbox(ctx, claimed[1].ID, "boom", &retry); err != nil {
+		t.Fatal(err)
+	}
+	left, err := d.ClaimOutbox(ctx, now.Add(time.Hour), now.Add(2*time.Hour), 10)
+	if err != nil || len(left) != 1 || left[0].Attempts != 1 || left[0].LastError != "boom" {
+		t.Fatalf("expected the failed message due again, got %+v, %v", left, err)
+	}
+	if err := d.FailOutbox(ctx, left[0].ID, "boom", nil); err != nil {
+		t.Fatal(err)
+	}
+	if none, err := d.ClaimOutbox(ctx, now.Add(24*time.Hour), now.Add(25*time.Hour), 10); err != nil || len(none) != 0 {
+		t.Errorf("expected a given up message never claimed, got %+v, %v", none, err)
+	}
+}
+
+func TestIntegrationRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	now := time.Now()
+
+	if err := d.ReplaceRecoveryCodes(ctx, alice, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ReplaceRecoveryCodes(ctx, alice, []string{"c", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.IssueRecoveryCode(ctx, alice, "issued", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.CountRecoveryCodes(ctx, alice); err != nil || n != 2 {
+		t.Fatalf("CountRecoveryCodes = %d, %v", n, err)
+	}
+	if ok, err := d.RedeemRecoveryCode(ctx, alice, "a", now, "hash"); err != nil || ok {
+		t.Errorf("expected a replaced code to be rejected, got %v, %v", ok, err)
+	}
+	if ok, err := d.RedeemRecoveryCode(ctx, alice, "issued", now.Add(2*time.Hour), "hash"); err != nil || ok {
+		t.Errorf("expected an expired code to be rejected, got %v, %v", ok, err)
+	}
+	if ok, err := d.RedeemRecoveryCode(ctx, alice, "issued", now, "hash"); err != nil || !ok {
+		t.Fatalf("RedeemRecoveryCode = %v, %v", ok, err)
+	}
+	if u, _ := d.GetByID(ctx, alice); u.PasswordHash != "hash" {
+		t.Errorf("expected the password hash set, got %q", u.PasswordHash)
+	}
+	if ok, _ := d.RedeemRecoveryCode(ctx, alice, "issued", now, "hash"); ok {
+		t.Error("expected a redeemed code to be spent")
+	}
+}
+
+func TestIntegrationWebhooks(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	hook, err := d.CreateWebhook(ctx, domain.Webhook{UserID: alice, URL: "https://hook", Secret: "s3cret",
+		Events: []string{"goal.reached", "water.recorded"}, Enabled: true, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetWebhook(ctx, alice, hook.ID)
+	if err != nil || got == nil || got.Secret != "s3cret" || len(got.Events) != 2 || got.URL != "https://hook" {
+		t.Fatalf("GetWebhook = %+v, %v", got, err)
+	}
+	if other, err := d.GetWebhook(ctx, bob, hook.ID); err != nil || other != nil {
+		t.Errorf("expected alice's webhook hidden from bob, got %+v, %v", other, err)
+	}
+	hook.Enabled, hook.Events = false, []string{"weight.recorded"}
+	if ok, err := d.UpdateWebhook(ctx, *hook); err != nil || !ok {
+		t.Fatalf("UpdateWebhook = %v, %v", ok, err)
+	}
+
+	payload := []byte(`{"event":"water.recorded","data":{"value":0.5}}`)
+	if err := d.EnqueueWebhookDeliveries(ctx,
+		domain.WebhookDelivery{WebhookID: hook.ID, UserID: alice, Event: "water.recorded", Payload: payload},
+		domain.WebhookDelivery{WebhookID: hook.ID, UserID: alice, Event: "water.recorded", Payload: payload},
+	); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	claimed, err := d.ClaimWebhookDeliveries(ctx, now, now.Add(time.Minute), 10)
+	if err != nil || len(claimed) != 2 || claimed[0].UserID != alice || claimed[0].Event != "water.recorded" {
+		t.Fatalf("ClaimWebhookDeliveries: %+v, %v", claimed, err)
+	}
+	if again, err := d.ClaimWebhookDeliveries(ctx, now, now.Add(time.Minute), 10); err != nil || len(again) != 0 {
+		t.Errorf("expected leased deliveries skipped, got %+v, %v", again, err)
+	}
+	if err := d.CompleteWebhookDelivery(ctx, claimed[0].ID, 200, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.FailWebhookDelivery(ctx, claimed[1].ID, 502, "bad gateway", nil); err != nil {
+		t.Fatal(err)
+	}
+	log, err := d.ListWebhookDeliveries(ctx, alice, hook.ID, 10)
+	if err != nil || len(log) != 2 || log[0].StatusCode != 502 || log[1].DeliveredAt == nil || log[1].Attempts != 1 {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v", log, err)
+	}
+	if n, err := d.PruneWebhookDeliveries(ctx, now.Add(time.Hour)); err != nil || n != 2 {
+		t.Errorf("PruneWebhookDeliveries = %d, %v", n, err)
+	}
+
+	if ok, err := d.DeleteWebhook(ctx, bob, hook.ID); err != nil || ok {
+		t.Errorf("expected bob unable to delete alice's webhook, got %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteWebhook(ctx, alice, hook.ID); err != nil || !ok {
+		t.Errorf("DeleteWebhook = %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationAlertsAndRules(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	if r, err := d.GetAlertRule(ctx, alice); err != nil || r != nil {
+		t.Fatalf("expected no alert rule yet, got %+v, %v", r, err)
+	}
+	for _, r := range []domain.AlertRule{
+		{UserID: alice, MaxWeeklyChangePct: 2, Channel: "webhook", Target: "https://hook", Enabled: true},
+		{UserID: bob, MaxWeeklyChangePct: 3, Channel: "email", Enabled: false},
+	} {
+		if err := d.SaveAlertRule(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := time.Now().Truncate(time.Microsecond)
+	if err := d.MarkAlerted(ctx, alice, at); err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.GetAlertRule(ctx, alice)
+	if err != nil || r == nil || r.Target != "https://hook" || r.LastAlertedAt == nil || !r.LastAlertedAt.Equal(at) {
+		t.Errorf("GetAlertRule: %+v, %v", r, err)
+	}
+	if rules, err := d.ListAlertRules(ctx); err != nil || len(rules) != 1 || rules[0].UserID != alice {
+		t.Errorf("expected only the enabled rule listed, got %+v, %v", rules, err)
+	}
+
+	created, err := d.CreateRule(ctx, domain.Rule{
+		UserID: alice, Name: "hydrate", Condition: domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00"},
+		Channel: "webhook", Target: "https://hook", Enabled: true, CreatedAt: time.Now(),
+	})
+	if err != nil || created.ID == 0 {
+		t.Fatalf("CreateRule: %+v, %v", created, err)
+	}
+	if _, err := d.CreateRule(ctx, domain.Rule{
+		UserID: bob, Name: "weigh", Condition: domain.RuleCondition{Kind: domain.RuleWeighInMissed, Days: 3},
+		Channel: "email", Enabled: false, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := d.GetRule(ctx, bob, created.ID); err != nil || r != nil {
+		t.Errorf("expected bob not to see alice's rule, got %+v, %v", r, err)
+	}
+
+	updated := *created
+	updated.Name = "drink"
+	updated.Condition = domain.RuleCondition{Kind: domain.RuleWeightAbove, Threshold: 90}
+	if ok, err := d.UpdateRule(ctx, updated); err != nil || !ok {
+		t.Fatalf("UpdateRule: %v, %v", ok, err)
+	}
+	stolen := updated
+	stolen.UserID = bob
+	if ok, err := d.UpdateRule(ctx, stolen); err != nil || ok {
+		t.Errorf("expected bob not to update alice's rule, got %v, %v", ok, err)
+	}
+	fired := time.Now().Truncate(time.Microsecond)
+	if err := d.MarkRuleFired(ctx, alice, created.ID, fired); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetRule(ctx, alice, created.ID)
+	if err != nil || got == nil || got.Name != "drink" || got.Condition.Threshold != 90 || got.LastFiredAt == nil || !got.LastFiredAt.Equal(fired) {
+		t.Errorf("GetRule: %+v, %v", got, err)
+	}
+
+	if rules, err := d.ListRules(ctx, alice); err != nil || len(rules) != 1 {
+		t.Errorf("ListRules: %+v, %v", rules, err)
+	}
+	if rules, err := d.ListEnabledRules(ctx); err != nil || len(rules) != 1 || rules[0].UserID != alice {
+		t.Errorf("expected only alice's enabled rule, got %+v, %v", rules, err)
+	}
+	if ok, err := d.DeleteRule(ctx, bob, created.ID); err != nil || ok {
+		t.Errorf("expected bob not to delete alice's rule, got %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteRule(ctx, alice, created.ID); err != nil || !ok {
+		t.Errorf("DeleteRule: %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationPlannedEntries(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	ring, err := fieldcrypt.New(fieldcrypt.Key{ID: "k1", Secret: []byte(strings.Repeat("k", 32))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.WithCipher(ring)
+
+	created := time.Now().Truncate(time.Microsecond)
+	var ids []int64
+	for _, p := range []domain.PlannedEntry{
+		{UserID: alice, Kind: domain.PlanKindWater, Day: "2026-03-02", At: "12:00", Liters: 2, Note: "secret note", Channel: domain.AlertChannelWebhook, Target: "https://hook"},
+		{UserID: alice, Kind: domain.PlanKindWeight, Day: "2026-03-01", At: "08:00", Channel: domain.AlertChannelWebhook},
+		{UserID: alice, Kind: domain.PlanKindWeight, Day: "2026-03-02"},
+		{UserID: bob, Kind: domain.PlanKindWeight, Day: "2026-03-02", At: "07:00", Channel: domain.AlertChannelWebhook},
+	} {
+		p.CreatedAt = created
+		entry, err := d.CreatePlannedEntry(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	var stored string
+	if err := d.sql.QueryRowContext(ctx, "SELECT note FROM planned_entries WHERE id=$1;", ids[0]).Scan(&stored); err != nil || strings.Contains(stored, "secret") {
+		t.Errorf("expected the note sealed at rest, got %q, %v", stored, err)
+	}
+
+	items, err := d.ListPlannedEntries(ctx, alice, "2026-03-01", "2026-03-02")
+	if err != nil || len(items) != 3 || items[0].Day != "2026-03-01" || items[2].Note != "secret note" || items[2].Target != "https://hook" || items[2].Liters != 2 {
+		t.Fatalf("ListPlannedEntries: %+v, %v", items, err)
+	}
+	if n, err := d.CountPlannedEntries(ctx, alice, "2026-03-02"); err != nil || n != 2 {
+		t.Errorf("CountPlannedEntries = %d, %v", n, err)
+	}
+
+	due, err := d.ListPlanReminders(ctx, "2026-03-02")
+	if err != nil || len(due) != 2 || due[0].UserID != alice || due[1].UserID != bob {
+		t.Fatalf("ListPlanReminders: %+v, %v", due, err)
+	}
+	reminded := time.Now().Truncate(time.Microsecond)
+	msg := domain.OutboxMessage{Channel: domain.AlertChannelWebhook, Notification: domain.Notification{UserID: alice, Kind: "plan.water", At: reminded}}
+	if err := d.MarkPlanReminded(ctx, alice, ids[0], reminded, msg); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.GetPlannedEntry(ctx, alice, ids[0]); err != nil || got == nil || got.RemindedAt == nil || !got.RemindedAt.Equal(reminded) {
+		t.Errorf("GetPlannedEntry: %+v, %v", got, err)
+	}
+	if due, _ := d.ListPlanReminders(ctx, "2026-03-02"); len(due) != 1 || due[0].UserID != bob {
+		t.Errorf("expected the reminded entry skipped, got %+v", due)
+	}
+	var queued int
+	if err := d.sql.QueryRowContext(ctx, "SELECT COUNT(*) FROM notification_outbox WHERE user_id=$1;", alice).Scan(&queued); err != nil || queued != 1 {
+		t.Errorf("expected the reminder queued, got %d, %v", queued, err)
+	}
+
+	moved := items[2]
+	moved.Day = "2026-03-03"
+	if ok, err := d.UpdatePlannedEntry(ctx, moved); err != nil || !ok {
+		t.Fatalf("UpdatePlannedEntry: %v, %v", ok, err)
+	}
+	if got, _ := d.GetPlannedEntry(ctx, alice, moved.ID); got == nil || got.Day != "2026-03-03" || got.RemindedAt != nil || !got.CreatedAt.Equal(created) {
+		t.Errorf("expected the entry moved and its reminder re-armed, got %+v", got)
+	}
+	moved.UserID = bob
+	if ok, err := d.UpdatePlannedEntry(ctx, moved); err != nil || ok {
+		t.Errorf("expected bob not to update alice's entry, got %v, %v", ok, err)
+	}
+	if ok, err := d.DeletePlannedEntry(ctx, bob, moved.ID); err != nil || ok {
+		t.Errorf("expected bob not to delete alice's entry, got %v, %v", ok, err)
+	}
+	if ok, err := d.DeletePlannedEntry(ctx, alice, moved.ID); err != nil || !ok {
+		t.Errorf("DeletePlannedEntry: %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationWeeklySummaries(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	kg := 80.0
+	sums := []domain.WeeklySummary{
+		{WeekStart: "2026-02-23", WeekEnd: "2026-03-01", WeighIns: 1, AvgKg: &kg, TotalWaterLiters: 14, AvgWaterLiters: 2, GoalDays: 7},
+		{WeekStart: "2026-03-02", WeekEnd: "2026-03-08", TotalWaterLiters: 7, AvgWaterLiters: 1},
+	}
+	if err := d.SaveWeeklySummaries(ctx, alice, sums); err != nil {
+		t.Fatal(err)
+	}
+	sums[1].GoalDays = 3
+	if err := d.SaveWeeklySummaries(ctx, alice, sums[1:]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.ListWeeklySummaries(ctx, alice, "2026-02-23")
+	if err != nil || len(got) != 2 || got[0].WeekStart != "2026-03-02" || got[0].GoalDays != 3 || got[0].AvgKg != nil {
+		t.Fatalf("expected the recomputed week first, got %+v, %v", got, err)
+	}
+	if got[1].AvgKg == nil || *got[1].AvgKg != 80 || got[1].WeekEnd != "2026-03-01" {
+		t.Errorf("unexpected first week %+v", got[1])
+	}
+	if got, _ := d.ListWeeklySummaries(ctx, alice, "2026-03-02"); len(got) != 1 {
+		t.Errorf("expected weeks from the given Monday only, got %+v", got)
+	}
+	if got, _ := d.ListWeeklySummaries(ctx, bob, "2000-01-01"); len(got) != 0 {
+		t.Errorf("expected bob to have no summaries, got %+v", got)
+	}
+}
+
+func TestIntegrationProfilesSharesTokens(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	p, err := d.CreateProfile(ctx, alice, "Kid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.GetProfile(ctx, alice, p.ID); err != nil || got == nil || got.Name != "Kid" {
+		t.Errorf("GetProfile: %+v, %v", got, err)
+	}
+	if got, err := d.GetProfile(ctx, bob, p.ID); err != nil || got != nil {
+		t.Errorf("expected bob not to see alice's profile, got %+v, %v", got, err)
+	}
+	if list, err := d.ListProfiles(ctx, alice); err != nil || len(list) != 1 || list[0].ID != p.ID {
+		t.Errorf("ListProfiles: %+v, %v", list, err)
+	}
+	if list, _ := d.ListProfiles(ctx, bob); len(list) != 0 {
+		t.Errorf("expected bob to have no profiles, got %+v", list)
+	}
+
+	sh, err := d.CreateShare(ctx, alice, bob)
+	if err != nil || sh == nil || sh.OwnerUsername != "alice" || sh.ViewerUsername != "bob" {
+		t.Fatalf("CreateShare: %+v, %v", sh, err)
+	}
+	if again, err := d.CreateShare(ctx, alice, bob); err != nil || again == nil || !again.CreatedAt.Equal(sh.CreatedAt) {
+		t.Errorf("expected sharing twice to keep the first share, got %+v, %v", again, err)
+	}
+	if got, err := d.GetShare(ctx, bob, alice); err != nil || got != nil {
+		t.Errorf("expected shares to be one-way, got %+v, %v", got, err)
+	}
+	for _, user := range []int64{alice, bob} {
+		if list, err := d.ListShares(ctx, user); err != nil || len(list) != 1 {
+			t.Errorf("expected user %d to see the share, got %+v, %v", user, list, err)
+		}
+	}
+	if ok, err := d.DeleteShare(ctx, alice, bob); err != nil || !ok {
+		t.Errorf("DeleteShare: %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteShare(ctx, alice, bob); err != nil || ok {
+		t.Errorf("expected a second delete to report false, got %v, %v", ok, err)
+	}
+
+	tok, err := d.CreateAPIToken(ctx, alice, "shortcut", domain.TokenScopeQuick, "token-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateAPIToken(ctx, bob, "clash", domain.TokenScopeFeed, "token-hash"); err == nil {
+		t.Error("expected a duplicate token hash to be rejected")
+	}
+	if got, err := d.GetAPITokenByHash(ctx, "token-hash"); err != nil || got == nil || got.UserID != alice || got.Scope != domain.TokenScopeQuick {
+		t.Errorf("GetAPITokenByHash: %+v, %v", got, err)
+	}
+	if got, err := d.GetAPITokenByHash(ctx, "unknown"); err != nil || got != nil {
+		t.Errorf("expected nil for an unknown hash, got %+v, %v", got, err)
+	}
+	used := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	if err := d.TouchAPIToken(ctx, tok.ID, used); err != nil {
+		t.Fatalf("TouchAPIToken: %v", err)
+	}
+	if list, err := d.ListAPITokens(ctx, alice); err != nil || len(list) != 1 || list[0].LastUsedAt == nil || !list[0].LastUsedAt.Equal(used) {
+		t.Errorf("expected the last use listed, got %+v, %v", list, err)
+	}
+	if list, err := d.ListAPITokens(ctx, bob); err != nil || list == nil || len(list) != 0 {
+		t.Errorf("expected an empty, non-nil list for bob, got %+v, %v", list, err)
+	}
+	if ok, err := d.DeleteAPIToken(ctx, bob, tok.ID); err != nil || ok {
+		t.Errorf("expected bob not to delete alice's token, got %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteAPIToken(ctx, alice, tok.ID); err != nil || !ok {
+		t.Errorf("DeleteAPIToken: %v, %v", ok, err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if _, err := d.CreateAPIToken(ctx, alice, name, domain.TokenScopeAPI, "hash-"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := d.DeleteAPITokens(ctx, alice); err != nil || n != 2 {
+		t.Errorf("DeleteAPITokens: %d, %v", n, err)
+	}
+}
+
+func TestIntegrationOAuthTokens(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	ring, err := fieldcrypt.New(fieldcrypt.Key{ID: "k1", Secret: []byte(strings.Repeat("k", 32))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.WithCipher(ring)
+
+	if tok, err := d.GetOAuthToken(ctx, alice, domain.IntegrationGoogleFit); err != nil || tok != nil {
+		t.Fatalf("expected no token yet, got %+v, %v", tok, err)
+	}
+	connected := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	for _, user := range []int64{bob, alice} {
+		tok := domain.OAuthToken{UserID: user, Provider: domain.IntegrationGoogleFit, AccessToken: "access-secret", RefreshToken: "refresh-secret", ConnectedAt: connected}
+		if err := d.SaveOAuthToken(ctx, tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stored string
+	if err := d.sql.QueryRowContext(ctx, "SELECT refresh_token FROM oauth_tokens WHERE user_id=$1;", alice).Scan(&stored); err != nil || strings.Contains(stored, "secret") {
+		t.Errorf("expected the token sealed at rest, got %q, %v", stored, err)
+	}
+
+	synced := connected.Add(time.Hour)
+	if err := d.SaveOAuthToken(ctx, domain.OAuthToken{UserID: alice, Provider: domain.IntegrationGoogleFit, AccessToken: "renewed", RefreshToken: "refresh-secret", Expiry: synced, ConnectedAt: connected, SyncedAt: synced}); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := d.GetOAuthToken(ctx, alice, domain.IntegrationGoogleFit)
+	if err != nil || tok == nil || tok.AccessToken != "renewed" || tok.RefreshToken != "refresh-secret" || !tok.SyncedAt.Equal(synced) || !tok.ConnectedAt.Equal(connected) {
+		t.Errorf("expected the saved token replaced, got %+v, %v", tok, err)
+	}
+	if list, err := d.ListOAuthTokens(ctx, domain.IntegrationGoogleFit); err != nil || len(list) != 2 || list[0].UserID != alice || !list[1].SyncedAt.IsZero() {
+		t.Errorf("ListOAuthTokens: %+v, %v", list, err)
+	}
+	if ok, err := d.DeleteOAuthToken(ctx, alice, domain.IntegrationGoogleFit); err != nil || !ok {
+		t.Errorf("DeleteOAuthToken: %v, %v", ok, err)
+	}
+	if ok, err := d.DeleteOAuthToken(ctx, alice, domain.IntegrationGoogleFit); err != nil || ok {
+		t.Errorf("expected a second delete to report false, got %v, %v", ok, err)
+	}
+}
+
+func TestIntegrationEncryption(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	// Written before a key is configured, so stored as plaintext.
+	if err := d.SaveJournalEntry(ctx, alice, domain.JournalEntry{Day: "2026-03-01", Note: "plain", UpdatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.RotateEncryption(ctx); err == nil {
+		t.Error("expected rotation without a key to fail")
+	}
+
+	ring, err := fieldcrypt.New(fieldcrypt.Key{ID: "k1", Secret: []byte(strings.Repeat("k", 32))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.WithCipher(ring)
+	if _, _, err := d.AddFoodEntry(ctx, alice, domain.FoodEntry{Description: "secret cake", Kcal: 400, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	var stored string
+	if err := d.sql.QueryRowContext(ctx, "SELECT description FROM food_entries;").Scan(&stored); err != nil || strings.Contains(stored, "cake") {
+		t.Errorf("expected the description sealed at rest, got %q, %v", stored, err)
+	}
+	if items, _ := d.ListRecentFoodEntries(ctx, alice, 1); len(items) != 1 || items[0].Description != "secret cake" {
+		t.Errorf("expected the description opened on read, got %+v", items)
+	}
+
+	// Rotation seals what was written in plaintext and leaves it readable.
+	n, err := d.RotateEncryption(ctx)
+	if err != nil || n < 1 {
+		t.Fatalf("RotateEncryption: %d, %v", n, err)
+	}
+	if err := d.sql.QueryRowContext(ctx, "SELECT note FROM journal_entries;").Scan(&stored); err != nil || stored == "plain" {
+		t.Errorf("expected the note sealed by rotation, got %q, %v", stored, err)
+	}
+	if e, _ := d.GetJournalEntry(ctx, alice, "2026-03-01"); e == nil || e.Note != "plain" {
+		t.Errorf("expected the rotated note readable, got %+v", e)
+	}
+	if n, err := d.RotateEncryption(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing left to rotate, got %d, %v", n, err)
+	}
+}
+
+func TestIntegrationMaintenance(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+
+	if _, err := d.AddWeightEvent(ctx, alice, 80, "kg", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.sql.ExecContext(ctx, "INSERT INTO weight_events (value, unit, created_at) VALUES (70, 'kg', now());"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.CountOrphanedEvents(ctx)
+	if err != nil || c.WeightEvents != 1 || c.WaterEvents != 0 {
+		t.Errorf("expected one orphaned weight event, got %+v, %v", c, err)
+	}
+	if err := d.Vacuum(ctx); err != nil {
+		t.Errorf("Vacuum: %v", err)
+	}
+
+	// Old deletions and superseded changes are purged; a client whose
+	// cursor predates a purged deletion must sync again from 0.
+	gone, _ := d.AddWaterEvent(ctx, alice, 0.25, time.Now())
+	_ = d.DeleteWaterEvent(ctx, alice, gone)
+	changes, _ := d.ListChanges(ctx, alice, 0, 10)
+	if len(changes) != 2 {
+		t.Fatalf("expected the weight and the deletion, got %+v", changes)
+	}
+	cursor := changes[0].Seq
+	later := time.Now().Add(time.Minute)
+	if n, err := d.CountOldChanges(ctx, later); err != nil || n != 2 {
+		t.Fatalf("CountOldChanges = %d, %v", n, err)
+	}
+	if n, err := d.PurgeOldChanges(ctx, later); err != nil || n != 2 {
+		t.Fatalf("PurgeOldChanges = %d, %v", n, err)
+	}
+	if changes, err := d.ListChanges(ctx, alice, 0, 10); err != nil || len(changes) != 1 || changes[0].Entity != domain.ChangeEntityWeight {
+		t.Errorf("expected a full sync to return the weight, got %+v, %v", changes, err)
+	}
+	if _, err := d.ListChanges(ctx, alice, cursor, 10); !errors.Is(err, domain.ErrCursorExpired) {
+		t.Errorf("expected ErrCursorExpired, got %v", err)
+	}
+}
+
+func TestIntegrationWeeklyUsage(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	monday := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	if _, err := d.AddWeightEvent(ctx, alice, 80, "kg", monday.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = d.AddWeightEvent(ctx, alice, 80, "kg", monday.Add(time.Hour))
+	_, _ = d.AddWaterEvent(ctx, bob, 0.5, monday.AddDate(0, 0, 6))
+	_, _ = d.AddWaterEvent(ctx, bob, 0.5, monday.AddDate(0, 0, 7))
+
+	weeks, err := d.WeeklyUsage(ctx, monday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.UsageWeek{
+		{WeekStart: "2026-01-05", ActiveUsers: 2, Entries: 2},
+		{WeekStart: "2026-01-12", ActiveUsers: 1, Entries: 1},
+	}
+	if len(weeks) != len(want) || weeks[0] != want[0] || weeks[1] != want[1] {
+		t.Fatalf("WeeklyUsage = %+v, want %+v", weeks, want)
+	}
+}
+
+func TestIntegrationCluster(t *testing.T) {
+	connStr := createTestDatabase(t)
+	d, err := Open(connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = d.Close() }()
+
+	a, err := NewCluster(d, connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = a.Close() }()
+	b, err := NewCluster(d, connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+
+	got := make(chan string, 2)
+	a.Subscribe("topic", func(p []byte) { got <- "a:" + string(p) })
+	b.Subscribe("topic", func(p []byte) { got <- "b:" + string(p) })
+
+	if err := a.Publish(context.Background(), "topic", []byte(`"hello"`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-got:
+		if msg != `b:"hello"` {
+			t.Errorf("expected only the other instance to receive the message, got %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the cluster message")
+	}
+	select {
+	case msg := <-got:
+		t.Errorf("expected the publisher to skip its own message, got %s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestIntegrationActivity(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	_, _ = d.AddWeightEvent(ctx, alice, 80, "kg", base)
+	_, _ = d.AddWaterEvent(ctx, alice, 0.5, base.Add(time.Hour))
+	_, _ = d.AddWaterEvent(ctx, bob, 0.5, base.Add(time.Hour))
+	if err := d.RecordGoal(ctx, alice, "2026-03-01", 3); err != nil {
+		t.Fatal(err)
+	}
+	target := 75.0
+	if err := d.RecordWeightGoal(ctx, alice, "2026-02-20", &target); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if _, err := d.ImportWaterEvent(ctx, alice, "b1", "", 0.25, base.AddDate(0, 0, -30+i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	var before *domain.ActivityCursor
+	for range 5 {
+		page, err := d.ListActivity(ctx, alice, before, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range page {
+			got = append(got, a.Type)
+			switch a.Type {
+			case domain.ActivityImport:
+				if a.Import.Waters != 2 || a.Import.Weights != 0 {
+					t.Errorf("unexpected import: %+v", a.Import)
+				}
+			case domain.ActivityWeightGoal:
+				if a.WeightGoal.TargetKg == nil || *a.WeightGoal.TargetKg != 75 || !a.At.Equal(time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected weight goal: %+v at %v", a.WeightGoal, a.At)
+				}
+			}
+		}
+		if len(page) < 2 {
+			break
+		}
+		last := page[len(page)-1]
+		before = &domain.ActivityCursor{At: last.At, Type: last.Type, ID: last.ID}
+	}
+	want := []string{"import", "water", "weight", "goal.water", "goal.weight"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ListActivity = %v, want %v", got, want)
+	}
+}
+
+func TestIntegrationRetention(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	alice := newTestUser(t, d, "alice")
+	bob := newTestUser(t, d, "bob")
+
+	old := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	first, _ := d.AddWaterEvent(ctx, alice, 0.25, old)
+	if _, err := d.ImportWaterEvent(ctx, alice, "b1", "", 0.5, old.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = d.AddWaterEvent(ctx, alice, 1, time.Now())
+	_, _ = d.AddWaterEvent(ctx, bob, 1, time.Now())
+	cutoff := old.AddDate(0, 0, 1)
+
+	users, err := d.WaterUsersBefore(ctx, cutoff)
+	if err != nil || !slices.Equal(users, []int64{alice}) {
+		t.Fatalf("WaterUsersBefore = %v, %v", users, err)
+	}
+	events, err := d.ListWaterEventsBefore(ctx, alice, cutoff)
+	if err != nil || len(events) != 2 || events[0].ID != first {
+		t.Fatalf("ListWaterEventsBefore = %+v, %v", events, err)
+	}
+	kept := events[1].ID
+	// A set that lost an event since it was listed is left alone.
+	if ok, err := d.CollapseWaterEvents(ctx, alice, kept, []int64{first, first + 1000}); err != nil || ok {
+		t.Fatalf("expected a stale collapse to be skipped, got %v, %v", ok, err)
+	}
+	if events, _ = d.ListWaterEventsBefore(ctx, alice, cutoff); len(events) != 2 {
+		t.Fatalf("expected the skipped collapse to change nothing, got %+v", events)
+	}
+	if ok, err := d.CollapseWaterEvents(ctx, alice, kept, []int64{first}); err != nil || !ok {
+		t.Fatalf("CollapseWaterEvents = %v, %v", ok, err)
+	}
+	events, _ = d.ListWaterEventsBefore(ctx, alice, cutoff)
+	if len(events) != 1 || events[0].ID != kept || events[0].DeltaLiters != 0.75 {
+		t.Errorf("expected one collapsed event of 0.75 L, got %+v", events)
+	}
+	// The collapsed event no longer belongs to its import.
+	if n, err := d.DeleteImportBatch(ctx, alice, "b1"); err != nil || n != 0 {
+		t.Errorf("expected the import batch to be empty, got %d, %v", n, err)
+	}
+	changes, _ := d.ListChanges(ctx, alice, 0, 100)
+	ops := map[int64]string{}
+	for _, c := range changes {
+		ops[c.EntityID] = c.Op
+	}
+	if ops[first] != domain.ChangeOpDelete || ops[kept] != domain.ChangeOpUpsert {
+		t.Errorf("expected a delete and an upsert logged, got %v", ops)
+	}
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+
+	"vitals/internal/domain"
+)
+
+// WaterUsersBefore returns the users with water events created before t.
+func (d *DB) WaterUsersBefore(ctx context.Context, t time.Time) ([]int64, error) {
+	var out []int64
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT DISTINCT user_id FROM water_events WHERE created_at < $1 AND user_id IS NOT NULL ORDER BY user_id;", t.UTC())
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			out = append(out, id)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
+
+// ListWaterEventsBefore returns the user's water events created before t,
+// oldest first.
+func (d *DB) ListWaterEventsBefore(ctx context.Context, userID int64, t time.Time) ([]domain.WaterEvent, error) {
+	var out []domain.WaterEvent
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT id, delta_liters, COALESCE(client_id, ''), created_at FROM water_events WHERE user_id=$1 AND created_at < $2 ORDER BY created_at, id;",
+			userID, t.UTC())
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.WaterEvent{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.DeltaLiters, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
+
+// CollapseWaterEvents locks the user's event keepID and removeIDs, sets
+// keepID's volume to their rounded total, detaches it from its import
+// batch, and deletes removeIDs with their tags, in one transaction, logging
+// an upsert and the deletes. It returns false, changing nothing, if any of
+// the events was deleted since they were listed.
+func (d *DB) CollapseWaterEvents(ctx context.Context, userID, keepID int64, removeIDs []int64) (bool, error) {
+	collapsed := false
+	err := d.userTx(ctx, userID, func(q querier) error {
+		ids := append([]int64{keepID}, removeIDs...)
+		rows, err := q.QueryContext(ctx,
+			"SELECT delta_liters FROM water_events WHERE id = ANY($1) AND user_id=$2 ORDER BY id FOR UPDATE;",
+			pq.Array(ids), userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		n, total := 0, 0.0
+		for rows.Next() {
+			var liters float64
+			if err := rows.Scan(&liters); err != nil {
+				return err
+			}
+			n++
+			total += liters
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n != len(ids) {
+			return nil
+		}
+
+		if _, err := q.ExecContext(ctx,
+			`WITH upd AS (
+				UPDATE water_events SET delta_liters=$3, import_batch=NULL WHERE id=$1 AND user_id=$2 RETURNING id
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $2, 'water', id, 'upsert', now() FROM upd;`,
+			keepID, userID, domain.RoundLiters(total)); err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx,
+			`WITH del AS (
+				DELETE FROM water_events WHERE id = ANY($1) AND user_id=$2 RETURNING id
+			), untag AS (
+				DELETE FROM entry_tags WHERE entity='water' AND entry_id IN (SELECT id FROM del)
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $2, 'water', id, 'delete', now() FROM del;`,
+			pq.Array(removeIDs), userID)
+		collapsed = err == nil
+		return err
+	})
+	return collapsed, err
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// Row-level security mode. When enabled, the per-user tables carry a policy
+// that only exposes rows whose user_id matches the vitals.user_id setting,
+// which every user-scoped repository call sets for the duration of its own
+// transaction. This backs up the WHERE clauses: a query that forgets to
+// filter by user still cannot read or write another user's rows. Unset
+// settings match nothing, so unscoped access fails closed; maintenance paths
+// that legitimately span users set vitals.bypass_rls instead.
+//
+// Postgres skips RLS for superusers and roles with BYPASSRLS, so the mode is
+// only effective when the app connects as an ordinary role.
+
+// rlsTables lists the tables isolated by user_id.
+var rlsTables = []string{
+	"weight_events", "water_events", "changes", "hydration_settings", "alert_rules",
+	"user_settings", "entry_tags", "journal_entries", "weekly_summaries", "user_rules",
+	"hydration_goal_history", "food_entries", "mood_entries", "medications",
+	"medication_events", "steps_entries", "temperature_readings", "custom_metrics",
+	"metric_events", "weight_goal_history", "oauth_tokens", "notification_outbox",
+	"webhooks", "webhook_deliveries", "planned_entries",
+}
+
+const rlsPolicy = "vitals_user_isolation"
+
+// SetRowLevelSecurity installs (enabled) or removes the row-level security
+// policies. The choice persists in the schema: later connections detect it
+// on Open, so CLI commands follow whatever the server configured.
+func (d *DB) SetRowLevelSecurity(ctx context.Context, enabled bool) error {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range rlsTables {
+		stmts := []string{
+			fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", rlsPolicy, table),
+			fmt.Sprintf("ALTER TABLE %s NO FORCE ROW LEVEL SECURITY;", table),
+			fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY;", table),
+		}
+		if enabled {
+			stmts = []string{
+				fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", rlsPolicy, table),
+				fmt.Sprintf(`CREATE POLICY %s ON %s
+					USING (current_setting('vitals.bypass_rls', true) = 'on'
+						OR user_id = NULLIF(current_setting('vitals.user_id', true), '')::bigint);`, rlsPolicy, table),
+				fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", table),
+				// FORCE applies the policy to the table owner, which is
+				// usually the role the app connects as.
+				fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY;", table),
+			}
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("row-level security on %s: %w", table, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	d.rls = enabled
+	return nil
+}
+
+// RowLevelSecurity reports whether the row-level security mode is active.
+func (d *DB) RowLevelSecurity() bool {
+	return d.rls
+}
+
+// detectRowLevelSecurity reads whether the policies are installed.
+func (d *DB) detectRowLevelSecurity(ctx context.Context) error {
+	return d.sql.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_policies WHERE schemaname = current_schema() AND policyname = $1);", rlsPolicy,
+	).Scan(&d.rls)
+}
+
+// asUser runs fn with a querier restricted to userID's rows. In RLS mode fn
+// runs in a transaction with vitals.user_id set, committed if fn succeeds;
+// otherwise fn runs directly against the pool.
+func (d *DB) asUser(ctx context.Context, userID int64, fn func(q querier) error) error {
+	if !d.rls {
+		return fn(d.sql)
+	}
+	return d.inTx(ctx, "vitals.user_id", strconv.FormatInt(userID, 10), fn)
+}
+
+// userTx is asUser for multi-statement writes: fn always runs in a
+// transaction, with vitals.user_id set in RLS mode.
+func (d *DB) userTx(ctx context.Context, userID int64, fn func(q querier) error) error {
+	if d.rls {
+		return d.asUser(ctx, userID, fn)
+	}
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(traced{tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// systemTx is asSystem for multi-statement writes: fn always runs in a
+// transaction, with vitals.bypass_rls set in RLS mode.
+func (d *DB) systemTx(ctx context.Context, fn func(q querier) error) error {
+	if d.rls {
+		return d.asSystem(ctx, fn)
+	}
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(traced{tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// asSystem runs fn with a querier that may touch every user's rows, for
+// migrations and maintenance.
+func (d *DB) asSystem(ctx context.Context, fn func(q querier) error) error {
+	if !d.rls {
+		return fn(d.sql)
+	}
+	return d.inTx(ctx, "vitals.bypass_rls", "on", fn)
+}
+
+// inTx runs fn in a transaction with a transaction-local setting, so the
+// setting never leaks to other requests sharing the pooled connection.
+func (d *DB) inTx(ctx context.Context, setting, value string, fn func(q querier) error) error {
+	tx, err := d.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := setLocal(ctx, tx, setting, value); err != nil {
+		return err
+	}
+	if err := fn(traced{tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setLocal sets a configuration parameter for the rest of tx.
+func setLocal(ctx context.Context, tx *sql.Tx, setting, value string) error {
+	_, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true);", setting, value)
+	return err
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const ruleColumns = "id, user_id, name, kind, threshold, at_time, days, channel, target, enabled, last_fired_at, created_at"
+
+// CreateRule stores a new rule and returns it with its ID.
+func (d *DB) CreateRule(ctx context.Context, rule domain.Rule) (*domain.Rule, error) {
+	target, err := d.seal(rule.Target)
+	if err != nil {
+		return nil, err
+	}
+	err = d.asUser(ctx, rule.UserID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`INSERT INTO user_rules (user_id, name, kind, threshold, at_time, days, channel, target, enabled, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id;`,
+			rule.UserID, rule.Name, rule.Condition.Kind, rule.Condition.Threshold, rule.Condition.At, rule.Condition.Days,
+			rule.Channel, target, rule.Enabled, rule.CreatedAt,
+		).Scan(&rule.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetRule returns the user's rule with id, or nil.
+func (d *DB) GetRule(ctx context.Context, userID, id int64) (*domain.Rule, error) {
+	var r *domain.Rule
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		r, err = d.scanRule(q.QueryRowContext(ctx,
+			"SELECT "+ruleColumns+" FROM user_rules WHERE user_id=$1 AND id=$2;", userID, id))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListRules returns the user's rules, oldest first.
+func (d *DB) ListRules(ctx context.Context, userID int64) ([]domain.Rule, error) {
+	out := []domain.Rule{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+ruleColumns+" FROM user_rules WHERE user_id=$1 ORDER BY id;", userID)
+		if err != nil {
+			return err
+		}
+		out, err = d.scanRules(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateRule replaces a rule's definition, keeping its history.
+func (d *DB) UpdateRule(ctx context.Context, rule domain.Rule) (bool, error) {
+	target, err := d.seal(rule.Target)
+	if err != nil {
+		return false, err
+	}
+	var n int64
+	err = d.asUser(ctx, rule.UserID, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			`UPDATE user_rules SET name=$3, kind=$4, threshold=$5, at_time=$6, days=$7, channel=$8, target=$9, enabled=$10
+			WHERE user_id=$1 AND id=$2;`,
+			rule.UserID, rule.ID, rule.Name, rule.Condition.Kind, rule.Condition.Threshold, rule.Condition.At, rule.Condition.Days,
+			rule.Channel, target, rule.Enabled)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// DeleteRule removes one of a user's rules and reports whether it existed.
+func (d *DB) DeleteRule(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM user_rules WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// ListEnabledRules returns every user's enabled rules, ordered by user.
+func (d *DB) ListEnabledRules(ctx context.Context) ([]domain.Rule, error) {
+	var out []domain.Rule
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+ruleColumns+" FROM user_rules WHERE enabled ORDER BY user_id, id;")
+		if err != nil {
+			return err
+		}
+		out, err = d.scanRules(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkRuleFired records when a rule last fired and queues the
+// notifications, in one transaction.
+func (d *DB) MarkRuleFired(ctx context.Context, userID, id int64, at time.Time, queue ...domain.OutboxMessage) error {
+	return d.userTx(ctx, userID, func(q querier) error {
+		if _, err := q.ExecContext(ctx, "UPDATE user_rules SET last_fired_at=$3 WHERE user_id=$1 AND id=$2;", userID, id, at.UTC()); err != nil {
+			return err
+		}
+		return d.insertOutbox(ctx, q, queue)
+	})
+}
+
+func (d *DB) scanRules(rows *sql.Rows) ([]domain.Rule, error) {
+	defer rows.Close() //nolint:errcheck
+
+	out := []domain.Rule{}
+	for rows.Next() {
+		r, err := d.scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+// scanRule reads one row selected with ruleColumns.
+func (d *DB) scanRule(row interface{ Scan(...any) error }) (*domain.Rule, error) {
+	var (
+		r    domain.Rule
+		last sql.NullTime
+	)
+	if err := row.Scan(&r.ID, &r.UserID, &r.Name, &r.Condition.Kind, &r.Condition.Threshold, &r.Condition.At, &r.Condition.Days,
+		&r.Channel, &r.Target, &r.Enabled, &last, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.Target, err = d.open(r.Target); err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		r.LastFiredAt = &last.Time
+	}
+	return &r, nil
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetSettings returns all of the user's settings.
+func (d *DB) GetSettings(ctx context.Context, userID int64) (domain.UserSettings, error) {
+	settings := domain.UserSettings{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx, "SELECT key, value FROM user_settings WHERE user_id=$1;", userID)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var key string
+			var value []byte
+			if err := rows.Scan(&key, &value); err != nil {
+				return err
+			}
+			settings[key] = value
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateSettings stores set and deletes the keys in remove in one
+// transaction.
+func (d *DB) UpdateSettings(ctx context.Context, userID int64, set domain.UserSettings, remove []string) error {
+	now := time.Now().UTC()
+	return d.userTx(ctx, userID, func(q querier) error {
+		for key, value := range set {
+			if _, err := q.ExecContext(ctx,
+				`INSERT INTO user_settings (user_id, key, value, updated_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id, key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at;`,
+				userID, key, []byte(value), now); err != nil {
+				return err
+			}
+		}
+		for _, key := range remove {
+			if _, err := q.ExecContext(ctx, "DELETE FROM user_settings WHERE user_id=$1 AND key=$2;", userID, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
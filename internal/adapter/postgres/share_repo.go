@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const shareSelect = `SELECT s.owner_id, o.username, s.viewer_id, v.username, s.created_at
+	FROM shares s JOIN users o ON o.id = s.owner_id JOIN users v ON v.id = s.viewer_id`
+
+// CreateShare grants viewerID read access to ownerID's data.
+func (d *DB) CreateShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	_, err := d.sql.ExecContext(ctx,
+		"INSERT INTO shares (owner_id, viewer_id, created_at) VALUES ($1, $2, $3) ON CONFLICT (owner_id, viewer_id) DO NOTHING;",
+		ownerID, viewerID, time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetShare(ctx, ownerID, viewerID)
+}
+
+// DeleteShare revokes a grant and reports whether one existed.
+func (d *DB) DeleteShare(ctx context.Context, ownerID, viewerID int64) (bool, error) {
+	res, err := d.sql.ExecContext(ctx, "DELETE FROM shares WHERE owner_id=$1 AND viewer_id=$2;", ownerID, viewerID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetShare returns the grant from ownerID to viewerID, or nil.
+func (d *DB) GetShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	var sh domain.Share
+	err := d.sql.QueryRowContext(ctx, shareSelect+" WHERE s.owner_id=$1 AND s.viewer_id=$2;", ownerID, viewerID).
+		Scan(&sh.OwnerID, &sh.OwnerUsername, &sh.ViewerID, &sh.ViewerUsername, &sh.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+// ListShares returns grants where userID is the owner or the viewer.
+func (d *DB) ListShares(ctx context.Context, userID int64) ([]domain.Share, error) {
+	rows, err := d.sql.QueryContext(ctx, shareSelect+" WHERE s.owner_id=$1 OR s.viewer_id=$1 ORDER BY s.created_at;", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var out []domain.Share
+	for rows.Next() {
+		var sh domain.Share
+		if err := rows.Scan(&sh.OwnerID, &sh.OwnerUsername, &sh.ViewerID, &sh.ViewerUsername, &sh.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	return out, rows.Err()
+}
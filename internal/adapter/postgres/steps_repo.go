@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// GetStepsEntry returns the step count for day, or nil.
+func (d *DB) GetStepsEntry(ctx context.Context, userID int64, day string) (*domain.StepsEntry, error) {
+	e := domain.StepsEntry{Day: day}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT steps, updated_at FROM steps_entries WHERE user_id=$1 AND day=$2;", userID, day,
+		).Scan(&e.Steps, &e.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveStepsEntry creates or replaces the step count for e.Day.
+func (d *DB) SaveStepsEntry(ctx context.Context, userID int64, e domain.StepsEntry) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO steps_entries (user_id, day, steps, updated_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, day) DO UPDATE SET steps = EXCLUDED.steps, updated_at = EXCLUDED.updated_at;`,
+			userID, e.Day, e.Steps, e.UpdatedAt.UTC())
+		return err
+	})
+}
+
+// ListStepsEntries returns the step counts for days from through to, oldest
+// first.
+func (d *DB) ListStepsEntries(ctx context.Context, userID int64, from, to string) ([]domain.StepsEntry, error) {
+	out := []domain.StepsEntry{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT day, steps, updated_at FROM steps_entries WHERE user_id=$1 AND day BETWEEN $2 AND $3 ORDER BY day;",
+			userID, from, to)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				e   domain.StepsEntry
+				day time.Time
+			)
+			if err := rows.Scan(&day, &e.Steps, &e.UpdatedAt); err != nil {
+				return err
+			}
+			e.Day = day.Format("2006-01-02")
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// SaveWeeklySummaries creates or replaces the user's summaries in one
+// transaction.
+func (d *DB) SaveWeeklySummaries(ctx context.Context, userID int64, sums []domain.WeeklySummary) error {
+	now := time.Now().UTC()
+	return d.userTx(ctx, userID, func(q querier) error {
+		for _, s := range sums {
+			_, err := q.ExecContext(ctx,
+				`INSERT INTO weekly_summaries (user_id, week_start, week_end, weigh_ins, start_kg, end_kg, change_kg, avg_kg, total_water_liters, avg_water_liters, goal_days, computed_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				ON CONFLICT (user_id, week_start) DO UPDATE SET
+					week_end = EXCLUDED.week_end,
+					weigh_ins = EXCLUDED.weigh_ins,
+					start_kg = EXCLUDED.start_kg,
+					end_kg = EXCLUDED.end_kg,
+					change_kg = EXCLUDED.change_kg,
+					avg_kg = EXCLUDED.avg_kg,
+					total_water_liters = EXCLUDED.total_water_liters,
+					avg_water_liters = EXCLUDED.avg_water_liters,
+					goal_days = EXCLUDED.goal_days,
+					computed_at = EXCLUDED.computed_at;`,
+				userID, s.WeekStart, s.WeekEnd, s.WeighIns, s.StartKg, s.EndKg, s.ChangeKg, s.AvgKg,
+				s.TotalWaterLiters, s.AvgWaterLiters, s.GoalDays, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListWeeklySummaries returns the user's summaries for weeks starting on or
+// after from, newest first.
+func (d *DB) ListWeeklySummaries(ctx context.Context, userID int64, from string) ([]domain.WeeklySummary, error) {
+	out := []domain.WeeklySummary{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT week_start, week_end, weigh_ins, start_kg, end_kg, change_kg, avg_kg, total_water_liters, avg_water_liters, goal_days
+			FROM weekly_summaries WHERE user_id=$1 AND week_start >= $2 ORDER BY week_start DESC;`,
+			userID, from)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				s          domain.WeeklySummary
+				start, end time.Time
+			)
+			if err := rows.Scan(&start, &end, &s.WeighIns, &s.StartKg, &s.EndKg, &s.ChangeKg, &s.AvgKg,
+				&s.TotalWaterLiters, &s.AvgWaterLiters, &s.GoalDays); err != nil {
+				return err
+			}
+			s.WeekStart, s.WeekEnd = start.Format("2006-01-02"), end.Format("2006-01-02")
+			out = append(out, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListActiveUserIDs returns the users with changes since the given time.
+func (d *DB) ListActiveUserIDs(ctx context.Context, since time.Time) ([]int64, error) {
+	var out []int64
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT DISTINCT user_id FROM changes WHERE changed_at >= $1 ORDER BY user_id;", since.UTC())
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			out = append(out, id)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
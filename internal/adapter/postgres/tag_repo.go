@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"vitals/internal/domain"
+)
+
+// entryTables maps tagged entities to their event tables.
+var entryTables = map[string]string{
+	domain.ChangeEntityWeight: "weight_events",
+	domain.ChangeEntityWater:  "water_events",
+}
+
+// SetEntryTags replaces the tags on one of the user's entries.
+func (d *DB) SetEntryTags(ctx context.Context, userID int64, entity string, entryID int64, tags []string) (bool, error) {
+	table, ok := entryTables[entity]
+	if !ok {
+		return false, fmt.Errorf("unknown entity %q", entity)
+	}
+	var found bool
+	err := d.userTx(ctx, userID, func(q querier) error {
+		err := q.QueryRowContext(ctx,
+			fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id=$1 AND user_id=$2);", table), entryID, userID,
+		).Scan(&found)
+		if err != nil || !found {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, "DELETE FROM entry_tags WHERE entity=$1 AND entry_id=$2;", entity, entryID); err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx,
+			"INSERT INTO entry_tags (user_id, entity, entry_id, tag) SELECT $1, $2, $3, unnest($4::text[]);",
+			userID, entity, entryID, pq.Array(tags))
+		return err
+	})
+	return found, err
+}
+
+// EntryTags returns the tags of the given entries, keyed by entry ID.
+func (d *DB) EntryTags(ctx context.Context, userID int64, entity string, ids []int64) (map[int64][]string, error) {
+	out := map[int64][]string{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT entry_id, tag FROM entry_tags WHERE user_id=$1 AND entity=$2 AND entry_id = ANY($3) ORDER BY entry_id, tag;",
+			userID, entity, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				id  int64
+				tag string
+			)
+			if err := rows.Scan(&id, &tag); err != nil {
+				return err
+			}
+			out[id] = append(out[id], tag)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTags returns the user's tags with their use counts, by tag.
+func (d *DB) ListTags(ctx context.Context, userID int64) ([]domain.TagCount, error) {
+	out := []domain.TagCount{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT tag, count(*) FROM entry_tags WHERE user_id=$1 GROUP BY tag ORDER BY tag;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var tc domain.TagCount
+			if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+				return err
+			}
+			out = append(out, tc)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
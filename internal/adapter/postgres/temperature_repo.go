@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+
+	"vitals/internal/domain"
+)
+
+// AddTemperatureReading inserts a reading unless the user has already
+// stored one with the same non-empty client ID, in which case the existing
+// row is returned.
+func (d *DB) AddTemperatureReading(ctx context.Context, userID int64, r domain.TemperatureReading) (*domain.TemperatureReading, bool, error) {
+	var (
+		out     = domain.TemperatureReading{UserID: userID, ClientID: r.ClientID}
+		created bool
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO temperature_readings(user_id, value, unit, client_id, created_at)
+				VALUES($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+				RETURNING id, value, unit, created_at
+			)
+			SELECT id, value, unit, created_at, true FROM ins
+			UNION ALL
+			SELECT id, value, unit, created_at, false FROM temperature_readings WHERE user_id=$1 AND client_id=$4;`,
+			userID, r.Value, r.Unit, nullString(r.ClientID), r.CreatedAt.UTC(),
+		).Scan(&out.ID, &out.Value, &out.Unit, &out.CreatedAt, &created)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &out, created, nil
+}
+
+// ListRecentTemperatureReadings returns the user's latest readings, newest
+// first.
+func (d *DB) ListRecentTemperatureReadings(ctx context.Context, userID int64, limit int) ([]domain.TemperatureReading, error) {
+	out := []domain.TemperatureReading{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT id, value, unit, COALESCE(client_id, ''), created_at
+			FROM temperature_readings WHERE user_id=$1
+			ORDER BY created_at DESC, id DESC LIMIT $2;`, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			r := domain.TemperatureReading{UserID: userID}
+			if err := rows.Scan(&r.ID, &r.Value, &r.Unit, &r.ClientID, &r.CreatedAt); err != nil {
+				return err
+			}
+			out = append(out, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteTemperatureReading removes one of the user's readings.
+func (d *DB) DeleteTemperatureReading(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM temperature_readings WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// CreateAPIToken stores a new API token hash.
+func (d *DB) CreateAPIToken(ctx context.Context, userID int64, name, scope, tokenHash string) (*domain.APIToken, error) {
+	t := domain.APIToken{UserID: userID, Name: name, Scope: scope, TokenHash: tokenHash}
+	err := d.sql.QueryRowContext(ctx,
+		"INSERT INTO api_tokens (user_id, name, scope, token_hash, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at;",
+		userID, name, scope, tokenHash, time.Now(),
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetAPITokenByHash returns the token with the given hash, or nil.
+func (d *DB) GetAPITokenByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	var (
+		t    = domain.APIToken{TokenHash: tokenHash}
+		used sql.NullTime
+	)
+	err := d.sql.QueryRowContext(ctx,
+		"SELECT id, user_id, name, scope, created_at, last_used_at FROM api_tokens WHERE token_hash=$1;", tokenHash,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.CreatedAt, &used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if used.Valid {
+		t.LastUsedAt = &used.Time
+	}
+	return &t, nil
+}
+
+// ListAPITokens returns a user's tokens, newest first.
+func (d *DB) ListAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	rows, err := d.sql.QueryContext(ctx,
+		"SELECT id, name, scope, created_at, last_used_at FROM api_tokens WHERE user_id=$1 ORDER BY created_at DESC;", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []domain.APIToken{}
+	for rows.Next() {
+		var (
+			t    = domain.APIToken{UserID: userID}
+			used sql.NullTime
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Scope, &t.CreatedAt, &used); err != nil {
+			return nil, err
+		}
+		if used.Valid {
+			t.LastUsedAt = &used.Time
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// DeleteAPIToken removes one of a user's tokens and reports whether it existed.
+func (d *DB) DeleteAPIToken(ctx context.Context, userID, id int64) (bool, error) {
+	res, err := d.sql.ExecContext(ctx, "DELETE FROM api_tokens WHERE id=$1 AND user_id=$2;", id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteAPITokens revokes all of the user's tokens.
+func (d *DB) DeleteAPITokens(ctx context.Context, userID int64) (int, error) {
+	res, err := d.sql.ExecContext(ctx, "DELETE FROM api_tokens WHERE user_id=$1;", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// TouchAPIToken records that the token with id was used at at.
+func (d *DB) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	_, err := d.sql.ExecContext(ctx, "UPDATE api_tokens SET last_used_at=$2 WHERE id=$1;", id, at.UTC())
+	return err
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("vitals/internal/adapter/postgres")
+
+// pool is the connection pool. Its statements, like those of the
+// transactions handed to fn by asUser and friends, are traced.
+type pool struct{ *sql.DB }
+
+func (p pool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return traced{p.DB}.ExecContext(ctx, query, args...)
+}
+
+func (p pool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return traced{p.DB}.QueryContext(ctx, query, args...)
+}
+
+func (p pool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return traced{p.DB}.QueryRowContext(ctx, query, args...)
+}
+
+// traced wraps a querier, recording a client span per statement. Rows are
+// read after the span ends, so it covers the round trip but not the scan.
+type traced struct{ q querier }
+
+func (t traced) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	res, err := t.q.ExecContext(ctx, query, args...)
+	endQuery(span, err)
+	return res, err
+}
+
+func (t traced) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	endQuery(span, err)
+	return rows, err
+}
+
+func (t traced) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	endQuery(span, row.Err())
+	return row
+}
+
+// startQuery starts the span for query, named after its operation, e.g.
+// SELECT. Queries only hold placeholders, so their text is recorded as is.
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	op := "query"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		op = strings.ToUpper(strings.TrimSuffix(fields[0], ";"))
+	}
+	return tracer.Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", op),
+		attribute.String("db.query.text", query),
+	))
+}
+
+// endQuery ends span, marking it failed on err. No rows is an answer, not
+// a failure.
+func endQuery(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	_, span := startQuery(context.Background(), "\n\t\tselect id FROM users WHERE id=$1;")
+	endQuery(span, sql.ErrNoRows)
+	_, span = startQuery(context.Background(), "VACUUM;")
+	endQuery(span, sql.ErrConnDone)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "SELECT" || spans[0].Status().Code == codes.Error {
+		t.Errorf("expected an unfailed SELECT span for no rows, got %q (%v)", spans[0].Name(), spans[0].Status().Code)
+	}
+	if spans[1].Name() != "VACUUM" || spans[1].Status().Code != codes.Error {
+		t.Errorf("expected a failed VACUUM span, got %q (%v)", spans[1].Name(), spans[1].Status().Code)
+	}
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// WeeklyUsage counts weight and water events and the users logging them
+// per week since the given time. Only the counts leave the database.
+func (d *DB) WeeklyUsage(ctx context.Context, since time.Time) ([]domain.UsageWeek, error) {
+	var out []domain.UsageWeek
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT to_char(date_trunc('week', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS week,
+				COUNT(DISTINCT user_id), COUNT(*)
+			FROM (
+				SELECT user_id, created_at FROM weight_events WHERE created_at >= $1
+				UNION ALL
+				SELECT user_id, created_at FROM water_events WHERE created_at >= $1
+			) e
+			GROUP BY week ORDER BY week;`, since.UTC())
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var w domain.UsageWeek
+			if err := rows.Scan(&w.WeekStart, &w.ActiveUsers, &w.Entries); err != nil {
+				return err
+			}
+			out = append(out, w)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
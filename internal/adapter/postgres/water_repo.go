@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"time"
 
 	"vitals/internal/domain"
@@ -10,52 +12,250 @@ import (
 // AddWaterEvent inserts a new water intake event.
 func (d *DB) AddWaterEvent(ctx context.Context, userID int64, deltaLiters float64, createdAt time.Time) (int64, error) {
 	var id int64
-	err := d.sql.QueryRowContext(ctx,
-		"INSERT INTO water_events(user_id, delta_liters, created_at) VALUES($1, $2, $3) RETURNING id;",
-		userID, deltaLiters, createdAt.UTC(),
-	).Scan(&id)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO water_events(user_id, delta_liters, created_at) VALUES($1, $2, $3) RETURNING id
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'water', id, 'upsert', now() FROM ins RETURNING entity_id;`,
+			userID, deltaLiters, createdAt.UTC(),
+		).Scan(&id)
+	})
 	return id, err
 }
 
+// AddWaterEventWithClientID inserts a water event unless the user has
+// already stored one with the same client ID, in which case the existing row
+// is returned.
+func (d *DB) AddWaterEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error) {
+	var (
+		e       *domain.WaterEvent
+		created bool
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		e, created, err = insertWater(ctx, q, userID, clientID, deltaLiters, createdAt)
+		return err
+	})
+	return e, created, err
+}
+
+// insertWater inserts a water event, deduplicating on a non-empty clientID,
+// and logs the change.
+func insertWater(ctx context.Context, q querier, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error) {
+	var (
+		e       domain.WaterEvent
+		created bool
+	)
+	err := q.QueryRowContext(ctx,
+		`WITH ins AS (
+			INSERT INTO water_events(user_id, client_id, delta_liters, created_at) VALUES($1, $2, $3, $4)
+			ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+			RETURNING id, delta_liters, created_at
+		), log AS (
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'water', id, 'upsert', now() FROM ins
+		)
+		SELECT id, delta_liters, created_at, true FROM ins
+		UNION ALL
+		SELECT id, delta_liters, created_at, false FROM water_events WHERE user_id=$1 AND client_id=$2;`,
+		userID, nullString(clientID), deltaLiters, createdAt.UTC(),
+	).Scan(&e.ID, &e.DeltaLiters, &e.CreatedAt, &created)
+	if err != nil {
+		return nil, false, err
+	}
+	e.UserID = userID
+	e.ClientID = clientID
+	return &e, created, nil
+}
+
 // DeleteWaterEvent removes a water event by ID, scoped to a user.
 func (d *DB) DeleteWaterEvent(ctx context.Context, userID int64, id int64) error {
-	_, err := d.sql.ExecContext(ctx, "DELETE FROM water_events WHERE id=$1 AND user_id=$2;", id, userID)
-	return err
+	return d.asUser(ctx, userID, func(q querier) error {
+		_, err := deleteWater(ctx, q, userID, id)
+		return err
+	})
+}
+
+// DeleteLatestWaterEvent removes the user's most recent water event in one
+// statement, locking the row as DeleteLatestWeightEvent does, and returns
+// its ID, or 0 if there was none or a concurrent undo deleted it first.
+func (d *DB) DeleteLatestWaterEvent(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH latest AS (
+				SELECT id FROM water_events WHERE user_id=$1
+				ORDER BY created_at DESC, id DESC LIMIT 1 FOR UPDATE
+			), del AS (
+				DELETE FROM water_events WHERE id IN (SELECT id FROM latest) RETURNING id
+			), untag AS (
+				DELETE FROM entry_tags WHERE entity='water' AND entry_id IN (SELECT id FROM del)
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'water', id, 'delete', now() FROM del RETURNING entity_id;`,
+			userID,
+		).Scan(&id)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// deleteWater removes a water event by ID, scoped to a user, and logs the
+// change.
+func deleteWater(ctx context.Context, q querier, userID, id int64) (bool, error) {
+	res, err := q.ExecContext(ctx,
+		`WITH del AS (
+			DELETE FROM water_events WHERE id=$1 AND user_id=$2 RETURNING id
+		), untag AS (
+			DELETE FROM entry_tags WHERE entity='water' AND entry_id IN (SELECT id FROM del)
+		)
+		INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+		SELECT $2, 'water', id, 'delete', now() FROM del;`,
+		id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpdateWaterEvent changes a water event's volume or time, scoped to a user,
+// and logs the change.
+func (d *DB) UpdateWaterEvent(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+	var createdAt *time.Time
+	if p.CreatedAt != nil {
+		at := p.CreatedAt.UTC()
+		createdAt = &at
+	}
+	e := domain.WaterEvent{ID: id, UserID: userID}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH upd AS (
+				UPDATE water_events SET delta_liters = COALESCE($3, delta_liters), created_at = COALESCE($4, created_at)
+				WHERE id=$1 AND user_id=$2
+				RETURNING id, delta_liters, COALESCE(client_id, '') AS client_id, created_at
+			), log AS (
+				INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+				SELECT $2, 'water', id, 'upsert', now() FROM upd
+			)
+			SELECT delta_liters, client_id, created_at FROM upd;`,
+			id, userID, p.DeltaLiters, createdAt,
+		).Scan(&e.DeltaLiters, &e.ClientID, &e.CreatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
 }
 
 // ListRecentWaterEvents returns the most recent water events up to limit for a user.
 func (d *DB) ListRecentWaterEvents(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error) {
-	rows, err := d.sql.QueryContext(ctx,
-		"SELECT id, delta_liters, created_at FROM water_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;", userID, limit)
+	out := make([]domain.WaterEvent, 0, limit)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT id, delta_liters, COALESCE(client_id, ''), created_at FROM water_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;", userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var e domain.WaterEvent
+			if err := rows.Scan(&e.ID, &e.DeltaLiters, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			e.UserID = userID
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close() //nolint:errcheck
+	return out, nil
+}
 
+// ListRecentWaterPage returns the most recent water events up to limit and,
+// through window functions over the same query, a summary of all the
+// user's water events. limit must be positive.
+func (d *DB) ListRecentWaterPage(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, domain.ListSummary, error) {
 	out := make([]domain.WaterEvent, 0, limit)
-	for rows.Next() {
-		var e domain.WaterEvent
-		if err := rows.Scan(&e.ID, &e.DeltaLiters, &e.CreatedAt); err != nil {
-			return nil, err
+	var sum domain.ListSummary
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT id, delta_liters, COALESCE(client_id, ''), created_at,
+				COUNT(*) OVER (), MIN(created_at) OVER (), MAX(created_at) OVER ()
+			FROM water_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;`, userID, limit)
+		if err != nil {
+			return err
 		}
-		e.UserID = userID
-		out = append(out, e)
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				e             domain.WaterEvent
+				first, latest time.Time
+			)
+			if err := rows.Scan(&e.ID, &e.DeltaLiters, &e.ClientID, &e.CreatedAt, &sum.Total, &first, &latest); err != nil {
+				return err
+			}
+			e.UserID = userID
+			sum.FirstAt, sum.LastAt = &first, &latest
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, domain.ListSummary{}, err
 	}
-	return out, rows.Err()
+	sum.HasMore = sum.Total > len(out)
+	return out, sum, nil
+}
+
+// EachWaterEvent streams the user's water events to fn, oldest first, as
+// rows arrive rather than collecting them in memory.
+func (d *DB) EachWaterEvent(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT id, delta_liters, COALESCE(client_id, ''), created_at FROM water_events WHERE user_id=$1 ORDER BY created_at, id;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.WaterEvent{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.DeltaLiters, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
 }
 
 // WaterTotalForLocalDay returns the total water intake for a local calendar day for a user.
 func (d *DB) WaterTotalForLocalDay(ctx context.Context, userID int64, localDay string) (float64, error) {
-	dayStart, err := time.ParseInLocation("2006-01-02", localDay, time.Local)
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
 	if err != nil {
 		return 0, err
 	}
-	dayEnd := dayStart.Add(24 * time.Hour)
 
 	var total float64
-	err = d.sql.QueryRowContext(ctx,
-		"SELECT COALESCE(SUM(delta_liters), 0) FROM water_events WHERE user_id=$1 AND created_at >= $2 AND created_at < $3;",
-		userID, dayStart.UTC(), dayEnd.UTC(),
-	).Scan(&total)
+	err = d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT COALESCE(SUM(delta_liters), 0) FROM water_events WHERE user_id=$1 AND created_at >= $2 AND created_at < $3;",
+			userID, dayStart.UTC(), dayEnd.UTC(),
+		).Scan(&total)
+	})
 	return total, err
 }
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+
+	"vitals/internal/domain"
+)
+
+const (
+	webhookColumns  = "id, user_id, url, secret, events, enabled, created_at"
+	deliveryColumns = "id, webhook_id, user_id, event, payload, attempts, status_code, last_error, created_at, delivered_at, next_attempt_at"
+)
+
+// CreateWebhook stores a new webhook and returns it with its ID.
+func (d *DB) CreateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	url, err := d.seal(h.URL)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := d.seal(h.Secret)
+	if err != nil {
+		return nil, err
+	}
+	err = d.asUser(ctx, h.UserID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`INSERT INTO webhooks (user_id, url, secret, events, enabled, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;`,
+			h.UserID, url, secret, pq.Array(h.Events), h.Enabled, h.CreatedAt.UTC(),
+		).Scan(&h.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// GetWebhook returns the user's webhook with id, or nil.
+func (d *DB) GetWebhook(ctx context.Context, userID, id int64) (*domain.Webhook, error) {
+	var h *domain.Webhook
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		h, err = d.scanWebhook(q.QueryRowContext(ctx,
+			"SELECT "+webhookColumns+" FROM webhooks WHERE user_id=$1 AND id=$2;", userID, id))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return h, err
+}
+
+// ListWebhooks returns the user's webhooks, oldest first.
+func (d *DB) ListWebhooks(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	out := []domain.Webhook{}
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+webhookColumns+" FROM webhooks WHERE user_id=$1 ORDER BY id;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			h, err := d.scanWebhook(rows)
+			if err != nil {
+				return err
+			}
+			out = append(out, *h)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateWebhook replaces a webhook's URL, events and enabled flag.
+func (d *DB) UpdateWebhook(ctx context.Context, h domain.Webhook) (bool, error) {
+	url, err := d.seal(h.URL)
+	if err != nil {
+		return false, err
+	}
+	var n int64
+	err = d.asUser(ctx, h.UserID, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			"UPDATE webhooks SET url=$3, events=$4, enabled=$5 WHERE user_id=$1 AND id=$2;",
+			h.UserID, h.ID, url, pq.Array(h.Events), h.Enabled)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// DeleteWebhook removes a webhook; its deliveries go with it.
+func (d *DB) DeleteWebhook(ctx context.Context, userID, id int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx, "DELETE FROM webhooks WHERE user_id=$1 AND id=$2;", userID, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
+}
+
+// scanWebhook reads one row selected with webhookColumns.
+func (d *DB) scanWebhook(row interface{ Scan(...any) error }) (*domain.Webhook, error) {
+	var h domain.Webhook
+	if err := row.Scan(&h.ID, &h.UserID, &h.URL, &h.Secret, pq.Array(&h.Events), &h.Enabled, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if h.URL, err = d.open(h.URL); err != nil {
+		return nil, err
+	}
+	if h.Secret, err = d.open(h.Secret); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// EnqueueWebhookDeliveries stores ds, due at once.
+func (d *DB) EnqueueWebhookDeliveries(ctx context.Context, ds ...domain.WebhookDelivery) error {
+	return d.asSystem(ctx, func(q querier) error {
+		for _, del := range ds {
+			if _, err := q.ExecContext(ctx,
+				"INSERT INTO webhook_deliveries (user_id, webhook_id, event, payload) VALUES ($1, $2, $3, $4);",
+				del.UserID, del.WebhookID, del.Event, []byte(del.Payload)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimWebhookDeliveries leases up to limit due deliveries until until,
+// skipping rows locked by another worker's claim.
+func (d *DB) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var out []domain.WebhookDelivery
+	err := d.asSystem(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`UPDATE webhook_deliveries SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE next_attempt_at <= $1
+				ORDER BY id LIMIT $3
+				FOR UPDATE SKIP LOCKED)
+			RETURNING `+deliveryColumns+`;`,
+			now.UTC(), until.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		out, err = scanDeliveries(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the subquery's order.
+	slices.SortFunc(out, func(a, b domain.WebhookDelivery) int { return cmp.Compare(a.ID, b.ID) })
+	return out, nil
+}
+
+// CompleteWebhookDelivery records a successful attempt.
+func (d *DB) CompleteWebhookDelivery(ctx context.Context, id int64, status int, at time.Time) error {
+	return d.asSystem(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET attempts = attempts + 1, status_code = $2, last_error = '',
+			delivered_at = $3, next_attempt_at = NULL WHERE id=$1;`,
+			id, status, at.UTC())
+		return err
+	})
+}
+
+// FailWebhookDelivery records a failed attempt and schedules the next one,
+// or none.
+func (d *DB) FailWebhookDelivery(ctx context.Context, id int64, status int, lastErr string, next *time.Time) error {
+	var at sql.NullTime
+	if next != nil {
+		at = sql.NullTime{Time: next.UTC(), Valid: true}
+	}
+	return d.asSystem(ctx, func(q querier) error {
+		_, err := q.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET attempts = attempts + 1, status_code = $2, last_error = $3,
+			next_attempt_at = $4 WHERE id=$1;`,
+			id, status, lastErr, at)
+		return err
+	})
+}
+
+// ListWebhookDeliveries returns the webhook's latest deliveries, newest
+// first.
+func (d *DB) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]domain.WebhookDelivery, error) {
+	var out []domain.WebhookDelivery
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE user_id=$1 AND webhook_id=$2 ORDER BY id DESC LIMIT $3;",
+			userID, webhookID, limit)
+		if err != nil {
+			return err
+		}
+		out, err = scanDeliveries(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PruneWebhookDeliveries removes settled deliveries created before before.
+func (d *DB) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := d.asSystem(ctx, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			"DELETE FROM webhook_deliveries WHERE created_at < $1 AND next_attempt_at IS NULL;", before.UTC())
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+// scanDeliveries reads rows selected with deliveryColumns.
+func scanDeliveries(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	defer rows.Close() //nolint:errcheck
+
+	out := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			del             domain.WebhookDelivery
+			payload         []byte
+			delivered, next sql.NullTime
+		)
+		if err := rows.Scan(&del.ID, &del.WebhookID, &del.UserID, &del.Event, &payload, &del.Attempts,
+			&del.StatusCode, &del.LastError, &del.CreatedAt, &delivered, &next); err != nil {
+			return nil, err
+		}
+		del.Payload = payload
+		if delivered.Valid {
+			del.DeliveredAt = &delivered.Time
+		}
+		if next.Valid {
+			del.NextAttemptAt = &next.Time
+		}
+		out = append(out, del)
+	}
+	return out, rows.Err()
+}
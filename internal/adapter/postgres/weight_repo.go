@@ -12,42 +12,128 @@ import (
 // AddWeightEvent inserts a new weight event.
 func (d *DB) AddWeightEvent(ctx context.Context, userID int64, value float64, unit string, createdAt time.Time) (int64, error) {
 	var id int64
-	err := d.sql.QueryRowContext(ctx,
-		"INSERT INTO weight_events(user_id, value, unit, created_at) VALUES($1, $2, $3, $4) RETURNING id;",
-		userID, value, unit, createdAt.UTC(),
-	).Scan(&id)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`WITH ins AS (
+				INSERT INTO weight_events(user_id, value, unit, created_at) VALUES($1, $2, $3, $4) RETURNING id
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'weight', id, 'upsert', now() FROM ins RETURNING entity_id;`,
+			userID, value, unit, createdAt.UTC(),
+		).Scan(&id)
+	})
 	return id, err
 }
 
-// DeleteLatestWeightEvent removes the most recent weight event for a user.
-func (d *DB) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
-	var id int64
-	err := d.sql.QueryRowContext(ctx, "SELECT id FROM weight_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT 1;", userID).Scan(&id)
+// AddWeightEventWithClientID inserts a weight event unless the user has
+// already stored one with the same client ID, in which case the existing row
+// is returned.
+func (d *DB) AddWeightEventWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error) {
+	var (
+		e       *domain.WeightEntry
+		created bool
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		var err error
+		e, created, err = insertWeight(ctx, q, userID, clientID, value, unit, createdAt)
+		return err
+	})
+	return e, created, err
+}
+
+// insertWeight inserts a weight event, deduplicating on a non-empty
+// clientID, and logs the change.
+func insertWeight(ctx context.Context, q querier, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error) {
+	var (
+		e       domain.WeightEntry
+		created bool
+	)
+	err := q.QueryRowContext(ctx,
+		`WITH ins AS (
+			INSERT INTO weight_events(user_id, client_id, value, unit, created_at) VALUES($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+			RETURNING id, value, unit, created_at
+		), log AS (
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'weight', id, 'upsert', now() FROM ins
+		)
+		SELECT id, value, unit, created_at, true FROM ins
+		UNION ALL
+		SELECT id, value, unit, created_at, false FROM weight_events WHERE user_id=$1 AND client_id=$2;`,
+		userID, nullString(clientID), value, unit, createdAt.UTC(),
+	).Scan(&e.ID, &e.Value, &e.Unit, &e.CreatedAt, &created)
+	if err != nil {
+		return nil, false, err
+	}
+	e.UserID = userID
+	e.ClientID = clientID
+	e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
+	return &e, created, nil
+}
+
+// deleteWeight removes a weight event by ID, scoped to a user, and logs the
+// change.
+func deleteWeight(ctx context.Context, q querier, userID, id int64) (bool, error) {
+	res, err := q.ExecContext(ctx,
+		`WITH del AS (
+			DELETE FROM weight_events WHERE id=$1 AND user_id=$2 RETURNING id
+		), untag AS (
+			DELETE FROM entry_tags WHERE entity='weight' AND entry_id IN (SELECT id FROM del)
+		)
+		INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+		SELECT $2, 'weight', id, 'delete', now() FROM del;`,
+		id, userID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
-		}
 		return false, err
 	}
-	_, err = d.sql.ExecContext(ctx, "DELETE FROM weight_events WHERE id=$1 AND user_id=$2;", id, userID)
-	return err == nil, err
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteLatestWeightEvent removes the most recent weight event for a user.
+// Finding and deleting the event is one statement that locks the row, so
+// two concurrent undos never both delete it. Under READ COMMITTED the one
+// that waited on the lock finds the row gone and deletes nothing; it does
+// not move on to the next event, and reports false.
+func (d *DB) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
+	var n int64
+	err := d.asUser(ctx, userID, func(q querier) error {
+		res, err := q.ExecContext(ctx,
+			`WITH latest AS (
+				SELECT id FROM weight_events WHERE user_id=$1
+				ORDER BY created_at DESC, id DESC LIMIT 1 FOR UPDATE
+			), del AS (
+				DELETE FROM weight_events WHERE id IN (SELECT id FROM latest) RETURNING id
+			), untag AS (
+				DELETE FROM entry_tags WHERE entity='weight' AND entry_id IN (SELECT id FROM del)
+			)
+			INSERT INTO changes(user_id, entity, entity_id, op, changed_at)
+			SELECT $1, 'weight', id, 'delete', now() FROM del;`,
+			userID)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n > 0, err
 }
 
 // LatestWeightForLocalDay returns the most recent weight entry for a local calendar day for a user.
 func (d *DB) LatestWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error) {
-	dayStart, err := time.ParseInLocation("2006-01-02", localDay, time.Local)
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
 	if err != nil {
 		return nil, err
 	}
-	dayEnd := dayStart.Add(24 * time.Hour)
-
-	row := d.sql.QueryRowContext(ctx,
-		"SELECT id, value, unit, created_at FROM weight_events WHERE user_id=$1 AND created_at >= $2 AND created_at < $3 ORDER BY created_at DESC LIMIT 1;",
-		userID, dayStart.UTC(), dayEnd.UTC(),
-	)
 
 	var e domain.WeightEntry
-	if err := row.Scan(&e.ID, &e.Value, &e.Unit, &e.CreatedAt); err != nil {
+	err = d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			"SELECT id, value, unit, COALESCE(client_id, ''), created_at FROM weight_events WHERE user_id=$1 AND created_at >= $2 AND created_at < $3 ORDER BY created_at DESC LIMIT 1;",
+			userID, dayStart.UTC(), dayEnd.UTC(),
+		).Scan(&e.ID, &e.Value, &e.Unit, &e.ClientID, &e.CreatedAt)
+	})
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -58,24 +144,147 @@ func (d *DB) LatestWeightForLocalDay(ctx context.Context, userID int64, localDay
 	return &e, nil
 }
 
+// AverageWeightForLocalDay returns the mean of the day's weigh-ins in kg,
+// converting lb entries in the query.
+func (d *DB) AverageWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*domain.WeightEntry, error) {
+	dayStart, dayEnd, err := domain.DayWindow(localDay, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		avg    sql.NullFloat64
+		latest sql.NullTime
+	)
+	err = d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`SELECT AVG(CASE WHEN unit = 'lb' THEN value / $4 ELSE value END), MAX(created_at)
+			FROM weight_events WHERE user_id=$1 AND created_at >= $2 AND created_at < $3;`,
+			userID, dayStart.UTC(), dayEnd.UTC(), domain.ConvertWeight(1, "kg", "lb"),
+		).Scan(&avg, &latest)
+	})
+	if err != nil || !avg.Valid {
+		return nil, err
+	}
+	return &domain.WeightEntry{UserID: userID, Day: localDay, Value: avg.Float64, Unit: "kg", CreatedAt: latest.Time}, nil
+}
+
+// WeightSpanSince aggregates the user's weigh-ins at or after since in one
+// pass, converting lb entries to kg in the query.
+func (d *DB) WeightSpanSince(ctx context.Context, userID int64, since time.Time) (*domain.WeightSpan, error) {
+	var (
+		span            domain.WeightSpan
+		first, last     sql.NullFloat64
+		firstAt, lastAt sql.NullTime
+	)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		return q.QueryRowContext(ctx,
+			`SELECT COUNT(*),
+				(ARRAY_AGG(kg ORDER BY created_at ASC, id ASC))[1],
+				(ARRAY_AGG(kg ORDER BY created_at DESC, id DESC))[1],
+				MIN(created_at), MAX(created_at)
+			FROM (
+				SELECT id, created_at, CASE WHEN unit = 'lb' THEN value / $3 ELSE value END AS kg
+				FROM weight_events WHERE user_id=$1 AND created_at >= $2
+			) w;`,
+			userID, since.UTC(), domain.ConvertWeight(1, "kg", "lb"),
+		).Scan(&span.Count, &first, &last, &firstAt, &lastAt)
+	})
+	if err != nil || span.Count == 0 {
+		return nil, err
+	}
+	span.FirstKg, span.LastKg = first.Float64, last.Float64
+	span.FirstAt, span.LastAt = firstAt.Time, lastAt.Time
+	return &span, nil
+}
+
 // ListRecentWeightEvents returns the most recent weight events up to limit for a user.
 func (d *DB) ListRecentWeightEvents(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error) {
-	rows, err := d.sql.QueryContext(ctx,
-		"SELECT id, value, unit, created_at FROM weight_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;", userID, limit)
+	out := make([]domain.WeightEntry, 0, limit)
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT id, value, unit, COALESCE(client_id, ''), created_at FROM weight_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;", userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var e domain.WeightEntry
+			if err := rows.Scan(&e.ID, &e.Value, &e.Unit, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			e.UserID = userID
+			e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
+			out = append(out, e)
+		}
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close() //nolint:errcheck
+	return out, nil
+}
 
+// ListRecentWeightPage returns the most recent weight events up to limit
+// and, through window functions over the same query, a summary of all the
+// user's weight events. limit must be positive.
+func (d *DB) ListRecentWeightPage(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, domain.ListSummary, error) {
 	out := make([]domain.WeightEntry, 0, limit)
-	for rows.Next() {
-		var e domain.WeightEntry
-		if err := rows.Scan(&e.ID, &e.Value, &e.Unit, &e.CreatedAt); err != nil {
-			return nil, err
+	var sum domain.ListSummary
+	err := d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			`SELECT id, value, unit, COALESCE(client_id, ''), created_at,
+				COUNT(*) OVER (), MIN(created_at) OVER (), MAX(created_at) OVER ()
+			FROM weight_events WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2;`, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			var (
+				e             domain.WeightEntry
+				first, latest time.Time
+			)
+			if err := rows.Scan(&e.ID, &e.Value, &e.Unit, &e.ClientID, &e.CreatedAt, &sum.Total, &first, &latest); err != nil {
+				return err
+			}
+			e.UserID = userID
+			e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
+			sum.FirstAt, sum.LastAt = &first, &latest
+			out = append(out, e)
 		}
-		e.UserID = userID
-		e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
-		out = append(out, e)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, domain.ListSummary{}, err
 	}
-	return out, rows.Err()
+	sum.HasMore = sum.Total > len(out)
+	return out, sum, nil
+}
+
+// EachWeightEvent streams the user's weight events to fn, oldest first, as
+// rows arrive rather than collecting them in memory.
+func (d *DB) EachWeightEvent(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error {
+	return d.asUser(ctx, userID, func(q querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT id, value, unit, COALESCE(client_id, ''), created_at FROM weight_events WHERE user_id=$1 ORDER BY created_at, id;", userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		for rows.Next() {
+			e := domain.WeightEntry{UserID: userID}
+			if err := rows.Scan(&e.ID, &e.Value, &e.Unit, &e.ClientID, &e.CreatedAt); err != nil {
+				return err
+			}
+			e.Day = e.CreatedAt.In(time.Local).Format("2006-01-02")
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
 }
@@ -0,0 +1,60 @@
+package pow
+
+import (
+	"context"
+	"crypto/sha256"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve finds a counter for challenge by brute force.
+func solve(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		s := challenge + ":" + strconv.Itoa(i)
+		if sum := sha256.Sum256([]byte(s)); leadingZeros(sum[:]) >= difficulty {
+			return s
+		}
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := c.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued["kind"] != "pow" || issued["difficulty"] != 8 {
+		t.Fatalf("unexpected challenge %v", issued)
+	}
+	challenge := issued["challenge"].(string)
+	solution := solve(challenge, 8)
+
+	for _, bad := range []string{"", challenge, challenge + ":", "1.2.3:4", "9" + solution} {
+		if ok, _ := c.Verify(ctx, bad, "10.0.0.1"); ok {
+			t.Errorf("Verify(%q) = true", bad)
+		}
+	}
+	if ok, err := c.Verify(ctx, solution, "10.0.0.1"); err != nil || !ok {
+		t.Fatalf("Verify(solution) = %v, %v", ok, err)
+	}
+	if ok, _ := c.Verify(ctx, solution, "10.0.0.1"); ok {
+		t.Error("expected a solution to be accepted only once")
+	}
+
+	// Challenges signed by another instance, or expired, are refused.
+	other, _ := New(8)
+	if ok, _ := other.Verify(ctx, solve(challenge, 8), "10.0.0.1"); ok {
+		t.Error("expected another key to refuse the challenge")
+	}
+	issued, _ = c.New(ctx)
+	solution = solve(issued["challenge"].(string), 8)
+	c.now = func() time.Time { return time.Now().Add(challengeTTL + time.Second) }
+	if ok, _ := c.Verify(ctx, solution, "10.0.0.1"); ok {
+		t.Error("expected an expired challenge to be refused")
+	}
+}
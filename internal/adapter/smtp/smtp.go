@@ -0,0 +1,75 @@
+// Package smtp sends transactional email, such as address verification
+// links, through an SMTP relay.
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Config configures the relay. Username and Password are optional; when set
+// they are sent with PLAIN auth, which net/smtp only allows over TLS or to
+// localhost.
+type Config struct {
+	// Addr is the relay's host:port, e.g. "smtp.example.com:587".
+	Addr     string
+	Username string
+	Password string
+	// From is the sender address, e.g. "vitals@example.com".
+	From string
+}
+
+// Mailer implements domain.Mailer over SMTP.
+type Mailer struct {
+	cfg Config
+}
+
+var _ domain.Mailer = (*Mailer)(nil)
+
+// New validates cfg and returns a Mailer for it.
+func New(cfg Config) (*Mailer, error) {
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("smtp: address %q must be host:port", cfg.Addr)
+	}
+	if cfg.From == "" {
+		return nil, errors.New("smtp: a sender address is required")
+	}
+	return &Mailer{cfg: cfg}, nil
+}
+
+// SendMail sends a plain-text message to a single recipient.
+func (m *Mailer) SendMail(ctx context.Context, to, subject, body string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("smtp: header values must not contain line breaks")
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(m.cfg.Addr)
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.cfg.Addr, auth, m.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
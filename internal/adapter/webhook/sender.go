@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Headers set on every user webhook delivery.
+const (
+	HeaderEvent     = "X-Vitals-Event"
+	HeaderDelivery  = "X-Vitals-Delivery"
+	HeaderTimestamp = "X-Vitals-Timestamp"
+	HeaderSignature = "X-Vitals-Signature"
+)
+
+// ErrPrivateAddress indicates a webhook URL that resolved to a loopback,
+// link-local, private or otherwise non-public address.
+var ErrPrivateAddress = errors.New("webhook: refusing to connect to a non-public address")
+
+// nonPublic lists ranges that are not public yet that netip.Addr does not
+// classify as private: carrier-grade NAT, "this network" and benchmarking.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// guard refuses connections to non-public addresses. Webhook URLs are
+// user-supplied, so every client in this package dials through one.
+type guard struct {
+	allowPrivate bool
+}
+
+// client returns an HTTP client with a bounded request timeout. It checks
+// each address as it dials it, redirects included, rather than the URL's
+// host name, so DNS rebinding cannot lead it to an internal service.
+func (g *guard) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: g.checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed in place of the receiver and defeat the
+	// check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// checkAddress is the dialer's Control hook: it refuses non-public
+// addresses unless private networks are allowed.
+func (g *guard) checkAddress(_, address string, _ syscall.RawConn) error {
+	if g.allowPrivate {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	addr := ap.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() ||
+		slices.ContainsFunc(nonPublic, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// Sender implements domain.WebhookSender, signing each payload so the
+// receiver can check that it came from this server. By default it only
+// connects to public addresses.
+type Sender struct {
+	guard
+	client *http.Client
+	now    func() time.Time
+}
+
+var _ domain.WebhookSender = (*Sender)(nil)
+
+// NewSender returns a Sender that only dials public addresses.
+func NewSender() *Sender {
+	s := &Sender{now: time.Now}
+	s.client = s.guard.client()
+	return s
+}
+
+// AllowPrivateNetworks lets the Sender deliver to loopback and private
+// addresses, for receivers on the same network such as Home Assistant.
+func (s *Sender) AllowPrivateNetworks() *Sender {
+	s.allowPrivate = true
+	return s
+}
+
+// Send POSTs d's payload to url and fails on any non-2xx response.
+func (s *Sender) Send(ctx context.Context, url, secret string, d domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("webhook: %w", err)
+	}
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vitals")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook: %s returned %s", url, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for body sent at timestamp ts:
+// "sha256=" and the hex HMAC-SHA256, keyed by secret, of ts, a dot and
+// body. Receivers recompute it, compare in constant time, and reject old
+// timestamps to stop replays.
+func Sign(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
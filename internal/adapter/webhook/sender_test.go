@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestSenderSignsPayload(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	d := domain.WebhookDelivery{ID: 7, Event: domain.WebhookEventWeightRecorded, Payload: []byte(`{"event":"weight.recorded"}`)}
+	code, err := NewSender().AllowPrivateNetworks().Send(context.Background(), srv.URL, "s3cret", d)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("Send = %d, %v", code, err)
+	}
+	if string(body) != string(d.Payload) || got.Header.Get(HeaderEvent) != "weight.recorded" || got.Header.Get(HeaderDelivery) != "7" {
+		t.Errorf("unexpected request %v: %s", got.Header, body)
+	}
+	ts := got.Header.Get(HeaderTimestamp)
+	if sig := got.Header.Get(HeaderSignature); ts == "" || sig != Sign("s3cret", ts, body) {
+		t.Errorf("signature %q does not verify", sig)
+	}
+	if Sign("other", ts, body) == Sign("s3cret", ts, body) || Sign("s3cret", ts+"0", body) == Sign("s3cret", ts, body) {
+		t.Error("expected the signature to depend on the secret and timestamp")
+	}
+
+	status = http.StatusGone
+	if code, err := NewSender().AllowPrivateNetworks().Send(context.Background(), srv.URL, "s3cret", d); err == nil || code != http.StatusGone {
+		t.Errorf("expected a non-2xx response to fail with its status, got %d, %v", code, err)
+	}
+}
+
+func TestSenderRefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	d := domain.WebhookDelivery{ID: 1, Event: domain.WebhookEventWeightRecorded, Payload: []byte(`{}`)}
+	// localhost resolves only at dial time, as a rebinding name would.
+	for _, url := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)} {
+		if _, err := NewSender().Send(context.Background(), url, "s3cret", d); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: expected ErrPrivateAddress, got %v", url, err)
+		}
+	}
+	if hit {
+		t.Error("expected no request to reach the loopback receiver")
+	}
+
+	s := NewSender()
+	for _, addr := range []string{"10.1.2.3:80", "192.168.0.1:443", "169.254.169.254:80", "[::1]:80", "[fe80::1]:80", "[fd00::1]:80", "100.64.0.1:80", "0.0.0.0:80"} {
+		if err := s.checkAddress("tcp", addr, nil); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: expected ErrPrivateAddress, got %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34:443", "[2606:4700::1111]:443"} {
+		if err := s.checkAddress("tcp", addr, nil); err != nil {
+			t.Errorf("%s: expected a public address allowed, got %v", addr, err)
+		}
+	}
+}
+
+func TestNotifierRefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	n := domain.Notification{Target: srv.URL, Title: "Weight alert"}
+	if err := New().Notify(context.Background(), n); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected ErrPrivateAddress, got %v", err)
+	}
+	if hit {
+		t.Error("expected no request to reach the loopback receiver")
+	}
+	if err := New().AllowPrivateNetworks().Notify(context.Background(), n); err != nil || !hit {
+		t.Errorf("expected delivery once private networks are allowed, got %v", err)
+	}
+}
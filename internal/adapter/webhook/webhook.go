@@ -0,0 +1,61 @@
+// Package webhook delivers notifications as JSON POST requests, e.g. to
+// ntfy, Home Assistant or a chat integration.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"vitals/internal/domain"
+)
+
+// Notifier implements domain.Notifier by POSTing each notification to its
+// Target URL. Targets are user-supplied, so like Sender it only connects
+// to public addresses by default.
+type Notifier struct {
+	guard
+	client *http.Client
+}
+
+var _ domain.Notifier = (*Notifier)(nil)
+
+// New returns a Notifier that only dials public addresses.
+func New() *Notifier {
+	w := &Notifier{}
+	w.client = w.guard.client()
+	return w
+}
+
+// AllowPrivateNetworks lets the Notifier deliver to loopback and private
+// addresses, as Sender.AllowPrivateNetworks does.
+func (w *Notifier) AllowPrivateNetworks() *Notifier {
+	w.allowPrivate = true
+	return w
+}
+
+// Notify sends n and fails on any non-2xx response.
+func (w *Notifier) Notify(ctx context.Context, n domain.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vitals")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s returned %s", n.Target, resp.Status)
+	}
+	return nil
+}
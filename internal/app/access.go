@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly indicates that a write was attempted with read-only access.
+var ErrReadOnly = errors.New("read-only access")
+
+type readOnlyKey struct{}
+
+// WithReadOnly returns a context under which service write operations are
+// rejected with ErrReadOnly. Driving adapters set it for guest/demo access.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly reports whether ctx carries read-only access.
+func IsReadOnly(ctx context.Context) bool {
+	v, _ := ctx.Value(readOnlyKey{}).(bool)
+	return v
+}
+
+// checkWritable is the service-level write guard.
+func checkWritable(ctx context.Context) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	return nil
+}
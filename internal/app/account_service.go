@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrUsernameTaken indicates that another account uses the username.
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrEmailTaken indicates that another account verified the email.
+	ErrEmailTaken = errors.New("email is already in use")
+	// ErrEmailUnavailable indicates that no mailer is configured to send
+	// verification links.
+	ErrEmailUnavailable = errors.New("email verification is not configured")
+	// ErrVerificationInvalid indicates an unknown or expired verification
+	// link.
+	ErrVerificationInvalid = errors.New("verification link is invalid or expired")
+)
+
+// Account is a user's own view of their account details.
+type Account struct {
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	// PendingEmail awaits verification and replaces Email once verified.
+	PendingEmail string `json:"pendingEmail,omitempty"`
+	// Admin is set for accounts that may use the /api/admin endpoints.
+	Admin bool `json:"admin,omitempty"`
+	// Format holds the user's formatting hints, when preferences are
+	// enabled.
+	Format *FormatHints `json:"format,omitempty"`
+}
+
+// AccountService changes a user's username and email. Sessions refer to
+// accounts by id, so both changes keep the user signed in.
+type AccountService struct {
+	users      domain.UserRepository
+	accounts   domain.AccountRepository
+	identities domain.IdentityRepository
+	mailer     domain.Mailer
+	verifyURL  string
+}
+
+// NewAccountService creates an AccountService. Email changes are rejected
+// until a mailer is registered with WithMailer.
+func NewAccountService(users domain.UserRepository, accounts domain.AccountRepository) *AccountService {
+	return &AccountService{users: users, accounts: accounts}
+}
+
+// WithIdentities reserves usernames that a forward-auth proxy's users are
+// linked under, so renaming into one cannot capture that proxy user.
+func (s *AccountService) WithIdentities(repo domain.IdentityRepository) *AccountService {
+	s.identities = repo
+	return s
+}
+
+// WithMailer sends email verification links through m. verifyURL is the
+// absolute URL of the verification endpoint; the token is appended as
+// ?token=.
+func (s *AccountService) WithMailer(m domain.Mailer, verifyURL string) *AccountService {
+	s.mailer = m
+	s.verifyURL = verifyURL
+	return s
+}
+
+// Get returns the user's account details.
+func (s *AccountService) Get(ctx context.Context, userID int64) (*Account, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	acct := &Account{Username: user.Username, Email: user.Email, Admin: user.IsAdmin()}
+	pending, err := s.accounts.GetEmailChange(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil && time.Now().Before(pending.ExpiresAt) {
+		acct.PendingEmail = pending.Email
+	}
+	return acct, nil
+}
+
+// ChangeUsername renames the user. Usernames that look like email
+// addresses are how SSO logins find accounts, so only the user's own
+// verified address may be taken as one.
+func (s *AccountService) ChangeUsername(ctx context.Context, userID int64, username string) (*Account, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	username, err := domain.ValidateUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if username == user.Username {
+		return s.Get(ctx, userID)
+	}
+	if strings.Contains(username, "@") && !strings.EqualFold(username, user.Email) {
+		return nil, errors.New("username may only be an email address once that address is verified")
+	}
+
+	other, err := s.users.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if other != nil && other.ID != userID {
+		return nil, ErrUsernameTaken
+	}
+	if s.identities != nil {
+		linked, err := s.identities.GetLinkedIdentity(ctx, domain.ForwardAuthIssuer, username)
+		if err != nil {
+			return nil, err
+		}
+		if linked != nil && linked.UserID != userID {
+			return nil, ErrUsernameTaken
+		}
+	}
+
+	if err := s.accounts.UpdateUsername(ctx, userID, username); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID)
+}
+
+// RequestEmailChange starts changing the user's email to email by mailing
+// a verification link to it. The current address, if any, stays in effect
+// until the link is followed.
+func (s *AccountService) RequestEmailChange(ctx context.Context, userID int64, email string) (*Account, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if s.mailer == nil {
+		return nil, ErrEmailUnavailable
+	}
+	email, err := domain.NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEmailFree(ctx, userID, email); err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	change := domain.EmailChange{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(domain.EmailVerificationTTL),
+	}
+	if err := s.accounts.SaveEmailChange(ctx, change); err != nil {
+		return nil, err
+	}
+
+	link := s.verifyURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Follow this link within %s to use %s for your vitals account:\n\n%s\n\nIf you did not ask for this, ignore this email.\n",
+		domain.EmailVerificationTTL, email, link)
+	if err := s.mailer.SendMail(ctx, email, "Verify your email address", body); err != nil {
+		return nil, fmt.Errorf("send verification email: %w", err)
+	}
+	return s.Get(ctx, userID)
+}
+
+// VerifyEmail completes the email change that token was mailed for and
+// returns the updated user.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" {
+		return nil, ErrVerificationInvalid
+	}
+	change, err := s.accounts.TakeEmailChange(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if change == nil || time.Now().After(change.ExpiresAt) {
+		return nil, ErrVerificationInvalid
+	}
+	// Another account may have verified the address in the meantime.
+	if err := s.checkEmailFree(ctx, change.UserID, change.Email); err != nil {
+		return nil, err
+	}
+	if err := s.accounts.SetEmail(ctx, change.UserID, change.Email); err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByID(ctx, change.UserID)
+	if err == nil && user == nil {
+		err = ErrUserNotFound
+	}
+	return user, err
+}
+
+// checkEmailFree rejects email if another account verified it.
+func (s *AccountService) checkEmailFree(ctx context.Context, userID int64, email string) error {
+	other, err := s.accounts.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.ID != userID {
+		return ErrEmailTaken
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// mockAccountRepo keeps users by id and serves both the user and account
+// ports over them.
+type mockAccountRepo struct {
+	users   map[int64]*domain.User
+	changes map[int64]domain.EmailChange
+}
+
+func newMockAccountRepo(users ...*domain.User) *mockAccountRepo {
+	m := &mockAccountRepo{users: map[int64]*domain.User{}, changes: map[int64]domain.EmailChange{}}
+	for _, u := range users {
+		m.users[u.ID] = u
+	}
+	return m
+}
+
+// userRepo returns a mockUserRepo reading the same users.
+func (m *mockAccountRepo) userRepo() *mockUserRepo {
+	return &mockUserRepo{
+		getByIDFn: func(_ context.Context, id int64) (*domain.User, error) {
+			return m.users[id], nil
+		},
+		getByUsernameFn: func(_ context.Context, username string) (*domain.User, error) {
+			for _, u := range m.users {
+				if u.Username == username {
+					return u, nil
+				}
+			}
+			return nil, nil
+		},
+		createFn: func(_ context.Context, username, passwordHash string) (*domain.User, error) {
+			u := &domain.User{ID: int64(len(m.users) + 1), Username: username, PasswordHash: passwordHash, Role: domain.RoleUser}
+			m.users[u.ID] = u
+			return u, nil
+		},
+		countFn: func(context.Context) (int, error) {
+			return len(m.users), nil
+		},
+	}
+}
+
+func (m *mockAccountRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, u := range m.users {
+		if u.Email != "" && u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAccountRepo) UpdateUsername(ctx context.Context, userID int64, username string) error {
+	m.users[userID].Username = username
+	return nil
+}
+
+func (m *mockAccountRepo) SetEmail(ctx context.Context, userID int64, email string) error {
+	m.users[userID].Email = email
+	return nil
+}
+
+func (m *mockAccountRepo) SetRole(ctx context.Context, userID int64, role string) error {
+	m.users[userID].Role = role
+	return nil
+}
+
+func (m *mockAccountRepo) ClaimAdmin(ctx context.Context, userID int64) (bool, error) {
+	for _, u := range m.users {
+		if u.IsAdmin() {
+			return false, nil
+		}
+	}
+	m.users[userID].Role = domain.RoleAdmin
+	return true, nil
+}
+
+func (m *mockAccountRepo) SaveEmailChange(ctx context.Context, c domain.EmailChange) error {
+	m.changes[c.UserID] = c
+	return nil
+}
+
+func (m *mockAccountRepo) GetEmailChange(ctx context.Context, userID int64) (*domain.EmailChange, error) {
+	c, ok := m.changes[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (m *mockAccountRepo) TakeEmailChange(ctx context.Context, tokenHash string) (*domain.EmailChange, error) {
+	for id, c := range m.changes {
+		if c.TokenHash == tokenHash {
+			delete(m.changes, id)
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+type recordingMailer struct {
+	to, body []string
+}
+
+func (m *recordingMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.body = append(m.body, body)
+	return nil
+}
+
+// verificationToken extracts the token from the link in a mailed body.
+func verificationToken(t *testing.T, body string) string {
+	t.Helper()
+	i := strings.Index(body, "https://")
+	if i < 0 {
+		t.Fatalf("no link in %q", body)
+	}
+	u, err := url.Parse(strings.Fields(body[i:])[0])
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestAccountService_ChangeUsername(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockAccountRepo(
+		&domain.User{ID: 1, Username: "sam"},
+		&domain.User{ID: 2, Username: "alex"},
+	)
+	identities := &mockIdentityRepo{links: map[string]domain.LinkedIdentity{
+		domain.ForwardAuthIssuer + " proxied": {UserID: 2, Issuer: domain.ForwardAuthIssuer, Subject: "proxied"},
+	}}
+	svc := app.NewAccountService(repo.userRepo(), repo).WithIdentities(identities)
+
+	if _, err := svc.ChangeUsername(ctx, 1, "alex"); !errors.Is(err, app.ErrUsernameTaken) {
+		t.Errorf("expected ErrUsernameTaken for another user's name, got %v", err)
+	}
+	if _, err := svc.ChangeUsername(ctx, 1, "proxied"); !errors.Is(err, app.ErrUsernameTaken) {
+		t.Errorf("expected ErrUsernameTaken for a linked forward-auth name, got %v", err)
+	}
+	if _, err := svc.ChangeUsername(ctx, 1, "alex@example.com"); err == nil {
+		t.Error("expected an unverified email address to be rejected as a username")
+	}
+	if _, err := svc.ChangeUsername(ctx, 1, " "); err == nil {
+		t.Error("expected a blank username to be rejected")
+	}
+
+	acct, err := svc.ChangeUsername(ctx, 1, " samantha ")
+	if err != nil {
+		t.Fatalf("ChangeUsername: %v", err)
+	}
+	if acct.Username != "samantha" || repo.users[1].Username != "samantha" {
+		t.Errorf("expected rename to samantha, got %+v", acct)
+	}
+
+	readOnly := app.WithReadOnly(ctx)
+	if _, err := svc.ChangeUsername(readOnly, 1, "sammy"); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestAccountService_EmailChange(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockAccountRepo(
+		&domain.User{ID: 1, Username: "sam", Email: "old@example.com"},
+		&domain.User{ID: 2, Username: "alex", Email: "alex@example.com"},
+	)
+	svc := app.NewAccountService(repo.userRepo(), repo)
+
+	if _, err := svc.RequestEmailChange(ctx, 1, "new@example.com"); !errors.Is(err, app.ErrEmailUnavailable) {
+		t.Fatalf("expected ErrEmailUnavailable without a mailer, got %v", err)
+	}
+
+	mailer := &recordingMailer{}
+	svc.WithMailer(mailer, "https://vitals.example.com/api/auth/verify-email")
+
+	if _, err := svc.RequestEmailChange(ctx, 1, "Alex@Example.com"); !errors.Is(err, app.ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+	if _, err := svc.RequestEmailChange(ctx, 1, "Sam <sam@example.com>"); err == nil {
+		t.Error("expected a display name to be rejected")
+	}
+
+	acct, err := svc.RequestEmailChange(ctx, 1, "New@Example.com")
+	if err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	if acct.Email != "old@example.com" || acct.PendingEmail != "new@example.com" {
+		t.Errorf("expected old email kept until verified, got %+v", acct)
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "new@example.com" {
+		t.Fatalf("expected a mail to the new address, got %v", mailer.to)
+	}
+	token := verificationToken(t, mailer.body[0])
+
+	if _, err := svc.VerifyEmail(ctx, "bogus"); !errors.Is(err, app.ErrVerificationInvalid) {
+		t.Errorf("expected ErrVerificationInvalid, got %v", err)
+	}
+	user, err := svc.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if user.ID != 1 || user.Email != "new@example.com" {
+		t.Errorf("expected user 1 to have the new email, got %+v", user)
+	}
+	if _, err := svc.VerifyEmail(ctx, token); !errors.Is(err, app.ErrVerificationInvalid) {
+		t.Errorf("expected a used link to be invalid, got %v", err)
+	}
+
+	// Expired links do nothing.
+	if _, err := svc.RequestEmailChange(ctx, 1, "later@example.com"); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	c := repo.changes[1]
+	c.ExpiresAt = time.Now().Add(-time.Minute)
+	repo.changes[1] = c
+	if _, err := svc.VerifyEmail(ctx, verificationToken(t, mailer.body[1])); !errors.Is(err, app.ErrVerificationInvalid) {
+		t.Errorf("expected an expired link to be invalid, got %v", err)
+	}
+	if repo.users[1].Email != "new@example.com" {
+		t.Errorf("expected email unchanged, got %q", repo.users[1].Email)
+	}
+}
+
+func TestAuthService_MatchingAfterAccountChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockAccountRepo(&domain.User{ID: 1, Username: "sam", Email: "sam@example.com"})
+	users := repo.userRepo()
+	var sessionUser int64
+	sessions := &mockSessionRepo{
+		createFn: func(_ context.Context, userID int64, _, _, _ string, _ time.Time) error {
+			sessionUser = userID
+			return nil
+		},
+	}
+	identities := &mockIdentityRepo{links: map[string]domain.LinkedIdentity{}}
+	auth := app.NewAuthService(users, sessions).WithIdentities(identities).WithAccounts(repo)
+	accounts := app.NewAccountService(users, repo).WithIdentities(identities)
+
+	// The first forward-auth request links the proxy user by username.
+	if u, err := auth.ValidateForwardAuth(ctx, "sam"); err != nil || u.ID != 1 {
+		t.Fatalf("expected user 1, got %+v, %v", u, err)
+	}
+	if _, err := accounts.ChangeUsername(ctx, 1, "samantha"); err != nil {
+		t.Fatalf("ChangeUsername: %v", err)
+	}
+	if u, err := auth.ValidateForwardAuth(ctx, "sam"); err != nil || u.ID != 1 || u.Username != "samantha" {
+		t.Errorf("expected the proxy user to follow the rename, got %+v, %v", u, err)
+	}
+
+	// SSO finds the account by its verified email, whatever its username.
+	id := domain.LinkedIdentity{Issuer: "https://sso", Subject: "sam-sub", Email: "Sam@Example.com", EmailVerified: true}
+	if _, err := auth.LoginWithIdentity(ctx, id, testUserAgent, "127.0.0.1"); err != nil || sessionUser != 1 {
+		t.Errorf("expected session for user 1, got user %d, err %v", sessionUser, err)
+	}
+
+	// An email the provider did not verify matches neither the account's
+	// email nor a username.
+	sessionUser = 0
+	for _, email := range []string{"sam@example.com", "samantha"} {
+		id := domain.LinkedIdentity{Issuer: "https://sso", Subject: "mallory-sub", Email: email}
+		if _, err := auth.LoginWithIdentity(ctx, id, testUserAgent, "127.0.0.1"); !errors.Is(err, app.ErrLinkRequired) || sessionUser != 0 {
+			t.Errorf("unverified %q: expected ErrLinkRequired, got user %d, err %v", email, sessionUser, err)
+		}
+	}
+}
+
+func TestAuthService_FirstUserIsAdmin(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockAccountRepo()
+	auth := app.NewAuthService(repo.userRepo(), &mockSessionRepo{}).WithAccounts(repo)
+
+	if err := auth.CreateInitialUser(ctx, "owner", "correct horse"); err != nil {
+		t.Fatalf("CreateInitialUser: %v", err)
+	}
+	if !repo.users[1].IsAdmin() {
+		t.Errorf("expected the initial user to be an admin, got role %q", repo.users[1].Role)
+	}
+
+	u, err := auth.ValidateForwardAuth(ctx, "guest")
+	if err != nil {
+		t.Fatalf("ValidateForwardAuth: %v", err)
+	}
+	if u.IsAdmin() {
+		t.Error("expected later accounts to be plain users")
+	}
+}
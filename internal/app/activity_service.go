@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"vitals/internal/domain"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 500
+)
+
+// ActivityService serves a user's activity timeline: their weigh-ins, water,
+// goal changes and imports in one feed, newest first, for a history screen.
+type ActivityService struct {
+	repo domain.ActivityRepository
+}
+
+// NewActivityService creates an ActivityService backed by the given
+// repository.
+func NewActivityService(repo domain.ActivityRepository) *ActivityService {
+	return &ActivityService{repo: repo}
+}
+
+// ActivityPage is one page of the timeline. Clients pass Cursor back to get
+// the next page; it is empty on the last one.
+type ActivityPage struct {
+	Items  []domain.Activity `json:"items"`
+	Cursor string            `json:"cursor,omitempty"`
+}
+
+// List returns up to limit items after cursor, or from the newest item when
+// cursor is empty.
+func (s *ActivityService) List(ctx context.Context, userID int64, cursor string, limit int) (_ *ActivityPage, err error) {
+	ctx, span := startSpan(ctx, "ActivityService.List", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	var before *domain.ActivityCursor
+	if cursor != "" {
+		if before, err = decodeActivityCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := s.repo.ListActivity(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ActivityPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.Cursor = encodeActivityCursor(domain.ActivityCursor{At: last.At, Type: last.Type, ID: last.ID})
+	}
+	if page.Items == nil {
+		page.Items = []domain.Activity{}
+	}
+	return page, nil
+}
+
+// Cursors are opaque to clients: the position of the last item served,
+// encoded as base64url JSON.
+func encodeActivityCursor(c domain.ActivityCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeActivityCursor(s string) (*domain.ActivityCursor, error) {
+	invalid := domain.Errorf(domain.ErrInvalidArgument, "invalid activity cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	var c domain.ActivityCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.At.IsZero() || c.Type == "" {
+		return nil, invalid
+	}
+	return &c, nil
+}
@@ -0,0 +1,82 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// mockActivityRepo serves items, which must be newest first.
+type mockActivityRepo struct {
+	items []domain.Activity
+}
+
+func (m *mockActivityRepo) ListActivity(ctx context.Context, userID int64, before *domain.ActivityCursor, limit int) ([]domain.Activity, error) {
+	var out []domain.Activity
+	for _, a := range m.items {
+		if (before == nil || before.Admits(a)) && len(out) < limit {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func TestActivityService_Paging(t *testing.T) {
+	at := time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC)
+	// Items at the same time are ordered by type, then ID, descending.
+	repo := &mockActivityRepo{items: []domain.Activity{
+		{Type: domain.ActivityWeight, At: at.Add(2 * time.Hour), ID: "7"},
+		{Type: domain.ActivityWater, At: at.Add(time.Hour), ID: "9"},
+		{Type: domain.ActivityImport, At: at.Add(time.Hour), ID: "b1"},
+		{Type: domain.ActivityWeightGoal, At: at, ID: "2026-05-03"},
+		{Type: domain.ActivityWaterGoal, At: at, ID: "2026-05-03"},
+	}}
+	svc := app.NewActivityService(repo)
+	ctx := context.Background()
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages == 5 {
+			t.Fatal("paging did not end")
+		}
+		page, err := svc.List(ctx, 1, cursor, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, a := range page.Items {
+			got = append(got, a.Type+"/"+a.ID)
+		}
+		if page.Cursor == "" {
+			break
+		}
+		cursor = page.Cursor
+	}
+	want := []string{"weight/7", "water/9", "import/b1", "goal.weight/2026-05-03", "goal.water/2026-05-03"}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged through %v, want %v", got, want)
+	}
+
+	page, err := svc.List(ctx, 1, "", 0)
+	if err != nil || len(page.Items) != 5 || page.Cursor != "" {
+		t.Errorf("default limit: %+v, %v", page, err)
+	}
+	empty, err := app.NewActivityService(&mockActivityRepo{}).List(ctx, 1, "", 10)
+	if err != nil || empty.Items == nil || len(empty.Items) != 0 {
+		t.Errorf("empty timeline: %+v, %v", empty, err)
+	}
+}
+
+func TestActivityService_InvalidCursor(t *testing.T) {
+	svc := app.NewActivityService(&mockActivityRepo{})
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := svc.List(context.Background(), 1, cursor, 10); !errors.Is(err, domain.ErrInvalidArgument) {
+			t.Errorf("cursor %q: expected an invalid argument error, got %v", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	// alertCooldown is the minimum time between two alerts for one user.
+	alertCooldown = 7 * 24 * time.Hour
+	// alertHistoryLimit bounds how many weigh-ins an evaluation reads.
+	alertHistoryLimit = 200
+	// maxAlertThresholdPct caps the configurable weekly change threshold.
+	maxAlertThresholdPct = 10
+	// alertEvaluateTimeout bounds a background evaluation, which outlives
+	// the request's own deadline.
+	alertEvaluateTimeout = 30 * time.Second
+)
+
+// NotificationKindWeightChange marks rapid weight-change notifications.
+const NotificationKindWeightChange = "weight.rapid-change"
+
+// AlertService configures and evaluates rapid weight-change alerts. It is an
+// EventPublisher so that wiring it to the WeightService evaluates the rule
+// after every weigh-in.
+type AlertService struct {
+	rules     domain.AlertRuleRepository
+	weight    domain.WeightRepository
+	notifiers map[string]domain.Notifier
+	outbox    domain.OutboxRepository
+}
+
+var _ domain.EventPublisher = (*AlertService)(nil)
+
+// NewAlertService creates an AlertService backed by the given repositories.
+// Channels become available as notifiers are registered with WithNotifier.
+func NewAlertService(rules domain.AlertRuleRepository, weight domain.WeightRepository) *AlertService {
+	return &AlertService{rules: rules, weight: weight, notifiers: map[string]domain.Notifier{}}
+}
+
+// WithNotifier registers n as the notifier for channel.
+func (s *AlertService) WithNotifier(channel string, n domain.Notifier) *AlertService {
+	s.notifiers[channel] = n
+	return s
+}
+
+// WithOutbox queues notifications in outbox, for an OutboxService to
+// deliver, instead of sending them directly. Alerts are queued in the same
+// transaction as marking the rule alerted, so none is lost or sent twice
+// when the process stops in between.
+func (s *AlertService) WithOutbox(outbox domain.OutboxRepository) *AlertService {
+	s.outbox = outbox
+	return s
+}
+
+// Channels lists the channels alerts can be delivered over.
+func (s *AlertService) Channels() []string {
+	return channels(s.notifiers)
+}
+
+// channels lists the known channels that have a registered notifier.
+func channels(notifiers map[string]domain.Notifier) []string {
+	out := make([]string, 0, len(notifiers))
+	for _, c := range []string{domain.AlertChannelWebhook, domain.AlertChannelMQTT} {
+		if _, ok := notifiers[c]; ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// GetRule returns the user's rule, or nil if none is configured.
+func (s *AlertService) GetRule(ctx context.Context, userID int64) (*domain.AlertRule, error) {
+	return s.rules.GetAlertRule(ctx, userID)
+}
+
+// SaveRule validates and stores the user's rule.
+func (s *AlertService) SaveRule(ctx context.Context, rule domain.AlertRule) (*domain.AlertRule, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	// Keep the cooldown across edits so re-saving does not re-arm the alert.
+	existing, err := s.rules.GetAlertRule(ctx, rule.UserID)
+	if err != nil {
+		return nil, err
+	}
+	rule.LastAlertedAt = nil
+	if existing != nil {
+		rule.LastAlertedAt = existing.LastAlertedAt
+	}
+	if err := s.rules.SaveAlertRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// validateRule checks rule against the available channels, clearing the
+// target of channels that do not use one.
+func (s *AlertService) validateRule(rule *domain.AlertRule) error {
+	if rule.MaxWeeklyChangePct <= 0 || rule.MaxWeeklyChangePct > maxAlertThresholdPct {
+		return fmt.Errorf("maxWeeklyChangePct must be within (0, %d]", maxAlertThresholdPct)
+	}
+	return validateChannel(s.notifiers, rule.Channel, &rule.Target)
+}
+
+// validateChannel checks that channel has a registered notifier and that
+// target suits it, clearing the target of channels that do not use one.
+func validateChannel(notifiers map[string]domain.Notifier, channel string, target *string) error {
+	if _, ok := notifiers[channel]; !ok {
+		return fmt.Errorf("channel %q is not available", channel)
+	}
+	if channel == domain.AlertChannelWebhook {
+		u, err := url.Parse(*target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("target must be an http(s) URL")
+		}
+	} else {
+		*target = ""
+	}
+	return nil
+}
+
+// Evaluate checks the user's enabled rule against their recent weigh-ins and
+// sends a notification when the weekly change exceeds the threshold. It
+// returns the notification sent, or nil.
+func (s *AlertService) Evaluate(ctx context.Context, userID int64, now time.Time) (_ *domain.Notification, err error) {
+	ctx, span := startSpan(ctx, "AlertService.Evaluate", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	rule, err := s.rules.GetAlertRule(ctx, userID)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, *rule, now)
+}
+
+// EvaluateAll evaluates every enabled rule, e.g. from a nightly job, and
+// returns the number of notifications sent.
+func (s *AlertService) EvaluateAll(ctx context.Context, now time.Time) (_ int, err error) {
+	ctx, span := startSpan(ctx, "AlertService.EvaluateAll")
+	defer func() { endSpan(span, err) }()
+
+	rules, err := s.rules.ListAlertRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, rule := range rules {
+		n, err := s.evaluate(ctx, rule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", rule.UserID, err))
+			continue
+		}
+		if n != nil {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (s *AlertService) evaluate(ctx context.Context, rule domain.AlertRule, now time.Time) (*domain.Notification, error) {
+	if !rule.Enabled {
+		return nil, nil
+	}
+	if rule.LastAlertedAt != nil && now.Sub(*rule.LastAlertedAt) < alertCooldown {
+		return nil, nil
+	}
+	notifier, ok := s.notifiers[rule.Channel]
+	if !ok {
+		return nil, fmt.Errorf("channel %q is not available", rule.Channel)
+	}
+
+	entries, err := s.weight.ListRecentWeightEvents(ctx, rule.UserID, alertHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	pct, ok := domain.WeeklyChangePercent(entries)
+	if !ok || math.Abs(pct) < rule.MaxWeeklyChangePct {
+		return nil, nil
+	}
+
+	direction := "gain"
+	if pct < 0 {
+		direction = "loss"
+	}
+	n := domain.Notification{
+		UserID:  rule.UserID,
+		Kind:    NotificationKindWeightChange,
+		Title:   fmt.Sprintf("Rapid weight %s", direction),
+		Message: fmt.Sprintf("Your weight changed by %+.1f%% per week, above your %.1f%% alert threshold.", pct, rule.MaxWeeklyChangePct),
+		At:      now,
+		Target:  rule.Target,
+	}
+	if s.outbox != nil {
+		if err := s.rules.MarkAlerted(ctx, rule.UserID, now, domain.OutboxMessage{Channel: rule.Channel, Notification: n}); err != nil {
+			return nil, err
+		}
+		return &n, nil
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return nil, err
+	}
+	if err := s.rules.MarkAlerted(ctx, rule.UserID, now); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Deliver sends n to its user over the channel and target of their
+// weight-change alert rule, enabled or not, reporting false when they have
+// none or its channel is not available. It is how other features reach the
+// user, e.g. when an export archive is ready. With an outbox, true means n
+// was queued.
+func (s *AlertService) Deliver(ctx context.Context, n domain.Notification) (bool, error) {
+	rule, err := s.rules.GetAlertRule(ctx, n.UserID)
+	if err != nil || rule == nil {
+		return false, err
+	}
+	notifier, ok := s.notifiers[rule.Channel]
+	if !ok {
+		return false, nil
+	}
+	n.Target = rule.Target
+	if s.outbox != nil {
+		if err := s.outbox.EnqueueOutbox(ctx, domain.OutboxMessage{Channel: rule.Channel, Notification: n}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Publish evaluates the user's rule in the background after each recorded
+// weight, so that a slow notifier never delays the write.
+func (s *AlertService) Publish(ctx context.Context, e domain.MetricEvent) {
+	if e.Type != domain.EventWeightRecorded {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), alertEvaluateTimeout)
+		defer cancel()
+		if _, err := s.Evaluate(ctx, e.UserID, time.Now()); err != nil {
+			log.Printf("alerts: evaluate user %d: %v", e.UserID, err)
+		}
+	}()
+}
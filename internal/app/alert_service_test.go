@@ -0,0 +1,143 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockAlertRuleRepo struct {
+	rules map[int64]domain.AlertRule
+	// queued holds the outbox messages passed to MarkAlerted.
+	queued []domain.OutboxMessage
+}
+
+func (m *mockAlertRuleRepo) GetAlertRule(ctx context.Context, userID int64) (*domain.AlertRule, error) {
+	r, ok := m.rules[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+func (m *mockAlertRuleRepo) SaveAlertRule(ctx context.Context, rule domain.AlertRule) error {
+	m.rules[rule.UserID] = rule
+	return nil
+}
+
+func (m *mockAlertRuleRepo) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	var out []domain.AlertRule
+	for _, r := range m.rules {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockAlertRuleRepo) MarkAlerted(ctx context.Context, userID int64, at time.Time, queue ...domain.OutboxMessage) error {
+	r := m.rules[userID]
+	r.LastAlertedAt = &at
+	m.rules[userID] = r
+	m.queued = append(m.queued, queue...)
+	return nil
+}
+
+type recordingNotifier struct {
+	sent []domain.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg domain.Notification) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func newAlertFixture(now time.Time, latestKg float64) (*app.AlertService, *mockAlertRuleRepo, *recordingNotifier) {
+	rules := &mockAlertRuleRepo{rules: map[int64]domain.AlertRule{}}
+	weights := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{
+				{Value: latestKg, Unit: "kg", CreatedAt: now},
+				{Value: 100, Unit: "kg", CreatedAt: now.AddDate(0, 0, -7)},
+			}, nil
+		},
+	}
+	notifier := &recordingNotifier{}
+	svc := app.NewAlertService(rules, weights).WithNotifier(domain.AlertChannelWebhook, notifier)
+	return svc, rules, notifier
+}
+
+func TestAlertService_Evaluate(t *testing.T) {
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	svc, _, notifier := newAlertFixture(now, 97)
+	if _, err := svc.SaveRule(ctx, domain.AlertRule{
+		UserID: 1, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelWebhook,
+		Target: "https://example.com/hook", Enabled: true,
+	}); err != nil {
+		t.Fatalf("SaveRule failed: %v", err)
+	}
+
+	n, err := svc.Evaluate(ctx, 1, now)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if n == nil || len(notifier.sent) != 1 {
+		t.Fatalf("expected one notification, got %v", notifier.sent)
+	}
+	if n.Target != "https://example.com/hook" || n.Title != "Rapid weight loss" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+
+	// A second evaluation within the cooldown stays quiet.
+	if n, _ := svc.Evaluate(ctx, 1, now.Add(time.Hour)); n != nil {
+		t.Errorf("expected no notification during cooldown, got %+v", n)
+	}
+	if n, _ := svc.Evaluate(ctx, 1, now.AddDate(0, 0, 8)); n == nil {
+		t.Error("expected a notification after the cooldown")
+	}
+}
+
+func TestAlertService_BelowThreshold(t *testing.T) {
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	svc, rules, notifier := newAlertFixture(now, 99)
+	rules.rules[1] = domain.AlertRule{UserID: 1, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelWebhook, Enabled: true}
+	rules.rules[2] = domain.AlertRule{UserID: 2, MaxWeeklyChangePct: 0.5, Channel: domain.AlertChannelWebhook}
+
+	sent, err := svc.EvaluateAll(ctx, now)
+	if err != nil {
+		t.Fatalf("EvaluateAll failed: %v", err)
+	}
+	if sent != 0 || len(notifier.sent) != 0 {
+		t.Fatalf("expected no notifications, got %d", sent)
+	}
+}
+
+func TestAlertService_SaveRuleValidation(t *testing.T) {
+	svc, _, _ := newAlertFixture(time.Now(), 100)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		rule domain.AlertRule
+	}{
+		{"zero threshold", domain.AlertRule{MaxWeeklyChangePct: 0, Channel: domain.AlertChannelWebhook, Target: "https://example.com"}},
+		{"threshold too high", domain.AlertRule{MaxWeeklyChangePct: 50, Channel: domain.AlertChannelWebhook, Target: "https://example.com"}},
+		{"unavailable channel", domain.AlertRule{MaxWeeklyChangePct: 2, Channel: domain.AlertChannelMQTT}},
+		{"bad webhook url", domain.AlertRule{MaxWeeklyChangePct: 2, Channel: domain.AlertChannelWebhook, Target: "ftp://example.com"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.UserID = 1
+			if _, err := svc.SaveRule(ctx, tc.rule); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
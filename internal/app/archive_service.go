@@ -0,0 +1,297 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrArchiveNotFound indicates that an export archive does not exist, has
+// expired or belongs to another user.
+var ErrArchiveNotFound = errors.New("export archive not found")
+
+// ErrArchiveNotReady indicates that an export archive is still being built
+// or failed, so there is nothing to download.
+var ErrArchiveNotReady = errors.New("export archive is not ready")
+
+// NotificationKindExportReady marks the notification sent when an export
+// archive has finished, successfully or not.
+const NotificationKindExportReady = "export.ready"
+
+const (
+	// archiveTTL is how long finished archives remain downloadable.
+	archiveTTL = 24 * time.Hour
+	// archiveBuildTimeout bounds building one archive; the job has no
+	// request deadline.
+	archiveBuildTimeout = 10 * time.Minute
+	// archiveNotifyTimeout bounds delivering the ready notification.
+	archiveNotifyTimeout = 30 * time.Second
+)
+
+// ArchiveJob reports the state of a background export archive.
+type ArchiveJob struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"userId"`
+	Status string `json:"status"`
+	// Size is the archive's length in bytes once it has been built.
+	Size       int        `json:"size,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Filter is the slice of history the archive holds; nil for all of it.
+	Filter *domain.ExportFilter `json:"filter,omitempty"`
+}
+
+// Done reports whether the archive has finished building.
+func (j ArchiveJob) Done() bool {
+	return j.Status != JobRunning
+}
+
+// ArchiveService builds ZIP archives of a user's full history in the
+// background and keeps them for download for archiveTTL. Archives are held
+// in memory by the instance that built them and do not survive a restart.
+type ArchiveService struct {
+	weight   domain.WeightRepository
+	water    domain.WaterRepository
+	config   *ConfigService
+	alerts   *AlertService
+	linkBase string
+
+	mu   sync.Mutex
+	jobs map[string]*archiveJob
+}
+
+type archiveJob struct {
+	ArchiveJob
+	data []byte
+}
+
+// NewArchiveService creates an ArchiveService that exports the given
+// repositories' events.
+func NewArchiveService(weight domain.WeightRepository, water domain.WaterRepository) *ArchiveService {
+	return &ArchiveService{weight: weight, water: water, jobs: make(map[string]*archiveJob)}
+}
+
+// WithConfig adds the user's configuration bundle to archives.
+func (s *ArchiveService) WithConfig(c *ConfigService) *ArchiveService {
+	s.config = c
+	return s
+}
+
+// WithNotifications tells the user when their archive is ready, over the
+// channel of their alert rule, with a download link under linkBase, the
+// public URL of the API (e.g. "https://vitals.example.com/api/v1").
+func (s *ArchiveService) WithNotifications(alerts *AlertService, linkBase string) *ArchiveService {
+	s.alerts = alerts
+	s.linkBase = linkBase
+	return s
+}
+
+// Start begins building an archive of the history f selects for userID in
+// the background and returns the new job immediately. While one is already
+// being built for the user, that job is returned instead.
+func (s *ArchiveService) Start(ctx context.Context, userID int64, f domain.ExportFilter) (ArchiveJob, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ArchiveJob{}, err
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	for _, job := range s.jobs {
+		if job.UserID == userID && !job.Done() {
+			s.mu.Unlock()
+			return job.ArchiveJob, nil
+		}
+	}
+	job := &archiveJob{ArchiveJob: ArchiveJob{
+		ID:        hex.EncodeToString(b),
+		UserID:    userID,
+		Status:    JobRunning,
+		CreatedAt: time.Now(),
+	}}
+	if !f.IsZero() {
+		job.Filter = &f
+	}
+	s.jobs[job.ID] = job
+	snapshot := job.ArchiveJob
+	s.mu.Unlock()
+
+	go s.run(job)
+	return snapshot, nil
+}
+
+// Get returns the current state of one of userID's archives.
+func (s *ArchiveService) Get(userID int64, id string) (ArchiveJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return ArchiveJob{}, ErrArchiveNotFound
+	}
+	return job.ArchiveJob, nil
+}
+
+// Download returns one of userID's finished archives along with its job.
+func (s *ArchiveService) Download(userID int64, id string) (ArchiveJob, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return ArchiveJob{}, nil, ErrArchiveNotFound
+	}
+	if job.Status != JobSucceeded {
+		return job.ArchiveJob, nil, ErrArchiveNotReady
+	}
+	return job.ArchiveJob, job.data, nil
+}
+
+func (s *ArchiveService) run(job *archiveJob) {
+	// The job outlives the request that started it.
+	ctx, cancel := context.WithTimeout(context.Background(), archiveBuildTimeout)
+	defer cancel()
+
+	var f domain.ExportFilter
+	if job.Filter != nil {
+		f = *job.Filter
+	}
+	data, err := s.build(ctx, job.UserID, f)
+
+	s.mu.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobSucceeded
+		job.Size = len(data)
+		job.data = data
+	}
+	snapshot := job.ArchiveJob
+	s.mu.Unlock()
+
+	s.notify(snapshot)
+}
+
+// build writes the user's data selected by f into a ZIP archive: one JSON
+// file per metric, each an array of events oldest first, and, for a full
+// archive, their configuration.
+func (s *ArchiveService) build(ctx context.Context, userID int64, f domain.ExportFilter) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	if f.Includes(domain.ExportMetricWeight) {
+		err := writeArchiveArray(zw, "weight.json", func(add func(any) error) error {
+			return exportRange(f, func(e domain.WeightEntry) time.Time { return e.CreatedAt }, func(e domain.WeightEntry) error { return add(e) },
+				func(fn func(domain.WeightEntry) error) error { return s.weight.EachWeightEvent(ctx, userID, fn) })
+		})
+		if err != nil {
+			return nil, fmt.Errorf("weight: %w", err)
+		}
+	}
+	if f.Includes(domain.ExportMetricWater) {
+		err := writeArchiveArray(zw, "water.json", func(add func(any) error) error {
+			return exportRange(f, func(e domain.WaterEvent) time.Time { return e.CreatedAt }, func(e domain.WaterEvent) error { return add(e) },
+				func(fn func(domain.WaterEvent) error) error { return s.water.EachWaterEvent(ctx, userID, fn) })
+		})
+		if err != nil {
+			return nil, fmt.Errorf("water: %w", err)
+		}
+	}
+	if s.config != nil && f.IsZero() {
+		bundle, err := s.config.Export(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		f, err := zw.Create("config.json")
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(bundle); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeArchiveArray adds a file holding a JSON array of the items each
+// passes to add, one per line.
+func writeArchiveArray(zw *zip.Writer, name string, each func(add func(any) error) error) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "["); err != nil {
+		return err
+	}
+	sep := "\n"
+	err = each(func(v any) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, sep); err != nil {
+			return err
+		}
+		sep = ",\n"
+		_, err = f.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, "\n]\n")
+	return err
+}
+
+// notify tells the user their archive has finished. Delivery is best
+// effort: the job's status can always be polled instead.
+func (s *ArchiveService) notify(job ArchiveJob) {
+	if s.alerts == nil {
+		return
+	}
+	n := domain.Notification{
+		UserID:  job.UserID,
+		Kind:    NotificationKindExportReady,
+		Title:   "Your export is ready",
+		Message: fmt.Sprintf("Download it within %d hours: %s/export/archive/%s/download", int(archiveTTL.Hours()), s.linkBase, job.ID),
+		At:      time.Now(),
+	}
+	if job.Status != JobSucceeded {
+		n.Title = "Your export failed"
+		n.Message = "The archive could not be built: " + job.Error
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), archiveNotifyTimeout)
+	defer cancel()
+	if _, err := s.alerts.Deliver(ctx, n); err != nil {
+		log.Printf("archives: notify user %d of %s: %v", job.UserID, job.ID, err)
+	}
+}
+
+// pruneLocked forgets finished archives past their TTL. Callers hold s.mu.
+func (s *ArchiveService) pruneLocked() {
+	cutoff := time.Now().Add(-archiveTTL)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
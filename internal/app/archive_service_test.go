@@ -0,0 +1,165 @@
+package app_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// chanNotifier passes notifications to the test goroutine.
+type chanNotifier chan domain.Notification
+
+func (c chanNotifier) Notify(_ context.Context, n domain.Notification) error {
+	c <- n
+	return nil
+}
+
+func waitForNotification(t *testing.T, c chanNotifier) domain.Notification {
+	t.Helper()
+	select {
+	case n := <-c:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the notification")
+		return domain.Notification{}
+	}
+}
+
+func TestArchiveService(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	weights := &mockWeightRepo{eachFn: func(_ context.Context, _ int64, fn func(domain.WeightEntry) error) error {
+		for i := 0; i < 3; i++ {
+			if err := fn(domain.WeightEntry{ID: int64(i + 1), Value: 80, Unit: "kg", CreatedAt: at}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	rules := &mockAlertRuleRepo{rules: map[int64]domain.AlertRule{
+		1: {UserID: 1, Channel: domain.AlertChannelWebhook, Target: "https://example.com/hook"},
+	}}
+	notifier := make(chanNotifier, 1)
+	alerts := app.NewAlertService(rules, weights).WithNotifier(domain.AlertChannelWebhook, notifier)
+	svc := app.NewArchiveService(weights, &mockWaterRepo{}).
+		WithNotifications(alerts, "https://vitals.example.com/api")
+
+	job, err := svc.Start(ctx, 1, domain.ExportFilter{})
+	if err != nil || job.Status != app.JobRunning {
+		t.Fatalf("Start = %+v, %v", job, err)
+	}
+
+	n := waitForNotification(t, notifier)
+	if n.Kind != app.NotificationKindExportReady || n.Target != "https://example.com/hook" ||
+		!strings.Contains(n.Message, "https://vitals.example.com/api/export/archive/"+job.ID+"/download") {
+		t.Errorf("expected a ready notification with the download link, got %+v", n)
+	}
+
+	if _, err := svc.Get(2, job.ID); !errors.Is(err, app.ErrArchiveNotFound) {
+		t.Errorf("expected another user's archive to be hidden, got %v", err)
+	}
+	done, data, err := svc.Download(1, job.ID)
+	if err != nil || done.Status != app.JobSucceeded || done.Size != len(data) {
+		t.Fatalf("Download = %+v, %d bytes, %v", done, len(data), err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		_ = rc.Close()
+	}
+	var ws []domain.WeightEntry
+	if err := json.Unmarshal(files["weight.json"], &ws); err != nil || len(ws) != 3 {
+		t.Errorf("expected all 3 weigh-ins in weight.json, got %s, %v", files["weight.json"], err)
+	}
+	var water []domain.WaterEvent
+	if err := json.Unmarshal(files["water.json"], &water); err != nil || len(water) != 0 {
+		t.Errorf("expected an empty water.json array, got %s, %v", files["water.json"], err)
+	}
+}
+
+func TestArchiveService_Failure(t *testing.T) {
+	water := &mockWaterRepo{eachFn: func(context.Context, int64, func(domain.WaterEvent) error) error {
+		return errors.New("connection reset")
+	}}
+	rules := &mockAlertRuleRepo{rules: map[int64]domain.AlertRule{1: {UserID: 1, Channel: domain.AlertChannelWebhook}}}
+	notifier := make(chanNotifier, 1)
+	alerts := app.NewAlertService(rules, &mockWeightRepo{}).WithNotifier(domain.AlertChannelWebhook, notifier)
+	svc := app.NewArchiveService(&mockWeightRepo{}, water).WithNotifications(alerts, "")
+
+	job, _ := svc.Start(context.Background(), 1, domain.ExportFilter{})
+	if n := waitForNotification(t, notifier); n.Title != "Your export failed" {
+		t.Errorf("expected a failure notification, got %+v", n)
+	}
+	if _, _, err := svc.Download(1, job.ID); !errors.Is(err, app.ErrArchiveNotReady) {
+		t.Errorf("expected ErrArchiveNotReady, got %v", err)
+	}
+	if got, _ := svc.Get(1, job.ID); got.Status != app.JobFailed || !strings.Contains(got.Error, "connection reset") {
+		t.Errorf("expected the job failed with the cause, got %+v", got)
+	}
+}
+
+func TestArchiveService_Filter(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	visited := 0
+	weights := &mockWeightRepo{eachFn: func(_ context.Context, _ int64, fn func(domain.WeightEntry) error) error {
+		for i := 0; i < 10; i++ {
+			visited++
+			if err := fn(domain.WeightEntry{ID: int64(i + 1), Value: 80, Unit: "kg", CreatedAt: start.AddDate(0, 0, i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	water := &mockWaterRepo{eachFn: func(context.Context, int64, func(domain.WaterEvent) error) error {
+		t.Error("expected water not to be read")
+		return nil
+	}}
+	rules := &mockAlertRuleRepo{rules: map[int64]domain.AlertRule{1: {UserID: 1, Channel: domain.AlertChannelWebhook}}}
+	notifier := make(chanNotifier, 1)
+	alerts := app.NewAlertService(rules, weights).WithNotifier(domain.AlertChannelWebhook, notifier)
+	svc := app.NewArchiveService(weights, water).WithNotifications(alerts, "")
+
+	filter := domain.ExportFilter{From: "2026-03-03", To: "2026-03-05", Metrics: []string{domain.ExportMetricWeight}}
+	job, err := svc.Start(ctx, 1, filter)
+	if err != nil || job.Filter == nil || job.Filter.From != "2026-03-03" {
+		t.Fatalf("Start = %+v, %v", job, err)
+	}
+	waitForNotification(t, notifier)
+	_, data, err := svc.Download(1, job.ID)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != "weight.json" {
+		t.Fatalf("expected only weight.json, got %+v, %v", zr.File, err)
+	}
+	rc, _ := zr.File[0].Open()
+	defer rc.Close() //nolint:errcheck
+	var ws []domain.WeightEntry
+	if err := json.NewDecoder(rc).Decode(&ws); err != nil || len(ws) != 3 || ws[0].ID != 3 {
+		t.Errorf("expected the weigh-ins of 3 to 5 March, got %+v, %v", ws, err)
+	}
+	if visited != 6 {
+		t.Errorf("expected the export to stop after the last day, visited %d events", visited)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"vitals/internal/domain"
@@ -23,12 +27,56 @@ var (
 	ErrSessionExpired = errors.New("session expired")
 	// ErrUserNotFound indicates that the user does not exist.
 	ErrUserNotFound = errors.New("user not found")
+	// ErrLinkRequired indicates that an SSO identity matches no account and
+	// the user must link it to one or choose to create a new one.
+	ErrLinkRequired = errors.New("sso identity is not linked to an account")
+	// ErrLinkExpired indicates that a pending SSO link is unknown or expired.
+	ErrLinkExpired = errors.New("sso link expired; sign in with SSO again")
+	// ErrOwnRole indicates an admin trying to change their own role.
+	ErrOwnRole = errors.New("cannot change your own role")
+	// ErrAdminDelete indicates an admin trying to delete their own account.
+	ErrAdminDelete = errors.New("admins cannot delete their account; another admin must take away the admin role first")
+)
+
+const (
+	// pendingLinkTTL bounds how long an unlinked SSO identity waits for the
+	// user to link it.
+	pendingLinkTTL = 10 * time.Minute
+	// sessionTouchInterval is how stale a session's last-seen time may get
+	// before a request refreshes it, so not every request writes.
+	sessionTouchInterval = 5 * time.Minute
+	// topicPendingLink carries pending SSO links between instances.
+	topicPendingLink = "auth.link"
 )
 
 // AuthService handles authentication and session management.
 type AuthService struct {
-	users    domain.UserRepository
-	sessions domain.SessionRepository
+	users       domain.UserRepository
+	sessions    domain.SessionRepository
+	identities  domain.IdentityRepository
+	accounts    domain.AccountRepository
+	tokens      domain.APITokenRepository
+	revocations domain.RevocationRepository
+	cluster     domain.Cluster
+	recovery    domain.RecoveryRepository
+	throttle    *loginThrottle
+
+	mu sync.Mutex
+	// pending is keyed by the hash of the link token.
+	pending map[string]pendingLink
+}
+
+// pendingLink is an SSO identity awaiting the user's linking decision.
+type pendingLink struct {
+	Identity  domain.LinkedIdentity `json:"identity"`
+	ExpiresAt time.Time             `json:"expiresAt"`
+}
+
+// pendingLinkMessage shares a pending link, or its resolution, with the
+// other instances.
+type pendingLinkMessage struct {
+	TokenHash string       `json:"tokenHash"`
+	Link      *pendingLink `json:"link,omitempty"`
 }
 
 // NewAuthService creates a new authentication service.
@@ -36,27 +84,89 @@ func NewAuthService(users domain.UserRepository, sessions domain.SessionReposito
 	return &AuthService{
 		users:    users,
 		sessions: sessions,
+		throttle: newLoginThrottle(),
+		pending:  map[string]pendingLink{},
 	}
 }
 
+// WithIdentities remembers which account each SSO identity belongs to. An
+// SSO login that matches neither a linked identity nor a username then asks
+// the user to link an existing account instead of creating a new one.
+func (s *AuthService) WithIdentities(repo domain.IdentityRepository) *AuthService {
+	s.identities = repo
+	return s
+}
+
+// WithCluster shares pending SSO links with the other instances in c, so
+// the provider's callback and the link page may be served by different
+// instances.
+func (s *AuthService) WithCluster(c domain.Cluster) *AuthService {
+	s.cluster = c
+	c.Subscribe(topicPendingLink, s.applyPendingLink)
+	return s
+}
+
+// WithAccounts lets SSO logins match an account by its verified email.
+func (s *AuthService) WithAccounts(repo domain.AccountRepository) *AuthService {
+	s.accounts = repo
+	return s
+}
+
+// WithRevocations makes RevokeAll move the user's revocation epoch on
+// too, revoking grants such as embed links.
+func (s *AuthService) WithRevocations(repo domain.RevocationRepository) *AuthService {
+	s.revocations = repo
+	return s
+}
+
+// WithAPITokens makes RevokeAll revoke the user's API tokens along with
+// their sessions.
+func (s *AuthService) WithAPITokens(repo domain.APITokenRepository) *AuthService {
+	s.tokens = repo
+	return s
+}
+
 // Login authenticates a user and creates a session.
 func (s *AuthService) Login(ctx context.Context, username, password, userAgent, ip string) (string, error) {
+	return s.LoginWithChallenge(ctx, username, password, "", userAgent, ip)
+}
+
+// LoginWithChallenge is Login with the client's solution to the challenge
+// its address must solve after repeated failures; see WithLoginChallenge.
+// Failures from ip count towards its sign-in limits until its window ends;
+// a successful sign-in does not clear them, or anyone with an account of
+// their own could reset the count between guesses at someone else's.
+func (s *AuthService) LoginWithChallenge(ctx context.Context, username, password, solution, userAgent, ip string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AuthService.Login")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.throttle.admit(ctx, ip, solution); err != nil {
+		return "", err
+	}
+
 	user, err := s.users.GetByUsername(ctx, username)
 	if err != nil || user == nil {
+		s.throttle.fail(ip)
 		return "", ErrInvalidCredentials
 	}
 
 	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		s.throttle.fail(ip)
 		return "", ErrInvalidCredentials
 	}
 
+	return s.startSession(ctx, user.ID, userAgent, ip)
+}
+
+// startSession creates a 24-hour session for userID and returns its token.
+func (s *AuthService) startSession(ctx context.Context, userID int64, userAgent, ip string) (string, error) {
 	token, err := generateToken()
 	if err != nil {
 		return "", err
 	}
 
 	expiresAt := time.Now().Add(24 * time.Hour)
-	if err := s.sessions.Create(ctx, user.ID, token, userAgent, ip, expiresAt); err != nil {
+	if err := s.sessions.Create(ctx, userID, token, userAgent, ip, expiresAt); err != nil {
 		return "", err
 	}
 
@@ -69,7 +179,10 @@ func (s *AuthService) Logout(ctx context.Context, token string) error {
 }
 
 // ValidateSession checks if a session token is valid and matches the user agent.
-func (s *AuthService) ValidateSession(ctx context.Context, token, userAgent string) (*domain.User, error) {
+func (s *AuthService) ValidateSession(ctx context.Context, token, userAgent string) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "AuthService.ValidateSession")
+	defer func() { endSpan(span, err) }()
+
 	session, err := s.sessions.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, ErrSessionNotFound
@@ -85,6 +198,10 @@ func (s *AuthService) ValidateSession(ctx context.Context, token, userAgent stri
 		return nil, ErrSessionExpired
 	}
 
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		_ = s.sessions.Touch(ctx, token, now)
+	}
+
 	user, err := s.users.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, ErrUserNotFound
@@ -93,6 +210,230 @@ func (s *AuthService) ValidateSession(ctx context.Context, token, userAgent stri
 	return user, nil
 }
 
+// DeviceSession is a session as listed to its user, without its token.
+type DeviceSession struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+}
+
+// Sessions lists the user's unexpired sessions, most recently seen first.
+// currentToken, if any, marks the caller's own session. Read-only callers
+// such as guests share the account, so they may not see its sessions.
+func (s *AuthService) Sessions(ctx context.Context, userID int64, currentToken string) ([]DeviceSession, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]DeviceSession, 0, len(sessions))
+	for _, sess := range sessions {
+		if now.After(sess.ExpiresAt) {
+			continue
+		}
+		out = append(out, DeviceSession{
+			ID:         sess.ID,
+			Name:       sess.Name,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    currentToken != "" && ConstantTimeCompare(sess.Token, currentToken),
+		})
+	}
+	return out, nil
+}
+
+// RenameSession names the device behind one of the user's sessions; an
+// empty name clears it.
+func (s *AuthService) RenameSession(ctx context.Context, userID, id int64, name string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if len(name) > domain.MaxSessionNameLength {
+		return fmt.Errorf("name must be at most %d characters", domain.MaxSessionNameLength)
+	}
+	ok, err := s.sessions.Rename(ctx, userID, id, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSession signs out one of the user's sessions.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.sessions.DeleteByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAll signs the user out everywhere: it deletes all their sessions
+// and, with API tokens and revocations configured, all their tokens and
+// embed links. It runs when the user's password or role changes, so
+// nothing issued under the old credentials outlives them.
+func (s *AuthService) RevokeAll(ctx context.Context, userID int64) (err error) {
+	ctx, span := startSpan(ctx, "AuthService.RevokeAll", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.sessions.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if s.tokens != nil {
+		if _, err := s.tokens.DeleteAPITokens(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if s.revocations != nil {
+		return s.revocations.BumpRevocationEpoch(ctx, userID)
+	}
+	return nil
+}
+
+// ChangePassword sets the user's password to password after checking
+// current, their present one, and signs them out everywhere else: it
+// revokes all their sessions and tokens and returns a new session for the
+// caller. Wrong current passwords count towards ip's sign-in limits like
+// failed logins, and fail with ErrInvalidCredentials.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, current, password, solution, userAgent, ip string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AuthService.ChangePassword", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkWritable(ctx); err != nil {
+		return "", err
+	}
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	if err := s.throttle.admit(ctx, ip, solution); err != nil {
+		return "", err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrUserNotFound
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		s.throttle.fail(ip)
+		return "", ErrInvalidCredentials
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	if err := s.users.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return "", err
+	}
+	if err := s.RevokeAll(ctx, userID); err != nil {
+		return "", err
+	}
+	return s.startSession(ctx, userID, userAgent, ip)
+}
+
+// DeleteAccount erases the user after checking password, their present
+// one: their sessions are revoked, then the account is deleted with its
+// profiles and all their data at once. Wrong passwords count towards ip's
+// sign-in limits like failed logins, and fail with ErrInvalidCredentials.
+// Admins cannot delete their own account, so an instance is not left
+// without one by accident. Accounts without a password, such as SSO-only
+// ones, must set one with a recovery code first.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID int64, password, solution, ip string) (err error) {
+	ctx, span := startSpan(ctx, "AuthService.DeleteAccount", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.throttle.admit(ctx, ip, solution); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		s.throttle.fail(ip)
+		return ErrInvalidCredentials
+	}
+	// Only a caller who proved the password learns the account's role.
+	if user.IsAdmin() {
+		return ErrAdminDelete
+	}
+
+	// Sessions may live outside the database, so they go first: a failure
+	// after them leaves the account signed out rather than sessions
+	// pointing at no account.
+	if _, err := s.sessions.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.users.DeleteUser(ctx, userID)
+}
+
+// SetRole sets the named user's role to domain.RoleUser or
+// domain.RoleAdmin on behalf of the admin actorID. Taking admin away
+// revokes all the user's sessions and tokens, so they hold no admin
+// sessions afterwards. Admins cannot change their own role, so an
+// instance is not left without one by accident.
+func (s *AuthService) SetRole(ctx context.Context, actorID int64, username, role string) (*domain.User, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if s.accounts == nil {
+		return nil, errors.New("roles are not supported by this store")
+	}
+	if role != domain.RoleUser && role != domain.RoleAdmin {
+		return nil, fmt.Errorf("role must be %q or %q", domain.RoleUser, domain.RoleAdmin)
+	}
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.ID == actorID {
+		return nil, ErrOwnRole
+	}
+	demoted := user.IsAdmin() && role != domain.RoleAdmin
+	if err := s.accounts.SetRole(ctx, user.ID, role); err != nil {
+		return nil, err
+	}
+	if demoted {
+		if err := s.RevokeAll(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	user.Role = role
+	return user, nil
+}
+
 // CreateInitialUser creates the first user if no users exist.
 func (s *AuthService) CreateInitialUser(ctx context.Context, username, password string) error {
 	count, err := s.users.Count(ctx)
@@ -109,58 +450,293 @@ func (s *AuthService) CreateInitialUser(ctx context.Context, username, password
 		return err
 	}
 
-	_, err = s.users.Create(ctx, username, string(hash))
-	return err
+	user, err := s.users.Create(ctx, username, string(hash))
+	if err != nil {
+		return err
+	}
+	return s.promoteFirstUser(ctx, user)
+}
+
+// promoteFirstUser makes user, just created, an admin if the instance has
+// none yet, so whoever sets up an instance can administer it. The check
+// and the promotion happen together in the repository, so racing first
+// sign-ups cannot all see another account and leave nobody in charge.
+// Without an account repository roles cannot be stored and nothing
+// changes.
+func (s *AuthService) promoteFirstUser(ctx context.Context, user *domain.User) error {
+	if s.accounts == nil {
+		return nil
+	}
+	claimed, err := s.accounts.ClaimAdmin(ctx, user.ID)
+	if err != nil || !claimed {
+		return err
+	}
+	user.Role = domain.RoleAdmin
+	return nil
 }
 
 // ValidateForwardAuth validates a request from Authelia forward auth.
-// It checks for the Remote-User header set by Authelia.
+// It checks for the Remote-User header set by Authelia. With identities
+// tracked, the first sign-in links the remote user to the account of the
+// same username (created if missing), and later ones follow the link, so
+// renaming the account does not detach it from the proxy's user.
 func (s *AuthService) ValidateForwardAuth(ctx context.Context, remoteUser string) (*domain.User, error) {
 	if remoteUser == "" {
 		return nil, errors.New("no remote user header")
 	}
 
-	user, err := s.users.GetByUsername(ctx, remoteUser)
-	if err != nil {
-		// Auto-create user from SSO if they don't exist
-		user, err = s.users.Create(ctx, remoteUser, "")
+	if s.identities != nil {
+		linked, err := s.identities.GetLinkedIdentity(ctx, domain.ForwardAuthIssuer, remoteUser)
 		if err != nil {
 			return nil, err
 		}
+		if linked != nil {
+			user, err := s.users.GetByID(ctx, linked.UserID)
+			if err == nil && user == nil {
+				err = ErrUserNotFound
+			}
+			return user, err
+		}
 	}
 
+	user, err := s.users.GetByUsername(ctx, remoteUser)
+	if err != nil || user == nil {
+		// Auto-create user from SSO if they don't exist. The proxy vouches
+		// for the username, so an account a concurrent request created
+		// first is theirs too.
+		user, err = s.createSSOUser(ctx, remoteUser)
+		if errors.Is(err, ErrUsernameTaken) {
+			user, err = s.users.GetByUsername(ctx, remoteUser)
+			if err == nil && user == nil {
+				err = ErrUserNotFound
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	id := domain.LinkedIdentity{Issuer: domain.ForwardAuthIssuer, Subject: remoteUser}
+	if err := s.link(ctx, user.ID, id); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
-// LoginWithUser creates a session for an already authenticated user (e.g. via SSO).
-func (s *AuthService) LoginWithUser(ctx context.Context, username, userAgent, ip string) (string, error) {
+// GuestUser returns the account used for unauthenticated read-only access.
+func (s *AuthService) GuestUser(ctx context.Context, username string) (*domain.User, error) {
 	user, err := s.users.GetByUsername(ctx, username)
-	if err != nil {
-		// Auto-provision if missing. Use empty password hash as they login via SSO.
-		// Or random password.
-		user, err = s.users.Create(ctx, username, "")
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// LoginWithIdentity creates a session for an identity verified by the SSO
+// provider. The identity's account is, in order: the account it was linked
+// to, the account that verified its email, the account whose username is
+// its email, or, without an identity repository, a newly created one.
+// Otherwise it returns ErrLinkRequired along with a pending link token for
+// CompleteLink or CreateLinkedAccount. An email the provider has not
+// verified matches no account, and neither does the subject, so nobody
+// signs in to an account by claiming its address or naming a subject
+// after it.
+func (s *AuthService) LoginWithIdentity(ctx context.Context, id domain.LinkedIdentity, userAgent, ip string) (string, error) {
+	if s.identities != nil {
+		linked, err := s.identities.GetLinkedIdentity(ctx, id.Issuer, id.Subject)
 		if err != nil {
-			// Try getting again if creation failed due to race (e.g. unique constraint)
-			user, err = s.users.GetByUsername(ctx, username)
+			return "", err
+		}
+		if linked != nil {
+			return s.startSession(ctx, linked.UserID, userAgent, ip)
+		}
+	}
+
+	user, err := s.userByEmail(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	username := identityUsername(id)
+	if user == nil && id.Email != "" && id.EmailVerified {
+		if user, err = s.users.GetByUsername(ctx, username); err != nil {
+			return "", err
+		}
+	}
+	if user == nil {
+		if s.identities != nil {
+			token, err := s.holdPendingLink(ctx, id)
 			if err != nil {
 				return "", err
 			}
+			return token, ErrLinkRequired
+		}
+		if user, err = s.createSSOUser(ctx, username); err != nil {
+			return "", err
 		}
 	}
+	if err := s.link(ctx, user.ID, id); err != nil {
+		return "", err
+	}
+	return s.startSession(ctx, user.ID, userAgent, ip)
+}
 
-	token, err := generateToken()
+// userByEmail returns the account that verified the email of id, or nil
+// when the provider did not verify it too.
+func (s *AuthService) userByEmail(ctx context.Context, id domain.LinkedIdentity) (*domain.User, error) {
+	if s.accounts == nil || id.Email == "" || !id.EmailVerified {
+		return nil, nil
+	}
+	email, err := domain.NormalizeEmail(id.Email)
+	if err != nil {
+		return nil, nil
+	}
+	return s.accounts.GetByEmail(ctx, email)
+}
+
+// PendingLink returns the SSO identity awaiting a linking decision under
+// token.
+func (s *AuthService) PendingLink(token string) (*domain.LinkedIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[hashToken(token)]
+	if !ok || time.Now().After(p.ExpiresAt) {
+		return nil, ErrLinkExpired
+	}
+	return &p.Identity, nil
+}
+
+// CompleteLink links the pending SSO identity to the local account that
+// username and password sign in to, and starts a session for it.
+func (s *AuthService) CompleteLink(ctx context.Context, token, username, password, userAgent, ip string) (string, error) {
+	id, err := s.PendingLink(token)
 	if err != nil {
 		return "", err
 	}
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil || user == nil || user.PasswordHash == "" {
+		return "", ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return "", ErrInvalidCredentials
+	}
+	return s.finishLink(ctx, token, user.ID, *id, userAgent, ip)
+}
 
-	expiresAt := time.Now().Add(24 * time.Hour)
-	if err := s.sessions.Create(ctx, user.ID, token, userAgent, ip, expiresAt); err != nil {
+// CreateLinkedAccount creates a new account for the pending SSO identity,
+// when the user confirms they have no existing one, and starts a session.
+func (s *AuthService) CreateLinkedAccount(ctx context.Context, token, userAgent, ip string) (string, error) {
+	id, err := s.PendingLink(token)
+	if err != nil {
 		return "", err
 	}
+	user, err := s.createSSOUser(ctx, identityUsername(*id))
+	if err != nil {
+		return "", err
+	}
+	return s.finishLink(ctx, token, user.ID, *id, userAgent, ip)
+}
 
+func (s *AuthService) finishLink(ctx context.Context, token string, userID int64, id domain.LinkedIdentity, userAgent, ip string) (string, error) {
+	if err := s.link(ctx, userID, id); err != nil {
+		return "", err
+	}
+	hash := hashToken(token)
+	s.mu.Lock()
+	delete(s.pending, hash)
+	s.mu.Unlock()
+	s.publishPendingLink(ctx, pendingLinkMessage{TokenHash: hash})
+	return s.startSession(ctx, userID, userAgent, ip)
+}
+
+// link records that id belongs to userID, if identities are tracked.
+func (s *AuthService) link(ctx context.Context, userID int64, id domain.LinkedIdentity) error {
+	if s.identities == nil {
+		return nil
+	}
+	id.UserID = userID
+	id.LinkedAt = time.Now().UTC()
+	return s.identities.LinkIdentity(ctx, id)
+}
+
+// holdPendingLink stores id for a later linking decision and returns the
+// token that refers to it, dropping expired entries on the way.
+func (s *AuthService) holdPendingLink(ctx context.Context, id domain.LinkedIdentity) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	hash := hashToken(token)
+	link := pendingLink{Identity: id, ExpiresAt: time.Now().Add(pendingLinkTTL)}
+	s.mu.Lock()
+	s.pruneLinksLocked()
+	s.pending[hash] = link
+	s.mu.Unlock()
+	s.publishPendingLink(ctx, pendingLinkMessage{TokenHash: hash, Link: &link})
 	return token, nil
 }
 
+// publishPendingLink tells the other instances about msg, best effort: an
+// instance that misses it asks the user to sign in with SSO again.
+func (s *AuthService) publishPendingLink(ctx context.Context, msg pendingLinkMessage) {
+	if s.cluster == nil {
+		return
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	_ = s.cluster.Publish(ctx, topicPendingLink, payload)
+}
+
+// applyPendingLink stores or drops a pending link another instance shared.
+func (s *AuthService) applyPendingLink(payload []byte) {
+	var msg pendingLinkMessage
+	if err := json.Unmarshal(payload, &msg); err != nil || msg.TokenHash == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg.Link == nil {
+		delete(s.pending, msg.TokenHash)
+		return
+	}
+	s.pruneLinksLocked()
+	s.pending[msg.TokenHash] = *msg.Link
+}
+
+// pruneLinksLocked drops expired pending links. Callers hold s.mu.
+func (s *AuthService) pruneLinksLocked() {
+	now := time.Now()
+	for t, p := range s.pending {
+		if now.After(p.ExpiresAt) {
+			delete(s.pending, t)
+		}
+	}
+}
+
+// createSSOUser provisions an account without a password for an SSO user.
+// It fails with ErrUsernameTaken rather than hand out an existing account
+// that has the username.
+func (s *AuthService) createSSOUser(ctx context.Context, username string) (*domain.User, error) {
+	user, err := s.users.Create(ctx, username, "")
+	if err != nil {
+		if existing, gerr := s.users.GetByUsername(ctx, username); gerr == nil && existing != nil {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return user, s.promoteFirstUser(ctx, user)
+}
+
+// identityUsername is the username an SSO identity maps to: its email, or
+// its subject when the provider asserts no verified email.
+func identityUsername(id domain.LinkedIdentity) string {
+	if id.Email != "" && id.EmailVerified {
+		return id.Email
+	}
+	return id.Subject
+}
+
 func generateToken() (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -3,6 +3,9 @@ package app_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,10 +18,12 @@ import (
 const testUserAgent = "test-agent"
 
 type mockUserRepo struct {
-	getByUsernameFn func(ctx context.Context, username string) (*domain.User, error)
-	getByIDFn       func(ctx context.Context, id int64) (*domain.User, error)
-	createFn        func(ctx context.Context, username, passwordHash string) (*domain.User, error)
-	countFn         func(ctx context.Context) (int, error)
+	getByUsernameFn  func(ctx context.Context, username string) (*domain.User, error)
+	getByIDFn        func(ctx context.Context, id int64) (*domain.User, error)
+	createFn         func(ctx context.Context, username, passwordHash string) (*domain.User, error)
+	countFn          func(ctx context.Context) (int, error)
+	updatePasswordFn func(ctx context.Context, userID int64, passwordHash string) error
+	deleteUserFn     func(ctx context.Context, userID int64) error
 }
 
 func (m *mockUserRepo) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
@@ -49,11 +54,30 @@ func (m *mockUserRepo) Count(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	if m.updatePasswordFn != nil {
+		return m.updatePasswordFn(ctx, userID, passwordHash)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) DeleteUser(ctx context.Context, userID int64) error {
+	if m.deleteUserFn != nil {
+		return m.deleteUserFn(ctx, userID)
+	}
+	return nil
+}
+
 type mockSessionRepo struct {
 	createFn        func(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error
 	getByTokenFn    func(ctx context.Context, token string) (*domain.Session, error)
 	deleteFn        func(ctx context.Context, token string) error
 	deleteExpiredFn func(ctx context.Context) error
+	listByUserFn    func(ctx context.Context, userID int64) ([]domain.Session, error)
+	renameFn        func(ctx context.Context, userID, id int64, name string) (bool, error)
+	deleteByIDFn    func(ctx context.Context, userID, id int64) (bool, error)
+	deleteByUserFn  func(ctx context.Context, userID int64) (int, error)
+	touchFn         func(ctx context.Context, token string, at time.Time) error
 }
 
 func (m *mockSessionRepo) Create(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
@@ -84,6 +108,41 @@ func (m *mockSessionRepo) DeleteExpired(ctx context.Context) error {
 	return nil
 }
 
+func (m *mockSessionRepo) ListByUser(ctx context.Context, userID int64) ([]domain.Session, error) {
+	if m.listByUserFn != nil {
+		return m.listByUserFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockSessionRepo) Rename(ctx context.Context, userID, id int64, name string) (bool, error) {
+	if m.renameFn != nil {
+		return m.renameFn(ctx, userID, id, name)
+	}
+	return false, nil
+}
+
+func (m *mockSessionRepo) DeleteByID(ctx context.Context, userID, id int64) (bool, error) {
+	if m.deleteByIDFn != nil {
+		return m.deleteByIDFn(ctx, userID, id)
+	}
+	return false, nil
+}
+
+func (m *mockSessionRepo) DeleteByUser(ctx context.Context, userID int64) (int, error) {
+	if m.deleteByUserFn != nil {
+		return m.deleteByUserFn(ctx, userID)
+	}
+	return 0, nil
+}
+
+func (m *mockSessionRepo) Touch(ctx context.Context, token string, at time.Time) error {
+	if m.touchFn != nil {
+		return m.touchFn(ctx, token, at)
+	}
+	return nil
+}
+
 func TestAuthService_Login_Success(t *testing.T) {
 	ctx := context.Background()
 	password := "testpass123"
@@ -145,6 +204,93 @@ func TestAuthService_Login_InvalidPassword(t *testing.T) {
 	}
 }
 
+type stubChallenge struct{ issued int }
+
+func (c *stubChallenge) New(context.Context) (map[string]any, error) {
+	c.issued++
+	return map[string]any{"kind": "stub"}, nil
+}
+
+func (c *stubChallenge) Verify(_ context.Context, solution, _ string) (bool, error) {
+	return solution == "solved", nil
+}
+
+func TestAuthService_Login_Throttle(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correctpass"), bcrypt.MinCost)
+	users := &mockUserRepo{
+		getByUsernameFn: func(ctx context.Context, username string) (*domain.User, error) {
+			return &domain.User{ID: 1, Username: "testuser", PasswordHash: string(hash)}, nil
+		},
+	}
+	challenge := &stubChallenge{}
+	svc := app.NewAuthService(users, &mockSessionRepo{}).
+		WithLoginLimits(4, time.Minute).
+		WithLoginChallenge(challenge, 2)
+
+	remaining := func(ip string) (int, map[string]any) {
+		t.Helper()
+		st, err := svc.LoginStatus(ctx, ip)
+		if err != nil || st.RemainingAttempts == nil {
+			t.Fatalf("LoginStatus = %+v, %v", st, err)
+		}
+		return *st.RemainingAttempts, st.Challenge
+	}
+
+	for range 2 {
+		if _, err := svc.Login(ctx, "testuser", "wrongpass", testUserAgent, "10.0.0.1:5000"); !errors.Is(err, app.ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+	// Failures count per address, whatever the port.
+	if n, c := remaining("10.0.0.1:6000"); n != 2 || c["kind"] != "stub" {
+		t.Fatalf("remaining = %d, challenge %v; want 2 and a challenge", n, c)
+	}
+	if n, c := remaining("10.0.0.2:5000"); n != 4 || c != nil {
+		t.Fatalf("other address: remaining = %d, challenge %v", n, c)
+	}
+
+	// A challenge is now required; a missing solution does not count, a
+	// wrong one does.
+	if _, err := svc.Login(ctx, "testuser", "correctpass", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrChallengeRequired) {
+		t.Fatalf("expected ErrChallengeRequired, got %v", err)
+	}
+	if _, err := svc.LoginWithChallenge(ctx, "testuser", "correctpass", "wrong", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrChallengeRequired) {
+		t.Fatalf("expected ErrChallengeRequired, got %v", err)
+	}
+	if n, _ := remaining("10.0.0.1"); n != 1 {
+		t.Fatalf("remaining = %d, want 1", n)
+	}
+	if _, err := svc.LoginWithChallenge(ctx, "testuser", "wrongpass", "solved", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	// Locked out: even the right password and solution are refused.
+	st, err := svc.LoginStatus(ctx, "10.0.0.1")
+	if err != nil || *st.RemainingAttempts != 0 || st.RetryAfter <= 0 || st.RetryAfter > time.Minute || st.Challenge != nil {
+		t.Fatalf("LoginStatus = %+v, %v", st, err)
+	}
+	if _, err := svc.LoginWithChallenge(ctx, "testuser", "correctpass", "solved", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrLoginLocked) {
+		t.Fatalf("expected ErrLoginLocked, got %v", err)
+	}
+
+	// Signing in to an account of one's own between guesses at another
+	// does not clear the address's failures.
+	for i := range 4 {
+		if _, err := svc.LoginWithChallenge(ctx, "victim", "wrongpass", "solved", testUserAgent, "10.0.0.3"); !errors.Is(err, app.ErrInvalidCredentials) {
+			t.Fatalf("guess %d: expected ErrInvalidCredentials, got %v", i, err)
+		}
+		if i < 3 {
+			if _, err := svc.LoginWithChallenge(ctx, "mallory", "correctpass", "solved", testUserAgent, "10.0.0.3"); err != nil {
+				t.Fatalf("own login %d: %v", i, err)
+			}
+		}
+	}
+	if _, err := svc.LoginWithChallenge(ctx, "mallory", "correctpass", "solved", testUserAgent, "10.0.0.3"); !errors.Is(err, app.ErrLoginLocked) {
+		t.Errorf("expected the lockout to survive interleaved successful logins, got %v", err)
+	}
+}
+
 func TestAuthService_ValidateSession_Valid(t *testing.T) {
 	ctx := context.Background()
 	token := "validtoken"
@@ -252,3 +398,399 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 		t.Errorf("expected ErrInvalidCredentials, got %v", err)
 	}
 }
+
+type mockIdentityRepo struct {
+	links map[string]domain.LinkedIdentity
+}
+
+func (m *mockIdentityRepo) GetLinkedIdentity(ctx context.Context, issuer, subject string) (*domain.LinkedIdentity, error) {
+	id, ok := m.links[issuer+" "+subject]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+func (m *mockIdentityRepo) LinkIdentity(ctx context.Context, id domain.LinkedIdentity) error {
+	m.links[id.Issuer+" "+id.Subject] = id
+	return nil
+}
+
+func TestAuthService_LoginWithIdentity(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	accounts := map[string]*domain.User{
+		"gjcourt":         {ID: 1, Username: "gjcourt", PasswordHash: string(hash)},
+		"sam@example.com": {ID: 2, Username: "sam@example.com"},
+	}
+	users := &mockUserRepo{
+		getByUsernameFn: func(_ context.Context, username string) (*domain.User, error) {
+			return accounts[username], nil
+		},
+		createFn: func(_ context.Context, username, _ string) (*domain.User, error) {
+			u := &domain.User{ID: int64(len(accounts) + 1), Username: username}
+			accounts[username] = u
+			return u, nil
+		},
+	}
+	var sessionUser int64
+	sessions := &mockSessionRepo{
+		createFn: func(_ context.Context, userID int64, _, _, _ string, _ time.Time) error {
+			sessionUser = userID
+			return nil
+		},
+	}
+	identities := &mockIdentityRepo{links: map[string]domain.LinkedIdentity{}}
+	svc := app.NewAuthService(users, sessions).WithIdentities(identities)
+
+	// An email matching a username logs in and links the identity.
+	sam := domain.LinkedIdentity{Issuer: "https://sso", Subject: "sam-sub", Email: "sam@example.com", EmailVerified: true}
+	if _, err := svc.LoginWithIdentity(ctx, sam, testUserAgent, "127.0.0.1"); err != nil || sessionUser != 2 {
+		t.Fatalf("expected session for user 2, got user %d, err %v", sessionUser, err)
+	}
+	if link := identities.links["https://sso sam-sub"]; link.UserID != 2 {
+		t.Errorf("expected identity linked to user 2, got %+v", link)
+	}
+
+	// A subject without a verified email never matches a username.
+	sessionUser = 0
+	for _, id := range []domain.LinkedIdentity{
+		{Issuer: "https://sso", Subject: "gjcourt"},
+		{Issuer: "https://sso", Subject: "gjcourt-sub", Email: "gjcourt", EmailVerified: false},
+	} {
+		if _, err := svc.LoginWithIdentity(ctx, id, testUserAgent, "127.0.0.1"); !errors.Is(err, app.ErrLinkRequired) || sessionUser != 0 {
+			t.Errorf("%+v: expected ErrLinkRequired, got user %d, err %v", id, sessionUser, err)
+		}
+	}
+
+	// An unmatched email asks to link instead of creating an account.
+	greg := domain.LinkedIdentity{Issuer: "https://sso", Subject: "greg-sub", Email: "greg@example.com", EmailVerified: true}
+	pending, err := svc.LoginWithIdentity(ctx, greg, testUserAgent, "127.0.0.1")
+	if !errors.Is(err, app.ErrLinkRequired) || pending == "" {
+		t.Fatalf("expected ErrLinkRequired with a pending token, got %q, %v", pending, err)
+	}
+	if _, ok := accounts["greg@example.com"]; ok {
+		t.Fatal("expected no account to be created")
+	}
+	if id, err := svc.PendingLink(pending); err != nil || id.Email != "greg@example.com" {
+		t.Fatalf("PendingLink = %+v, %v", id, err)
+	}
+
+	if _, err := svc.CompleteLink(ctx, pending, "gjcourt", "wrong", testUserAgent, "127.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.CompleteLink(ctx, pending, "sam@example.com", "", testUserAgent, "127.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected SSO-only accounts to be refused, got %v", err)
+	}
+	if _, err := svc.CompleteLink(ctx, pending, "gjcourt", "secret", testUserAgent, "127.0.0.1"); err != nil || sessionUser != 1 {
+		t.Fatalf("expected session for user 1, got user %d, err %v", sessionUser, err)
+	}
+	if _, err := svc.CompleteLink(ctx, pending, "gjcourt", "secret", testUserAgent, "127.0.0.1"); !errors.Is(err, app.ErrLinkExpired) {
+		t.Errorf("expected the pending link to be consumed, got %v", err)
+	}
+
+	// Later logins follow the link even though the email matches no username.
+	sessionUser = 0
+	if _, err := svc.LoginWithIdentity(ctx, greg, testUserAgent, "127.0.0.1"); err != nil || sessionUser != 1 {
+		t.Fatalf("expected linked login as user 1, got user %d, err %v", sessionUser, err)
+	}
+
+	// Choosing a new account creates one for the identity.
+	alex := domain.LinkedIdentity{Issuer: "https://sso", Subject: "alex-sub", Email: "alex@example.com", EmailVerified: true}
+	pending, _ = svc.LoginWithIdentity(ctx, alex, testUserAgent, "127.0.0.1")
+	if _, err := svc.CreateLinkedAccount(ctx, pending, testUserAgent, "127.0.0.1"); err != nil {
+		t.Fatalf("CreateLinkedAccount failed: %v", err)
+	}
+	if u := accounts["alex@example.com"]; u == nil || sessionUser != u.ID || identities.links["https://sso alex-sub"].UserID != u.ID {
+		t.Errorf("expected a new linked account with a session, got %+v", u)
+	}
+}
+
+func TestAuthService_LoginWithIdentity_NoIdentityRepo(t *testing.T) {
+	created := ""
+	users := &mockUserRepo{
+		getByUsernameFn: func(_ context.Context, _ string) (*domain.User, error) { return nil, nil },
+		createFn: func(_ context.Context, username, _ string) (*domain.User, error) {
+			created = username
+			return &domain.User{ID: 7, Username: username}, nil
+		},
+	}
+	svc := app.NewAuthService(users, &mockSessionRepo{})
+	if _, err := svc.LoginWithIdentity(context.Background(), domain.LinkedIdentity{Subject: "sub-only"}, testUserAgent, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != "sub-only" {
+		t.Errorf("expected an account named after the subject, got %q", created)
+	}
+}
+
+func TestAuthService_LoginWithIdentity_SubjectCollision(t *testing.T) {
+	users := &mockUserRepo{
+		getByUsernameFn: func(_ context.Context, username string) (*domain.User, error) {
+			if username == "gjcourt" {
+				return &domain.User{ID: 1, Username: username}, nil
+			}
+			return nil, nil
+		},
+		createFn: func(_ context.Context, _, _ string) (*domain.User, error) {
+			return nil, errors.New("duplicate username")
+		},
+	}
+	started := false
+	sessions := &mockSessionRepo{
+		createFn: func(_ context.Context, _ int64, _, _, _ string, _ time.Time) error {
+			started = true
+			return nil
+		},
+	}
+	svc := app.NewAuthService(users, sessions)
+	_, err := svc.LoginWithIdentity(context.Background(), domain.LinkedIdentity{Subject: "gjcourt"}, testUserAgent, "")
+	if !errors.Is(err, app.ErrUsernameTaken) || started {
+		t.Errorf("expected ErrUsernameTaken without a session, got %v (session %v)", err, started)
+	}
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	stored := []domain.Session{
+		{ID: 3, Token: "current", UserID: 1, Name: "iPad kitchen", ExpiresAt: now.Add(time.Hour), LastSeenAt: now},
+		{ID: 2, Token: "other", UserID: 1, ExpiresAt: now.Add(time.Hour), LastSeenAt: now.Add(-time.Hour)},
+		{ID: 1, Token: "old", UserID: 1, ExpiresAt: now.Add(-time.Minute)},
+	}
+	var renamed string
+	var revoked int64
+	sessions := &mockSessionRepo{
+		listByUserFn: func(_ context.Context, userID int64) ([]domain.Session, error) {
+			return stored, nil
+		},
+		renameFn: func(_ context.Context, userID, id int64, name string) (bool, error) {
+			renamed = name
+			return id == 2, nil
+		},
+		deleteByIDFn: func(_ context.Context, userID, id int64) (bool, error) {
+			revoked = id
+			return id == 2, nil
+		},
+	}
+	svc := app.NewAuthService(&mockUserRepo{}, sessions)
+
+	items, err := svc.Sessions(ctx, 1, "current")
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(items) != 2 || !items[0].Current || items[0].Name != "iPad kitchen" || items[1].Current {
+		t.Errorf("expected two live sessions with the first current, got %+v", items)
+	}
+	if _, err := svc.Sessions(app.WithReadOnly(ctx), 1, ""); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected guests to be refused, got %v", err)
+	}
+
+	if err := svc.RenameSession(ctx, 1, 2, "  Phone  "); err != nil || renamed != "Phone" {
+		t.Errorf("expected trimmed rename, got %q, %v", renamed, err)
+	}
+	if err := svc.RenameSession(ctx, 1, 2, strings.Repeat("x", domain.MaxSessionNameLength+1)); err == nil {
+		t.Error("expected a long name to be rejected")
+	}
+	if err := svc.RenameSession(ctx, 1, 9, "x"); !errors.Is(err, app.ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+	if err := svc.RevokeSession(ctx, 1, 2); err != nil || revoked != 2 {
+		t.Errorf("expected session 2 revoked, got %d, %v", revoked, err)
+	}
+	if err := svc.RevokeSession(ctx, 1, 9); !errors.Is(err, app.ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestAuthService_ValidateSession_TouchesLastSeen(t *testing.T) {
+	ctx := context.Background()
+	lastSeen := time.Now()
+	touches := 0
+	sessions := &mockSessionRepo{
+		getByTokenFn: func(_ context.Context, token string) (*domain.Session, error) {
+			return &domain.Session{Token: token, UserID: 1, UserAgent: testUserAgent, ExpiresAt: time.Now().Add(time.Hour), LastSeenAt: lastSeen}, nil
+		},
+		touchFn: func(_ context.Context, _ string, at time.Time) error {
+			touches++
+			lastSeen = at
+			return nil
+		},
+	}
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id int64) (*domain.User, error) {
+			return &domain.User{ID: id}, nil
+		},
+	}
+	svc := app.NewAuthService(users, sessions)
+
+	if _, err := svc.ValidateSession(ctx, "tok", testUserAgent); err != nil || touches != 0 {
+		t.Fatalf("expected a fresh session not to be touched, got %d touches, %v", touches, err)
+	}
+	lastSeen = time.Now().Add(-time.Hour)
+	if _, err := svc.ValidateSession(ctx, "tok", testUserAgent); err != nil || touches != 1 {
+		t.Errorf("expected a stale session to be touched once, got %d touches, %v", touches, err)
+	}
+}
+
+func TestAuthService_PendingLinkAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	users := &mockUserRepo{
+		getByUsernameFn: func(context.Context, string) (*domain.User, error) { return nil, nil },
+	}
+	identities := &mockIdentityRepo{links: map[string]domain.LinkedIdentity{}}
+	hub := &fakeHub{}
+	callback := app.NewAuthService(users, &mockSessionRepo{}).WithIdentities(identities).WithCluster(hub.join())
+	linkPage := app.NewAuthService(users, &mockSessionRepo{}).WithIdentities(identities).WithCluster(hub.join())
+
+	id := domain.LinkedIdentity{Issuer: "https://sso", Subject: "new-sub", Email: "new@example.com"}
+	token, err := callback.LoginWithIdentity(ctx, id, testUserAgent, "127.0.0.1")
+	if !errors.Is(err, app.ErrLinkRequired) {
+		t.Fatalf("expected ErrLinkRequired, got %v", err)
+	}
+	if got, err := linkPage.PendingLink(token); err != nil || got.Subject != "new-sub" {
+		t.Fatalf("expected the other instance to see the pending link, got %+v, %v", got, err)
+	}
+
+	if _, err := linkPage.CreateLinkedAccount(ctx, token, testUserAgent, "127.0.0.1"); err != nil {
+		t.Fatalf("CreateLinkedAccount: %v", err)
+	}
+	if _, err := callback.PendingLink(token); !errors.Is(err, app.ErrLinkExpired) {
+		t.Errorf("expected the resolved link to be dropped everywhere, got %v", err)
+	}
+}
+
+func TestAuthService_SetRole(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockAccountRepo(
+		&domain.User{ID: 1, Username: "root", Role: domain.RoleAdmin},
+		&domain.User{ID: 2, Username: "sam", Role: domain.RoleUser},
+	)
+	var revoked []int64
+	sessions := &mockSessionRepo{
+		deleteByUserFn: func(_ context.Context, userID int64) (int, error) {
+			revoked = append(revoked, userID)
+			return 1, nil
+		},
+	}
+	tokens := &mockTokenRepo{tokens: []domain.APIToken{{ID: 1, UserID: 2}, {ID: 2, UserID: 1}}}
+	svc := app.NewAuthService(repo.userRepo(), sessions).WithAccounts(repo).WithAPITokens(tokens)
+
+	if u, err := svc.SetRole(ctx, 1, "sam", domain.RoleAdmin); err != nil || u.Role != domain.RoleAdmin {
+		t.Fatalf("SetRole = %+v, %v", u, err)
+	}
+	if len(revoked) != 0 || len(tokens.tokens) != 2 {
+		t.Errorf("expected a promotion to keep sessions and tokens, got revocations %v", revoked)
+	}
+
+	if _, err := svc.SetRole(ctx, 1, "sam", domain.RoleUser); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if !slices.Equal(revoked, []int64{2}) || len(tokens.tokens) != 1 || tokens.tokens[0].UserID != 1 {
+		t.Errorf("expected the demotion to revoke sam's sessions and tokens, got revocations %v and tokens %+v", revoked, tokens.tokens)
+	}
+
+	if _, err := svc.SetRole(ctx, 1, "root", domain.RoleUser); !errors.Is(err, app.ErrOwnRole) {
+		t.Errorf("expected ErrOwnRole, got %v", err)
+	}
+	if _, err := svc.SetRole(ctx, 1, "nobody", domain.RoleUser); !errors.Is(err, app.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := svc.SetRole(ctx, 1, "sam", "owner"); err == nil {
+		t.Error("expected an unknown role to fail")
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	user := &domain.User{ID: 2, Username: "sam", PasswordHash: string(hash)}
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id int64) (*domain.User, error) { return user, nil },
+		updatePasswordFn: func(_ context.Context, _ int64, passwordHash string) error {
+			user.PasswordHash = passwordHash
+			return nil
+		},
+	}
+	var revoked []int64
+	var created []string
+	sessions := &mockSessionRepo{
+		deleteByUserFn: func(_ context.Context, userID int64) (int, error) {
+			revoked = append(revoked, userID)
+			return 2, nil
+		},
+		createFn: func(_ context.Context, _ int64, token, _, _ string, _ time.Time) error {
+			created = append(created, token)
+			return nil
+		},
+	}
+	svc := app.NewAuthService(users, sessions)
+
+	if _, err := svc.ChangePassword(ctx, 2, "wrong", "new-password", "", "ua", "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.ChangePassword(ctx, 2, "old-password", "short", "", "ua", "10.0.0.1"); err == nil {
+		t.Error("expected a short password to fail")
+	}
+	if len(revoked) != 0 || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("old-password")) != nil {
+		t.Fatal("expected failed changes to leave the password and sessions alone")
+	}
+
+	token, err := svc.ChangePassword(ctx, 2, "old-password", "new-password", "", "ua", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")) != nil {
+		t.Error("expected the new password to be stored")
+	}
+	if !slices.Equal(revoked, []int64{2}) || !slices.Equal(created, []string{token}) {
+		t.Errorf("expected the other sessions revoked and a new one started, got revocations %v and sessions %v", revoked, created)
+	}
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	accounts := map[int64]*domain.User{
+		1: {ID: 1, Username: "root", PasswordHash: string(hash), Role: domain.RoleAdmin},
+		2: {ID: 2, Username: "sam", PasswordHash: string(hash)},
+	}
+	var calls []string
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id int64) (*domain.User, error) { return accounts[id], nil },
+		deleteUserFn: func(_ context.Context, id int64) error {
+			calls = append(calls, fmt.Sprintf("delete user %d", id))
+			return nil
+		},
+	}
+	sessions := &mockSessionRepo{
+		deleteByUserFn: func(_ context.Context, id int64) (int, error) {
+			calls = append(calls, fmt.Sprintf("revoke sessions %d", id))
+			return 1, nil
+		},
+	}
+	svc := app.NewAuthService(users, sessions)
+
+	if err := svc.DeleteAccount(ctx, 2, "wrong", "", "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := svc.DeleteAccount(ctx, 1, "wrong", "", "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected an admin's wrong password refused before the role, got %v", err)
+	}
+	if err := svc.DeleteAccount(ctx, 1, "password", "", "10.0.0.1"); !errors.Is(err, app.ErrAdminDelete) {
+		t.Errorf("expected ErrAdminDelete, got %v", err)
+	}
+	if err := svc.DeleteAccount(app.WithReadOnly(ctx), 2, "password", "", "10.0.0.1"); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected read-only callers refused, got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected refused deletions to change nothing, got %v", calls)
+	}
+
+	if err := svc.DeleteAccount(ctx, 2, "password", "", "10.0.0.1"); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if want := []string{"revoke sessions 2", "delete user 2"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
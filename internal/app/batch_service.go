@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vitals/internal/domain"
+)
+
+// maxBatchOps caps the number of operations in one batch.
+const maxBatchOps = 500
+
+// BatchService applies queued writes from sync clients in one request.
+type BatchService struct {
+	repo domain.BatchRepository
+}
+
+// NewBatchService creates a BatchService backed by the given repository.
+func NewBatchService(repo domain.BatchRepository) *BatchService {
+	return &BatchService{repo: repo}
+}
+
+// Apply validates every op up front and then applies them atomically, so a
+// bad op rejects the whole batch before anything is written.
+func (s *BatchService) Apply(ctx context.Context, userID int64, ops []domain.BatchOp) ([]domain.BatchResult, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("ops must not be empty")
+	}
+	if len(ops) > maxBatchOps {
+		return nil, fmt.Errorf("at most %d ops per batch", maxBatchOps)
+	}
+	for i := range ops {
+		if err := validateBatchOp(&ops[i]); err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+	}
+	return s.repo.ApplyBatch(ctx, userID, ops)
+}
+
+// validateBatchOp checks op and normalizes its client ID and timestamp.
+func validateBatchOp(op *domain.BatchOp) error {
+	switch op.Op {
+	case domain.BatchOpWeight, domain.BatchOpWater:
+		if op.ClientID != "" {
+			id, err := validateClientWrite(op.ClientID, &op.CreatedAt)
+			if err != nil {
+				return err
+			}
+			op.ClientID = id
+		} else if err := validateWriteTime(&op.CreatedAt); err != nil {
+			return err
+		}
+		if op.Op == domain.BatchOpWeight {
+			return validateWeight(op.Value, op.Unit)
+		}
+		return validateWaterDelta(op.DeltaLiters)
+	case domain.BatchOpDeleteWeight, domain.BatchOpDeleteWater:
+		if op.ID <= 0 {
+			return errors.New("id must be > 0")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown op %q", op.Op)
+}
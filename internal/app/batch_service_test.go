@@ -0,0 +1,86 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockBatchRepo struct {
+	applied [][]domain.BatchOp
+}
+
+func (m *mockBatchRepo) ApplyBatch(ctx context.Context, userID int64, ops []domain.BatchOp) ([]domain.BatchResult, error) {
+	m.applied = append(m.applied, ops)
+	results := make([]domain.BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = domain.BatchResult{Op: op.Op, ID: int64(i + 1), Created: true}
+	}
+	return results, nil
+}
+
+func TestBatchService_Apply(t *testing.T) {
+	repo := &mockBatchRepo{}
+	svc := app.NewBatchService(repo)
+	ctx := context.Background()
+
+	results, err := svc.Apply(ctx, 1, []domain.BatchOp{
+		{Op: domain.BatchOpWeight, Value: 80, Unit: "kg", ClientID: "0B6F3C1E-8A3D-4C5E-9F2A-1D2E3F4A5B6C"},
+		{Op: domain.BatchOpWater, DeltaLiters: 0.5},
+		{Op: domain.BatchOpDeleteWater, ID: 7},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 || len(repo.applied) != 1 {
+		t.Fatalf("expected one batch of 3 results, got %d results in %d batches", len(results), len(repo.applied))
+	}
+	ops := repo.applied[0]
+	if ops[0].ClientID != "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c" {
+		t.Errorf("expected normalized client id, got %q", ops[0].ClientID)
+	}
+	if ops[1].CreatedAt.IsZero() {
+		t.Error("expected missing createdAt to default to now")
+	}
+}
+
+func TestBatchService_RejectsInvalidOps(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []domain.BatchOp
+	}{
+		{"empty", nil},
+		{"unknown op", []domain.BatchOp{{Op: "bogus"}}},
+		{"bad weight", []domain.BatchOp{{Op: domain.BatchOpWeight, Value: 80, Unit: "stones"}}},
+		{"bad water", []domain.BatchOp{{Op: domain.BatchOpWater, DeltaLiters: 0}}},
+		{"bad client id", []domain.BatchOp{{Op: domain.BatchOpWater, DeltaLiters: 1, ClientID: "x"}}},
+		{"delete without id", []domain.BatchOp{{Op: domain.BatchOpDeleteWeight}}},
+		{"one bad op among good", []domain.BatchOp{
+			{Op: domain.BatchOpWater, DeltaLiters: 1},
+			{Op: domain.BatchOpWater, DeltaLiters: 20},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockBatchRepo{}
+			if _, err := app.NewBatchService(repo).Apply(context.Background(), 1, tc.ops); err == nil {
+				t.Fatal("expected error")
+			}
+			if len(repo.applied) != 0 {
+				t.Fatal("expected nothing to be applied")
+			}
+		})
+	}
+}
+
+func TestBatchService_ReadOnly(t *testing.T) {
+	svc := app.NewBatchService(&mockBatchRepo{})
+	ctx := app.WithReadOnly(context.Background())
+	_, err := svc.Apply(ctx, 1, []domain.BatchOp{{Op: domain.BatchOpWater, DeltaLiters: 1}})
+	if !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
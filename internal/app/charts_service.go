@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"vitals/internal/domain"
@@ -12,6 +11,14 @@ import (
 type ChartsService struct {
 	weightRepo domain.WeightRepository
 	waterRepo  domain.WaterRepository
+	tags       domain.TagRepository
+	journal    domain.JournalRepository
+	mood       domain.MoodRepository
+	steps      domain.StepsRepository
+	settings   domain.SettingsRepository
+	goals      domain.GoalHistoryRepository
+	targets    domain.WeightGoalRepository
+	plans      domain.PlanRepository
 }
 
 // NewChartsService creates a ChartsService backed by the given repositories.
@@ -19,42 +26,167 @@ func NewChartsService(wr domain.WeightRepository, wa domain.WaterRepository) *Ch
 	return &ChartsService{weightRepo: wr, waterRepo: wa}
 }
 
+// WithTags enables tag filters on GetDaily.
+func (s *ChartsService) WithTags(repo domain.TagRepository) *ChartsService {
+	s.tags = repo
+	return s
+}
+
+// WithJournal attaches each day's journal note to chart points and calendar
+// days.
+func (s *ChartsService) WithJournal(repo domain.JournalRepository) *ChartsService {
+	s.journal = repo
+	return s
+}
+
+// WithMood adds each day's mood score to chart points as an optional series.
+func (s *ChartsService) WithMood(repo domain.MoodRepository) *ChartsService {
+	s.mood = repo
+	return s
+}
+
+// WithSteps adds each day's step count to chart points as an optional
+// series, so activity can be compared with the weight trend.
+func (s *ChartsService) WithSteps(repo domain.StepsRepository) *ChartsService {
+	s.steps = repo
+	return s
+}
+
+// WithSettings reads each user's default daily weight mode from the
+// charts.dailyWeight setting.
+func (s *ChartsService) WithSettings(repo domain.SettingsRepository) *ChartsService {
+	s.settings = repo
+	return s
+}
+
+// WithGoalHistory judges each chart day against the base water goal in
+// effect that day; without it every day uses the default goal.
+func (s *ChartsService) WithGoalHistory(repo domain.GoalHistoryRepository) *ChartsService {
+	s.goals = repo
+	return s
+}
+
+// WithWeightGoals adds the target weight in effect on each chart day.
+func (s *ChartsService) WithWeightGoals(repo domain.WeightGoalRepository) *ChartsService {
+	s.targets = repo
+	return s
+}
+
+// WithPlans lists each calendar day's planned entries.
+func (s *ChartsService) WithPlans(repo domain.PlanRepository) *ChartsService {
+	s.plans = repo
+	return s
+}
+
 // DayPoint is a single data point returned by GetDaily.
 type DayPoint struct {
 	Day         string       `json:"day"`
 	WaterLiters float64      `json:"waterLiters"`
 	Weight      *WeightPoint `json:"weight"`
+	Note        string       `json:"note,omitempty"`
+	// Mood is the day's mood score (1–5), or nil when none was recorded.
+	Mood *int `json:"mood,omitempty"`
+	// Steps is the day's step count, or nil when none was recorded.
+	Steps *int `json:"steps,omitempty"`
+	// GoalLiters is the base water goal in effect on Day.
+	GoalLiters float64 `json:"goalLiters"`
+	GoalMet    bool    `json:"goalMet"`
+	// WeightGoal is the target weight in effect on Day, in the chart's
+	// unit, or nil when none was set.
+	WeightGoal *float64 `json:"weightGoal,omitempty"`
 }
 
 // WeightPoint is the optional weight value within a DayPoint.
 type WeightPoint struct {
 	Value float64 `json:"value"`
 	Unit  string  `json:"unit"`
+	// Filled marks a value made up for a day without a weigh-in, as the
+	// fill mode asked.
+	Filled bool `json:"filled,omitempty"`
 }
 
+// Fill modes for days without a weigh-in in GetDaily.
+const (
+	// FillNull leaves the day's weight out.
+	FillNull = "null"
+	// FillPrevious repeats the latest earlier weight in the range.
+	FillPrevious = "previous"
+	// FillInterpolate draws a straight line between the weights on either
+	// side; days before the first or after the last weigh-in stay empty.
+	FillInterpolate = "interpolate"
+)
+
 // GetDaily returns per-day chart data for the last days days, with weights
-// converted to the requested unit.
-func (s *ChartsService) GetDaily(ctx context.Context, userID int64, days int, unit string) ([]DayPoint, error) {
+// converted to the requested unit. A non-empty filter computes each day from
+// the matching entries only, e.g. to leave out days tagged "sick". weight
+// selects the daily weight mode; empty uses the user's default. fill sets
+// how days without a weigh-in are represented; empty means FillNull.
+func (s *ChartsService) GetDaily(ctx context.Context, userID int64, days int, unit string, f domain.TagFilter, weight, fill string) (_ []DayPoint, err error) {
+	ctx, span := startSpan(ctx, "ChartsService.GetDaily", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
 	if unit != "kg" && unit != "lb" {
-		return nil, errors.New("unit must be \"kg\" or \"lb\"")
+		return nil, domain.Errorf(domain.ErrInvalidUnit, "unit must be \"kg\" or \"lb\"")
+	}
+	switch fill {
+	case "", FillNull, FillPrevious, FillInterpolate:
+	default:
+		return nil, domain.Errorf(domain.ErrInvalidArgument, "fill must be %q, %q or %q", FillNull, FillPrevious, FillInterpolate)
 	}
 	if days > 366 {
 		days = 366
 	}
+	weight, err = dailyWeightMode(ctx, s.settings, userID, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	waterFor, weightFor := s.waterRepo.WaterTotalForLocalDay, dailyWeightLookup(s.weightRepo, weight)
+	if !f.IsZero() {
+		if waterFor, weightFor, err = s.filteredDays(ctx, userID, f, weight); err != nil {
+			return nil, err
+		}
+	}
 
 	today := time.Now().In(time.Local)
 	points := make([]DayPoint, 0, days)
+	from, to := today.AddDate(0, 0, -(days-1)).Format("2006-01-02"), today.Format("2006-01-02")
+	notes, err := journalNotes(ctx, s.journal, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	moods, err := moodScores(ctx, s.mood, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stepsOn, err := stepCounts(ctx, s.steps, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var goals domain.GoalHistory
+	if s.goals != nil {
+		if goals, err = s.goals.GoalHistory(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	var targets domain.WeightGoalHistory
+	if s.targets != nil {
+		if targets, err = s.targets.WeightGoalHistory(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
 
 	for i := days - 1; i >= 0; i-- {
 		d := today.AddDate(0, 0, -i)
 		dayStr := d.Format("2006-01-02")
 
-		waterLiters, err := s.waterRepo.WaterTotalForLocalDay(ctx, userID, dayStr)
+		waterLiters, err := waterFor(ctx, userID, dayStr)
 		if err != nil {
 			return nil, err
 		}
+		waterLiters = domain.RoundLiters(waterLiters)
 
-		entry, err := s.weightRepo.LatestWeightForLocalDay(ctx, userID, dayStr)
+		entry, err := weightFor(ctx, userID, dayStr)
 		if err != nil {
 			return nil, err
 		}
@@ -68,7 +200,222 @@ func (s *ChartsService) GetDaily(ctx context.Context, userID int64, days int, un
 			wp = &WeightPoint{Value: val, Unit: unit}
 		}
 
-		points = append(points, DayPoint{Day: dayStr, WaterLiters: waterLiters, Weight: wp})
+		var mood *int
+		if score, ok := moods[dayStr]; ok {
+			mood = &score
+		}
+		var steps *int
+		if n, ok := stepsOn[dayStr]; ok {
+			steps = &n
+		}
+
+		var target *float64
+		if kg := targets.TargetOn(dayStr); kg != nil {
+			v := domain.ConvertWeight(*kg, "kg", unit)
+			target = &v
+		}
+
+		goal := goals.LitersOn(dayStr)
+		points = append(points, DayPoint{
+			Day: dayStr, WaterLiters: waterLiters, Weight: wp, Note: notes[dayStr], Mood: mood, Steps: steps,
+			GoalLiters: goal, GoalMet: waterLiters >= goal, WeightGoal: target,
+		})
 	}
+	fillWeights(points, unit, fill)
 	return points, nil
 }
+
+// fillWeights gives the points without a weight one made up as fill asks.
+func fillWeights(points []DayPoint, unit, fill string) {
+	prev := -1
+	for i := range points {
+		if points[i].Weight == nil || points[i].Weight.Filled {
+			continue
+		}
+		for j := prev + 1; j < i; j++ {
+			switch {
+			case fill == FillPrevious && prev >= 0:
+				points[j].Weight = &WeightPoint{Value: points[prev].Weight.Value, Unit: unit, Filled: true}
+			case fill == FillInterpolate && prev >= 0:
+				from, to := points[prev].Weight.Value, points[i].Weight.Value
+				v := from + (to-from)*float64(j-prev)/float64(i-prev)
+				points[j].Weight = &WeightPoint{Value: v, Unit: unit, Filled: true}
+			}
+		}
+		prev = i
+	}
+	if fill == FillPrevious && prev >= 0 {
+		for j := prev + 1; j < len(points); j++ {
+			points[j].Weight = &WeightPoint{Value: points[prev].Weight.Value, Unit: unit, Filled: true}
+		}
+	}
+}
+
+// filteredDays returns per-day lookups like the repositories' over the
+// user's latest tagScanLimit entries of each kind that match f.
+func (s *ChartsService) filteredDays(ctx context.Context, userID int64, f domain.TagFilter, weight string) (
+	func(context.Context, int64, string) (float64, error),
+	func(context.Context, int64, string) (*domain.WeightEntry, error),
+	error,
+) {
+	if s.tags == nil {
+		return nil, nil, errTagsDisabled
+	}
+	events, err := s.waterRepo.ListRecentWaterEvents(ctx, userID, tagScanLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if events, err = tagWater(ctx, s.tags, userID, events, f); err != nil {
+		return nil, nil, err
+	}
+	entries, err := s.weightRepo.ListRecentWeightEvents(ctx, userID, tagScanLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entries, err = tagWeights(ctx, s.tags, userID, entries, f); err != nil {
+		return nil, nil, err
+	}
+
+	water := map[string]float64{}
+	for _, e := range events {
+		water[e.CreatedAt.In(time.Local).Format("2006-01-02")] += e.DeltaLiters
+	}
+	// Entries are newest first, so the first one seen is the day's latest.
+	weights := map[string]*domain.WeightEntry{}
+	counts := map[string]int{}
+	for i, e := range entries {
+		day := e.CreatedAt.In(time.Local).Format("2006-01-02")
+		if weight == domain.DailyWeightAverage {
+			kg := domain.ConvertWeight(e.Value, e.Unit, "kg")
+			if w, ok := weights[day]; ok {
+				w.Value += kg
+			} else {
+				weights[day] = &domain.WeightEntry{UserID: userID, Day: day, Value: kg, Unit: "kg", CreatedAt: e.CreatedAt}
+			}
+			counts[day]++
+		} else if _, ok := weights[day]; !ok {
+			weights[day] = &entries[i]
+		}
+	}
+	for day, n := range counts {
+		weights[day].Value /= float64(n)
+	}
+	waterFor := func(_ context.Context, _ int64, day string) (float64, error) {
+		return water[day], nil
+	}
+	weightFor := func(_ context.Context, _ int64, day string) (*domain.WeightEntry, error) {
+		return weights[day], nil
+	}
+	return waterFor, weightFor, nil
+}
+
+// Goal statuses reported by Month.
+const (
+	GoalMet        = "met"
+	GoalMissed     = "missed"
+	GoalInProgress = "inProgress"
+)
+
+// CalendarDay is one cell of a month calendar.
+type CalendarDay struct {
+	Day         string       `json:"day"`
+	Weight      *WeightPoint `json:"weight"`
+	WaterLiters float64      `json:"waterLiters"`
+	// GoalLiters is the base water goal in effect on Day.
+	GoalLiters float64 `json:"goalLiters"`
+	// GoalStatus is GoalMet, GoalMissed, GoalInProgress for an unmet today,
+	// or empty for future days.
+	GoalStatus string `json:"goalStatus,omitempty"`
+	Note       string `json:"note,omitempty"`
+	// Planned lists the entries planned for Day, logged or not.
+	Planned []domain.PlannedEntry `json:"planned,omitempty"`
+}
+
+// Month returns one CalendarDay for every day of month ("YYYY-MM"), with
+// water totals compared against the goal in effect each day and weights in
+// unit, reduced per day by the weight mode as in GetDaily. goalLiters, the
+// user's current goal, applies to every day when no goal history is kept.
+func (s *ChartsService) Month(ctx context.Context, userID int64, month, unit string, goalLiters float64, weight string) (_ []CalendarDay, err error) {
+	ctx, span := startSpan(ctx, "ChartsService.Month", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if unit != "kg" && unit != "lb" {
+		return nil, domain.Errorf(domain.ErrInvalidUnit, "unit must be \"kg\" or \"lb\"")
+	}
+	weight, err = dailyWeightMode(ctx, s.settings, userID, weight)
+	if err != nil {
+		return nil, err
+	}
+	weightFor := dailyWeightLookup(s.weightRepo, weight)
+	first, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return nil, domain.Errorf(domain.ErrInvalidArgument, "month must be YYYY-MM")
+	}
+	today := time.Now().In(time.Local).Format("2006-01-02")
+	notes, err := journalNotes(ctx, s.journal, userID, first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	goalOn, err := s.goalOn(ctx, userID, goalLiters)
+	if err != nil {
+		return nil, err
+	}
+	planned := map[string][]domain.PlannedEntry{}
+	if s.plans != nil {
+		entries, err := s.plans.ListPlannedEntries(ctx, userID, first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range entries {
+			planned[p.Day] = append(planned[p.Day], p)
+		}
+	}
+
+	var days []CalendarDay
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		cell := CalendarDay{Day: d.Format("2006-01-02"), Note: notes[d.Format("2006-01-02")], Planned: planned[d.Format("2006-01-02")]}
+		cell.GoalLiters = goalOn(cell.Day)
+		if cell.Day > today {
+			days = append(days, cell)
+			continue
+		}
+
+		cell.WaterLiters, err = waterTotal(ctx, s.waterRepo, userID, cell.Day)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := weightFor(ctx, userID, cell.Day)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			cell.Weight = &WeightPoint{Value: domain.ConvertWeight(entry.Value, entry.Unit, unit), Unit: unit}
+		}
+
+		switch {
+		case cell.WaterLiters >= cell.GoalLiters:
+			cell.GoalStatus = GoalMet
+		case cell.Day == today:
+			cell.GoalStatus = GoalInProgress
+		default:
+			cell.GoalStatus = GoalMissed
+		}
+		days = append(days, cell)
+	}
+	return days, nil
+}
+
+// goalOn returns the base water goal in effect on a day: from the user's
+// goal history when one is kept and not empty, else current for every day.
+func (s *ChartsService) goalOn(ctx context.Context, userID int64, current float64) (func(day string) float64, error) {
+	if s.goals != nil {
+		h, err := s.goals.GoalHistory(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(h) > 0 {
+			return h.LitersOn, nil
+		}
+	}
+	return func(string) float64 { return current }, nil
+}
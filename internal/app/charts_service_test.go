@@ -2,7 +2,10 @@ package app_test
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"vitals/internal/app"
 	"vitals/internal/domain"
@@ -10,7 +13,7 @@ import (
 
 func TestGetDaily_BadUnit(t *testing.T) {
 	svc := app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{})
-	_, err := svc.GetDaily(context.Background(), 1, 7, "stones")
+	_, err := svc.GetDaily(context.Background(), 1, 7, "stones", domain.TagFilter{}, "", "")
 	if err == nil {
 		t.Fatal("expected error for bad unit")
 	}
@@ -23,11 +26,11 @@ func TestGetDaily_Success(t *testing.T) {
 		},
 	}
 	wa := &mockWaterRepo{
-		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 2.5, nil },
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 2.5000000001, nil },
 	}
 
 	svc := app.NewChartsService(wr, wa)
-	points, err := svc.GetDaily(context.Background(), 1, 3, "kg")
+	points, err := svc.GetDaily(context.Background(), 1, 3, "kg", domain.TagFilter{}, "", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -44,6 +47,62 @@ func TestGetDaily_Success(t *testing.T) {
 	}
 }
 
+func TestGetDaily_GoalHistory(t *testing.T) {
+	today := time.Now()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 2.8, nil },
+	}
+	history := &mockGoalHistory{changes: map[int64]domain.GoalHistory{
+		1: {{Day: day(-1), Liters: 3}},
+	}}
+
+	target := 80.0
+	targets := &mockWeightGoals{changes: map[int64]domain.WeightGoalHistory{
+		1: {{Day: day(-1), TargetKg: &target}},
+	}}
+
+	svc := app.NewChartsService(&mockWeightRepo{}, wa).WithGoalHistory(history).WithWeightGoals(targets)
+	points, err := svc.GetDaily(context.Background(), 1, 3, "lb", domain.TagFilter{}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		goal float64
+		met  bool
+	}{
+		{domain.DefaultHydrationGoalLiters, true},
+		{3, false},
+		{3, false},
+	}
+	for i, p := range points {
+		if p.GoalLiters != want[i].goal || p.GoalMet != want[i].met {
+			t.Errorf("%s: expected goal %v met %v, got %v %v", p.Day, want[i].goal, want[i].met, p.GoalLiters, p.GoalMet)
+		}
+		if hasTarget := p.WeightGoal != nil; hasTarget != (i > 0) {
+			t.Errorf("%s: expected a weight goal only from the day it was set, got %v", p.Day, p.WeightGoal)
+		}
+	}
+	if g := points[2].WeightGoal; g == nil || *g != domain.ConvertWeight(80, "kg", "lb") {
+		t.Errorf("expected the weight goal in the chart unit, got %v", g)
+	}
+
+	month := time.Now().Format("2006-01")
+	days, err := svc.Month(context.Background(), 1, month, "kg", 2, "")
+	if err != nil {
+		t.Fatalf("Month failed: %v", err)
+	}
+	for _, d := range days {
+		want := 3.0
+		if d.Day < day(-1) {
+			want = domain.DefaultHydrationGoalLiters
+		}
+		if d.GoalLiters != want {
+			t.Errorf("%s: expected the calendar to use the goal in effect, %v, got %v", d.Day, want, d.GoalLiters)
+		}
+	}
+}
+
 func TestGetDaily_ConvertUnit(t *testing.T) {
 	wr := &mockWeightRepo{
 		latestFn: func(_ context.Context, _ int64, _ string) (*domain.WeightEntry, error) {
@@ -55,7 +114,7 @@ func TestGetDaily_ConvertUnit(t *testing.T) {
 	}
 
 	svc := app.NewChartsService(wr, wa)
-	points, err := svc.GetDaily(context.Background(), 1, 1, "lb")
+	points, err := svc.GetDaily(context.Background(), 1, 1, "lb", domain.TagFilter{}, "", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -78,7 +137,7 @@ func TestGetDaily_ClampsTo366(t *testing.T) {
 	}
 
 	svc := app.NewChartsService(wr, wa)
-	points, err := svc.GetDaily(context.Background(), 1, 500, "kg")
+	points, err := svc.GetDaily(context.Background(), 1, 500, "kg", domain.TagFilter{}, "", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -98,7 +157,7 @@ func TestGetDaily_NoWeight(t *testing.T) {
 	}
 
 	svc := app.NewChartsService(wr, wa)
-	points, err := svc.GetDaily(context.Background(), 1, 1, "kg")
+	points, err := svc.GetDaily(context.Background(), 1, 1, "kg", domain.TagFilter{}, "", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -112,3 +171,144 @@ func TestGetDaily_NoWeight(t *testing.T) {
 		t.Errorf("expected waterLiters=1.0, got %v", points[0].WaterLiters)
 	}
 }
+
+func TestGetDaily_Fill(t *testing.T) {
+	today := time.Now()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	weights := map[string]float64{day(-4): 80, day(-1): 83}
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, d string) (*domain.WeightEntry, error) {
+			if v, ok := weights[d]; ok {
+				return &domain.WeightEntry{Value: v, Unit: "kg"}, nil
+			}
+			return nil, nil
+		},
+	}
+	svc := app.NewChartsService(wr, &mockWaterRepo{})
+
+	tests := []struct {
+		fill string
+		want []float64 // 0 means no weight
+	}{
+		{"", []float64{0, 80, 0, 0, 83, 0}},
+		{app.FillNull, []float64{0, 80, 0, 0, 83, 0}},
+		{app.FillPrevious, []float64{0, 80, 80, 80, 83, 83}},
+		{app.FillInterpolate, []float64{0, 80, 81, 82, 83, 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fill, func(t *testing.T) {
+			points, err := svc.GetDaily(context.Background(), 1, 6, "kg", domain.TagFilter{}, "", tc.fill)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, p := range points {
+				switch {
+				case tc.want[i] == 0 && p.Weight != nil:
+					t.Errorf("%s: expected no weight, got %+v", p.Day, p.Weight)
+				case tc.want[i] != 0 && (p.Weight == nil || p.Weight.Value != tc.want[i]):
+					t.Errorf("%s: expected weight %v, got %+v", p.Day, tc.want[i], p.Weight)
+				case p.Weight != nil && p.Weight.Filled != (weights[p.Day] == 0):
+					t.Errorf("%s: filled = %v", p.Day, p.Weight.Filled)
+				}
+			}
+		})
+	}
+
+	if _, err := svc.GetDaily(context.Background(), 1, 6, "kg", domain.TagFilter{}, "", "zero"); err == nil {
+		t.Error("expected error for bad fill")
+	}
+}
+
+func TestMonth(t *testing.T) {
+	now := time.Now().In(time.Local)
+	today := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, day string) (*domain.WeightEntry, error) {
+			if strings.HasSuffix(day, "-01") {
+				return &domain.WeightEntry{Value: 80, Unit: "kg"}, nil
+			}
+			return nil, nil
+		},
+	}
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, day string) (float64, error) {
+			if strings.HasSuffix(day, "-01") {
+				return 3, nil
+			}
+			return 1, nil
+		},
+	}
+
+	days, err := app.NewChartsService(wr, wa).Month(context.Background(), 1, month, "kg", 2.5, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first := days[0]; first.Weight == nil || first.Weight.Value != 80 || first.GoalStatus != app.GoalMet {
+		t.Errorf("unexpected first day: %+v", first)
+	}
+	for _, d := range days {
+		if !strings.HasPrefix(d.Day, month) {
+			t.Fatalf("day %s outside %s", d.Day, month)
+		}
+		switch {
+		case d.Day > today && (d.GoalStatus != "" || d.WaterLiters != 0):
+			t.Errorf("expected future day %s to be empty, got %+v", d.Day, d)
+		case d.Day == today && !strings.HasSuffix(d.Day, "-01") && d.GoalStatus != app.GoalInProgress:
+			t.Errorf("expected today to be in progress, got %q", d.GoalStatus)
+		case d.Day < today && !strings.HasSuffix(d.Day, "-01") && d.GoalStatus != app.GoalMissed:
+			t.Errorf("expected %s to be missed, got %q", d.Day, d.GoalStatus)
+		}
+	}
+	if last := days[len(days)-1]; last.Day != now.AddDate(0, 1, -now.Day()).Format("2006-01-02") {
+		t.Errorf("expected the month to end on its last day, got %s", last.Day)
+	}
+
+	if _, err := app.NewChartsService(wr, wa).Month(context.Background(), 1, "2026-13", "kg", 2.5, ""); err == nil {
+		t.Error("expected error for invalid month")
+	}
+}
+
+func TestGetDaily_DailyWeightMode(t *testing.T) {
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, _ string) (*domain.WeightEntry, error) {
+			return &domain.WeightEntry{Value: 81, Unit: "kg"}, nil
+		},
+		averageFn: func(_ context.Context, _ int64, _ string) (*domain.WeightEntry, error) {
+			return &domain.WeightEntry{Value: 80.5, Unit: "kg"}, nil
+		},
+	}
+	settings := &mockSettingsRepo{settings: map[int64]domain.UserSettings{
+		2: {"charts.dailyWeight": json.RawMessage(`"average"`)},
+	}}
+	svc := app.NewChartsService(wr, &mockWaterRepo{}).WithSettings(settings)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		userID int64
+		mode   string
+		want   float64
+	}{
+		{"default latest", 1, "", 81},
+		{"requested average", 1, domain.DailyWeightAverage, 80.5},
+		{"user default average", 2, "", 80.5},
+		{"request overrides user default", 2, domain.DailyWeightLatest, 81},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			points, err := svc.GetDaily(ctx, tc.userID, 1, "kg", domain.TagFilter{}, tc.mode, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if points[0].Weight == nil || points[0].Weight.Value != tc.want {
+				t.Errorf("expected weight %v, got %v", tc.want, points[0].Weight)
+			}
+		})
+	}
+
+	if _, err := svc.GetDaily(ctx, 1, 1, "kg", domain.TagFilter{}, "median", ""); err == nil {
+		t.Error("expected error for unknown weight mode")
+	}
+}
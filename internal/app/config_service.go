@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ConfigService exports and imports a user's configuration (preferences,
+// hydration goal and alert rule) as a bundle, separate from measurement
+// data, so it can be moved between instances.
+type ConfigService struct {
+	settings  *SettingsService
+	hydration *HydrationService
+	alerts    *AlertService
+}
+
+// NewConfigService creates a ConfigService over the services that own each
+// section of the bundle.
+func NewConfigService(settings *SettingsService, hydration *HydrationService, alerts *AlertService) *ConfigService {
+	return &ConfigService{settings: settings, hydration: hydration, alerts: alerts}
+}
+
+// ImportResult summarizes which sections an import applied.
+type ImportResult struct {
+	Settings          int  `json:"settings"`
+	Hydration         bool `json:"hydration"`
+	WeightChangeAlert bool `json:"weightChangeAlert"`
+}
+
+// Export returns the user's configuration. Sections the user never set are
+// omitted rather than exported as defaults.
+func (s *ConfigService) Export(ctx context.Context, userID int64) (*domain.ConfigBundle, error) {
+	b := &domain.ConfigBundle{Version: domain.ConfigBundleVersion, ExportedAt: time.Now().UTC()}
+
+	settings, err := s.settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	b.Settings = settings
+
+	hs, err := s.hydration.settings.GetHydrationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hs != nil {
+		b.Hydration = &domain.HydrationConfig{BaseGoalLiters: hs.BaseGoalLiters, Latitude: hs.Latitude, Longitude: hs.Longitude}
+	}
+
+	rule, err := s.alerts.GetRule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		b.WeightChangeAlert = &domain.AlertRuleConfig{
+			MaxWeeklyChangePct: rule.MaxWeeklyChangePct,
+			Channel:            rule.Channel,
+			Target:             rule.Target,
+			Enabled:            rule.Enabled,
+		}
+	}
+	return b, nil
+}
+
+// Import applies b to the user's configuration. Settings are merged into the
+// existing ones; the hydration goal and alert rule are replaced when
+// present. Every section is validated before anything is written, so an
+// invalid bundle changes nothing.
+func (s *ConfigService) Import(ctx context.Context, userID int64, b domain.ConfigBundle) (*ImportResult, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	hs, rule, err := s.check(userID, b)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ImportResult{}
+	if len(b.Settings) > 0 {
+		if _, err := s.settings.Update(ctx, userID, b.Settings); err != nil {
+			return nil, fmt.Errorf("settings: %w", err)
+		}
+		res.Settings = len(b.Settings)
+	}
+	if hs != nil {
+		if _, err := s.hydration.SaveSettings(ctx, *hs); err != nil {
+			return nil, fmt.Errorf("hydration: %w", err)
+		}
+		res.Hydration = true
+	}
+	if rule != nil {
+		if _, err := s.alerts.SaveRule(ctx, *rule); err != nil {
+			return nil, fmt.Errorf("weightChangeAlert: %w", err)
+		}
+		res.WeightChangeAlert = true
+	}
+	return res, nil
+}
+
+// Validate checks b as Import would, without applying it.
+func (s *ConfigService) Validate(b domain.ConfigBundle) error {
+	_, _, err := s.check(0, b)
+	return err
+}
+
+// check validates every section of b, returning the hydration settings and
+// alert rule it would save for userID.
+func (s *ConfigService) check(userID int64, b domain.ConfigBundle) (*domain.HydrationSettings, *domain.AlertRule, error) {
+	if b.Version < 1 || b.Version > domain.ConfigBundleVersion {
+		return nil, nil, fmt.Errorf("unsupported bundle version %d (expected 1 to %d)", b.Version, domain.ConfigBundleVersion)
+	}
+
+	if _, _, err := splitSettingsPatch(b.Settings); err != nil {
+		return nil, nil, fmt.Errorf("settings: %w", err)
+	}
+	var hs *domain.HydrationSettings
+	if c := b.Hydration; c != nil {
+		hs = &domain.HydrationSettings{UserID: userID, BaseGoalLiters: c.BaseGoalLiters, Latitude: c.Latitude, Longitude: c.Longitude}
+		if err := validateHydrationSettings(*hs); err != nil {
+			return nil, nil, fmt.Errorf("hydration: %w", err)
+		}
+	}
+	var rule *domain.AlertRule
+	if c := b.WeightChangeAlert; c != nil {
+		rule = &domain.AlertRule{
+			UserID:             userID,
+			MaxWeeklyChangePct: c.MaxWeeklyChangePct,
+			Channel:            c.Channel,
+			Target:             c.Target,
+			Enabled:            c.Enabled,
+		}
+		if err := s.alerts.validateRule(rule); err != nil {
+			return nil, nil, fmt.Errorf("weightChangeAlert: %w", err)
+		}
+	}
+	return hs, rule, nil
+}
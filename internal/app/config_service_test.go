@@ -0,0 +1,101 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type configFixture struct {
+	svc       *app.ConfigService
+	settings  *mockSettingsRepo
+	hydration *mockHydrationRepo
+	rules     *mockAlertRuleRepo
+}
+
+func newConfigFixture() configFixture {
+	f := configFixture{
+		settings:  &mockSettingsRepo{settings: map[int64]domain.UserSettings{}},
+		hydration: &mockHydrationRepo{settings: map[int64]domain.HydrationSettings{}},
+		rules:     &mockAlertRuleRepo{rules: map[int64]domain.AlertRule{}},
+	}
+	alerts := app.NewAlertService(f.rules, &mockWeightRepo{}).WithNotifier(domain.AlertChannelWebhook, &recordingNotifier{})
+	f.svc = app.NewConfigService(app.NewSettingsService(f.settings), app.NewHydrationService(f.hydration), alerts)
+	return f
+}
+
+func TestConfigService_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newConfigFixture()
+	lat, lon := 52.52, 13.4
+	src.settings.settings[1] = domain.UserSettings{"ui.theme": json.RawMessage(`"dark"`)}
+	src.hydration.settings[1] = domain.HydrationSettings{UserID: 1, BaseGoalLiters: 3, Latitude: &lat, Longitude: &lon}
+	src.rules.rules[1] = domain.AlertRule{UserID: 1, MaxWeeklyChangePct: 1.5, Channel: domain.AlertChannelWebhook, Target: "https://example.com/hook", Enabled: true}
+
+	bundle, err := src.svc.Export(ctx, 1)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if bundle.Version != domain.ConfigBundleVersion || bundle.Hydration == nil || bundle.WeightChangeAlert == nil {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+
+	// Move the bundle through JSON to another instance and user.
+	raw, _ := json.Marshal(bundle)
+	var decoded domain.ConfigBundle
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	dst := newConfigFixture()
+	dst.settings.settings[7] = domain.UserSettings{"web.pinnedCards": json.RawMessage(`["water"]`)}
+	res, err := dst.svc.Import(ctx, 7, decoded)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if res.Settings != 1 || !res.Hydration || !res.WeightChangeAlert {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got := dst.settings.settings[7]; len(got) != 2 || string(got["ui.theme"]) != `"dark"` {
+		t.Fatalf("expected settings to be merged, got %v", got)
+	}
+	if hs := dst.hydration.settings[7]; hs.BaseGoalLiters != 3 || *hs.Latitude != lat {
+		t.Fatalf("unexpected hydration settings: %+v", hs)
+	}
+	if r := dst.rules.rules[7]; r.Target != "https://example.com/hook" || !r.Enabled {
+		t.Fatalf("unexpected alert rule: %+v", r)
+	}
+}
+
+func TestConfigService_ImportRejectsInvalidBundle(t *testing.T) {
+	ctx := context.Background()
+	f := newConfigFixture()
+
+	for name, b := range map[string]domain.ConfigBundle{
+		"future version": {Version: domain.ConfigBundleVersion + 1},
+		"bad setting": {
+			Version:   1,
+			Settings:  domain.UserSettings{"ui.theme": json.RawMessage(`"neon"`)},
+			Hydration: &domain.HydrationConfig{BaseGoalLiters: 2},
+		},
+		"bad goal": {
+			Version:   1,
+			Settings:  domain.UserSettings{"ui.theme": json.RawMessage(`"dark"`)},
+			Hydration: &domain.HydrationConfig{BaseGoalLiters: 50},
+		},
+		"unavailable channel": {
+			Version:           1,
+			Hydration:         &domain.HydrationConfig{BaseGoalLiters: 2},
+			WeightChangeAlert: &domain.AlertRuleConfig{MaxWeeklyChangePct: 1, Channel: domain.AlertChannelMQTT, Enabled: true},
+		},
+	} {
+		if _, err := f.svc.Import(ctx, 1, b); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if len(f.settings.settings[1]) != 0 || len(f.hydration.settings) != 0 || len(f.rules.rules) != 0 {
+		t.Fatal("expected rejected bundles to change nothing")
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"vitals/internal/domain"
+)
+
+// dailyWeightSetting is the user setting holding the default daily weight
+// mode.
+const dailyWeightSetting = "charts.dailyWeight"
+
+// weightUnitSetting is the user setting holding the preferred weight unit.
+const weightUnitSetting = "units.weight"
+
+// dailyWeightMode resolves a requested daily weight mode. An empty mode
+// falls back to the user's charts.dailyWeight setting, then to
+// domain.DailyWeightLatest.
+func dailyWeightMode(ctx context.Context, settings domain.SettingsRepository, userID int64, mode string) (string, error) {
+	if mode != "" {
+		if !domain.ValidDailyWeight(mode) {
+			return "", errors.New("weight must be \"latest\" or \"average\"")
+		}
+		return mode, nil
+	}
+	if settings == nil {
+		return domain.DailyWeightLatest, nil
+	}
+	all, err := settings.GetSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if raw, ok := all[dailyWeightSetting]; ok {
+		if err := json.Unmarshal(raw, &mode); err == nil && domain.ValidDailyWeight(mode) {
+			return mode, nil
+		}
+	}
+	return domain.DailyWeightLatest, nil
+}
+
+// dailyWeightLookup returns repo's per-day weight query for mode.
+func dailyWeightLookup(repo domain.WeightRepository, mode string) func(context.Context, int64, string) (*domain.WeightEntry, error) {
+	if mode == domain.DailyWeightAverage {
+		return repo.AverageWeightForLocalDay
+	}
+	return repo.LatestWeightForLocalDay
+}
+
+// preferredWeightUnit returns the user's preferred weight unit, or "" when it
+// is unset or settings is nil.
+func preferredWeightUnit(ctx context.Context, settings domain.SettingsRepository, userID int64) (string, error) {
+	if settings == nil {
+		return "", nil
+	}
+	all, err := settings.GetSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	var unit string
+	if raw, ok := all[weightUnitSetting]; ok {
+		if err := json.Unmarshal(raw, &unit); err == nil && (unit == "kg" || unit == "lb") {
+			return unit, nil
+		}
+	}
+	return "", nil
+}
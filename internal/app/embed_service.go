@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// MaxEmbedDays is the longest range an embedded chart may show.
+const MaxEmbedDays = 366
+
+// MaxEmbedTTL is the longest an embed token may stay valid.
+const MaxEmbedTTL = 365 * 24 * time.Hour
+
+// ErrInvalidEmbed indicates an embed token that is malformed, was not
+// signed with the current secret, has expired or was revoked.
+var ErrInvalidEmbed = errors.New("invalid or expired embed token")
+
+// EmbedChart is the grant an embed token carries: whose daily chart, over
+// how many days, in which weight unit, and until when.
+type EmbedChart struct {
+	UserID int64  `json:"u"`
+	Days   int    `json:"d"`
+	Unit   string `json:"unit"`
+	// ExpiresAt is when the token stops working, in Unix seconds.
+	ExpiresAt int64 `json:"exp"`
+	// Epoch is the user's revocation epoch when the token was signed; see
+	// WithRevocations.
+	Epoch int64 `json:"e,omitempty"`
+}
+
+// EmbedService signs and verifies the tokens of embeddable charts, which
+// dashboards such as Organizr or Homepage show in an iframe without a
+// session or API token. Tokens are stateless: each is its grant signed
+// with the instance's embed secret, so rotating the secret revokes them
+// all.
+type EmbedService struct {
+	secret      []byte
+	revocations domain.RevocationRepository
+}
+
+// NewEmbedService creates an EmbedService signing with secret.
+func NewEmbedService(secret []byte) *EmbedService {
+	return &EmbedService{secret: secret}
+}
+
+// WithRevocations ties each token to its user's revocation epoch, so
+// AuthService.RevokeAll revokes the user's tokens and deleting the
+// account revokes them for good.
+func (s *EmbedService) WithRevocations(repo domain.RevocationRepository) *EmbedService {
+	s.revocations = repo
+	return s
+}
+
+// Sign returns a token granting c, valid for ttl of at most MaxEmbedTTL.
+func (s *EmbedService) Sign(ctx context.Context, c EmbedChart, ttl time.Duration) (string, error) {
+	if c.Days < 1 || c.Days > MaxEmbedDays {
+		return "", fmt.Errorf("days must be between 1 and %d", MaxEmbedDays)
+	}
+	if c.Unit != "kg" && c.Unit != "lb" {
+		return "", errors.New("unit must be \"kg\" or \"lb\"")
+	}
+	if ttl <= 0 || ttl > MaxEmbedTTL {
+		return "", fmt.Errorf("expiry must be between 1 and %d days", MaxEmbedTTL/(24*time.Hour))
+	}
+	c.ExpiresAt = time.Now().Add(ttl).Unix()
+	c.Epoch = 0
+	if s.revocations != nil {
+		epoch, ok, err := s.revocations.RevocationEpoch(ctx, c.UserID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", ErrUserNotFound
+		}
+		c.Epoch = epoch
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + s.sign(p), nil
+}
+
+// Verify returns the grant of token, or ErrInvalidEmbed.
+func (s *EmbedService) Verify(ctx context.Context, token string) (*EmbedChart, error) {
+	p, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.sign(p))) {
+		return nil, ErrInvalidEmbed
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return nil, ErrInvalidEmbed
+	}
+	var c EmbedChart
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidEmbed
+	}
+	if !time.Now().Before(time.Unix(c.ExpiresAt, 0)) {
+		return nil, ErrInvalidEmbed
+	}
+	if s.revocations != nil {
+		epoch, ok, err := s.revocations.RevocationEpoch(ctx, c.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || epoch != c.Epoch {
+			return nil, ErrInvalidEmbed
+		}
+	}
+	return &c, nil
+}
+
+func (s *EmbedService) sign(payload string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
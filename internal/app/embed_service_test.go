@@ -0,0 +1,101 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+)
+
+// mockRevocationRepo keeps the epochs of the users it lists.
+type mockRevocationRepo struct {
+	epochs map[int64]int64
+}
+
+func (m *mockRevocationRepo) RevocationEpoch(_ context.Context, userID int64) (int64, bool, error) {
+	epoch, ok := m.epochs[userID]
+	return epoch, ok, nil
+}
+
+func (m *mockRevocationRepo) BumpRevocationEpoch(_ context.Context, userID int64) error {
+	m.epochs[userID]++
+	return nil
+}
+
+func TestEmbedService_SignVerify(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewEmbedService([]byte("secret"))
+
+	token, err := svc.Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "lb"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	c, err := svc.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if c.UserID != 7 || c.Days != 30 || c.Unit != "lb" || c.ExpiresAt == 0 {
+		t.Errorf("unexpected grant %+v", c)
+	}
+
+	for _, bad := range []app.EmbedChart{
+		{UserID: 7, Days: 0, Unit: "kg"},
+		{UserID: 7, Days: app.MaxEmbedDays + 1, Unit: "kg"},
+		{UserID: 7, Days: 30, Unit: "stone"},
+	} {
+		if _, err := svc.Sign(ctx, bad, time.Hour); err == nil {
+			t.Errorf("expected Sign %+v to fail", bad)
+		}
+	}
+	for _, ttl := range []time.Duration{0, -time.Hour, app.MaxEmbedTTL + time.Hour} {
+		if _, err := svc.Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "kg"}, ttl); err == nil {
+			t.Errorf("expected Sign for %v to fail", ttl)
+		}
+	}
+
+	expired, _ := svc.Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "kg"}, time.Nanosecond)
+	other, _ := app.NewEmbedService([]byte("rotated")).Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "kg"}, time.Hour)
+	for name, tok := range map[string]string{
+		"expired":  expired,
+		"rotated":  other,
+		"tampered": "eyJ1Ijo4LCJkIjozMCwidW5pdCI6ImtnIn0" + token[len(token)-44:],
+		"garbage":  "not-a-token",
+	} {
+		if _, err := svc.Verify(ctx, tok); !errors.Is(err, app.ErrInvalidEmbed) {
+			t.Errorf("%s: expected ErrInvalidEmbed, got %v", name, err)
+		}
+	}
+}
+
+func TestEmbedService_Revocation(t *testing.T) {
+	ctx := context.Background()
+	revocations := &mockRevocationRepo{epochs: map[int64]int64{7: 3}}
+	svc := app.NewEmbedService([]byte("secret")).WithRevocations(revocations)
+	auth := app.NewAuthService(&mockUserRepo{}, &mockSessionRepo{}).WithRevocations(revocations)
+
+	token, err := svc.Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "kg"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if _, err := svc.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if _, err := svc.Sign(ctx, app.EmbedChart{UserID: 8, Days: 30, Unit: "kg"}, time.Hour); !errors.Is(err, app.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound for an unknown user, got %v", err)
+	}
+
+	if err := auth.RevokeAll(ctx, 7); err != nil {
+		t.Fatalf("RevokeAll: %v", err)
+	}
+	if _, err := svc.Verify(ctx, token); !errors.Is(err, app.ErrInvalidEmbed) {
+		t.Errorf("expected RevokeAll to revoke the link, got %v", err)
+	}
+
+	token, _ = svc.Sign(ctx, app.EmbedChart{UserID: 7, Days: 30, Unit: "kg"}, time.Hour)
+	delete(revocations.epochs, 7)
+	if _, err := svc.Verify(ctx, token); !errors.Is(err, app.ErrInvalidEmbed) {
+		t.Errorf("expected a deleted account's link revoked, got %v", err)
+	}
+}
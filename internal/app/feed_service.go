@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// feedHistoryLimit bounds how many weigh-ins a feed considers.
+const feedHistoryLimit = 1000
+
+// milestoneStepKg is the weight lost between successive milestones.
+const milestoneStepKg = 2.5
+
+// FeedService assembles the read-only calendar and news feeds.
+type FeedService struct {
+	weight    domain.WeightRepository
+	summaries *SummaryService
+}
+
+// NewFeedService creates a FeedService backed by the given repositories.
+func NewFeedService(weight domain.WeightRepository, water domain.WaterRepository) *FeedService {
+	return &FeedService{weight: weight, summaries: NewSummaryService(weight, water)}
+}
+
+// WithSummaries reads weekly summaries through ss, e.g. to use its cache.
+func (s *FeedService) WithSummaries(ss *SummaryService) *FeedService {
+	s.summaries = ss
+	return s
+}
+
+// Calendar is the content of a user's calendar feed.
+type Calendar struct {
+	WeighIns   []domain.WeightEntry
+	Milestones []domain.Milestone
+}
+
+// Calendar returns the user's weigh-ins, newest first, and the milestones
+// they have achieved.
+func (s *FeedService) Calendar(ctx context.Context, userID int64) (*Calendar, error) {
+	entries, err := s.weight.ListRecentWeightEvents(ctx, userID, feedHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &Calendar{
+		WeighIns:   entries,
+		Milestones: domain.WeightMilestones(entries, milestoneStepKg),
+	}, nil
+}
+
+// WeeklySummaries returns summaries of the last weeks completed weeks before
+// now, newest first.
+func (s *FeedService) WeeklySummaries(ctx context.Context, userID int64, weeks int, now time.Time) ([]domain.WeeklySummary, error) {
+	return s.summaries.Weekly(ctx, userID, weeks, now, "")
+}
@@ -0,0 +1,58 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestWeeklySummaries(t *testing.T) {
+	weights := map[string]*domain.WeightEntry{
+		"2026-02-02": {Value: 90.0, Unit: "kg"},
+		"2026-02-05": {Value: 197.0, Unit: "lb"},
+		"2026-02-08": {Value: 88.5, Unit: "kg"},
+	}
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, day string) (*domain.WeightEntry, error) {
+			return weights[day], nil
+		},
+	}
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, day string) (float64, error) {
+			if day >= "2026-02-02" {
+				return 2.0, nil
+			}
+			return 0, nil
+		},
+	}
+	svc := app.NewFeedService(wr, wa)
+
+	// Wednesday: the current week (from Mon 9 Feb) is partial and skipped.
+	now := time.Date(2026, 2, 11, 12, 0, 0, 0, time.Local)
+	got, err := svc.WeeklySummaries(context.Background(), 1, 2, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(got))
+	}
+
+	last := got[0]
+	if last.WeekStart != "2026-02-02" || last.WeekEnd != "2026-02-08" {
+		t.Fatalf("unexpected week bounds: %s..%s", last.WeekStart, last.WeekEnd)
+	}
+	if last.WeighIns != 3 || last.ChangeKg == nil || *last.ChangeKg != -1.5 {
+		t.Fatalf("unexpected weight summary: %+v", last)
+	}
+	if last.AvgWaterLiters != 2.0 {
+		t.Fatalf("expected 2.0 L/day, got %v", last.AvgWaterLiters)
+	}
+
+	prev := got[1]
+	if prev.WeekStart != "2026-01-26" || prev.WeighIns != 0 || prev.ChangeKg != nil || prev.AvgWaterLiters != 0 {
+		t.Fatalf("unexpected empty week: %+v", prev)
+	}
+}
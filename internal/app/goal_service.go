@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Goals is a user's goal history: every water goal and target weight they
+// set, each with the day it took effect.
+type Goals struct {
+	Water  domain.GoalHistory       `json:"water"`
+	Weight domain.WeightGoalHistory `json:"weight"`
+}
+
+// GoalService keeps the history of users' goals, so past days are judged
+// against the goal in effect at the time rather than the current one.
+// Water goals are recorded by HydrationService as they change; target
+// weights are set here.
+type GoalService struct {
+	water  domain.GoalHistoryRepository
+	weight domain.WeightGoalRepository
+}
+
+// NewGoalService creates a GoalService backed by the given repositories.
+func NewGoalService(water domain.GoalHistoryRepository, weight domain.WeightGoalRepository) *GoalService {
+	return &GoalService{water: water, weight: weight}
+}
+
+// History returns the user's goal changes, oldest first.
+func (s *GoalService) History(ctx context.Context, userID int64) (*Goals, error) {
+	water, err := s.water.GoalHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	weight, err := s.weight.WeightGoalHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if water == nil {
+		water = domain.GoalHistory{}
+	}
+	if weight == nil {
+		weight = domain.WeightGoalHistory{}
+	}
+	return &Goals{Water: water, Weight: weight}, nil
+}
+
+// SetWeightGoal sets the user's target weight from day (YYYY-MM-DD; empty
+// means today) onward, until the next change. A nil value clears the
+// target. Days after today are rejected, so the history only records
+// targets that have been in effect.
+func (s *GoalService) SetWeightGoal(ctx context.Context, userID int64, value *float64, unit, day string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	today := time.Now().In(time.Local).Format("2006-01-02")
+	if day == "" {
+		day = today
+	}
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		return errors.New("effectiveFrom must be YYYY-MM-DD")
+	}
+	if day > today {
+		return errors.New("effectiveFrom must not be in the future")
+	}
+
+	var kg *float64
+	if value != nil {
+		if *value <= 0 {
+			return errors.New("value must be > 0")
+		}
+		if unit != "kg" && unit != "lb" {
+			return errors.New("unit must be \"kg\" or \"lb\"")
+		}
+		v := domain.ConvertWeight(*value, unit, "kg")
+		kg = &v
+	}
+	return s.weight.RecordWeightGoal(ctx, userID, day, kg)
+}
@@ -0,0 +1,69 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// mockWeightGoals keeps target changes in the order they are recorded.
+type mockWeightGoals struct {
+	changes map[int64]domain.WeightGoalHistory
+}
+
+func (m *mockWeightGoals) RecordWeightGoal(ctx context.Context, userID int64, day string, targetKg *float64) error {
+	m.changes[userID] = append(m.changes[userID], domain.WeightGoalChange{Day: day, TargetKg: targetKg})
+	return nil
+}
+
+func (m *mockWeightGoals) WeightGoalHistory(ctx context.Context, userID int64) (domain.WeightGoalHistory, error) {
+	return m.changes[userID], nil
+}
+
+func TestGoalService(t *testing.T) {
+	ctx := context.Background()
+	targets := &mockWeightGoals{changes: map[int64]domain.WeightGoalHistory{}}
+	svc := app.NewGoalService(&mockGoalHistory{changes: map[int64]domain.GoalHistory{}}, targets)
+
+	goals, err := svc.History(ctx, 1)
+	if err != nil || goals.Water == nil || goals.Weight == nil {
+		t.Fatalf("expected empty, non-nil histories, got %+v, %v", goals, err)
+	}
+
+	value := 165.0
+	if err := svc.SetWeightGoal(ctx, 1, &value, "lb", "2026-01-10"); err != nil {
+		t.Fatalf("SetWeightGoal failed: %v", err)
+	}
+	if err := svc.SetWeightGoal(ctx, 1, nil, "", ""); err != nil {
+		t.Fatalf("clearing the goal failed: %v", err)
+	}
+	goals, _ = svc.History(ctx, 1)
+	today := time.Now().Format("2006-01-02")
+	if len(goals.Weight) != 2 || goals.Weight[0].Day != "2026-01-10" || math.Abs(*goals.Weight[0].TargetKg-74.84) > 0.01 ||
+		goals.Weight[1].Day != today || goals.Weight[1].TargetKg != nil {
+		t.Fatalf("unexpected weight history: %+v", goals.Weight)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	for name, call := range map[string]func() error{
+		"zero":      func() error { v := 0.0; return svc.SetWeightGoal(ctx, 1, &v, "kg", "") },
+		"bad unit":  func() error { return svc.SetWeightGoal(ctx, 1, &value, "st", "") },
+		"bad day":   func() error { return svc.SetWeightGoal(ctx, 1, &value, "kg", "10/01/2026") },
+		"future":    func() error { return svc.SetWeightGoal(ctx, 1, &value, "kg", tomorrow) },
+		"read-only": func() error { return svc.SetWeightGoal(app.WithReadOnly(ctx), 1, &value, "kg", "") },
+	} {
+		if err := call(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if name == "read-only" && !errors.Is(err, app.ErrReadOnly) {
+			t.Errorf("expected ErrReadOnly, got %v", err)
+		}
+	}
+	if len(targets.changes[1]) != 2 {
+		t.Errorf("expected rejected goals not recorded, got %+v", targets.changes[1])
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// HydrationService manages daily water goals, raising them on hot days when
+// a weather provider is configured.
+type HydrationService struct {
+	settings domain.HydrationSettingsRepository
+	weather  domain.WeatherProvider
+	history  domain.GoalHistoryRepository
+}
+
+// NewHydrationService creates a HydrationService backed by the given repository.
+func NewHydrationService(settings domain.HydrationSettingsRepository) *HydrationService {
+	return &HydrationService{settings: settings}
+}
+
+// WithWeather enables weather-aware goal adjustment for users with a location.
+func (s *HydrationService) WithWeather(p domain.WeatherProvider) *HydrationService {
+	s.weather = p
+	return s
+}
+
+// WithGoalHistory records each change of base goal, so past days can be
+// judged against the goal in effect at the time.
+func (s *HydrationService) WithGoalHistory(repo domain.GoalHistoryRepository) *HydrationService {
+	s.history = repo
+	return s
+}
+
+// Settings returns the user's settings, falling back to the default goal.
+func (s *HydrationService) Settings(ctx context.Context, userID int64) (*domain.HydrationSettings, error) {
+	hs, err := s.settings.GetHydrationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hs == nil {
+		hs = &domain.HydrationSettings{UserID: userID, BaseGoalLiters: domain.DefaultHydrationGoalLiters}
+	}
+	return hs, nil
+}
+
+// SaveSettings validates and stores the user's settings.
+func (s *HydrationService) SaveSettings(ctx context.Context, hs domain.HydrationSettings) (*domain.HydrationSettings, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateHydrationSettings(hs); err != nil {
+		return nil, err
+	}
+	prev, err := s.Settings(ctx, hs.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.settings.SaveHydrationSettings(ctx, hs); err != nil {
+		return nil, err
+	}
+	if s.history != nil && hs.BaseGoalLiters != prev.BaseGoalLiters {
+		today := time.Now().In(time.Local).Format("2006-01-02")
+		if err := s.history.RecordGoal(ctx, hs.UserID, today, hs.BaseGoalLiters); err != nil {
+			return nil, err
+		}
+	}
+	return &hs, nil
+}
+
+// validateHydrationSettings checks the goal and optional location ranges.
+func validateHydrationSettings(hs domain.HydrationSettings) error {
+	if hs.BaseGoalLiters <= 0 || hs.BaseGoalLiters > 10 {
+		return errors.New("baseGoalLiters must be within (0, 10]")
+	}
+	if (hs.Latitude == nil) != (hs.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if hs.HasLocation() && (math.Abs(*hs.Latitude) > 90 || math.Abs(*hs.Longitude) > 180) {
+		return errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	return nil
+}
+
+// TodayGoal returns today's goal. Weather lookups are best effort: if the
+// provider fails, the unadjusted goal is returned.
+func (s *HydrationService) TodayGoal(ctx context.Context, userID int64) (*domain.HydrationGoal, error) {
+	hs, err := s.Settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	goal := &domain.HydrationGoal{BaseLiters: hs.BaseGoalLiters, Liters: hs.BaseGoalLiters}
+	if s.weather == nil || !hs.HasLocation() {
+		return goal, nil
+	}
+
+	w, err := s.weather.Current(ctx, *hs.Latitude, *hs.Longitude)
+	if err != nil {
+		log.Printf("hydration: weather lookup for user %d: %v", userID, err)
+		return goal, nil
+	}
+	goal.TempMaxC = &w.TempMaxC
+	goal.AdjustmentLiters, goal.Reason = domain.HeatAdjustment(w.TempMaxC)
+	goal.Liters += goal.AdjustmentLiters
+	return goal, nil
+}
@@ -0,0 +1,138 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockHydrationRepo struct {
+	settings map[int64]domain.HydrationSettings
+}
+
+func (m *mockHydrationRepo) GetHydrationSettings(ctx context.Context, userID int64) (*domain.HydrationSettings, error) {
+	hs, ok := m.settings[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &hs, nil
+}
+
+func (m *mockHydrationRepo) SaveHydrationSettings(ctx context.Context, hs domain.HydrationSettings) error {
+	m.settings[hs.UserID] = hs
+	return nil
+}
+
+// mockGoalHistory keeps goal changes in the order they are recorded.
+type mockGoalHistory struct {
+	changes map[int64]domain.GoalHistory
+}
+
+func (m *mockGoalHistory) RecordGoal(ctx context.Context, userID int64, day string, liters float64) error {
+	h := m.changes[userID]
+	if n := len(h); n > 0 && h[n-1].Day == day {
+		h[n-1].Liters = liters
+	} else {
+		h = append(h, domain.GoalChange{Day: day, Liters: liters})
+	}
+	m.changes[userID] = h
+	return nil
+}
+
+func (m *mockGoalHistory) GoalHistory(ctx context.Context, userID int64) (domain.GoalHistory, error) {
+	return m.changes[userID], nil
+}
+
+type fakeWeather struct {
+	tempC float64
+	err   error
+}
+
+func (f *fakeWeather) Current(ctx context.Context, lat, lon float64) (*domain.Weather, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &domain.Weather{TempMaxC: f.tempC}, nil
+}
+
+func TestHydrationService_TodayGoal(t *testing.T) {
+	ctx := context.Background()
+	lat, lon := 33.45, -112.07
+
+	repo := &mockHydrationRepo{settings: map[int64]domain.HydrationSettings{}}
+	weather := &fakeWeather{tempC: 36}
+	svc := app.NewHydrationService(repo).WithWeather(weather)
+
+	goal, err := svc.TodayGoal(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if goal.Liters != domain.DefaultHydrationGoalLiters || goal.AdjustmentLiters != 0 {
+		t.Fatalf("expected the unadjusted default goal without a location, got %+v", goal)
+	}
+
+	if _, err := svc.SaveSettings(ctx, domain.HydrationSettings{UserID: 1, BaseGoalLiters: 3, Latitude: &lat, Longitude: &lon}); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	goal, _ = svc.TodayGoal(ctx, 1)
+	if goal.BaseLiters != 3 || goal.AdjustmentLiters != 1 || goal.Liters != 4 || goal.Reason == "" {
+		t.Fatalf("expected a hot-day adjustment, got %+v", goal)
+	}
+
+	weather.err = errors.New("unavailable")
+	goal, err = svc.TodayGoal(ctx, 1)
+	if err != nil || goal.Liters != 3 {
+		t.Fatalf("expected the base goal when weather fails, got %+v, %v", goal, err)
+	}
+}
+
+func TestHydrationService_SaveSettingsValidation(t *testing.T) {
+	svc := app.NewHydrationService(&mockHydrationRepo{settings: map[int64]domain.HydrationSettings{}})
+	lat, badLon := 10.0, 200.0
+
+	tests := []struct {
+		name string
+		hs   domain.HydrationSettings
+	}{
+		{"zero goal", domain.HydrationSettings{BaseGoalLiters: 0}},
+		{"huge goal", domain.HydrationSettings{BaseGoalLiters: 20}},
+		{"latitude only", domain.HydrationSettings{BaseGoalLiters: 2, Latitude: &lat}},
+		{"longitude out of range", domain.HydrationSettings{BaseGoalLiters: 2, Latitude: &lat, Longitude: &badLon}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := svc.SaveSettings(context.Background(), tc.hs); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestHydrationService_RecordsGoalChanges(t *testing.T) {
+	ctx := context.Background()
+	history := &mockGoalHistory{changes: map[int64]domain.GoalHistory{}}
+	svc := app.NewHydrationService(&mockHydrationRepo{settings: map[int64]domain.HydrationSettings{}}).
+		WithGoalHistory(history)
+
+	// Saving the default goal changes nothing.
+	if _, err := svc.SaveSettings(ctx, domain.HydrationSettings{UserID: 1, BaseGoalLiters: domain.DefaultHydrationGoalLiters}); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	if len(history.changes[1]) != 0 {
+		t.Fatalf("expected no goal change, got %+v", history.changes[1])
+	}
+
+	for _, liters := range []float64{3, 3, 3.5} {
+		if _, err := svc.SaveSettings(ctx, domain.HydrationSettings{UserID: 1, BaseGoalLiters: liters}); err != nil {
+			t.Fatalf("SaveSettings failed: %v", err)
+		}
+	}
+	today := time.Now().Format("2006-01-02")
+	if h := history.changes[1]; len(h) != 1 || h[0] != (domain.GoalChange{Day: today, Liters: 3.5}) {
+		t.Fatalf("expected today's goal to be 3.5, got %+v", h)
+	}
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ImportFormatGarmin is a Garmin Connect weight export: the CSV download of
+// the weight report, or a FIT file from a Garmin scale. Body fat readings
+// are recorded in the "body-fat" custom metric.
+const ImportFormatGarmin = "garmin"
+
+// bodyFatMetric is the custom metric imported body fat readings are
+// recorded in, defined for the user on first use.
+var bodyFatMetric = domain.CustomMetric{Slug: "body-fat", Name: "Body fat", Unit: "%", Aggregation: domain.MetricLatest}
+
+// bodyFatRecord builds a deduplicated body fat record.
+func bodyFatRecord(percent float64, at time.Time) importRecord {
+	rec := importRecord{Kind: "bodyfat", Value: percent, Unit: "%", At: at}
+	rec.Key = dedupKey(rec)
+	return rec
+}
+
+// parseGarmin reads a Garmin export, telling FIT files from CSV by the
+// ".FIT" signature in their header.
+func parseGarmin(r io.Reader, emit func(importRecord, error)) error {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(12); len(head) == 12 && string(head[8:12]) == ".FIT" {
+		return parseFIT(br, emit)
+	}
+	return parseGarminCSV(br, emit)
+}
+
+// Garmin Connect date and time layouts. The weight report lists each day on
+// a line of its own, followed by that day's weigh-ins with only a time.
+var (
+	garminDayLayouts   = []string{"Jan 2, 2006", "2 Jan 2006", "2006-01-02"}
+	garminClockLayouts = []string{"3:04 PM", "15:04", "15:04:05"}
+	garminTimeLayouts  = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "Jan 2, 2006 3:04 PM", "2006-01-02"}
+)
+
+// parseGarminCSV reads the weight report CSV: a header with Time (or Date)
+// and Weight columns and an optional Body Fat column. Weights carry their
+// unit ("80.4 kg", "177.2 lbs") and default to kg; "--" marks a missing
+// value. Times are local.
+func parseGarminCSV(r io.Reader, emit func(importRecord, error)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	col, err := csvColumns(header, "weight")
+	if err != nil {
+		return err
+	}
+	timeCol, ok := col["time"]
+	if !ok {
+		if timeCol, ok = col["date"]; !ok {
+			return errors.New("missing \"time\" or \"date\" column")
+		}
+	}
+	fatCol, hasFat := col["body fat"]
+
+	var day time.Time
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		field := func(i int) string {
+			if i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		if field(col["weight"]) == "" {
+			if d, ok := parseGarminLayouts(garminDayLayouts, field(0)); ok {
+				day = d
+				continue
+			}
+		}
+
+		at, ok := parseGarminLayouts(garminTimeLayouts, field(timeCol))
+		if !ok && !day.IsZero() {
+			if clock, ok2 := parseGarminLayouts(garminClockLayouts, field(timeCol)); ok2 {
+				at = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local)
+				ok = true
+			}
+		}
+		if !ok {
+			emit(importRecord{}, fmt.Errorf("invalid time %q", field(timeCol)))
+			continue
+		}
+		emit(garminWeight(field(col["weight"]), at))
+		if hasFat {
+			if v := strings.TrimSpace(strings.TrimSuffix(field(fatCol), "%")); v != "" && v != "--" {
+				percent, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+				if err != nil {
+					emit(importRecord{}, fmt.Errorf("invalid body fat %q", field(fatCol)))
+					continue
+				}
+				emit(bodyFatRecord(percent, at), nil)
+			}
+		}
+	}
+}
+
+func parseGarminLayouts(layouts []string, s string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// garminWeight parses a weight such as "80.4 kg" or a bare "80.4" (kg).
+func garminWeight(s string, at time.Time) (importRecord, error) {
+	value, unit, _ := strings.Cut(s, " ")
+	v, err := parseTrackerWeight(value)
+	if err != nil {
+		return importRecord{}, err
+	}
+	u := "kg"
+	if unit = strings.TrimSpace(unit); unit != "" {
+		if u, err = trackerUnit(unit); err != nil {
+			return importRecord{}, err
+		}
+	}
+	return weightRecord(v, u, at), nil
+}
+
+// FIT protocol constants for the weight_scale message.
+const (
+	fitMesgWeightScale = 30
+	fitFieldTimestamp  = 253
+	fitFieldWeight     = 0
+	fitFieldPercentFat = 1
+	// fitEpoch is the FIT time origin, 1989-12-31T00:00:00Z, in Unix
+	// seconds.
+	fitEpoch = 631065600
+)
+
+// fitDefinition describes the layout of a local message type.
+type fitDefinition struct {
+	global uint16
+	order  binary.ByteOrder
+	fields []fitField
+	// devSize is the total size of developer fields, which are skipped.
+	devSize int
+}
+
+type fitField struct {
+	num, size byte
+}
+
+// parseFIT reads the weight_scale messages of one or more chained FIT
+// files. Other messages are skipped and checksums are not verified.
+func parseFIT(r io.Reader, emit func(importRecord, error)) error {
+	br := bufio.NewReader(r)
+	for {
+		if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err := parseFITFile(br, emit); err != nil {
+			return err
+		}
+	}
+}
+
+func parseFITFile(br *bufio.Reader, emit func(importRecord, error)) error {
+	size, err := br.ReadByte()
+	if err != nil {
+		return err
+	}
+	if size < 12 {
+		return fmt.Errorf("invalid FIT header size %d", size)
+	}
+	header := make([]byte, size-1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("read FIT header: %w", err)
+	}
+	if string(header[7:11]) != ".FIT" {
+		return errors.New("not a FIT file")
+	}
+	data := io.LimitReader(br, int64(binary.LittleEndian.Uint32(header[3:7])))
+
+	defs := map[byte]*fitDefinition{}
+	var lastTimestamp uint32
+	for {
+		h, err := readByte(data)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var local byte
+		var timestamp uint32
+		switch {
+		case h&0x80 != 0: // compressed timestamp header
+			local = (h >> 5) & 0x03
+			offset := uint32(h & 0x1f)
+			timestamp = lastTimestamp&^0x1f + offset
+			if offset < lastTimestamp&0x1f {
+				timestamp += 0x20
+			}
+		case h&0x40 != 0: // definition message
+			def, err := readFITDefinition(data, h&0x20 != 0)
+			if err != nil {
+				return err
+			}
+			defs[h&0x0f] = def
+			continue
+		default:
+			local = h & 0x0f
+		}
+
+		def, ok := defs[local]
+		if !ok {
+			return fmt.Errorf("FIT data message for undefined local type %d", local)
+		}
+		// 0xFFFF is FIT's invalid value, also standing for absent fields;
+		// a weight of 0xFFFE is one the scale was still calculating.
+		weight, fat := uint16(0xFFFF), uint16(0xFFFF)
+		for _, f := range def.fields {
+			b := make([]byte, f.size)
+			if _, err := io.ReadFull(data, b); err != nil {
+				return fmt.Errorf("read FIT message: %w", err)
+			}
+			switch {
+			case f.num == fitFieldTimestamp && f.size == 4:
+				timestamp = def.order.Uint32(b)
+			case f.num == fitFieldWeight && f.size == 2:
+				weight = def.order.Uint16(b)
+			case f.num == fitFieldPercentFat && f.size == 2:
+				fat = def.order.Uint16(b)
+			}
+		}
+		if _, err := io.CopyN(io.Discard, data, int64(def.devSize)); err != nil {
+			return fmt.Errorf("read FIT message: %w", err)
+		}
+		if timestamp != 0 {
+			lastTimestamp = timestamp
+		}
+		if def.global != fitMesgWeightScale {
+			continue
+		}
+
+		if timestamp == 0 {
+			emit(importRecord{}, errors.New("FIT weight without a timestamp"))
+			continue
+		}
+		at := time.Unix(fitEpoch+int64(timestamp), 0)
+		if weight != 0xFFFF && weight != 0xFFFE {
+			emit(weightRecord(float64(weight)/100, "kg", at), nil)
+		}
+		if fat != 0xFFFF && fat != 0 {
+			emit(bodyFatRecord(float64(fat)/100, at), nil)
+		}
+	}
+
+	// The file ends with a two-byte CRC.
+	if _, err := br.Discard(2); err != nil {
+		return fmt.Errorf("read FIT checksum: %w", err)
+	}
+	return nil
+}
+
+func readFITDefinition(r io.Reader, dev bool) (*fitDefinition, error) {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, fmt.Errorf("read FIT definition: %w", err)
+	}
+	def := &fitDefinition{order: binary.LittleEndian}
+	if head[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(head[2:4])
+	fields := make([]byte, 3*int(head[4]))
+	if _, err := io.ReadFull(r, fields); err != nil {
+		return nil, fmt.Errorf("read FIT definition: %w", err)
+	}
+	for i := 0; i < len(fields); i += 3 {
+		def.fields = append(def.fields, fitField{num: fields[i], size: fields[i+1]})
+	}
+	if dev {
+		n, err := readByte(r)
+		if err != nil {
+			return nil, fmt.Errorf("read FIT definition: %w", err)
+		}
+		devFields := make([]byte, 3*int(n))
+		if _, err := io.ReadFull(r, devFields); err != nil {
+			return nil, fmt.Errorf("read FIT definition: %w", err)
+		}
+		for i := 0; i < len(devFields); i += 3 {
+			def.devSize += int(devFields[i+1])
+		}
+	}
+	return def, nil
+}
+
+func readByte(r io.Reader) (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+	return b[0], err
+}
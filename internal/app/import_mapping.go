@@ -0,0 +1,191 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"vitals/internal/domain"
+)
+
+// CSVMapping describes how to read a CSV file exported by another app: which
+// header names the columns holding each field, and how to parse them.
+type CSVMapping struct {
+	// Kind is "weight" or "water" for a file holding a single kind of
+	// measurement. Empty reads each row's kind from the Type column.
+	Kind string `json:"kind,omitempty"`
+	// Type, Value, Unit and Time are the headers of the columns holding
+	// each field, matched ignoring case and surrounding space. Value and
+	// Time are required, and Type is when Kind is empty. Type values are
+	// "weight" or "water"; rows of any other type are errors.
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+	Time  string `json:"time"`
+	// DefaultUnit applies to rows without a unit: "kg" or "lb" for weight,
+	// a volume unit such as "ml" or "oz" for water.
+	DefaultUnit string `json:"defaultUnit,omitempty"`
+	// TimeLayout is the Go reference layout of the Time column, e.g.
+	// "02/01/2006 15:04". Empty accepts the layouts of the plain CSV format.
+	// Times without a zone are taken as local time.
+	TimeLayout string `json:"timeLayout,omitempty"`
+	// Delimiter is the field separator; empty means a comma. With any other
+	// separator, water amounts may use a decimal comma, as weights always
+	// may.
+	Delimiter string `json:"delimiter,omitempty"`
+}
+
+// validate checks the mapping on its own, before any file is read.
+func (m CSVMapping) validate() error {
+	switch m.Kind {
+	case "", "weight", "water":
+	default:
+		return fmt.Errorf("kind must be \"weight\" or \"water\", got %q", m.Kind)
+	}
+	if m.Kind == "" && strings.TrimSpace(m.Type) == "" {
+		return errors.New("mapping needs a type column or a fixed kind")
+	}
+	if strings.TrimSpace(m.Value) == "" || strings.TrimSpace(m.Time) == "" {
+		return errors.New("mapping needs value and time columns")
+	}
+	if m.Delimiter != "" {
+		if r, n := utf8.DecodeRuneInString(m.Delimiter); n != len(m.Delimiter) || r == '"' || r == '\r' || r == '\n' {
+			return fmt.Errorf("invalid delimiter %q", m.Delimiter)
+		}
+	}
+	return nil
+}
+
+func (m CSVMapping) reader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		cr.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+	return cr
+}
+
+// checkHeader reads the header line of r and checks that every mapped
+// column is in it.
+func (m CSVMapping) checkHeader(r io.Reader) error {
+	header, err := m.reader(r).Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	_, err = m.columns(header)
+	return err
+}
+
+// columns locates the mapped columns in header, keyed by their lowercased
+// names.
+func (m CSVMapping) columns(header []string) (map[string]int, error) {
+	for i, h := range header {
+		header[i] = strings.TrimPrefix(h, "\ufeff")
+	}
+	names := []string{m.key(m.Value), m.key(m.Time)}
+	for _, name := range []string{m.key(m.Type), m.key(m.Unit)} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return csvColumns(header, names...)
+}
+
+// key normalizes a mapped column name the way csvColumns does headers.
+func (m CSVMapping) key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// parser returns a recordParser reading files laid out as m describes.
+func (m CSVMapping) parser() (recordParser, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return func(r io.Reader, emit func(importRecord, error)) error {
+		cr := m.reader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return fmt.Errorf("read header: %w", err)
+		}
+		col, err := m.columns(header)
+		if err != nil {
+			return err
+		}
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			emit(m.record(col, row))
+		}
+	}, nil
+}
+
+func (m CSVMapping) record(col map[string]int, row []string) (importRecord, error) {
+	field := func(name string) string {
+		i, ok := col[m.key(name)]
+		if name == "" || !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	raw := field(m.Time)
+	var at time.Time
+	var err error
+	if m.TimeLayout != "" {
+		at, err = time.ParseInLocation(m.TimeLayout, raw, time.Local)
+	} else {
+		for _, layout := range csvTimeLayouts {
+			if at, err = time.ParseInLocation(layout, raw, time.Local); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return importRecord{}, fmt.Errorf("invalid time %q", raw)
+	}
+
+	kind := m.Kind
+	if kind == "" {
+		kind = strings.ToLower(field(m.Type))
+	}
+	unit := field(m.Unit)
+	if unit == "" {
+		unit = m.DefaultUnit
+	}
+	value := field(m.Value)
+	if m.Delimiter != "" && m.Delimiter != "," {
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+
+	switch kind {
+	case "weight":
+		v, err := parseTrackerWeight(value)
+		if err != nil {
+			return importRecord{}, err
+		}
+		u, err := trackerUnit(unit)
+		if err != nil {
+			return importRecord{}, err
+		}
+		return weightRecord(v, u, at), nil
+	case "water":
+		liters, err := domain.ParseVolume(value + strings.ToLower(unit))
+		if err != nil {
+			return importRecord{}, err
+		}
+		rec := importRecord{Kind: kind, Value: liters, Unit: "l", At: at}
+		rec.Key = dedupKey(rec)
+		return rec, nil
+	}
+	return importRecord{}, fmt.Errorf("unknown type %q", kind)
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Import formats accepted by ImportService.
+const (
+	ImportFormatCSV         = "csv"
+	ImportFormatAppleHealth = "apple-health"
+)
+
+// importRecord is one measurement read from an import file.
+type importRecord struct {
+	// Kind is "weight" or "water".
+	Kind string
+	// Value is in Unit for weight and in liters for water.
+	Value float64
+	Unit  string
+	At    time.Time
+	// Key, when set, deduplicates the record: a record whose key was
+	// already imported for the user is skipped.
+	Key string
+}
+
+// recordParser streams records from r, calling emit once per row. A non-nil
+// row error is reported through emit and parsing continues; the returned
+// error means the file as a whole could not be read.
+type recordParser func(r io.Reader, emit func(rec importRecord, err error)) error
+
+func parserFor(format string) (recordParser, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCSV, nil
+	case ImportFormatAppleHealth:
+		return parseAppleHealth, nil
+	case ImportFormatLibra:
+		return parseLibra, nil
+	case ImportFormatFitNotes:
+		return parseFitNotes, nil
+	case ImportFormatWger:
+		return parseWger, nil
+	case ImportFormatGarmin:
+		return parseGarmin, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// csvTimeLayouts are the timestamp layouts accepted in CSV imports.
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// parseCSV reads rows of type,value,unit,timestamp with a header line, e.g.
+// "weight,80.4,kg,2026-01-05T07:10:00Z" or "water,250,ml,2026-01-05 09:30".
+// Timestamps without a zone are taken as local time.
+func parseCSV(r io.Reader, emit func(importRecord, error)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	if _, err := cr.Read(); err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) && errors.Is(perr.Err, csv.ErrFieldCount) {
+				emit(importRecord{}, err)
+				continue
+			}
+			return err
+		}
+		emit(csvRecord(row))
+	}
+}
+
+func csvRecord(row []string) (importRecord, error) {
+	var at time.Time
+	var err error
+	for _, layout := range csvTimeLayouts {
+		if at, err = time.ParseInLocation(layout, row[3], time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return importRecord{}, fmt.Errorf("invalid timestamp %q", row[3])
+	}
+
+	kind, unit := strings.ToLower(row[0]), strings.ToLower(row[2])
+	switch kind {
+	case "weight":
+		v, err := strconv.ParseFloat(row[1], 64)
+		if err != nil {
+			return importRecord{}, fmt.Errorf("invalid value %q", row[1])
+		}
+		return importRecord{Kind: kind, Value: v, Unit: unit, At: at}, nil
+	case "water":
+		liters, err := domain.ParseVolume(row[1] + unit)
+		if err != nil {
+			return importRecord{}, err
+		}
+		return importRecord{Kind: kind, Value: liters, Unit: "l", At: at}, nil
+	}
+	return importRecord{}, fmt.Errorf("unknown type %q", row[0])
+}
+
+// appleHealthUnits maps Apple Health water units onto ParseVolume suffixes.
+var appleHealthUnits = map[string]string{"mL": "ml", "L": "l", "fl_oz_us": "oz"}
+
+// parseAppleHealth reads body mass and dietary water records from an Apple
+// Health export.xml, skipping every other record type.
+func parseAppleHealth(r io.Reader, emit func(importRecord, error)) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "Record" {
+			continue
+		}
+		attrs := make(map[string]string, len(el.Attr))
+		for _, a := range el.Attr {
+			attrs[a.Name.Local] = a.Value
+		}
+
+		var kind string
+		switch attrs["type"] {
+		case "HKQuantityTypeIdentifierBodyMass":
+			kind = "weight"
+		case "HKQuantityTypeIdentifierDietaryWater":
+			kind = "water"
+		default:
+			continue
+		}
+		emit(appleHealthRecord(kind, attrs))
+	}
+}
+
+func appleHealthRecord(kind string, attrs map[string]string) (importRecord, error) {
+	at, err := time.Parse("2006-01-02 15:04:05 -0700", attrs["startDate"])
+	if err != nil {
+		return importRecord{}, fmt.Errorf("invalid startDate %q", attrs["startDate"])
+	}
+	if kind == "water" {
+		suffix, ok := appleHealthUnits[attrs["unit"]]
+		if !ok {
+			return importRecord{}, fmt.Errorf("unsupported water unit %q", attrs["unit"])
+		}
+		liters, err := domain.ParseVolume(attrs["value"] + suffix)
+		if err != nil {
+			return importRecord{}, err
+		}
+		return importRecord{Kind: kind, Value: liters, Unit: "l", At: at}, nil
+	}
+
+	v, err := strconv.ParseFloat(attrs["value"], 64)
+	if err != nil {
+		return importRecord{}, fmt.Errorf("invalid value %q", attrs["value"])
+	}
+	unit := attrs["unit"]
+	if unit == "lb" || unit == "kg" {
+		return importRecord{Kind: kind, Value: v, Unit: unit, At: at}, nil
+	}
+	return importRecord{}, fmt.Errorf("unsupported weight unit %q", unit)
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Plausibility limits for dry runs. Rows outside them are still valid, but
+// are flagged as warnings: they are usually a mistyped value or a wrong unit.
+const (
+	importMinKg = 20
+	importMaxKg = 300
+	// importMaxWaterLiters bounds a single water event, either way.
+	importMaxWaterLiters = 3
+)
+
+// ImportReport summarizes what importing a file would store, as returned by
+// a dry run.
+type ImportReport struct {
+	RowsProcessed int `json:"rowsProcessed"`
+	// RowsValid counts the rows that would be imported: Weight weigh-ins,
+	// Water water events and BodyFat body fat readings.
+	RowsValid  int      `json:"rowsValid"`
+	Weight     int      `json:"weight"`
+	Water      int      `json:"water"`
+	BodyFat    int      `json:"bodyFat"`
+	ErrorCount int      `json:"errorCount"`
+	Errors     []string `json:"errors"`
+	// WarningCount and Warnings flag valid rows that are likely mistakes:
+	// values outside a plausible range, times in the future, and rows at
+	// the same time as an earlier row of the same kind. Rows with unknown
+	// units cannot be imported and are listed in Errors.
+	WarningCount int      `json:"warningCount"`
+	Warnings     []string `json:"warnings"`
+	// From and To span the valid rows' times.
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+
+	// seen maps the kind and time of each valid row to its row number.
+	seen map[string]int
+}
+
+// dryRun reads data with parse and reports every row, storing nothing.
+func dryRun(parse recordParser, data []byte) (*ImportReport, error) {
+	report := &ImportReport{Errors: []string{}, Warnings: []string{}, seen: map[string]int{}}
+	now := time.Now()
+	err := parse(bytes.NewReader(data), func(rec importRecord, rowErr error) {
+		report.RowsProcessed++
+		if rowErr == nil {
+			rowErr = checkRecord(rec)
+		}
+		if rowErr != nil {
+			report.ErrorCount++
+			if len(report.Errors) < importMaxErrors {
+				report.Errors = append(report.Errors, fmt.Sprintf("row %d: %v", report.RowsProcessed, rowErr))
+			}
+			return
+		}
+		report.add(rec)
+		report.check(rec, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *ImportReport) add(rec importRecord) {
+	r.RowsValid++
+	switch rec.Kind {
+	case "weight":
+		r.Weight++
+	case "water":
+		r.Water++
+	case "bodyfat":
+		r.BodyFat++
+	}
+	at := rec.At
+	if r.From == nil || at.Before(*r.From) {
+		r.From = &at
+	}
+	if r.To == nil || at.After(*r.To) {
+		r.To = &at
+	}
+}
+
+// check records warnings for the valid row rec, the latest one processed.
+func (r *ImportReport) check(rec importRecord, now time.Time) {
+	row := r.RowsProcessed
+	switch rec.Kind {
+	case "weight":
+		if kg := domain.ConvertWeight(rec.Value, rec.Unit, "kg"); kg < importMinKg || kg > importMaxKg {
+			r.warn(row, fmt.Sprintf("weight %g %s is outside the plausible range of %d to %d kg", rec.Value, rec.Unit, importMinKg, importMaxKg))
+		}
+	case "water":
+		if math.Abs(rec.Value) > importMaxWaterLiters {
+			r.warn(row, fmt.Sprintf("water amount %g l is over %d l", rec.Value, importMaxWaterLiters))
+		}
+	}
+	if rec.At.After(now) {
+		r.warn(row, "time is in the future")
+	}
+	key := fmt.Sprintf("%s %d", rec.Kind, rec.At.UnixNano())
+	if first, ok := r.seen[key]; ok {
+		r.warn(row, fmt.Sprintf("same %s time as row %d", rec.Kind, first))
+	} else {
+		r.seen[key] = row
+	}
+}
+
+func (r *ImportReport) warn(row int, msg string) {
+	r.WarningCount++
+	if len(r.Warnings) < importMaxErrors {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("row %d: %s", row, msg))
+	}
+}
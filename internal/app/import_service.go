@@ -0,0 +1,485 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrJobNotFound indicates that an import job does not exist or belongs to
+// another user.
+var ErrJobNotFound = errors.New("import job not found")
+
+// ErrBatchNotFound indicates that no events of an import batch remain for
+// the user.
+var ErrBatchNotFound = errors.New("import batch not found")
+
+// ErrImportRunning indicates that an import batch cannot be rolled back
+// while its job is still writing it.
+var ErrImportRunning = errors.New("import is still running")
+
+// Import job states.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	// importProgressEvery is how many rows pass between progress updates.
+	importProgressEvery = 100
+	// importMaxErrors caps the row errors kept on a job.
+	importMaxErrors = 100
+	// importJobTTL is how long finished jobs remain queryable.
+	importJobTTL = 24 * time.Hour
+	// importStoreTimeout bounds each row's write; the job itself has no
+	// request deadline.
+	importStoreTimeout = 10 * time.Second
+	// topicImportJob carries job snapshots between instances.
+	topicImportJob = "import.job"
+	// clusterJobErrors caps the row errors sent with a snapshot, keeping
+	// cluster messages small.
+	clusterJobErrors = 10
+)
+
+// ImportJob reports the progress of a background import.
+type ImportJob struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"userId"`
+	Format string `json:"format"`
+	// BatchID names the import batch the job's rows are stored in, for
+	// rolling it back; empty when batches are not tracked.
+	BatchID       string `json:"batchId,omitempty"`
+	Status        string `json:"status"`
+	RowsProcessed int    `json:"rowsProcessed"`
+	RowsImported  int    `json:"rowsImported"`
+	// RowsSkipped counts rows already imported before, or identical to an
+	// existing event when batches are tracked.
+	RowsSkipped int        `json:"rowsSkipped"`
+	ErrorCount  int        `json:"errorCount"`
+	Errors      []string   `json:"errors"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Done reports whether the job has finished.
+func (j ImportJob) Done() bool {
+	return j.Status != JobRunning
+}
+
+// ImportService runs imports of weight and water history as background jobs.
+// Jobs are tracked in memory and do not survive a restart.
+type ImportService struct {
+	weight  domain.WeightRepository
+	water   domain.WaterRepository
+	batches domain.ImportRepository
+	metrics *MetricsService
+	cluster domain.Cluster
+
+	mu   sync.Mutex
+	jobs map[string]*importJob
+}
+
+type importJob struct {
+	ImportJob
+	watchers []chan ImportJob
+	// remote marks a job running on another instance, mirrored from its
+	// cluster messages.
+	remote bool
+}
+
+// NewImportService creates an ImportService backed by the given repositories.
+func NewImportService(weight domain.WeightRepository, water domain.WaterRepository) *ImportService {
+	return &ImportService{weight: weight, water: water, jobs: make(map[string]*importJob)}
+}
+
+// WithBatches stores each job's rows as an import batch named by the job ID,
+// so Rollback can undo the whole import. Rows with the same timestamp and
+// value as an existing event are skipped, so re-importing a file does not
+// duplicate its data.
+func (s *ImportService) WithBatches(repo domain.ImportRepository) *ImportService {
+	s.batches = repo
+	return s
+}
+
+// WithMetrics records the body fat readings of formats that carry them in
+// the user's "body-fat" custom metric, defining it on first use. Without
+// it they are skipped. Body fat readings are deduplicated like tracker
+// rows but are not part of an import batch, so a rollback keeps them.
+func (s *ImportService) WithMetrics(m *MetricsService) *ImportService {
+	s.metrics = m
+	return s
+}
+
+// WithCluster shares job progress with the other instances in c, so a job's
+// status and events can be read from any of them, not only the one running
+// it.
+func (s *ImportService) WithCluster(c domain.Cluster) *ImportService {
+	s.cluster = c
+	c.Subscribe(topicImportJob, s.applyRemote)
+	return s
+}
+
+// Start validates the format and begins importing data for userID in the
+// background, returning the new job immediately.
+func (s *ImportService) Start(ctx context.Context, userID int64, format string, data []byte) (ImportJob, error) {
+	if err := checkWritable(ctx); err != nil {
+		return ImportJob{}, err
+	}
+	parse, err := parserFor(format)
+	if err != nil {
+		return ImportJob{}, err
+	}
+	return s.start(userID, format, parse, data)
+}
+
+// StartCSV begins importing a CSV file from another app for userID in the
+// background, reading its columns as m describes, and returns the new job
+// immediately. A mapping that does not fit the file's header is rejected up
+// front.
+func (s *ImportService) StartCSV(ctx context.Context, userID int64, m CSVMapping, data []byte) (ImportJob, error) {
+	if err := checkWritable(ctx); err != nil {
+		return ImportJob{}, err
+	}
+	parse, err := m.parser()
+	if err != nil {
+		return ImportJob{}, err
+	}
+	if err := m.checkHeader(bytes.NewReader(data)); err != nil {
+		return ImportJob{}, err
+	}
+	return s.start(userID, ImportFormatCSV, parse, data)
+}
+
+// DryRun reads data as Start would and reports what importing it would
+// store, without writing anything. Rows duplicating existing events are not
+// detected and count as valid.
+func (s *ImportService) DryRun(format string, data []byte) (*ImportReport, error) {
+	parse, err := parserFor(format)
+	if err != nil {
+		return nil, err
+	}
+	return dryRun(parse, data)
+}
+
+// DryRunCSV reads a CSV file as StartCSV would and reports what importing it
+// would store, like DryRun.
+func (s *ImportService) DryRunCSV(m CSVMapping, data []byte) (*ImportReport, error) {
+	parse, err := m.parser()
+	if err != nil {
+		return nil, err
+	}
+	return dryRun(parse, data)
+}
+
+func (s *ImportService) start(userID int64, format string, parse recordParser, data []byte) (ImportJob, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ImportJob{}, err
+	}
+	job := &importJob{ImportJob: ImportJob{
+		ID:        hex.EncodeToString(b),
+		UserID:    userID,
+		Format:    format,
+		Status:    JobRunning,
+		Errors:    []string{},
+		CreatedAt: time.Now(),
+	}}
+	if s.batches != nil {
+		job.BatchID = job.ID
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	s.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	s.publish(snapshot)
+	go s.run(job, parse, data)
+	return snapshot, nil
+}
+
+// Get returns the current state of one of userID's jobs.
+func (s *ImportService) Get(userID int64, id string) (ImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return ImportJob{}, ErrJobNotFound
+	}
+	return job.snapshot(), nil
+}
+
+// Rollback deletes every event userID's import batch id stored and returns
+// how many it removed. A batch outlives its job, so it can be rolled back
+// after the job is forgotten, but not while the job is still running.
+func (s *ImportService) Rollback(ctx context.Context, userID int64, id string) (int, error) {
+	if err := checkWritable(ctx); err != nil {
+		return 0, err
+	}
+	if s.batches == nil {
+		return 0, ErrBatchNotFound
+	}
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	running := ok && job.UserID == userID && !job.Done()
+	s.mu.Unlock()
+	if running {
+		return 0, ErrImportRunning
+	}
+	n, err := s.batches.DeleteImportBatch(ctx, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrBatchNotFound
+	}
+	return n, nil
+}
+
+// Watch streams snapshots of one of userID's jobs: the current state at once,
+// then each progress update. The channel closes after the final state or when
+// ctx ends. Slow readers only ever miss intermediate states.
+func (s *ImportService) Watch(ctx context.Context, userID int64, id string) (<-chan ImportJob, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		s.mu.Unlock()
+		return nil, ErrJobNotFound
+	}
+	ch := make(chan ImportJob, 1)
+	ch <- job.snapshot()
+	if job.Done() {
+		close(ch)
+		s.mu.Unlock()
+		return ch, nil
+	}
+	job.watchers = append(job.watchers, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range job.watchers {
+			if w == ch {
+				job.watchers = append(job.watchers[:i], job.watchers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (s *ImportService) run(job *importJob, parse recordParser, data []byte) {
+	// The job outlives the request that started it.
+	ctx := context.Background()
+	userID := job.UserID
+
+	err := parse(bytes.NewReader(data), func(rec importRecord, rowErr error) {
+		created := false
+		if rowErr == nil {
+			created, rowErr = s.store(ctx, userID, job.BatchID, rec)
+		}
+		s.update(job, func(j *ImportJob) bool {
+			j.RowsProcessed++
+			switch {
+			case rowErr != nil:
+				j.ErrorCount++
+				if len(j.Errors) < importMaxErrors {
+					j.Errors = append(j.Errors, fmt.Sprintf("row %d: %v", j.RowsProcessed, rowErr))
+				}
+			case created:
+				j.RowsImported++
+			default:
+				j.RowsSkipped++
+			}
+			return j.RowsProcessed%importProgressEvery == 0
+		})
+	})
+
+	s.update(job, func(j *ImportJob) bool {
+		now := time.Now()
+		j.FinishedAt = &now
+		j.Status = JobSucceeded
+		if err != nil {
+			j.Status = JobFailed
+			j.ErrorCount++
+			j.Errors = append(j.Errors, err.Error())
+		}
+		return true
+	})
+}
+
+// store writes rec, reporting false if its dedup key was already imported
+// or, with batches, if it duplicates an existing event.
+func (s *ImportService) store(ctx context.Context, userID int64, batchID string, rec importRecord) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, importStoreTimeout)
+	defer cancel()
+
+	var clientID string
+	if rec.Key != "" {
+		clientID = importClientID(rec.Key)
+	}
+	if err := checkRecord(rec); err != nil {
+		return false, err
+	}
+	switch rec.Kind {
+	case "bodyfat":
+		if s.metrics == nil {
+			return false, nil
+		}
+		m, err := s.metrics.ensure(ctx, userID, bodyFatMetric)
+		if err != nil {
+			return false, err
+		}
+		_, created, err := s.metrics.Record(ctx, userID, m.Slug, domain.CustomMetricEvent{ClientID: clientID, Value: rec.Value, CreatedAt: rec.At})
+		return created, err
+	case "weight":
+		if s.batches != nil {
+			return s.batches.ImportWeightEvent(ctx, userID, batchID, clientID, rec.Value, rec.Unit, rec.At)
+		}
+		if clientID != "" {
+			_, created, err := s.weight.AddWeightEventWithClientID(ctx, userID, clientID, rec.Value, rec.Unit, rec.At)
+			return created, err
+		}
+		_, err := s.weight.AddWeightEvent(ctx, userID, rec.Value, rec.Unit, rec.At)
+		return err == nil, err
+	case "water":
+		if s.batches != nil {
+			return s.batches.ImportWaterEvent(ctx, userID, batchID, clientID, rec.Value, rec.At)
+		}
+		if clientID != "" {
+			_, created, err := s.water.AddWaterEventWithClientID(ctx, userID, clientID, rec.Value, rec.At)
+			return created, err
+		}
+		_, err := s.water.AddWaterEvent(ctx, userID, rec.Value, rec.At)
+		return err == nil, err
+	}
+	return false, fmt.Errorf("unknown record kind %q", rec.Kind)
+}
+
+// checkRecord validates rec's value and unit for its kind.
+func checkRecord(rec importRecord) error {
+	switch rec.Kind {
+	case "weight":
+		if rec.Value <= 0 {
+			return errors.New("value must be > 0")
+		}
+		if rec.Unit != "kg" && rec.Unit != "lb" {
+			return errors.New("unit must be \"kg\" or \"lb\"")
+		}
+	case "water":
+		if rec.Value == 0 || rec.Value < -10 || rec.Value > 10 {
+			return errors.New("water amount must be non-zero and within [-10, 10] liters")
+		}
+	case "bodyfat":
+		if rec.Value <= 0 || rec.Value >= 100 {
+			return errors.New("body fat must be between 0 and 100 percent")
+		}
+	}
+	return nil
+}
+
+// update applies fn to the job and, if fn asks for it or the job finished,
+// notifies watchers and the other instances.
+func (s *ImportService) update(job *importJob, fn func(*ImportJob) bool) {
+	s.mu.Lock()
+	if !fn(&job.ImportJob) && !job.Done() {
+		s.mu.Unlock()
+		return
+	}
+	snap := s.notifyLocked(job)
+	s.mu.Unlock()
+	s.publish(snap)
+}
+
+// notifyLocked sends the job's current state to its watchers, closing them
+// once it is done, and returns that state. Callers hold s.mu.
+func (s *ImportService) notifyLocked(job *importJob) ImportJob {
+	snap := job.snapshot()
+	for _, w := range job.watchers {
+		select {
+		case <-w: // drop the stale snapshot
+		default:
+		}
+		w <- snap
+		if snap.Done() {
+			close(w)
+		}
+	}
+	if snap.Done() {
+		job.watchers = nil
+	}
+	return snap
+}
+
+// publish sends a snapshot to the other instances. Delivery is best effort:
+// a lost update is superseded by the next one.
+func (s *ImportService) publish(job ImportJob) {
+	if s.cluster == nil {
+		return
+	}
+	if len(job.Errors) > clusterJobErrors {
+		job.Errors = job.Errors[:clusterJobErrors]
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), importStoreTimeout)
+	defer cancel()
+	_ = s.cluster.Publish(ctx, topicImportJob, payload)
+}
+
+// applyRemote mirrors a snapshot of a job running on another instance.
+// Snapshots may arrive out of order, so older progress is ignored.
+func (s *ImportService) applyRemote(payload []byte) {
+	var snap ImportJob
+	if err := json.Unmarshal(payload, &snap); err != nil || snap.ID == "" {
+		return
+	}
+	if snap.Errors == nil {
+		snap.Errors = []string{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[snap.ID]
+	if !ok {
+		s.pruneLocked()
+		job = &importJob{remote: true}
+		s.jobs[snap.ID] = job
+	} else if !job.remote || job.Done() || (!snap.Done() && snap.RowsProcessed < job.RowsProcessed) {
+		return
+	}
+	job.ImportJob = snap
+	s.notifyLocked(job)
+}
+
+// pruneLocked forgets finished jobs past their TTL, and remote jobs whose
+// instance stopped reporting them. Callers hold s.mu.
+func (s *ImportService) pruneLocked() {
+	cutoff := time.Now().Add(-importJobTTL)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) ||
+			job.remote && job.CreatedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func (j *importJob) snapshot() ImportJob {
+	snap := j.ImportJob
+	snap.Errors = append([]string{}, j.Errors...)
+	return snap
+}
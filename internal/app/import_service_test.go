@@ -0,0 +1,531 @@
+package app_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func waitForJob(t *testing.T, svc *app.ImportService, userID int64, id string) app.ImportJob {
+	t.Helper()
+	updates, err := svc.Watch(context.Background(), userID, id)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	var last app.ImportJob
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case job, ok := <-updates:
+			if !ok {
+				return last
+			}
+			last = job
+		case <-timeout:
+			t.Fatal("timed out waiting for import job")
+		}
+	}
+}
+
+func TestImportService_CSV(t *testing.T) {
+	var mu sync.Mutex
+	var weights []float64
+	var water []float64
+	wr := &mockWeightRepo{
+		addFn: func(_ context.Context, _ int64, v float64, _ string, _ time.Time) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			weights = append(weights, v)
+			return 1, nil
+		},
+	}
+	wa := &mockWaterRepo{
+		addFn: func(_ context.Context, _ int64, d float64, _ time.Time) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			water = append(water, d)
+			return 1, nil
+		},
+	}
+	svc := app.NewImportService(wr, wa)
+
+	data := strings.Join([]string{
+		"type,value,unit,timestamp",
+		"weight,80.4,kg,2026-01-05T07:10:00Z",
+		"water,250,ml,2026-01-05 09:30",
+		"water,0.5,l,2026-01-05",
+		"weight,-1,kg,2026-01-06",
+		"steps,9000,count,2026-01-06",
+		"weight,80,kg,yesterday",
+	}, "\n")
+	job, err := svc.Start(context.Background(), 1, app.ImportFormatCSV, []byte(data))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if job.ID == "" || job.Status != app.JobRunning {
+		t.Fatalf("unexpected initial job: %+v", job)
+	}
+
+	final := waitForJob(t, svc, 1, job.ID)
+	if final.Status != app.JobSucceeded || final.RowsProcessed != 6 || final.RowsImported != 3 || final.ErrorCount != 3 {
+		t.Fatalf("unexpected final job: %+v", final)
+	}
+	if len(weights) != 1 || len(water) != 2 || water[0] != 0.25 {
+		t.Fatalf("unexpected stored records: weights=%v water=%v", weights, water)
+	}
+
+	if _, err := svc.Get(2, job.ID); !errors.Is(err, app.ErrJobNotFound) {
+		t.Fatalf("expected other users not to see the job, got %v", err)
+	}
+}
+
+func TestImportService_DryRun(t *testing.T) {
+	wr, wa := &mockWeightRepo{}, &mockWaterRepo{}
+	svc := app.NewImportService(wr, wa)
+
+	future := time.Now().Add(48 * time.Hour).Format("2006-01-02")
+	data := strings.Join([]string{
+		"type,value,unit,timestamp",
+		"weight,80.4,kg,2026-01-05 07:10",
+		"weight,80.6,kg,2026-01-05 07:10",
+		"weight,804,kg,2026-01-06",
+		"weight,12,lb,2026-01-07",
+		"water,5,l,2026-01-07",
+		"weight,80,stone,2026-01-08",
+		"water,250,ml," + future,
+	}, "\n")
+	report, err := svc.DryRun(app.ImportFormatCSV, []byte(data))
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if report.RowsProcessed != 7 || report.RowsValid != 6 || report.ErrorCount != 1 || report.WarningCount != 5 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !strings.HasPrefix(report.Errors[0], "row 6: ") {
+		t.Errorf("expected the unknown unit reported on row 6, got %v", report.Errors)
+	}
+	for i, want := range []string{
+		"row 2: same weight time as row 1",
+		"row 3: weight 804 kg is outside",
+		"row 4: weight 12 lb is outside",
+		"row 5: water amount 5 l is over",
+		"row 7: time is in the future",
+	} {
+		if !strings.HasPrefix(report.Warnings[i], want) {
+			t.Errorf("warning %d = %q, want prefix %q", i, report.Warnings[i], want)
+		}
+	}
+	if _, err := svc.DryRun("numbers", []byte(data)); err == nil {
+		t.Error("expected an unknown format rejected")
+	}
+}
+
+func TestImportService_AppleHealth(t *testing.T) {
+	var mu sync.Mutex
+	var units []string
+	wr := &mockWeightRepo{
+		addFn: func(_ context.Context, _ int64, _ float64, u string, _ time.Time) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			units = append(units, u)
+			return 1, nil
+		},
+	}
+	svc := app.NewImportService(wr, &mockWaterRepo{})
+
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<HealthData locale="en_US">
+ <Record type="HKQuantityTypeIdentifierBodyMass" unit="lb" value="180.2" startDate="2026-01-05 07:00:00 -0800"/>
+ <Record type="HKQuantityTypeIdentifierDietaryWater" unit="mL" value="330" startDate="2026-01-05 09:00:00 -0800"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" unit="count" value="9000" startDate="2026-01-05 09:00:00 -0800"/>
+</HealthData>`
+	job, err := svc.Start(context.Background(), 1, app.ImportFormatAppleHealth, []byte(data))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	final := waitForJob(t, svc, 1, job.ID)
+	if final.Status != app.JobSucceeded || final.RowsImported != 2 || final.ErrorCount != 0 {
+		t.Fatalf("unexpected final job: %+v", final)
+	}
+	if len(units) != 1 || units[0] != "lb" {
+		t.Fatalf("unexpected weight units: %v", units)
+	}
+}
+
+func TestImportService_Trackers(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var stored []string
+	wr := &mockWeightRepo{
+		addClientFn: func(_ context.Context, _ int64, clientID string, v float64, u string, at time.Time) (*domain.WeightEntry, bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[clientID] {
+				return &domain.WeightEntry{}, false, nil
+			}
+			seen[clientID] = true
+			stored = append(stored, fmt.Sprintf("%g %s %s", v, u, at.UTC().Format(time.RFC3339)))
+			return &domain.WeightEntry{}, true, nil
+		},
+	}
+	svc := app.NewImportService(wr, &mockWaterRepo{})
+	run := func(format, data string) app.ImportJob {
+		t.Helper()
+		job, err := svc.Start(context.Background(), 1, format, []byte(data))
+		if err != nil {
+			t.Fatalf("Start(%s): %v", format, err)
+		}
+		return waitForJob(t, svc, 1, job.ID)
+	}
+
+	libra := "#Version:6\n#Units:lb\n#date;weight;weight trend;body fat;body fat trend;muscle mass;muscle mass trend;log\n" +
+		"2026-01-05T07:00:00.000Z;180.4;180.4;;;;;\n" +
+		"2026-01-06T07:00:00.000Z;oops;;;;;;\n"
+	if job := run(app.ImportFormatLibra, libra); job.Status != app.JobSucceeded || job.RowsImported != 1 || job.ErrorCount != 1 {
+		t.Fatalf("unexpected libra job: %+v", job)
+	}
+	// Importing the same backup again stores nothing new.
+	if job := run(app.ImportFormatLibra, libra); job.RowsImported != 0 || job.RowsSkipped != 1 {
+		t.Fatalf("expected the re-import to be skipped, got %+v", job)
+	}
+
+	fitnotes := "Date,Time,Measurement,Value,Unit,Comment\n" +
+		"2026-01-07,07:30:00,Bodyweight,81.5,kgs,\n" +
+		"2026-01-07,07:30:00,Body Fat,18,%,\n"
+	if job := run(app.ImportFormatFitNotes, fitnotes); job.RowsProcessed != 1 || job.RowsImported != 1 {
+		t.Fatalf("unexpected fitnotes job: %+v", job)
+	}
+
+	wgerJSON := `{"count": 2, "results": [{"id": 1, "date": "2026-01-08T06:00:00Z", "weight": "81.20"}, {"id": 2, "date": "2026-01-05T07:00:00Z", "weight": "81.8"}]}`
+	if job := run(app.ImportFormatWger, wgerJSON); job.RowsImported != 2 {
+		t.Fatalf("unexpected wger JSON job: %+v", job)
+	}
+	if job := run(app.ImportFormatWger, "Date,Weight\n2026-01-08T06:00:00Z,81.2\n"); job.RowsSkipped != 1 {
+		t.Fatalf("expected the wger CSV to overlap the JSON, got %+v", job)
+	}
+
+	want := []string{
+		"180.4 lb 2026-01-05T07:00:00Z",
+		"81.2 kg 2026-01-08T06:00:00Z",
+		"81.8 kg 2026-01-05T07:00:00Z",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stored) != 4 || stored[0] != want[0] || stored[2] != want[1] || stored[3] != want[2] {
+		t.Fatalf("unexpected stored weights: %v", stored)
+	}
+
+	if job := run(app.ImportFormatLibra, "2026-01-05T07:00:00Z;80\n"); job.Status != app.JobFailed {
+		t.Fatalf("expected a file without the Libra header to fail, got %+v", job)
+	}
+}
+
+// fitWeightFile builds a FIT file of weight_scale messages, each a FIT
+// timestamp, weight in 1/100 kg and body fat in 1/100 %.
+func fitWeightFile(msgs ...[3]uint32) []byte {
+	var data []byte
+	// Definition of local message 0: global message 30 with timestamp,
+	// weight and percent_fat fields.
+	data = append(data, 0x40, 0, 0, 30, 0, 3, 253, 4, 0x86, 0, 2, 0x84, 1, 2, 0x84)
+	for _, m := range msgs {
+		data = append(data, 0)
+		data = binary.LittleEndian.AppendUint32(data, m[0])
+		data = binary.LittleEndian.AppendUint16(data, uint16(m[1]))
+		data = binary.LittleEndian.AppendUint16(data, uint16(m[2]))
+	}
+	header := []byte{14, 0x20, 0x52, 0x08}
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	header = append(header, '.', 'F', 'I', 'T', 0, 0)
+	return append(append(header, data...), 0, 0)
+}
+
+func TestImportService_Garmin(t *testing.T) {
+	var mu sync.Mutex
+	var stored []string
+	wr := &mockWeightRepo{
+		addClientFn: func(_ context.Context, _ int64, _ string, v float64, u string, at time.Time) (*domain.WeightEntry, bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			stored = append(stored, fmt.Sprintf("%g %s %s", v, u, at.In(time.Local).Format("2006-01-02 15:04")))
+			return &domain.WeightEntry{}, true, nil
+		},
+	}
+	metrics := &mockMetricRepo{}
+	svc := app.NewImportService(wr, &mockWaterRepo{}).WithMetrics(app.NewMetricsService(metrics))
+	run := func(data []byte) app.ImportJob {
+		t.Helper()
+		job, err := svc.Start(context.Background(), 1, app.ImportFormatGarmin, data)
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		return waitForJob(t, svc, 1, job.ID)
+	}
+
+	csv := "Time,Weight,Change,BMI,Body Fat,Skeletal Muscle Mass,Bone Mass,Body Water,\n" +
+		"\" Jan 5, 2026\",\n" +
+		"\" 7:10 AM\",80.4 kg,0.2 kg,24.1,18.5 %,35.2 kg,3.3 kg,55.0 %,\n" +
+		"\" 9:30 PM\",177.2 lbs,--,--,--,--,--,--,\n" +
+		"noon,80 kg,,,,,,,\n"
+	job := run([]byte(csv))
+	if job.Status != app.JobSucceeded || job.RowsImported != 3 || job.ErrorCount != 1 {
+		t.Fatalf("unexpected CSV job: %+v", job)
+	}
+
+	fit := fitWeightFile(
+		[3]uint32{uint32(time.Date(2026, 1, 6, 7, 0, 0, 0, time.Local).Unix() - 631065600), 8030, 1840},
+		[3]uint32{uint32(time.Date(2026, 1, 7, 7, 0, 0, 0, time.Local).Unix() - 631065600), 8010, 0xFFFF},
+	)
+	if job := run(fit); job.Status != app.JobSucceeded || job.RowsImported != 3 || job.ErrorCount != 0 {
+		t.Fatalf("unexpected FIT job: %+v", job)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"80.4 kg 2026-01-05 07:10", "177.2 lb 2026-01-05 21:30", "80.3 kg 2026-01-06 07:00", "80.1 kg 2026-01-07 07:00"}
+	if strings.Join(stored, "; ") != strings.Join(want, "; ") {
+		t.Errorf("stored weights %v, want %v", stored, want)
+	}
+	if len(metrics.metrics) != 1 || metrics.metrics[0].Slug != "body-fat" || metrics.metrics[0].Unit != "%" {
+		t.Fatalf("expected a body-fat metric defined once, got %+v", metrics.metrics)
+	}
+	if len(metrics.events) != 2 || metrics.events[0].Value != 18.5 || metrics.events[1].Value != 18.4 || metrics.events[0].ClientID == "" {
+		t.Errorf("unexpected body fat events %+v", metrics.events)
+	}
+
+	if job := run(fit[:20]); job.Status != app.JobFailed {
+		t.Errorf("expected a truncated FIT file to fail, got %+v", job)
+	}
+}
+
+// mockImportRepo stores imported events as "kind value timestamp" keys,
+// skipping duplicates, and remembers which batch stored each.
+type mockImportRepo struct {
+	mu     sync.Mutex
+	events map[string]string
+}
+
+func (m *mockImportRepo) add(batchID, key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.events[key]; ok {
+		return false
+	}
+	m.events[key] = batchID
+	return true
+}
+
+func (m *mockImportRepo) ImportWeightEvent(ctx context.Context, userID int64, batchID, clientID string, value float64, unit string, createdAt time.Time) (bool, error) {
+	return m.add(batchID, fmt.Sprintf("weight %g%s %s", value, unit, createdAt)), nil
+}
+
+func (m *mockImportRepo) ImportWaterEvent(ctx context.Context, userID int64, batchID, clientID string, deltaLiters float64, createdAt time.Time) (bool, error) {
+	return m.add(batchID, fmt.Sprintf("water %g %s", deltaLiters, createdAt)), nil
+}
+
+func (m *mockImportRepo) DeleteImportBatch(ctx context.Context, userID int64, batchID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for key, batch := range m.events {
+		if batch == batchID {
+			delete(m.events, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestImportService_Batches(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockImportRepo{events: map[string]string{}}
+	svc := app.NewImportService(&mockWeightRepo{}, &mockWaterRepo{}).WithBatches(repo)
+	data := []byte("type,value,unit,timestamp\nweight,80.4,kg,2026-01-05T07:10:00Z\nwater,0.5,l,2026-01-05\n")
+
+	first, _ := svc.Start(ctx, 1, app.ImportFormatCSV, data)
+	if first.BatchID != first.ID {
+		t.Fatalf("expected the job to name its batch, got %+v", first)
+	}
+	if final := waitForJob(t, svc, 1, first.ID); final.RowsImported != 2 {
+		t.Fatalf("expected both rows imported, got %+v", final)
+	}
+	// Re-importing the same file stores nothing new.
+	second, _ := svc.Start(ctx, 1, app.ImportFormatCSV, data)
+	if final := waitForJob(t, svc, 1, second.ID); final.RowsImported != 0 || final.RowsSkipped != 2 {
+		t.Fatalf("expected both rows skipped as duplicates, got %+v", final)
+	}
+
+	if _, err := svc.Rollback(app.WithReadOnly(ctx), 1, first.ID); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if n, err := svc.Rollback(ctx, 1, first.ID); err != nil || n != 2 {
+		t.Fatalf("expected the batch's two events removed, got %d, %v", n, err)
+	}
+	if _, err := svc.Rollback(ctx, 1, first.ID); !errors.Is(err, app.ErrBatchNotFound) {
+		t.Fatalf("expected ErrBatchNotFound once rolled back, got %v", err)
+	}
+	if _, err := svc.Rollback(ctx, 1, second.ID); !errors.Is(err, app.ErrBatchNotFound) {
+		t.Fatalf("expected an import that stored nothing to have no batch, got %v", err)
+	}
+
+	// After a rollback the file imports again.
+	third, _ := svc.Start(ctx, 1, app.ImportFormatCSV, data)
+	if final := waitForJob(t, svc, 1, third.ID); final.RowsImported != 2 {
+		t.Fatalf("expected the rows imported again, got %+v", final)
+	}
+}
+
+func TestImportService_MappedCSV(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockImportRepo{events: map[string]string{}}
+	svc := app.NewImportService(&mockWeightRepo{}, &mockWaterRepo{}).WithBatches(repo)
+	mapping := app.CSVMapping{
+		Type: "Metric", Value: "Amount", Unit: "Unit", Time: "Date",
+		TimeLayout: "02.01.2006 15:04", Delimiter: ";",
+	}
+	data := []byte("\ufeffDate;Metric;Amount;Unit;Note\n" +
+		"05.01.2026 07:10;Weight;80,4;kg;\n" +
+		"05.01.2026 09:30;Water;250;ml;after run\n" +
+		"06.01.2026 07:05;weight;177;lbs;\n" +
+		"06.01.2026 08:00;Steps;9000;;\n" +
+		"2026-01-07;Weight;80;kg;\n")
+
+	report, err := svc.DryRunCSV(mapping, data)
+	if err != nil {
+		t.Fatalf("DryRunCSV: %v", err)
+	}
+	if report.RowsProcessed != 5 || report.RowsValid != 3 || report.Weight != 2 || report.Water != 1 || report.ErrorCount != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !strings.HasPrefix(report.Errors[0], "row 4: unknown type") || !strings.HasPrefix(report.Errors[1], "row 5: invalid time") {
+		t.Errorf("expected row errors for the steps row and the bad date, got %v", report.Errors)
+	}
+	if report.From == nil || report.From.Day() != 5 || report.To.Day() != 6 {
+		t.Errorf("expected the valid rows to span Jan 5 to 6, got %v to %v", report.From, report.To)
+	}
+	if len(repo.events) != 0 {
+		t.Fatalf("expected the dry run to store nothing, got %v", repo.events)
+	}
+
+	job, err := svc.StartCSV(ctx, 1, mapping, data)
+	if err != nil {
+		t.Fatalf("StartCSV: %v", err)
+	}
+	if final := waitForJob(t, svc, 1, job.ID); final.RowsImported != 3 || final.ErrorCount != 2 {
+		t.Fatalf("expected the valid rows imported, got %+v", final)
+	}
+	if _, ok := repo.events[fmt.Sprintf("weight 80.4kg %s", time.Date(2026, 1, 5, 7, 10, 0, 0, time.Local))]; !ok {
+		t.Errorf("expected the decimal comma weight stored, got %v", repo.events)
+	}
+
+	// A single-kind file needs no type column; the unit defaults.
+	single := app.CSVMapping{Kind: "water", Value: "ml", Time: "when", DefaultUnit: "ml"}
+	if report, err := svc.DryRunCSV(single, []byte("when,ml\n2026-01-05 10:00,300\n")); err != nil || report.Water != 1 {
+		t.Errorf("expected one water row, got %+v, %v", report, err)
+	}
+
+	for name, bad := range map[string]app.CSVMapping{
+		"no kind or type": {Value: "v", Time: "t"},
+		"unknown kind":    {Kind: "steps", Value: "v", Time: "t"},
+		"no value":        {Kind: "weight", Time: "t"},
+		"long delimiter":  {Kind: "weight", Value: "v", Time: "t", Delimiter: ";;"},
+	} {
+		if _, err := svc.DryRunCSV(bad, data); err == nil {
+			t.Errorf("%s: expected an invalid mapping", name)
+		}
+	}
+	if _, err := svc.StartCSV(ctx, 1, app.CSVMapping{Kind: "weight", Value: "Kilos", Time: "Date", Delimiter: ";"}, data); err == nil {
+		t.Error("expected a mapping naming a missing column to be rejected up front")
+	}
+	if _, err := svc.StartCSV(app.WithReadOnly(ctx), 1, mapping, data); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestImportService_Rejects(t *testing.T) {
+	svc := app.NewImportService(&mockWeightRepo{}, &mockWaterRepo{})
+	if _, err := svc.Start(context.Background(), 1, "xlsx", nil); err == nil {
+		t.Fatal("expected unknown format to be rejected")
+	}
+	if _, err := svc.Start(app.WithReadOnly(context.Background()), 1, app.ImportFormatCSV, nil); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+
+	job, _ := svc.Start(context.Background(), 1, app.ImportFormatCSV, nil)
+	if final := waitForJob(t, svc, 1, job.ID); final.Status != app.JobFailed {
+		t.Fatalf("expected an empty file to fail, got %+v", final)
+	}
+}
+
+// fakeCluster connects in-process instances: each member's publishes are
+// delivered synchronously to the other members' subscribers.
+type fakeCluster struct {
+	hub      *fakeHub
+	handlers map[string][]func([]byte)
+}
+
+type fakeHub struct {
+	mu      sync.Mutex
+	members []*fakeCluster
+}
+
+func (h *fakeHub) join() *fakeCluster {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := &fakeCluster{hub: h, handlers: map[string][]func([]byte){}}
+	h.members = append(h.members, c)
+	return c
+}
+
+func (c *fakeCluster) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.hub.mu.Lock()
+	var fns []func([]byte)
+	for _, m := range c.hub.members {
+		if m != c {
+			fns = append(fns, m.handlers[topic]...)
+		}
+	}
+	c.hub.mu.Unlock()
+	for _, fn := range fns {
+		fn(payload)
+	}
+	return nil
+}
+
+func (c *fakeCluster) Subscribe(topic string, fn func(payload []byte)) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.handlers[topic] = append(c.handlers[topic], fn)
+}
+
+func TestImportService_Cluster(t *testing.T) {
+	hub := &fakeHub{}
+	running := app.NewImportService(&mockWeightRepo{}, &mockWaterRepo{}).WithCluster(hub.join())
+	other := app.NewImportService(&mockWeightRepo{}, &mockWaterRepo{}).WithCluster(hub.join())
+
+	rows := []string{"type,value,unit,timestamp"}
+	for i := 0; i < 250; i++ {
+		rows = append(rows, "weight,80,kg,2026-01-05")
+	}
+	rows = append(rows, "weight,-1,kg,2026-01-05")
+	job, err := running.Start(context.Background(), 1, app.ImportFormatCSV, []byte(strings.Join(rows, "\n")))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// The other instance follows the job it is not running.
+	final := waitForJob(t, other, 1, job.ID)
+	if final.Status != app.JobSucceeded || final.RowsProcessed != 251 || final.RowsImported != 250 || final.ErrorCount != 1 || len(final.Errors) != 1 {
+		t.Fatalf("unexpected mirrored job: %+v", final)
+	}
+	if _, err := other.Get(2, job.ID); !errors.Is(err, app.ErrJobNotFound) {
+		t.Errorf("expected other users not to see the mirrored job, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package app
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exports from other self-hosted trackers accepted by ImportService. Their
+// rows carry a dedup key, so importing an export twice, or two exports that
+// overlap, stores each measurement once.
+const (
+	// ImportFormatLibra is a Libra weight manager backup: "#Units:kg" and
+	// other "#" header lines, then date;weight;... rows.
+	ImportFormatLibra = "libra"
+	// ImportFormatFitNotes is a FitNotes body tracker CSV; only bodyweight
+	// measurements are imported.
+	ImportFormatFitNotes = "fitnotes"
+	// ImportFormatWger is a wger weight export, either the CSV download or
+	// the JSON of /api/v2/weightentry/. wger weights are taken as kg.
+	ImportFormatWger = "wger"
+)
+
+// dedupKey identifies a measurement independently of the file it came from.
+func dedupKey(rec importRecord) string {
+	return fmt.Sprintf("%s|%d|%g|%s", rec.Kind, rec.At.Unix(), rec.Value, rec.Unit)
+}
+
+// importClientID derives the client ID an imported record is stored under
+// from its dedup key, formatted like the UUIDs offline clients send.
+func importClientID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// trackerUnit maps the weight unit spellings used by other trackers to "kg"
+// or "lb".
+func trackerUnit(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "kg", "kgs":
+		return "kg", nil
+	case "lb", "lbs":
+		return "lb", nil
+	}
+	return "", fmt.Errorf("unsupported weight unit %q", s)
+}
+
+// parseTrackerWeight parses a decimal weight, accepting a decimal comma.
+func parseTrackerWeight(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid weight %q", s)
+	}
+	return v, nil
+}
+
+// weightRecord builds a deduplicated weight record.
+func weightRecord(value float64, unit string, at time.Time) importRecord {
+	rec := importRecord{Kind: "weight", Value: value, Unit: unit, At: at}
+	rec.Key = dedupKey(rec)
+	return rec
+}
+
+// parseLibra reads a Libra backup. Header lines start with "#"; "#Units:"
+// names the weight unit (kg when absent). Each remaining line is
+// semicolon-separated, starting with an RFC 3339 timestamp and the weight.
+func parseLibra(r io.Reader, emit func(importRecord, error)) error {
+	sc := bufio.NewScanner(r)
+	unit := "kg"
+	sawHeader := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			sawHeader = true
+			if u, ok := strings.CutPrefix(line, "#Units:"); ok {
+				var err error
+				if unit, err = trackerUnit(u); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sawHeader {
+			return errors.New("not a Libra backup: missing # header")
+		}
+		emit(libraRecord(strings.Split(line, ";"), unit))
+	}
+	return sc.Err()
+}
+
+func libraRecord(fields []string, unit string) (importRecord, error) {
+	if len(fields) < 2 {
+		return importRecord{}, errors.New("expected date;weight")
+	}
+	at, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return importRecord{}, fmt.Errorf("invalid date %q", fields[0])
+	}
+	v, err := parseTrackerWeight(fields[1])
+	if err != nil {
+		return importRecord{}, err
+	}
+	return weightRecord(v, unit, at), nil
+}
+
+// parseFitNotes reads a FitNotes body tracker export with the header
+// Date,Time,Measurement,Value,Unit[,Comment]. Rows for measurements other
+// than bodyweight are skipped. Times are local.
+func parseFitNotes(r io.Reader, emit func(importRecord, error)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	col, err := csvColumns(header, "date", "time", "measurement", "value", "unit")
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) < len(header) {
+			emit(importRecord{}, fmt.Errorf("expected %d fields, got %d", len(header), len(row)))
+			continue
+		}
+		switch strings.ToLower(strings.ReplaceAll(row[col["measurement"]], " ", "")) {
+		case "bodyweight", "weight":
+		default:
+			continue
+		}
+		emit(fitNotesRecord(row[col["date"]], row[col["time"]], row[col["value"]], row[col["unit"]]))
+	}
+}
+
+func fitNotesRecord(day, clock, value, unit string) (importRecord, error) {
+	at, err := time.ParseInLocation("2006-01-02 15:04:05", day+" "+clock, time.Local)
+	if err != nil {
+		if at, err = time.ParseInLocation("2006-01-02 15:04", day+" "+clock, time.Local); err != nil {
+			return importRecord{}, fmt.Errorf("invalid date and time %q %q", day, clock)
+		}
+	}
+	v, err := parseTrackerWeight(value)
+	if err != nil {
+		return importRecord{}, err
+	}
+	u, err := trackerUnit(unit)
+	if err != nil {
+		return importRecord{}, err
+	}
+	return weightRecord(v, u, at), nil
+}
+
+// csvColumns locates the named columns, case-insensitively, in header.
+func csvColumns(header []string, names ...string) (map[string]int, error) {
+	col := make(map[string]int, len(names))
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range names {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+	return col, nil
+}
+
+// wgerEntry is one weight entry of the wger API. Older servers send the
+// date as YYYY-MM-DD, newer ones as a timestamp; weight is a decimal string.
+type wgerEntry struct {
+	Date   string      `json:"date"`
+	Weight json.Number `json:"weight"`
+}
+
+// parseWger reads a wger export: the paginated JSON of /api/v2/weightentry/
+// (or a bare array of its results), or the Date,Weight CSV download.
+func parseWger(r io.Reader, emit func(importRecord, error)) error {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return err
+	}
+	if first != '{' && first != '[' {
+		return parseWgerCSV(br, emit)
+	}
+
+	var entries []wgerEntry
+	if first == '{' {
+		var page struct {
+			Results []wgerEntry `json:"results"`
+		}
+		err = json.NewDecoder(br).Decode(&page)
+		entries = page.Results
+	} else {
+		err = json.NewDecoder(br).Decode(&entries)
+	}
+	if err != nil {
+		return fmt.Errorf("read wger JSON: %w", err)
+	}
+	for _, e := range entries {
+		emit(wgerRecord(e.Date, e.Weight.String()))
+	}
+	return nil
+}
+
+func parseWgerCSV(r io.Reader, emit func(importRecord, error)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	col, err := csvColumns(header, "date", "weight")
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) < len(header) {
+			emit(importRecord{}, fmt.Errorf("expected %d fields, got %d", len(header), len(row)))
+			continue
+		}
+		emit(wgerRecord(row[col["date"]], row[col["weight"]]))
+	}
+}
+
+func wgerRecord(date, weight string) (importRecord, error) {
+	at, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		// Date-only entries are taken as local midnight.
+		if at, err = time.ParseInLocation("2006-01-02", date, time.Local); err != nil {
+			return importRecord{}, fmt.Errorf("invalid date %q", date)
+		}
+	}
+	v, err := parseTrackerWeight(weight)
+	if err != nil {
+		return importRecord{}, err
+	}
+	return weightRecord(v, "kg", at), nil
+}
+
+// peekNonSpace returns the first non-whitespace byte of br without
+// consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, errors.New("empty file")
+			}
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+		case 0xEF: // UTF-8 byte order mark
+			_, _ = br.Discard(3)
+		default:
+			return b[0], nil
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"vitals/internal/domain"
+)
+
+// ErrIntegrationNotFound indicates a provider that is not configured.
+var ErrIntegrationNotFound = errors.New("integration not found")
+
+// ErrIntegrationNotConnected indicates the user has not connected the
+// provider.
+var ErrIntegrationNotConnected = errors.New("integration not connected")
+
+const (
+	// integrationBackfill is how far back the first sync of a connection
+	// reaches.
+	integrationBackfill = 30 * 24 * time.Hour
+	// integrationOverlap is how far before the end of the last sync each
+	// sync starts, catching readings that reached the provider late.
+	// Readings already pulled are skipped by their client ID.
+	integrationOverlap = 24 * time.Hour
+)
+
+// Integration is a user's connection to a provider.
+type Integration struct {
+	Provider    string     `json:"provider"`
+	Connected   bool       `json:"connected"`
+	ConnectedAt *time.Time `json:"connectedAt,omitempty"`
+	SyncedAt    *time.Time `json:"syncedAt,omitempty"`
+}
+
+// IntegrationSync counts the entries a sync added.
+type IntegrationSync struct {
+	Weight int `json:"weight"`
+	Water  int `json:"water"`
+}
+
+// IntegrationService connects users' accounts to third-party health
+// services with OAuth2 and pulls their measurements into the weight and
+// water logs.
+type IntegrationService struct {
+	tokens  domain.OAuthTokenRepository
+	weight  domain.WeightRepository
+	water   domain.WaterRepository
+	sources map[string]domain.HealthSource
+	now     func() time.Time
+}
+
+// NewIntegrationService creates an IntegrationService without providers;
+// add them with WithSource.
+func NewIntegrationService(tokens domain.OAuthTokenRepository, weight domain.WeightRepository, water domain.WaterRepository) *IntegrationService {
+	return &IntegrationService{
+		tokens:  tokens,
+		weight:  weight,
+		water:   water,
+		sources: map[string]domain.HealthSource{},
+		now:     time.Now,
+	}
+}
+
+// WithSource makes provider available to connect to.
+func (s *IntegrationService) WithSource(provider string, src domain.HealthSource) *IntegrationService {
+	s.sources[provider] = src
+	return s
+}
+
+// Providers returns the configured providers, sorted.
+func (s *IntegrationService) Providers() []string {
+	out := make([]string, 0, len(s.sources))
+	for p := range s.sources {
+		out = append(out, p)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func (s *IntegrationService) source(provider string) (domain.HealthSource, error) {
+	src, ok := s.sources[provider]
+	if !ok {
+		return nil, ErrIntegrationNotFound
+	}
+	return src, nil
+}
+
+// AuthCodeURL returns the provider's consent page URL, carrying state back
+// to the callback.
+func (s *IntegrationService) AuthCodeURL(provider, state string) (string, error) {
+	src, err := s.source(provider)
+	if err != nil {
+		return "", err
+	}
+	return src.AuthCodeURL(state), nil
+}
+
+// Connect completes the consent flow: it trades code for a token and keeps
+// it, replacing any earlier connection. The first sync reaches back 30
+// days.
+func (s *IntegrationService) Connect(ctx context.Context, userID int64, provider, code string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	src, err := s.source(provider)
+	if err != nil {
+		return err
+	}
+	if code == "" {
+		return errors.New("code is required")
+	}
+	tok, err := src.Exchange(ctx, code)
+	if err != nil {
+		return err
+	}
+	tok.UserID, tok.Provider = userID, provider
+	tok.ConnectedAt, tok.SyncedAt = s.now().UTC(), time.Time{}
+	return s.tokens.SaveOAuthToken(ctx, *tok)
+}
+
+// Status reports whether the user has connected provider, and when it was
+// last synced.
+func (s *IntegrationService) Status(ctx context.Context, userID int64, provider string) (*Integration, error) {
+	if _, err := s.source(provider); err != nil {
+		return nil, err
+	}
+	tok, err := s.tokens.GetOAuthToken(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+	in := &Integration{Provider: provider}
+	if tok != nil {
+		in.Connected = true
+		in.ConnectedAt = &tok.ConnectedAt
+		if !tok.SyncedAt.IsZero() {
+			in.SyncedAt = &tok.SyncedAt
+		}
+	}
+	return in, nil
+}
+
+// Disconnect forgets the user's token for provider. Entries already pulled
+// are kept.
+func (s *IntegrationService) Disconnect(ctx context.Context, userID int64, provider string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	if _, err := s.source(provider); err != nil {
+		return err
+	}
+	ok, err := s.tokens.DeleteOAuthToken(ctx, userID, provider)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIntegrationNotConnected
+	}
+	return nil
+}
+
+// Sync pulls the user's new readings from provider.
+func (s *IntegrationService) Sync(ctx context.Context, userID int64, provider string) (_ *IntegrationSync, err error) {
+	ctx, span := startSpan(ctx, "IntegrationService.Sync", userAttr(userID), attribute.String("vitals.provider", provider))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	src, err := s.source(provider)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := s.tokens.GetOAuthToken(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, ErrIntegrationNotConnected
+	}
+	return s.sync(ctx, src, *tok)
+}
+
+// SyncAll pulls new readings for every connection to every provider and
+// returns how many connections were synced. Meant to be scheduled, e.g.
+// hourly.
+func (s *IntegrationService) SyncAll(ctx context.Context) (int, error) {
+	synced := 0
+	var errs []error
+	for _, provider := range s.Providers() {
+		tokens, err := s.tokens.ListOAuthTokens(ctx, provider)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			continue
+		}
+		for _, tok := range tokens {
+			if _, err := s.sync(ctx, s.sources[provider], tok); err != nil {
+				errs = append(errs, fmt.Errorf("%s user %d: %w", provider, tok.UserID, err))
+				continue
+			}
+			synced++
+		}
+	}
+	return synced, errors.Join(errs...)
+}
+
+// sync pulls the readings since the last sync into the user's logs, then
+// stores the refreshed token and the end of the synced range.
+func (s *IntegrationService) sync(ctx context.Context, src domain.HealthSource, tok domain.OAuthToken) (*IntegrationSync, error) {
+	to := s.now().UTC()
+	from := tok.SyncedAt.Add(-integrationOverlap)
+	if tok.SyncedAt.IsZero() {
+		from = to.Add(-integrationBackfill)
+	}
+	readings, current, err := src.Readings(ctx, tok, from, to)
+	if err != nil {
+		return nil, err
+	}
+	tok.AccessToken, tok.RefreshToken, tok.Expiry = current.AccessToken, current.RefreshToken, current.Expiry
+
+	res := &IntegrationSync{}
+	for _, r := range readings {
+		clientID := tok.Provider + ":" + r.ID
+		var created bool
+		switch r.Kind {
+		case "weight":
+			_, created, err = s.weight.AddWeightEventWithClientID(ctx, tok.UserID, clientID, r.Value, "kg", r.At)
+			if created {
+				res.Weight++
+			}
+		case "water":
+			_, created, err = s.water.AddWaterEventWithClientID(ctx, tok.UserID, clientID, r.Value, r.At)
+			if created {
+				res.Water++
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tok.SyncedAt = to
+	if err := s.tokens.SaveOAuthToken(ctx, tok); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
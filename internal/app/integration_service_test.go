@@ -0,0 +1,148 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockOAuthTokens struct {
+	tokens map[int64]domain.OAuthToken
+}
+
+func (m *mockOAuthTokens) SaveOAuthToken(_ context.Context, t domain.OAuthToken) error {
+	m.tokens[t.UserID] = t
+	return nil
+}
+
+func (m *mockOAuthTokens) GetOAuthToken(_ context.Context, userID int64, _ string) (*domain.OAuthToken, error) {
+	t, ok := m.tokens[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (m *mockOAuthTokens) DeleteOAuthToken(_ context.Context, userID int64, _ string) (bool, error) {
+	_, ok := m.tokens[userID]
+	delete(m.tokens, userID)
+	return ok, nil
+}
+
+func (m *mockOAuthTokens) ListOAuthTokens(_ context.Context, _ string) ([]domain.OAuthToken, error) {
+	var out []domain.OAuthToken
+	for _, t := range m.tokens {
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// fakeSource serves readings and hands out a new access token on each call.
+type fakeSource struct {
+	readings []domain.ExternalReading
+	from     time.Time
+}
+
+func (f *fakeSource) AuthCodeURL(state string) string {
+	return "https://consent.example/?state=" + state
+}
+
+func (f *fakeSource) Exchange(_ context.Context, code string) (*domain.OAuthToken, error) {
+	return &domain.OAuthToken{AccessToken: "access-" + code, RefreshToken: "refresh"}, nil
+}
+
+func (f *fakeSource) Readings(_ context.Context, tok domain.OAuthToken, from, _ time.Time) ([]domain.ExternalReading, *domain.OAuthToken, error) {
+	f.from = from
+	tok.AccessToken = "refreshed"
+	return f.readings, &tok, nil
+}
+
+func TestIntegrationService_Sync(t *testing.T) {
+	ctx := context.Background()
+	at := time.Now().Add(-2 * time.Hour)
+	src := &fakeSource{readings: []domain.ExternalReading{
+		{ID: "weight:1", Kind: "weight", Value: 80.2, At: at},
+		{ID: "water:2", Kind: "water", Value: 0.25, At: at},
+	}}
+	seen := map[string]bool{}
+	wr := &mockWeightRepo{
+		addClientFn: func(_ context.Context, _ int64, clientID string, value float64, unit string, _ time.Time) (*domain.WeightEntry, bool, error) {
+			if clientID != "googlefit:weight:1" || value != 80.2 || unit != "kg" {
+				t.Errorf("unexpected weight %q %v %s", clientID, value, unit)
+			}
+			created := !seen[clientID]
+			seen[clientID] = true
+			return &domain.WeightEntry{}, created, nil
+		},
+	}
+	tokens := &mockOAuthTokens{tokens: map[int64]domain.OAuthToken{}}
+	svc := app.NewIntegrationService(tokens, wr, &mockWaterRepo{}).WithSource(domain.IntegrationGoogleFit, src)
+
+	if _, err := svc.Sync(ctx, 1, domain.IntegrationGoogleFit); !errors.Is(err, app.ErrIntegrationNotConnected) {
+		t.Fatalf("expected ErrIntegrationNotConnected, got %v", err)
+	}
+	if err := svc.Connect(ctx, 1, domain.IntegrationGoogleFit, "abc"); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if tok := tokens.tokens[1]; tok.AccessToken != "access-abc" || tok.Provider != domain.IntegrationGoogleFit || tok.ConnectedAt.IsZero() {
+		t.Fatalf("unexpected stored token %+v", tok)
+	}
+
+	res, err := svc.Sync(ctx, 1, domain.IntegrationGoogleFit)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if res.Weight != 1 || res.Water != 1 {
+		t.Errorf("expected one weight and one water entry, got %+v", res)
+	}
+	if d := time.Since(src.from); d < 29*24*time.Hour {
+		t.Errorf("expected the first sync to backfill 30 days, started %v ago", d)
+	}
+	tok := tokens.tokens[1]
+	if tok.AccessToken != "refreshed" || tok.SyncedAt.IsZero() {
+		t.Errorf("expected the refreshed token and sync time stored, got %+v", tok)
+	}
+
+	// A second sync overlaps the first; repeated readings are not added again.
+	n, err := svc.SyncAll(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("SyncAll = %d, %v", n, err)
+	}
+	if d := tok.SyncedAt.Sub(src.from); d != 24*time.Hour {
+		t.Errorf("expected the next sync to start a day before the last, got %v", d)
+	}
+
+	in, err := svc.Status(ctx, 1, domain.IntegrationGoogleFit)
+	if err != nil || !in.Connected || in.SyncedAt == nil {
+		t.Errorf("Status = %+v, %v", in, err)
+	}
+	if err := svc.Disconnect(ctx, 1, domain.IntegrationGoogleFit); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+	if in, _ := svc.Status(ctx, 1, domain.IntegrationGoogleFit); in.Connected {
+		t.Error("expected the integration disconnected")
+	}
+}
+
+func TestIntegrationService_Checks(t *testing.T) {
+	ctx := context.Background()
+	tokens := &mockOAuthTokens{tokens: map[int64]domain.OAuthToken{}}
+	svc := app.NewIntegrationService(tokens, &mockWeightRepo{}, &mockWaterRepo{}).WithSource(domain.IntegrationGoogleFit, &fakeSource{})
+
+	if _, err := svc.AuthCodeURL("strava", "s"); !errors.Is(err, app.ErrIntegrationNotFound) {
+		t.Errorf("expected ErrIntegrationNotFound, got %v", err)
+	}
+	if err := svc.Connect(app.WithReadOnly(ctx), 1, domain.IntegrationGoogleFit, "abc"); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := svc.Connect(ctx, 1, domain.IntegrationGoogleFit, ""); err == nil {
+		t.Error("expected a missing code rejected")
+	}
+	if err := svc.Disconnect(ctx, 1, domain.IntegrationGoogleFit); !errors.Is(err, app.ErrIntegrationNotConnected) {
+		t.Errorf("expected ErrIntegrationNotConnected, got %v", err)
+	}
+}
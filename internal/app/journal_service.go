@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// JournalService keeps an optional free-text note per day.
+type JournalService struct {
+	repo domain.JournalRepository
+}
+
+// NewJournalService creates a JournalService backed by the given repository.
+func NewJournalService(repo domain.JournalRepository) *JournalService {
+	return &JournalService{repo: repo}
+}
+
+// Get returns the note for day ("YYYY-MM-DD"), or nil.
+func (s *JournalService) Get(ctx context.Context, userID int64, day string) (*domain.JournalEntry, error) {
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	return s.repo.GetJournalEntry(ctx, userID, day)
+}
+
+// Save stores the note for day. A blank note deletes it and returns nil.
+func (s *JournalService) Save(ctx context.Context, userID int64, day, note string) (*domain.JournalEntry, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		_, err := s.repo.DeleteJournalEntry(ctx, userID, day)
+		return nil, err
+	}
+	if len(note) > domain.MaxJournalNoteLength {
+		return nil, fmt.Errorf("note must be at most %d bytes", domain.MaxJournalNoteLength)
+	}
+	e := domain.JournalEntry{Day: day, Note: note, UpdatedAt: time.Now().UTC()}
+	if err := s.repo.SaveJournalEntry(ctx, userID, e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Delete removes the note for day, reporting whether one existed.
+func (s *JournalService) Delete(ctx context.Context, userID int64, day string) (bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return false, err
+	}
+	if err := validateJournalDay(day); err != nil {
+		return false, err
+	}
+	return s.repo.DeleteJournalEntry(ctx, userID, day)
+}
+
+// validateJournalDay checks that day is a calendar date no later than
+// tomorrow, leaving room for time zones ahead of the server's.
+func validateJournalDay(day string) error {
+	t, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return errors.New("date must be YYYY-MM-DD")
+	}
+	if t.After(time.Now().AddDate(0, 0, 1)) {
+		return errors.New("date must not be in the future")
+	}
+	return nil
+}
+
+// journalNotes returns the notes for days from through to, keyed by day.
+func journalNotes(ctx context.Context, repo domain.JournalRepository, userID int64, from, to string) (map[string]string, error) {
+	notes := map[string]string{}
+	if repo == nil {
+		return notes, nil
+	}
+	entries, err := repo.ListJournalEntries(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		notes[e.Day] = e.Note
+	}
+	return notes, nil
+}
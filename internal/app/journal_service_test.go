@@ -0,0 +1,99 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockJournalRepo struct {
+	entries map[string]domain.JournalEntry
+}
+
+func (m *mockJournalRepo) GetJournalEntry(ctx context.Context, userID int64, day string) (*domain.JournalEntry, error) {
+	e, ok := m.entries[day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (m *mockJournalRepo) SaveJournalEntry(ctx context.Context, userID int64, e domain.JournalEntry) error {
+	m.entries[e.Day] = e
+	return nil
+}
+
+func (m *mockJournalRepo) DeleteJournalEntry(ctx context.Context, userID int64, day string) (bool, error) {
+	_, ok := m.entries[day]
+	delete(m.entries, day)
+	return ok, nil
+}
+
+func (m *mockJournalRepo) ListJournalEntries(ctx context.Context, userID int64, from, to string) ([]domain.JournalEntry, error) {
+	var out []domain.JournalEntry
+	for day, e := range m.entries {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestJournalService_Save(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockJournalRepo{entries: map[string]domain.JournalEntry{}}
+	svc := app.NewJournalService(repo)
+	today := time.Now().Format("2006-01-02")
+
+	e, err := svc.Save(ctx, 1, today, "  long run  ")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if e.Note != "long run" || repo.entries[today].Note != "long run" {
+		t.Fatalf("expected a trimmed note, got %+v", e)
+	}
+
+	// A blank note deletes the entry.
+	if e, err := svc.Save(ctx, 1, today, " "); err != nil || e != nil {
+		t.Fatalf("expected a blank note to delete, got %+v, %v", e, err)
+	}
+	if _, ok := repo.entries[today]; ok {
+		t.Fatal("expected the note to be deleted")
+	}
+
+	for name, tc := range map[string]struct{ day, note string }{
+		"bad date":  {"05/01/2026", "x"},
+		"future":    {time.Now().AddDate(0, 0, 3).Format("2006-01-02"), "x"},
+		"too long":  {today, strings.Repeat("x", domain.MaxJournalNoteLength+1)},
+		"not a day": {"2026-02-30", "x"},
+	} {
+		if _, err := svc.Save(ctx, 1, tc.day, tc.note); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := svc.Save(app.WithReadOnly(ctx), 1, today, "x"); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestGetDaily_JournalNotes(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	journal := &mockJournalRepo{entries: map[string]domain.JournalEntry{
+		today:        {Day: today, Note: "ate out"},
+		"2000-01-01": {Day: "2000-01-01", Note: "out of range"},
+	}}
+	svc := app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}).WithJournal(journal)
+
+	points, err := svc.GetDaily(context.Background(), 1, 2, "kg", domain.TagFilter{}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if points[0].Note != "" || points[1].Note != "ate out" {
+		t.Fatalf("expected only today's note, got %+v", points)
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// localeSetting is the user setting holding the user's locale.
+const localeSetting = "ui.locale"
+
+// DefaultLocale is the locale of users who have not chosen one, unless the
+// instance sets another with SettingsService.WithDefaultLocale.
+const DefaultLocale = "en-US"
+
+// FormatHints tell clients how a user's numbers, dates and times are
+// written, so every frontend renders them the same way.
+type FormatHints struct {
+	// Locale is the BCP 47 tag the hints were derived from.
+	Locale           string `json:"locale"`
+	DecimalSeparator string `json:"decimalSeparator"`
+	// FirstDayOfWeek is "monday", "sunday", "saturday" or "friday".
+	FirstDayOfWeek string `json:"firstDayOfWeek"`
+	// Hour12 is set where a 12-hour clock is customary.
+	Hour12 bool `json:"hour12"`
+}
+
+// Regions whose week starts on a day other than Monday, and regions that
+// use a 12-hour clock, after the CLDR supplemental data.
+var (
+	weekStartsOn = map[string]string{
+		"AE": "saturday", "AF": "saturday", "BH": "saturday", "DJ": "saturday", "DZ": "saturday",
+		"EG": "saturday", "IQ": "saturday", "IR": "saturday", "JO": "saturday", "KW": "saturday",
+		"LY": "saturday", "OM": "saturday", "QA": "saturday", "SD": "saturday", "SY": "saturday",
+		"MV": "friday",
+		"AG": "sunday", "AS": "sunday", "BD": "sunday", "BR": "sunday", "BS": "sunday", "BT": "sunday",
+		"BW": "sunday", "BZ": "sunday", "CA": "sunday", "CN": "sunday", "CO": "sunday", "DM": "sunday",
+		"DO": "sunday", "ET": "sunday", "GT": "sunday", "GU": "sunday", "HK": "sunday", "HN": "sunday",
+		"ID": "sunday", "IL": "sunday", "IN": "sunday", "JM": "sunday", "JP": "sunday", "KE": "sunday",
+		"KH": "sunday", "KR": "sunday", "LA": "sunday", "MH": "sunday", "MM": "sunday", "MO": "sunday",
+		"MT": "sunday", "MX": "sunday", "MZ": "sunday", "NI": "sunday", "NP": "sunday", "PA": "sunday",
+		"PE": "sunday", "PH": "sunday", "PK": "sunday", "PR": "sunday", "PT": "sunday", "PY": "sunday",
+		"SA": "sunday", "SG": "sunday", "SV": "sunday", "TH": "sunday", "TT": "sunday", "TW": "sunday",
+		"UM": "sunday", "US": "sunday", "VE": "sunday", "VI": "sunday", "WS": "sunday", "YE": "sunday",
+		"ZA": "sunday", "ZW": "sunday",
+	}
+	hour12Regions = map[string]bool{
+		"AE": true, "AU": true, "BD": true, "BH": true, "CA": true, "CO": true, "EG": true,
+		"HK": true, "IN": true, "IQ": true, "JO": true, "KR": true, "KW": true, "LY": true,
+		"MY": true, "NZ": true, "OM": true, "PH": true, "PK": true, "QA": true, "SA": true,
+		"SY": true, "TW": true, "US": true,
+	}
+)
+
+// LocaleFormat returns the formatting hints for a BCP 47 locale. A locale
+// without a region, such as "de", uses its most likely one. Unknown locales
+// get the hints of DefaultLocale.
+func LocaleFormat(locale string) FormatHints {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.MustParse(DefaultLocale)
+	}
+	region, _ := tag.Region()
+	week := weekStartsOn[region.String()]
+	if week == "" {
+		week = "monday"
+	}
+	return FormatHints{
+		Locale:           tag.String(),
+		DecimalSeparator: decimalSeparator(tag),
+		FirstDayOfWeek:   week,
+		Hour12:           hour12Regions[region.String()],
+	}
+}
+
+// decimalSeparator is what tag writes between 0 and 5 in one half.
+func decimalSeparator(tag language.Tag) string {
+	s := message.NewPrinter(tag).Sprintf("%.1f", 0.5)
+	_, first := utf8.DecodeRuneInString(s)
+	_, last := utf8.DecodeLastRuneInString(s)
+	if len(s) <= first+last {
+		return "."
+	}
+	return s[first : len(s)-last]
+}
+
+// Printer formats numbers as the hints' locale writes them, for text such
+// as digests that the server renders for the user.
+func (h FormatHints) Printer() *message.Printer {
+	return message.NewPrinter(language.Make(h.Locale))
+}
+
+// WithDefaultLocale sets the locale of users who have not chosen one.
+func (s *SettingsService) WithDefaultLocale(locale string) *SettingsService {
+	s.defaultLocale = locale
+	return s
+}
+
+// DefaultFormat returns the formatting hints of the default locale, for
+// pages shown before sign-in.
+func (s *SettingsService) DefaultFormat() FormatHints {
+	return LocaleFormat(s.defaultLocale)
+}
+
+// Format returns the formatting hints of the user's ui.locale setting, or
+// of the default locale when it is unset or names no known language.
+func (s *SettingsService) Format(ctx context.Context, userID int64) (FormatHints, error) {
+	all, err := s.repo.GetSettings(ctx, userID)
+	if err != nil {
+		return FormatHints{}, err
+	}
+	locale := s.defaultLocale
+	var v string
+	if raw, ok := all[localeSetting]; ok && json.Unmarshal(raw, &v) == nil {
+		if tag, err := ParseLocale(v); err == nil {
+			locale = tag
+		}
+	}
+	return LocaleFormat(locale), nil
+}
+
+// ParseLocale checks that locale is a known BCP 47 tag, e.g. for an
+// instance's default locale, and returns it in canonical form.
+func ParseLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
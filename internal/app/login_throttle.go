@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrLoginLocked indicates that the client's address made too many
+	// failed sign-ins and must wait before trying again.
+	ErrLoginLocked = errors.New("too many failed sign-ins; try again later")
+	// ErrChallengeRequired indicates that the client's address must solve
+	// a challenge with its next sign-in, or sent a wrong solution.
+	ErrChallengeRequired = errors.New("solve the challenge to sign in")
+)
+
+// Default sign-in limits; see WithLoginLimits.
+const (
+	defaultLoginWindow    = 15 * time.Minute
+	defaultLockoutAfter   = 10
+	defaultChallengeAfter = 3
+)
+
+// LoginStatus reports how a client's address stands against the sign-in
+// limits, for the response to a failed sign-in.
+type LoginStatus struct {
+	// RemainingAttempts is how many more failed sign-ins the address may
+	// make before it is locked out; nil without a lockout.
+	RemainingAttempts *int `json:"remainingAttempts,omitempty"`
+	// Challenge must be solved with the next sign-in, when set.
+	Challenge map[string]any `json:"challenge,omitempty"`
+	// RetryAfter is how long a locked out address must wait.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// loginThrottle counts failed sign-ins per address over a fixed window
+// starting at the first failure. Counts are kept in memory, per instance.
+type loginThrottle struct {
+	window         time.Duration
+	lockoutAfter   int
+	challengeAfter int
+	challenge      domain.LoginChallenge
+	now            func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count int
+	first time.Time
+}
+
+func newLoginThrottle() *loginThrottle {
+	return &loginThrottle{
+		window:         defaultLoginWindow,
+		lockoutAfter:   defaultLockoutAfter,
+		challengeAfter: defaultChallengeAfter,
+		now:            time.Now,
+		failures:       map[string]*loginFailures{},
+	}
+}
+
+// WithLoginLimits locks an address out of password sign-in for the rest
+// of window once it failed lockoutAfter times within it; zero disables the
+// lockout, and a zero window keeps the default. The defaults are 10
+// failures in 15 minutes.
+func (s *AuthService) WithLoginLimits(lockoutAfter int, window time.Duration) *AuthService {
+	s.throttle.lockoutAfter = lockoutAfter
+	if window > 0 {
+		s.throttle.window = window
+	}
+	return s
+}
+
+// WithLoginChallenge requires addresses with challengeAfter failed sign-ins
+// in the current window to solve c with each further attempt.
+func (s *AuthService) WithLoginChallenge(c domain.LoginChallenge, challengeAfter int) *AuthService {
+	s.throttle.challenge, s.throttle.challengeAfter = c, challengeAfter
+	return s
+}
+
+// LoginStatus reports the sign-in limits for ip, issuing a new challenge
+// when its next attempt needs one.
+func (s *AuthService) LoginStatus(ctx context.Context, ip string) (*LoginStatus, error) {
+	t := s.throttle
+	count, retryAfter := t.state(ip)
+	st := &LoginStatus{}
+	if t.lockoutAfter > 0 {
+		remaining := max(t.lockoutAfter-count, 0)
+		st.RemainingAttempts = &remaining
+		if remaining == 0 {
+			st.RetryAfter = retryAfter
+			return st, nil
+		}
+	}
+	if t.challenge != nil && count >= t.challengeAfter {
+		c, err := t.challenge.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		st.Challenge = c
+	}
+	return st, nil
+}
+
+// admit checks ip against the limits before a sign-in is attempted,
+// verifying solution when a challenge is required. A wrong solution counts
+// as a failure.
+func (t *loginThrottle) admit(ctx context.Context, ip, solution string) error {
+	count, _ := t.state(ip)
+	if t.lockoutAfter > 0 && count >= t.lockoutAfter {
+		return ErrLoginLocked
+	}
+	if t.challenge == nil || count < t.challengeAfter {
+		return nil
+	}
+	if solution == "" {
+		return ErrChallengeRequired
+	}
+	ok, err := t.challenge.Verify(ctx, solution, loginAddr(ip))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		t.fail(ip)
+		return ErrChallengeRequired
+	}
+	return nil
+}
+
+// state returns ip's failures in the current window and how long until the
+// window ends.
+func (t *loginThrottle) state(ip string) (int, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.failures[loginAddr(ip)]
+	if !ok {
+		return 0, 0
+	}
+	left := f.first.Add(t.window).Sub(t.now())
+	if left <= 0 {
+		return 0, 0
+	}
+	return f.count, time.Duration(math.Ceil(left.Seconds())) * time.Second
+}
+
+// fail records a failed sign-in from ip, forgetting expired windows.
+func (t *loginThrottle) fail(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for addr, f := range t.failures {
+		if now.Sub(f.first) >= t.window {
+			delete(t.failures, addr)
+		}
+	}
+	addr := loginAddr(ip)
+	f, ok := t.failures[addr]
+	if !ok {
+		f = &loginFailures{first: now}
+		t.failures[addr] = f
+	}
+	f.count++
+}
+
+// loginAddr strips the port from a remote address, so each connection from
+// a client counts against the same limits.
+func loginAddr(ip string) string {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
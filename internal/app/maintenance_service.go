@@ -0,0 +1,390 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// Maintenance actions an operator can run on demand with Run.
+const (
+	// MaintenanceCleanupSessions runs Cleanup.
+	MaintenanceCleanupSessions = "cleanup-sessions"
+	// MaintenanceRefreshSummaries recomputes the weekly summary cache.
+	MaintenanceRefreshSummaries = "refresh-summaries"
+	// MaintenanceDownsampleWater runs DownsampleWater.
+	MaintenanceDownsampleWater = "downsample-water"
+)
+
+// DefaultWaterRetentionDays is how old water events get, unless configured
+// otherwise, before DownsampleWater collapses them into daily totals.
+const DefaultWaterRetentionDays = 365
+
+// DefaultChangeRetentionDays is how old change log entries get, unless
+// configured otherwise, before Cleanup purges the ones a full sync no
+// longer needs. A client that has not synced for longer must start over.
+const DefaultChangeRetentionDays = 90
+
+// ErrUnknownMaintenanceAction is returned by Run for an action it does not
+// offer.
+var ErrUnknownMaintenanceAction = errors.New("unknown maintenance action")
+
+// topicMaintenanceMode carries maintenance mode changes between instances.
+const topicMaintenanceMode = "maintenance.mode"
+
+// DefaultMaintenanceMessage is shown while maintenance mode is on and no
+// message was given.
+const DefaultMaintenanceMessage = "Vitals is undergoing maintenance. Changes are paused; please try again shortly."
+
+// MaintenanceMode reports whether writes are paused, for example during a
+// migration or restore.
+type MaintenanceMode struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// MaintenanceService runs database housekeeping tasks and holds the
+// maintenance mode.
+type MaintenanceService struct {
+	repo          domain.MaintenanceRepository
+	summaries     *SummaryService
+	retention     domain.RetentionRepository
+	retentionDays int
+	changeDays    int
+	cluster       domain.Cluster
+
+	mu   sync.RWMutex
+	mode MaintenanceMode
+}
+
+// NewMaintenanceService creates a MaintenanceService backed by the given repository.
+func NewMaintenanceService(repo domain.MaintenanceRepository) *MaintenanceService {
+	return &MaintenanceService{repo: repo}
+}
+
+// WithSummaries lets Run refresh the weekly summary cache of svc.
+func (s *MaintenanceService) WithSummaries(svc *SummaryService) *MaintenanceService {
+	s.summaries = svc
+	return s
+}
+
+// WithRetention lets Run downsample water events older than days (zero for
+// DefaultWaterRetentionDays) through repo.
+func (s *MaintenanceService) WithRetention(repo domain.RetentionRepository, days int) *MaintenanceService {
+	if days <= 0 {
+		days = DefaultWaterRetentionDays
+	}
+	s.retention, s.retentionDays = repo, days
+	return s
+}
+
+// WithChangeRetention makes Cleanup purge change log entries older than
+// days (zero for DefaultChangeRetentionDays) unless told otherwise.
+func (s *MaintenanceService) WithChangeRetention(days int) *MaintenanceService {
+	s.changeDays = days
+	return s
+}
+
+// WithCluster shares maintenance mode changes with the other instances in
+// c. An instance started later keeps its own configured mode until the
+// next change.
+func (s *MaintenanceService) WithCluster(c domain.Cluster) *MaintenanceService {
+	s.cluster = c
+	c.Subscribe(topicMaintenanceMode, s.applyRemoteMode)
+	return s
+}
+
+// WithMaintenanceMode starts the service in maintenance mode, showing
+// message (DefaultMaintenanceMessage if empty).
+func (s *MaintenanceService) WithMaintenanceMode(message string) *MaintenanceService {
+	s.mode = newMaintenanceMode(true, message, time.Now())
+	return s
+}
+
+// Mode returns the current maintenance mode.
+func (s *MaintenanceService) Mode() MaintenanceMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mode
+}
+
+// SetMode turns maintenance mode on or off on every instance.
+func (s *MaintenanceService) SetMode(ctx context.Context, enabled bool, message string) (MaintenanceMode, error) {
+	if err := checkWritable(ctx); err != nil {
+		return MaintenanceMode{}, err
+	}
+	mode := newMaintenanceMode(enabled, message, time.Now())
+	s.mu.Lock()
+	s.mode = mode
+	s.mu.Unlock()
+
+	if s.cluster != nil {
+		payload, err := json.Marshal(mode)
+		if err != nil {
+			return mode, err
+		}
+		if err := s.cluster.Publish(ctx, topicMaintenanceMode, payload); err != nil {
+			return mode, fmt.Errorf("maintenance mode set here but not shared: %w", err)
+		}
+	}
+	return mode, nil
+}
+
+func newMaintenanceMode(enabled bool, message string, now time.Time) MaintenanceMode {
+	if !enabled {
+		return MaintenanceMode{}
+	}
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+	now = now.UTC()
+	return MaintenanceMode{Enabled: true, Message: message, Since: &now}
+}
+
+// applyRemoteMode adopts a mode set on another instance.
+func (s *MaintenanceService) applyRemoteMode(payload []byte) {
+	var mode MaintenanceMode
+	if err := json.Unmarshal(payload, &mode); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// MaintenanceRequest names an action for Run and its options.
+type MaintenanceRequest struct {
+	Action string `json:"action"`
+	// DryRun applies to cleanup-sessions and downsample-water, Vacuum to
+	// cleanup-sessions.
+	DryRun bool `json:"dryRun"`
+	Vacuum bool `json:"vacuum"`
+	// Weeks applies to refresh-summaries; zero refreshes the last 4, as
+	// the scheduled job does.
+	Weeks int `json:"weeks"`
+	// Days is the age in days past which downsample-water collapses events
+	// and cleanup-sessions purges change log entries; zero uses the
+	// configured retention.
+	Days int `json:"days"`
+}
+
+// MaintenanceResult reports what a Run did.
+type MaintenanceResult struct {
+	Action         string            `json:"action"`
+	Cleanup        *CleanupReport    `json:"cleanup,omitempty"`
+	UsersRefreshed *int              `json:"usersRefreshed,omitempty"`
+	Downsample     *DownsampleReport `json:"downsample,omitempty"`
+}
+
+// Run performs one maintenance action now, the same work the scheduled
+// jobs do.
+func (s *MaintenanceService) Run(ctx context.Context, req MaintenanceRequest, now time.Time) (*MaintenanceResult, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	res := &MaintenanceResult{Action: req.Action}
+	switch req.Action {
+	case MaintenanceCleanupSessions:
+		report, err := s.Cleanup(ctx, CleanupOptions{DryRun: req.DryRun, Vacuum: req.Vacuum, ChangeRetentionDays: req.Days})
+		if err != nil {
+			return nil, err
+		}
+		res.Cleanup = report
+	case MaintenanceRefreshSummaries:
+		if s.summaries == nil {
+			return nil, fmt.Errorf("%w %q: no summary cache configured", ErrUnknownMaintenanceAction, req.Action)
+		}
+		weeks := req.Weeks
+		if weeks <= 0 {
+			weeks = 4
+		}
+		users, err := s.summaries.Refresh(ctx, weeks, now)
+		if err != nil {
+			return nil, err
+		}
+		res.UsersRefreshed = &users
+	case MaintenanceDownsampleWater:
+		if s.retention == nil {
+			return nil, fmt.Errorf("%w %q: no retention configured", ErrUnknownMaintenanceAction, req.Action)
+		}
+		days := req.Days
+		if days <= 0 {
+			days = s.retentionDays
+		}
+		report, err := s.DownsampleWater(ctx, RetentionCutoff(now, days), req.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		res.Downsample = report
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownMaintenanceAction, req.Action)
+	}
+	return res, nil
+}
+
+// CleanupOptions controls which optional steps Cleanup performs.
+type CleanupOptions struct {
+	// DryRun reports what would change without modifying anything.
+	DryRun bool
+	// Vacuum additionally reclaims storage and refreshes statistics.
+	Vacuum bool
+	// ChangeRetentionDays is the age in days past which change log entries
+	// a full sync no longer needs are purged; zero uses the configured
+	// retention.
+	ChangeRetentionDays int
+}
+
+// CleanupReport summarises the outcome of a Cleanup run.
+type CleanupReport struct {
+	DryRun          bool                `json:"dryRun"`
+	ExpiredSessions int64               `json:"expiredSessions"`
+	OldChanges      int64               `json:"oldChanges"`
+	Orphans         domain.OrphanCounts `json:"orphans"`
+	Vacuumed        bool                `json:"vacuumed"`
+}
+
+// Cleanup deletes expired sessions and old change log entries, counts
+// orphaned events and optionally vacuums. In dry-run mode only the counts
+// are gathered.
+func (s *MaintenanceService) Cleanup(ctx context.Context, opts CleanupOptions) (*CleanupReport, error) {
+	report := &CleanupReport{DryRun: opts.DryRun}
+
+	var err error
+	if opts.DryRun {
+		report.ExpiredSessions, err = s.repo.CountExpiredSessions(ctx)
+	} else {
+		report.ExpiredSessions, err = s.repo.PurgeExpiredSessions(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	days := opts.ChangeRetentionDays
+	if days <= 0 {
+		days = s.changeDays
+	}
+	if days <= 0 {
+		days = DefaultChangeRetentionDays
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	if opts.DryRun {
+		report.OldChanges, err = s.repo.CountOldChanges(ctx, before)
+	} else {
+		report.OldChanges, err = s.repo.PurgeOldChanges(ctx, before)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Orphans, err = s.repo.CountOrphanedEvents(ctx); err != nil {
+		return nil, err
+	}
+
+	if opts.Vacuum && !opts.DryRun {
+		if err := s.repo.Vacuum(ctx); err != nil {
+			return nil, err
+		}
+		report.Vacuumed = true
+	}
+	return report, nil
+}
+
+// DownsampleReport summarises the outcome of a DownsampleWater run.
+type DownsampleReport struct {
+	DryRun bool      `json:"dryRun"`
+	Before time.Time `json:"before"`
+	// Users and Days count the users and days with events collapsed, and
+	// EventsRemoved the events the collapsing removed.
+	Users         int `json:"users"`
+	Days          int `json:"days"`
+	EventsRemoved int `json:"eventsRemoved"`
+}
+
+// RetentionCutoff returns the start of the local day days before now, so
+// downsampling never splits a day.
+func RetentionCutoff(now time.Time, days int) time.Time {
+	y, m, d := now.In(time.Local).AddDate(0, 0, -days).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// DownsampleWater collapses each user's water events created before the
+// cutoff into one event per local day holding the day's total: the day's
+// last event is kept with the total, and the others are removed. Daily
+// totals, and so charts, goals and summaries, are unchanged; weight events
+// are left alone. Days already down to one event are skipped, so runs are
+// idempotent, as are days that lost an event while the run was under way.
+// In dry-run mode only the counts are gathered.
+func (s *MaintenanceService) DownsampleWater(ctx context.Context, before time.Time, dryRun bool) (_ *DownsampleReport, err error) {
+	ctx, span := startSpan(ctx, "MaintenanceService.DownsampleWater")
+	defer func() { endSpan(span, err) }()
+
+	if s.retention == nil {
+		return nil, errors.New("no retention configured")
+	}
+	if !dryRun {
+		if err := checkWritable(ctx); err != nil {
+			return nil, err
+		}
+	}
+	report := &DownsampleReport{DryRun: dryRun, Before: before.UTC()}
+	users, err := s.retention.WaterUsersBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range users {
+		events, err := s.retention.ListWaterEventsBefore(ctx, userID, before)
+		if err != nil {
+			return nil, err
+		}
+		collapsed := false
+		for _, day := range waterDays(events) {
+			if len(day) < 2 {
+				continue
+			}
+			keep := day[len(day)-1]
+			remove := make([]int64, 0, len(day)-1)
+			for _, e := range day[:len(day)-1] {
+				remove = append(remove, e.ID)
+			}
+			if !dryRun {
+				ok, err := s.retention.CollapseWaterEvents(ctx, userID, keep.ID, remove)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					// An event was deleted since the listing; the next
+					// run collapses what is left of the day.
+					continue
+				}
+			}
+			report.Days++
+			report.EventsRemoved += len(remove)
+			collapsed = true
+		}
+		if collapsed {
+			report.Users++
+		}
+	}
+	return report, nil
+}
+
+// waterDays splits events, oldest first, into runs on the same local day.
+func waterDays(events []domain.WaterEvent) [][]domain.WaterEvent {
+	var days [][]domain.WaterEvent
+	last := ""
+	for _, e := range events {
+		day := e.CreatedAt.In(time.Local).Format("2006-01-02")
+		if day != last || len(days) == 0 {
+			days = append(days, nil)
+			last = day
+		}
+		days[len(days)-1] = append(days[len(days)-1], e)
+	}
+	return days
+}
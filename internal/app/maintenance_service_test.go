@@ -0,0 +1,262 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockMaintenanceRepo struct {
+	expired       int64
+	purged        bool
+	vacuumed      bool
+	orphans       domain.OrphanCounts
+	oldChanges    int64
+	changesBefore time.Time
+	changesPurged bool
+}
+
+func (m *mockMaintenanceRepo) CountExpiredSessions(_ context.Context) (int64, error) {
+	return m.expired, nil
+}
+
+func (m *mockMaintenanceRepo) PurgeExpiredSessions(_ context.Context) (int64, error) {
+	m.purged = true
+	return m.expired, nil
+}
+
+func (m *mockMaintenanceRepo) CountOrphanedEvents(_ context.Context) (domain.OrphanCounts, error) {
+	return m.orphans, nil
+}
+
+func (m *mockMaintenanceRepo) CountOldChanges(_ context.Context, before time.Time) (int64, error) {
+	m.changesBefore = before
+	return m.oldChanges, nil
+}
+
+func (m *mockMaintenanceRepo) PurgeOldChanges(_ context.Context, before time.Time) (int64, error) {
+	m.changesBefore, m.changesPurged = before, true
+	return m.oldChanges, nil
+}
+
+func (m *mockMaintenanceRepo) Vacuum(_ context.Context) error {
+	m.vacuumed = true
+	return nil
+}
+
+// mockRetentionRepo holds one user's water events, oldest first, and
+// records collapses. Events in gone are still listed but are missing by
+// the time they are collapsed.
+type mockRetentionRepo struct {
+	events    []domain.WaterEvent
+	gone      map[int64]bool
+	collapsed map[int64]float64
+	removed   []int64
+}
+
+func (m *mockRetentionRepo) WaterUsersBefore(_ context.Context, t time.Time) ([]int64, error) {
+	if len(m.events) > 0 && m.events[0].CreatedAt.Before(t) {
+		return []int64{1}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRetentionRepo) ListWaterEventsBefore(_ context.Context, _ int64, t time.Time) ([]domain.WaterEvent, error) {
+	var out []domain.WaterEvent
+	for _, e := range m.events {
+		if e.CreatedAt.Before(t) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRetentionRepo) CollapseWaterEvents(_ context.Context, _ int64, keepID int64, removeIDs []int64) (bool, error) {
+	total := 0.0
+	for _, id := range append([]int64{keepID}, removeIDs...) {
+		i := slices.IndexFunc(m.events, func(e domain.WaterEvent) bool { return e.ID == id })
+		if i < 0 || m.gone[id] {
+			return false, nil
+		}
+		total += m.events[i].DeltaLiters
+	}
+	m.collapsed[keepID] = domain.RoundLiters(total)
+	m.removed = append(m.removed, removeIDs...)
+	return true, nil
+}
+
+func TestCleanup_DryRun(t *testing.T) {
+	repo := &mockMaintenanceRepo{expired: 3, orphans: domain.OrphanCounts{WaterEvents: 2}, oldChanges: 4}
+	svc := app.NewMaintenanceService(repo)
+
+	report, err := svc.Cleanup(context.Background(), app.CleanupOptions{DryRun: true, Vacuum: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.purged || repo.vacuumed || repo.changesPurged {
+		t.Fatal("dry run must not modify anything")
+	}
+	if report.ExpiredSessions != 3 || report.OldChanges != 4 || report.Orphans.WaterEvents != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestCleanup_Apply(t *testing.T) {
+	repo := &mockMaintenanceRepo{expired: 1, oldChanges: 5}
+	svc := app.NewMaintenanceService(repo)
+
+	report, err := svc.Cleanup(context.Background(), app.CleanupOptions{Vacuum: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !repo.purged || !repo.changesPurged || report.OldChanges != 5 || !repo.vacuumed || !report.Vacuumed {
+		t.Fatalf("expected purge and vacuum, got %+v", report)
+	}
+	if age := time.Since(repo.changesBefore); age < app.DefaultChangeRetentionDays*24*time.Hour-time.Hour {
+		t.Errorf("expected changes older than the default retention purged, got a cutoff %v ago", age)
+	}
+
+	// The configured retention applies unless the run names its own.
+	svc.WithChangeRetention(30)
+	if _, err := svc.Cleanup(context.Background(), app.CleanupOptions{}); err != nil || time.Since(repo.changesBefore) > 31*24*time.Hour {
+		t.Errorf("expected a 30-day cutoff, got %v ago, %v", time.Since(repo.changesBefore), err)
+	}
+	if _, err := svc.Cleanup(context.Background(), app.CleanupOptions{ChangeRetentionDays: 7}); err != nil || time.Since(repo.changesBefore) > 8*24*time.Hour {
+		t.Errorf("expected a 7-day cutoff, got %v ago, %v", time.Since(repo.changesBefore), err)
+	}
+}
+
+func TestMaintenanceService_Run(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 11, 12, 0, 0, 0, time.Local)
+	repo := &mockMaintenanceRepo{expired: 2}
+	cache := &mockSummaryRepo{rows: map[string]domain.WeeklySummary{}, active: []int64{1}, savedBy: map[int64]int{}}
+	summaries := app.NewSummaryService(&mockWeightRepo{}, &mockWaterRepo{}).WithCache(cache)
+	svc := app.NewMaintenanceService(repo)
+
+	if _, err := svc.Run(ctx, app.MaintenanceRequest{Action: app.MaintenanceRefreshSummaries}, now); !errors.Is(err, app.ErrUnknownMaintenanceAction) {
+		t.Errorf("expected refresh-summaries to be unavailable without summaries, got %v", err)
+	}
+	svc.WithSummaries(summaries)
+
+	res, err := svc.Run(ctx, app.MaintenanceRequest{Action: app.MaintenanceCleanupSessions}, now)
+	if err != nil {
+		t.Fatalf("cleanup-sessions: %v", err)
+	}
+	if !repo.purged || res.Cleanup == nil || res.Cleanup.ExpiredSessions != 2 {
+		t.Errorf("expected expired sessions purged, got %+v", res)
+	}
+
+	res, err = svc.Run(ctx, app.MaintenanceRequest{Action: app.MaintenanceRefreshSummaries, Weeks: 2}, now)
+	if err != nil {
+		t.Fatalf("refresh-summaries: %v", err)
+	}
+	if res.UsersRefreshed == nil || *res.UsersRefreshed != 1 || cache.savedBy[1] != 2 {
+		t.Errorf("expected 2 weeks refreshed for user 1, got %+v, %v", res, cache.savedBy)
+	}
+
+	if _, err := svc.Run(ctx, app.MaintenanceRequest{Action: "recompute-everything"}, now); !errors.Is(err, app.ErrUnknownMaintenanceAction) {
+		t.Errorf("expected ErrUnknownMaintenanceAction, got %v", err)
+	}
+	if _, err := svc.Run(app.WithReadOnly(ctx), app.MaintenanceRequest{Action: app.MaintenanceCleanupSessions}, now); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestMaintenanceService_Mode(t *testing.T) {
+	ctx := context.Background()
+	hub := &fakeHub{}
+	a := app.NewMaintenanceService(&mockMaintenanceRepo{}).WithCluster(hub.join())
+	b := app.NewMaintenanceService(&mockMaintenanceRepo{}).WithCluster(hub.join())
+
+	if a.Mode().Enabled {
+		t.Fatal("expected maintenance mode off by default")
+	}
+	mode, err := a.SetMode(ctx, true, "")
+	if err != nil {
+		t.Fatalf("SetMode: %v", err)
+	}
+	if !mode.Enabled || mode.Message != app.DefaultMaintenanceMessage || mode.Since == nil {
+		t.Errorf("unexpected mode %+v", mode)
+	}
+	if got := b.Mode(); !got.Enabled || got.Message != mode.Message {
+		t.Errorf("expected the other instance to follow, got %+v", got)
+	}
+
+	if _, err := b.SetMode(ctx, false, "ignored"); err != nil {
+		t.Fatalf("SetMode: %v", err)
+	}
+	if got := a.Mode(); got.Enabled || got.Message != "" {
+		t.Errorf("expected maintenance mode off everywhere, got %+v", got)
+	}
+
+	started := app.NewMaintenanceService(&mockMaintenanceRepo{}).WithMaintenanceMode("Restoring backups")
+	if got := started.Mode(); !got.Enabled || got.Message != "Restoring backups" {
+		t.Errorf("expected to start in maintenance mode, got %+v", got)
+	}
+}
+
+func TestMaintenanceService_DownsampleWater(t *testing.T) {
+	ctx := context.Background()
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.Local) }
+	repo := &mockRetentionRepo{collapsed: map[int64]float64{}, events: []domain.WaterEvent{
+		{ID: 1, UserID: 1, DeltaLiters: 0.1, CreatedAt: day(1, 8)},
+		{ID: 2, UserID: 1, DeltaLiters: 0.2, CreatedAt: day(1, 12)},
+		{ID: 3, UserID: 1, DeltaLiters: 0.25, CreatedAt: day(1, 20)},
+		{ID: 4, UserID: 1, DeltaLiters: 1, CreatedAt: day(2, 9)},
+		{ID: 5, UserID: 1, DeltaLiters: 0.5, CreatedAt: day(3, 9)},
+		{ID: 6, UserID: 1, DeltaLiters: 0.5, CreatedAt: day(3, 18)},
+	}}
+	svc := app.NewMaintenanceService(&mockMaintenanceRepo{}).WithRetention(repo, 0)
+
+	// The cutoff is the start of day 3, which is left alone.
+	before := app.RetentionCutoff(day(3, 15).AddDate(0, 0, 1), 1)
+	if !before.Equal(day(3, 0)) {
+		t.Fatalf("RetentionCutoff = %v, want %v", before, day(3, 0))
+	}
+
+	report, err := svc.DownsampleWater(ctx, before, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(repo.collapsed) != 0 || report.Days != 1 || report.EventsRemoved != 2 || report.Users != 1 {
+		t.Errorf("dry run: expected one day of two events counted and nothing changed, got %+v", report)
+	}
+
+	report, err = svc.DownsampleWater(ctx, before, false)
+	if err != nil {
+		t.Fatalf("DownsampleWater: %v", err)
+	}
+	if repo.collapsed[3] != 0.55 || len(repo.collapsed) != 1 || !slices.Equal(repo.removed, []int64{1, 2}) {
+		t.Errorf("expected day 1 collapsed into event 3, got %v and removed %v", repo.collapsed, repo.removed)
+	}
+	if report.DryRun || report.Days != 1 || report.EventsRemoved != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// A day that lost an event since it was listed is left for the next
+	// run rather than collapsed from a stale listing.
+	repo.events = append(repo.events[2:],
+		domain.WaterEvent{ID: 7, UserID: 1, DeltaLiters: 0.3, CreatedAt: day(2, 18)})
+	repo.gone = map[int64]bool{4: true}
+	report, err = svc.DownsampleWater(ctx, before, false)
+	if err != nil || report.Days != 0 || report.EventsRemoved != 0 || report.Users != 0 || len(repo.collapsed) != 1 {
+		t.Errorf("expected the changed day skipped, got %+v, %v and collapsed %v", report, err, repo.collapsed)
+	}
+
+	res, err := svc.Run(ctx, app.MaintenanceRequest{Action: app.MaintenanceDownsampleWater, DryRun: true}, day(3, 15))
+	if err != nil || res.Downsample == nil || !res.Downsample.Before.Equal(app.RetentionCutoff(day(3, 15), app.DefaultWaterRetentionDays).UTC()) {
+		t.Errorf("expected the default retention, got %+v, %v", res, err)
+	}
+	if _, err := svc.DownsampleWater(app.WithReadOnly(ctx), before, false); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if _, err := app.NewMaintenanceService(&mockMaintenanceRepo{}).Run(ctx, app.MaintenanceRequest{Action: app.MaintenanceDownsampleWater}, day(3, 15)); !errors.Is(err, app.ErrUnknownMaintenanceAction) {
+		t.Errorf("expected downsample-water to be unavailable without retention, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrMedicationNotFound is returned when the user has no medication with the
+// given id.
+var ErrMedicationNotFound = errors.New("medication not found")
+
+// MedicationService manages the user's medications and supplements and the
+// log of doses taken or skipped.
+type MedicationService struct {
+	repo domain.MedicationRepository
+}
+
+// NewMedicationService creates a MedicationService backed by the given
+// repository.
+func NewMedicationService(repo domain.MedicationRepository) *MedicationService {
+	return &MedicationService{repo: repo}
+}
+
+// List returns the user's medications, oldest first.
+func (s *MedicationService) List(ctx context.Context, userID int64) ([]domain.Medication, error) {
+	return s.repo.ListMedications(ctx, userID)
+}
+
+// Create validates and stores a new medication for m.UserID.
+func (s *MedicationService) Create(ctx context.Context, m domain.Medication) (*domain.Medication, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListMedications(ctx, m.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= domain.MaxMedicationsPerUser {
+		return nil, fmt.Errorf("at most %d medications per user", domain.MaxMedicationsPerUser)
+	}
+	m.CreatedAt = time.Now().UTC()
+	return s.repo.CreateMedication(ctx, m)
+}
+
+// Update validates and replaces the name, dose and schedule of an existing
+// medication. Its logged doses are kept.
+func (s *MedicationService) Update(ctx context.Context, m domain.Medication) (*domain.Medication, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.UpdateMedication(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMedicationNotFound
+	}
+	return s.repo.GetMedication(ctx, m.UserID, m.ID)
+}
+
+// Delete removes the user's medication along with its logged doses.
+func (s *MedicationService) Delete(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteMedication(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMedicationNotFound
+	}
+	return nil
+}
+
+// Log records that a dose of one of the user's medications was taken or
+// skipped. With e.ClientID set, retrying does not create a duplicate; the
+// stored event is returned either way along with whether this call created
+// it. A zero e.CreatedAt means now.
+func (s *MedicationService) Log(ctx context.Context, userID int64, e domain.MedicationEvent) (*domain.MedicationEvent, bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	var err error
+	if e.ClientID != "" {
+		e.ClientID, err = validateClientWrite(e.ClientID, &e.CreatedAt)
+	} else {
+		err = validateWriteTime(&e.CreatedAt)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if e.Status != domain.MedicationTaken && e.Status != domain.MedicationSkipped {
+		return nil, false, fmt.Errorf("status must be %q or %q", domain.MedicationTaken, domain.MedicationSkipped)
+	}
+	m, err := s.repo.GetMedication(ctx, userID, e.MedicationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if m == nil {
+		return nil, false, ErrMedicationNotFound
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	return s.repo.AddMedicationEvent(ctx, userID, e)
+}
+
+// ListRecent returns the user's latest logged doses up to limit, newest
+// first, for one medication or, with medicationID 0, all of them.
+func (s *MedicationService) ListRecent(ctx context.Context, userID, medicationID int64, limit int) ([]domain.MedicationEvent, error) {
+	return s.repo.ListRecentMedicationEvents(ctx, userID, medicationID, limit)
+}
@@ -0,0 +1,143 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockMedicationRepo struct {
+	meds   []domain.Medication
+	events []domain.MedicationEvent
+	nextID int64
+}
+
+func (m *mockMedicationRepo) CreateMedication(ctx context.Context, med domain.Medication) (*domain.Medication, error) {
+	m.nextID++
+	med.ID = m.nextID
+	m.meds = append(m.meds, med)
+	return &med, nil
+}
+
+func (m *mockMedicationRepo) GetMedication(ctx context.Context, userID, id int64) (*domain.Medication, error) {
+	for _, med := range m.meds {
+		if med.UserID == userID && med.ID == id {
+			return &med, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockMedicationRepo) ListMedications(ctx context.Context, userID int64) ([]domain.Medication, error) {
+	var out []domain.Medication
+	for _, med := range m.meds {
+		if med.UserID == userID {
+			out = append(out, med)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockMedicationRepo) UpdateMedication(ctx context.Context, med domain.Medication) (bool, error) {
+	for i, old := range m.meds {
+		if old.UserID == med.UserID && old.ID == med.ID {
+			med.CreatedAt = old.CreatedAt
+			m.meds[i] = med
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockMedicationRepo) DeleteMedication(ctx context.Context, userID, id int64) (bool, error) {
+	for i, med := range m.meds {
+		if med.UserID == userID && med.ID == id {
+			m.meds = append(m.meds[:i], m.meds[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockMedicationRepo) AddMedicationEvent(ctx context.Context, userID int64, e domain.MedicationEvent) (*domain.MedicationEvent, bool, error) {
+	e.ID = int64(len(m.events) + 1)
+	e.UserID = userID
+	m.events = append(m.events, e)
+	return &e, true, nil
+}
+
+func (m *mockMedicationRepo) ListRecentMedicationEvents(ctx context.Context, userID, medicationID int64, limit int) ([]domain.MedicationEvent, error) {
+	return m.events, nil
+}
+
+func TestMedicationService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewMedicationService(&mockMedicationRepo{})
+
+	med, err := svc.Create(ctx, domain.Medication{UserID: 1, Name: "  Magnesium ", Schedule: []string{"21:00", "07:30", "21:00"}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if med.Name != "Magnesium" || len(med.Schedule) != 2 || med.Schedule[0] != "07:30" || med.CreatedAt.IsZero() {
+		t.Errorf("expected a trimmed name and sorted, deduplicated schedule, got %+v", med)
+	}
+	for _, bad := range []domain.Medication{
+		{UserID: 1, Name: " "},
+		{UserID: 1, Name: "Zinc", Schedule: []string{"25:00"}},
+	} {
+		if _, err := svc.Create(ctx, bad); err == nil {
+			t.Errorf("expected error creating %+v", bad)
+		}
+	}
+	if _, err := svc.Create(app.WithReadOnly(ctx), domain.Medication{UserID: 1, Name: "Zinc"}); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+
+	med.Dose = "200 mg"
+	updated, err := svc.Update(ctx, *med)
+	if err != nil || updated.Dose != "200 mg" {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if _, err := svc.Update(ctx, domain.Medication{ID: med.ID, UserID: 2, Name: "Magnesium"}); !errors.Is(err, app.ErrMedicationNotFound) {
+		t.Errorf("expected ErrMedicationNotFound updating another user's medication, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, 1, med.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := svc.Delete(ctx, 1, med.ID); !errors.Is(err, app.ErrMedicationNotFound) {
+		t.Errorf("expected ErrMedicationNotFound on second delete, got %v", err)
+	}
+}
+
+func TestMedicationService_Log(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockMedicationRepo{}
+	svc := app.NewMedicationService(repo)
+	med, _ := svc.Create(ctx, domain.Medication{UserID: 1, Name: "Vitamin D"})
+
+	e, created, err := svc.Log(ctx, 1, domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationTaken})
+	if err != nil || !created || e.CreatedAt.IsZero() {
+		t.Fatalf("Log = %+v, %v, %v", e, created, err)
+	}
+
+	for name, bad := range map[string]domain.MedicationEvent{
+		"unknown status": {MedicationID: med.ID, Status: "forgot"},
+		"bad client id":  {MedicationID: med.ID, Status: domain.MedicationTaken, ClientID: "retry-1"},
+		"future":         {MedicationID: med.ID, Status: domain.MedicationTaken, CreatedAt: time.Now().Add(time.Hour)},
+	} {
+		if _, _, err := svc.Log(ctx, 1, bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, _, err := svc.Log(ctx, 2, domain.MedicationEvent{MedicationID: med.ID, Status: domain.MedicationTaken}); !errors.Is(err, app.ErrMedicationNotFound) {
+		t.Errorf("expected ErrMedicationNotFound, got %v", err)
+	}
+	if len(repo.events) != 1 {
+		t.Errorf("expected only the valid event stored, got %d", len(repo.events))
+	}
+}
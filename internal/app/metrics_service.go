@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrMetricNotFound is returned when the user has no custom metric with
+	// the given slug.
+	ErrMetricNotFound = errors.New("metric not found")
+	// ErrMetricExists is returned when defining a metric whose slug the
+	// user already uses.
+	ErrMetricExists = errors.New("a metric with this slug already exists")
+)
+
+// MetricDay is a custom metric's value for one day, combined from its
+// events according to the metric's aggregation.
+type MetricDay struct {
+	Day string `json:"day"`
+	// Value is nil when nothing was recorded on Day.
+	Value *float64 `json:"value"`
+	Count int      `json:"count"`
+}
+
+// MetricsService manages user-defined numeric trackers and their events.
+type MetricsService struct {
+	repo domain.CustomMetricRepository
+}
+
+// NewMetricsService creates a MetricsService backed by the given repository.
+func NewMetricsService(repo domain.CustomMetricRepository) *MetricsService {
+	return &MetricsService{repo: repo}
+}
+
+// List returns the user's metric definitions, oldest first.
+func (s *MetricsService) List(ctx context.Context, userID int64) ([]domain.CustomMetric, error) {
+	return s.repo.ListCustomMetrics(ctx, userID)
+}
+
+// Define validates and stores a new metric for m.UserID, deriving its slug
+// from the name unless one is given.
+func (s *MetricsService) Define(ctx context.Context, m domain.CustomMetric) (*domain.CustomMetric, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListCustomMetrics(ctx, m.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= domain.MaxCustomMetricsPerUser {
+		return nil, fmt.Errorf("at most %d custom metrics per user", domain.MaxCustomMetricsPerUser)
+	}
+	for _, e := range existing {
+		if e.Slug == m.Slug {
+			return nil, ErrMetricExists
+		}
+	}
+	m.CreatedAt = time.Now().UTC()
+	return s.repo.CreateCustomMetric(ctx, m)
+}
+
+// Delete removes the user's metric along with its events.
+func (s *MetricsService) Delete(ctx context.Context, userID int64, slug string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	m, err := s.metric(ctx, userID, slug)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteCustomMetric(ctx, userID, m.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMetricNotFound
+	}
+	return nil
+}
+
+// Record stores a value of the user's metric. With e.ClientID set, retrying
+// does not create a duplicate; the stored event is returned either way along
+// with whether this call created it. A zero e.CreatedAt means now.
+func (s *MetricsService) Record(ctx context.Context, userID int64, slug string, e domain.CustomMetricEvent) (*domain.CustomMetricEvent, bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	var err error
+	if e.ClientID != "" {
+		e.ClientID, err = validateClientWrite(e.ClientID, &e.CreatedAt)
+	} else {
+		err = validateWriteTime(&e.CreatedAt)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	m, err := s.metric(ctx, userID, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	e.MetricID = m.ID
+	e.CreatedAt = e.CreatedAt.UTC()
+	return s.repo.AddCustomMetricEvent(ctx, userID, e)
+}
+
+// Day returns the metric's definition and its aggregated value for day
+// ("YYYY-MM-DD") in the server's local time.
+func (s *MetricsService) Day(ctx context.Context, userID int64, slug, day string) (*domain.CustomMetric, *MetricDay, error) {
+	if err := validateJournalDay(day); err != nil {
+		return nil, nil, err
+	}
+	m, err := s.metric(ctx, userID, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+	from, _ := time.ParseInLocation("2006-01-02", day, time.Local)
+	events, err := s.repo.ListCustomMetricEventsBetween(ctx, userID, m.ID, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make([]float64, len(events))
+	for i, e := range events {
+		values[i] = e.Value
+	}
+	out := &MetricDay{Day: day, Count: len(events)}
+	if v, ok := domain.AggregateMetric(m.Aggregation, values); ok {
+		out.Value = &v
+	}
+	return m, out, nil
+}
+
+// ListRecent returns the metric's latest events up to limit, newest first.
+func (s *MetricsService) ListRecent(ctx context.Context, userID int64, slug string, limit int) ([]domain.CustomMetricEvent, error) {
+	m, err := s.metric(ctx, userID, slug)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListRecentCustomMetricEvents(ctx, userID, m.ID, limit)
+}
+
+// ensure returns the user's metric with m's slug, defining it from m first
+// if the user has none.
+func (s *MetricsService) ensure(ctx context.Context, userID int64, m domain.CustomMetric) (*domain.CustomMetric, error) {
+	existing, err := s.repo.GetCustomMetric(ctx, userID, m.Slug)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+	m.UserID = userID
+	created, err := s.Define(ctx, m)
+	if errors.Is(err, ErrMetricExists) {
+		// Defined concurrently since the lookup.
+		return s.metric(ctx, userID, m.Slug)
+	}
+	return created, err
+}
+
+// metric returns the user's metric with slug, or ErrMetricNotFound.
+func (s *MetricsService) metric(ctx context.Context, userID int64, slug string) (*domain.CustomMetric, error) {
+	m, err := s.repo.GetCustomMetric(ctx, userID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMetricNotFound
+	}
+	return m, nil
+}
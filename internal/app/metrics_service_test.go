@@ -0,0 +1,157 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockMetricRepo struct {
+	metrics []domain.CustomMetric
+	events  []domain.CustomMetricEvent
+}
+
+func (m *mockMetricRepo) CreateCustomMetric(ctx context.Context, metric domain.CustomMetric) (*domain.CustomMetric, error) {
+	metric.ID = int64(len(m.metrics) + 1)
+	m.metrics = append(m.metrics, metric)
+	return &metric, nil
+}
+
+func (m *mockMetricRepo) GetCustomMetric(ctx context.Context, userID int64, slug string) (*domain.CustomMetric, error) {
+	for _, metric := range m.metrics {
+		if metric.UserID == userID && metric.Slug == slug {
+			return &metric, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockMetricRepo) ListCustomMetrics(ctx context.Context, userID int64) ([]domain.CustomMetric, error) {
+	var out []domain.CustomMetric
+	for _, metric := range m.metrics {
+		if metric.UserID == userID {
+			out = append(out, metric)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockMetricRepo) DeleteCustomMetric(ctx context.Context, userID, id int64) (bool, error) {
+	for i, metric := range m.metrics {
+		if metric.UserID == userID && metric.ID == id {
+			m.metrics = append(m.metrics[:i], m.metrics[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockMetricRepo) AddCustomMetricEvent(ctx context.Context, userID int64, e domain.CustomMetricEvent) (*domain.CustomMetricEvent, bool, error) {
+	e.ID = int64(len(m.events) + 1)
+	e.UserID = userID
+	m.events = append(m.events, e)
+	return &e, true, nil
+}
+
+func (m *mockMetricRepo) ListRecentCustomMetricEvents(ctx context.Context, userID, metricID int64, limit int) ([]domain.CustomMetricEvent, error) {
+	return m.events, nil
+}
+
+func (m *mockMetricRepo) ListCustomMetricEventsBetween(ctx context.Context, userID, metricID int64, from, to time.Time) ([]domain.CustomMetricEvent, error) {
+	var out []domain.CustomMetricEvent
+	for _, e := range m.events {
+		if e.MetricID == metricID && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestMetricsService_Define(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewMetricsService(&mockMetricRepo{})
+
+	m, err := svc.Define(ctx, domain.CustomMetric{UserID: 1, Name: " Coffee (cups) ", Aggregation: domain.MetricSum})
+	if err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+	if m.Slug != "coffee-cups" || m.Name != "Coffee (cups)" || m.CreatedAt.IsZero() {
+		t.Errorf("expected a trimmed name and derived slug, got %+v", m)
+	}
+	if _, err := svc.Define(ctx, domain.CustomMetric{UserID: 1, Name: "Coffee", Slug: "coffee-cups", Aggregation: domain.MetricSum}); !errors.Is(err, app.ErrMetricExists) {
+		t.Errorf("expected ErrMetricExists, got %v", err)
+	}
+	if _, err := svc.Define(ctx, domain.CustomMetric{UserID: 2, Name: "Coffee (cups)", Aggregation: domain.MetricSum}); err != nil {
+		t.Errorf("expected another user to reuse the slug, got %v", err)
+	}
+	for _, bad := range []domain.CustomMetric{
+		{UserID: 1, Name: "Pain", Aggregation: "max"},
+		{UserID: 1, Name: "Pain", Slug: "Pain Level", Aggregation: domain.MetricAvg},
+		{UserID: 1, Name: "☕", Aggregation: domain.MetricSum},
+	} {
+		if _, err := svc.Define(ctx, bad); err == nil {
+			t.Errorf("expected error defining %+v", bad)
+		}
+	}
+	if _, err := svc.Define(app.WithReadOnly(ctx), domain.CustomMetric{UserID: 1, Name: "Pain", Aggregation: domain.MetricAvg}); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, 1, "coffee-cups"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := svc.Delete(ctx, 1, "coffee-cups"); !errors.Is(err, app.ErrMetricNotFound) {
+		t.Errorf("expected ErrMetricNotFound on second delete, got %v", err)
+	}
+}
+
+func TestMetricsService_Day(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewMetricsService(&mockMetricRepo{})
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	midnight, _ := time.ParseInLocation("2006-01-02", today, time.Local)
+
+	for _, agg := range []string{domain.MetricLatest, domain.MetricSum, domain.MetricAvg} {
+		if _, err := svc.Define(ctx, domain.CustomMetric{UserID: 1, Name: agg, Aggregation: agg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, d, err := svc.Day(ctx, 1, agg, today); err != nil || d.Value != nil || d.Count != 0 {
+			t.Fatalf("expected no value before recording, got %+v, %v", d, err)
+		}
+		yesterday := domain.CustomMetricEvent{Value: 100, CreatedAt: midnight.Add(-time.Minute)}
+		if _, _, err := svc.Record(ctx, 1, agg, yesterday); err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range []float64{2, 6, 1} {
+			at := midnight.Add(time.Duration(i) * time.Second)
+			if at.After(now) {
+				at = now
+			}
+			if _, _, err := svc.Record(ctx, 1, agg, domain.CustomMetricEvent{Value: v, CreatedAt: at}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for agg, want := range map[string]float64{domain.MetricLatest: 1, domain.MetricSum: 9, domain.MetricAvg: 3} {
+		m, d, err := svc.Day(ctx, 1, agg, today)
+		if err != nil || m.Aggregation != agg || d.Value == nil || *d.Value != want || d.Count != 3 {
+			t.Errorf("%s: expected %v over 3 events, got %+v, %v", agg, want, d, err)
+		}
+	}
+
+	if _, _, err := svc.Day(ctx, 1, "unknown", today); !errors.Is(err, app.ErrMetricNotFound) {
+		t.Errorf("expected ErrMetricNotFound, got %v", err)
+	}
+	if _, _, err := svc.Record(ctx, 2, domain.MetricSum, domain.CustomMetricEvent{Value: 1}); !errors.Is(err, app.ErrMetricNotFound) {
+		t.Errorf("expected another user's metric to be invisible, got %v", err)
+	}
+	if _, _, err := svc.Record(ctx, 1, domain.MetricSum, domain.CustomMetricEvent{Value: 1, CreatedAt: now.Add(time.Hour)}); err == nil {
+		t.Error("expected error recording a future value")
+	}
+}
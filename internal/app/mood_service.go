@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// MoodService records a daily mood score with an optional note.
+type MoodService struct {
+	repo domain.MoodRepository
+}
+
+// NewMoodService creates a MoodService backed by the given repository.
+func NewMoodService(repo domain.MoodRepository) *MoodService {
+	return &MoodService{repo: repo}
+}
+
+// Get returns the mood for day ("YYYY-MM-DD"), or nil.
+func (s *MoodService) Get(ctx context.Context, userID int64, day string) (*domain.MoodEntry, error) {
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMoodEntry(ctx, userID, day)
+}
+
+// Record stores score (1–5) and note as the mood for day, replacing any
+// earlier one.
+func (s *MoodService) Record(ctx context.Context, userID int64, day string, score int, note string) (*domain.MoodEntry, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	if score < domain.MinMoodScore || score > domain.MaxMoodScore {
+		return nil, fmt.Errorf("score must be between %d and %d", domain.MinMoodScore, domain.MaxMoodScore)
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > domain.MaxMoodNoteLength {
+		return nil, fmt.Errorf("note must be at most %d bytes", domain.MaxMoodNoteLength)
+	}
+	e := domain.MoodEntry{Day: day, Score: score, Note: note, UpdatedAt: time.Now().UTC()}
+	if err := s.repo.SaveMoodEntry(ctx, userID, e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListRecent returns the user's latest limit moods, newest day first.
+func (s *MoodService) ListRecent(ctx context.Context, userID int64, limit int) ([]domain.MoodEntry, error) {
+	return s.repo.ListRecentMoodEntries(ctx, userID, limit)
+}
+
+// moodScores returns the mood scores for days from through to, keyed by day.
+func moodScores(ctx context.Context, repo domain.MoodRepository, userID int64, from, to string) (map[string]int, error) {
+	scores := map[string]int{}
+	if repo == nil {
+		return scores, nil
+	}
+	entries, err := repo.ListMoodEntries(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		scores[e.Day] = e.Score
+	}
+	return scores, nil
+}
@@ -0,0 +1,115 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockMoodRepo struct {
+	entries map[string]domain.MoodEntry
+}
+
+func (m *mockMoodRepo) GetMoodEntry(ctx context.Context, userID int64, day string) (*domain.MoodEntry, error) {
+	e, ok := m.entries[day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (m *mockMoodRepo) SaveMoodEntry(ctx context.Context, userID int64, e domain.MoodEntry) error {
+	m.entries[e.Day] = e
+	return nil
+}
+
+func (m *mockMoodRepo) ListMoodEntries(ctx context.Context, userID int64, from, to string) ([]domain.MoodEntry, error) {
+	var out []domain.MoodEntry
+	for day, e := range m.entries {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockMoodRepo) ListRecentMoodEntries(ctx context.Context, userID int64, limit int) ([]domain.MoodEntry, error) {
+	var out []domain.MoodEntry
+	for _, e := range m.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day > out[j].Day })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func TestMoodService_Record(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockMoodRepo{entries: map[string]domain.MoodEntry{}}
+	svc := app.NewMoodService(repo)
+	today := time.Now().Format("2006-01-02")
+
+	e, err := svc.Record(ctx, 1, today, 4, "  slept well  ")
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if e.Score != 4 || e.Note != "slept well" || repo.entries[today].Note != "slept well" {
+		t.Fatalf("expected a trimmed note, got %+v", e)
+	}
+	if _, err := svc.Record(ctx, 1, today, 2, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := svc.Get(ctx, 1, today); got == nil || got.Score != 2 || got.Note != "" {
+		t.Fatalf("expected the entry replaced, got %+v", got)
+	}
+
+	for name, tc := range map[string]struct {
+		day   string
+		score int
+		note  string
+	}{
+		"score too low":  {today, 0, ""},
+		"score too high": {today, 6, ""},
+		"bad date":       {"05/01/2026", 3, ""},
+		"future":         {time.Now().AddDate(0, 0, 3).Format("2006-01-02"), 3, ""},
+		"note too long":  {today, 3, strings.Repeat("x", domain.MaxMoodNoteLength+1)},
+	} {
+		if _, err := svc.Record(ctx, 1, tc.day, tc.score, tc.note); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := svc.Record(app.WithReadOnly(ctx), 1, today, 3, ""); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestGetDaily_Mood(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	mood := &mockMoodRepo{entries: map[string]domain.MoodEntry{
+		today:        {Day: today, Score: 5},
+		"2000-01-01": {Day: "2000-01-01", Score: 1},
+	}}
+
+	points, err := app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}).
+		GetDaily(context.Background(), 1, 2, "kg", domain.TagFilter{}, "", "")
+	if err != nil || points[1].Mood != nil {
+		t.Fatalf("expected no mood series without a repository, got %+v, %v", points, err)
+	}
+
+	svc := app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}).WithMood(mood)
+	points, err = svc.GetDaily(context.Background(), 1, 2, "kg", domain.TagFilter{}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if points[0].Mood != nil || points[1].Mood == nil || *points[1].Mood != 5 {
+		t.Fatalf("expected only today's mood, got %+v", points)
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vitals/internal/domain"
+)
+
+// Upper bounds for one food entry, well above any single meal.
+const (
+	maxFoodKcal  = 10000
+	maxFoodGrams = 1000
+)
+
+// NutritionService encapsulates food and calorie logging use cases.
+type NutritionService struct {
+	repo domain.FoodRepository
+}
+
+// NewNutritionService creates a NutritionService backed by the given
+// repository.
+func NewNutritionService(repo domain.FoodRepository) *NutritionService {
+	return &NutritionService{repo: repo}
+}
+
+// DayTotals returns the energy and macronutrients logged on the given local
+// day.
+func (s *NutritionService) DayTotals(ctx context.Context, userID int64, day string) (domain.NutritionTotals, error) {
+	return s.repo.FoodTotalsForLocalDay(ctx, userID, day)
+}
+
+// RecordEntry validates and stores a food entry. With e.ClientID set, as
+// queued by an offline client, retrying does not create a duplicate; the
+// stored entry is returned either way along with whether this call created
+// it. A zero e.CreatedAt means now.
+func (s *NutritionService) RecordEntry(ctx context.Context, userID int64, e domain.FoodEntry) (*domain.FoodEntry, bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	var err error
+	if e.ClientID != "" {
+		e.ClientID, err = validateClientWrite(e.ClientID, &e.CreatedAt)
+	} else {
+		err = validateWriteTime(&e.CreatedAt)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	e.Description = strings.TrimSpace(e.Description)
+	if err := validateFoodEntry(e); err != nil {
+		return nil, false, err
+	}
+	return s.repo.AddFoodEntry(ctx, userID, e)
+}
+
+func validateFoodEntry(e domain.FoodEntry) error {
+	if e.Description == "" {
+		return errors.New("description is required")
+	}
+	if len(e.Description) > domain.MaxFoodDescriptionLength {
+		return fmt.Errorf("description must be at most %d bytes", domain.MaxFoodDescriptionLength)
+	}
+	if e.Kcal < 0 || e.Kcal > maxFoodKcal {
+		return fmt.Errorf("kcal must be within [0, %d]", maxFoodKcal)
+	}
+	for _, g := range []struct {
+		name  string
+		value float64
+	}{{"proteinG", e.ProteinG}, {"carbsG", e.CarbsG}, {"fatG", e.FatG}} {
+		if g.value < 0 || g.value > maxFoodGrams {
+			return fmt.Errorf("%s must be within [0, %d]", g.name, maxFoodGrams)
+		}
+	}
+	return nil
+}
+
+// ListRecent returns the most recent food entries up to limit.
+func (s *NutritionService) ListRecent(ctx context.Context, userID int64, limit int) ([]domain.FoodEntry, error) {
+	return s.repo.ListRecentFoodEntries(ctx, userID, limit)
+}
+
+// UndoLast deletes the most recent food entry.
+func (s *NutritionService) UndoLast(ctx context.Context, userID int64) (bool, int64, error) {
+	if err := checkWritable(ctx); err != nil {
+		return false, 0, err
+	}
+	id, err := s.repo.DeleteLatestFoodEntry(ctx, userID)
+	if err != nil {
+		return false, 0, err
+	}
+	return id != 0, id, nil
+}
@@ -0,0 +1,111 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockFoodRepo struct {
+	entries []domain.FoodEntry
+	deleted []int64
+}
+
+func (m *mockFoodRepo) AddFoodEntry(ctx context.Context, userID int64, e domain.FoodEntry) (*domain.FoodEntry, bool, error) {
+	e.ID = int64(len(m.entries) + 1)
+	e.UserID = userID
+	m.entries = append(m.entries, e)
+	return &e, true, nil
+}
+
+func (m *mockFoodRepo) DeleteFoodEntry(ctx context.Context, userID int64, id int64) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *mockFoodRepo) DeleteLatestFoodEntry(ctx context.Context, userID int64) (int64, error) {
+	if len(m.entries) == 0 {
+		return 0, nil
+	}
+	last := m.entries[len(m.entries)-1]
+	m.entries = m.entries[:len(m.entries)-1]
+	m.deleted = append(m.deleted, last.ID)
+	return last.ID, nil
+}
+
+func (m *mockFoodRepo) ListRecentFoodEntries(ctx context.Context, userID int64, limit int) ([]domain.FoodEntry, error) {
+	var out []domain.FoodEntry
+	for i := len(m.entries) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, m.entries[i])
+	}
+	return out, nil
+}
+
+func (m *mockFoodRepo) FoodTotalsForLocalDay(ctx context.Context, userID int64, localDay string) (domain.NutritionTotals, error) {
+	var t domain.NutritionTotals
+	for _, e := range m.entries {
+		t.Add(e)
+	}
+	return t, nil
+}
+
+func TestNutritionService_RecordEntry(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockFoodRepo{}
+	svc := app.NewNutritionService(repo)
+
+	invalid := []domain.FoodEntry{
+		{Description: " ", Kcal: 100},
+		{Description: strings.Repeat("x", domain.MaxFoodDescriptionLength+1), Kcal: 100},
+		{Description: "toast", Kcal: -1},
+		{Description: "toast", Kcal: 100, FatG: 1001},
+		{Description: "toast", Kcal: 100, ClientID: "not-a-uuid"},
+		{Description: "toast", Kcal: 100, CreatedAt: time.Now().Add(time.Hour)},
+	}
+	for _, e := range invalid {
+		if _, _, err := svc.RecordEntry(ctx, 1, e); err == nil {
+			t.Errorf("expected %+v to be rejected", e)
+		}
+	}
+	if len(repo.entries) != 0 {
+		t.Fatalf("expected nothing stored, got %+v", repo.entries)
+	}
+
+	e, created, err := svc.RecordEntry(ctx, 1, domain.FoodEntry{Description: " porridge ", Kcal: 300, ProteinG: 10})
+	if err != nil || !created {
+		t.Fatalf("RecordEntry: %v, created %v", err, created)
+	}
+	if e.Description != "porridge" || e.CreatedAt.IsZero() {
+		t.Errorf("expected a trimmed description and a timestamp, got %+v", e)
+	}
+
+	if _, _, err := svc.RecordEntry(app.WithReadOnly(ctx), 1, domain.FoodEntry{Description: "toast", Kcal: 100}); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestNutritionService_UndoLast(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockFoodRepo{}
+	svc := app.NewNutritionService(repo)
+
+	if undone, _, err := svc.UndoLast(ctx, 1); err != nil || undone {
+		t.Fatalf("expected nothing to undo, got %v, %v", undone, err)
+	}
+	_, _, _ = svc.RecordEntry(ctx, 1, domain.FoodEntry{Description: "toast", Kcal: 100})
+	_, _, _ = svc.RecordEntry(ctx, 1, domain.FoodEntry{Description: "jam", Kcal: 50})
+
+	totals, _ := svc.DayTotals(ctx, 1, "2026-05-04")
+	if totals.Kcal != 150 || totals.Entries != 2 {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+	undone, id, err := svc.UndoLast(ctx, 1)
+	if err != nil || !undone || id != 2 || len(repo.deleted) != 1 || repo.deleted[0] != 2 {
+		t.Errorf("expected entry 2 undone, got %v, %d, %v", undone, id, err)
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// maxClientClockSkew bounds how far in the future an offline client may date
+// a queued write.
+const maxClientClockSkew = 5 * time.Minute
+
+// validateClientWrite normalizes the client ID of an offline write and
+// defaults a zero *at to now.
+func validateClientWrite(clientID string, at *time.Time) (string, error) {
+	id, ok := domain.NormalizeClientID(clientID)
+	if !ok {
+		return "", errors.New("clientId must be a UUID")
+	}
+	return id, validateWriteTime(at)
+}
+
+// validateWriteTime defaults a zero *at to now and rejects timestamps too
+// far in the future.
+func validateWriteTime(at *time.Time) error {
+	now := time.Now()
+	if at.IsZero() {
+		*at = now
+	} else if at.After(now.Add(maxClientClockSkew)) {
+		return errors.New("createdAt must not be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	// outboxBatch is how many messages a drain claims at a time.
+	outboxBatch = 50
+	// outboxLease is how long a claimed message is reserved for its
+	// worker; a crashed worker's messages are due again after it.
+	outboxLease = 2 * time.Minute
+	// outboxRetryBase and outboxRetryMax bound the exponential backoff
+	// between failed deliveries.
+	outboxRetryBase = 30 * time.Second
+	outboxRetryMax  = 6 * time.Hour
+	// outboxMaxAttempts is how many deliveries are tried, over about two
+	// days, before the message is given up on.
+	outboxMaxAttempts = 16
+)
+
+// OutboxService delivers the notifications queued in the outbox, retrying
+// failed deliveries with exponential backoff. Several instances may drain
+// the same outbox: each message is leased to one of them at a time.
+type OutboxService struct {
+	repo      domain.OutboxRepository
+	notifiers map[string]domain.Notifier
+	now       func() time.Time
+}
+
+// NewOutboxService creates an OutboxService draining repo. Channels become
+// deliverable as notifiers are registered with WithNotifier.
+func NewOutboxService(repo domain.OutboxRepository) *OutboxService {
+	return &OutboxService{repo: repo, notifiers: map[string]domain.Notifier{}, now: time.Now}
+}
+
+// WithNotifier registers n as the notifier for channel.
+func (s *OutboxService) WithNotifier(channel string, n domain.Notifier) *OutboxService {
+	s.notifiers[channel] = n
+	return s
+}
+
+// Run drains the outbox every interval until ctx is done.
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Drain(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain delivers every message due now and returns how many were
+// delivered. Failed deliveries are rescheduled, not returned as errors.
+func (s *OutboxService) Drain(ctx context.Context) (delivered int, err error) {
+	ctx, span := startSpan(ctx, "OutboxService.Drain")
+	defer func() { endSpan(span, err) }()
+
+	for {
+		now := s.now()
+		msgs, err := s.repo.ClaimOutbox(ctx, now, now.Add(outboxLease), outboxBatch)
+		if err != nil {
+			return delivered, err
+		}
+		for _, m := range msgs {
+			ok, err := s.deliver(ctx, m)
+			if err != nil {
+				return delivered, err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if len(msgs) < outboxBatch {
+			return delivered, nil
+		}
+	}
+}
+
+// deliver sends m, deleting it once delivered and rescheduling it
+// otherwise. It reports whether m was delivered.
+func (s *OutboxService) deliver(ctx context.Context, m domain.OutboxMessage) (bool, error) {
+	var sendErr error
+	if notifier, ok := s.notifiers[m.Channel]; ok {
+		sendErr = notifier.Notify(ctx, m.Notification)
+	} else {
+		sendErr = fmt.Errorf("channel %q is not available", m.Channel)
+	}
+	if sendErr == nil {
+		return true, s.repo.DeleteOutbox(ctx, m.ID)
+	}
+
+	attempts := m.Attempts + 1
+	if attempts >= outboxMaxAttempts {
+		log.Printf("outbox: giving up on %s notification %d for user %d after %d attempts: %v", m.Channel, m.ID, m.Notification.UserID, attempts, sendErr)
+		return false, s.repo.FailOutbox(ctx, m.ID, sendErr.Error(), nil)
+	}
+	next := s.now().Add(outboxBackoff(attempts))
+	return false, s.repo.FailOutbox(ctx, m.ID, sendErr.Error(), &next)
+}
+
+// outboxBackoff is the wait before the next delivery after attempts
+// failed ones.
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxRetryBase
+	for i := 1; i < attempts && d < outboxRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, outboxRetryMax)
+}
@@ -0,0 +1,149 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockOutboxRepo struct {
+	msgs   []domain.OutboxMessage
+	nextID int64
+}
+
+func (m *mockOutboxRepo) EnqueueOutbox(ctx context.Context, msgs ...domain.OutboxMessage) error {
+	for _, msg := range msgs {
+		m.nextID++
+		msg.ID = m.nextID
+		due := time.Time{}
+		msg.NextAttemptAt = &due
+		m.msgs = append(m.msgs, msg)
+	}
+	return nil
+}
+
+func (m *mockOutboxRepo) ClaimOutbox(ctx context.Context, now, until time.Time, limit int) ([]domain.OutboxMessage, error) {
+	var out []domain.OutboxMessage
+	for i, msg := range m.msgs {
+		if len(out) < limit && msg.NextAttemptAt != nil && !msg.NextAttemptAt.After(now) {
+			m.msgs[i].NextAttemptAt = &until
+			out = append(out, m.msgs[i])
+		}
+	}
+	return out, nil
+}
+
+func (m *mockOutboxRepo) DeleteOutbox(ctx context.Context, id int64) error {
+	m.msgs = slices.DeleteFunc(m.msgs, func(msg domain.OutboxMessage) bool { return msg.ID == id })
+	return nil
+}
+
+func (m *mockOutboxRepo) FailOutbox(ctx context.Context, id int64, lastErr string, next *time.Time) error {
+	for i := range m.msgs {
+		if m.msgs[i].ID == id {
+			m.msgs[i].Attempts++
+			m.msgs[i].LastError = lastErr
+			m.msgs[i].NextAttemptAt = next
+		}
+	}
+	return nil
+}
+
+// flakyNotifier fails while down is set.
+type flakyNotifier struct {
+	recordingNotifier
+	down bool
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, msg domain.Notification) error {
+	if n.down {
+		return errors.New("connection refused")
+	}
+	return n.recordingNotifier.Notify(ctx, msg)
+}
+
+func TestAlertService_Outbox(t *testing.T) {
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	outbox := &mockOutboxRepo{}
+	svc, rules, notifier := newAlertFixture(now, 97)
+	svc.WithOutbox(outbox)
+	rules.rules[1] = domain.AlertRule{UserID: 1, MaxWeeklyChangePct: 2, Channel: domain.AlertChannelWebhook, Target: "https://example.com/hook", Enabled: true}
+
+	// The alert is queued with the rule marked alerted, not sent.
+	n, err := svc.Evaluate(ctx, 1, now)
+	if err != nil || n == nil {
+		t.Fatalf("Evaluate = %v, %v", n, err)
+	}
+	if len(notifier.sent) != 0 || len(rules.queued) != 1 || rules.rules[1].LastAlertedAt == nil {
+		t.Fatalf("expected the alert queued with the rule marked, got %d sent, %d queued", len(notifier.sent), len(rules.queued))
+	}
+	q := rules.queued[0]
+	if q.Channel != domain.AlertChannelWebhook || q.Notification.Target != "https://example.com/hook" || q.Notification.UserID != 1 {
+		t.Errorf("unexpected outbox message %+v", q)
+	}
+
+	// Deliver queues through the outbox repository.
+	if ok, err := svc.Deliver(ctx, domain.Notification{UserID: 1, Kind: "archive.ready"}); err != nil || !ok {
+		t.Fatalf("Deliver = %v, %v", ok, err)
+	}
+	if len(outbox.msgs) != 1 || outbox.msgs[0].Notification.Target != "https://example.com/hook" || len(notifier.sent) != 0 {
+		t.Fatalf("expected Deliver to queue, got %+v", outbox.msgs)
+	}
+}
+
+func TestOutboxService_Drain(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockOutboxRepo{}
+	notifier := &flakyNotifier{down: true}
+	svc := app.NewOutboxService(repo).WithNotifier(domain.AlertChannelWebhook, notifier)
+	_ = repo.EnqueueOutbox(ctx,
+		domain.OutboxMessage{Channel: domain.AlertChannelWebhook, Notification: domain.Notification{UserID: 1, Title: "a"}},
+		domain.OutboxMessage{Channel: domain.AlertChannelMQTT, Notification: domain.Notification{UserID: 2, Title: "b"}},
+	)
+
+	// Failed deliveries are kept and retried later, not immediately.
+	if n, err := svc.Drain(ctx); err != nil || n != 0 {
+		t.Fatalf("Drain = %d, %v", n, err)
+	}
+	if len(repo.msgs) != 2 || repo.msgs[0].Attempts != 1 || repo.msgs[0].LastError != "connection refused" ||
+		repo.msgs[1].LastError != `channel "mqtt" is not available` {
+		t.Fatalf("unexpected outbox after failures: %+v", repo.msgs)
+	}
+	if wait := time.Until(*repo.msgs[0].NextAttemptAt); wait < 20*time.Second || wait > time.Minute {
+		t.Errorf("first retry in %v, want about 30s", wait)
+	}
+	if n, _ := svc.Drain(ctx); n != 0 || repo.msgs[0].Attempts != 1 {
+		t.Fatalf("expected nothing due, delivered %d", n)
+	}
+
+	// Once due and the endpoint is back, the message is delivered and
+	// removed.
+	notifier.down = false
+	past := time.Now().Add(-time.Second)
+	repo.msgs[0].NextAttemptAt = &past
+	if n, err := svc.Drain(ctx); err != nil || n != 1 {
+		t.Fatalf("Drain = %d, %v", n, err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Title != "a" || len(repo.msgs) != 1 {
+		t.Fatalf("expected a delivered, got sent %v, outbox %+v", notifier.sent, repo.msgs)
+	}
+
+	// A message that keeps failing is eventually given up on, and kept.
+	for range 20 {
+		if repo.msgs[0].NextAttemptAt == nil {
+			break
+		}
+		repo.msgs[0].NextAttemptAt = &past
+		_, _ = svc.Drain(ctx)
+	}
+	if repo.msgs[0].NextAttemptAt != nil || repo.msgs[0].Attempts != 16 {
+		t.Errorf("expected delivery given up after 16 attempts, got %+v", repo.msgs[0])
+	}
+}
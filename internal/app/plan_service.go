@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrPlannedEntryNotFound is returned when the user has no planned entry
+// with the given id.
+var ErrPlannedEntryNotFound = errors.New("planned entry not found")
+
+// NotificationKindPlan prefixes the kind of planned entry reminders, e.g.
+// "plan.weight".
+const NotificationKindPlan = "plan."
+
+// planDefaultDays is how far ahead List looks when not given an end day.
+const planDefaultDays = 90
+
+// PlanService manages planned entries and sends their reminders from the
+// scheduled `vitals alerts check`.
+type PlanService struct {
+	plans     domain.PlanRepository
+	weight    domain.WeightRepository
+	water     domain.WaterRepository
+	notifiers map[string]domain.Notifier
+	outbox    domain.OutboxRepository
+}
+
+// NewPlanService creates a PlanService backed by the given repositories.
+// Reminder channels become available as notifiers are registered with
+// WithNotifier.
+func NewPlanService(plans domain.PlanRepository, weight domain.WeightRepository, water domain.WaterRepository) *PlanService {
+	return &PlanService{plans: plans, weight: weight, water: water, notifiers: map[string]domain.Notifier{}}
+}
+
+// WithNotifier registers n as the notifier for channel.
+func (s *PlanService) WithNotifier(channel string, n domain.Notifier) *PlanService {
+	s.notifiers[channel] = n
+	return s
+}
+
+// WithOutbox queues reminders in outbox, for an OutboxService to deliver,
+// in the same transaction as marking the entry reminded.
+func (s *PlanService) WithOutbox(outbox domain.OutboxRepository) *PlanService {
+	s.outbox = outbox
+	return s
+}
+
+// Channels lists the channels reminders can be sent over.
+func (s *PlanService) Channels() []string {
+	return channels(s.notifiers)
+}
+
+// List returns the user's planned entries for days from through to
+// ("YYYY-MM-DD"). An empty from means today, an empty to planDefaultDays
+// after from.
+func (s *PlanService) List(ctx context.Context, userID int64, from, to string) ([]domain.PlannedEntry, error) {
+	if from == "" {
+		from = time.Now().In(time.Local).Format("2006-01-02")
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, errors.New("from must be YYYY-MM-DD")
+	}
+	if to == "" {
+		to = start.AddDate(0, 0, planDefaultDays).Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", to); err != nil {
+		return nil, errors.New("to must be YYYY-MM-DD")
+	}
+	if to < from {
+		return nil, errors.New("to must not be before from")
+	}
+	return s.plans.ListPlannedEntries(ctx, userID, from, to)
+}
+
+// Create validates and stores a planned entry for today or later.
+func (s *PlanService) Create(ctx context.Context, p domain.PlannedEntry) (*domain.PlannedEntry, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(&p); err != nil {
+		return nil, err
+	}
+	today := time.Now().In(time.Local).Format("2006-01-02")
+	if p.Day < today {
+		return nil, errors.New("day must be today or later")
+	}
+	n, err := s.plans.CountPlannedEntries(ctx, p.UserID, today)
+	if err != nil {
+		return nil, err
+	}
+	if n >= domain.MaxUpcomingPlannedEntries {
+		return nil, fmt.Errorf("at most %d upcoming planned entries per user", domain.MaxUpcomingPlannedEntries)
+	}
+	p.RemindedAt = nil
+	p.CreatedAt = time.Now().UTC()
+	return s.plans.CreatePlannedEntry(ctx, p)
+}
+
+// Update validates and replaces an existing planned entry. Its reminder is
+// re-armed, so moving an entry reminds on the new day.
+func (s *PlanService) Update(ctx context.Context, p domain.PlannedEntry) (*domain.PlannedEntry, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(&p); err != nil {
+		return nil, err
+	}
+	ok, err := s.plans.UpdatePlannedEntry(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPlannedEntryNotFound
+	}
+	return s.plans.GetPlannedEntry(ctx, p.UserID, p.ID)
+}
+
+// Delete removes the user's planned entry.
+func (s *PlanService) Delete(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.plans.DeletePlannedEntry(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPlannedEntryNotFound
+	}
+	return nil
+}
+
+// validate checks p and its reminder channel, if any.
+func (s *PlanService) validate(p *domain.PlannedEntry) error {
+	p.Note = strings.TrimSpace(p.Note)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.Channel == "" {
+		p.Target = ""
+		return nil
+	}
+	return validateChannel(s.notifiers, p.Channel, &p.Target)
+}
+
+// RemindAll sends the reminders of today's planned entries whose time has
+// passed and that were not logged yet, and returns how many it sent. Like
+// time-of-day rules, schedule it every few minutes.
+func (s *PlanService) RemindAll(ctx context.Context, now time.Time) (int, error) {
+	entries, err := s.plans.ListPlanReminders(ctx, now.In(time.Local).Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, p := range entries {
+		n, err := s.remind(ctx, p, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %d planned entry %d: %w", p.UserID, p.ID, err))
+			continue
+		}
+		if n != nil {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// remind notifies p's channel if p is due and not logged yet.
+func (s *PlanService) remind(ctx context.Context, p domain.PlannedEntry, now time.Time) (*domain.Notification, error) {
+	if !p.Due(now) {
+		return nil, nil
+	}
+	notifier, ok := s.notifiers[p.Channel]
+	if !ok {
+		return nil, fmt.Errorf("channel %q is not available", p.Channel)
+	}
+
+	var title, message string
+	switch p.Kind {
+	case domain.PlanKindWeight:
+		entry, err := s.weight.LatestWeightForLocalDay(ctx, p.UserID, p.Day)
+		if err != nil || entry != nil {
+			return nil, err
+		}
+		title, message = "Planned weigh-in", "You planned to weigh in today."
+	case domain.PlanKindWater:
+		liters, err := waterTotal(ctx, s.water, p.UserID, p.Day)
+		if err != nil {
+			return nil, err
+		}
+		if p.Liters > 0 && liters >= p.Liters || p.Liters == 0 && liters > 0 {
+			return nil, nil
+		}
+		title = "Planned water"
+		message = fmt.Sprintf("You planned to drink %g L today; %.1f L logged so far.", p.Liters, liters)
+		if p.Liters == 0 {
+			message = "You planned to log water today."
+		}
+	}
+	if p.Note != "" {
+		message += " " + p.Note
+	}
+
+	n := domain.Notification{
+		UserID:  p.UserID,
+		Kind:    NotificationKindPlan + p.Kind,
+		Title:   title,
+		Message: message,
+		At:      now,
+		Target:  p.Target,
+	}
+	if s.outbox != nil {
+		if err := s.plans.MarkPlanReminded(ctx, p.UserID, p.ID, now, domain.OutboxMessage{Channel: p.Channel, Notification: n}); err != nil {
+			return nil, err
+		}
+		return &n, nil
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return nil, err
+	}
+	if err := s.plans.MarkPlanReminded(ctx, p.UserID, p.ID, now); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -0,0 +1,208 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockPlanRepo struct {
+	entries []domain.PlannedEntry
+	nextID  int64
+}
+
+func (m *mockPlanRepo) CreatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (*domain.PlannedEntry, error) {
+	m.nextID++
+	p.ID = m.nextID
+	m.entries = append(m.entries, p)
+	return &p, nil
+}
+
+func (m *mockPlanRepo) GetPlannedEntry(ctx context.Context, userID, id int64) (*domain.PlannedEntry, error) {
+	for _, p := range m.entries {
+		if p.UserID == userID && p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockPlanRepo) ListPlannedEntries(ctx context.Context, userID int64, from, to string) ([]domain.PlannedEntry, error) {
+	var out []domain.PlannedEntry
+	for _, p := range m.entries {
+		if p.UserID == userID && p.Day >= from && p.Day <= to {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockPlanRepo) CountPlannedEntries(ctx context.Context, userID int64, from string) (int, error) {
+	n := 0
+	for _, p := range m.entries {
+		if p.UserID == userID && p.Day >= from {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockPlanRepo) UpdatePlannedEntry(ctx context.Context, p domain.PlannedEntry) (bool, error) {
+	for i, old := range m.entries {
+		if old.UserID == p.UserID && old.ID == p.ID {
+			p.CreatedAt, p.RemindedAt = old.CreatedAt, nil
+			m.entries[i] = p
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPlanRepo) DeletePlannedEntry(ctx context.Context, userID, id int64) (bool, error) {
+	for i, p := range m.entries {
+		if p.UserID == userID && p.ID == id {
+			m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPlanRepo) ListPlanReminders(ctx context.Context, day string) ([]domain.PlannedEntry, error) {
+	var out []domain.PlannedEntry
+	for _, p := range m.entries {
+		if p.Day == day && p.Channel != "" && p.RemindedAt == nil {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockPlanRepo) MarkPlanReminded(ctx context.Context, userID, id int64, at time.Time, _ ...domain.OutboxMessage) error {
+	for i, p := range m.entries {
+		if p.UserID == userID && p.ID == id {
+			m.entries[i].RemindedAt = &at
+		}
+	}
+	return nil
+}
+
+func TestPlanService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockPlanRepo{}
+	svc := app.NewPlanService(repo, &mockWeightRepo{}, &mockWaterRepo{}).
+		WithNotifier(domain.AlertChannelMQTT, &recordingNotifier{})
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	entry, err := svc.Create(ctx, domain.PlannedEntry{
+		UserID: 1, Kind: domain.PlanKindWeight, Day: tomorrow, At: "07:30", Liters: 2,
+		Note: "  before breakfast ", Channel: domain.AlertChannelMQTT, Target: "ignored",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if entry.Liters != 0 || entry.Note != "before breakfast" || entry.Target != "" {
+		t.Errorf("expected cleared liters and target and a trimmed note, got %+v", entry)
+	}
+
+	for _, p := range []domain.PlannedEntry{
+		{UserID: 1, Kind: domain.PlanKindWeight, Day: "2020-01-01"},
+		{UserID: 1, Kind: "steps", Day: tomorrow},
+		{UserID: 1, Kind: domain.PlanKindWater, Day: tomorrow, Channel: domain.AlertChannelMQTT},
+		{UserID: 1, Kind: domain.PlanKindWater, Day: tomorrow, At: "09:00", Channel: domain.AlertChannelWebhook},
+		{UserID: 1, Kind: domain.PlanKindWater, Day: tomorrow, Note: strings.Repeat("x", domain.MaxPlanNoteLength+1)},
+	} {
+		if _, err := svc.Create(ctx, p); err == nil {
+			t.Errorf("expected Create %+v to fail", p)
+		}
+	}
+
+	items, err := svc.List(ctx, 1, "", "")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List = %+v, %v", items, err)
+	}
+	if items, _ := svc.List(ctx, 1, today, today); len(items) != 0 {
+		t.Errorf("expected no entries today, got %+v", items)
+	}
+	if _, err := svc.List(ctx, 1, tomorrow, today); err == nil {
+		t.Error("expected error for a range ending before it starts")
+	}
+
+	entry.Day, entry.Kind, entry.Liters = today, domain.PlanKindWater, 2.5
+	updated, err := svc.Update(ctx, *entry)
+	if err != nil || updated.Day != today || updated.Liters != 2.5 {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if _, err := svc.Update(ctx, domain.PlannedEntry{ID: entry.ID, UserID: 2, Kind: domain.PlanKindWeight, Day: today}); !errors.Is(err, app.ErrPlannedEntryNotFound) {
+		t.Errorf("expected ErrPlannedEntryNotFound updating another user's entry, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, 1, entry.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := svc.Delete(ctx, 1, entry.ID); !errors.Is(err, app.ErrPlannedEntryNotFound) {
+		t.Errorf("expected ErrPlannedEntryNotFound on second delete, got %v", err)
+	}
+}
+
+func TestPlanService_RemindAll(t *testing.T) {
+	ctx := context.Background()
+	y, mo, d := time.Now().Date()
+	now := time.Date(y, mo, d, 15, 0, 0, 0, time.Local)
+	today := now.Format("2006-01-02")
+
+	repo := &mockPlanRepo{}
+	weights := &mockWeightRepo{
+		latestFn: func(_ context.Context, userID int64, _ string) (*domain.WeightEntry, error) {
+			if userID == 2 {
+				return &domain.WeightEntry{Value: 80, Unit: "kg"}, nil
+			}
+			return nil, nil
+		},
+	}
+	water := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 1, nil },
+	}
+	notifier := &recordingNotifier{}
+	svc := app.NewPlanService(repo, weights, water).WithNotifier(domain.AlertChannelMQTT, notifier)
+
+	for _, p := range []domain.PlannedEntry{
+		{UserID: 1, Kind: domain.PlanKindWeight, At: "08:00"},              // reminds
+		{UserID: 2, Kind: domain.PlanKindWeight, At: "08:00"},              // already weighed in
+		{UserID: 1, Kind: domain.PlanKindWeight, At: "18:00"},              // not yet due
+		{UserID: 1, Kind: domain.PlanKindWater, At: "12:00", Liters: 2},    // 1 L < 2 L: reminds
+		{UserID: 1, Kind: domain.PlanKindWater, At: "12:00", Liters: 0.75}, // reached
+		{UserID: 1, Kind: domain.PlanKindWater, At: "12:00"},               // water was logged
+		{UserID: 1, Kind: domain.PlanKindWater},                            // calendar only
+	} {
+		p.Day = today
+		if p.At != "" {
+			p.Channel = domain.AlertChannelMQTT
+		}
+		if _, err := svc.Create(ctx, p); err != nil {
+			t.Fatalf("Create %+v: %v", p, err)
+		}
+	}
+
+	sent, err := svc.RemindAll(ctx, now)
+	if err != nil {
+		t.Fatalf("RemindAll failed: %v", err)
+	}
+	if sent != 2 || len(notifier.sent) != 2 {
+		t.Fatalf("expected 2 reminders, got %d: %+v", sent, notifier.sent)
+	}
+	if got := notifier.sent[0]; got.Kind != "plan.weight" || got.UserID != 1 {
+		t.Errorf("first reminder = %+v", got)
+	}
+
+	// Each entry reminds once.
+	if sent, _ := svc.RemindAll(ctx, now.Add(30*time.Minute)); sent != 0 {
+		t.Errorf("expected no repeat reminders, got %d", sent)
+	}
+}
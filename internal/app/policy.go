@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"slices"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrAdminRequired indicates that only admins may use an endpoint.
+	ErrAdminRequired = errors.New("admin role required")
+	// ErrWriteOnly indicates that a write-only token tried to read.
+	ErrWriteOnly = errors.New("token may only log data")
+	// ErrNotShared indicates that a viewer of a share addressed an
+	// endpoint shares do not cover.
+	ErrNotShared = errors.New("shares only cover charts and recent entries")
+)
+
+// How a caller authenticated.
+const (
+	// CredentialSession is a session cookie or a trusted forward-auth header.
+	CredentialSession = "session"
+	// CredentialGuest is the read-only guest account of a demo instance.
+	CredentialGuest = "guest"
+	// CredentialToken is an API token, limited to its scope.
+	CredentialToken = "token"
+)
+
+// Principal is the caller a request acts for, as authenticated by a driving
+// adapter, together with whose data it addresses.
+type Principal struct {
+	User       *domain.User
+	Credential string
+	// Scope is the scope of the API token, for CredentialToken.
+	Scope string
+	// OwnerID is set when the request addresses another user's data that
+	// the owner shared with User.
+	OwnerID int64
+}
+
+// ReadOnly reports whether the principal may only read: guests, viewers of
+// a share and every token but a quick-logging, API or write-only one.
+func (p Principal) ReadOnly() bool {
+	switch {
+	case p.Credential == CredentialGuest, p.OwnerID != 0:
+		return true
+	case p.Credential == CredentialToken:
+		switch p.Scope {
+		case domain.TokenScopeQuick, domain.TokenScopeAPI, domain.TokenScopeWeightWrite, domain.TokenScopeWaterWrite:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// WriteOnly reports whether the principal may only write: tokens scoped
+// to logging one metric.
+func (p Principal) WriteOnly() bool {
+	return p.Credential == CredentialToken &&
+		(p.Scope == domain.TokenScopeWeightWrite || p.Scope == domain.TokenScopeWaterWrite)
+}
+
+// Policy is what an endpoint requires of its caller. A single
+// authorization middleware authenticates the caller with the credentials
+// the policy accepts and asks it whether the request may proceed, so
+// endpoints state their access rules in one place rather than by how they
+// are wrapped.
+type Policy struct {
+	// Admin limits the endpoint to admins.
+	Admin bool
+	// Scopes lists the API token scopes accepted besides a session.
+	Scopes []string
+	// TokenOnly refuses sessions, for endpoints called by automations.
+	TokenOnly bool
+	// Subject marks endpoints on one subject's metric data, which the
+	// caller picks among their own and a profile's.
+	Subject bool
+	// Shared lets viewers of a share read a Subject endpoint for the
+	// share's owner (?user=); other endpoints refuse them.
+	Shared bool
+}
+
+// The policies of the API's endpoints.
+var (
+	// PolicyAccount is for a signed-in user's own account and settings.
+	PolicyAccount = Policy{}
+	// PolicyOwner is for the caller's own configuration and exports, which
+	// api and read-only tokens may use too. It never addresses a profile
+	// or a share.
+	PolicyOwner = Policy{Scopes: []string{domain.TokenScopeAPI, domain.TokenScopeReadOnly}}
+	// PolicyMetric is for reading and logging metric data.
+	PolicyMetric = Policy{Subject: true, Scopes: []string{domain.TokenScopeAPI, domain.TokenScopeReadOnly}}
+	// PolicyWeightLog is for logging and undoing weigh-ins, which
+	// weight:write tokens may do too.
+	PolicyWeightLog = Policy{Subject: true, Scopes: []string{domain.TokenScopeAPI, domain.TokenScopeReadOnly, domain.TokenScopeWeightWrite}}
+	// PolicyWaterLog is for logging and undoing water, which water:write
+	// tokens may do too.
+	PolicyWaterLog = Policy{Subject: true, Scopes: []string{domain.TokenScopeAPI, domain.TokenScopeReadOnly, domain.TokenScopeWaterWrite}}
+	// PolicyDashboard is for aggregates, which dashboard tokens may read too.
+	PolicyDashboard = Policy{Subject: true, Scopes: []string{domain.TokenScopeDashboard, domain.TokenScopeAPI, domain.TokenScopeReadOnly}}
+	// PolicySharedEntries is PolicyMetric for listing recent entries, which
+	// viewers of a share may read too.
+	PolicySharedEntries = Policy{Subject: true, Shared: true, Scopes: []string{domain.TokenScopeAPI, domain.TokenScopeReadOnly}}
+	// PolicySharedCharts is PolicyDashboard for charts, which viewers of a
+	// share may read too.
+	PolicySharedCharts = Policy{Subject: true, Shared: true, Scopes: []string{domain.TokenScopeDashboard, domain.TokenScopeAPI, domain.TokenScopeReadOnly}}
+	// PolicyAdmin is for instance-wide operations.
+	PolicyAdmin = Policy{Admin: true}
+	// PolicyQuickWeight and PolicyQuickWater are for the one-tap logging
+	// endpoints.
+	PolicyQuickWeight = Policy{TokenOnly: true, Scopes: []string{domain.TokenScopeQuick, domain.TokenScopeWeightWrite}}
+	PolicyQuickWater  = Policy{TokenOnly: true, Scopes: []string{domain.TokenScopeQuick, domain.TokenScopeWaterWrite}}
+	// PolicyFeed is for the calendar and news feeds.
+	PolicyFeed = Policy{TokenOnly: true, Scopes: []string{domain.TokenScopeFeed}}
+)
+
+// Accepts reports whether the policy accepts tokens of scope.
+func (p Policy) Accepts(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Authorize reports whether pr may make a request to the endpoint; write
+// is whether the request may change data. It returns ErrAdminRequired,
+// ErrNotShared, ErrReadOnly or ErrWriteOnly when it may not. Authorize
+// assumes pr authenticated with a credential the policy accepts.
+func (p Policy) Authorize(pr Principal, write bool) error {
+	if p.Admin {
+		// Guests are refused even when the guest account is an admin.
+		if pr.Credential != CredentialSession {
+			return ErrReadOnly
+		}
+		if !pr.User.IsAdmin() {
+			return ErrAdminRequired
+		}
+	}
+	if pr.OwnerID != 0 && !p.Shared {
+		return ErrNotShared
+	}
+	if write && pr.ReadOnly() {
+		return ErrReadOnly
+	}
+	if !write && pr.WriteOnly() {
+		return ErrWriteOnly
+	}
+	return nil
+}
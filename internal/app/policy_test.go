@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"errors"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestPolicyAuthorize(t *testing.T) {
+	user := &domain.User{ID: 1, Role: domain.RoleUser}
+	admin := &domain.User{ID: 2, Role: domain.RoleAdmin}
+	session := app.Principal{User: user, Credential: app.CredentialSession}
+	viewer := app.Principal{User: user, Credential: app.CredentialSession, OwnerID: 3}
+	guest := app.Principal{User: admin, Credential: app.CredentialGuest}
+	dashboard := app.Principal{User: user, Credential: app.CredentialToken, Scope: domain.TokenScopeDashboard}
+	quick := app.Principal{User: user, Credential: app.CredentialToken, Scope: domain.TokenScopeQuick}
+	readOnly := app.Principal{User: user, Credential: app.CredentialToken, Scope: domain.TokenScopeReadOnly}
+	water := app.Principal{User: user, Credential: app.CredentialToken, Scope: domain.TokenScopeWaterWrite}
+	script := app.Principal{User: user, Credential: app.CredentialToken, Scope: domain.TokenScopeAPI}
+
+	tests := []struct {
+		name   string
+		policy app.Policy
+		pr     app.Principal
+		write  bool
+		want   error
+	}{
+		{"user writes own data", app.PolicyMetric, session, true, nil},
+		{"viewer reads shared entries", app.PolicySharedEntries, viewer, false, nil},
+		{"viewer reads shared charts", app.PolicySharedCharts, viewer, false, nil},
+		{"viewer writes share", app.PolicySharedEntries, viewer, true, app.ErrReadOnly},
+		{"viewer reads unshared endpoint", app.PolicyMetric, viewer, false, app.ErrNotShared},
+		{"viewer reads unshared aggregate", app.PolicyDashboard, viewer, false, app.ErrNotShared},
+		{"guest reads", app.PolicyAccount, guest, false, nil},
+		{"guest writes", app.PolicyAccount, guest, true, app.ErrReadOnly},
+		{"dashboard token reads", app.PolicyDashboard, dashboard, false, nil},
+		{"dashboard token writes", app.PolicyDashboard, dashboard, true, app.ErrReadOnly},
+		{"quick token writes", app.PolicyQuickWater, quick, true, nil},
+		{"read-only token reads", app.PolicyMetric, readOnly, false, nil},
+		{"read-only token writes", app.PolicyWeightLog, readOnly, true, app.ErrReadOnly},
+		{"water token writes", app.PolicyWaterLog, water, true, nil},
+		{"water token reads", app.PolicyWaterLog, water, false, app.ErrWriteOnly},
+		{"API token writes", app.PolicyMetric, script, true, nil},
+		{"user on admin endpoint", app.PolicyAdmin, session, false, app.ErrAdminRequired},
+		{"admin on admin endpoint", app.PolicyAdmin, app.Principal{User: admin, Credential: app.CredentialSession}, true, nil},
+		{"admin guest on admin endpoint", app.PolicyAdmin, guest, false, app.ErrReadOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Authorize(tt.pr, tt.write); !errors.Is(err, tt.want) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
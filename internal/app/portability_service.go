@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// AccountBundleVersion is the current format version of account bundles.
+const AccountBundleVersion = 1
+
+// ImportFormatAccount names import jobs restoring an account bundle's events.
+const ImportFormatAccount = "account"
+
+// AccountBundle is everything stored for a user in one JSON document, for
+// moving their account to another instance.
+type AccountBundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Profile    Account   `json:"profile"`
+	// Weight and Water hold every event, oldest first.
+	Weight []domain.WeightEntry `json:"weight"`
+	Water  []domain.WaterEvent  `json:"water"`
+	// Sessions describes the devices signed in when the bundle was made;
+	// it holds no tokens.
+	Sessions []DeviceSession      `json:"sessions"`
+	Config   *domain.ConfigBundle `json:"config,omitempty"`
+}
+
+// AccountImportResult reports how an account bundle is being restored.
+type AccountImportResult struct {
+	// Job imports the bundle's events in the background.
+	Job    ImportJob     `json:"job"`
+	Config *ImportResult `json:"config,omitempty"`
+}
+
+// PortabilityService exports a user's whole account as an AccountBundle and
+// restores one into the signed-in account of another instance.
+type PortabilityService struct {
+	accounts *AccountService
+	auth     *AuthService
+	weight   domain.WeightRepository
+	water    domain.WaterRepository
+	imports  *ImportService
+	config   *ConfigService
+}
+
+// NewPortabilityService creates a PortabilityService. Restored events are
+// written by imports, so they can be followed and rolled back like any
+// other import job.
+func NewPortabilityService(accounts *AccountService, auth *AuthService, weight domain.WeightRepository, water domain.WaterRepository, imports *ImportService) *PortabilityService {
+	return &PortabilityService{accounts: accounts, auth: auth, weight: weight, water: water, imports: imports}
+}
+
+// WithConfig adds the user's configuration bundle to exports and applies it
+// on import.
+func (s *PortabilityService) WithConfig(c *ConfigService) *PortabilityService {
+	s.config = c
+	return s
+}
+
+// Export returns the user's account bundle. It lists the account's
+// sessions, so read-only callers may not export.
+func (s *PortabilityService) Export(ctx context.Context, userID int64) (*AccountBundle, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	b := &AccountBundle{
+		Version:    AccountBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Weight:     []domain.WeightEntry{},
+		Water:      []domain.WaterEvent{},
+	}
+
+	profile, err := s.accounts.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	b.Profile = *profile
+
+	err = s.weight.EachWeightEvent(ctx, userID, func(e domain.WeightEntry) error {
+		b.Weight = append(b.Weight, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("weight: %w", err)
+	}
+	err = s.water.EachWaterEvent(ctx, userID, func(e domain.WaterEvent) error {
+		b.Water = append(b.Water, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("water: %w", err)
+	}
+
+	if b.Sessions, err = s.auth.Sessions(ctx, userID, ""); err != nil {
+		return nil, fmt.Errorf("sessions: %w", err)
+	}
+	if s.config != nil {
+		if b.Config, err = s.config.Export(ctx, userID); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// Import restores b into the user's account: its configuration is applied
+// at once and its events are imported by a background job. Events already
+// restored from the same bundle are skipped, so an interrupted import can
+// be retried. The profile and sessions are informational: the account
+// keeps its own username and email, and devices sign in again.
+func (s *PortabilityService) Import(ctx context.Context, userID int64, b AccountBundle) (*AccountImportResult, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := b.checkVersion(); err != nil {
+		return nil, err
+	}
+
+	res := &AccountImportResult{}
+	if b.Config != nil && s.config != nil {
+		cfg, err := s.config.Import(ctx, userID, *b.Config)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		res.Config = cfg
+	}
+
+	job, err := s.imports.start(userID, ImportFormatAccount, b.parser(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res.Job = job
+	return res, nil
+}
+
+// DryRun checks b as Import would and reports what importing its events
+// would store, without writing anything. An invalid configuration is an
+// error, as it is on import.
+func (s *PortabilityService) DryRun(b AccountBundle) (*ImportReport, error) {
+	if err := b.checkVersion(); err != nil {
+		return nil, err
+	}
+	if b.Config != nil && s.config != nil {
+		if err := s.config.Validate(*b.Config); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	return dryRun(b.parser(), nil)
+}
+
+func (b AccountBundle) checkVersion() error {
+	if b.Version < 1 || b.Version > AccountBundleVersion {
+		return fmt.Errorf("unsupported bundle version %d (expected 1 to %d)", b.Version, AccountBundleVersion)
+	}
+	return nil
+}
+
+// parser emits b's events as import records, weight first.
+func (b AccountBundle) parser() recordParser {
+	return func(_ io.Reader, emit func(importRecord, error)) error {
+		for _, e := range b.Weight {
+			emit(eventRecord("weight", e.Value, e.Unit, e.CreatedAt))
+		}
+		for _, e := range b.Water {
+			emit(eventRecord("water", e.DeltaLiters, "l", e.CreatedAt))
+		}
+		return nil
+	}
+}
+
+// eventRecord converts an exported event into an import record keyed for
+// deduplication.
+func eventRecord(kind string, value float64, unit string, at time.Time) (importRecord, error) {
+	if at.IsZero() {
+		return importRecord{}, errors.New("missing createdAt")
+	}
+	rec := importRecord{Kind: kind, Value: value, Unit: unit, At: at}
+	rec.Key = dedupKey(rec)
+	return rec, nil
+}
@@ -0,0 +1,45 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestPortabilityService_Checks(t *testing.T) {
+	ctx := context.Background()
+	accounts := newMockAccountRepo(&domain.User{ID: 1, Username: "alice"})
+	wr, wa := &mockWeightRepo{}, &mockWaterRepo{}
+	svc := app.NewPortabilityService(
+		app.NewAccountService(accounts.userRepo(), accounts),
+		app.NewAuthService(accounts.userRepo(), &mockSessionRepo{}),
+		wr, wa, app.NewImportService(wr, wa))
+
+	b, err := svc.Export(ctx, 1)
+	if err != nil || b.Profile.Username != "alice" || b.Weight == nil || b.Sessions == nil {
+		t.Fatalf("Export = %+v, %v", b, err)
+	}
+	if _, err := svc.Export(app.WithReadOnly(ctx), 1); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected read-only callers refused, got %v", err)
+	}
+	if _, err := svc.Import(app.WithReadOnly(ctx), 1, *b); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	at := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	b.Weight = []domain.WeightEntry{{Value: 80.4, Unit: "kg", CreatedAt: at}, {Value: 80.4, Unit: "kg"}}
+	report, err := svc.DryRun(*b)
+	if err != nil || report.RowsValid != 1 || report.ErrorCount != 1 {
+		t.Errorf("DryRun = %+v, %v", report, err)
+	}
+	b.Version = app.AccountBundleVersion + 1
+	if _, err := svc.Import(ctx, 1, *b); err == nil {
+		t.Error("expected an unsupported version rejected")
+	}
+	if _, err := svc.DryRun(*b); err == nil {
+		t.Error("expected an unsupported version rejected by a dry run")
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"vitals/internal/domain"
+)
+
+// ErrProfileNotFound indicates that the selected profile does not exist or
+// is not accessible to the caller.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileService manages the additional profiles tracked under an account.
+type ProfileService struct {
+	repo domain.ProfileRepository
+}
+
+// NewProfileService creates a ProfileService backed by the given repository.
+func NewProfileService(repo domain.ProfileRepository) *ProfileService {
+	return &ProfileService{repo: repo}
+}
+
+// Create adds a named profile owned by ownerID.
+func (s *ProfileService) Create(ctx context.Context, ownerID int64, name string) (*domain.Profile, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return nil, errors.New("name must be 1-64 characters")
+	}
+	return s.repo.CreateProfile(ctx, ownerID, name)
+}
+
+// List returns the profiles owned by ownerID.
+func (s *ProfileService) List(ctx context.Context, ownerID int64) ([]domain.Profile, error) {
+	return s.repo.ListProfiles(ctx, ownerID)
+}
+
+// Resolve maps a profile selector to the ID metrics are stored under. A zero
+// profileID, or the caller's own ID, selects the caller's own data.
+func (s *ProfileService) Resolve(ctx context.Context, userID, profileID int64) (int64, error) {
+	if profileID == 0 || profileID == userID {
+		return userID, nil
+	}
+	p, err := s.repo.GetProfile(ctx, userID, profileID)
+	if err != nil {
+		return 0, err
+	}
+	if p == nil {
+		return 0, ErrProfileNotFound
+	}
+	return p.ID, nil
+}
@@ -0,0 +1,89 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockProfileRepo struct {
+	profiles []domain.Profile
+}
+
+func (m *mockProfileRepo) CreateProfile(ctx context.Context, ownerID int64, name string) (*domain.Profile, error) {
+	p := domain.Profile{ID: int64(100 + len(m.profiles)), OwnerID: ownerID, Name: name}
+	m.profiles = append(m.profiles, p)
+	return &p, nil
+}
+
+func (m *mockProfileRepo) ListProfiles(ctx context.Context, ownerID int64) ([]domain.Profile, error) {
+	var out []domain.Profile
+	for _, p := range m.profiles {
+		if p.OwnerID == ownerID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockProfileRepo) GetProfile(ctx context.Context, ownerID, id int64) (*domain.Profile, error) {
+	for _, p := range m.profiles {
+		if p.ID == id && p.OwnerID == ownerID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestProfileService_Create(t *testing.T) {
+	svc := app.NewProfileService(&mockProfileRepo{})
+	ctx := context.Background()
+
+	p, err := svc.Create(ctx, 1, "  Sam ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "Sam" || p.OwnerID != 1 {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+	if _, err := svc.Create(ctx, 1, " "); err == nil {
+		t.Fatal("expected validation error for blank name")
+	}
+	if _, err := svc.Create(app.WithReadOnly(ctx), 1, "Alex"); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestProfileService_Resolve(t *testing.T) {
+	repo := &mockProfileRepo{profiles: []domain.Profile{{ID: 100, OwnerID: 1, Name: "Sam"}}}
+	svc := app.NewProfileService(repo)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		userID    int64
+		profileID int64
+		want      int64
+		wantErr   error
+	}{
+		{"own data", 1, 0, 1, nil},
+		{"self by id", 1, 1, 1, nil},
+		{"owned profile", 1, 100, 100, nil},
+		{"foreign profile", 2, 100, 0, app.ErrProfileNotFound},
+		{"unknown profile", 1, 555, 0, app.ErrProfileNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := svc.Resolve(ctx, tc.userID, tc.profileID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
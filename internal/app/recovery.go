@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrRecoveryUnavailable indicates that account recovery is not
+	// configured.
+	ErrRecoveryUnavailable = errors.New("account recovery is not configured")
+	// ErrHasPassword indicates that an admin tried to issue a recovery code
+	// for an account that can already sign in with a password.
+	ErrHasPassword = errors.New("account already has a password")
+)
+
+// Password length bounds; bcrypt ignores bytes past 72.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// recoveryCodeEncoding renders codes in lower case without padding.
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RecoveryStatus tells a user whether they could sign in without SSO.
+type RecoveryStatus struct {
+	HasPassword bool `json:"hasPassword"`
+	// RemainingCodes is how many unused emergency codes the user has.
+	RemainingCodes int `json:"remainingCodes"`
+}
+
+// WithRecovery enables account recovery codes, which let users without a
+// working sign-in set a local password.
+func (s *AuthService) WithRecovery(repo domain.RecoveryRepository) *AuthService {
+	s.recovery = repo
+	return s
+}
+
+// RecoveryStatus returns whether the user has a local password and how
+// many emergency codes they have left.
+func (s *AuthService) RecoveryStatus(ctx context.Context, userID int64) (*RecoveryStatus, error) {
+	if s.recovery == nil {
+		return nil, ErrRecoveryUnavailable
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	n, err := s.recovery.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryStatus{HasPassword: user.PasswordHash != "", RemainingCodes: n}, nil
+}
+
+// GenerateRecoveryCodes replaces the user's emergency codes with
+// domain.RecoveryCodeCount new ones and returns them. They are not
+// retrievable afterwards.
+func (s *AuthService) GenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	if s.recovery == nil {
+		return nil, ErrRecoveryUnavailable
+	}
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	codes := make([]string, domain.RecoveryCodeCount)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i], hashes[i] = code, hashRecoveryCode(code)
+	}
+	if err := s.recovery.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// IssueRecoveryCode returns a one-time code, valid for
+// domain.IssuedRecoveryCodeTTL, with which the named user can set a local
+// password. It is for admins helping a user whose identity provider is
+// gone, so accounts that already have a password are refused.
+func (s *AuthService) IssueRecoveryCode(ctx context.Context, username string) (string, time.Time, error) {
+	if s.recovery == nil {
+		return "", time.Time{}, ErrRecoveryUnavailable
+	}
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if user == nil {
+		return "", time.Time{}, ErrUserNotFound
+	}
+	if user.PasswordHash != "" {
+		return "", time.Time{}, ErrHasPassword
+	}
+	code, err := newRecoveryCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(domain.IssuedRecoveryCodeTTL).UTC()
+	if err := s.recovery.IssueRecoveryCode(ctx, user.ID, hashRecoveryCode(code), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expiresAt, nil
+}
+
+// Recover redeems a recovery code for the named user, sets their local
+// password and signs them in, revoking their other sessions and tokens.
+// Wrong codes count towards ip's sign-in limits like wrong passwords, and
+// fail with ErrInvalidCredentials.
+func (s *AuthService) Recover(ctx context.Context, username, code, password, solution, userAgent, ip string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "AuthService.Recover")
+	defer func() { endSpan(span, err) }()
+
+	if s.recovery == nil {
+		return "", ErrRecoveryUnavailable
+	}
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	if err := s.throttle.admit(ctx, ip, solution); err != nil {
+		return "", err
+	}
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil || user == nil {
+		s.throttle.fail(ip)
+		return "", ErrInvalidCredentials
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	ok, err := s.recovery.RedeemRecoveryCode(ctx, user.ID, hashRecoveryCode(code), time.Now(), string(hash))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		s.throttle.fail(ip)
+		return "", ErrInvalidCredentials
+	}
+	// The new password replaces whatever the old sessions and tokens
+	// were issued under.
+	if err := s.RevokeAll(ctx, user.ID); err != nil {
+		return "", err
+	}
+	return s.startSession(ctx, user.ID, userAgent, ip)
+}
+
+// validatePassword checks a new password's length.
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be between %d and %d bytes", minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// newRecoveryCode returns 80 random bits as four groups of four
+// characters, e.g. "k3vq-7m2a-xz4p-e9rt".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// hashRecoveryCode hashes code ignoring case, spaces and dashes, so codes
+// may be typed as read aloud.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return hashToken(code)
+}
@@ -0,0 +1,126 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+// mockRecoveryRepo keeps codes for the single user it sets the password of.
+type mockRecoveryRepo struct {
+	user      *domain.User
+	emergency []string
+	issued    string
+	expiresAt time.Time
+}
+
+func (m *mockRecoveryRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	m.emergency = slices.Clone(hashes)
+	return nil
+}
+
+func (m *mockRecoveryRepo) IssueRecoveryCode(ctx context.Context, userID int64, hash string, expiresAt time.Time) error {
+	m.issued, m.expiresAt = hash, expiresAt
+	return nil
+}
+
+func (m *mockRecoveryRepo) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	return len(m.emergency), nil
+}
+
+func (m *mockRecoveryRepo) RedeemRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time, passwordHash string) (bool, error) {
+	switch i := slices.Index(m.emergency, hash); {
+	case i >= 0:
+		m.emergency = slices.Delete(m.emergency, i, i+1)
+	case hash == m.issued && now.Before(m.expiresAt):
+		m.issued = ""
+	default:
+		return false, nil
+	}
+	m.user.PasswordHash = passwordHash
+	return true, nil
+}
+
+func TestAuthService_Recovery(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: 1, Username: "sso-user"}
+	users := &mockUserRepo{
+		getByUsernameFn: func(ctx context.Context, username string) (*domain.User, error) {
+			if username != user.Username {
+				return nil, nil
+			}
+			return user, nil
+		},
+		getByIDFn: func(ctx context.Context, id int64) (*domain.User, error) { return user, nil },
+	}
+	sessions, revoked := 0, 0
+	repo := &mockRecoveryRepo{user: user}
+	tokens := &mockTokenRepo{tokens: []domain.APIToken{{ID: 1, UserID: 1}}}
+	svc := app.NewAuthService(users, &mockSessionRepo{
+		createFn: func(ctx context.Context, userID int64, token, userAgent, ip string, expiresAt time.Time) error {
+			sessions++
+			return nil
+		},
+		deleteByUserFn: func(ctx context.Context, userID int64) (int, error) {
+			revoked++
+			return 0, nil
+		},
+	}).WithRecovery(repo).WithAPITokens(tokens)
+
+	if _, err := app.NewAuthService(users, &mockSessionRepo{}).RecoveryStatus(ctx, 1); !errors.Is(err, app.ErrRecoveryUnavailable) {
+		t.Fatalf("expected ErrRecoveryUnavailable without a repository, got %v", err)
+	}
+
+	codes, err := svc.GenerateRecoveryCodes(ctx, 1)
+	if err != nil || len(codes) != domain.RecoveryCodeCount || len(codes[0]) != 19 {
+		t.Fatalf("GenerateRecoveryCodes = %v, %v", codes, err)
+	}
+	if st, _ := svc.RecoveryStatus(ctx, 1); st.HasPassword || st.RemainingCodes != domain.RecoveryCodeCount {
+		t.Errorf("RecoveryStatus = %+v", st)
+	}
+
+	if _, err := svc.Recover(ctx, "sso-user", codes[0], "short", "", testUserAgent, "10.0.0.1"); err == nil {
+		t.Error("expected a too short password to be rejected")
+	}
+	if _, err := svc.Recover(ctx, "sso-user", "aaaa-bbbb-cccc-dddd", "newpassword", "", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected a wrong code to fail like a wrong password, got %v", err)
+	}
+	// Codes may be typed in upper case without dashes.
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	if token, err := svc.Recover(ctx, "sso-user", typed, "newpassword", "", testUserAgent, "10.0.0.1"); err != nil || token == "" || sessions != 1 {
+		t.Fatalf("Recover = %q, %v", token, err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("newpassword")) != nil {
+		t.Error("expected the new password to be set")
+	}
+	if revoked != 1 || len(tokens.tokens) != 0 {
+		t.Errorf("expected the old sessions and tokens to be revoked, got %d revocations and tokens %+v", revoked, tokens.tokens)
+	}
+	if _, err := svc.Recover(ctx, "sso-user", codes[0], "newpassword", "", testUserAgent, "10.0.0.1"); !errors.Is(err, app.ErrInvalidCredentials) {
+		t.Errorf("expected a redeemed code to be spent, got %v", err)
+	}
+
+	// Admins only issue codes for accounts without a password.
+	if _, _, err := svc.IssueRecoveryCode(ctx, "sso-user"); !errors.Is(err, app.ErrHasPassword) {
+		t.Errorf("expected ErrHasPassword, got %v", err)
+	}
+	if _, _, err := svc.IssueRecoveryCode(ctx, "nobody"); !errors.Is(err, app.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	user.PasswordHash = ""
+	code, expiresAt, err := svc.IssueRecoveryCode(ctx, "sso-user")
+	if err != nil || time.Until(expiresAt) > domain.IssuedRecoveryCodeTTL || time.Until(expiresAt) < domain.IssuedRecoveryCodeTTL-time.Minute {
+		t.Fatalf("IssueRecoveryCode = %q, %v, %v", code, expiresAt, err)
+	}
+	if _, err := svc.Recover(ctx, "sso-user", code, "otherpassword", "", testUserAgent, "10.0.0.2"); err != nil || user.PasswordHash == "" {
+		t.Errorf("expected the issued code to set a password, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrRuleNotFound is returned when the user has no rule with the given id.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// NotificationKindRule prefixes the kind of notifications sent by
+// user-defined rules, e.g. "rule.water.by".
+const NotificationKindRule = "rule."
+
+// RuleService manages user-defined alert rules and evaluates them from the
+// scheduled `vitals alerts check`.
+type RuleService struct {
+	rules     domain.RuleRepository
+	weight    domain.WeightRepository
+	water     domain.WaterRepository
+	notifiers map[string]domain.Notifier
+	outbox    domain.OutboxRepository
+}
+
+// NewRuleService creates a RuleService backed by the given repositories.
+// Channels become available as notifiers are registered with WithNotifier.
+func NewRuleService(rules domain.RuleRepository, weight domain.WeightRepository, water domain.WaterRepository) *RuleService {
+	return &RuleService{rules: rules, weight: weight, water: water, notifiers: map[string]domain.Notifier{}}
+}
+
+// WithNotifier registers n as the notifier for channel.
+func (s *RuleService) WithNotifier(channel string, n domain.Notifier) *RuleService {
+	s.notifiers[channel] = n
+	return s
+}
+
+// WithOutbox queues notifications in outbox, for an OutboxService to
+// deliver, instead of sending them directly. The rule repository queues
+// them in the same transaction as marking the rule fired.
+func (s *RuleService) WithOutbox(outbox domain.OutboxRepository) *RuleService {
+	s.outbox = outbox
+	return s
+}
+
+// Channels lists the channels rules can notify over.
+func (s *RuleService) Channels() []string {
+	return channels(s.notifiers)
+}
+
+// List returns the user's rules, oldest first.
+func (s *RuleService) List(ctx context.Context, userID int64) ([]domain.Rule, error) {
+	return s.rules.ListRules(ctx, userID)
+}
+
+// Create validates and stores a new rule for rule.UserID.
+func (s *RuleService) Create(ctx context.Context, rule domain.Rule) (*domain.Rule, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(&rule); err != nil {
+		return nil, err
+	}
+	existing, err := s.rules.ListRules(ctx, rule.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= domain.MaxRulesPerUser {
+		return nil, fmt.Errorf("at most %d rules per user", domain.MaxRulesPerUser)
+	}
+	rule.LastFiredAt = nil
+	rule.CreatedAt = time.Now().UTC()
+	return s.rules.CreateRule(ctx, rule)
+}
+
+// Update validates and replaces the definition of an existing rule. Its
+// firing history is kept, so re-saving does not re-arm it.
+func (s *RuleService) Update(ctx context.Context, rule domain.Rule) (*domain.Rule, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(&rule); err != nil {
+		return nil, err
+	}
+	ok, err := s.rules.UpdateRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	return s.rules.GetRule(ctx, rule.UserID, rule.ID)
+}
+
+// Delete removes the user's rule.
+func (s *RuleService) Delete(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.rules.DeleteRule(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// validate checks rule's condition, name and channel, defaulting the name
+// to a description of the condition.
+func (s *RuleService) validate(rule *domain.Rule) error {
+	if err := rule.Condition.Validate(); err != nil {
+		return err
+	}
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		rule.Name = rule.Condition.Describe()
+	}
+	if len(rule.Name) > domain.MaxRuleNameLength {
+		return fmt.Errorf("name must be at most %d characters", domain.MaxRuleNameLength)
+	}
+	return validateChannel(s.notifiers, rule.Channel, &rule.Target)
+}
+
+// EvaluateAll evaluates every enabled rule and returns the number of
+// notifications sent. Time-of-day rules only fire once their time has
+// passed, so schedule it every few minutes rather than nightly.
+func (s *RuleService) EvaluateAll(ctx context.Context, now time.Time) (int, error) {
+	rules, err := s.rules.ListEnabledRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, rule := range rules {
+		n, err := s.evaluate(ctx, rule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %d rule %d: %w", rule.UserID, rule.ID, err))
+			continue
+		}
+		if n != nil {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// evaluate notifies the rule's channel if its condition holds and it has
+// not already fired for the current day (water) or weigh-in (weight).
+func (s *RuleService) evaluate(ctx context.Context, rule domain.Rule, now time.Time) (*domain.Notification, error) {
+	if !rule.Enabled || !rule.Condition.Due(now) {
+		return nil, nil
+	}
+	notifier, ok := s.notifiers[rule.Channel]
+	if !ok {
+		return nil, fmt.Errorf("channel %q is not available", rule.Channel)
+	}
+
+	var detail string
+	switch rule.Condition.Kind {
+	case domain.RuleWaterBy:
+		today := now.In(time.Local).Format("2006-01-02")
+		if rule.LastFiredAt != nil && rule.LastFiredAt.In(time.Local).Format("2006-01-02") == today {
+			return nil, nil
+		}
+		liters, err := waterTotal(ctx, s.water, rule.UserID, today)
+		if err != nil {
+			return nil, err
+		}
+		if liters > rule.Condition.Threshold {
+			return nil, nil
+		}
+		detail = fmt.Sprintf("%.1f L logged so far today.", liters)
+
+	case domain.RuleWeightAbove, domain.RuleWeightBelow, domain.RuleWeighInMissed:
+		latest, err := s.weight.ListRecentWeightEvents(ctx, rule.UserID, 1)
+		if err != nil || len(latest) == 0 {
+			return nil, err
+		}
+		e := latest[0]
+		// Fire once per weigh-in, or once per gap for missed weigh-ins.
+		if rule.LastFiredAt != nil && !rule.LastFiredAt.Before(e.CreatedAt) {
+			return nil, nil
+		}
+		kg := domain.ConvertWeight(e.Value, e.Unit, "kg")
+		switch rule.Condition.Kind {
+		case domain.RuleWeightAbove:
+			if kg <= rule.Condition.Threshold {
+				return nil, nil
+			}
+			detail = fmt.Sprintf("Latest weigh-in: %.1f kg.", kg)
+		case domain.RuleWeightBelow:
+			if kg >= rule.Condition.Threshold {
+				return nil, nil
+			}
+			detail = fmt.Sprintf("Latest weigh-in: %.1f kg.", kg)
+		default:
+			if now.Sub(e.CreatedAt) < time.Duration(rule.Condition.Days)*24*time.Hour {
+				return nil, nil
+			}
+			detail = fmt.Sprintf("Last weigh-in: %s.", e.CreatedAt.In(time.Local).Format("2006-01-02"))
+		}
+	}
+
+	n := domain.Notification{
+		UserID:  rule.UserID,
+		Kind:    NotificationKindRule + rule.Condition.Kind,
+		Title:   rule.Name,
+		Message: rule.Condition.Describe() + ". " + detail,
+		At:      now,
+		Target:  rule.Target,
+	}
+	if s.outbox != nil {
+		if err := s.rules.MarkRuleFired(ctx, rule.UserID, rule.ID, now, domain.OutboxMessage{Channel: rule.Channel, Notification: n}); err != nil {
+			return nil, err
+		}
+		return &n, nil
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return nil, err
+	}
+	if err := s.rules.MarkRuleFired(ctx, rule.UserID, rule.ID, now); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
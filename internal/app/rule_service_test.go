@@ -0,0 +1,180 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockRuleRepo struct {
+	rules  []domain.Rule
+	nextID int64
+}
+
+func (m *mockRuleRepo) CreateRule(ctx context.Context, rule domain.Rule) (*domain.Rule, error) {
+	m.nextID++
+	rule.ID = m.nextID
+	m.rules = append(m.rules, rule)
+	return &rule, nil
+}
+
+func (m *mockRuleRepo) GetRule(ctx context.Context, userID, id int64) (*domain.Rule, error) {
+	for _, r := range m.rules {
+		if r.UserID == userID && r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRuleRepo) ListRules(ctx context.Context, userID int64) ([]domain.Rule, error) {
+	var out []domain.Rule
+	for _, r := range m.rules {
+		if r.UserID == userID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRuleRepo) UpdateRule(ctx context.Context, rule domain.Rule) (bool, error) {
+	for i, r := range m.rules {
+		if r.UserID == rule.UserID && r.ID == rule.ID {
+			rule.LastFiredAt, rule.CreatedAt = r.LastFiredAt, r.CreatedAt
+			m.rules[i] = rule
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRuleRepo) DeleteRule(ctx context.Context, userID, id int64) (bool, error) {
+	for i, r := range m.rules {
+		if r.UserID == userID && r.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRuleRepo) ListEnabledRules(ctx context.Context) ([]domain.Rule, error) {
+	var out []domain.Rule
+	for _, r := range m.rules {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRuleRepo) MarkRuleFired(ctx context.Context, userID, id int64, at time.Time, _ ...domain.OutboxMessage) error {
+	for i, r := range m.rules {
+		if r.UserID == userID && r.ID == id {
+			m.rules[i].LastFiredAt = &at
+		}
+	}
+	return nil
+}
+
+func TestRuleService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockRuleRepo{}
+	svc := app.NewRuleService(repo, &mockWeightRepo{}, &mockWaterRepo{}).
+		WithNotifier(domain.AlertChannelMQTT, &recordingNotifier{})
+
+	rule, err := svc.Create(ctx, domain.Rule{
+		UserID: 1, Channel: domain.AlertChannelMQTT, Target: "ignored", Enabled: true,
+		Condition: domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00", Days: 3},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if rule.Name != "No water logged by 14:00" || rule.Target != "" || rule.Condition.Days != 0 {
+		t.Errorf("expected defaulted name and cleared unused fields, got %+v", rule)
+	}
+
+	if _, err := svc.Create(ctx, domain.Rule{
+		UserID: 1, Channel: domain.AlertChannelWebhook, Enabled: true,
+		Condition: domain.RuleCondition{Kind: domain.RuleWeighInMissed, Days: 3},
+	}); err == nil {
+		t.Error("expected error for unavailable channel")
+	}
+	if _, err := svc.Create(ctx, domain.Rule{
+		UserID: 1, Channel: domain.AlertChannelMQTT,
+		Condition: domain.RuleCondition{Kind: "weight.wobbly"},
+	}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+
+	rule.Name = "Drink up"
+	updated, err := svc.Update(ctx, *rule)
+	if err != nil || updated.Name != "Drink up" {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if _, err := svc.Update(ctx, domain.Rule{ID: rule.ID, UserID: 2, Channel: domain.AlertChannelMQTT, Condition: rule.Condition}); !errors.Is(err, app.ErrRuleNotFound) {
+		t.Errorf("expected ErrRuleNotFound updating another user's rule, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, 1, rule.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := svc.Delete(ctx, 1, rule.ID); !errors.Is(err, app.ErrRuleNotFound) {
+		t.Errorf("expected ErrRuleNotFound on second delete, got %v", err)
+	}
+}
+
+func TestRuleService_EvaluateAll(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 20, 15, 0, 0, 0, time.Local)
+	lastWeighIn := now.AddDate(0, 0, -4)
+
+	repo := &mockRuleRepo{}
+	weights := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{{Value: 90, Unit: "kg", CreatedAt: lastWeighIn}}, nil
+		},
+	}
+	water := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 0.5, nil },
+	}
+	notifier := &recordingNotifier{}
+	svc := app.NewRuleService(repo, weights, water).WithNotifier(domain.AlertChannelMQTT, notifier)
+
+	for _, c := range []domain.RuleCondition{
+		{Kind: domain.RuleWaterBy, At: "14:00", Threshold: 1}, // due, 0.5 L <= 1 L: fires
+		{Kind: domain.RuleWaterBy, At: "16:00", Threshold: 1}, // not yet due
+		{Kind: domain.RuleWaterBy, At: "14:00"},               // water was logged
+		{Kind: domain.RuleWeightAbove, Threshold: 85},         // fires
+		{Kind: domain.RuleWeightBelow, Threshold: 85},
+		{Kind: domain.RuleWeighInMissed, Days: 3}, // fires
+		{Kind: domain.RuleWeighInMissed, Days: 7},
+	} {
+		if _, err := svc.Create(ctx, domain.Rule{UserID: 1, Channel: domain.AlertChannelMQTT, Enabled: true, Condition: c}); err != nil {
+			t.Fatalf("Create %+v: %v", c, err)
+		}
+	}
+
+	sent, err := svc.EvaluateAll(ctx, now)
+	if err != nil {
+		t.Fatalf("EvaluateAll failed: %v", err)
+	}
+	if sent != 3 || len(notifier.sent) != 3 {
+		t.Fatalf("expected 3 notifications, got %d: %+v", sent, notifier.sent)
+	}
+	if got := notifier.sent[0].Kind; got != "rule.water.by" {
+		t.Errorf("kind = %q", got)
+	}
+
+	// Each rule fires once per day or weigh-in.
+	if sent, _ := svc.EvaluateAll(ctx, now.Add(30*time.Minute)); sent != 0 {
+		t.Errorf("expected no repeat notifications, got %d", sent)
+	}
+	if sent, _ := svc.EvaluateAll(ctx, now.AddDate(0, 0, 1)); sent != 1 {
+		t.Errorf("expected only the water rule to fire again the next day, got %d", sent)
+	}
+}
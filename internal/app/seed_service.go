@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"vitals/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrDemoUserExists indicates that the demo user has already been seeded.
+var ErrDemoUserExists = errors.New("demo user already exists")
+
+// DemoSeeder generates realistic sample history for a demo account.
+type DemoSeeder struct {
+	users  domain.UserRepository
+	weight domain.WeightRepository
+	water  domain.WaterRepository
+}
+
+// NewDemoSeeder creates a DemoSeeder backed by the given repositories.
+func NewDemoSeeder(users domain.UserRepository, weight domain.WeightRepository, water domain.WaterRepository) *DemoSeeder {
+	return &DemoSeeder{users: users, weight: weight, water: water}
+}
+
+// DemoOptions configures a seeding run.
+type DemoOptions struct {
+	Username string
+	Password string
+	// Days is how many days of history to generate, ending today.
+	Days int
+	// Seed makes the generated history reproducible.
+	Seed uint64
+	// Now anchors the history; defaults to time.Now.
+	Now time.Time
+}
+
+// DemoSummary reports what a seeding run created.
+type DemoSummary struct {
+	UserID       int64 `json:"userId"`
+	WeightEvents int   `json:"weightEvents"`
+	WaterEvents  int   `json:"waterEvents"`
+}
+
+// Seed creates the demo user and fills its history. It returns
+// ErrDemoUserExists if the user is already present so repeated runs are safe.
+func (s *DemoSeeder) Seed(ctx context.Context, opts DemoOptions) (*DemoSummary, error) {
+	if opts.Username == "" {
+		return nil, errors.New("username is required")
+	}
+	if opts.Days <= 0 {
+		opts.Days = 120
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	if existing, err := s.users.GetByUsername(ctx, opts.Username); err == nil && existing != nil {
+		return nil, ErrDemoUserExists
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.Create(ctx, opts.Username, string(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	summary := &DemoSummary{UserID: user.ID}
+
+	// Weight drifts slowly downward with day-to-day noise, logged most
+	// mornings; water is a handful of glasses spread across waking hours.
+	weight := 84.0 + rng.Float64()*4
+	start := opts.Now.In(time.Local).AddDate(0, 0, -(opts.Days - 1))
+	for i := range opts.Days {
+		day := time.Date(start.Year(), start.Month(), start.Day()+i, 0, 0, 0, 0, time.Local)
+
+		weight += -0.04 + rng.NormFloat64()*0.25
+		if rng.Float64() < 0.88 {
+			at := day.Add(6*time.Hour + time.Duration(rng.IntN(150))*time.Minute)
+			if !at.After(opts.Now) {
+				value := math.Round(weight*10) / 10
+				if _, err := s.weight.AddWeightEvent(ctx, user.ID, value, "kg", at); err != nil {
+					return nil, err
+				}
+				summary.WeightEvents++
+			}
+		}
+
+		glasses := 5 + rng.IntN(5)
+		for g := range glasses {
+			minutes := 8*60 + g*(13*60/glasses) + rng.IntN(45)
+			at := day.Add(time.Duration(minutes) * time.Minute)
+			if at.After(opts.Now) {
+				break
+			}
+			delta := []float64{0.25, 0.33, 0.5}[rng.IntN(3)]
+			if _, err := s.water.AddWaterEvent(ctx, user.ID, delta, at); err != nil {
+				return nil, err
+			}
+			summary.WaterEvents++
+		}
+	}
+	return summary, nil
+}
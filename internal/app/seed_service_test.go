@@ -0,0 +1,67 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestDemoSeeder_Seed(t *testing.T) {
+	var weights, waters int
+	var latest time.Time
+	wr := &mockWeightRepo{
+		addFn: func(_ context.Context, userID int64, v float64, u string, at time.Time) (int64, error) {
+			if userID != 1 || u != "kg" || v < 60 || v > 110 {
+				t.Fatalf("implausible weight event: user=%d %v %s", userID, v, u)
+			}
+			weights++
+			return int64(weights), nil
+		},
+	}
+	wa := &mockWaterRepo{
+		addFn: func(_ context.Context, _ int64, d float64, at time.Time) (int64, error) {
+			if d <= 0 || d > 1 {
+				t.Fatalf("implausible water delta %v", d)
+			}
+			if at.After(latest) {
+				latest = at
+			}
+			waters++
+			return int64(waters), nil
+		},
+	}
+	users := &mockUserRepo{}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	svc := app.NewDemoSeeder(users, wr, wa)
+	summary, err := svc.Seed(context.Background(), app.DemoOptions{Username: "demo", Password: "demo", Days: 30, Seed: 7, Now: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.WeightEvents != weights || summary.WaterEvents != waters {
+		t.Fatalf("summary %+v does not match repo calls (%d, %d)", summary, weights, waters)
+	}
+	if weights < 20 || waters < 150 {
+		t.Fatalf("expected a month of history, got %d weights and %d water events", weights, waters)
+	}
+	if latest.After(now) {
+		t.Fatalf("generated event in the future: %v", latest)
+	}
+}
+
+func TestDemoSeeder_ExistingUser(t *testing.T) {
+	users := &mockUserRepo{
+		getByUsernameFn: func(_ context.Context, username string) (*domain.User, error) {
+			return &domain.User{ID: 3, Username: username}, nil
+		},
+	}
+	svc := app.NewDemoSeeder(users, &mockWeightRepo{}, &mockWaterRepo{})
+	_, err := svc.Seed(context.Background(), app.DemoOptions{Username: "demo", Password: "demo"})
+	if !errors.Is(err, app.ErrDemoUserExists) {
+		t.Fatalf("expected ErrDemoUserExists, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"vitals/internal/domain"
+)
+
+// SettingsService stores per-user preferences such as the UI theme, units
+// and default chart range, so they roam across devices.
+type SettingsService struct {
+	repo          domain.SettingsRepository
+	defaultLocale string
+}
+
+// NewSettingsService creates a SettingsService backed by the given repository.
+func NewSettingsService(repo domain.SettingsRepository) *SettingsService {
+	return &SettingsService{repo: repo, defaultLocale: DefaultLocale}
+}
+
+// Get returns all of the user's settings.
+func (s *SettingsService) Get(ctx context.Context, userID int64) (domain.UserSettings, error) {
+	return s.repo.GetSettings(ctx, userID)
+}
+
+// Update merges patch into the user's settings and returns the result. A
+// JSON null value deletes its key; every other value is validated first, so
+// an invalid key rejects the whole patch.
+func (s *SettingsService) Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (domain.UserSettings, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	set, remove, err := splitSettingsPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range set {
+		current[k] = v
+	}
+	for _, k := range remove {
+		delete(current, k)
+	}
+	if len(current) > domain.MaxSettingsPerUser {
+		return nil, fmt.Errorf("at most %d settings are allowed per user", domain.MaxSettingsPerUser)
+	}
+
+	if err := s.repo.UpdateSettings(ctx, userID, set, remove); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// splitSettingsPatch validates patch and splits it into the values to store
+// and the keys to delete.
+func splitSettingsPatch(patch map[string]json.RawMessage) (domain.UserSettings, []string, error) {
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	set := domain.UserSettings{}
+	var remove []string
+	for _, k := range keys {
+		v := patch[k]
+		if v == nil || string(v) == "null" {
+			remove = append(remove, k)
+			continue
+		}
+		if err := domain.ValidateSetting(k, v); err != nil {
+			return nil, nil, err
+		}
+		set[k] = v
+	}
+	return set, remove, nil
+}
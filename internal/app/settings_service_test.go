@@ -0,0 +1,141 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockSettingsRepo struct {
+	settings map[int64]domain.UserSettings
+}
+
+func (m *mockSettingsRepo) GetSettings(ctx context.Context, userID int64) (domain.UserSettings, error) {
+	out := domain.UserSettings{}
+	for k, v := range m.settings[userID] {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (m *mockSettingsRepo) UpdateSettings(ctx context.Context, userID int64, set domain.UserSettings, remove []string) error {
+	if m.settings[userID] == nil {
+		m.settings[userID] = domain.UserSettings{}
+	}
+	for k, v := range set {
+		m.settings[userID][k] = v
+	}
+	for _, k := range remove {
+		delete(m.settings[userID], k)
+	}
+	return nil
+}
+
+func TestSettingsService_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockSettingsRepo{settings: map[int64]domain.UserSettings{}}
+	svc := app.NewSettingsService(repo)
+
+	got, err := svc.Update(ctx, 1, map[string]json.RawMessage{
+		"ui.theme":           json.RawMessage(`"dark"`),
+		"charts.defaultDays": json.RawMessage(`30`),
+		"web.pinnedCards":    json.RawMessage(`["weight","water"]`),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(got) != 3 || string(got["ui.theme"]) != `"dark"` {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+
+	// null deletes a key and leaves the rest untouched.
+	got, err = svc.Update(ctx, 1, map[string]json.RawMessage{"web.pinnedCards": json.RawMessage(`null`)})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, ok := got["web.pinnedCards"]; ok || len(got) != 2 {
+		t.Fatalf("expected web.pinnedCards to be deleted, got %v", got)
+	}
+
+	// Settings are per user.
+	if other, _ := svc.Get(ctx, 2); len(other) != 0 {
+		t.Fatalf("expected no settings for another user, got %v", other)
+	}
+}
+
+func TestSettingsService_UpdateRejectsInvalid(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockSettingsRepo{settings: map[int64]domain.UserSettings{}}
+	svc := app.NewSettingsService(repo)
+
+	for name, patch := range map[string]map[string]json.RawMessage{
+		"unknown theme":  {"ui.theme": json.RawMessage(`"neon"`)},
+		"days too large": {"charts.defaultDays": json.RawMessage(`1000`)},
+		"no namespace":   {"theme": json.RawMessage(`"dark"`)},
+		"mixed patch":    {"units.weight": json.RawMessage(`"lb"`), "units.volume": json.RawMessage(`"gallons"`)},
+	} {
+		if _, err := svc.Update(ctx, 1, patch); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+	if len(repo.settings[1]) != 0 {
+		t.Fatalf("expected rejected patches to store nothing, got %v", repo.settings[1])
+	}
+
+	patch := map[string]json.RawMessage{}
+	for i := range domain.MaxSettingsPerUser + 1 {
+		patch[fmt.Sprintf("web.key%d", i)] = json.RawMessage(`true`)
+	}
+	if _, err := svc.Update(ctx, 1, patch); err == nil {
+		t.Fatal("expected an error past the per-user settings limit")
+	}
+}
+
+func TestSettingsService_UpdateReadOnly(t *testing.T) {
+	svc := app.NewSettingsService(&mockSettingsRepo{settings: map[int64]domain.UserSettings{}})
+	ctx := app.WithReadOnly(context.Background())
+	if _, err := svc.Update(ctx, 1, map[string]json.RawMessage{"ui.theme": json.RawMessage(`"dark"`)}); err != app.ErrReadOnly {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   app.FormatHints
+	}{
+		{"en-US", app.FormatHints{Locale: "en-US", DecimalSeparator: ".", FirstDayOfWeek: "sunday", Hour12: true}},
+		{"de", app.FormatHints{Locale: "de", DecimalSeparator: ",", FirstDayOfWeek: "monday"}},
+		{"fr-CA", app.FormatHints{Locale: "fr-CA", DecimalSeparator: ",", FirstDayOfWeek: "sunday", Hour12: true}},
+		{"ar-AE", app.FormatHints{Locale: "ar-AE", DecimalSeparator: ".", FirstDayOfWeek: "saturday", Hour12: true}},
+		{"xx", app.FormatHints{Locale: "en-US", DecimalSeparator: ".", FirstDayOfWeek: "sunday", Hour12: true}},
+	}
+	for _, tc := range tests {
+		if got := app.LocaleFormat(tc.locale); got != tc.want {
+			t.Errorf("LocaleFormat(%q) = %+v, want %+v", tc.locale, got, tc.want)
+		}
+	}
+}
+
+func TestSettingsService_Format(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockSettingsRepo{settings: map[int64]domain.UserSettings{
+		1: {"ui.locale": json.RawMessage(`"pt-BR"`)},
+		2: {"ui.locale": json.RawMessage(`"xx"`)},
+	}}
+	svc := app.NewSettingsService(repo).WithDefaultLocale("en-GB")
+
+	for userID, want := range map[int64]string{1: "pt-BR", 2: "en-GB", 3: "en-GB"} {
+		got, err := svc.Format(ctx, userID)
+		if err != nil || got.Locale != want {
+			t.Errorf("user %d: Format = %+v, %v; want locale %s", userID, got, err, want)
+		}
+	}
+	if got := svc.DefaultFormat(); got.Locale != "en-GB" || got.Hour12 {
+		t.Errorf("DefaultFormat = %+v", got)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"vitals/internal/domain"
+)
+
+// ErrShareNotFound indicates that no share grant exists between two users.
+var ErrShareNotFound = errors.New("share not found")
+
+// ShareService manages read-only access grants between users, such as a
+// coach viewing a client's charts.
+type ShareService struct {
+	shares domain.ShareRepository
+	users  domain.UserRepository
+}
+
+// NewShareService creates a ShareService backed by the given repositories.
+func NewShareService(shares domain.ShareRepository, users domain.UserRepository) *ShareService {
+	return &ShareService{shares: shares, users: users}
+}
+
+// Grant gives viewerUsername read-only access to ownerID's data.
+func (s *ShareService) Grant(ctx context.Context, ownerID int64, viewerUsername string) (*domain.Share, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	viewer, err := s.users.GetByUsername(ctx, viewerUsername)
+	if err != nil {
+		return nil, err
+	}
+	if viewer == nil {
+		return nil, ErrUserNotFound
+	}
+	if viewer.ID == ownerID {
+		return nil, errors.New("cannot share with yourself")
+	}
+	return s.shares.CreateShare(ctx, ownerID, viewer.ID)
+}
+
+// Revoke removes viewerID's access to ownerID's data.
+func (s *ShareService) Revoke(ctx context.Context, ownerID, viewerID int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	deleted, err := s.shares.DeleteShare(ctx, ownerID, viewerID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// List returns the grants userID has given and the ones they have received.
+func (s *ShareService) List(ctx context.Context, userID int64) (granted, received []domain.Share, err error) {
+	all, err := s.shares.ListShares(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	granted, received = []domain.Share{}, []domain.Share{}
+	for _, sh := range all {
+		if sh.OwnerID == userID {
+			granted = append(granted, sh)
+		} else {
+			received = append(received, sh)
+		}
+	}
+	return granted, received, nil
+}
+
+// Authorize checks that viewerID may read ownerID's data and returns a
+// read-only context under which services reject every write.
+func (s *ShareService) Authorize(ctx context.Context, viewerID, ownerID int64) (context.Context, error) {
+	sh, err := s.shares.GetShare(ctx, ownerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if sh == nil {
+		return nil, ErrShareNotFound
+	}
+	return WithReadOnly(ctx), nil
+}
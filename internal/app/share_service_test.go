@@ -0,0 +1,99 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockShareRepo struct {
+	shares []domain.Share
+}
+
+func (m *mockShareRepo) CreateShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	sh := domain.Share{OwnerID: ownerID, ViewerID: viewerID}
+	m.shares = append(m.shares, sh)
+	return &sh, nil
+}
+
+func (m *mockShareRepo) DeleteShare(ctx context.Context, ownerID, viewerID int64) (bool, error) {
+	for i, sh := range m.shares {
+		if sh.OwnerID == ownerID && sh.ViewerID == viewerID {
+			m.shares = append(m.shares[:i], m.shares[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockShareRepo) GetShare(ctx context.Context, ownerID, viewerID int64) (*domain.Share, error) {
+	for _, sh := range m.shares {
+		if sh.OwnerID == ownerID && sh.ViewerID == viewerID {
+			return &sh, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockShareRepo) ListShares(ctx context.Context, userID int64) ([]domain.Share, error) {
+	var out []domain.Share
+	for _, sh := range m.shares {
+		if sh.OwnerID == userID || sh.ViewerID == userID {
+			out = append(out, sh)
+		}
+	}
+	return out, nil
+}
+
+func TestShareService_GrantAndAuthorize(t *testing.T) {
+	known := map[string]*domain.User{
+		"alice": {ID: 1, Username: "alice"},
+		"coach": {ID: 2, Username: "coach"},
+	}
+	users := &mockUserRepo{
+		getByUsernameFn: func(_ context.Context, username string) (*domain.User, error) {
+			return known[username], nil
+		},
+	}
+	svc := app.NewShareService(&mockShareRepo{}, users)
+	ctx := context.Background()
+
+	if _, err := svc.Grant(ctx, 1, "nobody"); !errors.Is(err, app.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := svc.Grant(ctx, 1, "alice"); err == nil {
+		t.Fatal("expected error sharing with yourself")
+	}
+	if _, err := svc.Authorize(ctx, 2, 1); !errors.Is(err, app.ErrShareNotFound) {
+		t.Fatalf("expected ErrShareNotFound before grant, got %v", err)
+	}
+
+	if _, err := svc.Grant(ctx, 1, "coach"); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	roCtx, err := svc.Authorize(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if !app.IsReadOnly(roCtx) {
+		t.Fatal("expected shared access to be read-only")
+	}
+	if _, err := svc.Authorize(ctx, 1, 2); !errors.Is(err, app.ErrShareNotFound) {
+		t.Fatalf("grants must be one-directional, got %v", err)
+	}
+
+	granted, received, _ := svc.List(ctx, 1)
+	if len(granted) != 1 || len(received) != 0 {
+		t.Fatalf("unexpected lists: granted=%v received=%v", granted, received)
+	}
+
+	if err := svc.Revoke(ctx, 1, 2); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := svc.Revoke(ctx, 1, 2); !errors.Is(err, app.ErrShareNotFound) {
+		t.Fatalf("expected ErrShareNotFound on second revoke, got %v", err)
+	}
+}
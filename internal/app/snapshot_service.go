@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"math"
+
+	"vitals/internal/domain"
+)
+
+// snapshotDays is how far back a snapshot looks for the latest weigh-in,
+// and so the longest streak it counts.
+const snapshotDays = 90
+
+// Snapshot is a glance at today for displays that poll rarely, such as
+// e-ink panels: the latest weight, water progress and streaks.
+type Snapshot struct {
+	Day string `json:"day"`
+	// Weight is the latest weigh-in of the last snapshotDays days, in
+	// WeightUnit, or nil; WeightDay is the day it was logged.
+	Weight     *float64 `json:"weight"`
+	WeightUnit string   `json:"weightUnit"`
+	WeightDay  string   `json:"weightDay,omitempty"`
+	// WaterFraction is WaterLiters over GoalLiters, capped at 1, ready to
+	// draw as a progress bar.
+	WaterLiters   float64 `json:"waterLiters"`
+	GoalLiters    float64 `json:"goalLiters"`
+	WaterFraction float64 `json:"waterFraction"`
+	// WeighInStreak and WaterStreak count the consecutive days with a
+	// weigh-in and with the water goal met. A streak that today has not
+	// extended yet still counts up to yesterday.
+	WeighInStreak int `json:"weighInStreak"`
+	WaterStreak   int `json:"waterStreak"`
+}
+
+// SnapshotService builds snapshots from the chart data.
+type SnapshotService struct {
+	charts    *ChartsService
+	hydration *HydrationService
+}
+
+// NewSnapshotService creates a SnapshotService reading charts.
+func NewSnapshotService(charts *ChartsService) *SnapshotService {
+	return &SnapshotService{charts: charts}
+}
+
+// WithHydration measures today's water against the weather-adjusted goal
+// rather than the base goal.
+func (s *SnapshotService) WithHydration(hs *HydrationService) *SnapshotService {
+	s.hydration = hs
+	return s
+}
+
+// Snapshot returns the user's snapshot for today with weights in unit.
+func (s *SnapshotService) Snapshot(ctx context.Context, userID int64, unit string) (*Snapshot, error) {
+	points, err := s.charts.GetDaily(ctx, userID, snapshotDays, unit, domain.TagFilter{}, "", FillNull)
+	if err != nil {
+		return nil, err
+	}
+	today := points[len(points)-1]
+	snap := &Snapshot{Day: today.Day, WeightUnit: unit, WaterLiters: today.WaterLiters, GoalLiters: today.GoalLiters}
+	if s.hydration != nil {
+		goal, err := s.hydration.TodayGoal(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		snap.GoalLiters = goal.Liters
+		points[len(points)-1].GoalMet = today.WaterLiters >= goal.Liters
+	}
+	if snap.GoalLiters > 0 {
+		snap.WaterFraction = math.Min(math.Round(snap.WaterLiters/snap.GoalLiters*100)/100, 1)
+	}
+	for i := len(points) - 1; i >= 0; i-- {
+		if w := points[i].Weight; w != nil {
+			snap.Weight, snap.WeightDay = &w.Value, points[i].Day
+			break
+		}
+	}
+	snap.WeighInStreak = streak(points, func(p DayPoint) bool { return p.Weight != nil })
+	snap.WaterStreak = streak(points, func(p DayPoint) bool { return p.GoalMet })
+	return snap, nil
+}
+
+// streak counts the consecutive points, newest first, that satisfy ok,
+// skipping the newest when it does not yet.
+func streak(points []DayPoint, ok func(DayPoint) bool) int {
+	i := len(points) - 1
+	if i >= 0 && !ok(points[i]) {
+		i--
+	}
+	n := 0
+	for ; i >= 0 && ok(points[i]); i-- {
+		n++
+	}
+	return n
+}
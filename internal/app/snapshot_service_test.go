@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestSnapshot(t *testing.T) {
+	today := time.Now()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	// Weighed in on the three days before today, not yet today.
+	weighIns := map[string]float64{day(-3): 81, day(-2): 80.5, day(-1): 80.2}
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, d string) (*domain.WeightEntry, error) {
+			if v, ok := weighIns[d]; ok {
+				return &domain.WeightEntry{Value: v, Unit: "kg"}, nil
+			}
+			return nil, nil
+		},
+	}
+	// Met the 2 L goal yesterday only; today is under way.
+	water := map[string]float64{day(-2): 1, day(-1): 2, day(0): 1.5}
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, d string) (float64, error) { return water[d], nil },
+	}
+	history := &mockGoalHistory{changes: map[int64]domain.GoalHistory{
+		1: {{Day: day(-30), Liters: 2}},
+	}}
+	charts := app.NewChartsService(wr, wa).WithGoalHistory(history)
+
+	snap, err := app.NewSnapshotService(charts).Snapshot(context.Background(), 1, "kg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Weight == nil || *snap.Weight != 80.2 || snap.WeightDay != day(-1) {
+		t.Errorf("expected yesterday's 80.2 kg, got %v on %s", snap.Weight, snap.WeightDay)
+	}
+	if snap.GoalLiters != 2 || snap.WaterFraction != 0.75 {
+		t.Errorf("expected 1.5 of 2 L (0.75), got %v of %v (%v)", snap.WaterLiters, snap.GoalLiters, snap.WaterFraction)
+	}
+	if snap.WeighInStreak != 3 {
+		t.Errorf("expected a 3-day weigh-in streak up to yesterday, got %d", snap.WeighInStreak)
+	}
+	if snap.WaterStreak != 1 {
+		t.Errorf("expected a 1-day water streak, got %d", snap.WaterStreak)
+	}
+
+	water[day(0)] = 2.5
+	snap, _ = app.NewSnapshotService(charts).Snapshot(context.Background(), 1, "kg")
+	if snap.WaterFraction != 1 || snap.WaterStreak != 2 {
+		t.Errorf("expected today to cap the bar and extend the streak, got %v and %d", snap.WaterFraction, snap.WaterStreak)
+	}
+}
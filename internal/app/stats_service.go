@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	defaultComplianceDays = 90
+	maxComplianceDays     = 366
+	// statsHistoryLimit bounds how many weigh-ins a report reads.
+	statsHistoryLimit = 5000
+)
+
+// StatsService produces reports over a user's history.
+type StatsService struct {
+	weight domain.WeightRepository
+	tags   domain.TagRepository
+}
+
+// NewStatsService creates a StatsService backed by the given repository.
+func NewStatsService(weight domain.WeightRepository) *StatsService {
+	return &StatsService{weight: weight}
+}
+
+// WithTags enables tag filters on reports.
+func (s *StatsService) WithTags(repo domain.TagRepository) *StatsService {
+	s.tags = repo
+	return s
+}
+
+// Compliance reports how consistently the user weighed in over the last
+// days days, including today, counting only weigh-ins that match f.
+func (s *StatsService) Compliance(ctx context.Context, userID int64, days int, f domain.TagFilter) (_ *domain.ComplianceReport, err error) {
+	ctx, span := startSpan(ctx, "StatsService.Compliance", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if days <= 0 {
+		days = defaultComplianceDays
+	}
+	if days > maxComplianceDays {
+		days = maxComplianceDays
+	}
+	entries, err := s.weight.ListRecentWeightEvents(ctx, userID, statsHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if !f.IsZero() {
+		if s.tags == nil {
+			return nil, errTagsDisabled
+		}
+		if entries, err = tagWeights(ctx, s.tags, userID, entries, f); err != nil {
+			return nil, err
+		}
+	}
+	r := domain.WeighInCompliance(entries, days, time.Now())
+	return &r, nil
+}
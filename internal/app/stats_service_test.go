@@ -0,0 +1,34 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+func TestStatsService_Compliance(t *testing.T) {
+	now := time.Now()
+	wr := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{
+				{Value: 80, Unit: "kg", CreatedAt: now},
+				{Value: 80, Unit: "kg", CreatedAt: now.AddDate(0, 0, -1)},
+			}, nil
+		},
+	}
+	svc := app.NewStatsService(wr)
+
+	r, err := svc.Compliance(context.Background(), 1, 0, domain.TagFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Days != 90 || r.DaysLogged != 2 {
+		t.Errorf("expected 2 of 90 days logged, got %d of %d", r.DaysLogged, r.Days)
+	}
+	if r, _ := svc.Compliance(context.Background(), 1, 1000, domain.TagFilter{}); r.Days != 366 {
+		t.Errorf("expected days clamped to 366, got %d", r.Days)
+	}
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// StepsService records a daily step count, typically synced from a phone
+// or wearable.
+type StepsService struct {
+	repo domain.StepsRepository
+}
+
+// NewStepsService creates a StepsService backed by the given repository.
+func NewStepsService(repo domain.StepsRepository) *StepsService {
+	return &StepsService{repo: repo}
+}
+
+// Get returns the step count for day ("YYYY-MM-DD"), or nil.
+func (s *StepsService) Get(ctx context.Context, userID int64, day string) (*domain.StepsEntry, error) {
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStepsEntry(ctx, userID, day)
+}
+
+// Record stores steps as the count for day, replacing any earlier one, so a
+// client can resend its running total as the day goes on.
+func (s *StepsService) Record(ctx context.Context, userID int64, day string, steps int) (*domain.StepsEntry, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateJournalDay(day); err != nil {
+		return nil, err
+	}
+	if steps < 0 || steps > domain.MaxDailySteps {
+		return nil, fmt.Errorf("steps must be within [0, %d]", domain.MaxDailySteps)
+	}
+	e := domain.StepsEntry{Day: day, Steps: steps, UpdatedAt: time.Now().UTC()}
+	if err := s.repo.SaveStepsEntry(ctx, userID, e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// stepCounts returns the step counts for days from through to, keyed by day.
+func stepCounts(ctx context.Context, repo domain.StepsRepository, userID int64, from, to string) (map[string]int, error) {
+	counts := map[string]int{}
+	if repo == nil {
+		return counts, nil
+	}
+	entries, err := repo.ListStepsEntries(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		counts[e.Day] = e.Steps
+	}
+	return counts, nil
+}
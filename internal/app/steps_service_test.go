@@ -0,0 +1,89 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockStepsRepo struct {
+	entries map[string]domain.StepsEntry
+}
+
+func (m *mockStepsRepo) GetStepsEntry(ctx context.Context, userID int64, day string) (*domain.StepsEntry, error) {
+	e, ok := m.entries[day]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (m *mockStepsRepo) SaveStepsEntry(ctx context.Context, userID int64, e domain.StepsEntry) error {
+	m.entries[e.Day] = e
+	return nil
+}
+
+func (m *mockStepsRepo) ListStepsEntries(ctx context.Context, userID int64, from, to string) ([]domain.StepsEntry, error) {
+	var out []domain.StepsEntry
+	for day, e := range m.entries {
+		if day >= from && day <= to {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestStepsService_Record(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockStepsRepo{entries: map[string]domain.StepsEntry{}}
+	svc := app.NewStepsService(repo)
+
+	if _, err := svc.Record(ctx, 1, "2026-03-01", 4000); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	e, err := svc.Record(ctx, 1, "2026-03-01", 9500)
+	if err != nil || e.Steps != 9500 || e.UpdatedAt.IsZero() {
+		t.Fatalf("Record = %+v, %v", e, err)
+	}
+	if got, _ := svc.Get(ctx, 1, "2026-03-01"); got == nil || got.Steps != 9500 {
+		t.Errorf("expected the later count to replace the earlier one, got %+v", got)
+	}
+
+	for name, tc := range map[string]struct {
+		day   string
+		steps int
+	}{
+		"negative":  {"2026-03-01", -1},
+		"too many":  {"2026-03-01", domain.MaxDailySteps + 1},
+		"bad day":   {"March 1", 100},
+		"wrong day": {"2026-02-30", 100},
+	} {
+		if _, err := svc.Record(ctx, 1, tc.day, tc.steps); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := svc.Record(app.WithReadOnly(ctx), 1, "2026-03-02", 100); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestGetDaily_Steps(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	steps := &mockStepsRepo{entries: map[string]domain.StepsEntry{
+		today:        {Day: today, Steps: 12000},
+		"2000-01-01": {Day: "2000-01-01", Steps: 300},
+	}}
+
+	svc := app.NewChartsService(&mockWeightRepo{}, &mockWaterRepo{}).WithSteps(steps)
+	points, err := svc.GetDaily(context.Background(), 1, 2, "kg", domain.TagFilter{}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if points[0].Steps != nil || points[1].Steps == nil || *points[1].Steps != 12000 {
+		t.Fatalf("expected only today's steps, got %+v", points)
+	}
+}
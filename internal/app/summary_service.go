@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// maxSummaryWeeks caps how many weeks a report or refresh covers.
+const maxSummaryWeeks = 52
+
+// SummaryService produces weekly summaries, reading precomputed rows when a
+// cache is configured and computing missing weeks from raw events.
+type SummaryService struct {
+	weight    domain.WeightRepository
+	water     domain.WaterRepository
+	hydration domain.HydrationSettingsRepository
+	history   domain.GoalHistoryRepository
+	cache     domain.WeeklySummaryRepository
+	settings  domain.SettingsRepository
+}
+
+// NewSummaryService creates a SummaryService backed by the given
+// repositories.
+func NewSummaryService(weight domain.WeightRepository, water domain.WaterRepository) *SummaryService {
+	return &SummaryService{weight: weight, water: water}
+}
+
+// WithGoals counts goal days against each user's own base water goal
+// instead of the default.
+func (s *SummaryService) WithGoals(repo domain.HydrationSettingsRepository) *SummaryService {
+	s.hydration = repo
+	return s
+}
+
+// WithGoalHistory counts each day against the base water goal in effect
+// that day, so changing the goal does not rewrite earlier weeks.
+func (s *SummaryService) WithGoalHistory(repo domain.GoalHistoryRepository) *SummaryService {
+	s.history = repo
+	return s
+}
+
+// WithCache reads and refreshes precomputed summaries in repo.
+func (s *SummaryService) WithCache(repo domain.WeeklySummaryRepository) *SummaryService {
+	s.cache = repo
+	return s
+}
+
+// WithSettings reads each user's default daily weight mode from the
+// charts.dailyWeight setting.
+func (s *SummaryService) WithSettings(repo domain.SettingsRepository) *SummaryService {
+	s.settings = repo
+	return s
+}
+
+// Weekly returns summaries of the last weeks completed weeks before now,
+// newest first. The current, partial week is never included so entries do
+// not change once published. weight selects the daily weight mode (empty
+// uses the user's default); the cache holds latest-weight summaries only,
+// so averaged ones are always computed from raw events.
+func (s *SummaryService) Weekly(ctx context.Context, userID int64, weeks int, now time.Time, weight string) (_ []domain.WeeklySummary, err error) {
+	ctx, span := startSpan(ctx, "SummaryService.Weekly", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	starts := completedWeeks(weeks, now)
+	if len(starts) == 0 {
+		return []domain.WeeklySummary{}, nil
+	}
+	weight, err = dailyWeightMode(ctx, s.settings, userID, weight)
+	if err != nil {
+		return nil, err
+	}
+	weightFor := dailyWeightLookup(s.weight, weight)
+
+	cached := map[string]domain.WeeklySummary{}
+	if s.cache != nil && weight == domain.DailyWeightLatest {
+		rows, err := s.cache.ListWeeklySummaries(ctx, userID, starts[len(starts)-1].Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		for _, sum := range rows {
+			cached[sum.WeekStart] = sum
+		}
+	}
+
+	var goalOn func(string) float64
+	out := make([]domain.WeeklySummary, 0, len(starts))
+	for _, start := range starts {
+		if sum, ok := cached[start.Format("2006-01-02")]; ok {
+			out = append(out, sum)
+			continue
+		}
+		if goalOn == nil {
+			if goalOn, err = s.goals(ctx, userID); err != nil {
+				return nil, err
+			}
+		}
+		sum, err := s.computeWeek(ctx, userID, start, goalOn, weightFor)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sum)
+	}
+	return out, nil
+}
+
+// Refresh recomputes the last weeks completed weeks for every user whose
+// data changed since the start of the oldest of them, and stores them in
+// the cache. It returns the number of users refreshed.
+func (s *SummaryService) Refresh(ctx context.Context, weeks int, now time.Time) (_ int, err error) {
+	ctx, span := startSpan(ctx, "SummaryService.Refresh")
+	defer func() { endSpan(span, err) }()
+
+	if s.cache == nil {
+		return 0, errors.New("no summary cache configured")
+	}
+	starts := completedWeeks(weeks, now)
+	if len(starts) == 0 {
+		return 0, nil
+	}
+	users, err := s.cache.ListActiveUserIDs(ctx, starts[len(starts)-1])
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	var errs []error
+	for _, userID := range users {
+		if err := s.refreshUser(ctx, userID, starts); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, errors.Join(errs...)
+}
+
+func (s *SummaryService) refreshUser(ctx context.Context, userID int64, starts []time.Time) error {
+	goalOn, err := s.goals(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sums := make([]domain.WeeklySummary, 0, len(starts))
+	for _, start := range starts {
+		sum, err := s.computeWeek(ctx, userID, start, goalOn, s.weight.LatestWeightForLocalDay)
+		if err != nil {
+			return err
+		}
+		sums = append(sums, *sum)
+	}
+	return s.cache.SaveWeeklySummaries(ctx, userID, sums)
+}
+
+// goals returns the base water goal in effect on each day: from the goal
+// history when one is kept and not empty, else the user's current goal.
+func (s *SummaryService) goals(ctx context.Context, userID int64) (func(day string) float64, error) {
+	if s.history != nil {
+		h, err := s.history.GoalHistory(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(h) > 0 {
+			return h.LitersOn, nil
+		}
+	}
+	goal, err := s.goalLiters(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return func(string) float64 { return goal }, nil
+}
+
+// goalLiters returns the user's base water goal.
+func (s *SummaryService) goalLiters(ctx context.Context, userID int64) (float64, error) {
+	if s.hydration == nil {
+		return domain.DefaultHydrationGoalLiters, nil
+	}
+	hs, err := s.hydration.GetHydrationSettings(ctx, userID)
+	if err != nil || hs == nil {
+		return domain.DefaultHydrationGoalLiters, err
+	}
+	return hs.BaseGoalLiters, nil
+}
+
+// computeWeek summarizes the week beginning on start from raw events, taking
+// each day's weight from weightFor and water goal from goalOn.
+func (s *SummaryService) computeWeek(ctx context.Context, userID int64, start time.Time, goalOn func(string) float64,
+	weightFor func(context.Context, int64, string) (*domain.WeightEntry, error),
+) (*domain.WeeklySummary, error) {
+	sum := &domain.WeeklySummary{
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   start.AddDate(0, 0, 6).Format("2006-01-02"),
+	}
+	var first, last *float64
+	var totalKg float64
+	for d := range 7 {
+		day := start.AddDate(0, 0, d).Format("2006-01-02")
+		liters, err := waterTotal(ctx, s.water, userID, day)
+		if err != nil {
+			return nil, err
+		}
+		sum.TotalWaterLiters += liters
+		if liters >= goalOn(day) {
+			sum.GoalDays++
+		}
+
+		entry, err := weightFor(ctx, userID, day)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		kg := domain.ConvertWeight(entry.Value, entry.Unit, "kg")
+		if first == nil {
+			first = &kg
+		}
+		last = &kg
+		totalKg += kg
+		sum.WeighIns++
+	}
+	sum.TotalWaterLiters = domain.RoundLiters(sum.TotalWaterLiters)
+	sum.AvgWaterLiters = domain.RoundLiters(sum.TotalWaterLiters / 7)
+	if sum.WeighIns > 0 {
+		avg := totalKg / float64(sum.WeighIns)
+		sum.AvgKg = &avg
+	}
+	if sum.WeighIns >= 2 {
+		change := *last - *first
+		sum.StartKg, sum.EndKg, sum.ChangeKg = first, last, &change
+	}
+	return sum, nil
+}
+
+// completedWeeks returns the Mondays of the last weeks completed weeks
+// before now, newest first.
+func completedWeeks(weeks int, now time.Time) []time.Time {
+	if weeks > maxSummaryWeeks {
+		weeks = maxSummaryWeeks
+	}
+	now = now.In(time.Local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	offset := (int(today.Weekday()) + 6) % 7 // days since Monday
+	thisMonday := today.AddDate(0, 0, -offset)
+
+	starts := make([]time.Time, 0, max(weeks, 0))
+	for w := 1; w <= weeks; w++ {
+		starts = append(starts, thisMonday.AddDate(0, 0, -7*w))
+	}
+	return starts
+}
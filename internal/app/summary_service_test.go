@@ -0,0 +1,108 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockSummaryRepo struct {
+	rows    map[string]domain.WeeklySummary
+	active  []int64
+	savedBy map[int64]int
+}
+
+func (m *mockSummaryRepo) SaveWeeklySummaries(ctx context.Context, userID int64, sums []domain.WeeklySummary) error {
+	for _, s := range sums {
+		m.rows[s.WeekStart] = s
+	}
+	m.savedBy[userID] += len(sums)
+	return nil
+}
+
+func (m *mockSummaryRepo) ListWeeklySummaries(ctx context.Context, userID int64, from string) ([]domain.WeeklySummary, error) {
+	var out []domain.WeeklySummary
+	for start, s := range m.rows {
+		if start >= from {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockSummaryRepo) ListActiveUserIDs(ctx context.Context, since time.Time) ([]int64, error) {
+	return m.active, nil
+}
+
+func TestSummaryService_WeeklyUsesCache(t *testing.T) {
+	var computed []string
+	wr := &mockWeightRepo{
+		latestFn: func(_ context.Context, _ int64, day string) (*domain.WeightEntry, error) {
+			computed = append(computed, day)
+			return &domain.WeightEntry{Value: 80, Unit: "kg"}, nil
+		},
+	}
+	wa := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, day string) (float64, error) {
+			if day == "2026-02-02" {
+				return 3, nil
+			}
+			return 1, nil
+		},
+	}
+	cache := &mockSummaryRepo{
+		rows:    map[string]domain.WeeklySummary{"2026-02-02": {WeekStart: "2026-02-02", WeighIns: 7, GoalDays: 5}},
+		savedBy: map[int64]int{},
+	}
+	hydration := &mockHydrationRepo{settings: map[int64]domain.HydrationSettings{1: {UserID: 1, BaseGoalLiters: 2}}}
+	svc := app.NewSummaryService(wr, wa).WithGoals(hydration).WithCache(cache)
+
+	now := time.Date(2026, 2, 11, 12, 0, 0, 0, time.Local)
+	got, err := svc.Weekly(context.Background(), 1, 2, now, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got[0].WeekStart != "2026-02-02" || got[0].GoalDays != 5 {
+		t.Fatalf("expected the cached week, got %+v", got[0])
+	}
+	if len(computed) != 7 || computed[0] != "2026-01-26" {
+		t.Fatalf("expected only the uncached week to be computed, got %v", computed)
+	}
+	prev := got[1]
+	if prev.TotalWaterLiters != 7 || prev.GoalDays != 0 || prev.AvgKg == nil || *prev.AvgKg != 80 {
+		t.Fatalf("unexpected computed week: %+v", prev)
+	}
+
+	// Refresh recomputes both weeks for each active user.
+	cache.active = []int64{1, 2}
+	n, err := svc.Refresh(context.Background(), 2, now)
+	if err != nil || n != 2 {
+		t.Fatalf("Refresh = %d, %v; want 2 users", n, err)
+	}
+	if cache.savedBy[1] != 2 || cache.rows["2026-02-02"].GoalDays != 1 {
+		t.Fatalf("unexpected refreshed rows: %+v", cache.rows)
+	}
+}
+
+func TestSummaryService_GoalHistory(t *testing.T) {
+	wa := &mockWaterRepo{
+		totalFn: func(context.Context, int64, string) (float64, error) { return 2.5, nil },
+	}
+	// The goal rose from 2 L to 3 L on Thursday of the week.
+	history := &mockGoalHistory{changes: map[int64]domain.GoalHistory{
+		1: {{Day: "2026-01-01", Liters: 2}, {Day: "2026-01-29", Liters: 3}},
+	}}
+	hydration := &mockHydrationRepo{settings: map[int64]domain.HydrationSettings{1: {UserID: 1, BaseGoalLiters: 3}}}
+	svc := app.NewSummaryService(&mockWeightRepo{}, wa).WithGoals(hydration).WithGoalHistory(history)
+
+	got, err := svc.Weekly(context.Background(), 1, 1, time.Date(2026, 2, 4, 12, 0, 0, 0, time.Local), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got[0].WeekStart != "2026-01-26" || got[0].GoalDays != 3 {
+		t.Fatalf("expected the 3 days before the change to meet the goal, got %+v", got[0])
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+)
+
+// topicSyncChange carries the ID of a user whose data changed, so the other
+// instances wake that user's live streams.
+const topicSyncChange = "sync.change"
+
+// liveRecheck is how often a live stream reads the change log without being
+// woken. It bounds how late changes that publish no event, such as edits,
+// deletions and imports, reach the stream.
+const liveRecheck = 15 * time.Second
+
+// SyncService serves incremental change feeds to offline clients and live
+// streams of them to open dashboards.
+type SyncService struct {
+	repo    domain.ChangeRepository
+	cluster domain.Cluster
+
+	mu       sync.Mutex
+	watchers map[int64]map[chan struct{}]struct{}
+}
+
+// NewSyncService creates a SyncService backed by the given repository.
+func NewSyncService(repo domain.ChangeRepository) *SyncService {
+	return &SyncService{repo: repo, watchers: make(map[int64]map[chan struct{}]struct{})}
+}
+
+// WithCluster shares recorded events with the other instances in c, so a
+// live stream served by one instance sees writes made through another.
+func (s *SyncService) WithCluster(c domain.Cluster) *SyncService {
+	s.cluster = c
+	c.Subscribe(topicSyncChange, func(payload []byte) {
+		if userID, err := strconv.ParseInt(string(payload), 10, 64); err == nil {
+			s.wake(userID)
+		}
+	})
+	return s
+}
+
+// SyncPage is one batch of changes. Clients store Cursor and pass it as
+// since on the next call; HasMore means another call would return more.
+type SyncPage struct {
+	Cursor  int64           `json:"cursor"`
+	HasMore bool            `json:"hasMore"`
+	Changes []domain.Change `json:"changes"`
+}
+
+// Changes returns the changes after the since cursor.
+func (s *SyncService) Changes(ctx context.Context, userID, since int64, limit int) (_ *SyncPage, err error) {
+	ctx, span := startSpan(ctx, "SyncService.Changes", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+	if since < 0 {
+		since = 0
+	}
+
+	changes, err := s.repo.ListChanges(ctx, userID, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &SyncPage{Cursor: since, Changes: changes}
+	if len(changes) > limit {
+		page.HasMore = true
+		page.Changes = changes[:limit]
+	}
+	if n := len(page.Changes); n > 0 {
+		page.Cursor = page.Changes[n-1].Seq
+	}
+	if page.Changes == nil {
+		page.Changes = []domain.Change{}
+	}
+	return page, nil
+}
+
+// LatestChange returns the user's most recent change to entity ("weight" or
+// "water"), or nil if there is none. It lets callers detect whether data
+// changed without reading it.
+func (s *SyncService) LatestChange(ctx context.Context, userID int64, entity string) (*domain.Change, error) {
+	return s.repo.LatestChange(ctx, userID, entity)
+}
+
+// Publish wakes the live streams of the event's user, here and on the other
+// instances. It makes SyncService a domain.EventPublisher.
+func (s *SyncService) Publish(ctx context.Context, e domain.MetricEvent) {
+	s.wake(e.UserID)
+	if s.cluster != nil {
+		_ = s.cluster.Publish(ctx, topicSyncChange, []byte(strconv.FormatInt(e.UserID, 10)))
+	}
+}
+
+// Watch streams userID's changes after the since cursor as they happen,
+// oldest first; with since < 0 it starts after the latest change. Recorded
+// entries arrive as soon as they are published, other changes within
+// liveRecheck. The channel closes when ctx ends, or when since expires
+// because the changes after it were purged; a since that has already
+// expired fails with domain.ErrCursorExpired.
+func (s *SyncService) Watch(ctx context.Context, userID, since int64) (<-chan domain.Change, error) {
+	if since < 0 {
+		var err error
+		if since, err = s.cursor(ctx, userID); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.repo.ListChanges(ctx, userID, since, 1); err != nil {
+		return nil, err
+	}
+
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.watchers[userID] == nil {
+		s.watchers[userID] = make(map[chan struct{}]struct{})
+	}
+	s.watchers[userID][wake] = struct{}{}
+	s.mu.Unlock()
+
+	out := make(chan domain.Change)
+	go func() {
+		defer close(out)
+		defer func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.watchers[userID], wake)
+			if len(s.watchers[userID]) == 0 {
+				delete(s.watchers, userID)
+			}
+		}()
+		tick := time.NewTicker(liveRecheck)
+		defer tick.Stop()
+
+		for {
+			for {
+				changes, err := s.repo.ListChanges(ctx, userID, since, defaultSyncLimit)
+				if errors.Is(err, domain.ErrCursorExpired) {
+					return
+				}
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("sync: watch user %d: %v", userID, err)
+					}
+					break
+				}
+				for _, c := range changes {
+					select {
+					case out <- c:
+						since = c.Seq
+					case <-ctx.Done():
+						return
+					}
+				}
+				if len(changes) < defaultSyncLimit {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-tick.C:
+			}
+		}
+	}()
+	return out, nil
+}
+
+// cursor returns the sequence number of userID's latest change, or zero.
+func (s *SyncService) cursor(ctx context.Context, userID int64) (int64, error) {
+	var seq int64
+	for _, entity := range []string{domain.ChangeEntityWeight, domain.ChangeEntityWater} {
+		c, err := s.repo.LatestChange(ctx, userID, entity)
+		if err != nil {
+			return 0, err
+		}
+		if c != nil && c.Seq > seq {
+			seq = c.Seq
+		}
+	}
+	return seq, nil
+}
+
+// wake tells userID's live streams to read the change log now.
+func (s *SyncService) wake(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watchers[userID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package app_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockChangeRepo struct {
+	mu      sync.Mutex
+	changes []domain.Change
+}
+
+func (m *mockChangeRepo) add(c domain.Change) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes = append(m.changes, c)
+}
+
+func (m *mockChangeRepo) ListChanges(ctx context.Context, userID, since int64, limit int) ([]domain.Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []domain.Change
+	for _, c := range m.changes {
+		if c.Seq > since && len(out) < limit {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockChangeRepo) LatestChange(ctx context.Context, userID int64, entity string) (*domain.Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		if m.changes[i].Entity == entity {
+			return &m.changes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestSyncService_Paging(t *testing.T) {
+	repo := &mockChangeRepo{}
+	for i := int64(1); i <= 5; i++ {
+		repo.changes = append(repo.changes, domain.Change{Seq: i * 10, Entity: domain.ChangeEntityWater, EntityID: i, Op: domain.ChangeOpUpsert})
+	}
+	svc := app.NewSyncService(repo)
+	ctx := context.Background()
+
+	page, err := svc.Changes(ctx, 1, 0, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Changes) != 3 || !page.HasMore || page.Cursor != 30 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	page, _ = svc.Changes(ctx, 1, page.Cursor, 3)
+	if len(page.Changes) != 2 || page.HasMore || page.Cursor != 50 {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+
+	page, _ = svc.Changes(ctx, 1, page.Cursor, 3)
+	if len(page.Changes) != 0 || page.HasMore || page.Cursor != 50 {
+		t.Fatalf("expected an empty page to keep the cursor, got %+v", page)
+	}
+	if page.Changes == nil {
+		t.Fatal("expected an empty, non-nil change list")
+	}
+}
+
+func TestSyncService_Watch(t *testing.T) {
+	repo := &mockChangeRepo{}
+	repo.add(domain.Change{Seq: 10, Entity: domain.ChangeEntityWeight, EntityID: 1, Op: domain.ChangeOpUpsert})
+	svc := app.NewSyncService(repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	next := func(ch <-chan domain.Change) domain.Change {
+		t.Helper()
+		select {
+		case c := <-ch:
+			return c
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a change")
+			return domain.Change{}
+		}
+	}
+
+	live, err := svc.Watch(ctx, 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, _ := svc.Watch(ctx, 1, 0)
+	if c := next(resumed); c.Seq != 10 {
+		t.Errorf("expected a resumed stream to replay seq 10, got %+v", c)
+	}
+
+	// A published event wakes the streams at once.
+	repo.add(domain.Change{Seq: 20, Entity: domain.ChangeEntityWater, EntityID: 2, Op: domain.ChangeOpUpsert})
+	svc.Publish(ctx, domain.MetricEvent{Type: domain.EventWaterRecorded, UserID: 1})
+	if c := next(live); c.Seq != 20 {
+		t.Errorf("expected the live stream to start after seq 10, got %+v", c)
+	}
+	if c := next(resumed); c.Seq != 20 {
+		t.Errorf("expected seq 20, got %+v", c)
+	}
+
+	cancel()
+	for range live {
+	}
+}
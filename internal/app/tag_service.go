@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vitals/internal/domain"
+)
+
+// ErrEntryNotFound is returned when tagging or editing an entry the user
+// does not have.
+var ErrEntryNotFound error = &domain.Error{Kind: domain.ErrNotFound, Message: "entry not found"}
+
+// tagScanLimit bounds how many recent entries a tag-filtered read scans.
+const tagScanLimit = 5000
+
+// TagService annotates weight and water entries with free-form tags.
+type TagService struct {
+	repo domain.TagRepository
+}
+
+// NewTagService creates a TagService backed by the given repository.
+func NewTagService(repo domain.TagRepository) *TagService {
+	return &TagService{repo: repo}
+}
+
+// SetTags replaces the tags on one of the user's entries and returns the
+// normalized tags.
+func (s *TagService) SetTags(ctx context.Context, userID int64, entity string, entryID int64, tags []string) ([]string, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if entity != domain.ChangeEntityWeight && entity != domain.ChangeEntityWater {
+		return nil, fmt.Errorf("unknown entity %q", entity)
+	}
+	tags, err := domain.NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.repo.SetEntryTags(ctx, userID, entity, entryID, tags)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrEntryNotFound
+	}
+	return tags, nil
+}
+
+// List returns the user's tags with their use counts.
+func (s *TagService) List(ctx context.Context, userID int64) ([]domain.TagCount, error) {
+	return s.repo.ListTags(ctx, userID)
+}
+
+// errTagsDisabled is returned for a tag filter when no TagRepository is
+// configured.
+var errTagsDisabled = errors.New("tag filters are not available")
+
+// tagWeights attaches tags to entries and keeps those matching f.
+func tagWeights(ctx context.Context, repo domain.TagRepository, userID int64, entries []domain.WeightEntry, f domain.TagFilter) ([]domain.WeightEntry, error) {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	tags, err := repo.EntryTags(ctx, userID, domain.ChangeEntityWeight, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.WeightEntry, 0, len(entries))
+	for _, e := range entries {
+		e.Tags = tags[e.ID]
+		if f.Match(e.Tags) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// tagWater attaches tags to events and keeps those matching f.
+func tagWater(ctx context.Context, repo domain.TagRepository, userID int64, events []domain.WaterEvent, f domain.TagFilter) ([]domain.WaterEvent, error) {
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	tags, err := repo.EntryTags(ctx, userID, domain.ChangeEntityWater, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.WaterEvent, 0, len(events))
+	for _, e := range events {
+		e.Tags = tags[e.ID]
+		if f.Match(e.Tags) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockTagRepo struct {
+	// tags maps entity to entry ID to tags.
+	tags map[string]map[int64][]string
+}
+
+func (m *mockTagRepo) SetEntryTags(ctx context.Context, userID int64, entity string, entryID int64, tags []string) (bool, error) {
+	if entryID != 1 {
+		return false, nil
+	}
+	if m.tags[entity] == nil {
+		m.tags[entity] = map[int64][]string{}
+	}
+	m.tags[entity][entryID] = tags
+	return true, nil
+}
+
+func (m *mockTagRepo) EntryTags(ctx context.Context, userID int64, entity string, ids []int64) (map[int64][]string, error) {
+	out := map[int64][]string{}
+	for _, id := range ids {
+		if tags, ok := m.tags[entity][id]; ok {
+			out[id] = tags
+		}
+	}
+	return out, nil
+}
+
+func (m *mockTagRepo) ListTags(ctx context.Context, userID int64) ([]domain.TagCount, error) {
+	return nil, nil
+}
+
+func TestTagService_SetTags(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockTagRepo{tags: map[string]map[int64][]string{}}
+	svc := app.NewTagService(repo)
+
+	tags, err := svc.SetTags(ctx, 1, domain.ChangeEntityWeight, 1, []string{"Sick", "sick", "travel"})
+	if err != nil {
+		t.Fatalf("SetTags failed: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != "sick" {
+		t.Fatalf("expected normalized tags, got %v", tags)
+	}
+	if _, err := svc.SetTags(ctx, 1, domain.ChangeEntityWeight, 2, []string{"sick"}); !errors.Is(err, app.ErrEntryNotFound) {
+		t.Fatalf("expected ErrEntryNotFound, got %v", err)
+	}
+	if _, err := svc.SetTags(ctx, 1, "sleep", 1, []string{"sick"}); err == nil {
+		t.Fatal("expected an error for an unknown entity")
+	}
+	if _, err := svc.SetTags(app.WithReadOnly(ctx), 1, domain.ChangeEntityWeight, 1, nil); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestTagFilters(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	tags := &mockTagRepo{tags: map[string]map[int64][]string{
+		domain.ChangeEntityWeight: {2: {"sick"}},
+		domain.ChangeEntityWater:  {20: {"sick"}},
+	}}
+	weights := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{
+				{ID: 2, Value: 75, Unit: "kg", CreatedAt: now},
+				{ID: 1, Value: 80, Unit: "kg", CreatedAt: yesterday},
+			}, nil
+		},
+	}
+	water := &mockWaterRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WaterEvent, error) {
+			return []domain.WaterEvent{
+				{ID: 20, DeltaLiters: 1, CreatedAt: now},
+				{ID: 10, DeltaLiters: 0.5, CreatedAt: now},
+			}, nil
+		},
+	}
+	notSick, _ := domain.ParseTagFilter([]string{"-sick"})
+
+	entries, sum, err := app.NewWeightService(weights).WithTags(tags).ListRecent(ctx, 1, 10, notSick, "")
+	if err != nil {
+		t.Fatalf("ListRecent failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != 1 || sum != nil {
+		t.Fatalf("expected only the untagged entry without a summary, got %+v, %+v", entries, sum)
+	}
+	if _, _, err := app.NewWeightService(weights).ListRecent(ctx, 1, 10, notSick, ""); err == nil {
+		t.Fatal("expected an error filtering without a tag repository")
+	}
+
+	points, err := app.NewChartsService(weights, water).WithTags(tags).GetDaily(ctx, 1, 2, "kg", notSick, "", "")
+	if err != nil {
+		t.Fatalf("GetDaily failed: %v", err)
+	}
+	if points[0].Weight == nil || points[0].Weight.Value != 80 || points[0].WaterLiters != 0 {
+		t.Fatalf("unexpected point for yesterday: %+v", points[0])
+	}
+	if points[1].Weight != nil || points[1].WaterLiters != 0.5 {
+		t.Fatalf("expected today's sick entries to be left out, got %+v", points[1])
+	}
+}
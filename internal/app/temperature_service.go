@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"vitals/internal/domain"
+)
+
+// TemperatureService records body temperature readings, for following an
+// illness or a cycle.
+type TemperatureService struct {
+	repo domain.TemperatureRepository
+}
+
+// NewTemperatureService creates a TemperatureService backed by the given
+// repository.
+func NewTemperatureService(repo domain.TemperatureRepository) *TemperatureService {
+	return &TemperatureService{repo: repo}
+}
+
+// Record validates and stores a reading in the unit it was taken in. With
+// r.ClientID set, retrying does not create a duplicate; the stored reading
+// is returned either way along with whether this call created it. A zero
+// r.CreatedAt means now.
+func (s *TemperatureService) Record(ctx context.Context, userID int64, r domain.TemperatureReading) (*domain.TemperatureReading, bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	var err error
+	if r.ClientID != "" {
+		r.ClientID, err = validateClientWrite(r.ClientID, &r.CreatedAt)
+	} else {
+		err = validateWriteTime(&r.CreatedAt)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := r.Validate(); err != nil {
+		return nil, false, err
+	}
+	r.CreatedAt = r.CreatedAt.UTC()
+	return s.repo.AddTemperatureReading(ctx, userID, r)
+}
+
+// ListRecent returns the user's latest readings up to limit, newest first.
+// A non-empty unit ("c" or "f") sets each reading's DisplayValue in it.
+func (s *TemperatureService) ListRecent(ctx context.Context, userID int64, limit int, unit string) ([]domain.TemperatureReading, error) {
+	if unit != "" && unit != domain.TemperatureCelsius && unit != domain.TemperatureFahrenheit {
+		return nil, fmt.Errorf("unit must be %q or %q", domain.TemperatureCelsius, domain.TemperatureFahrenheit)
+	}
+	items, err := s.repo.ListRecentTemperatureReadings(ctx, userID, limit)
+	if err != nil || unit == "" {
+		return items, err
+	}
+	for i := range items {
+		items[i].DisplayValue = domain.ConvertTemperature(items[i].Value, items[i].Unit, unit)
+		items[i].DisplayUnit = unit
+	}
+	return items, nil
+}
+
+// Delete removes one of the user's readings.
+func (s *TemperatureService) Delete(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteTemperatureReading(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrEntryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockTemperatureRepo struct {
+	readings []domain.TemperatureReading
+}
+
+func (m *mockTemperatureRepo) AddTemperatureReading(ctx context.Context, userID int64, r domain.TemperatureReading) (*domain.TemperatureReading, bool, error) {
+	r.ID = int64(len(m.readings) + 1)
+	r.UserID = userID
+	m.readings = append(m.readings, r)
+	return &r, true, nil
+}
+
+func (m *mockTemperatureRepo) ListRecentTemperatureReadings(ctx context.Context, userID int64, limit int) ([]domain.TemperatureReading, error) {
+	return append([]domain.TemperatureReading(nil), m.readings...), nil
+}
+
+func (m *mockTemperatureRepo) DeleteTemperatureReading(ctx context.Context, userID, id int64) (bool, error) {
+	for i, r := range m.readings {
+		if r.UserID == userID && r.ID == id {
+			m.readings = append(m.readings[:i], m.readings[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestTemperatureService_Record(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockTemperatureRepo{}
+	svc := app.NewTemperatureService(repo)
+
+	r, created, err := svc.Record(ctx, 1, domain.TemperatureReading{Value: 38.2, Unit: "C"})
+	if err != nil || !created || r.Unit != "c" || r.CreatedAt.IsZero() {
+		t.Fatalf("Record = %+v, %v, %v", r, created, err)
+	}
+
+	for name, bad := range map[string]domain.TemperatureReading{
+		"implausible":   {Value: 98.6, Unit: "c"},
+		"unknown unit":  {Value: 37, Unit: "k"},
+		"bad client id": {Value: 37, Unit: "c", ClientID: "retry-1"},
+		"future":        {Value: 37, Unit: "c", CreatedAt: time.Now().Add(time.Hour)},
+	} {
+		if _, _, err := svc.Record(ctx, 1, bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, _, err := svc.Record(app.WithReadOnly(ctx), 1, domain.TemperatureReading{Value: 37, Unit: "c"}); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if len(repo.readings) != 1 {
+		t.Errorf("expected only the valid reading stored, got %d", len(repo.readings))
+	}
+}
+
+func TestTemperatureService_ListRecentAndDelete(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewTemperatureService(&mockTemperatureRepo{})
+	r, _, _ := svc.Record(ctx, 1, domain.TemperatureReading{Value: 37, Unit: "c"})
+
+	items, err := svc.ListRecent(ctx, 1, 10, "f")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("ListRecent = %+v, %v", items, err)
+	}
+	if items[0].DisplayUnit != "f" || math.Abs(items[0].DisplayValue-98.6) > 0.001 || items[0].Value != 37 {
+		t.Errorf("expected 37 °C displayed as 98.6 °F, got %+v", items[0])
+	}
+	if items, _ := svc.ListRecent(ctx, 1, 10, ""); items[0].DisplayUnit != "" {
+		t.Errorf("expected no display conversion without a unit, got %+v", items[0])
+	}
+	if _, err := svc.ListRecent(ctx, 1, 10, "k"); err == nil {
+		t.Error("expected error for an unknown unit")
+	}
+
+	if err := svc.Delete(ctx, 2, r.ID); !errors.Is(err, app.ErrEntryNotFound) {
+		t.Errorf("expected ErrEntryNotFound deleting another user's reading, got %v", err)
+	}
+	if err := svc.Delete(ctx, 1, r.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+}
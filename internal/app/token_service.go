@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+var (
+	// ErrInvalidToken indicates that an API token is unknown or lacks the
+	// required scope.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenNotFound indicates that the API token to revoke does not exist.
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// tokenTouchInterval is how stale a token's last-used time may get before
+// a request refreshes it, so not every request writes.
+const tokenTouchInterval = 5 * time.Minute
+
+// TokenService issues and validates scoped API tokens.
+type TokenService struct {
+	tokens domain.APITokenRepository
+	users  domain.UserRepository
+}
+
+// NewTokenService creates a TokenService backed by the given repositories.
+func NewTokenService(tokens domain.APITokenRepository, users domain.UserRepository) *TokenService {
+	return &TokenService{tokens: tokens, users: users}
+}
+
+// Create issues a new token and returns its secret, which is not retrievable
+// afterwards.
+func (s *TokenService) Create(ctx context.Context, userID int64, name, scope string) (string, *domain.APIToken, error) {
+	if err := checkWritable(ctx); err != nil {
+		return "", nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return "", nil, errors.New("name must be 1-64 characters")
+	}
+	if !domain.ValidTokenScope(scope) {
+		return "", nil, errors.New("unknown scope")
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	tok, err := s.tokens.CreateAPIToken(ctx, userID, name, scope, hashToken(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, tok, nil
+}
+
+// List returns the user's tokens without their secrets.
+func (s *TokenService) List(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	return s.tokens.ListAPITokens(ctx, userID)
+}
+
+// Revoke deletes one of the user's tokens.
+func (s *TokenService) Revoke(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	deleted, err := s.tokens.DeleteAPIToken(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a token secret to its user, requiring scope.
+func (s *TokenService) Authenticate(ctx context.Context, secret, scope string) (*domain.User, error) {
+	user, _, err := s.AuthenticateAny(ctx, secret, []string{scope})
+	return user, err
+}
+
+// AuthenticateAny resolves a token secret to its user and scope, requiring
+// one of scopes.
+func (s *TokenService) AuthenticateAny(ctx context.Context, secret string, scopes []string) (*domain.User, string, error) {
+	if secret == "" {
+		return nil, "", ErrInvalidToken
+	}
+	tok, err := s.tokens.GetAPITokenByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, "", err
+	}
+	if tok == nil || !slices.Contains(scopes, tok.Scope) {
+		return nil, "", ErrInvalidToken
+	}
+	user, err := s.users.GetByID(ctx, tok.UserID)
+	if err != nil || user == nil {
+		return nil, "", ErrInvalidToken
+	}
+	if now := time.Now(); tok.LastUsedAt == nil || now.Sub(*tok.LastUsedAt) >= tokenTouchInterval {
+		_ = s.tokens.TouchAPIToken(ctx, tok.ID, now)
+	}
+	return user, tok.Scope, nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockTokenRepo struct {
+	tokens []domain.APIToken
+}
+
+func (m *mockTokenRepo) CreateAPIToken(ctx context.Context, userID int64, name, scope, tokenHash string) (*domain.APIToken, error) {
+	t := domain.APIToken{ID: int64(len(m.tokens) + 1), UserID: userID, Name: name, Scope: scope, TokenHash: tokenHash}
+	m.tokens = append(m.tokens, t)
+	return &t, nil
+}
+
+func (m *mockTokenRepo) GetAPITokenByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockTokenRepo) ListAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	return m.tokens, nil
+}
+
+func (m *mockTokenRepo) DeleteAPIToken(ctx context.Context, userID, id int64) (bool, error) {
+	for i, t := range m.tokens {
+		if t.ID == id && t.UserID == userID {
+			m.tokens = append(m.tokens[:i], m.tokens[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockTokenRepo) DeleteAPITokens(ctx context.Context, userID int64) (int, error) {
+	before := len(m.tokens)
+	m.tokens = slices.DeleteFunc(m.tokens, func(t domain.APIToken) bool { return t.UserID == userID })
+	return before - len(m.tokens), nil
+}
+
+func (m *mockTokenRepo) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	for i := range m.tokens {
+		if m.tokens[i].ID == id {
+			m.tokens[i].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func TestTokenService(t *testing.T) {
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id int64) (*domain.User, error) {
+			return &domain.User{ID: id, Username: "alice"}, nil
+		},
+	}
+	repo := &mockTokenRepo{}
+	svc := app.NewTokenService(repo, users)
+	ctx := context.Background()
+
+	if _, _, err := svc.Create(ctx, 1, "phone", "admin"); err == nil {
+		t.Fatal("expected unknown scope to be rejected")
+	}
+
+	secret, tok, err := svc.Create(ctx, 1, "phone", domain.TokenScopeQuick)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if secret == "" || tok.TokenHash == secret {
+		t.Fatal("expected the secret to be returned and only its hash stored")
+	}
+
+	user, err := svc.Authenticate(ctx, secret, domain.TokenScopeQuick)
+	if err != nil || user.ID != 1 {
+		t.Fatalf("Authenticate: user=%v err=%v", user, err)
+	}
+	used := repo.tokens[0].LastUsedAt
+	if used == nil {
+		t.Fatal("expected Authenticate to record the token's use")
+	}
+	if _, err := svc.Authenticate(ctx, secret, domain.TokenScopeQuick); err != nil || repo.tokens[0].LastUsedAt != used {
+		t.Errorf("expected a second use right after not to write, got %v, %v", repo.tokens[0].LastUsedAt, err)
+	}
+	if _, err := svc.Authenticate(ctx, secret, "other"); !errors.Is(err, app.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for wrong scope, got %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, "bogus", domain.TokenScopeQuick); !errors.Is(err, app.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for unknown secret, got %v", err)
+	}
+
+	if err := svc.Revoke(ctx, 1, tok.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, secret, domain.TokenScopeQuick); !errors.Is(err, app.ErrInvalidToken) {
+		t.Fatalf("expected revoked token to fail, got %v", err)
+	}
+}
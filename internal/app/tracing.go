@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer names the services' spans. Until a tracer provider is installed,
+// e.g. by tracing.Setup, spans are no-ops.
+var tracer = otel.Tracer("vitals/internal/app")
+
+// startSpan starts a span for a service operation. End it with endSpan.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// userAttr identifies the user whose data an operation touches.
+func userAttr(userID int64) attribute.KeyValue {
+	return attribute.Int64("vitals.user_id", userID)
+}
+
+// endSpan records err, if any, as the span's outcome and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
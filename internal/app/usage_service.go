@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+const (
+	defaultUsageWeeks = 12
+	maxUsageWeeks     = 104
+)
+
+// UsageWeek reports one week of instance-wide activity.
+type UsageWeek struct {
+	domain.UsageWeek
+	// EntriesPerDay is the mean number of entries an active user logged per
+	// day of the week, counting only the days elapsed in the current week.
+	EntriesPerDay float64 `json:"entriesPerDay"`
+}
+
+// UsageStats summarizes how an instance is used, for admins of shared
+// instances. It is built from counts alone, so it reveals neither who is
+// active nor anyone's measurements.
+type UsageStats struct {
+	// Weeks runs from the oldest week requested to the current one,
+	// including weeks without activity.
+	Weeks []UsageWeek `json:"weeks"`
+	// PeakActiveUsers is the most active users in any of the weeks.
+	PeakActiveUsers int `json:"peakActiveUsers"`
+	// EntriesPerDay is the mean of the active weeks' EntriesPerDay.
+	EntriesPerDay float64 `json:"entriesPerDay"`
+}
+
+// UsageService reports anonymized instance-wide usage. It is opt-in: the
+// server offers it only when the operator enables it.
+type UsageService struct {
+	repo domain.UsageRepository
+	now  func() time.Time
+}
+
+// NewUsageService creates a UsageService backed by the given repository.
+func NewUsageService(repo domain.UsageRepository) *UsageService {
+	return &UsageService{repo: repo, now: time.Now}
+}
+
+// Weekly reports the last weeks weeks of usage, including the current one.
+func (s *UsageService) Weekly(ctx context.Context, weeks int) (*UsageStats, error) {
+	if weeks <= 0 {
+		weeks = defaultUsageWeeks
+	}
+	if weeks > maxUsageWeeks {
+		weeks = maxUsageWeeks
+	}
+	now := s.now().UTC()
+	current := domain.UsageWeekOf(now)
+	first := current.AddDate(0, 0, -7*(weeks-1))
+
+	rows, err := s.repo.WeeklyUsage(ctx, first)
+	if err != nil {
+		return nil, err
+	}
+	byStart := make(map[string]domain.UsageWeek, len(rows))
+	for _, w := range rows {
+		byStart[w.WeekStart] = w
+	}
+
+	stats := &UsageStats{Weeks: make([]UsageWeek, 0, weeks)}
+	active := 0
+	for start := first; !start.After(current); start = start.AddDate(0, 0, 7) {
+		w := UsageWeek{UsageWeek: domain.UsageWeek{WeekStart: start.Format("2006-01-02")}}
+		if row, ok := byStart[w.WeekStart]; ok {
+			w.UsageWeek = row
+		}
+		if w.ActiveUsers > 0 {
+			days := 7.0
+			if start.Equal(current) {
+				days = max(1, math.Ceil(now.Sub(start).Hours()/24))
+			}
+			w.EntriesPerDay = math.Round(float64(w.Entries)/float64(w.ActiveUsers)/days*100) / 100
+			stats.EntriesPerDay += w.EntriesPerDay
+			active++
+		}
+		stats.PeakActiveUsers = max(stats.PeakActiveUsers, w.ActiveUsers)
+		stats.Weeks = append(stats.Weeks, w)
+	}
+	if active > 0 {
+		stats.EntriesPerDay = math.Round(stats.EntriesPerDay/float64(active)*100) / 100
+	}
+	return stats, nil
+}
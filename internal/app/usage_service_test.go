@@ -0,0 +1,54 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockUsageRepo struct {
+	weeks []domain.UsageWeek
+	since time.Time
+}
+
+func (m *mockUsageRepo) WeeklyUsage(_ context.Context, since time.Time) ([]domain.UsageWeek, error) {
+	m.since = since
+	return m.weeks, nil
+}
+
+func TestUsageService_Weekly(t *testing.T) {
+	current := domain.UsageWeekOf(time.Now())
+	lastWeek := current.AddDate(0, 0, -7).Format("2006-01-02")
+	repo := &mockUsageRepo{weeks: []domain.UsageWeek{
+		{WeekStart: current.AddDate(0, 0, -21).Format("2006-01-02"), ActiveUsers: 1, Entries: 7},
+		{WeekStart: lastWeek, ActiveUsers: 2, Entries: 28},
+	}}
+	svc := app.NewUsageService(repo)
+
+	stats, err := svc.Weekly(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("Weekly: %v", err)
+	}
+	if !repo.since.Equal(current.AddDate(0, 0, -21)) {
+		t.Errorf("expected four weeks read, from %v", repo.since)
+	}
+	if len(stats.Weeks) != 4 || stats.Weeks[3].WeekStart != current.Format("2006-01-02") {
+		t.Fatalf("expected four weeks ending with the current one, got %+v", stats.Weeks)
+	}
+	if w := stats.Weeks[1]; w.ActiveUsers != 0 || w.EntriesPerDay != 0 {
+		t.Errorf("expected an empty week filled in, got %+v", w)
+	}
+	if w := stats.Weeks[2]; w.WeekStart != lastWeek || w.EntriesPerDay != 2 {
+		t.Errorf("expected 2 entries per user per day last week, got %+v", w)
+	}
+	if stats.PeakActiveUsers != 2 || stats.EntriesPerDay != 1.5 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+
+	if stats, _ := svc.Weekly(context.Background(), 1000); len(stats.Weeks) != 104 {
+		t.Errorf("expected weeks capped at 104, got %d", len(stats.Weeks))
+	}
+}
@@ -2,7 +2,7 @@ package app
 
 import (
 	"context"
-	"errors"
+	"sync"
 	"time"
 
 	"vitals/internal/domain"
@@ -10,7 +10,27 @@ import (
 
 // WaterService encapsulates water-tracking use cases.
 type WaterService struct {
-	repo domain.WaterRepository
+	repo      domain.WaterRepository
+	publisher domain.EventPublisher
+	tags      domain.TagRepository
+
+	// dupWindow and dupMerge configure the duplicate guard; a zero window
+	// disables it. dupMu serializes guarded writes on this instance so that
+	// two taps racing each other are still seen as a pair.
+	dupWindow time.Duration
+	dupMerge  bool
+	dupMu     sync.Mutex
+}
+
+// WaterRecord is the outcome of RecordEvent.
+type WaterRecord struct {
+	ID int64
+	// Duplicate reports that the event repeats the user's previous one
+	// within the duplicate guard's window.
+	Duplicate bool
+	// Merged reports that the duplicate was not stored; ID is the previous
+	// event's.
+	Merged bool
 }
 
 // NewWaterService creates a WaterService backed by the given repository.
@@ -18,35 +38,223 @@ func NewWaterService(repo domain.WaterRepository) *WaterService {
 	return &WaterService{repo: repo}
 }
 
+// WithPublisher broadcasts every recorded water event, with the updated
+// daily total, to p.
+func (s *WaterService) WithPublisher(p domain.EventPublisher) *WaterService {
+	s.publisher = p
+	return s
+}
+
+// WithTags attaches tags to listed entries and enables tag filters.
+func (s *WaterService) WithTags(repo domain.TagRepository) *WaterService {
+	s.tags = repo
+	return s
+}
+
+// WithDuplicateGuard catches double taps from clients that send no client
+// ID, such as a retried request on a flaky connection: an event with the
+// same amount as the user's previous one, recorded less than window after
+// it, is stored and flagged or, with merge, dropped in favor of the previous
+// one. Events with a client ID are deduplicated by it instead and never
+// guarded.
+func (s *WaterService) WithDuplicateGuard(window time.Duration, merge bool) *WaterService {
+	s.dupWindow = window
+	s.dupMerge = merge
+	return s
+}
+
 // GetTodayTotal returns the total water intake in liters for the given local day.
 func (s *WaterService) GetTodayTotal(ctx context.Context, userID int64, today string) (float64, error) {
-	return s.repo.WaterTotalForLocalDay(ctx, userID, today)
+	return waterTotal(ctx, s.repo, userID, today)
+}
+
+// waterTotal returns the user's water total for the local day, rounded
+// with domain.RoundLiters.
+func waterTotal(ctx context.Context, repo domain.WaterRepository, userID int64, day string) (float64, error) {
+	total, err := repo.WaterTotalForLocalDay(ctx, userID, day)
+	return domain.RoundLiters(total), err
+}
+
+// RecordEvent validates and stores a water intake event. With the
+// duplicate guard on, a repeat of the previous event is flagged or, when
+// merged, not stored at all.
+func (s *WaterService) RecordEvent(ctx context.Context, userID int64, deltaLiters float64) (WaterRecord, error) {
+	if err := checkWritable(ctx); err != nil {
+		return WaterRecord{}, err
+	}
+	if err := validateWaterDelta(deltaLiters); err != nil {
+		return WaterRecord{}, err
+	}
+	var rec WaterRecord
+	if s.dupWindow > 0 {
+		s.dupMu.Lock()
+		defer s.dupMu.Unlock()
+		prev, err := s.previousIfDuplicate(ctx, userID, deltaLiters)
+		if err != nil {
+			return WaterRecord{}, err
+		}
+		if prev != nil {
+			rec.Duplicate = true
+			if s.dupMerge {
+				return WaterRecord{ID: prev.ID, Duplicate: true, Merged: true}, nil
+			}
+		}
+	}
+	now := time.Now()
+	id, err := s.repo.AddWaterEvent(ctx, userID, deltaLiters, now)
+	if err != nil {
+		return WaterRecord{}, err
+	}
+	s.publish(ctx, userID, deltaLiters, now)
+	rec.ID = id
+	return rec, nil
+}
+
+// previousIfDuplicate returns the user's latest water event if it has the
+// same amount and was recorded within the duplicate window, or nil.
+func (s *WaterService) previousIfDuplicate(ctx context.Context, userID int64, deltaLiters float64) (*domain.WaterEvent, error) {
+	latest, err := s.repo.ListRecentWaterEvents(ctx, userID, 1)
+	if err != nil || len(latest) == 0 {
+		return nil, err
+	}
+	prev := latest[0]
+	if age := time.Since(prev.CreatedAt); prev.DeltaLiters != deltaLiters || age < 0 || age >= s.dupWindow {
+		return nil, nil
+	}
+	return &prev, nil
 }
 
-// RecordEvent validates and stores a water intake event.
-func (s *WaterService) RecordEvent(ctx context.Context, userID int64, deltaLiters float64) (int64, error) {
+// RecordEventWithClientID stores a water event tagged with a
+// client-generated UUID, as queued by an offline client. Retrying with the
+// same client ID does not create a duplicate; the stored event is returned
+// either way along with whether this call created it. A zero at means now.
+func (s *WaterService) RecordEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, at time.Time) (_ *domain.WaterEvent, _ bool, err error) {
+	ctx, span := startSpan(ctx, "WaterService.RecordEvent", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	clientID, err = validateClientWrite(clientID, &at)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validateWaterDelta(deltaLiters); err != nil {
+		return nil, false, err
+	}
+	event, created, err := s.repo.AddWaterEventWithClientID(ctx, userID, clientID, deltaLiters, at)
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		s.publish(ctx, userID, deltaLiters, at)
+	}
+	return event, created, nil
+}
+
+// EditEvent corrects the volume or time of one of the user's water events,
+// e.g. when 0.5 L was logged but only 0.3 L drunk. The edit is recorded in
+// the change log so syncing clients pick it up.
+func (s *WaterService) EditEvent(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if p.DeltaLiters == nil && p.CreatedAt == nil {
+		return nil, domain.Errorf(domain.ErrInvalidArgument, "deltaLiters or createdAt is required")
+	}
+	if p.DeltaLiters != nil {
+		if err := validateWaterDelta(*p.DeltaLiters); err != nil {
+			return nil, err
+		}
+	}
+	if p.CreatedAt != nil && (p.CreatedAt.IsZero() || p.CreatedAt.After(time.Now().Add(maxClientClockSkew))) {
+		return nil, domain.Errorf(domain.ErrInvalidArgument, "createdAt must be set and not in the future")
+	}
+	event, err := s.repo.UpdateWaterEvent(ctx, userID, id, p)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrEntryNotFound
+	}
+	return event, nil
+}
+
+func (s *WaterService) publish(ctx context.Context, userID int64, deltaLiters float64, at time.Time) {
+	if s.publisher == nil {
+		return
+	}
+	day := at.In(time.Local).Format("2006-01-02")
+	total, err := waterTotal(ctx, s.repo, userID, day)
+	if err != nil {
+		return
+	}
+	s.publisher.Publish(ctx, domain.MetricEvent{
+		Type: domain.EventWaterRecorded, UserID: userID, Day: day,
+		Value: deltaLiters, Unit: "l", DayTotal: total, At: at,
+	})
+}
+
+func validateWaterDelta(deltaLiters float64) error {
 	if deltaLiters == 0 || deltaLiters < -10 || deltaLiters > 10 {
-		return 0, errors.New("deltaLiters must be non-zero and within [-10, 10]")
+		return domain.Errorf(domain.ErrValueOutOfRange, "deltaLiters must be non-zero and within [-10, 10]")
+	}
+	return nil
+}
+
+// Export calls fn with the user's water events selected by f, oldest
+// first, for a download.
+func (s *WaterService) Export(ctx context.Context, userID int64, f domain.ExportFilter, fn func(domain.WaterEvent) error) error {
+	if !f.Includes(domain.ExportMetricWater) {
+		return nil
 	}
-	return s.repo.AddWaterEvent(ctx, userID, deltaLiters, time.Now())
+	return exportRange(f, func(e domain.WaterEvent) time.Time { return e.CreatedAt }, fn, func(fn func(domain.WaterEvent) error) error {
+		return s.repo.EachWaterEvent(ctx, userID, fn)
+	})
 }
 
-// ListRecent returns the most recent water events up to limit.
-func (s *WaterService) ListRecent(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error) {
-	return s.repo.ListRecentWaterEvents(ctx, userID, limit)
+// ListRecent returns the most recent water events up to limit, with their
+// tags, and a summary of all the user's water events. With a non-empty
+// filter, the latest tagScanLimit events are scanned for up to limit
+// matches and there is no summary.
+func (s *WaterService) ListRecent(ctx context.Context, userID int64, limit int, f domain.TagFilter) ([]domain.WaterEvent, *domain.ListSummary, error) {
+	if f.IsZero() {
+		items, sum, err := s.repo.ListRecentWaterPage(ctx, userID, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s.tags != nil {
+			if items, err = tagWater(ctx, s.tags, userID, items, f); err != nil {
+				return nil, nil, err
+			}
+		}
+		return items, &sum, nil
+	}
+	if s.tags == nil {
+		return nil, nil, errTagsDisabled
+	}
+	items, err := s.repo.ListRecentWaterEvents(ctx, userID, tagScanLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err = tagWater(ctx, s.tags, userID, items, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil, nil
 }
 
 // UndoLast deletes the most recent water event.
 func (s *WaterService) UndoLast(ctx context.Context, userID int64) (bool, int64, error) {
-	items, err := s.repo.ListRecentWaterEvents(ctx, userID, 1)
-	if err != nil {
+	if err := checkWritable(ctx); err != nil {
 		return false, 0, err
 	}
-	if len(items) == 0 {
-		return false, 0, nil
-	}
-	if err := s.repo.DeleteWaterEvent(ctx, userID, items[0].ID); err != nil {
+	id, err := s.repo.DeleteLatestWaterEvent(ctx, userID)
+	if err != nil {
 		return false, 0, err
 	}
-	return true, items[0].ID, nil
+	return id != 0, id, nil
 }
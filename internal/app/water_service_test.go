@@ -2,6 +2,7 @@ package app_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,10 +11,14 @@ import (
 )
 
 type mockWaterRepo struct {
-	addFn   func(ctx context.Context, userID int64, d float64, t time.Time) (int64, error)
-	delFn   func(ctx context.Context, userID int64, id int64) error
-	listFn  func(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error)
-	totalFn func(ctx context.Context, userID int64, day string) (float64, error)
+	addClientFn func(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error)
+	addFn       func(ctx context.Context, userID int64, d float64, t time.Time) (int64, error)
+	delFn       func(ctx context.Context, userID int64, id int64) error
+	delLatestFn func(ctx context.Context, userID int64) (int64, error)
+	listFn      func(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error)
+	totalFn     func(ctx context.Context, userID int64, day string) (float64, error)
+	updateFn    func(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error)
+	eachFn      func(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error
 }
 
 func (m *mockWaterRepo) AddWaterEvent(ctx context.Context, userID int64, d float64, t time.Time) (int64, error) {
@@ -23,6 +28,13 @@ func (m *mockWaterRepo) AddWaterEvent(ctx context.Context, userID int64, d float
 	return 0, nil
 }
 
+func (m *mockWaterRepo) AddWaterEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*domain.WaterEvent, bool, error) {
+	if m.addClientFn != nil {
+		return m.addClientFn(ctx, userID, clientID, deltaLiters, createdAt)
+	}
+	return &domain.WaterEvent{ID: 42, UserID: userID, DeltaLiters: deltaLiters, ClientID: clientID, CreatedAt: createdAt}, true, nil
+}
+
 func (m *mockWaterRepo) DeleteWaterEvent(ctx context.Context, userID int64, id int64) error {
 	if m.delFn != nil {
 		return m.delFn(ctx, userID, id)
@@ -30,6 +42,20 @@ func (m *mockWaterRepo) DeleteWaterEvent(ctx context.Context, userID int64, id i
 	return nil
 }
 
+func (m *mockWaterRepo) DeleteLatestWaterEvent(ctx context.Context, userID int64) (int64, error) {
+	if m.delLatestFn != nil {
+		return m.delLatestFn(ctx, userID)
+	}
+	return 0, nil
+}
+
+func (m *mockWaterRepo) UpdateWaterEvent(ctx context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, userID, id, p)
+	}
+	return nil, nil
+}
+
 func (m *mockWaterRepo) ListRecentWaterEvents(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, error) {
 	if m.listFn != nil {
 		return m.listFn(ctx, userID, limit)
@@ -37,6 +63,18 @@ func (m *mockWaterRepo) ListRecentWaterEvents(ctx context.Context, userID int64,
 	return nil, nil
 }
 
+func (m *mockWaterRepo) ListRecentWaterPage(ctx context.Context, userID int64, limit int) ([]domain.WaterEvent, domain.ListSummary, error) {
+	items, err := m.ListRecentWaterEvents(ctx, userID, limit)
+	return items, domain.ListSummary{Total: len(items)}, err
+}
+
+func (m *mockWaterRepo) EachWaterEvent(ctx context.Context, userID int64, fn func(domain.WaterEvent) error) error {
+	if m.eachFn != nil {
+		return m.eachFn(ctx, userID, fn)
+	}
+	return nil
+}
+
 func (m *mockWaterRepo) WaterTotalForLocalDay(ctx context.Context, userID int64, day string) (float64, error) {
 	if m.totalFn != nil {
 		return m.totalFn(ctx, userID, day)
@@ -70,21 +108,56 @@ func TestRecordWaterEvent_Success(t *testing.T) {
 		addFn: func(_ context.Context, _ int64, _ float64, _ time.Time) (int64, error) { return 42, nil },
 	}
 	svc := app.NewWaterService(repo)
-	id, err := svc.RecordEvent(context.Background(), 1, 0.25)
+	rec, err := svc.RecordEvent(context.Background(), 1, 0.25)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if id != 42 {
-		t.Fatalf("expected id 42, got %d", id)
+	if rec.ID != 42 || rec.Duplicate {
+		t.Fatalf("expected id 42 and no duplicate, got %+v", rec)
 	}
 }
 
-func TestUndoLastWater_Empty(t *testing.T) {
+func TestRecordWaterEvent_DuplicateGuard(t *testing.T) {
+	var events []domain.WaterEvent
 	repo := &mockWaterRepo{
-		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WaterEvent, error) {
-			return nil, nil
+		addFn: func(_ context.Context, userID int64, d float64, at time.Time) (int64, error) {
+			events = append(events, domain.WaterEvent{ID: int64(len(events) + 1), UserID: userID, DeltaLiters: d, CreatedAt: at})
+			return int64(len(events)), nil
 		},
+		listFn: func(_ context.Context, _ int64, limit int) ([]domain.WaterEvent, error) {
+			if len(events) == 0 {
+				return nil, nil
+			}
+			return events[len(events)-1:], nil
+		},
+	}
+	ctx := context.Background()
+
+	svc := app.NewWaterService(repo).WithDuplicateGuard(5*time.Second, false)
+	first, _ := svc.RecordEvent(ctx, 1, 0.25)
+	flagged, err := svc.RecordEvent(ctx, 1, 0.25)
+	if err != nil || !flagged.Duplicate || flagged.Merged || flagged.ID == first.ID || len(events) != 2 {
+		t.Fatalf("expected the repeat stored and flagged, got %+v, %v", flagged, err)
+	}
+	if other, _ := svc.RecordEvent(ctx, 1, 0.5); other.Duplicate {
+		t.Errorf("expected a different amount not to be a duplicate, got %+v", other)
+	}
+
+	svc = app.NewWaterService(repo).WithDuplicateGuard(5*time.Second, true)
+	merged, err := svc.RecordEvent(ctx, 1, 0.5)
+	if err != nil || !merged.Merged || merged.ID != 3 || len(events) != 3 {
+		t.Fatalf("expected the repeat merged into event 3, got %+v, %v", merged, err)
+	}
+
+	// Outside the window the same amount is a new drink.
+	events[2].CreatedAt = time.Now().Add(-10 * time.Second)
+	if later, _ := svc.RecordEvent(ctx, 1, 0.5); later.Duplicate || len(events) != 4 {
+		t.Errorf("expected a repeat after the window stored, got %+v", later)
 	}
+}
+
+func TestUndoLastWater_Empty(t *testing.T) {
+	repo := &mockWaterRepo{}
 	svc := app.NewWaterService(repo)
 	undone, _, err := svc.UndoLast(context.Background(), 1)
 	if err != nil {
@@ -97,14 +170,11 @@ func TestUndoLastWater_Empty(t *testing.T) {
 
 func TestUndoLastWater_Success(t *testing.T) {
 	repo := &mockWaterRepo{
-		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WaterEvent, error) {
-			return []domain.WaterEvent{{ID: 7, DeltaLiters: 0.5}}, nil
-		},
-		delFn: func(_ context.Context, _ int64, id int64) error {
-			if id != 7 {
-				t.Fatalf("expected delete id 7, got %d", id)
+		delLatestFn: func(_ context.Context, userID int64) (int64, error) {
+			if userID != 1 {
+				t.Fatalf("expected user 1, got %d", userID)
 			}
-			return nil
+			return 7, nil
 		},
 	}
 	svc := app.NewWaterService(repo)
@@ -123,7 +193,8 @@ func TestGetTodayTotal(t *testing.T) {
 			if day != "2026-02-08" {
 				t.Fatalf("unexpected day: %s", day)
 			}
-			return 2.5, nil
+			// A sum of float deltas is rounded to the millilitre.
+			return 2.4999999997, nil
 		},
 	}
 	svc := app.NewWaterService(repo)
@@ -135,3 +206,133 @@ func TestGetTodayTotal(t *testing.T) {
 		t.Fatalf("expected 2.5, got %v", total)
 	}
 }
+
+func TestWaterService_ReadOnly(t *testing.T) {
+	repo := &mockWaterRepo{
+		addFn: func(_ context.Context, _ int64, _ float64, _ time.Time) (int64, error) {
+			t.Fatal("write reached the repository")
+			return 0, nil
+		},
+	}
+	svc := app.NewWaterService(repo)
+	ctx := app.WithReadOnly(context.Background())
+
+	if _, err := svc.RecordEvent(ctx, 1, 0.5); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if _, _, err := svc.UndoLast(ctx, 1); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+type recordingPublisher struct {
+	events []domain.MetricEvent
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e domain.MetricEvent) {
+	p.events = append(p.events, e)
+}
+
+func TestWaterService_PublishesEvent(t *testing.T) {
+	repo := &mockWaterRepo{
+		totalFn: func(_ context.Context, _ int64, _ string) (float64, error) { return 1.75, nil },
+	}
+	pub := &recordingPublisher{}
+	svc := app.NewWaterService(repo).WithPublisher(pub)
+
+	if _, err := svc.RecordEvent(context.Background(), 3, 0.25); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(pub.events))
+	}
+	e := pub.events[0]
+	if e.Type != domain.EventWaterRecorded || e.UserID != 3 || e.Value != 0.25 || e.DayTotal != 1.75 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	if _, err := svc.RecordEvent(context.Background(), 3, 0); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if len(pub.events) != 1 {
+		t.Fatal("rejected writes must not publish")
+	}
+}
+
+func TestWaterService_RecordEventWithClientID(t *testing.T) {
+	const clientID = "0B6F3C1E-8A3D-4C5E-9F2A-1D2E3F4A5B6C"
+	stored := map[string]*domain.WaterEvent{}
+	repo := &mockWaterRepo{
+		addClientFn: func(_ context.Context, userID int64, id string, d float64, at time.Time) (*domain.WaterEvent, bool, error) {
+			if e, ok := stored[id]; ok {
+				return e, false, nil
+			}
+			e := &domain.WaterEvent{ID: int64(len(stored) + 1), UserID: userID, DeltaLiters: d, ClientID: id, CreatedAt: at}
+			stored[id] = e
+			return e, true, nil
+		},
+	}
+	pub := &recordingPublisher{}
+	svc := app.NewWaterService(repo).WithPublisher(pub)
+	ctx := context.Background()
+	queuedAt := time.Now().Add(-2 * time.Hour)
+
+	e, created, err := svc.RecordEventWithClientID(ctx, 1, clientID, 0.5, queuedAt)
+	if err != nil || !created {
+		t.Fatalf("expected create, got created=%v err=%v", created, err)
+	}
+	if e.ClientID != "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c" || !e.CreatedAt.Equal(queuedAt) {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if _, created, _ := svc.RecordEventWithClientID(ctx, 1, clientID, 0.5, queuedAt); created {
+		t.Fatal("expected retry to be deduplicated")
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected 1 published event, got %d", len(pub.events))
+	}
+
+	if _, _, err := svc.RecordEventWithClientID(ctx, 1, "nope", 0.5, time.Time{}); err == nil {
+		t.Error("expected error for malformed client id")
+	}
+	if _, _, err := svc.RecordEventWithClientID(ctx, 1, clientID, 0.5, time.Now().Add(time.Hour)); err == nil {
+		t.Error("expected error for a future timestamp")
+	}
+}
+
+func TestWaterService_EditEvent(t *testing.T) {
+	var applied domain.WaterEventPatch
+	repo := &mockWaterRepo{
+		updateFn: func(_ context.Context, userID, id int64, p domain.WaterEventPatch) (*domain.WaterEvent, error) {
+			if userID != 1 || id != 7 {
+				return nil, nil
+			}
+			applied = p
+			return &domain.WaterEvent{ID: id, UserID: userID, DeltaLiters: *p.DeltaLiters}, nil
+		},
+	}
+	svc := app.NewWaterService(repo)
+	ctx := context.Background()
+	liters := 0.3
+
+	e, err := svc.EditEvent(ctx, 1, 7, domain.WaterEventPatch{DeltaLiters: &liters})
+	if err != nil || e.DeltaLiters != 0.3 || applied.CreatedAt != nil {
+		t.Fatalf("EditEvent = %+v, %v", e, err)
+	}
+	if _, err := svc.EditEvent(ctx, 2, 7, domain.WaterEventPatch{DeltaLiters: &liters}); !errors.Is(err, app.ErrEntryNotFound) {
+		t.Errorf("expected ErrEntryNotFound for another user's event, got %v", err)
+	}
+
+	zero, future := 0.0, time.Now().Add(time.Hour)
+	for name, p := range map[string]domain.WaterEventPatch{
+		"empty":  {},
+		"zero":   {DeltaLiters: &zero},
+		"future": {CreatedAt: &future},
+	} {
+		if _, err := svc.EditEvent(ctx, 1, 7, p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := svc.EditEvent(app.WithReadOnly(ctx), 1, 7, domain.WaterEventPatch{DeltaLiters: &liters}); !errors.Is(err, app.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
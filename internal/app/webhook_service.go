@@ -0,0 +1,304 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+// ErrWebhookNotFound is returned when the user has no webhook with the
+// given id.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const (
+	// maxWebhookURLLength bounds registered URLs.
+	maxWebhookURLLength = 2048
+	// webhookRetention is how long deliveries are kept in the log.
+	webhookRetention = 30 * 24 * time.Hour
+)
+
+// WebhookService manages users' webhooks and delivers their events. Events
+// are queued as deliveries when published and POSTed by Drain, which
+// retries failures with the outbox's backoff; delivered and abandoned
+// deliveries stay in the webhook's log for webhookRetention.
+type WebhookService struct {
+	repo      domain.WebhookRepository
+	sender    domain.WebhookSender
+	hydration *HydrationService
+	now       func() time.Time
+}
+
+var _ domain.EventPublisher = (*WebhookService)(nil)
+
+// webhookPayload is the JSON body POSTed for each event.
+type webhookPayload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// NewWebhookService creates a WebhookService storing webhooks in repo and
+// POSTing deliveries with sender.
+func NewWebhookService(repo domain.WebhookRepository, sender domain.WebhookSender) *WebhookService {
+	return &WebhookService{repo: repo, sender: sender, now: time.Now}
+}
+
+// WithHydration enables the goal.reached event, judged against the
+// user's goal for today.
+func (s *WebhookService) WithHydration(hs *HydrationService) *WebhookService {
+	s.hydration = hs
+	return s
+}
+
+// List returns the user's webhooks, oldest first, without their secrets.
+func (s *WebhookService) List(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	hooks, err := s.repo.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// Create validates and stores a new webhook for h.UserID with a fresh
+// signing secret. The returned webhook is the only one to carry the secret.
+func (s *WebhookService) Create(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(&h); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListWebhooks(ctx, h.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= domain.MaxWebhooksPerUser {
+		return nil, fmt.Errorf("at most %d webhooks per user", domain.MaxWebhooksPerUser)
+	}
+	if h.Secret, err = generateToken(); err != nil {
+		return nil, err
+	}
+	h.CreatedAt = s.now().UTC()
+	return s.repo.CreateWebhook(ctx, h)
+}
+
+// Update validates and replaces the URL, events and enabled flag of an
+// existing webhook. Its secret is kept.
+func (s *WebhookService) Update(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(&h); err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.UpdateWebhook(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	updated, err := s.repo.GetWebhook(ctx, h.UserID, h.ID)
+	if err != nil || updated == nil {
+		return nil, err
+	}
+	updated.Secret = ""
+	return updated, nil
+}
+
+// Delete removes the user's webhook and its delivery log.
+func (s *WebhookService) Delete(ctx context.Context, userID, id int64) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteWebhook(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries to the user's webhook, newest
+// first, up to limit.
+func (s *WebhookService) Deliveries(ctx context.Context, userID, id int64, limit int) ([]domain.WebhookDelivery, error) {
+	hook, err := s.repo.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return s.repo.ListWebhookDeliveries(ctx, userID, id, limit)
+}
+
+// validateWebhook checks h's URL and events, sorting the events and
+// dropping repeats.
+func validateWebhook(h *domain.Webhook) error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an http(s) URL")
+	}
+	if len(h.URL) > maxWebhookURLLength {
+		return fmt.Errorf("url must be at most %d characters", maxWebhookURLLength)
+	}
+	if len(h.Events) == 0 {
+		return errors.New("events must not be empty")
+	}
+	for _, e := range h.Events {
+		if !slices.Contains(domain.WebhookEvents, e) {
+			return fmt.Errorf("events must be among %q", domain.WebhookEvents)
+		}
+	}
+	slices.Sort(h.Events)
+	h.Events = slices.Compact(h.Events)
+	return nil
+}
+
+// Publish queues e for the user's webhooks subscribed to it, and a
+// goal.reached event when e brings today's water up to the goal. Like
+// other publishers it is best effort: failures are logged, not returned.
+func (s *WebhookService) Publish(ctx context.Context, e domain.MetricEvent) {
+	hooks, err := s.repo.ListWebhooks(ctx, e.UserID)
+	if err != nil {
+		log.Printf("webhooks: list for user %d: %v", e.UserID, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	ds := s.deliveries(hooks, e.Type, e)
+	if goal := s.goalReached(ctx, hooks, e); goal != nil {
+		ds = append(ds, s.deliveries(hooks, domain.WebhookEventGoalReached, goal)...)
+	}
+	if len(ds) == 0 {
+		return
+	}
+	if err := s.repo.EnqueueWebhookDeliveries(ctx, ds...); err != nil {
+		log.Printf("webhooks: queue %s for user %d: %v", e.Type, e.UserID, err)
+	}
+}
+
+// deliveries builds a delivery of event with data for each of hooks
+// subscribed to it.
+func (s *WebhookService) deliveries(hooks []domain.Webhook, event string, data any) []domain.WebhookDelivery {
+	var out []domain.WebhookDelivery
+	var payload []byte
+	for _, h := range hooks {
+		if !h.Subscribes(event) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			payload, err = json.Marshal(webhookPayload{Event: event, CreatedAt: s.now().UTC(), Data: data})
+			if err != nil {
+				log.Printf("webhooks: encode %s: %v", event, err)
+				return nil
+			}
+		}
+		out = append(out, domain.WebhookDelivery{WebhookID: h.ID, UserID: h.UserID, Event: event, Payload: payload})
+	}
+	return out
+}
+
+// goalReached returns the goal.reached data when e is a water event that
+// takes today's total from below the goal to at least it, and a webhook
+// listens for it.
+func (s *WebhookService) goalReached(ctx context.Context, hooks []domain.Webhook, e domain.MetricEvent) *domain.GoalReached {
+	if s.hydration == nil || e.Type != domain.EventWaterRecorded || e.Value <= 0 ||
+		e.Day != s.now().In(time.Local).Format("2006-01-02") ||
+		!slices.ContainsFunc(hooks, func(h domain.Webhook) bool { return h.Subscribes(domain.WebhookEventGoalReached) }) {
+		return nil
+	}
+	goal, err := s.hydration.TodayGoal(ctx, e.UserID)
+	if err != nil {
+		log.Printf("webhooks: goal for user %d: %v", e.UserID, err)
+		return nil
+	}
+	if e.DayTotal < goal.Liters || e.DayTotal-e.Value >= goal.Liters {
+		return nil
+	}
+	return &domain.GoalReached{Goal: "water", Day: e.Day, TotalLiters: e.DayTotal, GoalLiters: goal.Liters}
+}
+
+// Run drains the queued deliveries every interval until ctx is done.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Drain(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain sends every delivery due now, prunes the log, and returns how many
+// were delivered. Failed deliveries are rescheduled, not returned as
+// errors.
+func (s *WebhookService) Drain(ctx context.Context) (delivered int, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.Drain")
+	defer func() { endSpan(span, err) }()
+
+	for {
+		now := s.now()
+		ds, err := s.repo.ClaimWebhookDeliveries(ctx, now, now.Add(outboxLease), outboxBatch)
+		if err != nil {
+			return delivered, err
+		}
+		for _, d := range ds {
+			ok, err := s.deliver(ctx, d)
+			if err != nil {
+				return delivered, err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if len(ds) < outboxBatch {
+			break
+		}
+	}
+	_, err = s.repo.PruneWebhookDeliveries(ctx, s.now().Add(-webhookRetention))
+	return delivered, err
+}
+
+// deliver POSTs d to its webhook, recording the outcome. It reports
+// whether d was delivered.
+func (s *WebhookService) deliver(ctx context.Context, d domain.WebhookDelivery) (bool, error) {
+	hook, err := s.repo.GetWebhook(ctx, d.UserID, d.WebhookID)
+	if err != nil {
+		return false, err
+	}
+	if hook == nil || !hook.Enabled {
+		return false, s.repo.FailWebhookDelivery(ctx, d.ID, 0, "webhook is disabled", nil)
+	}
+
+	status, sendErr := s.sender.Send(ctx, hook.URL, hook.Secret, d)
+	if sendErr == nil {
+		return true, s.repo.CompleteWebhookDelivery(ctx, d.ID, status, s.now())
+	}
+	attempts := d.Attempts + 1
+	if attempts >= outboxMaxAttempts {
+		log.Printf("webhooks: giving up on delivery %d to webhook %d after %d attempts: %v", d.ID, d.WebhookID, attempts, sendErr)
+		return false, s.repo.FailWebhookDelivery(ctx, d.ID, status, sendErr.Error(), nil)
+	}
+	next := s.now().Add(outboxBackoff(attempts))
+	return false, s.repo.FailWebhookDelivery(ctx, d.ID, status, sendErr.Error(), &next)
+}
@@ -0,0 +1,235 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/app"
+	"vitals/internal/domain"
+)
+
+type mockWebhookRepo struct {
+	hooks      []domain.Webhook
+	deliveries []domain.WebhookDelivery
+	nextID     int64
+}
+
+func (m *mockWebhookRepo) CreateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	m.nextID++
+	h.ID = m.nextID
+	m.hooks = append(m.hooks, h)
+	return &h, nil
+}
+
+func (m *mockWebhookRepo) GetWebhook(ctx context.Context, userID, id int64) (*domain.Webhook, error) {
+	for _, h := range m.hooks {
+		if h.UserID == userID && h.ID == id {
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookRepo) ListWebhooks(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	var out []domain.Webhook
+	for _, h := range m.hooks {
+		if h.UserID == userID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepo) UpdateWebhook(ctx context.Context, h domain.Webhook) (bool, error) {
+	for i := range m.hooks {
+		if m.hooks[i].UserID == h.UserID && m.hooks[i].ID == h.ID {
+			h.Secret, h.CreatedAt = m.hooks[i].Secret, m.hooks[i].CreatedAt
+			m.hooks[i] = h
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockWebhookRepo) DeleteWebhook(ctx context.Context, userID, id int64) (bool, error) {
+	n := len(m.hooks)
+	m.hooks = slices.DeleteFunc(m.hooks, func(h domain.Webhook) bool { return h.UserID == userID && h.ID == id })
+	return len(m.hooks) < n, nil
+}
+
+func (m *mockWebhookRepo) EnqueueWebhookDeliveries(ctx context.Context, ds ...domain.WebhookDelivery) error {
+	for _, d := range ds {
+		m.nextID++
+		d.ID = m.nextID
+		d.CreatedAt = time.Now()
+		due := time.Time{}
+		d.NextAttemptAt = &due
+		m.deliveries = append(m.deliveries, d)
+	}
+	return nil
+}
+
+func (m *mockWebhookRepo) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var out []domain.WebhookDelivery
+	for i, d := range m.deliveries {
+		if len(out) < limit && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			m.deliveries[i].NextAttemptAt = &until
+			out = append(out, m.deliveries[i])
+		}
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepo) CompleteWebhookDelivery(ctx context.Context, id int64, status int, at time.Time) error {
+	for i := range m.deliveries {
+		if d := &m.deliveries[i]; d.ID == id {
+			d.Attempts++
+			d.StatusCode, d.LastError, d.DeliveredAt, d.NextAttemptAt = status, "", &at, nil
+		}
+	}
+	return nil
+}
+
+func (m *mockWebhookRepo) FailWebhookDelivery(ctx context.Context, id int64, status int, lastErr string, next *time.Time) error {
+	for i := range m.deliveries {
+		if d := &m.deliveries[i]; d.ID == id {
+			d.Attempts++
+			d.StatusCode, d.LastError, d.NextAttemptAt = status, lastErr, next
+		}
+	}
+	return nil
+}
+
+func (m *mockWebhookRepo) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]domain.WebhookDelivery, error) {
+	var out []domain.WebhookDelivery
+	for _, d := range slices.Backward(m.deliveries) {
+		if d.UserID == userID && d.WebhookID == webhookID && len(out) < limit {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepo) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// mockWebhookSender records what it sends and answers with status.
+type mockWebhookSender struct {
+	status int
+	sent   []domain.WebhookDelivery
+}
+
+func (m *mockWebhookSender) Send(ctx context.Context, url, secret string, d domain.WebhookDelivery) (int, error) {
+	if m.status != http.StatusOK {
+		return m.status, errors.New("unexpected status")
+	}
+	m.sent = append(m.sent, d)
+	return m.status, nil
+}
+
+func TestWebhookService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	svc := app.NewWebhookService(&mockWebhookRepo{}, &mockWebhookSender{})
+
+	for _, h := range []domain.Webhook{
+		{UserID: 1, URL: "ftp://example.com", Events: []string{domain.WebhookEventWeightRecorded}},
+		{UserID: 1, URL: "https://example.com/hook"},
+		{UserID: 1, URL: "https://example.com/hook", Events: []string{"weight.deleted"}},
+	} {
+		if _, err := svc.Create(ctx, h); err == nil {
+			t.Errorf("expected %+v to be rejected", h)
+		}
+	}
+
+	hook, err := svc.Create(ctx, domain.Webhook{UserID: 1, URL: "https://example.com/hook", Enabled: true,
+		Events: []string{domain.WebhookEventWaterRecorded, domain.WebhookEventWeightRecorded, domain.WebhookEventWaterRecorded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Secret == "" || len(hook.Events) != 2 {
+		t.Errorf("expected a secret and deduplicated events, got %+v", hook)
+	}
+	hooks, _ := svc.List(ctx, 1)
+	if len(hooks) != 1 || hooks[0].Secret != "" {
+		t.Errorf("expected the secret left out of the list, got %+v", hooks)
+	}
+
+	hook.Enabled = false
+	if updated, err := svc.Update(ctx, *hook); err != nil || updated.Enabled || updated.Secret != "" {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+	if _, err := svc.Update(ctx, domain.Webhook{ID: hook.ID, UserID: 2, URL: hook.URL, Events: hook.Events}); !errors.Is(err, app.ErrWebhookNotFound) {
+		t.Errorf("expected another user's webhook not found, got %v", err)
+	}
+	if err := svc.Delete(ctx, 1, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Deliveries(ctx, 1, hook.ID, 10); !errors.Is(err, app.ErrWebhookNotFound) {
+		t.Errorf("expected the deleted webhook not found, got %v", err)
+	}
+}
+
+func TestWebhookService_PublishAndDrain(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockWebhookRepo{}
+	sender := &mockWebhookSender{status: http.StatusServiceUnavailable}
+	hydration := app.NewHydrationService(&mockHydrationRepo{settings: map[int64]domain.HydrationSettings{
+		1: {UserID: 1, BaseGoalLiters: 2},
+	}})
+	svc := app.NewWebhookService(repo, sender).WithHydration(hydration)
+	water, _ := svc.Create(ctx, domain.Webhook{UserID: 1, URL: "https://example.com/water", Enabled: true,
+		Events: []string{domain.WebhookEventWaterRecorded, domain.WebhookEventGoalReached}})
+	_, _ = svc.Create(ctx, domain.Webhook{UserID: 1, URL: "https://example.com/weight", Enabled: true,
+		Events: []string{domain.WebhookEventWeightRecorded}})
+
+	today := time.Now().Format("2006-01-02")
+	svc.Publish(ctx, domain.MetricEvent{Type: domain.EventWaterRecorded, UserID: 1, Day: today, Value: 0.5, DayTotal: 1.5})
+	if len(repo.deliveries) != 1 || repo.deliveries[0].WebhookID != water.ID {
+		t.Fatalf("expected one water delivery, got %+v", repo.deliveries)
+	}
+	// Crossing the goal adds goal.reached; topping up past it does not.
+	svc.Publish(ctx, domain.MetricEvent{Type: domain.EventWaterRecorded, UserID: 1, Day: today, Value: 0.5, DayTotal: 2})
+	svc.Publish(ctx, domain.MetricEvent{Type: domain.EventWaterRecorded, UserID: 1, Day: today, Value: 0.5, DayTotal: 2.5})
+	events := []string{}
+	for _, d := range repo.deliveries {
+		events = append(events, d.Event)
+	}
+	if !slices.Equal(events, []string{"water.recorded", "water.recorded", "goal.reached", "water.recorded"}) {
+		t.Fatalf("unexpected deliveries %v", events)
+	}
+	var payload struct {
+		Event string             `json:"event"`
+		Data  domain.GoalReached `json:"data"`
+	}
+	if err := json.Unmarshal(repo.deliveries[2].Payload, &payload); err != nil || payload.Event != "goal.reached" || payload.Data.GoalLiters != 2 {
+		t.Errorf("unexpected goal.reached payload %s", repo.deliveries[2].Payload)
+	}
+
+	// Failures are logged on the delivery and retried later.
+	if n, err := svc.Drain(ctx); err != nil || n != 0 {
+		t.Fatalf("Drain = %d, %v", n, err)
+	}
+	d := repo.deliveries[0]
+	if d.Attempts != 1 || d.StatusCode != http.StatusServiceUnavailable || d.NextAttemptAt == nil || time.Until(*d.NextAttemptAt) < 20*time.Second {
+		t.Fatalf("expected a retry scheduled, got %+v", d)
+	}
+
+	sender.status = http.StatusOK
+	past := time.Now().Add(-time.Second)
+	for i := range repo.deliveries {
+		repo.deliveries[i].NextAttemptAt = &past
+	}
+	if n, err := svc.Drain(ctx); err != nil || n != 4 {
+		t.Fatalf("Drain = %d, %v", n, err)
+	}
+	log, _ := svc.Deliveries(ctx, 1, water.ID, 10)
+	if len(log) != 4 || log[0].DeliveredAt == nil || log[0].Attempts != 2 || log[0].StatusCode != http.StatusOK {
+		t.Errorf("expected the delivery log to show the deliveries, got %+v", log)
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 // WeightService encapsulates weight-tracking use cases.
 type WeightService struct {
-	repo domain.WeightRepository
+	repo      domain.WeightRepository
+	publisher domain.EventPublisher
+	tags      domain.TagRepository
+	settings  domain.SettingsRepository
 }
 
 // NewWeightService creates a WeightService backed by the given repository.
@@ -18,38 +21,219 @@ func NewWeightService(repo domain.WeightRepository) *WeightService {
 	return &WeightService{repo: repo}
 }
 
+// WithPublisher broadcasts every recorded weight to p.
+func (s *WeightService) WithPublisher(p domain.EventPublisher) *WeightService {
+	s.publisher = p
+	return s
+}
+
+// WithTags attaches tags to listed entries and enables tag filters.
+func (s *WeightService) WithTags(repo domain.TagRepository) *WeightService {
+	s.tags = repo
+	return s
+}
+
+// WithSettings lists entries in each user's units.weight setting by
+// default.
+func (s *WeightService) WithSettings(repo domain.SettingsRepository) *WeightService {
+	s.settings = repo
+	return s
+}
+
 // GetTodayWeight returns the latest weight entry for the given local day.
 func (s *WeightService) GetTodayWeight(ctx context.Context, userID int64, today string) (*domain.WeightEntry, error) {
 	return s.repo.LatestWeightForLocalDay(ctx, userID, today)
 }
 
+// weightTrendWindows are the windows, in days, reported by Trends.
+var weightTrendWindows = []int{7, 30}
+
+// Trends returns the weight change and direction over the last 7 and 30
+// days before today, each window starting at local midnight so a weigh-in
+// on the same weekday last week is included.
+func (s *WeightService) Trends(ctx context.Context, userID int64, today string) ([]domain.WeightTrend, error) {
+	day, err := time.ParseInLocation("2006-01-02", today, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	trends := make([]domain.WeightTrend, 0, len(weightTrendWindows))
+	for _, days := range weightTrendWindows {
+		span, err := s.repo.WeightSpanSince(ctx, userID, day.AddDate(0, 0, -days))
+		if err != nil {
+			return nil, err
+		}
+		trends = append(trends, domain.NewWeightTrend(days, span))
+	}
+	return trends, nil
+}
+
 // RecordWeight validates and stores a new weight measurement, returning the
 // latest entry for today after the insert.
 func (s *WeightService) RecordWeight(ctx context.Context, userID int64, value float64, unit string) (*domain.WeightEntry, string, error) {
-	if value <= 0 {
-		return nil, "", errors.New("value must be > 0")
+	if err := checkWritable(ctx); err != nil {
+		return nil, "", err
 	}
-	if unit != "kg" && unit != "lb" {
-		return nil, "", errors.New("unit must be \"kg\" or \"lb\"")
+	if err := validateWeight(value, unit); err != nil {
+		return nil, "", err
 	}
 	now := time.Now()
 	today := now.In(time.Local).Format("2006-01-02")
 	if _, err := s.repo.AddWeightEvent(ctx, userID, value, unit, now); err != nil {
 		return nil, today, err
 	}
+	s.publish(ctx, userID, value, unit, now)
 	entry, err := s.repo.LatestWeightForLocalDay(ctx, userID, today)
 	return entry, today, err
 }
 
-// ListRecent returns the most recent weight events up to limit.
-func (s *WeightService) ListRecent(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error) {
-	return s.repo.ListRecentWeightEvents(ctx, userID, limit)
+// RecordWeightWithClientID stores a weight measurement tagged with a
+// client-generated UUID, as queued by an offline client. Retrying with the
+// same client ID does not create a duplicate; the stored entry is returned
+// either way along with whether this call created it. A zero measuredAt
+// means now.
+func (s *WeightService) RecordWeightWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, measuredAt time.Time) (_ *domain.WeightEntry, _ bool, err error) {
+	ctx, span := startSpan(ctx, "WeightService.RecordWeight", userAttr(userID))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkWritable(ctx); err != nil {
+		return nil, false, err
+	}
+	clientID, err = validateClientWrite(clientID, &measuredAt)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validateWeight(value, unit); err != nil {
+		return nil, false, err
+	}
+	entry, created, err := s.repo.AddWeightEventWithClientID(ctx, userID, clientID, value, unit, measuredAt)
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		s.publish(ctx, userID, value, unit, measuredAt)
+	}
+	return entry, created, nil
+}
+
+func (s *WeightService) publish(ctx context.Context, userID int64, value float64, unit string, at time.Time) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Publish(ctx, domain.MetricEvent{
+		Type: domain.EventWeightRecorded, UserID: userID, Day: at.In(time.Local).Format("2006-01-02"),
+		Value: value, Unit: unit, At: at,
+	})
+}
+
+func validateWeight(value float64, unit string) error {
+	if value <= 0 {
+		return domain.Errorf(domain.ErrValueOutOfRange, "value must be > 0")
+	}
+	if unit != "kg" && unit != "lb" {
+		return domain.Errorf(domain.ErrInvalidUnit, "unit must be \"kg\" or \"lb\"")
+	}
+	return nil
+}
+
+// Export calls fn with the user's weight events selected by f, oldest
+// first, in their recorded unit, for a download.
+func (s *WeightService) Export(ctx context.Context, userID int64, f domain.ExportFilter, fn func(domain.WeightEntry) error) error {
+	if !f.Includes(domain.ExportMetricWeight) {
+		return nil
+	}
+	return exportRange(f, func(e domain.WeightEntry) time.Time { return e.CreatedAt }, fn, func(fn func(domain.WeightEntry) error) error {
+		return s.repo.EachWeightEvent(ctx, userID, fn)
+	})
+}
+
+// errExportDone stops an oldest-first export past the filter's last day.
+var errExportDone = errors.New("export done")
+
+// exportRange passes the events each yields, oldest first, to fn when at
+// puts them within f's days, and stops once past them.
+func exportRange[T any](f domain.ExportFilter, at func(T) time.Time, fn func(T) error, each func(func(T) error) error) error {
+	err := each(func(e T) error {
+		switch t := at(e); {
+		case f.Before(t):
+			return nil
+		case f.After(t):
+			return errExportDone
+		}
+		return fn(e)
+	})
+	if errors.Is(err, errExportDone) {
+		return nil
+	}
+	return err
+}
+
+// ListRecent returns the most recent weight events up to limit, with their
+// tags, and a summary of all the user's weight events. With a non-empty
+// filter, the latest tagScanLimit events are scanned for up to limit
+// matches and there is no summary. Each entry keeps its recorded value and
+// unit and also carries it converted to unit, which when empty falls back
+// to the user's units.weight setting and then to the unit of the latest
+// entry.
+func (s *WeightService) ListRecent(ctx context.Context, userID int64, limit int, f domain.TagFilter, unit string) ([]domain.WeightEntry, *domain.ListSummary, error) {
+	if unit != "" && unit != "kg" && unit != "lb" {
+		return nil, nil, domain.Errorf(domain.ErrInvalidUnit, "unit must be \"kg\" or \"lb\"")
+	}
+	items, sum, err := s.listRecent(ctx, userID, limit, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if unit == "" {
+		if unit, err = preferredWeightUnit(ctx, s.settings, userID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if unit == "" && len(items) > 0 {
+		unit = items[0].Unit
+	}
+	for i := range items {
+		items[i].DisplayValue = domain.ConvertWeight(items[i].Value, items[i].Unit, unit)
+		items[i].DisplayUnit = unit
+	}
+	return items, sum, nil
+}
+
+func (s *WeightService) listRecent(ctx context.Context, userID int64, limit int, f domain.TagFilter) ([]domain.WeightEntry, *domain.ListSummary, error) {
+	if f.IsZero() {
+		items, sum, err := s.repo.ListRecentWeightPage(ctx, userID, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s.tags != nil {
+			if items, err = tagWeights(ctx, s.tags, userID, items, f); err != nil {
+				return nil, nil, err
+			}
+		}
+		return items, &sum, nil
+	}
+	if s.tags == nil {
+		return nil, nil, errTagsDisabled
+	}
+	items, err := s.repo.ListRecentWeightEvents(ctx, userID, tagScanLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err = tagWeights(ctx, s.tags, userID, items, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil, nil
 }
 
 // UndoLast deletes the most recent weight event and returns the new latest
 // entry for today.
 func (s *WeightService) UndoLast(ctx context.Context, userID int64) (bool, *domain.WeightEntry, string, error) {
 	today := time.Now().In(time.Local).Format("2006-01-02")
+	if err := checkWritable(ctx); err != nil {
+		return false, nil, today, err
+	}
 	deleted, err := s.repo.DeleteLatestWeightEvent(ctx, userID)
 	if err != nil {
 		return false, nil, today, err
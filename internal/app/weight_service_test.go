@@ -2,7 +2,9 @@ package app_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"math"
 	"testing"
 	"time"
 
@@ -11,10 +13,14 @@ import (
 )
 
 type mockWeightRepo struct {
-	addFn    func(ctx context.Context, userID int64, v float64, u string, t time.Time) (int64, error)
-	deleteFn func(ctx context.Context, userID int64) (bool, error)
-	latestFn func(ctx context.Context, userID int64, day string) (*domain.WeightEntry, error)
-	listFn   func(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error)
+	addClientFn func(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error)
+	addFn       func(ctx context.Context, userID int64, v float64, u string, t time.Time) (int64, error)
+	deleteFn    func(ctx context.Context, userID int64) (bool, error)
+	latestFn    func(ctx context.Context, userID int64, day string) (*domain.WeightEntry, error)
+	averageFn   func(ctx context.Context, userID int64, day string) (*domain.WeightEntry, error)
+	listFn      func(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error)
+	spanFn      func(ctx context.Context, userID int64, since time.Time) (*domain.WeightSpan, error)
+	eachFn      func(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error
 }
 
 func (m *mockWeightRepo) AddWeightEvent(ctx context.Context, userID int64, v float64, u string, t time.Time) (int64, error) {
@@ -24,6 +30,13 @@ func (m *mockWeightRepo) AddWeightEvent(ctx context.Context, userID int64, v flo
 	return 0, nil
 }
 
+func (m *mockWeightRepo) AddWeightEventWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*domain.WeightEntry, bool, error) {
+	if m.addClientFn != nil {
+		return m.addClientFn(ctx, userID, clientID, value, unit, createdAt)
+	}
+	return &domain.WeightEntry{ID: 1, UserID: userID, Value: value, Unit: unit, ClientID: clientID, CreatedAt: createdAt}, true, nil
+}
+
 func (m *mockWeightRepo) DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error) {
 	if m.deleteFn != nil {
 		return m.deleteFn(ctx, userID)
@@ -38,6 +51,20 @@ func (m *mockWeightRepo) LatestWeightForLocalDay(ctx context.Context, userID int
 	return nil, nil
 }
 
+func (m *mockWeightRepo) AverageWeightForLocalDay(ctx context.Context, userID int64, day string) (*domain.WeightEntry, error) {
+	if m.averageFn != nil {
+		return m.averageFn(ctx, userID, day)
+	}
+	return nil, nil
+}
+
+func (m *mockWeightRepo) WeightSpanSince(ctx context.Context, userID int64, since time.Time) (*domain.WeightSpan, error) {
+	if m.spanFn != nil {
+		return m.spanFn(ctx, userID, since)
+	}
+	return nil, nil
+}
+
 func (m *mockWeightRepo) ListRecentWeightEvents(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, error) {
 	if m.listFn != nil {
 		return m.listFn(ctx, userID, limit)
@@ -45,6 +72,18 @@ func (m *mockWeightRepo) ListRecentWeightEvents(ctx context.Context, userID int6
 	return nil, nil
 }
 
+func (m *mockWeightRepo) ListRecentWeightPage(ctx context.Context, userID int64, limit int) ([]domain.WeightEntry, domain.ListSummary, error) {
+	items, err := m.ListRecentWeightEvents(ctx, userID, limit)
+	return items, domain.ListSummary{Total: len(items)}, err
+}
+
+func (m *mockWeightRepo) EachWeightEvent(ctx context.Context, userID int64, fn func(domain.WeightEntry) error) error {
+	if m.eachFn != nil {
+		return m.eachFn(ctx, userID, fn)
+	}
+	return nil
+}
+
 func TestRecordWeight_Validation(t *testing.T) {
 	svc := app.NewWeightService(&mockWeightRepo{})
 
@@ -52,16 +91,17 @@ func TestRecordWeight_Validation(t *testing.T) {
 		name  string
 		value float64
 		unit  string
+		kind  error
 	}{
-		{"zero value", 0, "kg"},
-		{"negative value", -5, "kg"},
-		{"bad unit", 80, "stones"},
+		{"zero value", 0, "kg", domain.ErrValueOutOfRange},
+		{"negative value", -5, "kg", domain.ErrValueOutOfRange},
+		{"bad unit", 80, "stones", domain.ErrInvalidUnit},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			_, _, err := svc.RecordWeight(context.Background(), 1, tc.value, tc.unit)
-			if err == nil {
-				t.Fatal("expected validation error")
+			if !errors.Is(err, tc.kind) {
+				t.Fatalf("expected %v, got %v", tc.kind, err)
 			}
 		})
 	}
@@ -123,6 +163,36 @@ func TestGetTodayWeight(t *testing.T) {
 	}
 }
 
+func TestWeightTrends(t *testing.T) {
+	var since []string
+	repo := &mockWeightRepo{
+		spanFn: func(_ context.Context, _ int64, t time.Time) (*domain.WeightSpan, error) {
+			since = append(since, t.Format("2006-01-02"))
+			if len(since) == 1 {
+				return &domain.WeightSpan{Count: 3, FirstKg: 81, LastKg: 80}, nil
+			}
+			return &domain.WeightSpan{Count: 1, FirstKg: 80, LastKg: 80}, nil
+		},
+	}
+	svc := app.NewWeightService(repo)
+	trends, err := svc.Trends(context.Background(), 1, "2026-01-31")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(since) != 2 || since[0] != "2026-01-24" || since[1] != "2026-01-01" {
+		t.Errorf("unexpected windows: %v", since)
+	}
+	if len(trends) != 2 || trends[0].Days != 7 || trends[1].Days != 30 {
+		t.Fatalf("unexpected trends: %+v", trends)
+	}
+	if trends[0].ChangeKg == nil || *trends[0].ChangeKg != -1 || trends[0].Direction != domain.TrendDown {
+		t.Errorf("7-day trend = %+v", trends[0])
+	}
+	if trends[1].ChangeKg != nil {
+		t.Errorf("expected no 30-day change from a single weigh-in, got %+v", trends[1])
+	}
+}
+
 func TestUndoLastWeight(t *testing.T) {
 	repo := &mockWeightRepo{
 		deleteFn: func(_ context.Context, _ int64) (bool, error) { return true, nil },
@@ -145,8 +215,73 @@ func TestListRecentWeight_Error(t *testing.T) {
 		},
 	}
 	svc := app.NewWeightService(repo)
-	_, err := svc.ListRecent(context.Background(), 1, 10)
+	_, _, err := svc.ListRecent(context.Background(), 1, 10, domain.TagFilter{}, "")
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
+
+func TestListRecentWeight_DisplayUnit(t *testing.T) {
+	repo := &mockWeightRepo{
+		listFn: func(_ context.Context, _ int64, _ int) ([]domain.WeightEntry, error) {
+			return []domain.WeightEntry{{Value: 170, Unit: "lb"}, {Value: 80, Unit: "kg"}}, nil
+		},
+	}
+	ctx := context.Background()
+
+	items, sum, err := app.NewWeightService(repo).ListRecent(ctx, 1, 10, domain.TagFilter{}, "")
+	if err != nil || sum == nil || sum.Total != 2 || items[0].DisplayUnit != "lb" || items[0].DisplayValue != 170 || math.Abs(items[1].DisplayValue-176.37) > 0.01 {
+		t.Errorf("expected entries shown in the latest entry's unit, got %+v, %v", items, err)
+	}
+
+	settings := &mockSettingsRepo{settings: map[int64]domain.UserSettings{1: {"units.weight": json.RawMessage(`"kg"`)}}}
+	svc := app.NewWeightService(repo).WithSettings(settings)
+	items, _, _ = svc.ListRecent(ctx, 1, 10, domain.TagFilter{}, "")
+	if items[0].DisplayUnit != "kg" || math.Abs(items[0].DisplayValue-77.11) > 0.01 || items[0].Value != 170 || items[0].Unit != "lb" {
+		t.Errorf("expected the preferred unit with the original kept, got %+v", items[0])
+	}
+	items, _, _ = svc.ListRecent(ctx, 1, 10, domain.TagFilter{}, "lb")
+	if items[1].DisplayUnit != "lb" {
+		t.Errorf("expected the requested unit to override the setting, got %+v", items[1])
+	}
+	if _, _, err := svc.ListRecent(ctx, 1, 10, domain.TagFilter{}, "stone"); !errors.Is(err, domain.ErrInvalidUnit) {
+		t.Error("expected error for an unknown unit")
+	}
+}
+
+func TestWeightService_ReadOnly(t *testing.T) {
+	repo := &mockWeightRepo{
+		addFn: func(_ context.Context, _ int64, _ float64, _ string, _ time.Time) (int64, error) {
+			t.Fatal("write reached the repository")
+			return 0, nil
+		},
+		deleteFn: func(_ context.Context, _ int64) (bool, error) {
+			t.Fatal("delete reached the repository")
+			return false, nil
+		},
+	}
+	svc := app.NewWeightService(repo)
+	ctx := app.WithReadOnly(context.Background())
+
+	if _, _, err := svc.RecordWeight(ctx, 1, 80, "kg"); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if _, _, _, err := svc.UndoLast(ctx, 1); !errors.Is(err, app.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestWeightService_PublishesEvent(t *testing.T) {
+	pub := &recordingPublisher{}
+	svc := app.NewWeightService(&mockWeightRepo{}).WithPublisher(pub)
+
+	if _, _, err := svc.RecordWeight(context.Background(), 3, 81.2, "kg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(pub.events))
+	}
+	if e := pub.events[0]; e.Type != domain.EventWeightRecorded || e.Value != 81.2 || e.Unit != "kg" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
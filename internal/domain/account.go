@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Account limits.
+const (
+	MaxUsernameLength = 64
+	// EmailVerificationTTL bounds how long an email verification link works.
+	EmailVerificationTTL = 24 * time.Hour
+)
+
+// ForwardAuthIssuer is the LinkedIdentity issuer for users signed in by a
+// forward-auth proxy; the subject is the proxy's Remote-User.
+const ForwardAuthIssuer = "forward-auth"
+
+// ValidateUsername trims username and checks its length and characters.
+func ValidateUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > MaxUsernameLength {
+		return "", fmt.Errorf("username must be between 1 and %d characters", MaxUsernameLength)
+	}
+	if strings.ContainsAny(username, " \t\r\n/") {
+		return "", errors.New("username must not contain spaces or slashes")
+	}
+	return username, nil
+}
+
+// NormalizeEmail parses a bare address such as "me@example.com" and returns
+// it lower-cased, so lookups and uniqueness ignore case.
+func NormalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" || addr.Address != strings.TrimSpace(email) {
+		return "", errors.New("email must be an address such as name@example.com")
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+// EmailChange is a requested email address awaiting verification. The
+// user's current address stays in place until the link sent to the new one
+// is followed.
+type EmailChange struct {
+	UserID    int64
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+// AccountRepository is the port for changing a user's account details.
+type AccountRepository interface {
+	// GetByEmail returns the user whose verified email is email, or nil.
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	UpdateUsername(ctx context.Context, userID int64, username string) error
+	// SetEmail stores email as the user's verified address.
+	SetEmail(ctx context.Context, userID int64, email string) error
+	// SetRole sets the user's role to RoleUser or RoleAdmin.
+	SetRole(ctx context.Context, userID int64, role string) error
+	// ClaimAdmin makes the user an admin if no user is one yet, and
+	// reports whether it did. Concurrent claims promote exactly one user.
+	ClaimAdmin(ctx context.Context, userID int64) (bool, error)
+	// SaveEmailChange stores c, replacing the user's pending change.
+	SaveEmailChange(ctx context.Context, c EmailChange) error
+	// GetEmailChange returns the user's pending change, or nil.
+	GetEmailChange(ctx context.Context, userID int64) (*EmailChange, error)
+	// TakeEmailChange removes and returns the change with tokenHash, or nil.
+	TakeEmailChange(ctx context.Context, tokenHash string) (*EmailChange, error)
+}
+
+// Mailer is the port for sending transactional email.
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
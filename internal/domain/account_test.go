@@ -0,0 +1,29 @@
+package domain_test
+
+import (
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestValidateUsername(t *testing.T) {
+	if got, err := domain.ValidateUsername("  sam "); err != nil || got != "sam" {
+		t.Errorf("expected trimmed sam, got %q, %v", got, err)
+	}
+	for _, bad := range []string{"", "   ", "sam smith", "a/b", string(make([]byte, domain.MaxUsernameLength+1))} {
+		if _, err := domain.ValidateUsername(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got, err := domain.NormalizeEmail(" Sam@Example.COM "); err != nil || got != "sam@example.com" {
+		t.Errorf("expected sam@example.com, got %q, %v", got, err)
+	}
+	for _, bad := range []string{"", "sam", "Sam <sam@example.com>", "a@b.com, c@d.com"} {
+		if _, err := domain.NormalizeEmail(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Types of activity items.
+const (
+	ActivityWeight     = "weight"
+	ActivityWater      = "water"
+	ActivityWaterGoal  = "goal.water"
+	ActivityWeightGoal = "goal.weight"
+	ActivityImport     = "import"
+)
+
+// Activity is one item of a user's activity timeline: an entry they
+// recorded, a goal they set, or an import. Type says which, and the
+// matching field holds it. Goal changes take effect from a day, so they are
+// placed at the start of that day (UTC). Imported entries appear only
+// through their import, which is placed when it ran.
+type Activity struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	// ID identifies the item within its type: the entry's ID, the goal's
+	// day, or the import batch.
+	ID         string            `json:"id"`
+	Weight     *WeightEntry      `json:"weight,omitempty"`
+	Water      *WaterEvent       `json:"water,omitempty"`
+	WaterGoal  *GoalChange       `json:"waterGoal,omitempty"`
+	WeightGoal *WeightGoalChange `json:"weightGoal,omitempty"`
+	Import     *ImportActivity   `json:"import,omitempty"`
+}
+
+// ImportActivity is an import batch and how many of its entries remain.
+type ImportActivity struct {
+	Batch   string `json:"batch"`
+	Weights int    `json:"weights"`
+	Waters  int    `json:"waters"`
+}
+
+// ActivityCursor is the position of an item in the timeline, which is
+// ordered by At, then Type, then ID, all descending.
+type ActivityCursor struct {
+	At   time.Time `json:"at"`
+	Type string    `json:"type"`
+	ID   string    `json:"id"`
+}
+
+// Admits reports whether a comes after c in the timeline, that is, belongs
+// on the page that starts at c.
+func (c ActivityCursor) Admits(a Activity) bool {
+	if !a.At.Equal(c.At) {
+		return a.At.Before(c.At)
+	}
+	if a.Type != c.Type {
+		return a.Type < c.Type
+	}
+	return a.ID < c.ID
+}
+
+// ActivityRepository is the port for reading a user's activity timeline.
+type ActivityRepository interface {
+	// ListActivity returns up to limit of the user's items, newest first,
+	// starting after before, or from the newest item when before is nil.
+	ListActivity(ctx context.Context, userID int64, before *ActivityCursor, limit int) ([]Activity, error)
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// Alert delivery channels.
+const (
+	// AlertChannelWebhook POSTs the notification as JSON to the rule's Target URL.
+	AlertChannelWebhook = "webhook"
+	// AlertChannelMQTT publishes the notification to the user's alerts topic.
+	AlertChannelMQTT = "mqtt"
+)
+
+// AlertRule is a user's rapid weight-change alert configuration.
+type AlertRule struct {
+	UserID int64 `json:"userId"`
+	// MaxWeeklyChangePct is the weekly change, as a percentage of body
+	// weight in either direction, above which the user is alerted.
+	MaxWeeklyChangePct float64 `json:"maxWeeklyChangePct"`
+	Channel            string  `json:"channel"`
+	// Target is channel specific: the URL for webhooks, unused for MQTT.
+	Target        string     `json:"target,omitempty"`
+	Enabled       bool       `json:"enabled"`
+	LastAlertedAt *time.Time `json:"lastAlertedAt,omitempty"`
+}
+
+// Notification is a message delivered to a user over an alert channel.
+type Notification struct {
+	UserID  int64     `json:"userId"`
+	Kind    string    `json:"kind"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+	// Target is the rule's channel-specific destination.
+	Target string `json:"-"`
+}
+
+// Notifier is the port for delivering notifications over one channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// AlertRuleRepository is the port for alert rule persistence.
+type AlertRuleRepository interface {
+	// GetAlertRule returns the user's rule, or nil if none is configured.
+	GetAlertRule(ctx context.Context, userID int64) (*AlertRule, error)
+	SaveAlertRule(ctx context.Context, rule AlertRule) error
+	// ListAlertRules returns every enabled rule.
+	ListAlertRules(ctx context.Context) ([]AlertRule, error)
+	// MarkAlerted records when the user was last alerted and, in the same
+	// transaction, adds queue to the outbox.
+	MarkAlerted(ctx context.Context, userID int64, at time.Time, queue ...OutboxMessage) error
+}
+
+// Bounds on the baseline a weekly change rate is measured against.
+const (
+	minRateWindow = 7 * 24 * time.Hour
+	maxRateWindow = 28 * 24 * time.Hour
+)
+
+// WeeklyChangePercent returns the rate of weight change, in percent of body
+// weight per week, between the latest weigh-in and the most recent one at
+// least a week before it. ok is false when no weigh-in falls 7 to 28 days
+// before the latest. Entries may be in any order or unit.
+func WeeklyChangePercent(entries []WeightEntry) (pct float64, ok bool) {
+	if len(entries) < 2 {
+		return 0, false
+	}
+	sorted := append([]WeightEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	latest := sorted[0]
+	for _, e := range sorted[1:] {
+		gap := latest.CreatedAt.Sub(e.CreatedAt)
+		if gap < minRateWindow {
+			continue
+		}
+		if gap > maxRateWindow {
+			return 0, false
+		}
+		base := ConvertWeight(e.Value, e.Unit, "kg")
+		if base <= 0 {
+			return 0, false
+		}
+		change := (ConvertWeight(latest.Value, latest.Unit, "kg") - base) / base * 100
+		weeks := gap.Hours() / (24 * 7)
+		return math.Round(change/weeks*100) / 100, true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestWeeklyChangePercent(t *testing.T) {
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+
+	tests := []struct {
+		name    string
+		entries []domain.WeightEntry
+		want    float64
+		wantOK  bool
+	}{
+		{"no history", nil, 0, false},
+		{
+			name: "one week loss",
+			entries: []domain.WeightEntry{
+				{Value: 98, Unit: "kg", CreatedAt: at(0)},
+				{Value: 99, Unit: "kg", CreatedAt: at(3)},
+				{Value: 100, Unit: "kg", CreatedAt: at(7)},
+			},
+			want: -2, wantOK: true,
+		},
+		{
+			name: "two week gain normalized per week",
+			entries: []domain.WeightEntry{
+				{Value: 100, Unit: "kg", CreatedAt: at(14)},
+				{Value: 102, Unit: "kg", CreatedAt: at(0)},
+			},
+			want: 1, wantOK: true,
+		},
+		{
+			name: "mixed units",
+			entries: []domain.WeightEntry{
+				{Value: 220.46226218, Unit: "lb", CreatedAt: at(7)},
+				{Value: 101, Unit: "kg", CreatedAt: at(0)},
+			},
+			want: 1, wantOK: true,
+		},
+		{
+			name: "baseline too recent",
+			entries: []domain.WeightEntry{
+				{Value: 100, Unit: "kg", CreatedAt: at(5)},
+				{Value: 95, Unit: "kg", CreatedAt: at(0)},
+			},
+			wantOK: false,
+		},
+		{
+			name: "baseline too old",
+			entries: []domain.WeightEntry{
+				{Value: 100, Unit: "kg", CreatedAt: at(40)},
+				{Value: 95, Unit: "kg", CreatedAt: at(0)},
+			},
+			wantOK: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := domain.WeeklyChangePercent(tc.entries)
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf("WeeklyChangePercent() = %v, %v; want %v, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
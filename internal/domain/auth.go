@@ -11,17 +11,41 @@ type User struct {
 	ID           int64
 	Username     string
 	PasswordHash string
-	CreatedAt    time.Time
+	// Email is the user's verified address, or empty.
+	Email string
+	// Role is RoleUser or RoleAdmin; empty means RoleUser.
+	Role      string
+	CreatedAt time.Time
+}
+
+// User roles. Admins may run instance-wide operations such as on-demand
+// maintenance.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user has the admin role.
+func (u *User) IsAdmin() bool {
+	return u != nil && u.Role == RoleAdmin
 }
 
+// MaxSessionNameLength bounds the name a user gives a session's device.
+const MaxSessionNameLength = 64
+
 // Session represents an active user session.
 type Session struct {
+	// ID identifies the session to its user without revealing Token.
+	ID        int64
 	Token     string
 	UserID    int64
 	UserAgent string
 	IP        string
-	ExpiresAt time.Time
-	CreatedAt time.Time
+	// Name is the user's label for the device, e.g. "iPad kitchen".
+	Name       string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastSeenAt time.Time
 }
 
 // UserRepository defines the port for user persistence operations.
@@ -30,6 +54,23 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id int64) (*User, error)
 	Create(ctx context.Context, username, passwordHash string) (*User, error)
 	Count(ctx context.Context) (int, error)
+	// UpdatePassword replaces the user's password hash.
+	UpdatePassword(ctx context.Context, userID int64, passwordHash string) error
+	// DeleteUser removes the user and everything they own: their
+	// profiles, events and other data, sessions, tokens and links, all at
+	// once.
+	DeleteUser(ctx context.Context, userID int64) error
+}
+
+// RevocationRepository is the port for users' revocation epochs. Grants
+// kept outside the database, such as embed links, carry the epoch they
+// were issued in and stop working once it moves on.
+type RevocationRepository interface {
+	// RevocationEpoch returns the user's current epoch; ok is false when
+	// there is no such user.
+	RevocationEpoch(ctx context.Context, userID int64) (epoch int64, ok bool, err error)
+	// BumpRevocationEpoch moves the user's epoch on.
+	BumpRevocationEpoch(ctx context.Context, userID int64) error
 }
 
 // SessionRepository defines the port for session persistence operations.
@@ -38,4 +79,27 @@ type SessionRepository interface {
 	GetByToken(ctx context.Context, token string) (*Session, error)
 	Delete(ctx context.Context, token string) error
 	DeleteExpired(ctx context.Context) error
+	// ListByUser returns the user's sessions, most recently seen first.
+	ListByUser(ctx context.Context, userID int64) ([]Session, error)
+	// Rename sets the name of the user's session id; it reports false if
+	// the user has no such session.
+	Rename(ctx context.Context, userID, id int64, name string) (bool, error)
+	// DeleteByID revokes the user's session id.
+	DeleteByID(ctx context.Context, userID, id int64) (bool, error)
+	// DeleteByUser revokes all of the user's sessions and returns how
+	// many there were.
+	DeleteByUser(ctx context.Context, userID int64) (int, error)
+	// Touch records activity on the session with token.
+	Touch(ctx context.Context, token string, at time.Time) error
+}
+
+// LoginChallenge is a CAPTCHA or proof-of-work check a client must pass to
+// sign in with a password after too many failed attempts from its address.
+type LoginChallenge interface {
+	// New returns a challenge for the client, sent with the failed login's
+	// response. Its fields depend on the implementation, e.g. a site key.
+	New(ctx context.Context) (map[string]any, error)
+	// Verify reports whether solution, sent by the client at ip, solves a
+	// challenge from New.
+	Verify(ctx context.Context, solution, ip string) (bool, error)
 }
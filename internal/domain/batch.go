@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Batch operation kinds.
+const (
+	BatchOpWeight       = "weight"
+	BatchOpWater        = "water"
+	BatchOpDeleteWeight = "deleteWeight"
+	BatchOpDeleteWater  = "deleteWater"
+)
+
+// BatchOp is one write in a batch. Weight ops use Value and Unit, water ops
+// use DeltaLiters, and deletes use ID. ClientID, when set, makes a weight or
+// water op idempotent.
+type BatchOp struct {
+	Op          string    `json:"op"`
+	ClientID    string    `json:"clientId,omitempty"`
+	Value       float64   `json:"value,omitempty"`
+	Unit        string    `json:"unit,omitempty"`
+	DeltaLiters float64   `json:"deltaLiters,omitempty"`
+	ID          int64     `json:"id,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+}
+
+// BatchResult reports the outcome of the BatchOp at the same index. Created
+// is false when a ClientID matched an existing entry; Deleted is false when
+// the entry to delete was already gone.
+type BatchResult struct {
+	Op      string       `json:"op"`
+	ID      int64        `json:"id"`
+	Created bool         `json:"created,omitempty"`
+	Deleted bool         `json:"deleted,omitempty"`
+	Weight  *WeightEntry `json:"weight,omitempty"`
+	Water   *WaterEvent  `json:"water,omitempty"`
+}
+
+// BatchRepository is the port for applying several writes atomically.
+type BatchRepository interface {
+	// ApplyBatch applies ops in order for one user. Either every op is
+	// applied or, on error, none is.
+	ApplyBatch(ctx context.Context, userID int64, ops []BatchOp) ([]BatchResult, error)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// ConfigBundleVersion is the current format version of configuration bundles.
+const ConfigBundleVersion = 1
+
+// ConfigBundle is a user's configuration without any measurement data, used
+// to move preferences, goals and alert rules between instances. Sections
+// that are absent are left untouched on import.
+type ConfigBundle struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exportedAt"`
+	Settings   UserSettings `json:"settings,omitempty"`
+	// Hydration is the daily water goal and optional weather location.
+	Hydration *HydrationConfig `json:"hydration,omitempty"`
+	// WeightChangeAlert is the rapid weight-change alert rule.
+	WeightChangeAlert *AlertRuleConfig `json:"weightChangeAlert,omitempty"`
+}
+
+// HydrationConfig is the portable part of HydrationSettings.
+type HydrationConfig struct {
+	BaseGoalLiters float64  `json:"baseGoalLiters"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+}
+
+// AlertRuleConfig is the portable part of AlertRule.
+type AlertRuleConfig struct {
+	MaxWeeklyChangePct float64 `json:"maxWeeklyChangePct"`
+	Channel            string  `json:"channel"`
+	Target             string  `json:"target,omitempty"`
+	Enabled            bool    `json:"enabled"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Change log entities and operations.
+const (
+	ChangeEntityWeight = "weight"
+	ChangeEntityWater  = "water"
+
+	ChangeOpUpsert = "upsert"
+	ChangeOpDelete = "delete"
+)
+
+// Change is one entry in a user's change log. Seq increases monotonically
+// across all changes and serves as the sync cursor. For upserts the current
+// state of the entity is attached.
+type Change struct {
+	Seq       int64        `json:"seq"`
+	Entity    string       `json:"entity"`
+	EntityID  int64        `json:"id"`
+	Op        string       `json:"op"`
+	ChangedAt time.Time    `json:"changedAt"`
+	Weight    *WeightEntry `json:"weight,omitempty"`
+	Water     *WaterEvent  `json:"water,omitempty"`
+}
+
+// ChangeRepository is the port for reading the change log. Metric
+// repositories append to the log as part of every write.
+type ChangeRepository interface {
+	// ListChanges returns up to limit changes with Seq > since, oldest first,
+	// collapsed to the latest change per entity. It returns an
+	// ErrCursorExpired error if deletions after since have been purged;
+	// the client must then sync again from 0.
+	ListChanges(ctx context.Context, userID, since int64, limit int) ([]Change, error)
+	// LatestChange returns the user's most recent change to entity, or nil
+	// if there is none.
+	LatestChange(ctx context.Context, userID int64, entity string) (*Change, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "strings"
+
+// NormalizeClientID validates a client-generated UUID and returns it in
+// canonical lower-case form. Offline clients tag queued writes with one so
+// that retries are deduplicated by the server.
+func NormalizeClientID(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) != 36 {
+		return "", false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return "", false
+			}
+		default:
+			if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+				return "", false
+			}
+		}
+	}
+	return s, true
+}
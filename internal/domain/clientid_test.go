@@ -0,0 +1,27 @@
+package domain_test
+
+import (
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestNormalizeClientID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c", "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c", true},
+		{" 0B6F3C1E-8A3D-4C5E-9F2A-1D2E3F4A5B6C ", "0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6c", true},
+		{"0b6f3c1e8a3d4c5e9f2a1d2e3f4a5b6c", "", false},
+		{"0b6f3c1e-8a3d-4c5e-9f2a-1d2e3f4a5b6g", "", false},
+		{"", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := domain.NormalizeClientID(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("NormalizeClientID(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "context"
+
+// Cluster is the port for app instances sharing a database to keep their
+// in-process state in step: each instance publishes its changes and applies
+// the ones the others publish. Delivery is best effort; messages published
+// while an instance is reconnecting are lost.
+type Cluster interface {
+	// Publish sends payload on topic to every other instance.
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe calls fn with each payload other instances publish on
+	// topic. fn runs on the cluster's delivery goroutine and must not block.
+	Subscribe(topic string, fn func(payload []byte))
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxReportedGaps bounds how many gaps a ComplianceReport lists.
+const maxReportedGaps = 3
+
+// ComplianceReport summarizes how consistently a user weighs in.
+type ComplianceReport struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Days       int     `json:"days"`
+	DaysLogged int     `json:"daysLogged"`
+	Rate       float64 `json:"rate"`
+	// LongestGaps are the longest runs of days without a weigh-in, longest
+	// first.
+	LongestGaps []Gap `json:"longestGaps"`
+	// AvgTimeOfDay is the average local time ("15:04") of each day's first
+	// weigh-in, or empty when nothing was logged.
+	AvgTimeOfDay string `json:"avgTimeOfDay,omitempty"`
+}
+
+// Gap is a run of consecutive days without a weigh-in.
+type Gap struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Days int    `json:"days"`
+}
+
+// WeighInCompliance reports on the days days ending on (and including) the
+// local day of now. Entries outside the window are ignored.
+func WeighInCompliance(entries []WeightEntry, days int, now time.Time) ComplianceReport {
+	end := now.In(time.Local)
+	start := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -(days - 1))
+	r := ComplianceReport{
+		From:        start.Format("2006-01-02"),
+		To:          end.Format("2006-01-02"),
+		Days:        days,
+		LongestGaps: []Gap{},
+	}
+
+	// First weigh-in per local day.
+	first := map[string]time.Time{}
+	for _, e := range entries {
+		t := e.CreatedAt.In(time.Local)
+		day := t.Format("2006-01-02")
+		if day < r.From || day > r.To {
+			continue
+		}
+		if f, ok := first[day]; !ok || t.Before(f) {
+			first[day] = t
+		}
+	}
+	r.DaysLogged = len(first)
+	r.Rate = math.Round(float64(r.DaysLogged)/float64(days)*100) / 100
+
+	var gaps []Gap
+	var cur *Gap
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format("2006-01-02")
+		if _, ok := first[day]; ok {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			gaps = append(gaps, Gap{From: day})
+			cur = &gaps[len(gaps)-1]
+		}
+		cur.To = day
+		cur.Days++
+	}
+	sort.SliceStable(gaps, func(i, j int) bool { return gaps[i].Days > gaps[j].Days })
+	if len(gaps) > maxReportedGaps {
+		gaps = gaps[:maxReportedGaps]
+	}
+	r.LongestGaps = append(r.LongestGaps, gaps...)
+
+	if len(first) > 0 {
+		r.AvgTimeOfDay = meanTimeOfDay(first)
+	}
+	return r
+}
+
+// meanTimeOfDay averages clock times on a circle so that 23:30 and 00:30
+// average to midnight rather than noon.
+func meanTimeOfDay(times map[string]time.Time) string {
+	var x, y float64
+	for _, t := range times {
+		mins := float64(t.Hour()*60 + t.Minute())
+		angle := mins / (24 * 60) * 2 * math.Pi
+		x += math.Cos(angle)
+		y += math.Sin(angle)
+	}
+	angle := math.Atan2(y, x)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	mins := int(math.Round(angle/(2*math.Pi)*24*60)) % (24 * 60)
+	return fmt.Sprintf("%02d:%02d", mins/60, mins%60)
+}
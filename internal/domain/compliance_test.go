@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestWeighInCompliance(t *testing.T) {
+	now := time.Date(2026, 3, 10, 20, 0, 0, 0, time.Local)
+	at := func(day, hour, min int) domain.WeightEntry {
+		return domain.WeightEntry{Value: 80, Unit: "kg", CreatedAt: time.Date(2026, 3, day, hour, min, 0, 0, time.Local)}
+	}
+
+	r := domain.WeighInCompliance([]domain.WeightEntry{
+		at(1, 7, 0),
+		at(2, 7, 30),
+		at(2, 21, 0), // later weigh-ins on a logged day don't move the average
+		at(6, 8, 30),
+		at(10, 7, 0),
+		{Value: 80, Unit: "kg", CreatedAt: time.Date(2026, 2, 20, 7, 0, 0, 0, time.Local)},
+	}, 10, now)
+
+	if r.From != "2026-03-01" || r.To != "2026-03-10" || r.Days != 10 {
+		t.Fatalf("unexpected window: %+v", r)
+	}
+	if r.DaysLogged != 4 || r.Rate != 0.4 {
+		t.Errorf("expected 4 logged days at 0.4, got %d at %v", r.DaysLogged, r.Rate)
+	}
+	want := []domain.Gap{
+		{From: "2026-03-03", To: "2026-03-05", Days: 3},
+		{From: "2026-03-07", To: "2026-03-09", Days: 3},
+	}
+	if len(r.LongestGaps) != len(want) {
+		t.Fatalf("expected %d gaps, got %+v", len(want), r.LongestGaps)
+	}
+	for i := range want {
+		if r.LongestGaps[i] != want[i] {
+			t.Errorf("gap %d = %+v; want %+v", i, r.LongestGaps[i], want[i])
+		}
+	}
+	if r.AvgTimeOfDay != "07:30" {
+		t.Errorf("expected average 07:30, got %q", r.AvgTimeOfDay)
+	}
+}
+
+func TestWeighInCompliance_AverageAcrossMidnight(t *testing.T) {
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.Local)
+	r := domain.WeighInCompliance([]domain.WeightEntry{
+		{CreatedAt: time.Date(2026, 3, 1, 23, 30, 0, 0, time.Local)},
+		{CreatedAt: time.Date(2026, 3, 3, 0, 30, 0, 0, time.Local)},
+	}, 3, now)
+	if r.AvgTimeOfDay != "00:00" {
+		t.Errorf("expected 00:00, got %q", r.AvgTimeOfDay)
+	}
+	if len(r.LongestGaps) != 1 || r.LongestGaps[0].Days != 1 {
+		t.Errorf("unexpected gaps: %+v", r.LongestGaps)
+	}
+}
+
+func TestWeighInCompliance_Empty(t *testing.T) {
+	r := domain.WeighInCompliance(nil, 7, time.Now())
+	if r.DaysLogged != 0 || r.AvgTimeOfDay != "" || len(r.LongestGaps) != 1 || r.LongestGaps[0].Days != 7 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+}
@@ -1,6 +1,15 @@
 package domain
 
-const kgToLb = 2.2046226218
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	kgToLb       = 2.2046226218
+	flOzToLiters = 0.0295735296
+)
 
 // ConvertWeight converts a weight value between "kg" and "lb".
 // Returns v unchanged if from == to or if the units are unrecognised.
@@ -16,3 +25,53 @@ func ConvertWeight(v float64, from, to string) float64 {
 	}
 	return v
 }
+
+// ParseVolume parses a human-entered amount such as "250ml", "0.5l" or
+// "8oz" into liters. A bare number is taken as milliliters.
+func ParseVolume(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	num, factor := s, 0.001
+	for _, u := range []struct {
+		suffix string
+		factor float64
+	}{
+		{"ml", 0.001},
+		{"oz", flOzToLiters},
+		{"l", 1},
+	} {
+		if strings.HasSuffix(s, u.suffix) {
+			num, factor = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.factor
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, errors.New("amount must be a number with an optional ml, l or oz unit")
+	}
+	return v * factor, nil
+}
+
+// CelsiusToFahrenheit converts a temperature from °C to °F.
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts a temperature from °F to °C.
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// ConvertTemperature converts a temperature value between "c" and "f".
+// Returns v unchanged if from == to or if the units are unrecognised.
+func ConvertTemperature(v float64, from, to string) float64 {
+	if from == to {
+		return v
+	}
+	if from == TemperatureCelsius && to == TemperatureFahrenheit {
+		return CelsiusToFahrenheit(v)
+	}
+	if from == TemperatureFahrenheit && to == TemperatureCelsius {
+		return FahrenheitToCelsius(v)
+	}
+	return v
+}
@@ -35,3 +35,83 @@ func TestConvertWeight(t *testing.T) {
 		})
 	}
 }
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"250ml", 0.25, false},
+		{"250 ML", 0.25, false},
+		{"0.5l", 0.5, false},
+		{"1L", 1, false},
+		{"8oz", 0.2366, false},
+		{"330", 0.33, false},
+		{"-250ml", -0.25, false},
+		{"", 0, true},
+		{"lots", 0, true},
+		{"ml", 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := domain.ParseVolume(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseVolume(%q) error = %v; wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if !tc.wantErr && !almostEqual(got, tc.want, 0.001) {
+				t.Errorf("ParseVolume(%q) = %v; want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertTemperature(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{"c to f", 37.0, "c", "f", 98.6},
+		{"f to c", 98.6, "f", "c", 37.0},
+		{"freezing", 0, "c", "f", 32},
+		{"same unit", 36.5, "c", "c", 36.5},
+		{"unknown units", 300, "k", "c", 300},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := domain.ConvertTemperature(tc.value, tc.from, tc.to)
+			if !almostEqual(got, tc.want, 0.001) {
+				t.Errorf("ConvertTemperature(%v, %q, %q) = %v; want %v",
+					tc.value, tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTemperatureReadingValidate(t *testing.T) {
+	tests := []struct {
+		value   float64
+		unit    string
+		wantErr bool
+	}{
+		{36.8, "c", false},
+		{101.2, " F ", false},
+		{98.6, "c", true},
+		{29.9, "c", true},
+		{45.1, "c", true},
+		{37, "k", true},
+		{37, "", true},
+	}
+	for _, tc := range tests {
+		r := domain.TemperatureReading{Value: tc.value, Unit: tc.unit}
+		if err := r.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("Validate(%v %q) error = %v; wantErr %v", tc.value, tc.unit, err, tc.wantErr)
+		}
+	}
+	r := domain.TemperatureReading{Value: 101.2, Unit: " F "}
+	if err := r.Validate(); err != nil || r.Unit != "f" {
+		t.Errorf("expected unit normalised to f, got %q (%v)", r.Unit, err)
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// DayWindow returns the instants [start, end) that fall on the calendar day
+// day ("2006-01-02") in loc, so repositories can select a local day's
+// entries by their UTC timestamps. Days are not always 24 hours long: they
+// last 23 or 25 hours when clocks change, start later than midnight when
+// midnight falls in a daylight saving gap, and are empty when the zone
+// skips them altogether, as Samoa skipped 2011-12-30.
+func DayWindow(day string, loc *time.Location) (start, end time.Time, err error) {
+	d, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return dayStart(d, loc), dayStart(d.AddDate(0, 0, 1), loc), nil
+}
+
+// dayStart returns the first instant of the calendar day of d, which is
+// midnight UTC, in loc.
+func dayStart(d time.Time, loc *time.Location) time.Time {
+	t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+	// Where midnight does not exist, time.Date picks an offset from either
+	// side of the gap; the day starts at the clock change instead.
+	got := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case got.Before(d):
+		_, next := t.ZoneBounds()
+		return next
+	case got.After(d), t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0:
+		change, _ := t.ZoneBounds()
+		return change
+	}
+	return t
+}
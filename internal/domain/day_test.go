@@ -0,0 +1,87 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+	"vitals/internal/testdoubles"
+)
+
+func TestDayWindow(t *testing.T) {
+	tests := []struct {
+		zone, day string
+		start     string // RFC 3339, UTC
+		length    time.Duration
+	}{
+		{"UTC", "2024-06-01", "2024-06-01T00:00:00Z", 24 * time.Hour},
+		{"America/New_York", "2024-03-10", "2024-03-10T05:00:00Z", 23 * time.Hour},
+		{"America/New_York", "2024-11-03", "2024-11-03T04:00:00Z", 25 * time.Hour},
+		// Clocks go from 24:00 to 01:00, so the day starts at 01:00.
+		{"America/Santiago", "2024-09-08", "2024-09-08T04:00:00Z", 23 * time.Hour},
+		// Clocks go from 24:00 back to 23:00, repeating an hour of the 6th.
+		{"America/Santiago", "2024-04-06", "2024-04-06T03:00:00Z", 25 * time.Hour},
+		{"Australia/Lord_Howe", "2024-10-06", "2024-10-05T13:30:00Z", 23*time.Hour + 30*time.Minute},
+		{"Pacific/Apia", "2011-12-30", "2011-12-30T10:00:00Z", 0},
+		{"Pacific/Apia", "2011-12-31", "2011-12-30T10:00:00Z", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone+"/"+tt.day, func(t *testing.T) {
+			start, end, err := domain.DayWindow(tt.day, testdoubles.Zone(t, tt.zone))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := start.UTC().Format(time.RFC3339); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := end.Sub(start); got != tt.length {
+				t.Errorf("length = %v, want %v", got, tt.length)
+			}
+		})
+	}
+
+	if _, _, err := domain.DayWindow("2024-02-30", time.UTC); err == nil {
+		t.Error("expected an invalid day to be rejected")
+	}
+}
+
+// TestDayWindow_Properties checks every day from 2010 to 2030 in each edge
+// zone: consecutive windows tile time without gaps or overlaps, and every
+// instant lies in the window of its own local day.
+func TestDayWindow_Properties(t *testing.T) {
+	first := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC)
+	for _, zone := range testdoubles.EdgeZones {
+		t.Run(zone, func(t *testing.T) {
+			loc := testdoubles.Zone(t, zone)
+			_, prevEnd, err := domain.DayWindow(first.AddDate(0, 0, -1).Format("2006-01-02"), loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+				day := d.Format("2006-01-02")
+				start, end, err := domain.DayWindow(day, loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !start.Equal(prevEnd) {
+					t.Fatalf("%s starts at %v, but the day before ends at %v", day, start, prevEnd)
+				}
+				if length := end.Sub(start); length != 0 && (length < 22*time.Hour || length > 26*time.Hour) {
+					t.Fatalf("%s lasts %v", day, length)
+				}
+				prevEnd = end
+				if start.Equal(end) {
+					continue // a skipped day
+				}
+				// Probe the edges and the hours around clock changes, which
+				// happen at night.
+				for _, at := range []time.Time{start, end.Add(-time.Nanosecond), start.Add(90 * time.Minute), end.Add(-90 * time.Minute)} {
+					if got := at.In(loc).Format("2006-01-02"); got != day {
+						t.Fatalf("%v is on %s, but in the window of %s", at.In(loc), got, day)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Services return an *Error of one of these kinds,
+// so callers test errors.Is(err, ErrInvalidUnit) instead of matching
+// messages, and the HTTP adapter maps each kind to a status and a stable
+// code clients can switch on or translate.
+var (
+	ErrValueOutOfRange = errors.New("value out of range")
+	ErrInvalidUnit     = errors.New("invalid unit")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrNotFound        = errors.New("not found")
+	ErrCursorExpired   = errors.New("cursor expired")
+)
+
+// Error is a domain error of Kind, one of the kinds above, with a message
+// for people.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// Errorf returns an *Error of kind with a formatted message.
+func Errorf(kind error, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Unwrap returns e's kind, so errors.Is matches it.
+func (e *Error) Unwrap() error { return e.Kind }
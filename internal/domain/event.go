@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Metric event types published after successful writes.
+const (
+	EventWeightRecorded = "weight.recorded"
+	EventWaterRecorded  = "water.recorded"
+)
+
+// MetricEvent describes a newly recorded measurement.
+type MetricEvent struct {
+	Type   string  `json:"type"`
+	UserID int64   `json:"userId"`
+	Day    string  `json:"day"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	// DayTotal is the running total for Day where the metric accumulates
+	// (water, in liters); zero otherwise.
+	DayTotal float64   `json:"dayTotal,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// EventPublisher is the port for broadcasting metric events to external
+// systems. Publishing is best effort: implementations handle and log their
+// own delivery failures so a slow or absent consumer never fails a write.
+type EventPublisher interface {
+	Publish(ctx context.Context, e MetricEvent)
+}
+
+// Publishers fans an event out to several publishers in order.
+type Publishers []EventPublisher
+
+// Publish sends e to every publisher.
+func (ps Publishers) Publish(ctx context.Context, e MetricEvent) {
+	for _, p := range ps {
+		p.Publish(ctx, e)
+	}
+}
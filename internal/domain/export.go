@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Exportable metrics.
+const (
+	ExportMetricWeight = "weight"
+	ExportMetricWater  = "water"
+)
+
+// ExportMetrics lists the metrics exports can be limited to.
+var ExportMetrics = []string{ExportMetricWeight, ExportMetricWater}
+
+// ExportFilter selects the slice of a user's history an export holds: the
+// events of local days From through To (inclusive, "YYYY-MM-DD"; empty for
+// no bound) of Metrics (empty for all).
+type ExportFilter struct {
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+// ParseExportFilter parses the ?from=, ?to= and ?metrics= parameters of
+// an export; metrics is a comma-separated list, e.g. "weight,water".
+func ParseExportFilter(from, to, metrics string) (ExportFilter, error) {
+	f := ExportFilter{From: from, To: to}
+	for _, d := range []struct{ name, value string }{{"from", from}, {"to", to}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return ExportFilter{}, fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+		}
+	}
+	if from != "" && to != "" && to < from {
+		return ExportFilter{}, errors.New("to must not be before from")
+	}
+	for _, m := range strings.Split(metrics, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" || slices.Contains(f.Metrics, m) {
+			continue
+		}
+		if !slices.Contains(ExportMetrics, m) {
+			return ExportFilter{}, fmt.Errorf("metrics must be a list of %q", ExportMetrics)
+		}
+		f.Metrics = append(f.Metrics, m)
+	}
+	return f, nil
+}
+
+// IsZero reports whether f selects the full history.
+func (f ExportFilter) IsZero() bool {
+	return f.From == "" && f.To == "" && len(f.Metrics) == 0
+}
+
+// Includes reports whether f selects metric.
+func (f ExportFilter) Includes(metric string) bool {
+	return len(f.Metrics) == 0 || slices.Contains(f.Metrics, metric)
+}
+
+// Before reports whether the local day of t is before From.
+func (f ExportFilter) Before(t time.Time) bool {
+	return f.From != "" && t.In(time.Local).Format("2006-01-02") < f.From
+}
+
+// After reports whether the local day of t is after To.
+func (f ExportFilter) After(t time.Time) bool {
+	return f.To != "" && t.In(time.Local).Format("2006-01-02") > f.To
+}
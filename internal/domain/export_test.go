@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestParseExportFilter(t *testing.T) {
+	f, err := domain.ParseExportFilter("2026-03-01", "2026-03-31", " Water,water ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(f.Metrics, []string{"water"}) || f.Includes(domain.ExportMetricWeight) || !f.Includes(domain.ExportMetricWater) {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+	if f.Before(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)) || !f.Before(time.Date(2026, 2, 28, 23, 59, 0, 0, time.Local)) {
+		t.Error("expected From to bound the first local day")
+	}
+	if f.After(time.Date(2026, 3, 31, 23, 59, 0, 0, time.Local)) || !f.After(time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)) {
+		t.Error("expected To to bound the last local day")
+	}
+
+	if f, err := domain.ParseExportFilter("", "", ""); err != nil || !f.IsZero() || !f.Includes(domain.ExportMetricWeight) {
+		t.Errorf("expected an empty filter to select everything, got %+v, %v", f, err)
+	}
+	for _, bad := range [][3]string{
+		{"2026-3-1", "", ""},
+		{"", "yesterday", ""},
+		{"2026-03-02", "2026-03-01", ""},
+		{"", "", "weight,steps"},
+	} {
+		if _, err := domain.ParseExportFilter(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("ParseExportFilter(%q): expected an error", bad)
+		}
+	}
+}
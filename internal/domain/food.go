@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaxFoodDescriptionLength caps a food entry's description, in bytes.
+const MaxFoodDescriptionLength = 200
+
+// FoodEntry is one logged food or meal with its energy and macronutrients.
+type FoodEntry struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	Description string    `json:"description"`
+	Kcal        float64   `json:"kcal"`
+	ProteinG    float64   `json:"proteinG"`
+	CarbsG      float64   `json:"carbsG"`
+	FatG        float64   `json:"fatG"`
+	ClientID    string    `json:"clientId,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NutritionTotals sums the food entries of a day.
+type NutritionTotals struct {
+	Kcal     float64 `json:"kcal"`
+	ProteinG float64 `json:"proteinG"`
+	CarbsG   float64 `json:"carbsG"`
+	FatG     float64 `json:"fatG"`
+	Entries  int     `json:"entries"`
+}
+
+// Add adds e to the totals.
+func (t *NutritionTotals) Add(e FoodEntry) {
+	t.Kcal += e.Kcal
+	t.ProteinG += e.ProteinG
+	t.CarbsG += e.CarbsG
+	t.FatG += e.FatG
+	t.Entries++
+}
+
+// FoodRepository is the port for food log persistence.
+type FoodRepository interface {
+	// AddFoodEntry stores e for the user unless e.ClientID is set and the
+	// user already stored an entry with it. It returns the stored entry and
+	// whether it was newly created.
+	AddFoodEntry(ctx context.Context, userID int64, e FoodEntry) (*FoodEntry, bool, error)
+	DeleteFoodEntry(ctx context.Context, userID int64, id int64) error
+	// DeleteLatestFoodEntry removes the user's most recent food entry in one
+	// atomic step and returns its ID, or 0 if there was none.
+	DeleteLatestFoodEntry(ctx context.Context, userID int64) (int64, error)
+	ListRecentFoodEntries(ctx context.Context, userID int64, limit int) ([]FoodEntry, error)
+	// FoodTotalsForLocalDay sums the user's entries on a local calendar day.
+	FoodTotalsForLocalDay(ctx context.Context, userID int64, localDay string) (NutritionTotals, error)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "context"
+
+// WeightGoalChange is a target weight a user set, in effect from Day
+// (YYYY-MM-DD) until the next change. A nil TargetKg clears the target.
+type WeightGoalChange struct {
+	Day      string   `json:"day"`
+	TargetKg *float64 `json:"targetKg"`
+}
+
+// WeightGoalHistory is a user's target weight changes, oldest first.
+type WeightGoalHistory []WeightGoalChange
+
+// TargetOn returns the target weight in kilograms in effect on day, or nil
+// if none was set then.
+func (h WeightGoalHistory) TargetOn(day string) *float64 {
+	var kg *float64
+	for _, c := range h {
+		if c.Day > day {
+			break
+		}
+		kg = c.TargetKg
+	}
+	return kg
+}
+
+// WeightGoalRepository is the port for the history of users' target
+// weights.
+type WeightGoalRepository interface {
+	// RecordWeightGoal sets the target in effect from day, replacing any
+	// change already recorded for that day; a nil targetKg clears it.
+	RecordWeightGoal(ctx context.Context, userID int64, day string, targetKg *float64) error
+	// WeightGoalHistory returns the user's target changes, oldest first.
+	WeightGoalHistory(ctx context.Context, userID int64) (WeightGoalHistory, error)
+}
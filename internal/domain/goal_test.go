@@ -0,0 +1,29 @@
+package domain_test
+
+import (
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestWeightGoalHistoryTargetOn(t *testing.T) {
+	kg := func(v float64) *float64 { return &v }
+	h := domain.WeightGoalHistory{
+		{Day: "2026-03-01", TargetKg: kg(80)},
+		{Day: "2026-04-15", TargetKg: kg(75)},
+		{Day: "2026-06-01", TargetKg: nil},
+	}
+	tests := map[string]*float64{
+		"2026-02-28": nil,
+		"2026-03-01": kg(80),
+		"2026-04-14": kg(80),
+		"2026-04-15": kg(75),
+		"2026-06-01": nil,
+	}
+	for day, want := range tests {
+		got := h.TargetOn(day)
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("TargetOn(%s) = %v, want %v", day, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultHydrationGoalLiters is the daily goal for users who have not set one.
+const DefaultHydrationGoalLiters = 2.5
+
+// HydrationSettings holds a user's daily water goal and, for weather-aware
+// adjustment, their location.
+type HydrationSettings struct {
+	UserID         int64    `json:"userId"`
+	BaseGoalLiters float64  `json:"baseGoalLiters"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+}
+
+// HasLocation reports whether both coordinates are set.
+func (s HydrationSettings) HasLocation() bool {
+	return s.Latitude != nil && s.Longitude != nil
+}
+
+// HydrationSettingsRepository is the port for hydration settings persistence.
+type HydrationSettingsRepository interface {
+	// GetHydrationSettings returns the user's settings, or nil if unset.
+	GetHydrationSettings(ctx context.Context, userID int64) (*HydrationSettings, error)
+	SaveHydrationSettings(ctx context.Context, s HydrationSettings) error
+}
+
+// GoalChange is a base water goal a user set, in effect from Day
+// (YYYY-MM-DD) until the next change.
+type GoalChange struct {
+	Day    string  `json:"day"`
+	Liters float64 `json:"liters"`
+}
+
+// GoalHistory is a user's goal changes, oldest first.
+type GoalHistory []GoalChange
+
+// LitersOn returns the base goal in effect on day, or the default goal for
+// days before the first change.
+func (h GoalHistory) LitersOn(day string) float64 {
+	liters := DefaultHydrationGoalLiters
+	for _, c := range h {
+		if c.Day > day {
+			break
+		}
+		liters = c.Liters
+	}
+	return liters
+}
+
+// GoalHistoryRepository is the port for the history of users' water goals.
+type GoalHistoryRepository interface {
+	// RecordGoal sets the goal in effect from day, replacing any change
+	// already recorded for that day.
+	RecordGoal(ctx context.Context, userID int64, day string, liters float64) error
+	// GoalHistory returns the user's goal changes, oldest first.
+	GoalHistory(ctx context.Context, userID int64) (GoalHistory, error)
+}
+
+// Weather is the current conditions at a location.
+type Weather struct {
+	// TempMaxC is the day's expected maximum temperature in Celsius.
+	TempMaxC float64 `json:"tempMaxC"`
+}
+
+// WeatherProvider is the port for looking up weather by coordinates.
+type WeatherProvider interface {
+	Current(ctx context.Context, lat, lon float64) (*Weather, error)
+}
+
+// HydrationGoal is the day's water goal after any adjustment.
+type HydrationGoal struct {
+	BaseLiters       float64 `json:"baseLiters"`
+	AdjustmentLiters float64 `json:"adjustmentLiters"`
+	Liters           float64 `json:"liters"`
+	// Reason explains a non-zero adjustment.
+	Reason   string   `json:"reason,omitempty"`
+	TempMaxC *float64 `json:"tempMaxC,omitempty"`
+}
+
+// heatSteps maps maximum temperatures to extra liters, hottest first.
+var heatSteps = []struct {
+	minTempC float64
+	liters   float64
+}{
+	{35, 1.0},
+	{30, 0.75},
+	{25, 0.5},
+}
+
+// HeatAdjustment returns the extra water to drink on a day reaching
+// tempMaxC, and why. Days below 25°C need no adjustment.
+func HeatAdjustment(tempMaxC float64) (float64, string) {
+	for _, s := range heatSteps {
+		if tempMaxC >= s.minTempC {
+			return s.liters, fmt.Sprintf("Hot day (high of %.0f°C): +%.2g L", tempMaxC, s.liters)
+		}
+	}
+	return 0, ""
+}
+
+// Drinking is expected to spread evenly over the waking day, from
+// PaceStartHour to PaceEndHour local time, so the share of the goal due by
+// a given time grows linearly between them.
+const (
+	PaceStartHour = 7
+	PaceEndHour   = 22
+)
+
+// paceStep is how often the expected intake advances, so a response and its
+// ETag stay the same between steps.
+const paceStep = 15 * time.Minute
+
+// paceTolerance is how far, as a share of the goal, intake may trail or
+// lead the expected amount and still count as on track.
+const paceTolerance = 0.1
+
+// Water pace statuses.
+const (
+	PaceAhead   = "ahead"
+	PaceOnTrack = "on_track"
+	PaceBehind  = "behind"
+)
+
+// WaterPace compares the day's intake so far with what is due by now.
+type WaterPace struct {
+	// ExpectedFraction is the share of the goal due by now, 0 to 1.
+	ExpectedFraction float64 `json:"expectedFraction"`
+	ExpectedLiters   float64 `json:"expectedLiters"`
+	// DeltaLiters is intake minus the expected amount; negative when behind.
+	DeltaLiters float64 `json:"deltaLiters"`
+	Status      string  `json:"status"`
+}
+
+// ExpectedWaterFraction returns the share of the daily goal due by now,
+// advancing in 15-minute steps through the waking day.
+func ExpectedWaterFraction(now time.Time) float64 {
+	now = now.In(time.Local)
+	start := time.Date(now.Year(), now.Month(), now.Day(), PaceStartHour, 0, 0, 0, time.Local)
+	elapsed := now.Sub(start).Truncate(paceStep)
+	day := time.Duration(PaceEndHour-PaceStartHour) * time.Hour
+	return math.Min(math.Max(float64(elapsed)/float64(day), 0), 1)
+}
+
+// PaceAt rates totalLiters drunk so far today against goalLiters at now.
+// Amounts are rounded to the millilitre.
+func PaceAt(totalLiters, goalLiters float64, now time.Time) WaterPace {
+	fraction := ExpectedWaterFraction(now)
+	expected := goalLiters * fraction
+	delta := totalLiters - expected
+	p := WaterPace{
+		ExpectedFraction: roundMilli(fraction),
+		ExpectedLiters:   RoundLiters(expected),
+		DeltaLiters:      RoundLiters(delta),
+		Status:           PaceOnTrack,
+	}
+	switch {
+	case delta > paceTolerance*goalLiters:
+		p.Status = PaceAhead
+	case delta < -paceTolerance*goalLiters:
+		p.Status = PaceBehind
+	}
+	return p
+}
+
+func roundMilli(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,71 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestHeatAdjustment(t *testing.T) {
+	tests := []struct {
+		tempC      float64
+		wantLiters float64
+		wantReason string
+	}{
+		{18, 0, ""},
+		{24.9, 0, ""},
+		{25, 0.5, "Hot day (high of 25°C): +0.5 L"},
+		{31.4, 0.75, "Hot day (high of 31°C): +0.75 L"},
+		{38, 1, "Hot day (high of 38°C): +1 L"},
+	}
+	for _, tc := range tests {
+		liters, reason := domain.HeatAdjustment(tc.tempC)
+		if liters != tc.wantLiters || reason != tc.wantReason {
+			t.Errorf("HeatAdjustment(%v) = %v, %q; want %v, %q", tc.tempC, liters, reason, tc.wantLiters, tc.wantReason)
+		}
+	}
+}
+
+func TestGoalHistoryLitersOn(t *testing.T) {
+	h := domain.GoalHistory{{Day: "2026-03-01", Liters: 3}, {Day: "2026-04-15", Liters: 2}}
+	tests := map[string]float64{
+		"2026-02-28": domain.DefaultHydrationGoalLiters,
+		"2026-03-01": 3,
+		"2026-04-14": 3,
+		"2026-04-15": 2,
+		"2026-06-01": 2,
+	}
+	for day, want := range tests {
+		if got := h.LitersOn(day); got != want {
+			t.Errorf("LitersOn(%s) = %v, want %v", day, got, want)
+		}
+	}
+}
+
+func TestPaceAt(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2026, 5, 4, hour, min, 0, 0, time.Local) }
+	tests := []struct {
+		now          time.Time
+		total        float64
+		wantFraction float64
+		wantDelta    float64
+		wantStatus   string
+	}{
+		{at(6, 0), 0, 0, 0, domain.PaceOnTrack},
+		{at(6, 0), 0.5, 0, 0.5, domain.PaceAhead},
+		// 14:40 counts as 14:30, halfway through the waking day.
+		{at(14, 40), 1.5, 0.5, 0, domain.PaceOnTrack},
+		{at(14, 40), 1.0, 0.5, -0.5, domain.PaceBehind},
+		{at(14, 40), 2.0, 0.5, 0.5, domain.PaceAhead},
+		{at(23, 0), 2.8, 1, -0.2, domain.PaceOnTrack},
+		{at(23, 0), 2.5, 1, -0.5, domain.PaceBehind},
+	}
+	for _, tc := range tests {
+		p := domain.PaceAt(tc.total, 3, tc.now)
+		if p.ExpectedFraction != tc.wantFraction || p.DeltaLiters != tc.wantDelta || p.Status != tc.wantStatus {
+			t.Errorf("PaceAt(%v, 3, %s) = %+v; want fraction %v, delta %v, status %s",
+				tc.total, tc.now.Format("15:04"), p, tc.wantFraction, tc.wantDelta, tc.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LinkedIdentity ties an external SSO identity, named by its issuer and
+// subject, to a local account.
+type LinkedIdentity struct {
+	UserID  int64  `json:"userId"`
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+	// Email is the address the provider asserted when the link was made.
+	Email string `json:"email,omitempty"`
+	// EmailVerified reports whether the provider asserted that it verified
+	// Email. It is only known at sign-in and is not stored.
+	EmailVerified bool      `json:"emailVerified,omitempty"`
+	LinkedAt      time.Time `json:"linkedAt"`
+}
+
+// IdentityRepository is the port for linked identity persistence.
+type IdentityRepository interface {
+	// GetLinkedIdentity returns the link for issuer and subject, or nil.
+	GetLinkedIdentity(ctx context.Context, issuer, subject string) (*LinkedIdentity, error)
+	// LinkIdentity stores id, replacing any link for the same issuer and
+	// subject.
+	LinkIdentity(ctx context.Context, id LinkedIdentity) error
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ImportRepository is the port for events written by imports. Each event is
+// tagged with the batch that imported it, so a whole import can be rolled
+// back.
+type ImportRepository interface {
+	// ImportWeightEvent stores a weight event as part of batchID. It skips
+	// the event, reporting false, when the user already has a weight event
+	// with the same timestamp, value and unit, or with the same non-empty
+	// clientID.
+	ImportWeightEvent(ctx context.Context, userID int64, batchID, clientID string, value float64, unit string, createdAt time.Time) (bool, error)
+	// ImportWaterEvent stores a water event as part of batchID, skipping it
+	// like ImportWeightEvent when the user already has one with the same
+	// timestamp and amount, or with the same non-empty clientID.
+	ImportWaterEvent(ctx context.Context, userID int64, batchID, clientID string, deltaLiters float64, createdAt time.Time) (bool, error)
+	// DeleteImportBatch removes the user's events imported as part of
+	// batchID and returns how many it removed.
+	DeleteImportBatch(ctx context.Context, userID int64, batchID string) (int, error)
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Integration providers users can connect their account to.
+const (
+	IntegrationGoogleFit = "googlefit"
+)
+
+// OAuthToken is a user's authorization for vitals to read their data from
+// an integration provider.
+type OAuthToken struct {
+	UserID       int64
+	Provider     string
+	AccessToken  string
+	RefreshToken string
+	// Expiry is when AccessToken stops working; zero if it does not
+	// expire.
+	Expiry time.Time
+	// ConnectedAt is when the user connected the provider.
+	ConnectedAt time.Time
+	// SyncedAt is the end of the last pulled time range, zero before the
+	// first sync.
+	SyncedAt time.Time
+}
+
+// OAuthTokenRepository is the port for integration tokens, one per user and
+// provider.
+type OAuthTokenRepository interface {
+	// SaveOAuthToken creates or replaces the user's token for t.Provider.
+	SaveOAuthToken(ctx context.Context, t OAuthToken) error
+	// GetOAuthToken returns the user's token for provider, or nil.
+	GetOAuthToken(ctx context.Context, userID int64, provider string) (*OAuthToken, error)
+	// DeleteOAuthToken removes the user's token for provider, reporting
+	// whether there was one.
+	DeleteOAuthToken(ctx context.Context, userID int64, provider string) (bool, error)
+	// ListOAuthTokens returns every user's token for provider, ordered by
+	// user.
+	ListOAuthTokens(ctx context.Context, provider string) ([]OAuthToken, error)
+}
+
+// ExternalReading is a measurement read from an integration provider.
+type ExternalReading struct {
+	// ID identifies the reading at the provider, stable across syncs.
+	ID string
+	// Kind is "weight", in kilograms, or "water", in liters.
+	Kind  string
+	Value float64
+	At    time.Time
+}
+
+// HealthSource is the port for an integration provider users connect with
+// OAuth2 and pull measurements from.
+type HealthSource interface {
+	// AuthCodeURL returns the provider's consent page URL, which redirects
+	// back with a code and state.
+	AuthCodeURL(state string) string
+	// Exchange trades the code from the consent redirect for a token.
+	Exchange(ctx context.Context, code string) (*OAuthToken, error)
+	// Readings returns the readings recorded in [from, to). The token is
+	// refreshed as needed; the one returned replaces tok.
+	Readings(ctx context.Context, tok OAuthToken, from, to time.Time) ([]ExternalReading, *OAuthToken, error)
+}
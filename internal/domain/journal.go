@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaxJournalNoteLength caps a journal note, in bytes.
+const MaxJournalNoteLength = 2000
+
+// JournalEntry is a user's free-text note for one local day, giving context
+// ("ate out", "long run") to that day's measurements.
+type JournalEntry struct {
+	Day       string    `json:"day"`
+	Note      string    `json:"note"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// JournalRepository is the port for journal note persistence.
+type JournalRepository interface {
+	// GetJournalEntry returns the note for day, or nil if there is none.
+	GetJournalEntry(ctx context.Context, userID int64, day string) (*JournalEntry, error)
+	// SaveJournalEntry creates or replaces the note for e.Day.
+	SaveJournalEntry(ctx context.Context, userID int64, e JournalEntry) error
+	// DeleteJournalEntry removes the note for day, reporting whether one
+	// existed.
+	DeleteJournalEntry(ctx context.Context, userID int64, day string) (bool, error)
+	// ListJournalEntries returns the notes for days from through to
+	// (inclusive, "YYYY-MM-DD"), oldest first.
+	ListJournalEntries(ctx context.Context, userID int64, from, to string) ([]JournalEntry, error)
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// ListSummary describes the whole list a page of recent entries was taken
+// from, so clients can render pagination.
+type ListSummary struct {
+	// Total is how many entries there are in all.
+	Total int `json:"total"`
+	// HasMore reports whether entries older than the page exist.
+	HasMore bool `json:"hasMore"`
+	// FirstAt and LastAt are when the oldest and newest entries were
+	// recorded; both are nil without entries.
+	FirstAt *time.Time `json:"firstAt,omitempty"`
+	LastAt  *time.Time `json:"lastAt,omitempty"`
+}
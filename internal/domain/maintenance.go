@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OrphanCounts reports events that no longer belong to an existing user.
+type OrphanCounts struct {
+	WeightEvents int64 `json:"weightEvents"`
+	WaterEvents  int64 `json:"waterEvents"`
+}
+
+// MaintenanceRepository is the port for housekeeping operations that span
+// several tables.
+type MaintenanceRepository interface {
+	CountExpiredSessions(ctx context.Context) (int64, error)
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
+	CountOrphanedEvents(ctx context.Context) (OrphanCounts, error)
+	// CountOldChanges counts the change log entries PurgeOldChanges would
+	// remove.
+	CountOldChanges(ctx context.Context, before time.Time) (int64, error)
+	// PurgeOldChanges deletes change log entries recorded before before
+	// that a full sync no longer needs: changes superseded by a later
+	// change to the same entry, and deletions. Clients whose cursor
+	// predates a purged deletion get ErrCursorExpired from ListChanges.
+	PurgeOldChanges(ctx context.Context, before time.Time) (int64, error)
+	// Vacuum reclaims storage and refreshes planner statistics. Backends
+	// without such a concept return nil.
+	Vacuum(ctx context.Context) error
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Medication event statuses.
+const (
+	MedicationTaken   = "taken"
+	MedicationSkipped = "skipped"
+)
+
+// Medication limits.
+const (
+	MaxMedicationsPerUser   = 50
+	MaxMedicationNameLength = 100
+	MaxMedicationDoseLength = 50
+	// MaxMedicationDoses caps the scheduled doses per day.
+	MaxMedicationDoses = 12
+)
+
+// Medication is a medication or supplement a user takes.
+type Medication struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"userId"`
+	Name   string `json:"name"`
+	// Dose is free text, e.g. "500 mg" or "2 capsules".
+	Dose string `json:"dose,omitempty"`
+	// Schedule lists the local times of day ("HH:MM") a dose is due, in
+	// order; empty means as needed.
+	Schedule  []string  `json:"schedule"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Validate trims the name and dose, sorts and deduplicates the schedule,
+// and checks them against the medication limits.
+func (m *Medication) Validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Dose = strings.TrimSpace(m.Dose)
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(m.Name) > MaxMedicationNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxMedicationNameLength)
+	}
+	if len(m.Dose) > MaxMedicationDoseLength {
+		return fmt.Errorf("dose must be at most %d characters", MaxMedicationDoseLength)
+	}
+	schedule := make([]string, 0, len(m.Schedule))
+	for _, at := range m.Schedule {
+		t, err := time.Parse(ruleTimeOfDayLayout, strings.TrimSpace(at))
+		if err != nil {
+			return errors.New("schedule times must be local times of day as HH:MM")
+		}
+		schedule = append(schedule, t.Format(ruleTimeOfDayLayout))
+	}
+	slices.Sort(schedule)
+	m.Schedule = slices.Compact(schedule)
+	if len(m.Schedule) > MaxMedicationDoses {
+		return fmt.Errorf("at most %d scheduled doses per day", MaxMedicationDoses)
+	}
+	return nil
+}
+
+// MedicationEvent records that a dose of a medication was taken or skipped.
+type MedicationEvent struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"userId"`
+	MedicationID int64  `json:"medicationId"`
+	Status       string `json:"status"`
+	ClientID     string `json:"clientId,omitempty"`
+	// CreatedAt is when the dose was taken or skipped.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MedicationRepository is the port for medication persistence.
+type MedicationRepository interface {
+	CreateMedication(ctx context.Context, m Medication) (*Medication, error)
+	// GetMedication returns the user's medication with id, or nil.
+	GetMedication(ctx context.Context, userID, id int64) (*Medication, error)
+	// ListMedications returns the user's medications, oldest first.
+	ListMedications(ctx context.Context, userID int64) ([]Medication, error)
+	// UpdateMedication replaces the medication's name, dose and schedule;
+	// it reports false if the user has no such medication.
+	UpdateMedication(ctx context.Context, m Medication) (bool, error)
+	// DeleteMedication removes the medication and its events, reporting
+	// whether it existed.
+	DeleteMedication(ctx context.Context, userID, id int64) (bool, error)
+	// AddMedicationEvent stores e for the user unless e.ClientID is set and
+	// the user already stored an event with it. It returns the stored event
+	// and whether it was newly created.
+	AddMedicationEvent(ctx context.Context, userID int64, e MedicationEvent) (*MedicationEvent, bool, error)
+	// ListRecentMedicationEvents returns the user's latest limit events,
+	// newest first, for one medication or, with medicationID 0, all of them.
+	ListRecentMedicationEvents(ctx context.Context, userID, medicationID int64, limit int) ([]MedicationEvent, error)
+}
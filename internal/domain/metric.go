@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Custom metric aggregations: how a day's events combine into one value.
+const (
+	// MetricLatest keeps the day's most recent value, e.g. blood pressure.
+	MetricLatest = "latest"
+	// MetricSum adds the day's values, e.g. cups of coffee.
+	MetricSum = "sum"
+	// MetricAvg averages the day's values, e.g. pain level.
+	MetricAvg = "avg"
+)
+
+// Custom metric limits.
+const (
+	MaxCustomMetricsPerUser   = 20
+	MaxCustomMetricNameLength = 50
+	MaxCustomMetricUnitLength = 20
+	MaxCustomMetricSlugLength = 40
+)
+
+var metricSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CustomMetric is a numeric tracker the user defined themselves, addressed
+// in the API by its slug.
+type CustomMetric struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"userId"`
+	Slug   string `json:"slug"`
+	Name   string `json:"name"`
+	// Unit is a free-text label such as "cups" or "mmHg"; it may be empty.
+	Unit        string    `json:"unit"`
+	Aggregation string    `json:"aggregation"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Validate trims the name and unit, derives the slug from the name when it
+// is empty, and checks them against the custom metric limits.
+func (m *CustomMetric) Validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Unit = strings.TrimSpace(m.Unit)
+	m.Slug = strings.TrimSpace(m.Slug)
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(m.Name) > MaxCustomMetricNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxCustomMetricNameLength)
+	}
+	if len(m.Unit) > MaxCustomMetricUnitLength {
+		return fmt.Errorf("unit must be at most %d characters", MaxCustomMetricUnitLength)
+	}
+	if m.Slug == "" {
+		m.Slug = MetricSlug(m.Name)
+	}
+	if !metricSlugPattern.MatchString(m.Slug) || len(m.Slug) > MaxCustomMetricSlugLength {
+		return fmt.Errorf("slug must be at most %d lowercase letters, digits and single hyphens", MaxCustomMetricSlugLength)
+	}
+	switch m.Aggregation {
+	case MetricLatest, MetricSum, MetricAvg:
+	default:
+		return fmt.Errorf("aggregation must be %q, %q or %q", MetricLatest, MetricSum, MetricAvg)
+	}
+	return nil
+}
+
+// MetricSlug derives a URL slug from a metric name: lowercase ASCII
+// letters and digits, with every other run of characters turned into a
+// single hyphen. "Coffee (cups)" becomes "coffee-cups".
+func MetricSlug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	return b.String()
+}
+
+// AggregateMetric combines values, oldest first, according to aggregation.
+// It reports false when there are no values.
+func AggregateMetric(aggregation string, values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	switch aggregation {
+	case MetricSum, MetricAvg:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if aggregation == MetricAvg {
+			return sum / float64(len(values)), true
+		}
+		return sum, true
+	default:
+		return values[len(values)-1], true
+	}
+}
+
+// CustomMetricEvent is one recorded value of a custom metric.
+type CustomMetricEvent struct {
+	ID       int64   `json:"id"`
+	UserID   int64   `json:"userId"`
+	MetricID int64   `json:"metricId"`
+	Value    float64 `json:"value"`
+	ClientID string  `json:"clientId,omitempty"`
+	// CreatedAt is when the value was measured.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CustomMetricRepository is the port for custom metric persistence.
+type CustomMetricRepository interface {
+	CreateCustomMetric(ctx context.Context, m CustomMetric) (*CustomMetric, error)
+	// GetCustomMetric returns the user's metric with slug, or nil.
+	GetCustomMetric(ctx context.Context, userID int64, slug string) (*CustomMetric, error)
+	// ListCustomMetrics returns the user's metrics, oldest first.
+	ListCustomMetrics(ctx context.Context, userID int64) ([]CustomMetric, error)
+	// DeleteCustomMetric removes the metric and its events, reporting
+	// whether it existed.
+	DeleteCustomMetric(ctx context.Context, userID, id int64) (bool, error)
+	// AddCustomMetricEvent stores e for the user unless e.ClientID is set
+	// and the user already stored an event with it. It returns the stored
+	// event and whether it was newly created.
+	AddCustomMetricEvent(ctx context.Context, userID int64, e CustomMetricEvent) (*CustomMetricEvent, bool, error)
+	// ListRecentCustomMetricEvents returns the metric's latest limit events,
+	// newest first.
+	ListRecentCustomMetricEvents(ctx context.Context, userID, metricID int64, limit int) ([]CustomMetricEvent, error)
+	// ListCustomMetricEventsBetween returns the metric's events created in
+	// [from, to), oldest first.
+	ListCustomMetricEventsBetween(ctx context.Context, userID, metricID int64, from, to time.Time) ([]CustomMetricEvent, error)
+}
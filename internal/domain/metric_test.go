@@ -0,0 +1,38 @@
+package domain_test
+
+import (
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestMetricSlug(t *testing.T) {
+	for name, want := range map[string]string{
+		"Coffee":             "coffee",
+		"Coffee (cups)":      "coffee-cups",
+		"  Blood Pressure  ": "blood-pressure",
+		"HRV/ms":             "hrv-ms",
+		"Café 2":             "caf-2",
+		"☕":                  "",
+	} {
+		if got := domain.MetricSlug(name); got != want {
+			t.Errorf("MetricSlug(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestAggregateMetric(t *testing.T) {
+	values := []float64{2, 6, 1}
+	for agg, want := range map[string]float64{
+		domain.MetricLatest: 1,
+		domain.MetricSum:    9,
+		domain.MetricAvg:    3,
+	} {
+		if got, ok := domain.AggregateMetric(agg, values); !ok || got != want {
+			t.Errorf("AggregateMetric(%q) = %v, %v; want %v", agg, got, ok, want)
+		}
+	}
+	if _, ok := domain.AggregateMetric(domain.MetricSum, nil); ok {
+		t.Error("expected no value without events")
+	}
+}
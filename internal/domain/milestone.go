@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Milestone is a notable achievement derived from a user's history.
+type Milestone struct {
+	Day   string    `json:"day"`
+	At    time.Time `json:"at"`
+	Title string    `json:"title"`
+}
+
+// WeightMilestones returns the first weigh-in plus one milestone for each
+// further step kilograms lost below the first recorded weight, dated at the
+// weigh-in that first reached it. Entries may be in any order or unit.
+func WeightMilestones(entries []WeightEntry, step float64) []Milestone {
+	if len(entries) == 0 || step <= 0 {
+		return nil
+	}
+	sorted := append([]WeightEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	first := sorted[0]
+	out := []Milestone{{Day: first.Day, At: first.CreatedAt, Title: "First weigh-in"}}
+	start := ConvertWeight(first.Value, first.Unit, "kg")
+	reached := 0
+	for _, e := range sorted[1:] {
+		lost := start - ConvertWeight(e.Value, e.Unit, "kg")
+		n := int(math.Floor(lost/step + 1e-9))
+		for reached < n {
+			reached++
+			out = append(out, Milestone{
+				Day:   e.Day,
+				At:    e.CreatedAt,
+				Title: fmt.Sprintf("Lost %g kg", float64(reached)*step),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestWeightMilestones(t *testing.T) {
+	base := time.Date(2026, 1, 1, 7, 0, 0, 0, time.UTC)
+	entry := func(day int, v float64, unit string) domain.WeightEntry {
+		at := base.AddDate(0, 0, day)
+		return domain.WeightEntry{Day: at.Format("2006-01-02"), Value: v, Unit: unit, CreatedAt: at}
+	}
+	entries := []domain.WeightEntry{
+		entry(20, 84.0, "kg"), // 6 kg lost: milestones 2.5 and 5
+		entry(0, 90.0, "kg"),
+		entry(5, 88.0, "kg"),
+		entry(10, 191.8, "lb"), // ~87.0 kg: 2.5 kg reached
+		entry(15, 88.5, "kg"),  // regain: nothing new
+	}
+
+	got := domain.WeightMilestones(entries, 2.5)
+	want := []struct{ day, title string }{
+		{"2026-01-01", "First weigh-in"},
+		{"2026-01-11", "Lost 2.5 kg"},
+		{"2026-01-21", "Lost 5 kg"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d milestones, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Day != w.day || got[i].Title != w.title {
+			t.Errorf("milestone %d = %s %q; want %s %q", i, got[i].Day, got[i].Title, w.day, w.title)
+		}
+	}
+
+	if domain.WeightMilestones(nil, 2.5) != nil {
+		t.Error("expected no milestones without entries")
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Bounds of the daily mood scale.
+const (
+	MinMoodScore = 1
+	MaxMoodScore = 5
+)
+
+// MaxMoodNoteLength caps the note attached to a mood entry, in bytes.
+const MaxMoodNoteLength = 500
+
+// MoodEntry is a user's mood for one local day on a 1–5 scale, with an
+// optional note.
+type MoodEntry struct {
+	Day       string    `json:"day"`
+	Score     int       `json:"score"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// MoodRepository is the port for daily mood persistence.
+type MoodRepository interface {
+	// GetMoodEntry returns the entry for day, or nil if there is none.
+	GetMoodEntry(ctx context.Context, userID int64, day string) (*MoodEntry, error)
+	// SaveMoodEntry creates or replaces the entry for e.Day.
+	SaveMoodEntry(ctx context.Context, userID int64, e MoodEntry) error
+	// ListMoodEntries returns the entries for days from through to
+	// (inclusive, "YYYY-MM-DD"), oldest first.
+	ListMoodEntries(ctx context.Context, userID int64, from, to string) ([]MoodEntry, error)
+	// ListRecentMoodEntries returns the user's latest limit entries, newest
+	// day first.
+	ListRecentMoodEntries(ctx context.Context, userID int64, limit int) ([]MoodEntry, error)
+}
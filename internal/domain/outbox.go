@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxMessage is a notification awaiting delivery. Messages are stored
+// with the write that triggers them, e.g. marking a rule fired, and
+// delivered afterwards, so a crash between the two neither loses the
+// notification nor re-fires the rule. Delivery is at least once.
+type OutboxMessage struct {
+	ID int64 `json:"id"`
+	// Channel is the alert channel to deliver Notification over, whose
+	// Target is stored with it.
+	Channel      string       `json:"channel"`
+	Notification Notification `json:"notification"`
+	// Attempts counts the failed deliveries so far.
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// NextAttemptAt is when the message is next due; while a worker
+	// delivers it, its lease. Nil once delivery was given up: such
+	// messages are kept for the operator to inspect.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// OutboxRepository is the port for the notification outbox. Alert and rule
+// repositories add their notifications in the same transaction as marking
+// the rule fired; see AlertRuleRepository.MarkAlerted.
+type OutboxRepository interface {
+	// EnqueueOutbox stores msgs, due at once.
+	EnqueueOutbox(ctx context.Context, msgs ...OutboxMessage) error
+	// ClaimOutbox returns up to limit messages due at now, oldest first,
+	// and leases them until until, so other workers skip them while they
+	// are delivered. A message whose worker crashed is due again once its
+	// lease ends.
+	ClaimOutbox(ctx context.Context, now, until time.Time, limit int) ([]OutboxMessage, error)
+	// DeleteOutbox removes a delivered message.
+	DeleteOutbox(ctx context.Context, id int64) error
+	// FailOutbox records a failed delivery of the message and when to try
+	// it again; a nil next gives up on it.
+	FailOutbox(ctx context.Context, id int64, lastErr string, next *time.Time) error
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Planned entry kinds.
+const (
+	PlanKindWeight = "weight"
+	PlanKindWater  = "water"
+)
+
+// Plan limits.
+const (
+	// MaxUpcomingPlannedEntries caps a user's planned entries from today on.
+	MaxUpcomingPlannedEntries = 400
+	MaxPlanNoteLength         = 200
+)
+
+// PlannedEntry is a measurement the user means to log on a local day, such
+// as a scheduled weigh-in. It is kept apart from logged entries: the
+// calendar shows it on its day, and with a Channel it becomes a reminder
+// sent at At that day unless the entry was logged by then.
+type PlannedEntry struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"userId"`
+	Kind   string `json:"kind"`
+	Day    string `json:"day"`
+	// At is the local "HH:MM" the reminder is due; required with Channel.
+	At string `json:"at,omitempty"`
+	// Liters is the intake planned for PlanKindWater. The reminder is
+	// skipped once the day's total reaches it, or, when zero, once any
+	// water is logged.
+	Liters float64 `json:"liters,omitempty"`
+	Note   string  `json:"note,omitempty"`
+	// Channel is the alert channel to remind over, or empty for none.
+	// Target is channel specific as for rules.
+	Channel    string     `json:"channel,omitempty"`
+	Target     string     `json:"target,omitempty"`
+	RemindedAt *time.Time `json:"remindedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Validate checks p's kind, day, time and note, and clears Liters for
+// weigh-ins.
+func (p *PlannedEntry) Validate() error {
+	switch p.Kind {
+	case PlanKindWeight:
+		p.Liters = 0
+	case PlanKindWater:
+		if p.Liters < 0 || p.Liters > MaxRuleWaterLiters {
+			return fmt.Errorf("liters must be between 0 and %d", MaxRuleWaterLiters)
+		}
+	default:
+		return fmt.Errorf("kind must be one of %q", []string{PlanKindWeight, PlanKindWater})
+	}
+	if _, err := time.Parse("2006-01-02", p.Day); err != nil {
+		return errors.New("day must be YYYY-MM-DD")
+	}
+	if p.At != "" {
+		if _, err := time.Parse(ruleTimeOfDayLayout, p.At); err != nil {
+			return errors.New("at must be a local time of day as HH:MM")
+		}
+	} else if p.Channel != "" {
+		return errors.New("at is required to send a reminder")
+	}
+	if len(p.Note) > MaxPlanNoteLength {
+		return fmt.Errorf("note must be at most %d characters", MaxPlanNoteLength)
+	}
+	return nil
+}
+
+// Due reports whether p's reminder time has passed at now.
+func (p PlannedEntry) Due(now time.Time) bool {
+	at, err := time.ParseInLocation("2006-01-02 "+ruleTimeOfDayLayout, p.Day+" "+p.At, time.Local)
+	return err == nil && !now.Before(at)
+}
+
+// PlanRepository is the port for planned entry persistence.
+type PlanRepository interface {
+	CreatePlannedEntry(ctx context.Context, p PlannedEntry) (*PlannedEntry, error)
+	// GetPlannedEntry returns the user's planned entry with id, or nil.
+	GetPlannedEntry(ctx context.Context, userID, id int64) (*PlannedEntry, error)
+	// ListPlannedEntries returns the user's entries for days from through
+	// to (inclusive, "YYYY-MM-DD"), by day and time.
+	ListPlannedEntries(ctx context.Context, userID int64, from, to string) ([]PlannedEntry, error)
+	// CountPlannedEntries returns how many entries the user has planned
+	// for from or later.
+	CountPlannedEntries(ctx context.Context, userID int64, from string) (int, error)
+	// UpdatePlannedEntry replaces the entry's plan, keeping CreatedAt and
+	// clearing RemindedAt so a moved entry reminds again; it reports false
+	// if the user has no such entry.
+	UpdatePlannedEntry(ctx context.Context, p PlannedEntry) (bool, error)
+	DeletePlannedEntry(ctx context.Context, userID, id int64) (bool, error)
+	// ListPlanReminders returns every user's entries for day that have a
+	// channel and were not reminded of yet.
+	ListPlanReminders(ctx context.Context, day string) ([]PlannedEntry, error)
+	// MarkPlanReminded records when the entry's reminder was sent and, in
+	// the same transaction, adds queue to the outbox.
+	MarkPlanReminded(ctx context.Context, userID, id int64, at time.Time, queue ...OutboxMessage) error
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Profile is an additional person tracked under an owning account, such as a
+// child. A profile has no credentials of its own; its metrics are stored
+// under the profile ID exactly as an account's are stored under its user ID.
+type Profile struct {
+	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"ownerId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ProfileRepository is the port for profile persistence. Profile IDs share
+// the user ID space so metric repositories can scope by either.
+type ProfileRepository interface {
+	CreateProfile(ctx context.Context, ownerID int64, name string) (*Profile, error)
+	ListProfiles(ctx context.Context, ownerID int64) ([]Profile, error)
+	// GetProfile returns the profile only if it belongs to ownerID, or nil.
+	GetProfile(ctx context.Context, ownerID, id int64) (*Profile, error)
+}
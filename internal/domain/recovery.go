@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Account recovery lets a user who cannot sign in, typically one
+// provisioned by SSO or forward auth whose identity provider is gone, set a
+// local password with a one-time code. Codes are either emergency codes the
+// user generated in advance or a code an admin issued for them.
+const (
+	// RecoveryCodeCount is how many emergency codes are generated at once.
+	RecoveryCodeCount = 10
+	// IssuedRecoveryCodeTTL bounds how long an admin-issued code works.
+	IssuedRecoveryCodeTTL = 24 * time.Hour
+)
+
+// RecoveryRepository is the port for account recovery codes. Only hashes
+// of the codes are stored.
+type RecoveryRepository interface {
+	// ReplaceRecoveryCodes replaces the user's emergency codes with hashes.
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	// IssueRecoveryCode stores a code that works until expiresAt,
+	// replacing the user's previously issued one.
+	IssueRecoveryCode(ctx context.Context, userID int64, hash string, expiresAt time.Time) error
+	// CountRecoveryCodes returns how many unused emergency codes the user
+	// has.
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+	// RedeemRecoveryCode consumes the user's code with hash, unless it
+	// expired before now, and sets the user's password hash in the same
+	// transaction. It reports false, changing nothing, without such a code.
+	RedeemRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time, passwordHash string) (bool, error)
+}
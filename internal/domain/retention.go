@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionRepository is the port for downsampling old raw data. Water
+// events past the retention age are collapsed into one event per day
+// holding the day's total, so daily charts read the same with a fraction
+// of the rows. Weight events are never downsampled.
+type RetentionRepository interface {
+	// WaterUsersBefore returns the users with water events created before
+	// t.
+	WaterUsersBefore(ctx context.Context, t time.Time) ([]int64, error)
+	// ListWaterEventsBefore returns the user's water events created before
+	// t, oldest first.
+	ListWaterEventsBefore(ctx context.Context, userID int64, t time.Time) ([]WaterEvent, error)
+	// CollapseWaterEvents locks the user's event keepID and the events
+	// removeIDs, sets keepID's volume to their rounded total, detaches it
+	// from its import batch, and deletes the others, all at once, logging
+	// each change. The total is summed from the locked rows, so an edit
+	// made since they were listed is kept. If any of the events is gone,
+	// nothing changes and it returns false.
+	CollapseWaterEvents(ctx context.Context, userID, keepID int64, removeIDs []int64) (bool, error)
+}
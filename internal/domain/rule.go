@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rule condition kinds.
+const (
+	// RuleWaterBy fires when at most Threshold liters have been logged by
+	// the local time At, e.g. "no water logged by 14:00" with Threshold 0.
+	RuleWaterBy = "water.by"
+	// RuleWeightAbove fires when a weigh-in is above Threshold kg.
+	RuleWeightAbove = "weight.above"
+	// RuleWeightBelow fires when a weigh-in is below Threshold kg.
+	RuleWeightBelow = "weight.below"
+	// RuleWeighInMissed fires when there has been no weigh-in for Days days.
+	RuleWeighInMissed = "weight.missed"
+)
+
+// Rule limits.
+const (
+	MaxRulesPerUser     = 20
+	MaxRuleNameLength   = 100
+	MaxRuleMissedDays   = 90
+	MaxRuleWaterLiters  = 20
+	MaxRuleWeightKg     = 500
+	ruleTimeOfDayLayout = "15:04"
+)
+
+// Rule is a user-defined alert: a condition over the user's data and the
+// channel to notify when it holds. Rules are evaluated by the scheduled
+// `vitals alerts check`, and fire at most once per local day (water) or
+// per weigh-in (weight).
+type Rule struct {
+	ID        int64         `json:"id"`
+	UserID    int64         `json:"userId"`
+	Name      string        `json:"name"`
+	Condition RuleCondition `json:"condition"`
+	Channel   string        `json:"channel"`
+	// Target is channel specific: the URL for webhooks, unused for MQTT.
+	Target      string     `json:"target,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	LastFiredAt *time.Time `json:"lastFiredAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// RuleCondition is what a Rule watches for. Which fields apply depends on
+// Kind.
+type RuleCondition struct {
+	Kind string `json:"kind"`
+	// Threshold is in liters for RuleWaterBy and kg for the weight kinds.
+	Threshold float64 `json:"threshold,omitempty"`
+	// At is the local "HH:MM" by which RuleWaterBy checks the day's total.
+	At string `json:"at,omitempty"`
+	// Days is the gap without a weigh-in for RuleWeighInMissed.
+	Days int `json:"days,omitempty"`
+}
+
+// Validate checks that c is a known kind with in-range parameters for it,
+// and clears the fields the kind does not use.
+func (c *RuleCondition) Validate() error {
+	switch c.Kind {
+	case RuleWaterBy:
+		if _, err := time.Parse(ruleTimeOfDayLayout, c.At); err != nil {
+			return errors.New("at must be a local time of day as HH:MM")
+		}
+		if c.Threshold < 0 || c.Threshold > MaxRuleWaterLiters {
+			return fmt.Errorf("threshold must be between 0 and %d liters", MaxRuleWaterLiters)
+		}
+		c.Days = 0
+	case RuleWeightAbove, RuleWeightBelow:
+		if c.Threshold <= 0 || c.Threshold > MaxRuleWeightKg {
+			return fmt.Errorf("threshold must be within (0, %d] kg", MaxRuleWeightKg)
+		}
+		c.At, c.Days = "", 0
+	case RuleWeighInMissed:
+		if c.Days < 1 || c.Days > MaxRuleMissedDays {
+			return fmt.Errorf("days must be between 1 and %d", MaxRuleMissedDays)
+		}
+		c.At, c.Threshold = "", 0
+	default:
+		return fmt.Errorf("kind must be one of %q", []string{RuleWaterBy, RuleWeightAbove, RuleWeightBelow, RuleWeighInMissed})
+	}
+	return nil
+}
+
+// Due reports whether a RuleWaterBy condition's time of day has passed on
+// now's local day. Other kinds are always due.
+func (c RuleCondition) Due(now time.Time) bool {
+	if c.Kind != RuleWaterBy {
+		return true
+	}
+	at, err := time.Parse(ruleTimeOfDayLayout, c.At)
+	if err != nil {
+		return false
+	}
+	now = now.In(time.Local)
+	return now.Hour()*60+now.Minute() >= at.Hour()*60+at.Minute()
+}
+
+// Describe renders c as a short sentence for notifications.
+func (c RuleCondition) Describe() string {
+	switch c.Kind {
+	case RuleWaterBy:
+		if c.Threshold == 0 {
+			return "No water logged by " + c.At
+		}
+		return fmt.Sprintf("At most %g L of water logged by %s", c.Threshold, c.At)
+	case RuleWeightAbove:
+		return fmt.Sprintf("Weight above %g kg", c.Threshold)
+	case RuleWeightBelow:
+		return fmt.Sprintf("Weight below %g kg", c.Threshold)
+	case RuleWeighInMissed:
+		if c.Days == 1 {
+			return "No weigh-in for a day"
+		}
+		return fmt.Sprintf("No weigh-in for %d days", c.Days)
+	}
+	return c.Kind
+}
+
+// RuleRepository is the port for user-defined rule persistence.
+type RuleRepository interface {
+	CreateRule(ctx context.Context, rule Rule) (*Rule, error)
+	// GetRule returns the user's rule with id, or nil.
+	GetRule(ctx context.Context, userID, id int64) (*Rule, error)
+	ListRules(ctx context.Context, userID int64) ([]Rule, error)
+	// UpdateRule replaces the rule's definition, keeping LastFiredAt and
+	// CreatedAt; it reports false if the user has no such rule.
+	UpdateRule(ctx context.Context, rule Rule) (bool, error)
+	DeleteRule(ctx context.Context, userID, id int64) (bool, error)
+	// ListEnabledRules returns every user's enabled rules.
+	ListEnabledRules(ctx context.Context) ([]Rule, error)
+	// MarkRuleFired records when a rule last fired and, in the same
+	// transaction, adds queue to the outbox.
+	MarkRuleFired(ctx context.Context, userID, id int64, at time.Time, queue ...OutboxMessage) error
+}
@@ -0,0 +1,46 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestRuleCondition_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       domain.RuleCondition
+		wantErr bool
+	}{
+		{"water by time", domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00"}, false},
+		{"water bad time", domain.RuleCondition{Kind: domain.RuleWaterBy, At: "2pm"}, true},
+		{"water negative threshold", domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00", Threshold: -1}, true},
+		{"weight above", domain.RuleCondition{Kind: domain.RuleWeightAbove, Threshold: 90}, false},
+		{"weight without threshold", domain.RuleCondition{Kind: domain.RuleWeightBelow}, true},
+		{"missed", domain.RuleCondition{Kind: domain.RuleWeighInMissed, Days: 3}, false},
+		{"missed zero days", domain.RuleCondition{Kind: domain.RuleWeighInMissed}, true},
+		{"unknown kind", domain.RuleCondition{Kind: "sleep.short"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.c.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleCondition_Due(t *testing.T) {
+	c := domain.RuleCondition{Kind: domain.RuleWaterBy, At: "14:00"}
+	day := time.Date(2026, 3, 20, 0, 0, 0, 0, time.Local)
+	if c.Due(day.Add(13*time.Hour + 59*time.Minute)) {
+		t.Error("expected not due before 14:00")
+	}
+	if !c.Due(day.Add(14 * time.Hour)) {
+		t.Error("expected due at 14:00")
+	}
+	if !(domain.RuleCondition{Kind: domain.RuleWeightAbove}).Due(day) {
+		t.Error("expected weight rules to always be due")
+	}
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Settings limits.
+const (
+	MaxSettingKeyLength   = 64
+	MaxSettingValueLength = 4096
+	MaxSettingsPerUser    = 100
+)
+
+// settingKeyPattern matches namespaced keys such as "ui.theme".
+var settingKeyPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*(\.[a-zA-Z0-9_-]+)+$`)
+
+// localePattern matches the shape of a BCP 47 language tag such as "en-GB".
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
+// UserSettings maps namespaced preference keys ("ui.theme",
+// "charts.defaultDays") to JSON values. They are stored server-side so
+// preferences follow the user across devices.
+type UserSettings map[string]json.RawMessage
+
+// knownSettings validates the value of each key the app itself reads.
+// Keys outside this list are stored as-is for clients to use.
+var knownSettings = map[string]func(json.RawMessage) error{
+	"ui.theme":           oneOf("light", "dark", "system"),
+	"ui.locale":          locale,
+	"units.weight":       oneOf("kg", "lb"),
+	"units.volume":       oneOf("ml", "l", "oz"),
+	"charts.defaultDays": intRange(1, 366),
+	"charts.dailyWeight": oneOf(DailyWeightLatest, DailyWeightAverage),
+}
+
+// ValidateSetting checks a key's format and, for known keys, its value.
+func ValidateSetting(key string, value json.RawMessage) error {
+	if len(key) > MaxSettingKeyLength || !settingKeyPattern.MatchString(key) {
+		return fmt.Errorf("setting key %q must be namespaced, e.g. \"ui.theme\", and at most %d characters", key, MaxSettingKeyLength)
+	}
+	if len(value) > MaxSettingValueLength {
+		return fmt.Errorf("setting %s exceeds %d bytes", key, MaxSettingValueLength)
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("setting %s is not valid JSON", key)
+	}
+	if check, ok := knownSettings[key]; ok {
+		if err := check(value); err != nil {
+			return fmt.Errorf("setting %s %w", key, err)
+		}
+	}
+	return nil
+}
+
+func oneOf(allowed ...string) func(json.RawMessage) error {
+	return func(v json.RawMessage) error {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			for _, a := range allowed {
+				if s == a {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("must be one of %q", allowed)
+	}
+}
+
+func locale(v json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(v, &s); err != nil || len(s) > 35 || !localePattern.MatchString(s) {
+		return fmt.Errorf("must be a language tag such as %q", "en-GB")
+	}
+	return nil
+}
+
+func intRange(lo, hi int) func(json.RawMessage) error {
+	return func(v json.RawMessage) error {
+		var n int
+		if err := json.Unmarshal(v, &n); err != nil || n < lo || n > hi {
+			return fmt.Errorf("must be an integer between %d and %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+// SettingsRepository is the port for per-user preference storage.
+type SettingsRepository interface {
+	// GetSettings returns all of the user's settings (empty if none).
+	GetSettings(ctx context.Context, userID int64) (UserSettings, error)
+	// UpdateSettings stores set and deletes the keys in remove.
+	UpdateSettings(ctx context.Context, userID int64, set UserSettings, remove []string) error
+}
@@ -0,0 +1,45 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestValidateSetting(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{"ui.theme", `"dark"`, true},
+		{"ui.theme", `"neon"`, false},
+		{"ui.theme", `1`, false},
+		{"ui.locale", `"en-GB"`, true},
+		{"ui.locale", `"pt"`, true},
+		{"ui.locale", `"en_GB"`, false},
+		{"ui.locale", `"english"`, false},
+		{"units.weight", `"lb"`, true},
+		{"units.volume", `"oz"`, true},
+		{"units.volume", `"cups"`, false},
+		{"charts.defaultDays", `90`, true},
+		{"charts.defaultDays", `0`, false},
+		{"charts.defaultDays", `7.5`, false},
+		{"web.pinnedCards", `["weight","water"]`, true},
+		{"ios.widget.style", `{"compact":true}`, true},
+		{"theme", `"dark"`, false},
+		{"UI.theme", `"dark"`, false},
+		{"ui.", `"dark"`, false},
+		{"web.note", `not json`, false},
+		{"web." + strings.Repeat("k", domain.MaxSettingKeyLength), `1`, false},
+		{"web.blob", `"` + strings.Repeat("x", domain.MaxSettingValueLength) + `"`, false},
+	}
+	for _, tc := range tests {
+		err := domain.ValidateSetting(tc.key, json.RawMessage(tc.value))
+		if (err == nil) != tc.ok {
+			t.Errorf("ValidateSetting(%q, %.20s) = %v; want ok=%v", tc.key, tc.value, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Share grants a viewer (e.g. a coach) read-only access to an owner's data.
+type Share struct {
+	OwnerID        int64     `json:"ownerId"`
+	OwnerUsername  string    `json:"ownerUsername"`
+	ViewerID       int64     `json:"viewerId"`
+	ViewerUsername string    `json:"viewerUsername"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// ShareRepository is the port for share grant persistence.
+type ShareRepository interface {
+	// CreateShare grants access; granting an existing share is a no-op that
+	// returns the original grant.
+	CreateShare(ctx context.Context, ownerID, viewerID int64) (*Share, error)
+	DeleteShare(ctx context.Context, ownerID, viewerID int64) (bool, error)
+	// GetShare returns the grant from ownerID to viewerID, or nil.
+	GetShare(ctx context.Context, ownerID, viewerID int64) (*Share, error)
+	// ListShares returns every grant where userID is the owner or the viewer.
+	ListShares(ctx context.Context, userID int64) ([]Share, error)
+}
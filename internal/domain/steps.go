@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaxDailySteps caps one day's step count, well above any real day.
+const MaxDailySteps = 200000
+
+// StepsEntry is a user's step count for one local day, as reported by a
+// phone or wearable.
+type StepsEntry struct {
+	Day       string    `json:"day"`
+	Steps     int       `json:"steps"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// StepsRepository is the port for daily step count persistence.
+type StepsRepository interface {
+	// GetStepsEntry returns the entry for day, or nil if there is none.
+	GetStepsEntry(ctx context.Context, userID int64, day string) (*StepsEntry, error)
+	// SaveStepsEntry creates or replaces the entry for e.Day.
+	SaveStepsEntry(ctx context.Context, userID int64, e StepsEntry) error
+	// ListStepsEntries returns the entries for days from through to
+	// (inclusive, "YYYY-MM-DD"), oldest first.
+	ListStepsEntries(ctx context.Context, userID int64, from, to string) ([]StepsEntry, error)
+}
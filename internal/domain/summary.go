@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// WeeklySummary condenses one Monday-to-Sunday week.
+type WeeklySummary struct {
+	// WeekStart is the Monday the week begins on.
+	WeekStart string `json:"weekStart"`
+	WeekEnd   string `json:"weekEnd"`
+	// WeighIns counts the days with at least one weigh-in.
+	WeighIns int `json:"weighIns"`
+	// StartKg and EndKg are the first and last daily weights of the week;
+	// ChangeKg is their difference. All are nil without two weigh-ins.
+	StartKg  *float64 `json:"startKg"`
+	EndKg    *float64 `json:"endKg"`
+	ChangeKg *float64 `json:"changeKg"`
+	// AvgKg is the mean of the daily weights, or nil without weigh-ins.
+	AvgKg *float64 `json:"avgKg"`
+	// TotalWaterLiters is the week's intake; AvgWaterLiters is the mean
+	// daily intake across all seven days.
+	TotalWaterLiters float64 `json:"totalWaterLiters"`
+	AvgWaterLiters   float64 `json:"avgWaterLiters"`
+	// GoalDays counts the days the base water goal was met.
+	GoalDays int `json:"goalDays"`
+}
+
+// WeeklySummaryRepository is the port for precomputed weekly summaries.
+// A nightly job fills it so reports read cached rows instead of
+// recomputing over raw events.
+type WeeklySummaryRepository interface {
+	// SaveWeeklySummaries creates or replaces the user's summaries, keyed
+	// by WeekStart.
+	SaveWeeklySummaries(ctx context.Context, userID int64, sums []WeeklySummary) error
+	// ListWeeklySummaries returns the user's stored summaries for weeks
+	// starting on or after from ("YYYY-MM-DD").
+	ListWeeklySummaries(ctx context.Context, userID int64, from string) ([]WeeklySummary, error)
+	// ListActiveUserIDs returns, across all users, those whose weight or
+	// water data changed since the given time (including backdated
+	// entries), from the change log.
+	ListActiveUserIDs(ctx context.Context, since time.Time) ([]int64, error)
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Tag limits.
+const (
+	MaxTagLength    = 32
+	MaxTagsPerEntry = 10
+)
+
+// tagPattern matches a normalized tag such as "sick" or "long-haul".
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// NormalizeTags trims and lowercases tags, drops duplicates and sorts them.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if len(t) > MaxTagLength || !tagPattern.MatchString(t) {
+			return nil, fmt.Errorf("tag %q must be 1 to %d letters, digits, '-' or '_'", t, MaxTagLength)
+		}
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	if len(out) > MaxTagsPerEntry {
+		return nil, fmt.Errorf("at most %d tags are allowed per entry", MaxTagsPerEntry)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// TagFilter selects entries by tag: an entry matches when it carries every
+// tag in Include and none in Exclude.
+type TagFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParseTagFilter parses ?tag= values. Each value is a comma-separated list
+// of tags; a leading '-' excludes the tag, e.g. "travel,-sick".
+func ParseTagFilter(values []string) (TagFilter, error) {
+	var f TagFilter
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			exclude := strings.HasPrefix(t, "-")
+			norm, err := NormalizeTags([]string{strings.TrimPrefix(t, "-")})
+			if err != nil {
+				return TagFilter{}, err
+			}
+			if exclude {
+				f.Exclude = append(f.Exclude, norm[0])
+			} else {
+				f.Include = append(f.Include, norm[0])
+			}
+		}
+	}
+	return f, nil
+}
+
+// IsZero reports whether the filter matches everything.
+func (f TagFilter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match reports whether an entry with tags passes the filter.
+func (f TagFilter) Match(tags []string) bool {
+	for _, t := range f.Include {
+		if !slices.Contains(tags, t) {
+			return false
+		}
+	}
+	for _, t := range f.Exclude {
+		if slices.Contains(tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// TagCount is a tag in use and how many entries carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TagRepository is the port for tags on weight and water entries. Entries
+// are addressed by their change log entity ("weight" or "water") and ID.
+type TagRepository interface {
+	// SetEntryTags replaces the tags on one of the user's entries. It
+	// reports false if the user has no such entry.
+	SetEntryTags(ctx context.Context, userID int64, entity string, entryID int64, tags []string) (bool, error)
+	// EntryTags returns the tags of the given entries, keyed by entry ID.
+	// Entries without tags are absent from the map.
+	EntryTags(ctx context.Context, userID int64, entity string, ids []int64) (map[int64][]string, error)
+	// ListTags returns the user's tags with their use counts, by tag.
+	ListTags(ctx context.Context, userID int64) ([]TagCount, error)
+}
@@ -0,0 +1,62 @@
+package domain_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := domain.NormalizeTags([]string{" Travel", "sick", "travel", "long-haul"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"long-haul", "sick", "travel"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for _, bad := range [][]string{
+		{""},
+		{"two words"},
+		{"-sick"},
+		{strings.Repeat("x", domain.MaxTagLength+1)},
+		strings.Fields("a b c d e f g h i j k"),
+	} {
+		if _, err := domain.NormalizeTags(bad); err == nil {
+			t.Errorf("NormalizeTags(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	f, err := domain.ParseTagFilter([]string{"travel,-sick", "Work"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(f.Include, []string{"travel", "work"}) || !slices.Equal(f.Exclude, []string{"sick"}) {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+
+	tests := []struct {
+		tags []string
+		want bool
+	}{
+		{[]string{"travel", "work"}, true},
+		{[]string{"travel"}, false},
+		{[]string{"sick", "travel", "work"}, false},
+	}
+	for _, tc := range tests {
+		if got := f.Match(tc.tags); got != tc.want {
+			t.Errorf("Match(%v) = %v, want %v", tc.tags, got, tc.want)
+		}
+	}
+
+	if f, _ := domain.ParseTagFilter(nil); !f.IsZero() || !f.Match(nil) {
+		t.Fatal("expected an empty filter to match everything")
+	}
+	if _, err := domain.ParseTagFilter([]string{"travel,"}); err == nil {
+		t.Fatal("expected an error for an empty tag")
+	}
+}
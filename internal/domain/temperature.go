@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Temperature units.
+const (
+	TemperatureCelsius    = "c"
+	TemperatureFahrenheit = "f"
+)
+
+// Plausible body temperatures in °C; readings outside are rejected as typos
+// or the wrong unit.
+const (
+	MinBodyTemperatureC = 30.0
+	MaxBodyTemperatureC = 45.0
+)
+
+// TemperatureReading is a body temperature measurement, stored in the unit
+// it was taken in.
+type TemperatureReading struct {
+	ID       int64   `json:"id"`
+	UserID   int64   `json:"userId"`
+	Value    float64 `json:"value"`
+	Unit     string  `json:"unit"`
+	ClientID string  `json:"clientId,omitempty"`
+	// CreatedAt is when the temperature was taken.
+	CreatedAt time.Time `json:"createdAt"`
+	// DisplayValue is Value converted to DisplayUnit, set only when a
+	// listing asks for a unit.
+	DisplayValue float64 `json:"displayValue,omitempty"`
+	DisplayUnit  string  `json:"displayUnit,omitempty"`
+}
+
+// Validate normalises the unit and checks the value is a plausible body
+// temperature.
+func (r *TemperatureReading) Validate() error {
+	r.Unit = strings.ToLower(strings.TrimSpace(r.Unit))
+	if r.Unit != TemperatureCelsius && r.Unit != TemperatureFahrenheit {
+		return fmt.Errorf("unit must be %q or %q", TemperatureCelsius, TemperatureFahrenheit)
+	}
+	if c := ConvertTemperature(r.Value, r.Unit, TemperatureCelsius); c < MinBodyTemperatureC || c > MaxBodyTemperatureC {
+		return fmt.Errorf("temperature must be between %g and %g °C (%g and %g °F)",
+			MinBodyTemperatureC, MaxBodyTemperatureC,
+			CelsiusToFahrenheit(MinBodyTemperatureC), CelsiusToFahrenheit(MaxBodyTemperatureC))
+	}
+	return nil
+}
+
+// TemperatureRepository is the port for body temperature persistence.
+type TemperatureRepository interface {
+	// AddTemperatureReading stores r for the user unless r.ClientID is set
+	// and the user already stored a reading with it. It returns the stored
+	// reading and whether it was newly created.
+	AddTemperatureReading(ctx context.Context, userID int64, r TemperatureReading) (*TemperatureReading, bool, error)
+	// ListRecentTemperatureReadings returns the user's latest limit
+	// readings, newest first.
+	ListRecentTemperatureReadings(ctx context.Context, userID int64, limit int) ([]TemperatureReading, error)
+	// DeleteTemperatureReading removes one of the user's readings,
+	// reporting whether it existed.
+	DeleteTemperatureReading(ctx context.Context, userID, id int64) (bool, error)
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// API token scopes. A token only authenticates requests for its own scope.
+const (
+	// TokenScopeQuick allows the one-tap /api/quick logging endpoints.
+	TokenScopeQuick = "quick"
+	// TokenScopeFeed allows the read-only calendar and news feeds.
+	TokenScopeFeed = "feed"
+	// TokenScopeDashboard allows reading aggregates (today's totals, charts
+	// and stats) for wall displays, never individual entries or account
+	// details.
+	TokenScopeDashboard = "dashboard"
+	// TokenScopeAPI allows reading and logging metric data through the same
+	// endpoints as the web app, for scripts, but never account, token or
+	// admin endpoints.
+	TokenScopeAPI = "api"
+	// TokenScopeReadOnly allows reading what an api token can, never
+	// writing.
+	TokenScopeReadOnly = "read-only"
+	// TokenScopeWeightWrite only allows logging and undoing weigh-ins,
+	// without reading anything back, e.g. for a smart scale.
+	TokenScopeWeightWrite = "weight:write"
+	// TokenScopeWaterWrite only allows logging and undoing water, without
+	// reading anything back, e.g. for a smart bottle.
+	TokenScopeWaterWrite = "water:write"
+)
+
+// ValidTokenScope reports whether scope is a known token scope.
+func ValidTokenScope(scope string) bool {
+	switch scope {
+	case TokenScopeQuick, TokenScopeFeed, TokenScopeDashboard, TokenScopeAPI,
+		TokenScopeReadOnly, TokenScopeWeightWrite, TokenScopeWaterWrite:
+		return true
+	}
+	return false
+}
+
+// APIToken is a long-lived credential for automations such as Apple
+// Shortcuts. Only a hash of the secret is stored.
+type APIToken struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	Name      string    `json:"name"`
+	Scope     string    `json:"scope"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	// LastUsedAt is when the token last authenticated a request, refreshed
+	// at most every few minutes; nil if it never has.
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// APITokenRepository is the port for API token persistence.
+type APITokenRepository interface {
+	CreateAPIToken(ctx context.Context, userID int64, name, scope, tokenHash string) (*APIToken, error)
+	// GetAPITokenByHash returns the token with the given hash, or nil.
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	ListAPITokens(ctx context.Context, userID int64) ([]APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, id int64) (bool, error)
+	// DeleteAPITokens revokes all of the user's tokens and returns how
+	// many there were.
+	DeleteAPITokens(ctx context.Context, userID int64) (int, error)
+	// TouchAPIToken records that the token with id was used at at.
+	TouchAPIToken(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// Trend directions.
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// TrendFlatKg is the smallest change, in kg, that counts as moving up or
+// down rather than flat; smaller changes are within day-to-day noise.
+const TrendFlatKg = 0.2
+
+// WeightSpan aggregates a user's weigh-ins since a point in time.
+type WeightSpan struct {
+	Count   int
+	FirstKg float64
+	LastKg  float64
+	FirstAt time.Time
+	LastAt  time.Time
+}
+
+// WeightTrend is the weight change over the last Days days.
+type WeightTrend struct {
+	Days int `json:"days"`
+	// ChangeKg is the last weigh-in minus the first in the window, or nil
+	// with fewer than two weigh-ins.
+	ChangeKg *float64 `json:"changeKg"`
+	// Direction is TrendUp, TrendDown or TrendFlat, or empty when ChangeKg
+	// is nil.
+	Direction string `json:"direction,omitempty"`
+}
+
+// NewWeightTrend derives the trend over days from span, which may be nil.
+func NewWeightTrend(days int, span *WeightSpan) WeightTrend {
+	t := WeightTrend{Days: days}
+	if span == nil || span.Count < 2 {
+		return t
+	}
+	change := span.LastKg - span.FirstKg
+	t.ChangeKg = &change
+	switch {
+	case math.Abs(change) < TrendFlatKg:
+		t.Direction = TrendFlat
+	case change > 0:
+		t.Direction = TrendUp
+	default:
+		t.Direction = TrendDown
+	}
+	return t
+}
@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"testing"
+
+	"vitals/internal/domain"
+)
+
+func TestNewWeightTrend(t *testing.T) {
+	tests := []struct {
+		name       string
+		span       *domain.WeightSpan
+		wantChange float64
+		wantDir    string
+	}{
+		{"no weigh-ins", nil, 0, ""},
+		{"single weigh-in", &domain.WeightSpan{Count: 1, FirstKg: 80, LastKg: 80}, 0, ""},
+		{"down", &domain.WeightSpan{Count: 3, FirstKg: 82, LastKg: 80.5}, -1.5, domain.TrendDown},
+		{"up", &domain.WeightSpan{Count: 2, FirstKg: 80, LastKg: 81}, 1, domain.TrendUp},
+		{"within noise", &domain.WeightSpan{Count: 2, FirstKg: 80, LastKg: 80.1}, 0.1, domain.TrendFlat},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := domain.NewWeightTrend(7, tc.span)
+			if got.Days != 7 || got.Direction != tc.wantDir {
+				t.Errorf("got %+v, want direction %q", got, tc.wantDir)
+			}
+			if tc.wantDir == "" {
+				if got.ChangeKg != nil {
+					t.Errorf("expected no change, got %v", *got.ChangeKg)
+				}
+				return
+			}
+			if got.ChangeKg == nil || *got.ChangeKg-tc.wantChange > 1e-9 || tc.wantChange-*got.ChangeKg > 1e-9 {
+				t.Errorf("change = %v, want %v", got.ChangeKg, tc.wantChange)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UsageWeek counts activity across every user for one Monday-to-Sunday
+// week, in UTC. It holds counts only: no user IDs and no measurements.
+type UsageWeek struct {
+	// WeekStart is the Monday the week begins on ("YYYY-MM-DD").
+	WeekStart string `json:"weekStart"`
+	// ActiveUsers counts the users and profiles with at least one weight
+	// or water event in the week; Entries counts those events.
+	ActiveUsers int `json:"activeUsers"`
+	Entries     int `json:"entries"`
+}
+
+// UsageRepository is the port for instance-wide usage aggregates.
+type UsageRepository interface {
+	// WeeklyUsage returns the weeks with events since the given time,
+	// oldest first.
+	WeeklyUsage(ctx context.Context, since time.Time) ([]UsageWeek, error)
+}
+
+// UsageWeekOf returns the start of t's usage week: midnight UTC on the
+// Monday on or before it.
+func UsageWeekOf(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
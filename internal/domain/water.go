@@ -2,21 +2,56 @@ package domain
 
 import (
 	"context"
+	"math"
 	"time"
 )
 
+// RoundLiters rounds a water amount to the millilitre. Events keep the
+// amount as logged and totals are summed at full precision, then rounded
+// with RoundLiters before they are shown or compared with a goal, so
+// 0.1 + 0.2 L reads 0.3 L, not 0.30000000000000004.
+func RoundLiters(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
 // WaterEvent represents a single water intake/decrement event.
 type WaterEvent struct {
 	ID          int64     `json:"id"`
 	UserID      int64     `json:"userId"`
 	DeltaLiters float64   `json:"deltaLiters"`
+	ClientID    string    `json:"clientId,omitempty"`
 	CreatedAt   time.Time `json:"createdAt"`
+	Tags        []string  `json:"tags,omitempty"`
+}
+
+// WaterEventPatch holds the fields of a water event to change; nil fields
+// are left as they are.
+type WaterEventPatch struct {
+	DeltaLiters *float64
+	CreatedAt   *time.Time
 }
 
 // WaterRepository is the port for water persistence.
 type WaterRepository interface {
 	AddWaterEvent(ctx context.Context, userID int64, deltaLiters float64, createdAt time.Time) (int64, error)
+	// AddWaterEventWithClientID inserts a water event unless one with the
+	// same client ID already exists for the user. It returns the stored
+	// event and whether it was newly created.
+	AddWaterEventWithClientID(ctx context.Context, userID int64, clientID string, deltaLiters float64, createdAt time.Time) (*WaterEvent, bool, error)
 	DeleteWaterEvent(ctx context.Context, userID int64, id int64) error
+	// DeleteLatestWaterEvent removes the user's most recent water event in
+	// one atomic step and returns its ID, or 0 if there was none.
+	DeleteLatestWaterEvent(ctx context.Context, userID int64) (int64, error)
+	// UpdateWaterEvent applies p to the user's water event with id, logging
+	// the change, and returns the updated event, or nil if there is none.
+	UpdateWaterEvent(ctx context.Context, userID, id int64, p WaterEventPatch) (*WaterEvent, error)
 	ListRecentWaterEvents(ctx context.Context, userID int64, limit int) ([]WaterEvent, error)
+	// ListRecentWaterPage returns the most recent water events up to limit
+	// along with a summary of all the user's water events.
+	ListRecentWaterPage(ctx context.Context, userID int64, limit int) ([]WaterEvent, ListSummary, error)
+	// EachWaterEvent calls fn with every water event of the user, oldest
+	// first, without loading them all at once. It stops at and returns the
+	// first error fn returns.
+	EachWaterEvent(ctx context.Context, userID int64, fn func(WaterEvent) error) error
 	WaterTotalForLocalDay(ctx context.Context, userID int64, localDay string) (float64, error)
 }
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Webhook events. The metric events carry the MetricEvent as their data.
+const (
+	WebhookEventWeightRecorded = EventWeightRecorded
+	WebhookEventWaterRecorded  = EventWaterRecorded
+	// WebhookEventGoalReached fires when a water event brings today's
+	// total up to the day's goal. Its data is a GoalReached.
+	WebhookEventGoalReached = "goal.reached"
+)
+
+// WebhookEvents lists the events webhooks can subscribe to.
+var WebhookEvents = []string{WebhookEventWeightRecorded, WebhookEventWaterRecorded, WebhookEventGoalReached}
+
+// MaxWebhooksPerUser bounds how many webhooks a user may register.
+const MaxWebhooksPerUser = 10
+
+// Webhook is a URL a user registered to be POSTed their events.
+type Webhook struct {
+	ID     int64    `json:"id"`
+	UserID int64    `json:"userId"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret keys the HMAC signature of each delivery. It is generated when
+	// the webhook is created and only shown then.
+	Secret    string    `json:"secret,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Subscribes reports whether h is enabled and subscribed to event.
+func (h Webhook) Subscribes(event string) bool {
+	return h.Enabled && slices.Contains(h.Events, event)
+}
+
+// GoalReached is the data of a WebhookEventGoalReached event.
+type GoalReached struct {
+	Goal        string  `json:"goal"`
+	Day         string  `json:"day"`
+	TotalLiters float64 `json:"totalLiters"`
+	GoalLiters  float64 `json:"goalLiters"`
+}
+
+// WebhookDelivery is one event POSTed, or to be POSTed, to a webhook. It
+// is queued like an OutboxMessage and kept after delivery as the webhook's
+// delivery log.
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhookId"`
+	UserID    int64  `json:"-"`
+	Event     string `json:"event"`
+	// Payload is the JSON body POSTed.
+	Payload json.RawMessage `json:"payload"`
+	// Attempts counts the deliveries tried so far.
+	Attempts int `json:"attempts"`
+	// StatusCode is the HTTP status of the last attempt, zero if it got no
+	// response.
+	StatusCode int       `json:"statusCode,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// DeliveredAt is set once the webhook accepted the delivery.
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	// NextAttemptAt is when the delivery is next due, or its lease while
+	// a worker sends it. Nil once delivered or given up on.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// WebhookSender is the port for POSTing deliveries.
+type WebhookSender interface {
+	// Send POSTs d's payload to url, signed with secret, and returns the
+	// response status. Non-2xx responses are errors.
+	Send(ctx context.Context, url, secret string, d WebhookDelivery) (int, error)
+}
+
+// WebhookRepository is the port for webhook persistence.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, h Webhook) (*Webhook, error)
+	// GetWebhook returns the user's webhook with id, with its secret, or
+	// nil.
+	GetWebhook(ctx context.Context, userID, id int64) (*Webhook, error)
+	// ListWebhooks returns the user's webhooks, oldest first, with their
+	// secrets.
+	ListWebhooks(ctx context.Context, userID int64) ([]Webhook, error)
+	// UpdateWebhook replaces the webhook's URL, events and enabled flag,
+	// keeping its secret; it reports false if the user has no such webhook.
+	UpdateWebhook(ctx context.Context, h Webhook) (bool, error)
+	// DeleteWebhook removes the webhook and its deliveries.
+	DeleteWebhook(ctx context.Context, userID, id int64) (bool, error)
+
+	// EnqueueWebhookDeliveries stores ds, due at once.
+	EnqueueWebhookDeliveries(ctx context.Context, ds ...WebhookDelivery) error
+	// ClaimWebhookDeliveries returns up to limit deliveries due at now,
+	// oldest first, leased until until; see OutboxRepository.ClaimOutbox.
+	ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]WebhookDelivery, error)
+	// CompleteWebhookDelivery records a successful attempt.
+	CompleteWebhookDelivery(ctx context.Context, id int64, status int, at time.Time) error
+	// FailWebhookDelivery records a failed attempt and when to try again;
+	// a nil next gives up on the delivery.
+	FailWebhookDelivery(ctx context.Context, id int64, status int, lastErr string, next *time.Time) error
+	// ListWebhookDeliveries returns the webhook's latest deliveries, newest
+	// first.
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]WebhookDelivery, error)
+	// PruneWebhookDeliveries removes deliveries created before before that
+	// are no longer due, and returns how many were removed.
+	PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
@@ -5,6 +5,18 @@ import (
 	"time"
 )
 
+// Daily weight modes: how a day with several weigh-ins is reduced to one
+// value in charts and reports.
+const (
+	DailyWeightLatest  = "latest"
+	DailyWeightAverage = "average"
+)
+
+// ValidDailyWeight reports whether mode is one of the daily weight modes.
+func ValidDailyWeight(mode string) bool {
+	return mode == DailyWeightLatest || mode == DailyWeightAverage
+}
+
 // WeightEntry represents a single weight measurement.
 type WeightEntry struct {
 	ID        int64     `json:"id"`
@@ -12,13 +24,39 @@ type WeightEntry struct {
 	Day       string    `json:"day"`
 	Value     float64   `json:"value"`
 	Unit      string    `json:"unit"`
+	ClientID  string    `json:"clientId,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
+	Tags      []string  `json:"tags,omitempty"`
+	// DisplayValue and DisplayUnit are Value converted to the unit the
+	// listing was requested in; they are only set on listings.
+	DisplayValue float64 `json:"displayValue,omitempty"`
+	DisplayUnit  string  `json:"displayUnit,omitempty"`
 }
 
 // WeightRepository is the port for weight persistence.
 type WeightRepository interface {
 	AddWeightEvent(ctx context.Context, userID int64, value float64, unit string, createdAt time.Time) (int64, error)
+	// AddWeightEventWithClientID inserts a weight event unless one with the
+	// same client ID already exists for the user. It returns the stored
+	// entry and whether it was newly created.
+	AddWeightEventWithClientID(ctx context.Context, userID int64, clientID string, value float64, unit string, createdAt time.Time) (*WeightEntry, bool, error)
+	// DeleteLatestWeightEvent removes the user's most recent weight event in
+	// one atomic step, reporting whether there was one.
 	DeleteLatestWeightEvent(ctx context.Context, userID int64) (bool, error)
 	LatestWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*WeightEntry, error)
+	// AverageWeightForLocalDay returns the mean of the day's weigh-ins in
+	// kg, as an entry stamped with the latest weigh-in's time, or nil if
+	// there were none.
+	AverageWeightForLocalDay(ctx context.Context, userID int64, localDay string) (*WeightEntry, error)
+	// WeightSpanSince aggregates the weigh-ins at or after since, or returns
+	// nil if there were none.
+	WeightSpanSince(ctx context.Context, userID int64, since time.Time) (*WeightSpan, error)
 	ListRecentWeightEvents(ctx context.Context, userID int64, limit int) ([]WeightEntry, error)
+	// ListRecentWeightPage returns the most recent weight events up to limit
+	// along with a summary of all the user's weight events.
+	ListRecentWeightPage(ctx context.Context, userID int64, limit int) ([]WeightEntry, ListSummary, error)
+	// EachWeightEvent calls fn with every weight event of the user, oldest
+	// first, without loading them all at once. It stops at and returns the
+	// first error fn returns.
+	EachWeightEvent(ctx context.Context, userID int64, fn func(WeightEntry) error) error
 }
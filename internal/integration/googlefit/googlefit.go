@@ -0,0 +1,172 @@
+// Package googlefit implements domain.HealthSource with the Google Fit REST
+// API, reading weight and hydration datapoints.
+package googlefit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"vitals/internal/domain"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultBaseURL = "https://www.googleapis.com/fitness/v1/users/me"
+	// The merged data sources combine every app writing to Google Fit.
+	weightSource    = "derived:com.google.weight:com.google.android.gms:merge_weight"
+	hydrationSource = "derived:com.google.hydration:com.google.android.gms:merged"
+)
+
+// endpoint is Google's OAuth2 endpoint.
+var endpoint = oauth2.Endpoint{
+	AuthURL:  "https://accounts.google.com/o/oauth2/auth",
+	TokenURL: "https://oauth2.googleapis.com/token",
+}
+
+// scopes grants read access to body measurements (weight) and nutrition
+// (hydration).
+var scopes = []string{
+	"https://www.googleapis.com/auth/fitness.body.read",
+	"https://www.googleapis.com/auth/fitness.nutrition.read",
+}
+
+// Client reads users' Google Fit data with their OAuth2 tokens.
+type Client struct {
+	config  oauth2.Config
+	baseURL string
+	http    *http.Client
+}
+
+var _ domain.HealthSource = (*Client)(nil)
+
+// New returns a Client for the OAuth2 client clientID. redirectURL is the
+// callback registered for it, where the consent page returns the user.
+func New(clientID, clientSecret, redirectURL string) *Client {
+	return &Client{
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     endpoint,
+			Scopes:       scopes,
+		},
+		baseURL: defaultBaseURL,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the consent page URL. It asks for offline access, so
+// the token can be refreshed when the user is not around.
+func (c *Client) AuthCodeURL(state string) string {
+	return c.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+}
+
+// Exchange trades the consent page's code for a token.
+func (c *Client) Exchange(ctx context.Context, code string) (*domain.OAuthToken, error) {
+	tok, err := c.config.Exchange(c.context(ctx), code)
+	if err != nil {
+		return nil, fmt.Errorf("googlefit: exchange: %w", err)
+	}
+	return fromOAuth2(tok), nil
+}
+
+// Readings returns the weigh-ins, in kilograms, and drinks, in liters,
+// recorded in [from, to).
+func (c *Client) Readings(ctx context.Context, tok domain.OAuthToken, from, to time.Time) ([]domain.ExternalReading, *domain.OAuthToken, error) {
+	ctx = c.context(ctx)
+	ts := c.config.TokenSource(ctx, &oauth2.Token{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		Expiry:       tok.Expiry,
+	})
+	hc := oauth2.NewClient(ctx, ts)
+
+	var out []domain.ExternalReading
+	for _, src := range []struct{ kind, id string }{
+		{"weight", weightSource},
+		{"water", hydrationSource},
+	} {
+		points, err := c.dataset(ctx, hc, src.id, from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range points {
+			if len(p.Value) == 0 {
+				continue
+			}
+			nanos, err := strconv.ParseInt(p.StartTimeNanos, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("googlefit: invalid start time %q", p.StartTimeNanos)
+			}
+			out = append(out, domain.ExternalReading{
+				ID:    src.kind + ":" + p.StartTimeNanos,
+				Kind:  src.kind,
+				Value: p.Value[0].FpVal,
+				At:    time.Unix(0, nanos),
+			})
+		}
+	}
+
+	current, err := ts.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("googlefit: token: %w", err)
+	}
+	return out, fromOAuth2(current), nil
+}
+
+// dataset is the subset of a dataset response used here.
+type dataset struct {
+	Point []point `json:"point"`
+}
+
+// point is a datapoint. Weight and hydration points hold one floating
+// point value.
+type point struct {
+	StartTimeNanos string `json:"startTimeNanos"`
+	Value          []struct {
+		FpVal float64 `json:"fpVal"`
+	} `json:"value"`
+}
+
+// dataset returns the points of the data source recorded in [from, to).
+func (c *Client) dataset(ctx context.Context, hc *http.Client, source string, from, to time.Time) ([]point, error) {
+	u := fmt.Sprintf("%s/dataSources/%s/datasets/%d-%d", c.baseURL, url.PathEscape(source), from.UnixNano(), to.UnixNano())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("googlefit: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googlefit: %s: %s", source, resp.Status)
+	}
+
+	var d dataset
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("googlefit: decode: %w", err)
+	}
+	return d.Point, nil
+}
+
+// context makes the oauth2 package use c's HTTP client for token requests.
+func (c *Client) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, c.http)
+}
+
+func fromOAuth2(t *oauth2.Token) *domain.OAuthToken {
+	return &domain.OAuthToken{
+		Provider:     domain.IntegrationGoogleFit,
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		Expiry:       t.Expiry,
+	}
+}
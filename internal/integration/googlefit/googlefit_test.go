@@ -0,0 +1,73 @@
+package googlefit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vitals/internal/domain"
+)
+
+func TestClientReadings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/token":
+			if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh" {
+				t.Errorf("unexpected token request: %v", r.Form)
+			}
+			_, _ = w.Write([]byte(`{"access_token":"fresh","token_type":"Bearer","expires_in":3600}`))
+		case r.Header.Get("Authorization") != "Bearer fresh":
+			t.Errorf("expected the refreshed token, got %q", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.Contains(r.URL.Path, "com.google.weight"):
+			if !strings.HasSuffix(r.URL.Path, "/datasets/1000000000-2000000000") {
+				t.Errorf("unexpected range in %s", r.URL.Path)
+			}
+			_, _ = w.Write([]byte(`{"point":[{"startTimeNanos":"1500000000","value":[{"fpVal":80.5}]}]}`))
+		case strings.Contains(r.URL.Path, "com.google.hydration"):
+			_, _ = w.Write([]byte(`{"point":[{"startTimeNanos":"1600000000","value":[{"fpVal":0.3}]},{"startTimeNanos":"1700000000","value":[]}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New("id", "secret", "https://vitals.example/api/integrations/googlefit/callback")
+	c.baseURL = srv.URL
+	c.config.Endpoint.TokenURL = srv.URL + "/token"
+
+	expired := domain.OAuthToken{AccessToken: "stale", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)}
+	readings, tok, err := c.Readings(context.Background(), expired, time.Unix(1, 0), time.Unix(2, 0))
+	if err != nil {
+		t.Fatalf("Readings failed: %v", err)
+	}
+	want := []domain.ExternalReading{
+		{ID: "weight:1500000000", Kind: "weight", Value: 80.5, At: time.Unix(0, 1500000000)},
+		{ID: "water:1600000000", Kind: "water", Value: 0.3, At: time.Unix(0, 1600000000)},
+	}
+	if len(readings) != len(want) {
+		t.Fatalf("expected %d readings, got %+v", len(want), readings)
+	}
+	for i := range want {
+		if readings[i] != want[i] {
+			t.Errorf("reading %d = %+v, want %+v", i, readings[i], want[i])
+		}
+	}
+	if tok.AccessToken != "fresh" || tok.RefreshToken != "refresh" {
+		t.Errorf("expected the refreshed token with the old refresh token, got %+v", tok)
+	}
+}
+
+func TestClientAuthCodeURL(t *testing.T) {
+	u := New("id", "secret", "https://vitals.example/cb").AuthCodeURL("xyz")
+	for _, want := range []string{"state=xyz", "access_type=offline", "fitness.body.read", "client_id=id"} {
+		if !strings.Contains(u, want) {
+			t.Errorf("expected %q in %s", want, u)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package testdoubles
+
+import (
+	"testing"
+	"time"
+	// Embed the zone database so zone tests pass on hosts without one.
+	_ "time/tzdata"
+)
+
+// EdgeZones are IANA zones whose calendar days are irregular: days of 23
+// and 25 hours, clock changes at midnight, half-hour changes and a skipped
+// day. Day-boundary tests should hold in each of them.
+var EdgeZones = []string{
+	"UTC",
+	"America/New_York",
+	"Europe/London",
+	"America/Santiago",    // clocks change at midnight
+	"America/Havana",      // clocks change at midnight
+	"Asia/Tehran",         // clocks changed at midnight until 2022
+	"Australia/Lord_Howe", // half-hour change
+	"Asia/Kathmandu",      // +05:45
+	"Pacific/Apia",        // skipped 2011-12-30
+	"Pacific/Kiritimati",  // +14
+	"Pacific/Pago_Pago",   // -11
+}
+
+// Zone loads the IANA zone name or fails the test.
+func Zone(t testing.TB, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load zone %s: %v", name, err)
+	}
+	return loc
+}
+
+// InZone makes name the local zone, which the repositories bucket days in,
+// until the test ends. Tests using it must not run in parallel.
+func InZone(t testing.TB, name string) *time.Location {
+	t.Helper()
+	loc := Zone(t, name)
+	prev := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = prev })
+	return loc
+}
@@ -49,7 +49,7 @@ decWaterBtn.addEventListener('click', async ()=>{
 });
 
 undoWaterBtn.addEventListener('click', async ()=>{
-  await fetch('/api/water/undo-last', {method:'POST'});
+  await fetch('/api/v1/water/undo-last', {method:'POST'});
   await refresh();
 });
 
@@ -59,7 +59,7 @@ saveWeightBtn.addEventListener('click', async ()=>{
     toast('Enter a valid weight');
     return;
   }
-  const res = await fetch('/api/weight/today', {
+  const res = await fetch('/api/v1/weight/today', {
     method:'PUT',
     headers:{'Content-Type':'application/json'},
     body: JSON.stringify({value, unit})
@@ -74,7 +74,7 @@ saveWeightBtn.addEventListener('click', async ()=>{
 });
 
 undoWeightBtn.addEventListener('click', async ()=>{
-  const res = await fetch('/api/weight/undo-last', {method:'POST'});
+  const res = await fetch('/api/v1/weight/undo-last', {method:'POST'});
   const j = await safeJson(res);
   await refresh();
   if(j?.deleted){
@@ -84,7 +84,7 @@ undoWeightBtn.addEventListener('click', async ()=>{
   }
 });
 logoutBtn.addEventListener('click', async () => {
-    await fetch('/api/auth/logout', { method: 'POST' });
+    await fetch('/api/v1/auth/logout', { method: 'POST' });
     location.reload();
 });
 
@@ -100,7 +100,7 @@ document.querySelectorAll('button[data-delta]').forEach(btn=>{
 });
 
 async function postWater(deltaLiters){
-  const res = await fetch('/api/water/event', {
+  const res = await fetch('/api/v1/water/event', {
     method:'POST',
     headers:{'Content-Type':'application/json'},
     body: JSON.stringify({deltaLiters})
@@ -168,10 +168,10 @@ function waterItem(it){
 async function refresh(){
   statusEl.textContent = 'Syncing…';
   const [wToday, wRecent, waterToday, waterRecent] = await Promise.all([
-    fetch('/api/weight/today').then(safeJson),
-    fetch('/api/weight/recent?limit=14').then(safeJson),
-    fetch('/api/water/today').then(safeJson),
-    fetch('/api/water/recent?limit=20').then(safeJson),
+    fetch('/api/v1/weight/today').then(safeJson),
+    fetch('/api/v1/weight/recent?limit=14').then(safeJson),
+    fetch('/api/v1/water/today').then(safeJson),
+    fetch('/api/v1/water/recent?limit=20').then(safeJson),
   ]);
 
   if(wToday?.entry){
@@ -204,7 +204,20 @@ function toast(msg){
   toastTimer = setTimeout(()=>{statusEl.textContent='';}, 2000);
 }
 
+// Live updates: refresh when another device changes today's data. Bursts
+// of changes, such as an import, refresh once.
+let liveTimer;
+function watchChanges(){
+  if(!window.EventSource) return;
+  const stream = new EventSource('/api/v1/events/stream');
+  stream.addEventListener('change', ()=>{
+    clearTimeout(liveTimer);
+    liveTimer = setTimeout(refresh, 300);
+  });
+}
+
 setUnit(unit);
 initTheme();
 setAddDelta(addDelta);
 refresh();
+watchChanges();
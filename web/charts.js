@@ -45,7 +45,7 @@ document.querySelectorAll('button[data-days]').forEach((btn) => {
 });
 
 logoutBtn.addEventListener('click', async () => {
-  await fetch('/api/auth/logout', { method: 'POST' });
+  await fetch('/api/v1/auth/logout', { method: 'POST' });
   location.reload();
 });
 
@@ -163,7 +163,7 @@ function drawLineChart(canvas, series, opts) {
 
 async function refresh() {
   statusEl.textContent = 'Loading…';
-  const res = await fetch(`/api/charts/daily?days=${encodeURIComponent(days)}&unit=${encodeURIComponent(unit)}`);
+  const res = await fetch(`/api/v1/charts/daily?days=${encodeURIComponent(days)}&unit=${encodeURIComponent(unit)}`);
   const j = await safeJson(res);
   if (!res.ok) {
     statusEl.textContent = j?.error || 'Failed to load';